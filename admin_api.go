@@ -0,0 +1,132 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// adminAuth protects an admin API handler with the configured bearer token.
+// With no token configured the admin API is open, so only enable it on a
+// trusted network in that case. Each of admin.users has a token of its own,
+// and the identity it names is attached to the request for dual control;
+// the shared token is the identity adminSharedIdentity.
+func adminAuth(cfg *AdminConfig, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if cfg.Token != "" || len(cfg.Users) > 0 {
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			identity := ""
+			if ok {
+				identity = adminTokenIdentity(cfg, token)
+			}
+			if identity == "" {
+				writeJSONError(w, http.StatusUnauthorized, "missing or invalid admin token")
+				return
+			}
+			ctx := context.WithValue(r.Context(), adminIdentityKey{}, identity)
+			if customers := adminUserCustomers(cfg, identity); len(customers) > 0 {
+				ctx = context.WithValue(ctx, adminCustomersKey{}, customers)
+			}
+			r = r.WithContext(ctx)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// adminSharedIdentity is the identity of the shared admin.token. Anyone
+// holding the token is this identity, so it can't take part in dual control.
+const adminSharedIdentity = "admin"
+
+// adminIdentityKey holds the authenticated admin identity in a request context
+type adminIdentityKey struct{}
+
+// adminIdentity returns the admin who made a request, or "" if the admin API is open
+func adminIdentity(ctx context.Context) string {
+	identity, _ := ctx.Value(adminIdentityKey{}).(string)
+	return identity
+}
+
+// adminCustomersKey holds the customers a request's admin is limited to
+type adminCustomersKey struct{}
+
+// adminUserCustomers returns the admin.users customers of an identity
+func adminUserCustomers(cfg *AdminConfig, identity string) []string {
+	if identity == adminSharedIdentity {
+		return nil
+	}
+	for _, user := range cfg.Users {
+		if user.Name == identity {
+			return user.Customers
+		}
+	}
+	return nil
+}
+
+// ErrAdminScope is returned when an admin limited to some customers changes
+// an entry that serves another customer
+var ErrAdminScope = errors.New("outside the customers of this admin")
+
+// checkAdminCustomers refuses a change to an entry serving the given
+// customers, or every customer, unless the admin who made the request may
+// provision for all of them
+func checkAdminCustomers(ctx context.Context, what string, customers []string, everyCustomer bool) error {
+	scope, _ := ctx.Value(adminCustomersKey{}).([]string)
+	if len(scope) == 0 {
+		return nil
+	}
+	if everyCustomer {
+		return fmt.Errorf("%s serves every customer: %w", what, ErrAdminScope)
+	}
+	for _, customer := range customers {
+		if !slices.Contains(scope, customer) {
+			return fmt.Errorf("%s serves customer %q: %w", what, customer, ErrAdminScope)
+		}
+	}
+	return nil
+}
+
+// scopeStatus is the HTTP status of a failed change: 403 when it was outside
+// the admin's customers, otherwise status
+func scopeStatus(err error, status int) int {
+	if errors.Is(err, ErrAdminScope) {
+		return http.StatusForbidden
+	}
+	return status
+}
+
+// adminTokenIdentity returns the identity a token belongs to, or "" if it matches none
+func adminTokenIdentity(cfg *AdminConfig, token string) string {
+	identity := ""
+	for _, user := range cfg.Users {
+		if user.Token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(user.Token)) == 1 {
+			identity = user.Name
+		}
+	}
+	if cfg.Token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(cfg.Token)) == 1 {
+		identity = adminSharedIdentity
+	}
+	return identity
+}
+
+// writeJSON writes v as a JSON response with the given status code
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		fmt.Printf("⚠️  Failed to write admin API response: %v\n", err)
+	}
+}
+
+// writeJSONError writes an {"error": ...} response
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
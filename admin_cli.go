@@ -0,0 +1,165 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"fdo-manufacturing-station/api/client"
+)
+
+// adminClientFlags adds -url and -token to a subcommand that calls the admin
+// API of a running station. By default it calls the admin listener of the
+// loaded config with admin.token.
+func adminClientFlags(fs *flag.FlagSet) func() (*client.Client, error) {
+	baseURL := fs.String("url", "", "Admin API of the station (default: the admin listener in the config)")
+	token := fs.String("token", "", "Admin token (default: admin.token)")
+	return func() (*client.Client, error) {
+		if !config.Admin.Enabled && *baseURL == "" {
+			return nil, fmt.Errorf("admin.enabled is off in the config; give the station with -url")
+		}
+		addr, useTLS, certFile := config.Server.Addr, config.Server.UseTLS, config.Server.CertFile
+		if config.Server.Admin.Addr != "" {
+			addr, useTLS, certFile = config.Server.Admin.Addr, config.Server.Admin.UseTLS, config.Server.Admin.CertFile
+		}
+		c := client.New(*baseURL, *token)
+		if *token == "" {
+			c.Token = config.Admin.Token
+		}
+		if *baseURL != "" {
+			return c, nil
+		}
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, fmt.Errorf("cannot derive the admin URL from %q: %w", addr, err)
+		}
+		if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+			host = "localhost"
+		}
+		scheme := "http"
+		if useTLS {
+			// Trust the station's own certificate, which is often self-signed
+			scheme = "https"
+			pool, err := x509.SystemCertPool()
+			if err != nil {
+				pool = x509.NewCertPool()
+			}
+			if pem, err := os.ReadFile(certFile); err == nil {
+				pool.AppendCertsFromPEM(pem)
+			}
+			transport := http.DefaultTransport.(*http.Transport).Clone()
+			transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+			c.HTTPClient = &http.Client{Transport: transport}
+		}
+		c.BaseURL = scheme + "://" + net.JoinHostPort(host, port)
+		return c, nil
+	}
+}
+
+// runHealth implements "health [-strict]": it prints the health of a running
+// station and fails when new devices are refused, or with -strict when the
+// station is degraded
+func runHealth(args []string) error {
+	fs := flag.NewFlagSet("health", flag.ContinueOnError)
+	adminClient := adminClientFlags(fs)
+	strict := fs.Bool("strict", false, "Fail when the station is degraded, not only when it refuses devices")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	c, err := adminClient()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	report, err := c.GetHealth(ctx)
+	if err != nil {
+		return err
+	}
+	for _, check := range report.Checks {
+		icon := "✅"
+		if check.Status != "ok" {
+			icon = "⚠️ "
+		}
+		if check.Status == "refusing" {
+			icon = "❌"
+		}
+		if check.Detail != "" {
+			fmt.Printf("%s %-13s %s: %s\n", icon, check.Name, check.Status, check.Detail)
+		} else {
+			fmt.Printf("%s %-13s %s\n", icon, check.Name, check.Status)
+		}
+	}
+	if report.Status == "refusing" || (*strict && report.Status != "ok") {
+		return fmt.Errorf("station is %s", report.Status)
+	}
+	return nil
+}
+
+// runDIDCache implements "did-cache show|evict <did>" and "did-cache purge
+// [-all]" against the DID cache of a running station. Unlike
+// -purge-did-cache-expired and -purge-did-cache-all it doesn't open the
+// database behind the station's back.
+func runDIDCache(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: did-cache show|evict <did> | did-cache purge [-all]")
+	}
+	fs := flag.NewFlagSet("did-cache "+args[0], flag.ContinueOnError)
+	adminClient := adminClientFlags(fs)
+	all := false
+	if args[0] == "purge" {
+		fs.BoolVar(&all, "all", false, "Purge every entry, not just those unused for did_cache.purge_unused")
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	c, err := adminClient()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	switch {
+	case args[0] == "show" && fs.NArg() == 1:
+		entry, err := c.GetDIDCacheEntry(ctx, fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		fmt.Printf("DID:        %s\n", entry.DID)
+		fmt.Printf("Key:        %s\n", entry.KeySHA256)
+		if entry.DIDURL != "" {
+			fmt.Printf("Voucher to: %s\n", entry.DIDURL)
+		}
+		fmt.Printf("Cached:     %s\n", entry.CachedAt.Format(time.RFC3339))
+		fmt.Printf("Last used:  %s\n", entry.LastUsed.Format(time.RFC3339))
+		if entry.LastRefreshError != "" {
+			fmt.Printf("⚠️  Last refresh failed: %s\n", entry.LastRefreshError)
+		}
+		return nil
+	case args[0] == "evict" && fs.NArg() == 1:
+		if err := c.EvictDIDCacheEntry(ctx, fs.Arg(0)); err != nil {
+			return err
+		}
+		fmt.Printf("🗑️  Evicted %s; the next device resolves it again\n", fs.Arg(0))
+		return nil
+	case args[0] == "purge" && fs.NArg() == 0:
+		n, err := c.PurgeDIDCache(ctx, all)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("✅ Purged %d DID cache entries\n", n)
+		return nil
+	}
+	return fmt.Errorf("usage: did-cache show|evict <did> | did-cache purge [-all]")
+}
@@ -0,0 +1,168 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultCORSMaxAge is how long browsers cache a preflight when no max_age is configured
+const defaultCORSMaxAge = 10 * time.Minute
+
+// strictSecurityHeaders are sent on every admin API response unless
+// admin.http.security_headers overrides them. The API serves JSON only, so
+// nothing in it may be framed, run or sniffed.
+var strictSecurityHeaders = map[string]string{
+	"X-Content-Type-Options":  "nosniff",
+	"X-Frame-Options":         "DENY",
+	"Content-Security-Policy": "default-src 'none'; frame-ancestors 'none'",
+	"Referrer-Policy":         "no-referrer",
+}
+
+// adminHTTP guards the admin API (/api/...) for access from outside the line:
+// it refuses source addresses outside the API group's allowlist, adds the
+// security headers and answers CORS for the configured dashboard origins.
+// Other paths, the FDO protocol among them, pass through untouched. The
+// config is read on every request, so reloads apply at once.
+func adminHTTP(cfg *AdminHTTPConfig, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rest, ok := strings.CutPrefix(r.URL.Path, "/api/")
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		group, _, _ := strings.Cut(rest, "/")
+
+		if !cfg.allows(group, r.RemoteAddr) {
+			writeJSONError(w, http.StatusForbidden, "source address not allowed")
+			return
+		}
+
+		headers := w.Header()
+		for name, value := range strictSecurityHeaders {
+			headers.Set(name, value)
+		}
+		if r.TLS != nil {
+			headers.Set("Strict-Transport-Security", "max-age=31536000")
+		}
+		for name, value := range cfg.SecurityHeaders {
+			if value == "" {
+				headers.Del(name) // Drops a default
+			} else {
+				headers.Set(name, value)
+			}
+		}
+
+		origin := r.Header.Get("Origin")
+		if origin == "" || len(cfg.CORS.AllowedOrigins) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		headers.Add("Vary", "Origin")
+		allowed := slices.Contains(cfg.CORS.AllowedOrigins, origin) || slices.Contains(cfg.CORS.AllowedOrigins, "*")
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+		if !allowed {
+			if preflight {
+				writeJSONError(w, http.StatusForbidden, "origin not allowed")
+				return
+			}
+			next.ServeHTTP(w, r) // The browser withholds the response from the page
+			return
+		}
+		headers.Set("Access-Control-Allow-Origin", origin)
+		if preflight {
+			maxAge := cfg.CORS.MaxAge
+			if maxAge <= 0 {
+				maxAge = defaultCORSMaxAge
+			}
+			headers.Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE")
+			headers.Set("Access-Control-Allow-Headers", "Authorization, Content-Type, If-None-Match")
+			headers.Set("Access-Control-Max-Age", strconv.Itoa(int(maxAge.Seconds())))
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		headers.Set("Access-Control-Expose-Headers", "ETag, Link, X-Next-Cursor")
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allows reports whether a request from remoteAddr may reach an API group:
+// the group's own allowlist if it has one, else allow_cidrs. An empty list
+// allows any address.
+func (cfg *AdminHTTPConfig) allows(group, remoteAddr string) bool {
+	entries, ok := cfg.GroupCIDRs[group]
+	if !ok {
+		entries = cfg.AllowCIDRs
+	}
+	if len(entries) == 0 {
+		return true
+	}
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, entry := range entries {
+		prefix, err := parseAllowEntry(entry)
+		if err == nil && prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseAllowEntry parses an allowlist entry: a CIDR, or a bare address for one host
+func parseAllowEntry(entry string) (netip.Prefix, error) {
+	prefix, err := netip.ParsePrefix(entry)
+	if err != nil {
+		addr, addrErr := netip.ParseAddr(entry)
+		if addrErr != nil {
+			return netip.Prefix{}, err
+		}
+		prefix = netip.PrefixFrom(addr, addr.BitLen())
+	}
+	return prefix.Masked(), nil
+}
+
+// validateAdminHTTP checks the allowlists and CORS origins of admin.http
+func validateAdminHTTP(config *AdminConfig) error {
+	for _, entry := range config.HTTP.AllowCIDRs {
+		if _, err := parseAllowEntry(entry); err != nil {
+			return fmt.Errorf("admin.http.allow_cidrs: invalid entry %q: %w", entry, err)
+		}
+	}
+	for group, entries := range config.HTTP.GroupCIDRs {
+		for _, entry := range entries {
+			if _, err := parseAllowEntry(entry); err != nil {
+				return fmt.Errorf("admin.http.group_cidrs.%s: invalid entry %q: %w", group, entry, err)
+			}
+		}
+	}
+	for _, origin := range config.HTTP.CORS.AllowedOrigins {
+		if origin == "*" {
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || (u.Path != "" && u.Path != "/") || u.RawQuery != "" {
+			return fmt.Errorf("admin.http.cors.allowed_origins: %q is not an origin like https://dashboard.example.com", origin)
+		}
+		if strings.HasSuffix(origin, "/") {
+			return fmt.Errorf("admin.http.cors.allowed_origins: %q must not end with /", origin)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,226 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Every admin API list endpoint takes the same query parameters:
+//
+//	limit=<n>         page size (default 100, max 1000)
+//	sort=<field>      sort field, "-<field>" for descending
+//	cursor=<cursor>   continue after the page that returned it
+//	<filter>=<value>  exact-match filters named by the endpoint
+//
+// The body is a JSON array. When there are more rows the response carries the
+// cursor of the next page in X-Next-Cursor and a Link rel="next" header. Every
+// list response has an ETag; a matching If-None-Match gets 304 Not Modified.
+const (
+	defaultListLimit = 100
+	maxListLimit     = 1000
+)
+
+// listSpec maps a list endpoint's sort and filter parameters to SQL columns
+type listSpec struct {
+	Key         string            // Unique column that breaks sort ties and anchors cursors
+	Sorts       map[string]string // Sort fields to columns; sort columns must be NOT NULL
+	DefaultSort string            // Sort when the request names none
+	Filters     map[string]string // Filter parameters to columns
+}
+
+// listQuery is a parsed list request
+type listQuery struct {
+	spec    *listSpec
+	limit   int
+	sort    string // As requested, e.g. "-time"
+	column  string
+	desc    bool
+	columns []string // Filter columns, matching args
+	args    []any
+	after   []any // Sort and key values of the last row of the previous page
+}
+
+// listCursor is the decoded form of a cursor
+type listCursor struct {
+	Sort  string `json:"s"`
+	After []any  `json:"a"`
+}
+
+// parseListQuery reads the pagination, sort and filter parameters of a list request
+func parseListQuery(r *http.Request, spec *listSpec) (*listQuery, error) {
+	return parseListParams(r.URL.Query(), spec)
+}
+
+// parseListParams reads list parameters from query parameters or GraphQL arguments
+func parseListParams(params url.Values, spec *listSpec) (*listQuery, error) {
+	q := &listQuery{spec: spec, limit: defaultListLimit, sort: spec.DefaultSort}
+
+	if s := params.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("limit must be a positive integer")
+		}
+		q.limit = min(n, maxListLimit)
+	}
+
+	if s := params.Get("sort"); s != "" {
+		q.sort = s
+	}
+	field, desc := strings.CutPrefix(q.sort, "-")
+	column, ok := spec.Sorts[field]
+	if !ok {
+		fields := make([]string, 0, len(spec.Sorts))
+		for name := range spec.Sorts {
+			fields = append(fields, name)
+		}
+		slices.Sort(fields)
+		return nil, fmt.Errorf("unsupported sort %q (want one of %v, prefixed with - for descending)", field, fields)
+	}
+	q.column, q.desc = column, desc
+
+	filters := make([]string, 0, len(spec.Filters))
+	for name := range spec.Filters {
+		filters = append(filters, name)
+	}
+	slices.Sort(filters)
+	for _, name := range filters {
+		if params.Has(name) {
+			q.filter(spec.Filters[name], params.Get(name))
+		}
+	}
+
+	if s := params.Get("cursor"); s != "" {
+		after, err := decodeListCursor(s, q.sort)
+		if err != nil {
+			return nil, err
+		}
+		q.after = after
+	}
+	return q, nil
+}
+
+// filter adds an exact-match condition, for path parameters and fixed filters
+func (q *listQuery) filter(column string, value any) {
+	q.columns = append(q.columns, column)
+	q.args = append(q.args, value)
+}
+
+// sql returns the WHERE, ORDER BY and LIMIT clauses of the page and their
+// arguments. One extra row is fetched to tell whether there is a next page.
+func (q *listQuery) sql() (string, []any) {
+	var conds []string
+	args := slices.Clone(q.args)
+	for _, column := range q.columns {
+		conds = append(conds, column+" = ?")
+	}
+	op, dir := ">", "ASC"
+	if q.desc {
+		op, dir = "<", "DESC"
+	}
+	if q.after != nil {
+		conds = append(conds, fmt.Sprintf("(%s, %s) %s (?, ?)", q.column, q.spec.Key, op))
+		args = append(args, q.after...)
+	}
+
+	var clause string
+	if len(conds) > 0 {
+		clause = " WHERE " + strings.Join(conds, " AND ")
+	}
+	clause += fmt.Sprintf(" ORDER BY %s %s, %s %s LIMIT %d", q.column, dir, q.spec.Key, dir, q.limit+1)
+	return clause, args
+}
+
+// listPage trims the extra row fetched by listQuery.sql and returns the cursor
+// of the next page, if there is one. position returns an item's value of a column.
+func listPage[T any](q *listQuery, items []T, position func(item T, column string) any) ([]T, string) {
+	if len(items) <= q.limit {
+		return items, ""
+	}
+	items = items[:q.limit]
+	last := items[len(items)-1]
+	data, err := json.Marshal(listCursor{Sort: q.sort, After: []any{position(last, q.column), position(last, q.spec.Key)}})
+	if err != nil {
+		return items, ""
+	}
+	return items, base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeListCursor returns the position a cursor continues from
+func decodeListCursor(s, sort string) ([]any, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var cursor listCursor
+	if err := dec.Decode(&cursor); err != nil || len(cursor.After) != 2 {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	if cursor.Sort != sort {
+		return nil, fmt.Errorf("cursor was issued for sort %q, not %q", cursor.Sort, sort)
+	}
+	// Numbers go back to SQLite as integers so they compare like the column values
+	for i, v := range cursor.After {
+		if n, ok := v.(json.Number); ok {
+			if i64, err := n.Int64(); err == nil {
+				cursor.After[i] = i64
+			} else {
+				cursor.After[i] = n.String()
+			}
+		}
+	}
+	return cursor.After, nil
+}
+
+// writeJSONList writes one page of a list endpoint with its ETag and next-page cursor
+func writeJSONList(w http.ResponseWriter, r *http.Request, items any, next string) {
+	body, err := json.Marshal(items)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to encode response: %v", err))
+		return
+	}
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	w.Header().Set("ETag", etag)
+	if next != "" {
+		nextURL := *r.URL
+		params := nextURL.Query()
+		params.Set("cursor", next)
+		nextURL.RawQuery = params.Encode()
+		w.Header().Set("X-Next-Cursor", next)
+		w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, nextURL.RequestURI()))
+	}
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(append(body, '\n'))
+}
+
+// etagMatches reports whether an If-None-Match header matches etag
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,330 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Andon states sent to the line signal tower
+const (
+	AndonStateClear = "clear"
+	AndonStateAlarm = "alarm"
+)
+
+// AndonEvent is sent to every action when the andon state changes
+type AndonEvent struct {
+	Station     string    `json:"station"`
+	State       string    `json:"state"` // "alarm" | "clear"
+	Reason      string    `json:"reason,omitempty"`
+	Message     string    `json:"message"`      // State and reason for the line, in the station locale
+	FailureRate float64   `json:"failure_rate"` // Percent of DI sessions failed within the window
+	Sessions    int       `json:"sessions"`     // DI sessions within the window
+	QueueDepth  int       `json:"queue_depth"`  // Vouchers waiting for batch upload
+	Time        time.Time `json:"time"`
+}
+
+// Andon drives the line's signal tower (light tower, horn, PLC) when DI
+// provisioning is failing: the failure rate over a window crosses a threshold,
+// or vouchers back up in the upload queue. Actions run on every state change
+// and when the station stops. A failed action is retried at the next check.
+type Andon struct {
+	config    *AndonConfig
+	status    *StationStatus
+	stationID string
+	client    *http.Client
+
+	signaled string // State every action last accepted; "" until the first check
+}
+
+// NewAndon creates the andon integration, or returns nil if it is disabled
+func NewAndon(config *AndonConfig, status *StationStatus, stationID string) (*Andon, error) {
+	if !config.Enabled {
+		return nil, nil
+	}
+	if config.FailureRate <= 0 && config.QueueDepth <= 0 {
+		return nil, fmt.Errorf("andon enabled but neither failure_rate nor queue_depth is set")
+	}
+	if config.FailureRate > 100 {
+		return nil, fmt.Errorf("andon: failure_rate must be a percentage between 0 and 100")
+	}
+	if config.Window > time.Hour {
+		return nil, fmt.Errorf("andon: window must be at most 1h")
+	}
+	if len(config.Actions) == 0 {
+		return nil, fmt.Errorf("andon enabled but no actions configured")
+	}
+	for i, action := range config.Actions {
+		switch {
+		case action.Type == "http" && action.URL != "":
+		case action.Type == "command" && action.Command != "":
+		case action.Type == "mqtt" && action.Broker != "" && action.Topic != "":
+			if _, _, err := net.SplitHostPort(action.Broker); err != nil {
+				return nil, fmt.Errorf("andon action %d: broker must be host:port: %w", i, err)
+			}
+		default:
+			return nil, fmt.Errorf("andon action %d: want type http with url, command with command, or mqtt with broker and topic", i)
+		}
+	}
+	return &Andon{config: config, status: status, stationID: stationID, client: &http.Client{}}, nil
+}
+
+// Run checks the thresholds every interval until ctx is cancelled, then signals
+// the tower that the station has stopped
+func (a *Andon) Run(ctx context.Context) {
+	if a == nil {
+		return
+	}
+
+	ticker := time.NewTicker(a.interval())
+	defer ticker.Stop()
+
+	a.check(ctx)
+	for {
+		select {
+		case <-ticker.C:
+			a.check(ctx)
+		case <-ctx.Done():
+			stopCtx, cancel := context.WithTimeout(context.Background(), a.timeout(AndonAction{}))
+			defer cancel()
+			event := a.event(stopCtx)
+			event.State, event.Reason = AndonStateAlarm, "station stopped"
+			event.Message = stationMessages.StationText("andon.stopped")
+			a.signal(stopCtx, event)
+			return
+		}
+	}
+}
+
+// check evaluates the thresholds and signals the tower if the state changed
+func (a *Andon) check(ctx context.Context) {
+	event := a.event(ctx)
+	if event.State == a.signaled {
+		return
+	}
+	if a.signal(ctx, event) {
+		a.signaled = event.State
+	}
+}
+
+// event evaluates the thresholds against the current station status
+func (a *Andon) event(ctx context.Context) AndonEvent {
+	rate, sessions := a.status.FailureRate(a.window())
+	event := AndonEvent{
+		Station:     a.stationID,
+		State:       AndonStateClear,
+		FailureRate: rate,
+		Sessions:    sessions,
+		QueueDepth:  a.status.Snapshot(ctx).QueueDepth,
+		Time:        time.Now().UTC(),
+		Message:     stationMessages.StationText("andon.clear"),
+	}
+	switch {
+	case a.config.FailureRate > 0 && sessions >= a.minSessions() && rate >= a.config.FailureRate:
+		event.State = AndonStateAlarm
+		event.Reason = fmt.Sprintf("%.0f%% of %d DI sessions failed in the last %s", rate, sessions, a.window())
+		event.Message = stationMessages.StationText("andon.failure_rate",
+			"rate", fmt.Sprintf("%.0f", rate), "sessions", strconv.Itoa(sessions), "window", a.window().String())
+	case a.config.QueueDepth > 0 && event.QueueDepth >= a.config.QueueDepth:
+		event.State = AndonStateAlarm
+		event.Reason = fmt.Sprintf("%d vouchers waiting for upload", event.QueueDepth)
+		event.Message = stationMessages.StationText("andon.queue_depth", "count", strconv.Itoa(event.QueueDepth))
+	}
+	return event
+}
+
+// signal runs every action and reports whether all of them succeeded
+func (a *Andon) signal(ctx context.Context, event AndonEvent) bool {
+	if event.State == AndonStateAlarm {
+		fmt.Printf("🚨 Andon alarm: %s\n", event.Reason)
+	} else {
+		fmt.Printf("✅ Andon clear\n")
+	}
+
+	ok := true
+	for i, action := range a.config.Actions {
+		actionCtx, cancel := context.WithTimeout(ctx, a.timeout(action))
+		var err error
+		switch action.Type {
+		case "http":
+			err = a.post(actionCtx, action, event)
+		case "command":
+			err = a.run(actionCtx, action, event)
+		case "mqtt":
+			err = a.publish(actionCtx, action, event)
+		}
+		cancel()
+		if err != nil {
+			fmt.Printf("⚠️  Andon action %d (%s) failed: %v\n", i, action.Type, err)
+			ok = false
+		}
+	}
+	return ok
+}
+
+// post sends the event as JSON to an HTTP endpoint
+func (a *Andon) post(ctx context.Context, action AndonAction, event AndonEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, action.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range action.Headers {
+		req.Header.Set(name, value)
+	}
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s returned HTTP %d", action.URL, resp.StatusCode)
+	}
+	return nil
+}
+
+// run calls an external command, e.g. a GPIO script wired to the tower
+func (a *Andon) run(ctx context.Context, action AndonAction, event AndonEvent) error {
+	executor := NewExternalCommandExecutor(CommandAndon, action.Command, a.timeout(action))
+	_, err := executor.Execute(ctx, map[string]string{
+		"state":        event.State,
+		"reason":       event.Reason,
+		"message":      event.Message,
+		"failure_rate": strconv.FormatFloat(event.FailureRate, 'f', 1, 64),
+		"queue_depth":  strconv.Itoa(event.QueueDepth),
+		"station":      event.Station,
+	})
+	return err
+}
+
+// publish sends the event as a retained MQTT 3.1.1 message at QoS 0, so a
+// tower controller that subscribes later still sees the current state
+func (a *Andon) publish(ctx context.Context, action AndonAction, event AndonEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	var conn net.Conn
+	dialer := &net.Dialer{}
+	if action.TLS {
+		conn, err = (&tls.Dialer{NetDialer: dialer}).DialContext(ctx, "tcp", action.Broker)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", action.Broker)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to MQTT broker %s: %w", action.Broker, err)
+	}
+	defer func() { _ = conn.Close() }()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	clientID := action.ClientID
+	if clientID == "" {
+		clientID = "fdo-station-" + a.stationID
+	}
+	flags := byte(0x02) // Clean session
+	connect := mqttString(nil, "MQTT")
+	connect = append(connect, 4, 0, 0, 30) // Protocol level 3.1.1, flags, 30s keep-alive
+	connect = mqttString(connect, clientID)
+	if action.Username != "" {
+		flags |= 0x80
+		connect = mqttString(connect, action.Username)
+	}
+	if action.Password != "" {
+		flags |= 0x40
+		connect = mqttString(connect, action.Password)
+	}
+	connect[7] = flags
+	if _, err := conn.Write(mqttPacket(0x10, connect)); err != nil {
+		return fmt.Errorf("failed to send MQTT CONNECT: %w", err)
+	}
+
+	connack := make([]byte, 4)
+	if _, err := io.ReadFull(conn, connack); err != nil {
+		return fmt.Errorf("failed to read MQTT CONNACK: %w", err)
+	}
+	if connack[0] != 0x20 || connack[1] != 2 {
+		return fmt.Errorf("unexpected MQTT response to CONNECT")
+	}
+	if connack[3] != 0 {
+		return fmt.Errorf("MQTT broker refused connection (return code %d)", connack[3])
+	}
+
+	publish := append(mqttString(nil, action.Topic), payload...)
+	if _, err := conn.Write(mqttPacket(0x31, publish)); err != nil { // PUBLISH, QoS 0, retained
+		return fmt.Errorf("failed to publish to %s: %w", action.Topic, err)
+	}
+	_, _ = conn.Write([]byte{0xe0, 0}) // DISCONNECT
+	return nil
+}
+
+// mqttPacket frames an MQTT control packet with its remaining length
+func mqttPacket(packetType byte, body []byte) []byte {
+	packet := []byte{packetType}
+	n := len(body)
+	for {
+		b := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			b |= 0x80
+		}
+		packet = append(packet, b)
+		if n == 0 {
+			break
+		}
+	}
+	return append(packet, body...)
+}
+
+// mqttString appends a length-prefixed UTF-8 string
+func mqttString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+func (a *Andon) interval() time.Duration {
+	if a.config.Interval > 0 {
+		return a.config.Interval
+	}
+	return 15 * time.Second
+}
+
+func (a *Andon) window() time.Duration {
+	if a.config.Window > 0 {
+		return a.config.Window
+	}
+	return 10 * time.Minute
+}
+
+func (a *Andon) minSessions() int {
+	if a.config.MinSessions > 0 {
+		return a.config.MinSessions
+	}
+	return 5
+}
+
+func (a *Andon) timeout(action AndonAction) time.Duration {
+	if action.Timeout > 0 {
+		return action.Timeout
+	}
+	return 10 * time.Second
+}
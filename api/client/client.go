@@ -0,0 +1,1455 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+// Package client is a Go client for the manufacturing station admin API. It
+// follows the OpenAPI document the station serves at /api/openapi.json (the
+// openapi.json file at the repository root): one method per operationId, and
+// one type per schema.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Client calls one station's admin API
+type Client struct {
+	BaseURL    string       // e.g. "http://station-01:8080"
+	Token      string       // admin.token or an admin.users token; empty if the API is open
+	HTTPClient *http.Client // nil = http.DefaultClient
+}
+
+// New creates a client for the station at baseURL
+func New(baseURL, token string) *Client {
+	return &Client{BaseURL: strings.TrimSuffix(baseURL, "/"), Token: token}
+}
+
+// Error is an error response of the admin API
+type Error struct {
+	StatusCode int    `json:"-"`
+	Message    string `json:"error"`
+	Code       string `json:"code,omitempty"`    // Message key of an operator-facing error
+	Localized  string `json:"message,omitempty"` // Operator-facing error in the requested locale
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("station returned HTTP %d: %s", e.StatusCode, e.Message)
+}
+
+// ListOptions are the parameters every list operation takes
+type ListOptions struct {
+	Limit   int               // Page size (0 = station default of 100)
+	Sort    string            // Sort field, "-<field>" for descending
+	Cursor  string            // NextCursor of the previous page
+	Filters map[string]string // Exact-match filters named by the operation
+	ETag    string            // ETag of a previous page; an unchanged page returns ErrNotModified
+}
+
+// Page is one page of a list operation
+type Page[T any] struct {
+	Items      []T
+	NextCursor string // Empty on the last page
+	ETag       string
+}
+
+// ErrNotModified is returned by list operations when ListOptions.ETag still matches
+var ErrNotModified = errors.New("not modified")
+
+// BuildInfo is the response of getVersion
+type BuildInfo struct {
+	Version    string `json:"version"`
+	GitCommit  string `json:"git_commit"`
+	BuildDate  string `json:"build_date"`
+	GoVersion  string `json:"go_version"`
+	InstanceID string `json:"instance_id,omitempty"`
+	SiteCode   string `json:"site_code,omitempty"`
+	LineID     string `json:"line_id,omitempty"`
+	StationID  string `json:"station_id,omitempty"`
+	Features   struct {
+		HSM              bool     `json:"hsm"`
+		KMS              bool     `json:"kms"`
+		DIDMethods       []string `json:"did_methods"`
+		UploadModes      []string `json:"upload_modes"`
+		ProtocolVersions []string `json:"protocol_versions"`
+		ExtensionAPI     int      `json:"extension_api"`
+		Extensions       []string `json:"extensions"`
+	} `json:"features"`
+	Entitlements struct {
+		Status   string     `json:"status"` // "unrestricted" | "valid" | "expired" | "missing" | "invalid"
+		Licensee string     `json:"licensee,omitempty"`
+		SiteCode string     `json:"site_code,omitempty"`
+		Features []string   `json:"features"`
+		NotAfter *time.Time `json:"not_after,omitempty"`
+		Problem  string     `json:"problem,omitempty"`
+	} `json:"entitlements"`
+}
+
+// StartupReport is the response of getStartupReport
+type StartupReport struct {
+	StartedAt time.Time `json:"started_at"`
+	Build     BuildInfo `json:"build"`
+	Signover  struct {
+		Mode        string `json:"mode"`
+		Target      string `json:"target,omitempty"`
+		Customer    string `json:"customer,omitempty"`
+		KeyEncoding string `json:"key_encoding,omitempty"`
+		Policies    int    `json:"policies"`
+	} `json:"signover"`
+	Signing struct {
+		Mode         string `json:"mode"`
+		OwnerKeyType string `json:"owner_key_type,omitempty"`
+		Command      string `json:"command,omitempty"`
+		Fallback     bool   `json:"fallback"`
+	} `json:"signing"`
+	Upload struct {
+		Enabled               bool     `json:"enabled"`
+		Mode                  string   `json:"mode,omitempty"`
+		Command               string   `json:"command,omitempty"`
+		URL                   string   `json:"url,omitempty"`
+		AuthProfile           string   `json:"auth_profile,omitempty"`
+		Batch                 bool     `json:"batch"`
+		RequireSignedReceipts bool     `json:"require_signed_receipts"`
+		Destinations          []string `json:"destinations"`
+	} `json:"upload"`
+	SaveToDisk []string `json:"save_to_disk"`
+	DIDCache   struct {
+		Enabled           bool     `json:"enabled"`
+		RefreshInterval   string   `json:"refresh_interval"`
+		MaxAge            string   `json:"max_age"`
+		AllowedDomains    []string `json:"allowed_domains"`
+		DeniedDomains     []string `json:"denied_domains"`
+		AllowPrivateCIDRs []string `json:"allow_private_cidrs"`
+	} `json:"did_cache"`
+	Database struct {
+		Backend     string `json:"backend"`
+		Path        string `json:"path"`
+		Encrypted   bool   `json:"encrypted"`
+		StationPath string `json:"station_path"`
+	} `json:"database"`
+	Listeners []struct {
+		Name    string `json:"name"`
+		Addr    string `json:"addr"`
+		TLS     bool   `json:"tls"`
+		MTLS    bool   `json:"mtls"`
+		Enabled bool   `json:"enabled"`
+	} `json:"listeners"`
+	DryRun   bool     `json:"dry_run"`
+	Warnings []string `json:"warnings"`
+}
+
+// AuditEvent is one entry in the station audit log
+type AuditEvent struct {
+	ID       int64     `json:"id"`
+	Time     time.Time `json:"time"`
+	Event    string    `json:"event"`
+	Serial   string    `json:"serial,omitempty"`
+	GUID     string    `json:"guid,omitempty"`
+	Customer string    `json:"customer,omitempty"`
+	Model    string    `json:"model,omitempty"`
+	Detail   string    `json:"detail,omitempty"`
+	Site     string    `json:"site_code,omitempty"`
+	Line     string    `json:"line_id,omitempty"`
+	Station  string    `json:"station_id,omitempty"`
+
+	ChainHash string `json:"chain_hash,omitempty"`
+}
+
+// AuditAnchorRecord is one audit checkpoint delivered, or not, to one sink
+type AuditAnchorRecord struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	EventID   int64     `json:"event_id"`
+	ChainHash string    `json:"chain_hash"`
+	Sink      string    `json:"sink"`
+	Reference string    `json:"reference,omitempty"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// AuditVerification is the response of verifyAuditLog
+type AuditVerification struct {
+	CheckedAt time.Time `json:"checked_at"`
+	Events    int       `json:"events"`
+	Chained   int       `json:"chained"`
+	HeadID    int64     `json:"head_id"`
+	HeadHash  string    `json:"head_hash"`
+	Intact    bool      `json:"intact"`
+	BrokenAt  int64     `json:"broken_at,omitempty"`
+	Anchors   []struct {
+		AuditAnchorRecord
+		Matches bool `json:"matches"`
+	} `json:"anchors"`
+}
+
+// Batch is a production run of one lot
+type Batch struct {
+	ID           string     `json:"id"`
+	LotNumber    string     `json:"lot_number"`
+	Profile      string     `json:"profile,omitempty"`
+	OperatorID   string     `json:"operator_id,omitempty"`
+	Status       string     `json:"status"` // "open" | "closed" | "expired"
+	OpenedAt     time.Time  `json:"opened_at"`
+	ClosedAt     *time.Time `json:"closed_at,omitempty"`
+	VoucherCount int        `json:"voucher_count"`
+}
+
+// GUIDReservation is one entry of listGUIDReservations
+type GUIDReservation struct {
+	GUID      string     `json:"guid"`
+	Serial    string     `json:"serial,omitempty"`
+	Model     string     `json:"model,omitempty"`
+	BatchID   string     `json:"batch_id,omitempty"`
+	Status    string     `json:"status"` // "reserved", "assigned", "used" or "released"
+	CreatedAt time.Time  `json:"created_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+}
+
+// ReserveGUIDsRequest is the body of reserveGUIDs
+type ReserveGUIDsRequest struct {
+	Count   int      `json:"count,omitempty"`
+	Serials []string `json:"serials,omitempty"`
+	Model   string   `json:"model,omitempty"`
+	BatchID string   `json:"batch_id,omitempty"`
+}
+
+// OverrideClaims is what an override token grants
+type OverrideClaims struct {
+	ID        string    `json:"id"`
+	Serials   []string  `json:"serials"`
+	Checks    []string  `json:"checks"` // "quota", "schedule", "signover_policy" or "guid_reservation"
+	Reason    string    `json:"reason"`
+	IssuedBy  string    `json:"issued_by"`
+	IssuedAt  time.Time `json:"issued_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// IssueOverrideRequest is the body of issueOverride
+type IssueOverrideRequest struct {
+	Serials []string `json:"serials"`
+	Checks  []string `json:"checks"`
+	Reason  string   `json:"reason"`
+	TTL     string   `json:"ttl,omitempty"` // Go duration, e.g. "2h"
+}
+
+// IssuedOverride is a new override token
+type IssuedOverride struct {
+	Token string `json:"token"`
+	OverrideClaims
+}
+
+// ApplyOverrideRequest is the body of applyOverride
+type ApplyOverrideRequest struct {
+	Token      string `json:"token"`
+	OperatorID string `json:"operator_id,omitempty"`
+	OTP        string `json:"otp,omitempty"`
+	Badge      string `json:"badge,omitempty"`
+}
+
+// PolicyOverride is an applied override token
+type PolicyOverride struct {
+	OverrideClaims
+	AppliedBy string     `json:"applied_by"`
+	AppliedAt time.Time  `json:"applied_at"`
+	Uses      int        `json:"uses"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// RegisterOwnerKeyRequest is the body of registerOwnerKey
+type RegisterOwnerKeyRequest struct {
+	Customer string `json:"customer,omitempty"`
+	Owner    string `json:"owner"` // DID, https:// owner key URL or PEM public key
+}
+
+// OwnerKeyProofRequest is the body of proveOwnerKey
+type OwnerKeyProofRequest struct {
+	Signature string `json:"signature"` // Base64 signature of the challenge
+}
+
+// OwnerKeyRegistration is an owner key registered for a customer, active once
+// its owner has signed the challenge
+type OwnerKeyRegistration struct {
+	ID           string     `json:"id"`
+	Customer     string     `json:"customer"`
+	Owner        string     `json:"owner"`
+	KeySHA256    string     `json:"key_sha256"`
+	KeyType      string     `json:"key_type"`
+	Status       string     `json:"status"` // "pending" | "active"
+	Challenge    string     `json:"challenge"`
+	ExpiresAt    time.Time  `json:"expires_at"`
+	Attempts     int        `json:"attempts"`
+	RegisteredBy string     `json:"registered_by"`
+	RegisteredAt time.Time  `json:"registered_at"`
+	ProvenAt     *time.Time `json:"proven_at,omitempty"`
+}
+
+// VoucherChain is the ownership chain of a stored voucher
+type VoucherChain struct {
+	GUID       string      `json:"guid"`
+	Source     string      `json:"source"`
+	DeviceInfo string      `json:"device_info"`
+	Serial     string      `json:"serial,omitempty"`
+	Model      string      `json:"model,omitempty"`
+	Customer   string      `json:"customer,omitempty"`
+	CreatedAt  *time.Time  `json:"created_at,omitempty"`
+	Links      []ChainLink `json:"links"` // Manufacturer key first, then one per voucher entry
+	Upload     *struct {
+		RecipientURL string    `json:"recipient_url"`
+		Status       string    `json:"status"`
+		ReceiptID    string    `json:"receipt_id,omitempty"`
+		UploadedAt   time.Time `json:"uploaded_at"`
+	} `json:"upload,omitempty"`
+	Events []struct {
+		Time   time.Time `json:"time"`
+		Event  string    `json:"event"`
+		Detail string    `json:"detail,omitempty"`
+	} `json:"events"`
+}
+
+// ChainLink is one key of a voucher's ownership chain
+type ChainLink struct {
+	Index       int        `json:"index"` // 0 = manufacturer key, n = voucher entry n
+	Role        string     `json:"role"`  // "manufacturer" | "owner"
+	Current     bool       `json:"current"`
+	KeyType     string     `json:"key_type"`
+	KeyEncoding string     `json:"key_encoding"`
+	Key         string     `json:"key"`
+	KeySHA256   string     `json:"key_sha256,omitempty"`
+	DIDs        []string   `json:"dids,omitempty"`
+	Customers   []string   `json:"customers,omitempty"`
+	FirstSeen   *time.Time `json:"first_seen,omitempty"`
+	Error       string     `json:"error,omitempty"`
+}
+
+// VoucherComparison is the response of diffVoucher and checkVoucher
+type VoucherComparison struct {
+	GUID        string `json:"guid"`
+	Source      string `json:"source,omitempty"`
+	Match       bool   `json:"match"`
+	Differences []struct {
+		Field    string `json:"field"`
+		Got      string `json:"got"`
+		Expected string `json:"expected"`
+	} `json:"differences"`
+}
+
+// VoucherCheck names what checkVoucher compares; empty members are not checked
+type VoucherCheck struct {
+	RvInfo      bool     // The rendezvous info of the station's config
+	OwnerSHA256 string   // Fingerprint of the current owner key
+	OVEKeys     []string // OVEExtra keys of the last entry, numbers or names; non-nil and empty for none
+}
+
+// OpenBatchRequest is the body of openBatch
+type OpenBatchRequest struct {
+	LotNumber  string `json:"lot_number"`
+	Profile    string `json:"profile,omitempty"`
+	OperatorID string `json:"operator_id,omitempty"`
+	OTP        string `json:"otp,omitempty"`
+	Badge      string `json:"badge,omitempty"`
+}
+
+// BatchVoucher links a voucher to the batch it was built in
+type BatchVoucher struct {
+	GUID      string    `json:"guid"`
+	Serial    string    `json:"serial"`
+	Model     string    `json:"model"`
+	Customer  string    `json:"customer,omitempty"`
+	BatchID   string    `json:"batch_id"`
+	LotNumber string    `json:"lot_number"`
+	CreatedAt time.Time `json:"created_at"`
+	Hash      string    `json:"voucher_sha256,omitempty"` // SHA-256 of the final CBOR voucher
+
+	ReceiptVerification string `json:"receipt_verification,omitempty"` // "verified" | "invalid" | "unsigned"; empty = not uploaded
+}
+
+// LotReport summarizes every batch produced under one lot number
+type LotReport struct {
+	LotNumber    string    `json:"lot_number"`
+	Batches      []Batch   `json:"batches"`
+	VoucherCount int       `json:"voucher_count"`
+	Models       []string  `json:"models"`
+	FirstVoucher time.Time `json:"first_voucher"`
+	LastVoucher  time.Time `json:"last_voucher"`
+}
+
+// UploadReceipt records a recipient's acknowledgment of an uploaded voucher
+type UploadReceipt struct {
+	GUID         string    `json:"guid"`
+	Serial       string    `json:"serial"`
+	RecipientURL string    `json:"recipient_url"`
+	ReceiptID    string    `json:"receipt_id,omitempty"`
+	Status       string    `json:"status"` // "accepted" | "duplicate"
+	UploadedAt   time.Time `json:"uploaded_at"`
+	VoucherHash  string    `json:"voucher_sha256,omitempty"` // SHA-256 of the uploaded CBOR voucher
+
+	Signature      string `json:"receipt_signature,omitempty"` // Owner's base64 signature of the receipt statement
+	Verification   string `json:"verification,omitempty"`      // "verified" | "invalid" | "unsigned"
+	OwnerKeySHA256 string `json:"owner_key_sha256,omitempty"`  // Owner key the signature was checked with
+}
+
+// QuotaStatus is the state of one quota rule for its current period
+type QuotaStatus struct {
+	Name      string `json:"name"`
+	Customer  string `json:"customer,omitempty"`
+	Model     string `json:"model,omitempty"`
+	Period    string `json:"period"`
+	PeriodKey string `json:"period_key"`
+	Limit     int    `json:"limit"`
+	Extra     int    `json:"extra"`
+	Used      int    `json:"used"`
+	Remaining int    `json:"remaining"`
+	WarnAt    int    `json:"warn_at"`
+	Soft      bool   `json:"soft"`
+	Reason    string `json:"override_reason,omitempty"`
+}
+
+// QuotaOverrideRequest is the body of overrideQuota
+type QuotaOverrideRequest struct {
+	Extra  int    `json:"extra"`
+	Reason string `json:"reason"`
+}
+
+// UploadDestination is a known voucher recipient and its health
+type UploadDestination struct {
+	Name                string     `json:"name"`
+	URL                 string     `json:"url"`
+	AuthProfile         string     `json:"auth_profile"`
+	Owner               string     `json:"owner"`
+	Enabled             bool       `json:"enabled"`
+	LastSuccess         *time.Time `json:"last_success,omitempty"`
+	LastFailure         *time.Time `json:"last_failure,omitempty"`
+	LastError           string     `json:"last_error,omitempty"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	TotalFailures       int        `json:"total_failures"`
+	TotalSuccesses      int        `json:"total_successes"`
+	BreakerState        string     `json:"breaker_state"` // "closed" | "open" | "half_open"
+	BreakerOpenedAt     *time.Time `json:"breaker_opened_at,omitempty"`
+}
+
+// UploadDestinationRequest is the body of createDestination and putDestination
+type UploadDestinationRequest struct {
+	Name        string `json:"name"`
+	URL         string `json:"url"`
+	AuthProfile string `json:"auth_profile"`
+	Owner       string `json:"owner"`
+	Enabled     *bool  `json:"enabled,omitempty"` // Default true
+}
+
+// Provisioned is a config entry managed by name, the response of the
+// upload auth profile and disk destination operations
+type Provisioned[T any] struct {
+	Name            string   `json:"name"`
+	Spec            T        `json:"spec"` // Secrets read back as "***"
+	RestartRequired bool     `json:"restart_required,omitempty"`
+	UsedBy          []string `json:"used_by,omitempty"` // Upload destinations using the entry by name
+}
+
+// UploadAuthProfileSpec is an entry of voucher_management.upload_auth_profiles
+type UploadAuthProfileSpec struct {
+	Type             string `json:"type"` // "none", "bearer", "basic", "hmac" or "mtls"
+	OwnerKeyEncoding string `json:"owner_key_encoding,omitempty"`
+	ContentEncoding  string `json:"content_encoding,omitempty"`
+	Token            string `json:"token,omitempty"`
+	Username         string `json:"username,omitempty"`
+	Password         string `json:"password,omitempty"`
+	HMACKey          string `json:"hmac_key,omitempty"`
+	HMACHeader       string `json:"hmac_header,omitempty"`
+	ClientCert       string `json:"client_cert,omitempty"`
+	ClientKey        string `json:"client_key,omitempty"`
+}
+
+// DiskDestinationSpec is an entry of voucher_management.save_to_disk.destinations
+type DiskDestinationSpec struct {
+	Directory   string   `json:"directory,omitempty"`
+	Command     string   `json:"command,omitempty"`
+	Timeout     string   `json:"timeout,omitempty"` // e.g. "30s"
+	Filename    string   `json:"filename,omitempty"`
+	Compression string   `json:"compression,omitempty"`
+	OnError     string   `json:"on_error,omitempty"`
+	Customers   []string `json:"customers,omitempty"`
+	Profiles    []string `json:"profiles,omitempty"`
+}
+
+// ConfigChange is one setting a config document changes
+type ConfigChange struct {
+	Path            string `json:"path"`
+	Old             any    `json:"old"`
+	New             any    `json:"new"`
+	RestartRequired bool   `json:"restart_required"`
+}
+
+// ConfigDiff is the response of diffConfig and applyConfig
+type ConfigDiff struct {
+	Changes         []ConfigChange `json:"changes"`
+	RestartRequired bool           `json:"restart_required"`
+	Applied         bool           `json:"applied"`
+}
+
+// RoutingTable is the upload routing table of exportRouting and importRouting
+type RoutingTable struct {
+	Version      int                        `json:"version"`
+	Destinations []UploadDestinationRequest `json:"destinations"`
+}
+
+// RoutingDiff is the response of diffRouting and importRouting
+type RoutingDiff struct {
+	Changes []ConfigChange `json:"changes"`
+	Applied bool           `json:"applied"`
+}
+
+// ChangeRequest is a signover or routing change that needs a second admin's approval
+type ChangeRequest struct {
+	ID          string                    `json:"id"`
+	Kind        string                    `json:"kind"`   // "config" | "destination_put" | "destination_delete" | "routing_import" | "resource_put" | "resource_delete"
+	Target      string                    `json:"target"` // Destination name, or the changed config paths
+	Changes     []ConfigChange            `json:"changes,omitempty"`
+	Destination *UploadDestinationRequest `json:"destination,omitempty"`
+	Status      string                    `json:"status"` // "pending" | "approved" | "rejected" | "expired" | "failed"
+	RequestedBy string                    `json:"requested_by"`
+	RequestedAt time.Time                 `json:"requested_at"`
+	DecidedBy   string                    `json:"decided_by,omitempty"`
+	DecidedAt   *time.Time                `json:"decided_at,omitempty"`
+	Detail      string                    `json:"detail,omitempty"`
+}
+
+// ApprovalDecision is the response of approveChange
+type ApprovalDecision struct {
+	Change *ChangeRequest  `json:"change"`
+	Result json.RawMessage `json:"result,omitempty"` // A ConfigDiff or UploadDestination
+}
+
+// PendingApprovalError is returned by applyConfig and the destination
+// operations when dual control filed the change for a second admin's approval
+type PendingApprovalError struct {
+	Change *ChangeRequest
+}
+
+func (e *PendingApprovalError) Error() string {
+	return fmt.Sprintf("change %s awaits approval by a second admin", e.Change.ID)
+}
+
+// CommandPoolStats is one entry of listExecutors
+type CommandPoolStats struct {
+	Command       string  `json:"command"`
+	MaxConcurrent int     `json:"max_concurrent"`
+	Running       int     `json:"running"`
+	Queued        int     `json:"queued"`
+	Started       uint64  `json:"started"`
+	Rejected      uint64  `json:"rejected"`
+	TimedOut      uint64  `json:"timed_out"`
+	WaitAvgMillis float64 `json:"wait_avg_ms"`
+	WaitMaxMillis float64 `json:"wait_max_ms"`
+}
+
+// CommandInvocation is one entry of listCommandInvocations
+type CommandInvocation struct {
+	ID         int64             `json:"id"`
+	Name       string            `json:"name"`
+	Template   string            `json:"template"`
+	Command    string            `json:"command"`
+	Variables  map[string]string `json:"variables"`
+	Files      []string          `json:"files,omitempty"`
+	Serial     string            `json:"serial,omitempty"`
+	GUID       string            `json:"guid,omitempty"`
+	ExitCode   int               `json:"exit_code"`
+	Error      string            `json:"error,omitempty"`
+	Stdout     string            `json:"stdout"`
+	Stderr     string            `json:"stderr"`
+	Truncated  bool              `json:"truncated"`
+	DurationMS int64             `json:"duration_ms"`
+	StartedAt  time.Time         `json:"started_at"`
+}
+
+// PluginStatus is one entry of listPlugins
+type PluginStatus struct {
+	Name       string     `json:"name"`
+	Command    string     `json:"command"`
+	Running    bool       `json:"running"`
+	PID        int        `json:"pid,omitempty"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	Restarts   uint64     `json:"restarts"`
+	Calls      uint64     `json:"calls"`
+	Failures   uint64     `json:"failures"`
+	LastHealth *time.Time `json:"last_health,omitempty"`
+	LastError  string     `json:"last_error,omitempty"`
+}
+
+// HealthReport is the response of getHealth
+type HealthReport struct {
+	Status string        `json:"status"` // "ok", "degraded" or "refusing"
+	Checks []HealthCheck `json:"checks"`
+}
+
+// HealthCheck is one part of the station's health
+type HealthCheck struct {
+	Name   string `json:"name"` // "database", "disk", "backpressure" or "integrity"
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// DiskReport is the response of getDiskStatus
+type DiskReport struct {
+	Level    string       `json:"level"` // "ok", "warning" or "critical"
+	Refusing bool         `json:"refusing"`
+	Paths    []DiskStatus `json:"paths"`
+}
+
+// DiskStatus is the last check of one monitored location
+type DiskStatus struct {
+	Name       string    `json:"name"`
+	Path       string    `json:"path"`
+	FreeBytes  uint64    `json:"free_bytes"`
+	TotalBytes uint64    `json:"total_bytes"`
+	SizeBytes  int64     `json:"size_bytes,omitempty"`
+	Level      string    `json:"level"`
+	Error      string    `json:"error,omitempty"`
+	CheckedAt  time.Time `json:"checked_at"`
+}
+
+// BackpressureState is the response of getBackpressure
+type BackpressureState struct {
+	Engaged           bool           `json:"engaged"`
+	Since             *time.Time     `json:"since,omitempty"`
+	Reasons           []string       `json:"reasons"`
+	RetryAfterSeconds int            `json:"retry_after_seconds"`
+	UploadQueueDepth  int            `json:"upload_queue_depth"`
+	UploadQueueLimit  int            `json:"upload_queue_limit"`
+	CommandQueued     map[string]int `json:"command_queued"`
+	CommandQueueLimit int            `json:"command_queue_limit"`
+	Refused           uint64         `json:"refused"`
+}
+
+// IntegrityReport is the response of getVoucherIntegrity
+type IntegrityReport struct {
+	StartedAt  time.Time          `json:"started_at"`
+	FinishedAt time.Time          `json:"finished_at"`
+	Checked    map[string]int     `json:"checked"`
+	Corrupt    int                `json:"corrupt"`
+	Failures   []IntegrityFailure `json:"failures"`
+	Errors     []string           `json:"errors,omitempty"`
+}
+
+// IntegrityFailure is one stored voucher that failed verification
+type IntegrityFailure struct {
+	Source string `json:"source"`
+	Key    string `json:"key"`
+	Error  string `json:"error"`
+}
+
+// GCReport is the response of getGCReport and runGC
+type GCReport struct {
+	StartedAt  time.Time      `json:"started_at"`
+	FinishedAt time.Time      `json:"finished_at"`
+	Repair     bool           `json:"repair"`
+	Counts     map[string]int `json:"counts"`
+	Findings   []GCFinding    `json:"findings"`
+	Errors     []string       `json:"errors,omitempty"`
+}
+
+// GCFinding is one leftover a GC run found and what it did about it
+type GCFinding struct {
+	Kind   string `json:"kind"`
+	Key    string `json:"key"`
+	Detail string `json:"detail"`
+	Action string `json:"action"` // "reported" | "repaired" | "removed" | "failed"
+}
+
+// SignoverTarget is an owner key a customer/model's vouchers have been extended to
+type SignoverTarget struct {
+	ID        int64     `json:"id"`
+	Customer  string    `json:"customer,omitempty"`
+	Model     string    `json:"model,omitempty"`
+	KeySHA256 string    `json:"key_sha256"`
+	DID       string    `json:"did,omitempty"`
+	Vouchers  int       `json:"vouchers"`
+	Anomaly   bool      `json:"anomaly"` // The first voucher to it raised a signover anomaly
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// DeviceLabel is the label data of a voucher
+type DeviceLabel struct {
+	GUID      string            `json:"guid"`
+	Serial    string            `json:"serial"`
+	Model     string            `json:"model,omitempty"`
+	Customer  string            `json:"customer,omitempty"`
+	ClaimURL  string            `json:"claim_url"`
+	CreatedAt time.Time         `json:"created_at"`
+	Locale    string            `json:"locale"`
+	Captions  map[string]string `json:"captions"`
+}
+
+// MessageBundle is the operator-facing message catalog of one locale
+type MessageBundle struct {
+	Locale   string            `json:"locale"`
+	Default  string            `json:"default"`
+	Locales  []string          `json:"locales"`
+	Messages map[string]string `json:"messages"`
+}
+
+// DIDPin holds a DID to one resolved key until it expires
+type DIDPin struct {
+	DID       string    `json:"did"`
+	KeySHA256 string    `json:"key_sha256"`
+	DIDURL    string    `json:"did_url,omitempty"`
+	PinnedBy  string    `json:"pinned_by,omitempty"`
+	Reason    string    `json:"reason,omitempty"`
+	PinnedAt  time.Time `json:"pinned_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// DIDPinRequest is the body of pinDID
+type DIDPinRequest struct {
+	KeySHA256 string `json:"key_sha256,omitempty"` // Must name the DID's last resolved key; empty pins that key
+	Duration  string `json:"duration"`             // e.g. "6h"
+	Reason    string `json:"reason"`
+}
+
+// DIDCacheStatus is the response of getDIDCacheEntry
+type DIDCacheStatus struct {
+	DID                string     `json:"did"`
+	KeySHA256          string     `json:"key_sha256"`
+	DIDURL             string     `json:"did_url,omitempty"`
+	CachedAt           time.Time  `json:"cached_at"`
+	LastUsed           time.Time  `json:"last_used"`
+	LastRefreshAttempt *time.Time `json:"last_refresh_attempt,omitempty"`
+	LastRefreshError   string     `json:"last_refresh_error,omitempty"`
+}
+
+// TransferEnvelope is the response of exportVouchers and the body of importVouchers.
+// Pass it to the importing station unchanged.
+type TransferEnvelope struct {
+	Station   string `json:"station"`
+	Bundle    string `json:"bundle"`
+	Signature string `json:"signature"`
+}
+
+// TransferImportError is a voucher that failed verification on import
+type TransferImportError struct {
+	GUID  string `json:"guid"`
+	Error string `json:"error"`
+}
+
+// TransferImportResult is the response of importVouchers
+type TransferImportResult struct {
+	Station    string                `json:"station"`
+	Imported   []string              `json:"imported"`
+	Duplicates []string              `json:"duplicates"`
+	Conflicts  []string              `json:"conflicts"`
+	Invalid    []TransferImportError `json:"invalid"`
+}
+
+// GraphQLRequest is the body of queryGraphQL
+type GraphQLRequest struct {
+	Query     string         `json:"query"`
+	Variables map[string]any `json:"variables,omitempty"`
+}
+
+// GraphQLError is a field error of a GraphQL query
+type GraphQLError struct {
+	Message string `json:"message"`
+	Path    []any  `json:"path,omitempty"`
+}
+
+// GraphQLErrors is returned by QueryGraphQL when some fields failed. The data of
+// the other fields is still decoded.
+type GraphQLErrors []GraphQLError
+
+func (e GraphQLErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Message
+	}
+	return "graphql: " + strings.Join(messages, "; ")
+}
+
+// GetVersion calls GET /version
+func (c *Client) GetVersion(ctx context.Context) (*BuildInfo, error) {
+	var info BuildInfo
+	return &info, c.do(ctx, http.MethodGet, "/version", nil, nil, &info)
+}
+
+// GetStartupReport calls GET /api/startup
+func (c *Client) GetStartupReport(ctx context.Context) (*StartupReport, error) {
+	var report StartupReport
+	return &report, c.do(ctx, http.MethodGet, "/api/startup", nil, nil, &report)
+}
+
+// GetHealth calls GET /api/health
+func (c *Client) GetHealth(ctx context.Context) (*HealthReport, error) {
+	var report HealthReport
+	return &report, c.do(ctx, http.MethodGet, "/api/health", nil, nil, &report)
+}
+
+// GetMessages calls GET /api/messages; an empty lang asks for the station default
+func (c *Client) GetMessages(ctx context.Context, lang string) (*MessageBundle, error) {
+	var bundle MessageBundle
+	return &bundle, c.do(ctx, http.MethodGet, "/api/messages"+langQuery(lang), nil, nil, &bundle)
+}
+
+// langQuery is the ?lang= query string selecting a locale, empty for none
+func langQuery(lang string) string {
+	if lang == "" {
+		return ""
+	}
+	return "?" + url.Values{"lang": {lang}}.Encode()
+}
+
+// ListAuditEvents calls GET /api/audit
+func (c *Client) ListAuditEvents(ctx context.Context, opts *ListOptions) (*Page[AuditEvent], error) {
+	return list[AuditEvent](ctx, c, "/api/audit", opts)
+}
+
+// VerifyAuditLog calls GET /api/audit/verify
+func (c *Client) VerifyAuditLog(ctx context.Context) (*AuditVerification, error) {
+	var v AuditVerification
+	return &v, c.do(ctx, http.MethodGet, "/api/audit/verify", nil, nil, &v)
+}
+
+// AnchorAuditLog calls POST /api/audit/anchor
+func (c *Client) AnchorAuditLog(ctx context.Context) ([]AuditAnchorRecord, error) {
+	var records []AuditAnchorRecord
+	return records, c.do(ctx, http.MethodPost, "/api/audit/anchor", nil, nil, &records)
+}
+
+// ListBatches calls GET /api/batches
+func (c *Client) ListBatches(ctx context.Context, opts *ListOptions) (*Page[Batch], error) {
+	return list[Batch](ctx, c, "/api/batches", opts)
+}
+
+// ListGUIDReservations calls GET /api/guids
+func (c *Client) ListGUIDReservations(ctx context.Context, opts *ListOptions) (*Page[GUIDReservation], error) {
+	return list[GUIDReservation](ctx, c, "/api/guids", opts)
+}
+
+// ReserveGUIDs calls POST /api/guids/reserve
+func (c *Client) ReserveGUIDs(ctx context.Context, req *ReserveGUIDsRequest) ([]GUIDReservation, error) {
+	var reservations []GUIDReservation
+	return reservations, c.do(ctx, http.MethodPost, "/api/guids/reserve", nil, req, &reservations)
+}
+
+// BindGUID calls POST /api/guids/{guid}/bind
+func (c *Client) BindGUID(ctx context.Context, guid, serial string) (*GUIDReservation, error) {
+	var reservation GUIDReservation
+	body := map[string]string{"serial": serial}
+	return &reservation, c.do(ctx, http.MethodPost, "/api/guids/"+url.PathEscape(guid)+"/bind", nil, body, &reservation)
+}
+
+// ReleaseGUID calls DELETE /api/guids/{guid}
+func (c *Client) ReleaseGUID(ctx context.Context, guid string) (*GUIDReservation, error) {
+	var reservation GUIDReservation
+	return &reservation, c.do(ctx, http.MethodDelete, "/api/guids/"+url.PathEscape(guid), nil, nil, &reservation)
+}
+
+// ListOverrides calls GET /api/overrides
+func (c *Client) ListOverrides(ctx context.Context, opts *ListOptions) (*Page[PolicyOverride], error) {
+	return list[PolicyOverride](ctx, c, "/api/overrides", opts)
+}
+
+// IssueOverride calls POST /api/overrides
+func (c *Client) IssueOverride(ctx context.Context, req *IssueOverrideRequest) (*IssuedOverride, error) {
+	var issued IssuedOverride
+	return &issued, c.do(ctx, http.MethodPost, "/api/overrides", nil, req, &issued)
+}
+
+// ApplyOverride calls POST /api/overrides/apply
+func (c *Client) ApplyOverride(ctx context.Context, req *ApplyOverrideRequest) (*PolicyOverride, error) {
+	var override PolicyOverride
+	return &override, c.do(ctx, http.MethodPost, "/api/overrides/apply", nil, req, &override)
+}
+
+// RevokeOverride calls DELETE /api/overrides/{id}
+func (c *Client) RevokeOverride(ctx context.Context, id string) (*PolicyOverride, error) {
+	var override PolicyOverride
+	return &override, c.do(ctx, http.MethodDelete, "/api/overrides/"+url.PathEscape(id), nil, nil, &override)
+}
+
+// ListOwnerKeys calls GET /api/owner-keys
+func (c *Client) ListOwnerKeys(ctx context.Context, opts *ListOptions) (*Page[OwnerKeyRegistration], error) {
+	return list[OwnerKeyRegistration](ctx, c, "/api/owner-keys", opts)
+}
+
+// RegisterOwnerKey calls POST /api/owner-keys
+func (c *Client) RegisterOwnerKey(ctx context.Context, req *RegisterOwnerKeyRequest) (*OwnerKeyRegistration, error) {
+	var reg OwnerKeyRegistration
+	return &reg, c.do(ctx, http.MethodPost, "/api/owner-keys", nil, req, &reg)
+}
+
+// ProveOwnerKey calls POST /api/owner-keys/{id}/proof
+func (c *Client) ProveOwnerKey(ctx context.Context, id string, req *OwnerKeyProofRequest) (*OwnerKeyRegistration, error) {
+	var reg OwnerKeyRegistration
+	return &reg, c.do(ctx, http.MethodPost, "/api/owner-keys/"+url.PathEscape(id)+"/proof", nil, req, &reg)
+}
+
+// DeleteOwnerKey calls DELETE /api/owner-keys/{id}
+func (c *Client) DeleteOwnerKey(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/api/owner-keys/"+url.PathEscape(id), nil, nil, nil)
+}
+
+// OpenBatch calls POST /api/batches
+func (c *Client) OpenBatch(ctx context.Context, req *OpenBatchRequest) (*Batch, error) {
+	var batch Batch
+	return &batch, c.do(ctx, http.MethodPost, "/api/batches", nil, req, &batch)
+}
+
+// GetCurrentBatch calls GET /api/batches/current
+func (c *Client) GetCurrentBatch(ctx context.Context) (*Batch, error) {
+	var batch Batch
+	return &batch, c.do(ctx, http.MethodGet, "/api/batches/current", nil, nil, &batch)
+}
+
+// GetBatch calls GET /api/batches/{id}
+func (c *Client) GetBatch(ctx context.Context, id string) (*Batch, error) {
+	var batch Batch
+	return &batch, c.do(ctx, http.MethodGet, "/api/batches/"+url.PathEscape(id), nil, nil, &batch)
+}
+
+// CloseBatch calls POST /api/batches/{id}/close
+func (c *Client) CloseBatch(ctx context.Context, id string) (*Batch, error) {
+	var batch Batch
+	return &batch, c.do(ctx, http.MethodPost, "/api/batches/"+url.PathEscape(id)+"/close", nil, nil, &batch)
+}
+
+// ListBatchVouchers calls GET /api/batches/{id}/vouchers
+func (c *Client) ListBatchVouchers(ctx context.Context, id string, opts *ListOptions) (*Page[BatchVoucher], error) {
+	return list[BatchVoucher](ctx, c, "/api/batches/"+url.PathEscape(id)+"/vouchers", opts)
+}
+
+// GetLotReport calls GET /api/lots/{lot}
+func (c *Client) GetLotReport(ctx context.Context, lot string) (*LotReport, error) {
+	var report LotReport
+	return &report, c.do(ctx, http.MethodGet, "/api/lots/"+url.PathEscape(lot), nil, nil, &report)
+}
+
+// ListLotVouchers calls GET /api/lots/{lot}/vouchers
+func (c *Client) ListLotVouchers(ctx context.Context, lot string, opts *ListOptions) (*Page[BatchVoucher], error) {
+	return list[BatchVoucher](ctx, c, "/api/lots/"+url.PathEscape(lot)+"/vouchers", opts)
+}
+
+// ListVouchers calls GET /api/vouchers
+func (c *Client) ListVouchers(ctx context.Context, opts *ListOptions) (*Page[BatchVoucher], error) {
+	return list[BatchVoucher](ctx, c, "/api/vouchers", opts)
+}
+
+// GetVoucherDiag calls GET /api/vouchers/{guid}/diag and writes the stored
+// voucher, as annotated CBOR diagnostic notation, to w
+func (c *Client) GetVoucherDiag(ctx context.Context, guid string, w io.Writer) error {
+	return c.copyText(ctx, "/api/vouchers/"+url.PathEscape(guid)+"/diag", w)
+}
+
+// GetVoucherChain calls GET /api/vouchers/{guid}/chain
+func (c *Client) GetVoucherChain(ctx context.Context, guid string) (*VoucherChain, error) {
+	var chain VoucherChain
+	return &chain, c.do(ctx, http.MethodGet, "/api/vouchers/"+url.PathEscape(guid)+"/chain", nil, nil, &chain)
+}
+
+// GetVoucherChainGraph calls GET /api/vouchers/{guid}/chain and writes the
+// chain as a graph to w; format is "dot" (Graphviz) or "svg"
+func (c *Client) GetVoucherChainGraph(ctx context.Context, guid, format string, w io.Writer) error {
+	return c.copyText(ctx, "/api/vouchers/"+url.PathEscape(guid)+"/chain?format="+url.QueryEscape(format), w)
+}
+
+// DiffVoucher calls POST /api/vouchers/{guid}/diff with a voucher (.fdoov
+// PEM or raw CBOR) to compare with the stored voucher of guid
+func (c *Client) DiffVoucher(ctx context.Context, guid string, voucher []byte) (*VoucherComparison, error) {
+	var comparison VoucherComparison
+	header := http.Header{"Content-Type": {"application/octet-stream"}}
+	return &comparison, c.do(ctx, http.MethodPost, "/api/vouchers/"+url.PathEscape(guid)+"/diff", header, rawBody(voucher), &comparison)
+}
+
+// CheckVoucher calls GET /api/vouchers/{guid}/check
+func (c *Client) CheckVoucher(ctx context.Context, guid string, check VoucherCheck) (*VoucherComparison, error) {
+	query := url.Values{}
+	if check.RvInfo {
+		query.Set("rv", "true")
+	}
+	if check.OwnerSHA256 != "" {
+		query.Set("owner_sha256", check.OwnerSHA256)
+	}
+	if check.OVEKeys != nil {
+		query.Set("ove_keys", strings.Join(check.OVEKeys, ","))
+	}
+	var comparison VoucherComparison
+	return &comparison, c.do(ctx, http.MethodGet, "/api/vouchers/"+url.PathEscape(guid)+"/check?"+query.Encode(), nil, nil, &comparison)
+}
+
+// GetDeviceLabel calls GET /api/vouchers/{guid}/label
+func (c *Client) GetDeviceLabel(ctx context.Context, guid string) (*DeviceLabel, error) {
+	return c.GetDeviceLabelIn(ctx, guid, "")
+}
+
+// GetDeviceLabelIn calls GET /api/vouchers/{guid}/label with its captions in
+// a locale; an empty lang leaves the choice to the station
+func (c *Client) GetDeviceLabelIn(ctx context.Context, guid, lang string) (*DeviceLabel, error) {
+	var label DeviceLabel
+	return &label, c.do(ctx, http.MethodGet, "/api/vouchers/"+url.PathEscape(guid)+"/label"+langQuery(lang), nil, nil, &label)
+}
+
+// GetCaptureDiag calls GET /api/captures/{file}/diag and writes the DI
+// messages of the capture, as annotated CBOR diagnostic notation, to w
+func (c *Client) GetCaptureDiag(ctx context.Context, file string, w io.Writer) error {
+	return c.copyText(ctx, "/api/captures/"+url.PathEscape(file)+"/diag", w)
+}
+
+// copyText copies the text response of a GET to w
+func (c *Client) copyText(ctx context.Context, path string, w io.Writer) error {
+	resp, err := c.send(ctx, http.MethodGet, path, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := decodeResponse(resp, nil); err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return nil
+}
+
+// ListUploadReceipts calls GET /api/uploads
+func (c *Client) ListUploadReceipts(ctx context.Context, opts *ListOptions) (*Page[UploadReceipt], error) {
+	return list[UploadReceipt](ctx, c, "/api/uploads", opts)
+}
+
+// ListQuotas calls GET /api/quotas
+func (c *Client) ListQuotas(ctx context.Context) ([]QuotaStatus, error) {
+	var statuses []QuotaStatus
+	return statuses, c.do(ctx, http.MethodGet, "/api/quotas", nil, nil, &statuses)
+}
+
+// OverrideQuota calls POST /api/quotas/{name}/override
+func (c *Client) OverrideQuota(ctx context.Context, name string, req *QuotaOverrideRequest) (*QuotaStatus, error) {
+	var status QuotaStatus
+	return &status, c.do(ctx, http.MethodPost, "/api/quotas/"+url.PathEscape(name)+"/override", nil, req, &status)
+}
+
+// ListExecutors calls GET /api/executors
+func (c *Client) ListExecutors(ctx context.Context) ([]CommandPoolStats, error) {
+	var stats []CommandPoolStats
+	return stats, c.do(ctx, http.MethodGet, "/api/executors", nil, nil, &stats)
+}
+
+// ListCommandInvocations calls GET /api/executors/invocations
+func (c *Client) ListCommandInvocations(ctx context.Context, opts *ListOptions) (*Page[CommandInvocation], error) {
+	return list[CommandInvocation](ctx, c, "/api/executors/invocations", opts)
+}
+
+// ListPlugins calls GET /api/executors/plugins
+func (c *Client) ListPlugins(ctx context.Context) ([]PluginStatus, error) {
+	var plugins []PluginStatus
+	return plugins, c.do(ctx, http.MethodGet, "/api/executors/plugins", nil, nil, &plugins)
+}
+
+// GetDiskStatus calls GET /api/disk
+func (c *Client) GetDiskStatus(ctx context.Context) (*DiskReport, error) {
+	var report DiskReport
+	return &report, c.do(ctx, http.MethodGet, "/api/disk", nil, nil, &report)
+}
+
+// GetBackpressure calls GET /api/backpressure
+func (c *Client) GetBackpressure(ctx context.Context) (*BackpressureState, error) {
+	var state BackpressureState
+	return &state, c.do(ctx, http.MethodGet, "/api/backpressure", nil, nil, &state)
+}
+
+// GetVoucherIntegrity calls GET /api/integrity
+func (c *Client) GetVoucherIntegrity(ctx context.Context) (*IntegrityReport, error) {
+	var report IntegrityReport
+	return &report, c.do(ctx, http.MethodGet, "/api/integrity", nil, nil, &report)
+}
+
+// GetGCReport calls GET /api/gc
+func (c *Client) GetGCReport(ctx context.Context) (*GCReport, error) {
+	var report GCReport
+	return &report, c.do(ctx, http.MethodGet, "/api/gc", nil, nil, &report)
+}
+
+// RunGC calls POST /api/gc; repair overrides gc.repair for this run
+func (c *Client) RunGC(ctx context.Context, repair bool) (*GCReport, error) {
+	var report GCReport
+	return &report, c.do(ctx, http.MethodPost, "/api/gc?repair="+strconv.FormatBool(repair), nil, nil, &report)
+}
+
+// ListSignoverTargets calls GET /api/signover/targets
+func (c *Client) ListSignoverTargets(ctx context.Context, opts *ListOptions) (*Page[SignoverTarget], error) {
+	return list[SignoverTarget](ctx, c, "/api/signover/targets", opts)
+}
+
+// ListDIDPins calls GET /api/did/pins
+func (c *Client) ListDIDPins(ctx context.Context) ([]DIDPin, error) {
+	var pins []DIDPin
+	return pins, c.do(ctx, http.MethodGet, "/api/did/pins", nil, nil, &pins)
+}
+
+// PinDID calls PUT /api/did/pins/{did}
+func (c *Client) PinDID(ctx context.Context, did string, req *DIDPinRequest) (*DIDPin, error) {
+	var pin DIDPin
+	return &pin, c.do(ctx, http.MethodPut, "/api/did/pins/"+url.PathEscape(did), nil, req, &pin)
+}
+
+// UnpinDID calls DELETE /api/did/pins/{did}
+func (c *Client) UnpinDID(ctx context.Context, did string) error {
+	return c.do(ctx, http.MethodDelete, "/api/did/pins/"+url.PathEscape(did), nil, nil, nil)
+}
+
+// GetDIDCacheEntry calls GET /api/did/cache/{did}
+func (c *Client) GetDIDCacheEntry(ctx context.Context, did string) (*DIDCacheStatus, error) {
+	var status DIDCacheStatus
+	return &status, c.do(ctx, http.MethodGet, "/api/did/cache/"+url.PathEscape(did), nil, nil, &status)
+}
+
+// EvictDIDCacheEntry calls DELETE /api/did/cache/{did}
+func (c *Client) EvictDIDCacheEntry(ctx context.Context, did string) error {
+	return c.do(ctx, http.MethodDelete, "/api/did/cache/"+url.PathEscape(did), nil, nil, nil)
+}
+
+// PurgeDIDCache calls POST /api/did/cache/purge and returns the number of
+// entries removed: those unused for did_cache.purge_unused, or all of them
+func (c *Client) PurgeDIDCache(ctx context.Context, all bool) (int, error) {
+	var result struct {
+		Purged int `json:"purged"`
+	}
+	err := c.do(ctx, http.MethodPost, "/api/did/cache/purge", nil, map[string]bool{"all": all}, &result)
+	return result.Purged, err
+}
+
+// ListDestinations calls GET /api/destinations
+func (c *Client) ListDestinations(ctx context.Context, opts *ListOptions) (*Page[UploadDestination], error) {
+	return list[UploadDestination](ctx, c, "/api/destinations", opts)
+}
+
+// CreateDestination calls POST /api/destinations
+func (c *Client) CreateDestination(ctx context.Context, req *UploadDestinationRequest) (*UploadDestination, error) {
+	var d UploadDestination
+	return &d, c.do(ctx, http.MethodPost, "/api/destinations", nil, req, &d)
+}
+
+// GetDestination calls GET /api/destinations/{name}
+func (c *Client) GetDestination(ctx context.Context, name string) (*UploadDestination, error) {
+	var d UploadDestination
+	return &d, c.do(ctx, http.MethodGet, "/api/destinations/"+url.PathEscape(name), nil, nil, &d)
+}
+
+// PutDestination calls PUT /api/destinations/{name}
+func (c *Client) PutDestination(ctx context.Context, name string, req *UploadDestinationRequest) (*UploadDestination, error) {
+	var d UploadDestination
+	return &d, c.do(ctx, http.MethodPut, "/api/destinations/"+url.PathEscape(name), nil, req, &d)
+}
+
+// DeleteDestination calls DELETE /api/destinations/{name}
+func (c *Client) DeleteDestination(ctx context.Context, name string) error {
+	return c.do(ctx, http.MethodDelete, "/api/destinations/"+url.PathEscape(name), nil, nil, nil)
+}
+
+// ResetDestination calls POST /api/destinations/{name}/reset
+func (c *Client) ResetDestination(ctx context.Context, name string) (*UploadDestination, error) {
+	var d UploadDestination
+	return &d, c.do(ctx, http.MethodPost, "/api/destinations/"+url.PathEscape(name)+"/reset", nil, nil, &d)
+}
+
+// ListUploadAuthProfiles calls GET /api/upload-auth-profiles
+func (c *Client) ListUploadAuthProfiles(ctx context.Context) ([]Provisioned[UploadAuthProfileSpec], error) {
+	var profiles []Provisioned[UploadAuthProfileSpec]
+	return profiles, c.do(ctx, http.MethodGet, "/api/upload-auth-profiles", nil, nil, &profiles)
+}
+
+// GetUploadAuthProfile calls GET /api/upload-auth-profiles/{name}
+func (c *Client) GetUploadAuthProfile(ctx context.Context, name string) (*Provisioned[UploadAuthProfileSpec], error) {
+	var profile Provisioned[UploadAuthProfileSpec]
+	return &profile, c.do(ctx, http.MethodGet, "/api/upload-auth-profiles/"+url.PathEscape(name), nil, nil, &profile)
+}
+
+// PutUploadAuthProfile calls PUT /api/upload-auth-profiles/{name}
+func (c *Client) PutUploadAuthProfile(ctx context.Context, name string, spec *UploadAuthProfileSpec) (*Provisioned[UploadAuthProfileSpec], error) {
+	var profile Provisioned[UploadAuthProfileSpec]
+	return &profile, c.do(ctx, http.MethodPut, "/api/upload-auth-profiles/"+url.PathEscape(name), nil, spec, &profile)
+}
+
+// DeleteUploadAuthProfile calls DELETE /api/upload-auth-profiles/{name}
+func (c *Client) DeleteUploadAuthProfile(ctx context.Context, name string) error {
+	return c.do(ctx, http.MethodDelete, "/api/upload-auth-profiles/"+url.PathEscape(name), nil, nil, nil)
+}
+
+// ListDiskDestinations calls GET /api/disk-destinations
+func (c *Client) ListDiskDestinations(ctx context.Context) ([]Provisioned[DiskDestinationSpec], error) {
+	var dests []Provisioned[DiskDestinationSpec]
+	return dests, c.do(ctx, http.MethodGet, "/api/disk-destinations", nil, nil, &dests)
+}
+
+// GetDiskDestination calls GET /api/disk-destinations/{name}
+func (c *Client) GetDiskDestination(ctx context.Context, name string) (*Provisioned[DiskDestinationSpec], error) {
+	var dest Provisioned[DiskDestinationSpec]
+	return &dest, c.do(ctx, http.MethodGet, "/api/disk-destinations/"+url.PathEscape(name), nil, nil, &dest)
+}
+
+// PutDiskDestination calls PUT /api/disk-destinations/{name}
+func (c *Client) PutDiskDestination(ctx context.Context, name string, spec *DiskDestinationSpec) (*Provisioned[DiskDestinationSpec], error) {
+	var dest Provisioned[DiskDestinationSpec]
+	return &dest, c.do(ctx, http.MethodPut, "/api/disk-destinations/"+url.PathEscape(name), nil, spec, &dest)
+}
+
+// DeleteDiskDestination calls DELETE /api/disk-destinations/{name}
+func (c *Client) DeleteDiskDestination(ctx context.Context, name string) error {
+	return c.do(ctx, http.MethodDelete, "/api/disk-destinations/"+url.PathEscape(name), nil, nil, nil)
+}
+
+// ExportRouting calls GET /api/routing
+func (c *Client) ExportRouting(ctx context.Context) (*RoutingTable, error) {
+	var table RoutingTable
+	return &table, c.do(ctx, http.MethodGet, "/api/routing", nil, nil, &table)
+}
+
+// DiffRouting calls POST /api/routing/diff with a YAML or JSON routing table
+func (c *Client) DiffRouting(ctx context.Context, document []byte) (*RoutingDiff, error) {
+	var diff RoutingDiff
+	return &diff, c.do(ctx, http.MethodPost, "/api/routing/diff", nil, rawBody(document), &diff)
+}
+
+// ImportRouting calls PUT /api/routing with a YAML or JSON routing table
+func (c *Client) ImportRouting(ctx context.Context, document []byte) (*RoutingDiff, error) {
+	var diff RoutingDiff
+	return &diff, c.do(ctx, http.MethodPut, "/api/routing", nil, rawBody(document), &diff)
+}
+
+// DiffConfig calls POST /api/config/diff with a partial YAML or JSON config document
+func (c *Client) DiffConfig(ctx context.Context, document []byte) (*ConfigDiff, error) {
+	var diff ConfigDiff
+	return &diff, c.do(ctx, http.MethodPost, "/api/config/diff", nil, rawBody(document), &diff)
+}
+
+// ApplyConfig calls POST /api/config/apply with a partial YAML or JSON config document
+func (c *Client) ApplyConfig(ctx context.Context, document []byte) (*ConfigDiff, error) {
+	var diff ConfigDiff
+	return &diff, c.do(ctx, http.MethodPost, "/api/config/apply", nil, rawBody(document), &diff)
+}
+
+// ListApprovals calls GET /api/approvals
+func (c *Client) ListApprovals(ctx context.Context, opts *ListOptions) (*Page[ChangeRequest], error) {
+	return list[ChangeRequest](ctx, c, "/api/approvals", opts)
+}
+
+// GetApproval calls GET /api/approvals/{id}
+func (c *Client) GetApproval(ctx context.Context, id string) (*ChangeRequest, error) {
+	var change ChangeRequest
+	return &change, c.do(ctx, http.MethodGet, "/api/approvals/"+url.PathEscape(id), nil, nil, &change)
+}
+
+// ApproveChange calls POST /api/approvals/{id}/approve
+func (c *Client) ApproveChange(ctx context.Context, id string) (*ApprovalDecision, error) {
+	var decision ApprovalDecision
+	return &decision, c.do(ctx, http.MethodPost, "/api/approvals/"+url.PathEscape(id)+"/approve", nil, nil, &decision)
+}
+
+// RejectChange calls POST /api/approvals/{id}/reject
+func (c *Client) RejectChange(ctx context.Context, id, reason string) (*ChangeRequest, error) {
+	var change ChangeRequest
+	req := map[string]string{"reason": reason}
+	return &change, c.do(ctx, http.MethodPost, "/api/approvals/"+url.PathEscape(id)+"/reject", nil, req, &change)
+}
+
+// ExportVouchers calls GET /api/transfer/export with exact-match filters
+func (c *Client) ExportVouchers(ctx context.Context, filters map[string]string) (*TransferEnvelope, error) {
+	query := url.Values{}
+	for name, value := range filters {
+		query.Set(name, value)
+	}
+	path := "/api/transfer/export"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	var envelope TransferEnvelope
+	return &envelope, c.do(ctx, http.MethodGet, path, nil, nil, &envelope)
+}
+
+// ImportVouchers calls POST /api/transfer/import
+func (c *Client) ImportVouchers(ctx context.Context, envelope *TransferEnvelope) (*TransferImportResult, error) {
+	var result TransferImportResult
+	return &result, c.do(ctx, http.MethodPost, "/api/transfer/import", nil, envelope, &result)
+}
+
+// GetStandbySnapshot calls GET /api/standby/snapshot/{db} and writes the
+// SQLite snapshot ("fdo" or "station") to w
+func (c *Client) GetStandbySnapshot(ctx context.Context, db string, w io.Writer) error {
+	resp, err := c.send(ctx, http.MethodGet, "/api/standby/snapshot/"+url.PathEscape(db), nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := decodeResponse(resp, nil); err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	return nil
+}
+
+// GetMetrics calls GET /api/metrics and writes the Prometheus text exposition to w
+func (c *Client) GetMetrics(ctx context.Context, w io.Writer) error {
+	resp, err := c.send(ctx, http.MethodGet, "/api/metrics", nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := decodeResponse(resp, nil); err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return fmt.Errorf("failed to read metrics: %w", err)
+	}
+	return nil
+}
+
+// QueryGraphQL calls POST /api/graphql and decodes the query's data into data
+func (c *Client) QueryGraphQL(ctx context.Context, query string, variables map[string]any, data any) error {
+	var resp struct {
+		Data   json.RawMessage `json:"data"`
+		Errors GraphQLErrors   `json:"errors"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/api/graphql", nil, &GraphQLRequest{Query: query, Variables: variables}, &resp); err != nil {
+		return err
+	}
+	if len(resp.Data) > 0 && data != nil {
+		if err := json.Unmarshal(resp.Data, data); err != nil {
+			return fmt.Errorf("failed to decode response: %w", err)
+		}
+	}
+	if len(resp.Errors) > 0 {
+		return resp.Errors
+	}
+	return nil
+}
+
+// rawBody is a request body sent as is instead of JSON encoded
+type rawBody []byte
+
+// list calls a list operation and returns one page
+func list[T any](ctx context.Context, c *Client, path string, opts *ListOptions) (*Page[T], error) {
+	query := url.Values{}
+	header := http.Header{}
+	if opts != nil {
+		if opts.Limit > 0 {
+			query.Set("limit", fmt.Sprint(opts.Limit))
+		}
+		if opts.Sort != "" {
+			query.Set("sort", opts.Sort)
+		}
+		if opts.Cursor != "" {
+			query.Set("cursor", opts.Cursor)
+		}
+		for name, value := range opts.Filters {
+			query.Set(name, value)
+		}
+		if opts.ETag != "" {
+			header.Set("If-None-Match", opts.ETag)
+		}
+	}
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	page := &Page[T]{}
+	resp, err := c.send(ctx, http.MethodGet, path, header, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, ErrNotModified
+	}
+	if err := decodeResponse(resp, &page.Items); err != nil {
+		return nil, err
+	}
+	page.NextCursor = resp.Header.Get("X-Next-Cursor")
+	page.ETag = resp.Header.Get("ETag")
+	return page, nil
+}
+
+// do calls an operation and decodes its JSON response into out
+func (c *Client) do(ctx context.Context, method, path string, header http.Header, in, out any) error {
+	resp, err := c.send(ctx, method, path, header, in)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return decodeResponse(resp, out)
+}
+
+// send builds and sends a request
+func (c *Client) send(ctx context.Context, method, path string, header http.Header, in any) (*http.Response, error) {
+	var body io.Reader
+	contentType := ""
+	switch v := in.(type) {
+	case nil:
+	case rawBody:
+		body = bytes.NewReader(v)
+		contentType = "application/yaml"
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode request: %w", err)
+		}
+		body = bytes.NewReader(data)
+		contentType = "application/json"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(c.BaseURL, "/")+path, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	if contentType != "" && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s failed: %w", method, path, err)
+	}
+	return resp, nil
+}
+
+// decodeResponse decodes a success response into out, or returns the API error
+func decodeResponse(resp *http.Response, out any) error {
+	if resp.StatusCode/100 != 2 {
+		apiErr := &Error{StatusCode: resp.StatusCode}
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		if json.Unmarshal(data, apiErr) != nil || apiErr.Message == "" {
+			apiErr.Message = strings.TrimSpace(string(data))
+		}
+		return apiErr
+	}
+	if resp.StatusCode == http.StatusAccepted {
+		var change ChangeRequest
+		if err := json.NewDecoder(resp.Body).Decode(&change); err != nil {
+			return fmt.Errorf("failed to decode response: %w", err)
+		}
+		return &PendingApprovalError{Change: &change}
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,471 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Changes that need a second admin's approval
+const (
+	ApprovalKindConfig            = "config"             // POST /api/config/apply changing signover or admin settings
+	ApprovalKindDestinationPut    = "destination_put"    // POST /api/destinations, PUT /api/destinations/{name}
+	ApprovalKindDestinationDelete = "destination_delete" // DELETE /api/destinations/{name}
+	ApprovalKindRoutingImport     = "routing_import"     // PUT /api/routing
+	ApprovalKindResourcePut       = "resource_put"       // PUT /api/upload-auth-profiles/{name}
+	ApprovalKindResourceDelete    = "resource_delete"    // DELETE /api/upload-auth-profiles/{name}
+)
+
+// Approval states
+const (
+	ApprovalPending  = "pending"
+	ApprovalApproved = "approved" // Approved and applied
+	ApprovalRejected = "rejected"
+	ApprovalExpired  = "expired"
+	ApprovalFailed   = "failed" // Approved, but applying it failed
+)
+
+// defaultApprovalTTL is how long a change waits for approval
+const defaultApprovalTTL = 24 * time.Hour
+
+// Approval errors
+var (
+	ErrApprovalNotFound     = errors.New("unknown change request")
+	ErrApprovalNotPending   = errors.New("change request is not pending")
+	ErrApprovalSameIdentity = errors.New("a change must be approved by a different admin than the one who requested it")
+	ErrApprovalNoIdentity   = errors.New("dual control needs a named admin identity (admin.users), not the shared admin token")
+)
+
+// dualControlConfigPaths are the settings whose changes redirect vouchers or
+// weaken the admin API, so a config push changing them waits for approval
+var dualControlConfigPaths = []string{
+	"admin",
+	"voucher_management.owner_signover",
+	"voucher_management.voucher_upload.url",
+	"voucher_management.voucher_upload.auth_profile",
+	"voucher_management.upload_auth_profiles",
+	"rollouts",
+}
+
+// ChangeRequest is a change to the signover targets waiting for, or decided by, a second admin
+type ChangeRequest struct {
+	ID          string                    `json:"id"`
+	Kind        string                    `json:"kind"`
+	Target      string                    `json:"target"` // Destination name, or the changed config paths
+	Changes     []ConfigChange            `json:"changes,omitempty"`
+	Destination *UploadDestinationRequest `json:"destination,omitempty"`
+	Status      string                    `json:"status"`
+	RequestedBy string                    `json:"requested_by"`
+	RequestedAt time.Time                 `json:"requested_at"`
+	DecidedBy   string                    `json:"decided_by,omitempty"`
+	DecidedAt   *time.Time                `json:"decided_at,omitempty"`
+	Detail      string                    `json:"detail,omitempty"` // Rejection reason or apply error
+
+	payload []byte // Request body replayed on approval
+}
+
+// approvalDescriber decides whether an admin request needs approval. It
+// returns nil if the request may run now, or the change request to file.
+type approvalDescriber func(r *http.Request, body []byte) (*ChangeRequest, error)
+
+// approvalApplier applies an approved change and returns its result
+type approvalApplier func(ctx context.Context, change *ChangeRequest) (any, error)
+
+// ApprovalService enforces dual control on the admin API: changes to static
+// owner keys, DIDs and upload routing are filed as pending change requests by
+// one admin and take effect only when a different admin approves them, so a
+// single compromised account can't redirect vouchers. Whether dual control is
+// on is read from the running config on each request.
+type ApprovalService struct {
+	config   *AdminConfig
+	db       *StationDB
+	auditLog *AuditLog
+
+	mu       sync.Mutex
+	appliers map[string]approvalApplier
+}
+
+// NewApprovalService creates the approval service
+func NewApprovalService(config *AdminConfig, db *StationDB, auditLog *AuditLog) *ApprovalService {
+	return &ApprovalService{config: config, db: db, auditLog: auditLog, appliers: map[string]approvalApplier{}}
+}
+
+// Initialize creates the change_requests table if it doesn't exist
+func (a *ApprovalService) Initialize(ctx context.Context) error {
+	_, err := a.db.db.ExecContext(ctx, `
+	CREATE TABLE IF NOT EXISTS change_requests (
+		id TEXT PRIMARY KEY,
+		kind TEXT NOT NULL,
+		target TEXT NOT NULL,
+		changes TEXT NOT NULL,
+		payload BLOB NOT NULL,
+		status TEXT NOT NULL,
+		requested_by TEXT NOT NULL,
+		requested_at INTEGER NOT NULL,
+		decided_by TEXT NOT NULL DEFAULT '',
+		decided_at INTEGER,
+		detail TEXT NOT NULL DEFAULT ''
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create change_requests table: %w", err)
+	}
+	return nil
+}
+
+// Register sets how approved changes of a kind are applied
+func (a *ApprovalService) Register(kind string, apply approvalApplier) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.appliers[kind] = apply
+}
+
+// enabled reports whether dual control is on
+func (a *ApprovalService) enabled() bool {
+	return a != nil && a.config.DualControl.Enabled
+}
+
+// ttl returns how long a change waits for approval
+func (a *ApprovalService) ttl() time.Duration {
+	if a.config.DualControl.TTL > 0 {
+		return a.config.DualControl.TTL
+	}
+	return defaultApprovalTTL
+}
+
+// Gate files requests that describe says need approval as change requests,
+// answering 202 with the request; others are passed to next unchanged
+func (a *ApprovalService) Gate(kind string, describe approvalDescriber, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !a.enabled() {
+			next.ServeHTTP(w, r)
+			return
+		}
+		body, err := io.ReadAll(io.LimitReader(r.Body, 1024*1024))
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("failed to read request: %v", err))
+			return
+		}
+		change, err := describe(r, body)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if change == nil {
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			next.ServeHTTP(w, r)
+			return
+		}
+		change.Kind, change.payload = kind, body
+		if err := a.Request(r.Context(), change, adminIdentity(r.Context())); err != nil {
+			writeJSONError(w, approvalStatus(err), err.Error())
+			return
+		}
+		writeJSON(w, http.StatusAccepted, change)
+	})
+}
+
+// Request files a change as pending
+func (a *ApprovalService) Request(ctx context.Context, change *ChangeRequest, identity string) error {
+	if identity == "" || identity == adminSharedIdentity {
+		return ErrApprovalNoIdentity
+	}
+	var id [8]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return fmt.Errorf("failed to generate change request ID: %w", err)
+	}
+	change.ID = hex.EncodeToString(id[:])
+	change.Status = ApprovalPending
+	change.RequestedBy = identity
+	change.RequestedAt = stationClock.Now()
+
+	described, err := json.Marshal(struct {
+		Changes     []ConfigChange            `json:"changes,omitempty"`
+		Destination *UploadDestinationRequest `json:"destination,omitempty"`
+	}{change.Changes, change.Destination})
+	if err != nil {
+		return fmt.Errorf("failed to encode change request: %w", err)
+	}
+	if _, err := a.db.db.ExecContext(ctx, `
+	INSERT INTO change_requests (id, kind, target, changes, payload, status, requested_by, requested_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		change.ID, change.Kind, change.Target, string(described), change.payload, change.Status, identity, change.RequestedAt.Unix()); err != nil {
+		return fmt.Errorf("failed to store change request: %w", err)
+	}
+	fmt.Printf("🔏 Change %s (%s %s) by %s awaits approval by a second admin\n", change.ID, change.Kind, change.Target, identity)
+	a.auditLog.Record(ctx, AuditEvent{Event: "change_requested", Detail: fmt.Sprintf("%s: %s %s by %s", change.ID, change.Kind, change.Target, identity)})
+	return nil
+}
+
+// Approve applies a pending change on behalf of a second admin
+func (a *ApprovalService) Approve(ctx context.Context, id, identity string) (*ChangeRequest, any, error) {
+	if identity == "" || identity == adminSharedIdentity {
+		return nil, nil, ErrApprovalNoIdentity
+	}
+	change, err := a.Get(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if change.Status != ApprovalPending {
+		return change, nil, fmt.Errorf("%w: %s", ErrApprovalNotPending, change.Status)
+	}
+	if change.RequestedBy == identity {
+		return change, nil, ErrApprovalSameIdentity
+	}
+	if time.Since(change.RequestedAt) > a.ttl() {
+		if err := a.decide(ctx, change, ApprovalExpired, identity, "not approved within "+a.ttl().String()); err != nil {
+			return nil, nil, err
+		}
+		return change, nil, fmt.Errorf("%w: %s", ErrApprovalNotPending, change.Status)
+	}
+
+	// Claim the change first, so two approvers racing can't apply it twice
+	if err := a.decide(ctx, change, ApprovalApproved, identity, ""); err != nil {
+		return nil, nil, err
+	}
+	a.mu.Lock()
+	apply := a.appliers[change.Kind]
+	a.mu.Unlock()
+	if apply == nil {
+		err = fmt.Errorf("change kind %s cannot be applied on this station", change.Kind)
+	} else {
+		var result any
+		if result, err = apply(ctx, change); err == nil {
+			fmt.Printf("🔏 Change %s approved by %s and applied\n", change.ID, identity)
+			a.auditLog.Record(ctx, AuditEvent{Event: "change_approved", Detail: fmt.Sprintf("%s: %s %s requested by %s, approved by %s", change.ID, change.Kind, change.Target, change.RequestedBy, identity)})
+			return change, result, nil
+		}
+	}
+	if _, dbErr := a.db.db.ExecContext(ctx, `UPDATE change_requests SET status = ?, detail = ? WHERE id = ?`, ApprovalFailed, err.Error(), change.ID); dbErr != nil {
+		fmt.Printf("⚠️  Failed to record failure of change %s: %v\n", change.ID, dbErr)
+	}
+	change.Status, change.Detail = ApprovalFailed, err.Error()
+	a.auditLog.Record(ctx, AuditEvent{Event: "change_apply_failed", Detail: fmt.Sprintf("%s: %v", change.ID, err)})
+	return change, nil, err
+}
+
+// Reject declines a pending change; the requester may withdraw their own
+func (a *ApprovalService) Reject(ctx context.Context, id, identity, reason string) (*ChangeRequest, error) {
+	if identity == "" || identity == adminSharedIdentity {
+		return nil, ErrApprovalNoIdentity
+	}
+	change, err := a.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if change.Status != ApprovalPending {
+		return change, fmt.Errorf("%w: %s", ErrApprovalNotPending, change.Status)
+	}
+	if err := a.decide(ctx, change, ApprovalRejected, identity, reason); err != nil {
+		return nil, err
+	}
+	fmt.Printf("🔏 Change %s rejected by %s\n", change.ID, identity)
+	a.auditLog.Record(ctx, AuditEvent{Event: "change_rejected", Detail: fmt.Sprintf("%s: %s %s rejected by %s: %s", change.ID, change.Kind, change.Target, identity, reason)})
+	return change, nil
+}
+
+// decide moves a pending change to its final state
+func (a *ApprovalService) decide(ctx context.Context, change *ChangeRequest, status, identity, detail string) error {
+	now := stationClock.Now()
+	res, err := a.db.db.ExecContext(ctx, `
+	UPDATE change_requests SET status = ?, decided_by = ?, decided_at = ?, detail = ? WHERE id = ? AND status = ?`,
+		status, identity, now.Unix(), detail, change.ID, ApprovalPending)
+	if err != nil {
+		return fmt.Errorf("failed to update change request %s: %w", change.ID, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("%w: decided concurrently", ErrApprovalNotPending)
+	}
+	change.Status, change.DecidedBy, change.DecidedAt, change.Detail = status, identity, &now, detail
+	return nil
+}
+
+const changeRequestColumns = `id, kind, target, changes, payload, status, requested_by, requested_at, decided_by, decided_at, detail`
+
+// scanChangeRequest reads one change_requests row
+func scanChangeRequest(row interface{ Scan(...any) error }) (*ChangeRequest, error) {
+	var c ChangeRequest
+	var described string
+	var requestedAt int64
+	var decidedAt sql.NullInt64
+	if err := row.Scan(&c.ID, &c.Kind, &c.Target, &described, &c.payload, &c.Status, &c.RequestedBy, &requestedAt, &c.DecidedBy, &decidedAt, &c.Detail); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(described), &c); err != nil {
+		return nil, fmt.Errorf("change request %s is corrupt: %w", c.ID, err)
+	}
+	c.RequestedAt = time.Unix(requestedAt, 0)
+	c.DecidedAt = nullUnixTime(decidedAt)
+	return &c, nil
+}
+
+// Get returns a change request
+func (a *ApprovalService) Get(ctx context.Context, id string) (*ChangeRequest, error) {
+	c, err := scanChangeRequest(a.db.db.QueryRowContext(ctx, `SELECT `+changeRequestColumns+` FROM change_requests WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrApprovalNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read change request %s: %w", id, err)
+	}
+	return c, nil
+}
+
+// changeRequestListSpec is the sort and filter spec of GET /api/approvals
+var changeRequestListSpec = listSpec{
+	Key:         "id",
+	Sorts:       map[string]string{"requested_at": "requested_at", "id": "id"},
+	DefaultSort: "-requested_at",
+	Filters:     map[string]string{"status": "status", "kind": "kind", "requested_by": "requested_by"},
+}
+
+// Page returns one page of change requests
+func (a *ApprovalService) Page(ctx context.Context, q *listQuery) ([]*ChangeRequest, string, error) {
+	clause, args := q.sql()
+	rows, err := a.db.db.QueryContext(ctx, `SELECT `+changeRequestColumns+` FROM change_requests`+clause, args...)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list change requests: %w", err)
+	}
+	defer rows.Close()
+	changes := []*ChangeRequest{}
+	for rows.Next() {
+		c, err := scanChangeRequest(rows)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to list change requests: %w", err)
+		}
+		changes = append(changes, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+	changes, next := listPage(q, changes, func(c *ChangeRequest, column string) any {
+		if column == "requested_at" {
+			return c.RequestedAt.Unix()
+		}
+		return c.ID
+	})
+	return changes, next, nil
+}
+
+// ListHandler serves GET /api/approvals
+func (a *ApprovalService) ListHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q, err := parseListQuery(r, &changeRequestListSpec)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		changes, next, err := a.Page(r.Context(), q)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSONList(w, r, changes, next)
+	})
+}
+
+// GetHandler serves GET /api/approvals/{id}
+func (a *ApprovalService) GetHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		change, err := a.Get(r.Context(), r.PathValue("id"))
+		if err != nil {
+			writeJSONError(w, approvalStatus(err), err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, change)
+	})
+}
+
+// ApprovalDecision is the response of POST /api/approvals/{id}/approve
+type ApprovalDecision struct {
+	Change *ChangeRequest `json:"change"`
+	Result any            `json:"result,omitempty"` // What the applied change returned, e.g. the config diff
+}
+
+// ApproveHandler serves POST /api/approvals/{id}/approve
+func (a *ApprovalService) ApproveHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		change, result, err := a.Approve(r.Context(), r.PathValue("id"), adminIdentity(r.Context()))
+		if err != nil {
+			writeJSONError(w, approvalStatus(err), err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, ApprovalDecision{Change: change, Result: result})
+	})
+}
+
+// RejectHandler serves POST /api/approvals/{id}/reject with an optional {"reason": "..."}
+func (a *ApprovalService) RejectHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Reason string `json:"reason"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid reject request: %v", err))
+			return
+		}
+		change, err := a.Reject(r.Context(), r.PathValue("id"), adminIdentity(r.Context()), req.Reason)
+		if err != nil {
+			writeJSONError(w, approvalStatus(err), err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, change)
+	})
+}
+
+// approvalStatus maps an approval error to its HTTP status
+func approvalStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrApprovalNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrApprovalSameIdentity), errors.Is(err, ErrApprovalNoIdentity):
+		return http.StatusForbidden
+	case errors.Is(err, ErrApprovalNotPending):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+// needsDualControl reports whether a config diff changes a signover or admin setting
+func needsDualControl(diff *ConfigDiff) bool {
+	return slices.ContainsFunc(diff.Changes, func(change ConfigChange) bool {
+		return slices.ContainsFunc(dualControlConfigPaths, func(prefix string) bool {
+			return change.Path == prefix || strings.HasPrefix(change.Path, prefix+".")
+		})
+	})
+}
+
+// validateDualControl checks that dual control has two admins to work with
+func validateDualControl(config *AdminConfig) error {
+	if !config.DualControl.Enabled {
+		return nil
+	}
+	names := map[string]bool{}
+	for _, user := range config.Users {
+		if user.Name == "" || user.Token == "" {
+			return fmt.Errorf("admin.users: every user needs a name and a token")
+		}
+		if user.Name == adminSharedIdentity {
+			return fmt.Errorf("admin.users: %q is the identity of the shared admin token", adminSharedIdentity)
+		}
+		names[user.Name] = true
+	}
+	if len(names) < 2 {
+		return fmt.Errorf("admin.dual_control needs at least two admin.users")
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// AuditEvent is one entry in the station audit log
+type AuditEvent struct {
+	ID       int64     `json:"id"`
+	Time     time.Time `json:"time"`
+	Event    string    `json:"event"` // e.g. "di_rejected_schedule"
+	Serial   string    `json:"serial,omitempty"`
+	GUID     string    `json:"guid,omitempty"`
+	Customer string    `json:"customer,omitempty"`
+	Model    string    `json:"model,omitempty"`
+	Detail   string    `json:"detail,omitempty"`
+	Site     string    `json:"site_code,omitempty"`
+	Line     string    `json:"line_id,omitempty"`
+	Station  string    `json:"station_id,omitempty"`
+
+	// Hex SHA-256 over the previous event's chain hash and this event, so
+	// deleting or editing an event breaks every hash after it
+	ChainHash string `json:"chain_hash,omitempty"`
+}
+
+// AuditLog records policy decisions and other notable station events in the
+// station database, stamped with the site, line and station they happened at.
+// Events are hash chained, and AuditAnchor copies the head of the chain out
+// of the station. A nil *AuditLog only prints events.
+type AuditLog struct {
+	db      *StationDB
+	station *StationConfig
+
+	chainMu sync.Mutex // Serializes this instance's inserts; other instances wait on the database lock
+}
+
+// NewAuditLog creates a new audit log
+func NewAuditLog(db *StationDB, station *StationConfig) *AuditLog {
+	return &AuditLog{db: db, station: station}
+}
+
+// Initialize creates the audit_events table if it doesn't exist
+func (a *AuditLog) Initialize(ctx context.Context) error {
+	if a == nil {
+		return nil
+	}
+	_, err := a.db.db.ExecContext(ctx, `
+	CREATE TABLE IF NOT EXISTS audit_events (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		time INTEGER NOT NULL,
+		event TEXT NOT NULL,
+		serial TEXT,
+		guid TEXT,
+		customer TEXT,
+		model TEXT,
+		detail TEXT,
+		site_code TEXT,
+		line_id TEXT,
+		station_id TEXT
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create audit_events table: %w", err)
+	}
+	// Audit tables created before site/line/station and the hash chain were recorded lack these columns
+	for _, column := range []string{"site_code", "line_id", "station_id", "chain_hash"} {
+		if err := a.db.addColumnIfMissing(ctx, "audit_events", column, "TEXT"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Record stores an audit event. Failures are logged rather than returned so
+// auditing never changes the outcome of the operation being audited.
+func (a *AuditLog) Record(ctx context.Context, event AuditEvent) {
+	if event.Time.IsZero() {
+		event.Time = stationClock.Now()
+	}
+	if a != nil && a.station != nil {
+		event.Site = a.station.SiteCode
+		event.Line = a.station.LineID
+		event.Station = a.station.StationID
+	}
+	fmt.Printf("📝 AUDIT %s site=%s line=%s station=%s serial=%s guid=%s customer=%s model=%s: %s\n",
+		event.Event, event.Site, event.Line, event.Station, serialRules.Serial(event.Serial), event.GUID, event.Customer, serialRules.Model(event.Model), event.Detail)
+	if a == nil {
+		return
+	}
+	if err := a.insert(ctx, event); err != nil {
+		fmt.Printf("⚠️  Failed to store audit event %s: %v\n", event.Event, err)
+	}
+}
+
+// Import stores an event recorded by another station, keeping its time and
+// the site, line and station it happened at
+func (a *AuditLog) Import(ctx context.Context, event AuditEvent) error {
+	if a == nil {
+		return nil
+	}
+	return a.insert(ctx, event)
+}
+
+// insert stores an event linked to the last one in the chain. The head is
+// read and the event inserted in one transaction, which the station database
+// begins IMMEDIATE, so CLI commands recording events while the server runs
+// can't fork the chain.
+func (a *AuditLog) insert(ctx context.Context, event AuditEvent) error {
+	a.chainMu.Lock()
+	defer a.chainMu.Unlock()
+	tx, err := a.db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin audit transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	var prev string
+	err = tx.QueryRowContext(ctx, `SELECT COALESCE(chain_hash, '') FROM audit_events ORDER BY id DESC LIMIT 1`).Scan(&prev)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("failed to read audit chain head: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+	INSERT INTO audit_events (time, event, serial, guid, customer, model, detail, site_code, line_id, station_id, chain_hash)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		event.Time.Unix(), event.Event, event.Serial, event.GUID, event.Customer, event.Model, event.Detail,
+		event.Site, event.Line, event.Station, auditChainHash(prev, event)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// auditChainHash links an event to the previous event's chain hash. Fields
+// are length-prefixed so they can't run into each other, and the time is
+// hashed at the second resolution it is stored with.
+func auditChainHash(prev string, e AuditEvent) string {
+	h := sha256.New()
+	for _, field := range []string{prev, strconv.FormatInt(e.Time.Unix(), 10), e.Event, e.Serial, e.GUID,
+		e.Customer, e.Model, e.Detail, e.Site, e.Line, e.Station} {
+		fmt.Fprintf(h, "%d:%s", len(field), field)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// auditListSpec is the sort and filter spec of GET /api/audit
+var auditListSpec = listSpec{
+	Key:         "id",
+	Sorts:       map[string]string{"id": "id", "time": "time", "event": "event"},
+	DefaultSort: "-id",
+	Filters: map[string]string{
+		"event": "event", "serial": "serial", "guid": "guid", "customer": "customer", "model": "model",
+		"site_code": "site_code", "line_id": "line_id", "station_id": "station_id",
+	},
+}
+
+// List returns one page of audit events
+func (a *AuditLog) List(ctx context.Context, q *listQuery) ([]AuditEvent, string, error) {
+	events := []AuditEvent{}
+	if a == nil {
+		return events, "", nil
+	}
+	clause, args := q.sql()
+	rows, err := a.db.db.QueryContext(ctx, `
+	SELECT id, time, event, COALESCE(serial, ''), COALESCE(guid, ''), COALESCE(customer, ''), COALESCE(model, ''), COALESCE(detail, ''),
+		COALESCE(site_code, ''), COALESCE(line_id, ''), COALESCE(station_id, ''), COALESCE(chain_hash, '')
+	FROM audit_events`+clause, args...)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to query audit events: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e AuditEvent
+		var t int64
+		if err := rows.Scan(&e.ID, &t, &e.Event, &e.Serial, &e.GUID, &e.Customer, &e.Model, &e.Detail,
+			&e.Site, &e.Line, &e.Station, &e.ChainHash); err != nil {
+			return nil, "", fmt.Errorf("failed to read audit event: %w", err)
+		}
+		e.Time = time.Unix(t, 0)
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+	events, next := listPage(q, events, func(e AuditEvent, column string) any {
+		switch column {
+		case "time":
+			return e.Time.Unix()
+		case "event":
+			return e.Event
+		default:
+			return e.ID
+		}
+	})
+	return events, next, nil
+}
+
+// Handler serves GET /api/audit with the list parameters of auditListSpec
+func (a *AuditLog) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q, err := parseListQuery(r, &auditListSpec)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		events, next, err := a.List(r.Context(), q)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSONList(w, r, events, next)
+	})
+}
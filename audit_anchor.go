@@ -0,0 +1,488 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fido-device-onboard/go-fdo/cbor"
+)
+
+// Places the head of the audit chain can be anchored
+const (
+	AuditSinkCheckpointFile  = "checkpoint_file"  // Signed checkpoint file
+	AuditSinkTransparencyLog = "transparency_log" // POST to an external append-only log
+	AuditSinkVoucher         = "voucher"          // OVE extra entry of the vouchers built after the checkpoint
+)
+
+// AuditCheckpointFormat identifies the audit checkpoint format
+const AuditCheckpointFormat = "fdo-station-audit-checkpoint/1"
+
+// Defaults when no interval, directory or timeout is configured
+const (
+	defaultAuditAnchorInterval      = time.Hour
+	defaultAuditCheckpointDirectory = "audit-checkpoints"
+	defaultTransparencyLogTimeout   = 10 * time.Second
+)
+
+// AuditCheckpoint is the head of the audit chain at one moment. Any later
+// change to the events up to EventID changes the chain hash there, so it no
+// longer matches the checkpoint.
+type AuditCheckpoint struct {
+	Format     string    `json:"format"`
+	Station    string    `json:"station"`
+	InstanceID string    `json:"instance_id"`
+	EventID    int64     `json:"event_id"`   // Last audit event covered
+	ChainHash  string    `json:"chain_hash"` // Chain hash of that event
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// AuditAnchorRecord is one checkpoint delivered, or not, to one sink
+type AuditAnchorRecord struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	EventID   int64     `json:"event_id"`
+	ChainHash string    `json:"chain_hash"`
+	Sink      string    `json:"sink"`
+	Reference string    `json:"reference,omitempty"` // Checkpoint file or transparency log response
+	Error     string    `json:"error,omitempty"`
+}
+
+// auditAnchorSink delivers a checkpoint somewhere the station can't rewrite
+// it, and returns where it went
+type auditAnchorSink interface {
+	anchor(ctx context.Context, checkpoint *AuditCheckpoint, body []byte) (string, error)
+}
+
+// AuditAnchor periodically anchors the head of the audit log's hash chain in
+// the configured sinks: a signed checkpoint file, an external transparency
+// log, and the OVE extra data of the vouchers built afterwards, which leave
+// the station with the devices. A nil *AuditAnchor anchors nothing.
+type AuditAnchor struct {
+	config    *AuditAnchorConfig
+	db        *StationDB
+	auditLog  *AuditLog
+	buildInfo BuildInfo
+	sinks     map[string]auditAnchorSink
+
+	runMu   sync.Mutex // Serializes anchoring
+	mu      sync.Mutex
+	voucher *AuditCheckpoint // Latest checkpoint of the voucher sink
+}
+
+// NewAuditAnchor creates the audit anchoring job, or returns nil if it is
+// disabled. Without a signing key of its own the checkpoint file is signed
+// with the transfer key.
+func NewAuditAnchor(config *AuditAnchorConfig, transferKeyFile string, db *StationDB, auditLog *AuditLog, buildInfo BuildInfo) (*AuditAnchor, error) {
+	if !config.Enabled {
+		return nil, nil
+	}
+	a := &AuditAnchor{config: config, db: db, auditLog: auditLog, buildInfo: buildInfo, sinks: map[string]auditAnchorSink{}}
+	for _, name := range config.Sinks {
+		switch name {
+		case AuditSinkCheckpointFile:
+			keyFile := config.CheckpointFile.SigningKeyFile
+			if keyFile == "" {
+				keyFile = transferKeyFile
+			}
+			if keyFile == "" {
+				return nil, fmt.Errorf("audit_anchor.checkpoint_file needs a signing_key_file (or transfer.signing_key_file)")
+			}
+			signer, err := loadPrivateKeyFile(keyFile)
+			if err != nil {
+				return nil, fmt.Errorf("audit checkpoint signing key: %w", err)
+			}
+			directory := config.CheckpointFile.Directory
+			if directory == "" {
+				directory = defaultAuditCheckpointDirectory
+			}
+			a.sinks[name] = &checkpointFileSink{directory: directory, signer: signer}
+		case AuditSinkTransparencyLog:
+			timeout := config.TransparencyLog.Timeout
+			if timeout <= 0 {
+				timeout = defaultTransparencyLogTimeout
+			}
+			a.sinks[name] = &transparencyLogSink{config: &config.TransparencyLog, client: &http.Client{Timeout: timeout}}
+		case AuditSinkVoucher:
+			a.sinks[name] = &voucherAnchorSink{owner: a}
+		}
+	}
+	fmt.Printf("⚓ Audit log anchored to %s\n", strings.Join(config.Sinks, ", "))
+	return a, nil
+}
+
+// Initialize creates the audit_anchors table if it doesn't exist
+func (a *AuditAnchor) Initialize(ctx context.Context) error {
+	if a == nil {
+		return nil
+	}
+	_, err := a.db.db.ExecContext(ctx, `
+	CREATE TABLE IF NOT EXISTS audit_anchors (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		created_at INTEGER NOT NULL,
+		event_id INTEGER NOT NULL,
+		chain_hash TEXT NOT NULL,
+		sink TEXT NOT NULL,
+		reference TEXT NOT NULL DEFAULT '',
+		error TEXT NOT NULL DEFAULT ''
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create audit_anchors table: %w", err)
+	}
+	return nil
+}
+
+// Run anchors at startup and then every interval until ctx is done
+func (a *AuditAnchor) Run(ctx context.Context) {
+	if a == nil {
+		return
+	}
+	interval := a.config.Interval
+	if interval <= 0 {
+		interval = defaultAuditAnchorInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := a.Anchor(ctx); err != nil {
+			fmt.Printf("⚠️  Audit anchoring: %v\n", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Anchor checkpoints the head of the audit chain in every sink that hasn't
+// anchored it yet, and returns what each sink did. A sink that fails is
+// recorded and audited, and tried again next time.
+func (a *AuditAnchor) Anchor(ctx context.Context) ([]AuditAnchorRecord, error) {
+	a.runMu.Lock()
+	defer a.runMu.Unlock()
+
+	checkpoint := &AuditCheckpoint{
+		Format:     AuditCheckpointFormat,
+		Station:    a.buildInfo.StationID,
+		InstanceID: a.buildInfo.InstanceID,
+		CreatedAt:  stationClock.Now().UTC(),
+	}
+	err := a.db.db.QueryRowContext(ctx, `
+	SELECT id, COALESCE(chain_hash, '') FROM audit_events ORDER BY id DESC LIMIT 1`).Scan(&checkpoint.EventID, &checkpoint.ChainHash)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && checkpoint.ChainHash == "") {
+		return nil, nil // No events, or none since the chain was introduced
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit chain head: %w", err)
+	}
+	body, err := json.MarshalIndent(checkpoint, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode audit checkpoint: %w", err)
+	}
+	body = append(body, '\n')
+
+	records := []AuditAnchorRecord{}
+	for _, name := range a.config.Sinks {
+		if a.anchored(ctx, name, checkpoint.EventID) {
+			continue
+		}
+		record := AuditAnchorRecord{CreatedAt: checkpoint.CreatedAt, EventID: checkpoint.EventID, ChainHash: checkpoint.ChainHash, Sink: name}
+		record.Reference, err = a.sinks[name].anchor(ctx, checkpoint, body)
+		if err != nil {
+			record.Error = err.Error()
+			fmt.Printf("⚠️  Failed to anchor audit event %d in %s: %v\n", checkpoint.EventID, name, err)
+		}
+		res, err := a.db.db.ExecContext(ctx, `
+		INSERT INTO audit_anchors (created_at, event_id, chain_hash, sink, reference, error) VALUES (?, ?, ?, ?, ?, ?)`,
+			record.CreatedAt.Unix(), record.EventID, record.ChainHash, record.Sink, record.Reference, record.Error)
+		if err != nil {
+			return records, fmt.Errorf("failed to record audit anchor: %w", err)
+		}
+		record.ID, _ = res.LastInsertId()
+		records = append(records, record)
+		if record.Error != "" {
+			a.auditLog.Record(ctx, AuditEvent{
+				Event:  "audit_anchor_failed",
+				Detail: fmt.Sprintf("%s: event %d: %s", name, record.EventID, record.Error),
+			})
+		}
+	}
+	return records, nil
+}
+
+// anchored reports whether a sink already holds a checkpoint of eventID. The
+// voucher sink keeps its checkpoint in memory, so it anchors again after a restart.
+func (a *AuditAnchor) anchored(ctx context.Context, sink string, eventID int64) bool {
+	if sink == AuditSinkVoucher {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		return a.voucher != nil && a.voucher.EventID == eventID
+	}
+	var last int64
+	err := a.db.db.QueryRowContext(ctx, `
+	SELECT COALESCE(MAX(event_id), 0) FROM audit_anchors WHERE sink = ? AND error = ''`, sink).Scan(&last)
+	return err == nil && last == eventID
+}
+
+// addToExtraData stamps the latest checkpoint of the voucher sink into the
+// extra data, keyed like a "fdo_audit_anchor" string key from the external script
+func (a *AuditAnchor) addToExtraData(extraData map[int][]byte) (map[int][]byte, error) {
+	if a == nil {
+		return extraData, nil
+	}
+	a.mu.Lock()
+	checkpoint := a.voucher
+	a.mu.Unlock()
+	if checkpoint == nil {
+		return extraData, nil
+	}
+	valueBytes, err := cbor.Marshal(map[string]any{
+		"station":    checkpoint.Station,
+		"event_id":   checkpoint.EventID,
+		"chain_hash": checkpoint.ChainHash,
+		"created_at": checkpoint.CreatedAt.Unix(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal audit anchor: %w", err)
+	}
+	if extraData == nil {
+		extraData = make(map[int][]byte)
+	}
+	extraData[hashString("fdo_audit_anchor")] = valueBytes
+	return extraData, nil
+}
+
+// checkpointFileSink writes <directory>/checkpoint-<event id>.json and its base64 signature as .sig
+type checkpointFileSink struct {
+	directory string
+	signer    crypto.Signer
+}
+
+func (s *checkpointFileSink) anchor(ctx context.Context, checkpoint *AuditCheckpoint, body []byte) (string, error) {
+	signature, err := signBundle(s.signer, body)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(s.directory, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create checkpoint directory: %w", err)
+	}
+	path := filepath.Join(s.directory, fmt.Sprintf("checkpoint-%010d.json", checkpoint.EventID))
+	if err := os.WriteFile(path, body, 0o644); err != nil {
+		return "", fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	if err := os.WriteFile(path+".sig", []byte(signature+"\n"), 0o644); err != nil {
+		return "", fmt.Errorf("failed to write checkpoint signature: %w", err)
+	}
+	return path, nil
+}
+
+// transparencyLogSink POSTs the checkpoint to an external append-only log and
+// keeps its response, e.g. an entry ID, as the reference
+type transparencyLogSink struct {
+	config *AuditTransparencyLogConfig
+	client *http.Client
+}
+
+func (s *transparencyLogSink) anchor(ctx context.Context, checkpoint *AuditCheckpoint, body []byte) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range s.config.Headers {
+		req.Header.Set(name, value)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	reply, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode/100 != 2 {
+		return "", fmt.Errorf("%s returned HTTP %d", s.config.URL, resp.StatusCode)
+	}
+	reference := strings.TrimSpace(string(reply))
+	if len(reference) > 512 {
+		reference = reference[:512]
+	}
+	return reference, nil
+}
+
+// voucherAnchorSink makes the checkpoint the one stamped into vouchers
+type voucherAnchorSink struct {
+	owner *AuditAnchor
+}
+
+func (s *voucherAnchorSink) anchor(ctx context.Context, checkpoint *AuditCheckpoint, body []byte) (string, error) {
+	s.owner.mu.Lock()
+	s.owner.voucher = checkpoint
+	s.owner.mu.Unlock()
+	return "vouchers built from now on", nil
+}
+
+// AuditVerification is the result of re-hashing the audit chain and checking
+// it against the recorded anchors, served by GET /api/audit/verify
+type AuditVerification struct {
+	CheckedAt time.Time          `json:"checked_at"`
+	Events    int                `json:"events"`
+	Chained   int                `json:"chained"` // Events with a chain hash; older ones predate the chain
+	HeadID    int64              `json:"head_id"`
+	HeadHash  string             `json:"head_hash"`
+	Intact    bool               `json:"intact"`
+	BrokenAt  int64              `json:"broken_at,omitempty"` // First event whose chain hash doesn't match its content
+	Anchors   []AuditAnchorCheck `json:"anchors"`
+}
+
+// AuditAnchorCheck is one recorded anchor and whether the chain still matches it
+type AuditAnchorCheck struct {
+	AuditAnchorRecord
+	Matches bool `json:"matches"`
+}
+
+// Verify re-hashes the audit chain from its first chained event and checks
+// the successful anchors against it. Edited, inserted or removed events break
+// the chain; a chain rewritten consistently no longer matches its anchors,
+// which is what the copies outside the station are for.
+func (a *AuditLog) Verify(ctx context.Context) (*AuditVerification, error) {
+	v := &AuditVerification{CheckedAt: stationClock.Now().UTC(), Anchors: []AuditAnchorCheck{}}
+	if a == nil {
+		v.Intact = true
+		return v, nil
+	}
+
+	anchors := map[int64][]int{} // Event ID -> indexes in v.Anchors
+	rows, err := a.db.db.QueryContext(ctx, `
+	SELECT id, created_at, event_id, chain_hash, sink, reference, error FROM audit_anchors WHERE error = '' ORDER BY id`)
+	if err != nil && !strings.Contains(err.Error(), "no such table") {
+		return nil, fmt.Errorf("failed to read audit anchors: %w", err)
+	}
+	if err == nil {
+		for rows.Next() {
+			var c AuditAnchorCheck
+			var createdAt int64
+			if err := rows.Scan(&c.ID, &createdAt, &c.EventID, &c.ChainHash, &c.Sink, &c.Reference, &c.Error); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to read audit anchor: %w", err)
+			}
+			c.CreatedAt = time.Unix(createdAt, 0).UTC()
+			anchors[c.EventID] = append(anchors[c.EventID], len(v.Anchors))
+			v.Anchors = append(v.Anchors, c)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	rows, err = a.db.db.QueryContext(ctx, `
+	SELECT id, time, event, COALESCE(serial, ''), COALESCE(guid, ''), COALESCE(customer, ''), COALESCE(model, ''), COALESCE(detail, ''),
+		COALESCE(site_code, ''), COALESCE(line_id, ''), COALESCE(station_id, ''), COALESCE(chain_hash, '')
+	FROM audit_events ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit events: %w", err)
+	}
+	defer rows.Close()
+	var prev string
+	for rows.Next() {
+		var e AuditEvent
+		var t int64
+		if err := rows.Scan(&e.ID, &t, &e.Event, &e.Serial, &e.GUID, &e.Customer, &e.Model, &e.Detail,
+			&e.Site, &e.Line, &e.Station, &e.ChainHash); err != nil {
+			return nil, fmt.Errorf("failed to read audit event: %w", err)
+		}
+		e.Time = time.Unix(t, 0)
+		v.Events++
+		v.HeadID = e.ID
+		if e.ChainHash == "" && v.Chained == 0 {
+			continue // Recorded before the chain was introduced
+		}
+		v.Chained++
+		want := auditChainHash(prev, e)
+		if e.ChainHash != want && v.BrokenAt == 0 {
+			v.BrokenAt = e.ID
+		}
+		for _, i := range anchors[e.ID] {
+			v.Anchors[i].Matches = v.Anchors[i].ChainHash == want
+		}
+		prev = want
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	v.HeadHash = prev
+	v.Intact = v.BrokenAt == 0 && !slices.ContainsFunc(v.Anchors, func(c AuditAnchorCheck) bool { return !c.Matches })
+	return v, nil
+}
+
+// VerifyHandler serves GET /api/audit/verify
+func (a *AuditLog) VerifyHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		v, err := a.Verify(r.Context())
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, v)
+	})
+}
+
+// Handler serves POST /api/audit/anchor, which anchors the head of the chain now
+func (a *AuditAnchor) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if a == nil {
+			writeJSONError(w, http.StatusNotFound, "audit anchoring is disabled")
+			return
+		}
+		records, err := a.Anchor(r.Context())
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if records == nil {
+			records = []AuditAnchorRecord{}
+		}
+		writeJSON(w, http.StatusOK, records)
+	})
+}
+
+// validateAuditAnchor checks the sinks of audit_anchor
+func validateAuditAnchor(config *AuditAnchorConfig) error {
+	if !config.Enabled {
+		return nil
+	}
+	if len(config.Sinks) == 0 {
+		return fmt.Errorf("audit_anchor.sinks: name at least one of checkpoint_file, transparency_log, voucher")
+	}
+	for i, sink := range config.Sinks {
+		switch sink {
+		case AuditSinkCheckpointFile, AuditSinkVoucher:
+		case AuditSinkTransparencyLog:
+			if config.TransparencyLog.URL == "" {
+				return fmt.Errorf("audit_anchor.transparency_log.url is required for the transparency_log sink")
+			}
+		default:
+			return fmt.Errorf("audit_anchor.sinks: unknown sink %q", sink)
+		}
+		if slices.Contains(config.Sinks[:i], sink) {
+			return fmt.Errorf("audit_anchor.sinks: %q listed twice", sink)
+		}
+	}
+	return nil
+}
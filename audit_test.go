@@ -0,0 +1,57 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// TestAuditChainTwoInstances checks that two AuditLog instances on one
+// station database, like the server and a CLI command, don't fork the chain
+func TestAuditChainTwoInstances(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "station.db")
+	var logs []*AuditLog
+	for _, stationID := range []string{"server", "cli"} {
+		db, err := OpenStationDB(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+		auditLog := NewAuditLog(db, &StationConfig{StationID: stationID})
+		if err := auditLog.Initialize(ctx); err != nil {
+			t.Fatal(err)
+		}
+		logs = append(logs, auditLog)
+	}
+
+	const perInstance = 50
+	var wg sync.WaitGroup
+	for _, auditLog := range logs {
+		wg.Add(1)
+		go func(auditLog *AuditLog) {
+			defer wg.Done()
+			for i := 0; i < perInstance; i++ {
+				if err := auditLog.insert(ctx, AuditEvent{Event: "test_event", Detail: fmt.Sprint(i), Station: auditLog.station.StationID}); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(auditLog)
+	}
+	wg.Wait()
+
+	v, err := logs[0].Verify(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Events != 2*perInstance || !v.Intact {
+		t.Errorf("chain of %d events intact=%v broken at %d, want %d intact events", v.Events, v.Intact, v.BrokenAt, 2*perInstance)
+	}
+}
@@ -0,0 +1,184 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Backpressure defaults
+const (
+	defaultBackpressureRetryAfter = 10 * time.Second
+	backpressureCheckInterval     = time.Second // Queue depths are read at most this often
+)
+
+// backpressureCommands are the external commands whose queues hold devices back
+var backpressureCommands = []string{CommandVoucherSign, CommandVoucherUpload}
+
+// Backpressure tells line fixtures to hold off starting devices while the
+// station's queues are backed up, instead of letting sessions time out at
+// random. While the batch upload queue or the queue of the signing or upload
+// command is over its threshold, DI.AppStart is answered with 503 and a
+// Retry-After header; sessions already past AppStart finish normally. It is
+// released once every queue drains below three quarters of its threshold, so
+// the line doesn't flap at the limit.
+type Backpressure struct {
+	config   *BackpressureConfig
+	batcher  *VoucherBatchUploader
+	pools    *CommandPools
+	auditLog *AuditLog
+
+	mu        sync.Mutex
+	state     BackpressureState
+	checkedAt time.Time
+}
+
+// BackpressureState is served by GET /api/backpressure
+type BackpressureState struct {
+	Engaged           bool           `json:"engaged"`
+	Since             *time.Time     `json:"since,omitempty"` // When it was last engaged or released
+	Reasons           []string       `json:"reasons"`
+	RetryAfterSeconds int            `json:"retry_after_seconds"`
+	UploadQueueDepth  int            `json:"upload_queue_depth"`
+	UploadQueueLimit  int            `json:"upload_queue_limit"`  // 0 = not checked
+	CommandQueued     map[string]int `json:"command_queued"`      // Calls waiting for a slot, by command
+	CommandQueueLimit int            `json:"command_queue_limit"` // 0 = not checked
+	Refused           uint64         `json:"refused"`             // DI starts refused since the station started
+}
+
+// NewBackpressure creates the DI backpressure gate, or nil if it is disabled
+func NewBackpressure(config *BackpressureConfig, batcher *VoucherBatchUploader, pools *CommandPools, auditLog *AuditLog) *Backpressure {
+	if !config.Enabled {
+		return nil
+	}
+	return &Backpressure{config: config, batcher: batcher, pools: pools, auditLog: auditLog}
+}
+
+// retryAfter returns the wait suggested to fixtures
+func (b *Backpressure) retryAfter() time.Duration {
+	if b.config.RetryAfter > 0 {
+		return b.config.RetryAfter
+	}
+	return defaultBackpressureRetryAfter
+}
+
+// Check reads the queue depths, if they haven't been read in the last second,
+// and returns the current state. A nil *Backpressure is never engaged.
+func (b *Backpressure) Check(ctx context.Context) BackpressureState {
+	if b == nil {
+		return BackpressureState{Reasons: []string{}, CommandQueued: map[string]int{}}
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	if now.Sub(b.checkedAt) < backpressureCheckInterval {
+		return b.snapshot()
+	}
+	b.checkedAt = now
+
+	depth, err := b.batcher.QueueDepth(ctx)
+	if err != nil {
+		fmt.Printf("⚠️  Backpressure: %v\n", err)
+	}
+	queued := map[string]int{}
+	for _, stats := range b.pools.Stats() {
+		for _, command := range backpressureCommands {
+			if stats.Command == command {
+				queued[command] = stats.Queued
+			}
+		}
+	}
+
+	// Engage at the threshold; once engaged, hold until below three quarters of it
+	over := func(value, limit int) bool {
+		if limit <= 0 {
+			return false
+		}
+		if b.state.Engaged {
+			return value >= int(math.Ceil(float64(limit)*0.75))
+		}
+		return value >= limit
+	}
+	var reasons []string
+	if over(depth, b.config.UploadQueueDepth) {
+		reasons = append(reasons, fmt.Sprintf("%d vouchers waiting for upload (limit %d)", depth, b.config.UploadQueueDepth))
+	}
+	for _, command := range backpressureCommands {
+		if over(queued[command], b.config.CommandQueue) {
+			reasons = append(reasons, fmt.Sprintf("%d %s calls queued (limit %d)", queued[command], command, b.config.CommandQueue))
+		}
+	}
+
+	engaged := len(reasons) > 0
+	if engaged != b.state.Engaged {
+		b.state.Since = &now
+		event, detail := "backpressure_released", "queues drained"
+		if engaged {
+			event, detail = "backpressure_engaged", strings.Join(reasons, "; ")
+			fmt.Printf("🚦 Backpressure engaged, fixtures told to retry after %s: %s\n", b.retryAfter(), detail)
+		} else {
+			fmt.Printf("✅ Backpressure released\n")
+		}
+		b.auditLog.Record(context.Background(), AuditEvent{Event: event, Detail: detail})
+	}
+	b.state.Engaged = engaged
+	b.state.Reasons = reasons
+	b.state.UploadQueueDepth = depth
+	b.state.CommandQueued = queued
+	return b.snapshot()
+}
+
+// snapshot copies the state with the configured limits; b.mu must be held
+func (b *Backpressure) snapshot() BackpressureState {
+	state := b.state
+	state.Reasons = append([]string{}, b.state.Reasons...)
+	state.RetryAfterSeconds = int(math.Ceil(b.retryAfter().Seconds()))
+	state.UploadQueueLimit = b.config.UploadQueueDepth
+	state.CommandQueueLimit = b.config.CommandQueue
+	if state.CommandQueued == nil {
+		state.CommandQueued = map[string]int{}
+	}
+	return state
+}
+
+// Middleware answers DI.AppStart with 503 and Retry-After while backpressure
+// is engaged; the FDO error message carries the same wait for fixtures that
+// only read the protocol response
+func (b *Backpressure) Middleware(next http.Handler) http.Handler {
+	if b == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("msg") != "10" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		state := b.Check(r.Context())
+		if !state.Engaged {
+			next.ServeHTTP(w, r)
+			return
+		}
+		b.mu.Lock()
+		b.state.Refused++
+		b.mu.Unlock()
+		w.Header().Set("Retry-After", strconv.Itoa(state.RetryAfterSeconds))
+		writeFDOErrorStatus(w, http.StatusServiceUnavailable, r.PathValue("msg"),
+			fmt.Sprintf("station busy, retry after %ds: %s", state.RetryAfterSeconds, strings.Join(state.Reasons, "; ")))
+	})
+}
+
+// Handler serves GET /api/backpressure
+func (b *Backpressure) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, b.Check(r.Context()))
+	})
+}
@@ -0,0 +1,579 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ErrNoOpenBatch is returned for DI attempts while batches are required and none is open
+var ErrNoOpenBatch = errors.New("no manufacturing batch is open")
+
+// ErrBatchNotOpen is returned for closing a batch that is already closed or doesn't exist
+var ErrBatchNotOpen = errors.New("is not open")
+
+// Batch is a production run of one lot. At most one batch is open at a time;
+// every voucher built while it is open is linked to it.
+type Batch struct {
+	ID           string     `json:"id"`
+	LotNumber    string     `json:"lot_number"`
+	Profile      string     `json:"profile,omitempty"`
+	OperatorID   string     `json:"operator_id,omitempty"`
+	Status       string     `json:"status"` // "open" | "closed" | "expired"
+	OpenedAt     time.Time  `json:"opened_at"`
+	ClosedAt     *time.Time `json:"closed_at,omitempty"`
+	VoucherCount int        `json:"voucher_count"`
+}
+
+// BatchVoucher links a voucher to the batch it was built in
+type BatchVoucher struct {
+	GUID      string    `json:"guid"`
+	Serial    string    `json:"serial"`
+	Model     string    `json:"model"`
+	Customer  string    `json:"customer,omitempty"`
+	BatchID   string    `json:"batch_id"`
+	LotNumber string    `json:"lot_number"`
+	Hash      string    `json:"voucher_sha256,omitempty"` // SHA-256 of the final CBOR voucher
+	CreatedAt time.Time `json:"created_at"`
+
+	ReceiptVerification string `json:"receipt_verification,omitempty"` // Owner signature check of the upload receipt; empty = not uploaded
+}
+
+// LotReport summarizes every batch produced under one lot number
+type LotReport struct {
+	LotNumber    string    `json:"lot_number"`
+	Batches      []Batch   `json:"batches"`
+	VoucherCount int       `json:"voucher_count"`
+	Models       []string  `json:"models"`
+	FirstVoucher time.Time `json:"first_voucher,omitzero"`
+	LastVoucher  time.Time `json:"last_voucher,omitzero"`
+}
+
+// OpenBatchRequest is the body of POST /api/batches
+type OpenBatchRequest struct {
+	LotNumber string `json:"lot_number"`
+	Profile   string `json:"profile"`
+	OperatorCredentials
+}
+
+// BatchService stores batches and their vouchers in the station database
+type BatchService struct {
+	config       *BatchConfig
+	db           *StationDB
+	operatorGate *OperatorGate
+	auditLog     *AuditLog
+}
+
+// NewBatchService creates a new batch service
+func NewBatchService(config *BatchConfig, db *StationDB, operatorGate *OperatorGate, auditLog *AuditLog) *BatchService {
+	return &BatchService{
+		config:       config,
+		db:           db,
+		operatorGate: operatorGate,
+		auditLog:     auditLog,
+	}
+}
+
+// Initialize creates the batches and batch_vouchers tables if they don't exist
+func (b *BatchService) Initialize(ctx context.Context) error {
+	if _, err := b.db.db.ExecContext(ctx, `
+	CREATE TABLE IF NOT EXISTS batches (
+		id TEXT PRIMARY KEY,
+		lot_number TEXT NOT NULL,
+		profile TEXT,
+		operator_id TEXT,
+		status TEXT NOT NULL,
+		opened_at INTEGER NOT NULL,
+		closed_at INTEGER
+	)`); err != nil {
+		return fmt.Errorf("failed to create batches table: %w", err)
+	}
+	if _, err := b.db.db.ExecContext(ctx, `
+	CREATE TABLE IF NOT EXISTS batch_vouchers (
+		guid TEXT PRIMARY KEY,
+		batch_id TEXT NOT NULL REFERENCES batches(id),
+		serial TEXT NOT NULL,
+		model TEXT NOT NULL,
+		customer TEXT,
+		created_at INTEGER NOT NULL
+	)`); err != nil {
+		return fmt.Errorf("failed to create batch_vouchers table: %w", err)
+	}
+	if _, err := b.db.db.ExecContext(ctx,
+		`CREATE INDEX IF NOT EXISTS batch_vouchers_batch ON batch_vouchers (batch_id)`); err != nil {
+		return fmt.Errorf("failed to create batch_vouchers index: %w", err)
+	}
+	return b.db.addColumnIfMissing(ctx, "batch_vouchers", "voucher_hash", "TEXT")
+}
+
+// required reports whether DI needs an open batch
+func (b *BatchService) required() bool {
+	return b.config.Required || b.operatorGate != nil
+}
+
+// Open authenticates the operator (if the operator gate is enabled) and opens a
+// new batch, closing the batch that was open before
+func (b *BatchService) Open(ctx context.Context, req OpenBatchRequest) (*Batch, error) {
+	if req.LotNumber == "" {
+		return nil, fmt.Errorf("lot_number is required")
+	}
+	if err := b.operatorGate.Authenticate(ctx, req.OperatorCredentials); err != nil {
+		return nil, err
+	}
+
+	id, err := newUUID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate batch ID: %w", err)
+	}
+	now := stationClock.Now()
+
+	tx, err := b.db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin batch transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var previous string
+	err = tx.QueryRowContext(ctx, `SELECT id FROM batches WHERE status = 'open'`).Scan(&previous)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to read open batch: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE batches SET status = 'closed', closed_at = ? WHERE status = 'open'`, now.Unix()); err != nil {
+		return nil, fmt.Errorf("failed to close open batch: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+	INSERT INTO batches (id, lot_number, profile, operator_id, status, opened_at)
+	VALUES (?, ?, ?, ?, 'open', ?)`,
+		id, req.LotNumber, req.Profile, req.OperatorID, now.Unix()); err != nil {
+		return nil, fmt.Errorf("failed to create batch: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit batch: %w", err)
+	}
+
+	if previous != "" {
+		b.auditLog.Record(ctx, AuditEvent{Event: "batch_closed", Detail: fmt.Sprintf("batch %s closed by opening batch %s", previous, id)})
+	}
+	b.auditLog.Record(ctx, AuditEvent{
+		Event:  "batch_opened",
+		Detail: fmt.Sprintf("batch %s lot %s profile %q operator %q", id, req.LotNumber, req.Profile, req.OperatorID),
+	})
+	return b.Get(ctx, id)
+}
+
+// Close closes a batch; closing a batch that is not open is an error
+func (b *BatchService) Close(ctx context.Context, id string) (*Batch, error) {
+	if err := b.closeBatch(ctx, id, "closed"); err != nil {
+		return nil, err
+	}
+	b.auditLog.Record(ctx, AuditEvent{Event: "batch_closed", Detail: fmt.Sprintf("batch %s", id)})
+	return b.Get(ctx, id)
+}
+
+func (b *BatchService) closeBatch(ctx context.Context, id, status string) error {
+	result, err := b.db.db.ExecContext(ctx,
+		`UPDATE batches SET status = ?, closed_at = ? WHERE id = ? AND status = 'open'`, status, stationClock.Now().Unix(), id)
+	if err != nil {
+		return fmt.Errorf("failed to close batch %s: %w", id, err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("batch %s %w", id, ErrBatchNotOpen)
+	}
+	return nil
+}
+
+// Current returns the open batch. With no open batch it returns ErrNoOpenBatch
+// if batches are required, or nil otherwise. Batches open longer than
+// max_duration are closed as "expired".
+func (b *BatchService) Current(ctx context.Context) (*Batch, error) {
+	if b == nil {
+		return nil, nil
+	}
+	var id string
+	var openedAt int64
+	err := b.db.db.QueryRowContext(ctx,
+		`SELECT id, opened_at FROM batches WHERE status = 'open'`).Scan(&id, &openedAt)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to read open batch: %w", err)
+	}
+
+	if id != "" && b.config.MaxDuration > 0 && stationClock.Now().Sub(time.Unix(openedAt, 0)) > b.config.MaxDuration {
+		if err := b.closeBatch(ctx, id, "expired"); err == nil {
+			b.auditLog.Record(ctx, AuditEvent{Event: "batch_expired", Detail: fmt.Sprintf("batch %s open longer than %s", id, b.config.MaxDuration)})
+		}
+		id = ""
+	}
+
+	if id == "" {
+		if b.required() {
+			return nil, ErrNoOpenBatch
+		}
+		return nil, nil
+	}
+	return b.Get(ctx, id)
+}
+
+// RecordVoucher links a voucher to a batch
+func (b *BatchService) RecordVoucher(ctx context.Context, batch *Batch, guid, serial, model, customer, hash string) error {
+	if batch == nil {
+		return nil
+	}
+	if _, err := b.db.db.ExecContext(ctx, `
+	INSERT OR REPLACE INTO batch_vouchers (guid, batch_id, serial, model, customer, voucher_hash, created_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		guid, batch.ID, serial, model, customer, hash, stationClock.Now().Unix()); err != nil {
+		return fmt.Errorf("failed to link voucher %s to batch %s: %w", guid, batch.ID, err)
+	}
+	return nil
+}
+
+// Get returns a batch by ID, or nil if it doesn't exist
+func (b *BatchService) Get(ctx context.Context, id string) (*Batch, error) {
+	batches, err := b.queryBatches(ctx, `WHERE b.id = ?`, id)
+	if err != nil || len(batches) == 0 {
+		return nil, err
+	}
+	return &batches[0], nil
+}
+
+// List returns batches, newest first, optionally only those of one lot
+func (b *BatchService) List(ctx context.Context, lot string) ([]Batch, error) {
+	return b.queryBatches(ctx, `WHERE ? = '' OR b.lot_number = ? ORDER BY b.opened_at DESC`, lot, lot)
+}
+
+// batchListSpec is the sort and filter spec of GET /api/batches
+var batchListSpec = listSpec{
+	Key:         "b.id",
+	Sorts:       map[string]string{"opened_at": "b.opened_at", "id": "b.id", "lot": "b.lot_number", "status": "b.status"},
+	DefaultSort: "-opened_at",
+	Filters:     map[string]string{"lot": "b.lot_number", "status": "b.status", "profile": "b.profile", "operator_id": "b.operator_id"},
+}
+
+// Page returns one page of batches
+func (b *BatchService) Page(ctx context.Context, q *listQuery) ([]Batch, string, error) {
+	clause, args := q.sql()
+	batches, err := b.queryBatches(ctx, clause, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	batches, next := listPage(q, batches, func(batch Batch, column string) any {
+		switch column {
+		case "b.opened_at":
+			return batch.OpenedAt.Unix()
+		case "b.lot_number":
+			return batch.LotNumber
+		case "b.status":
+			return batch.Status
+		default:
+			return batch.ID
+		}
+	})
+	return batches, next, nil
+}
+
+// queryBatches returns the batches selected by clause (WHERE, ORDER BY and LIMIT)
+func (b *BatchService) queryBatches(ctx context.Context, clause string, args ...any) ([]Batch, error) {
+	rows, err := b.db.db.QueryContext(ctx, `
+	SELECT b.id, b.lot_number, COALESCE(b.profile, ''), COALESCE(b.operator_id, ''), b.status, b.opened_at, b.closed_at,
+		(SELECT COUNT(*) FROM batch_vouchers v WHERE v.batch_id = b.id)
+	FROM batches b `+clause, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query batches: %w", err)
+	}
+	defer rows.Close()
+
+	batches := []Batch{}
+	for rows.Next() {
+		var batch Batch
+		var openedAt int64
+		var closedAt sql.NullInt64
+		if err := rows.Scan(&batch.ID, &batch.LotNumber, &batch.Profile, &batch.OperatorID, &batch.Status,
+			&openedAt, &closedAt, &batch.VoucherCount); err != nil {
+			return nil, fmt.Errorf("failed to read batch: %w", err)
+		}
+		batch.OpenedAt = time.Unix(openedAt, 0)
+		if closedAt.Valid {
+			t := time.Unix(closedAt.Int64, 0)
+			batch.ClosedAt = &t
+		}
+		batches = append(batches, batch)
+	}
+	return batches, rows.Err()
+}
+
+// Vouchers returns the vouchers of one batch (batchID) or of every batch in a lot (lot)
+func (b *BatchService) Vouchers(ctx context.Context, batchID, lot string) ([]BatchVoucher, error) {
+	return b.queryVouchers(ctx, `WHERE (? = '' OR v.batch_id = ?) AND (? = '' OR b.lot_number = ?)
+	ORDER BY v.created_at, v.guid`, batchID, batchID, lot, lot)
+}
+
+// voucherListSpec is the sort and filter spec of the voucher list endpoints
+var voucherListSpec = listSpec{
+	Key:         "v.guid",
+	Sorts:       map[string]string{"created_at": "v.created_at", "guid": "v.guid", "serial": "v.serial", "model": "v.model"},
+	DefaultSort: "created_at",
+	Filters: map[string]string{
+		"guid": "v.guid", "serial": "v.serial", "model": "v.model", "customer": "v.customer", "batch_id": "v.batch_id", "lot": "b.lot_number", "voucher_sha256": "v.voucher_hash",
+		"receipt_verification": "r.verification",
+	},
+}
+
+// VoucherPage returns one page of batch vouchers
+func (b *BatchService) VoucherPage(ctx context.Context, q *listQuery) ([]BatchVoucher, string, error) {
+	clause, args := q.sql()
+	vouchers, err := b.queryVouchers(ctx, clause, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	vouchers, next := listPage(q, vouchers, func(v BatchVoucher, column string) any {
+		switch column {
+		case "v.created_at":
+			return v.CreatedAt.Unix()
+		case "v.serial":
+			return v.Serial
+		case "v.model":
+			return v.Model
+		default:
+			return v.GUID
+		}
+	})
+	return vouchers, next, nil
+}
+
+// queryVouchers returns the batch vouchers selected by clause (WHERE, ORDER BY and LIMIT)
+func (b *BatchService) queryVouchers(ctx context.Context, clause string, args ...any) ([]BatchVoucher, error) {
+	rows, err := b.db.db.QueryContext(ctx, `
+	SELECT v.guid, v.serial, v.model, COALESCE(v.customer, ''), v.batch_id, b.lot_number, COALESCE(v.voucher_hash, ''), v.created_at,
+		COALESCE(r.verification, '')
+	FROM batch_vouchers v JOIN batches b ON b.id = v.batch_id
+	LEFT JOIN voucher_upload_receipts r ON r.guid = v.guid `+clause, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query batch vouchers: %w", err)
+	}
+	defer rows.Close()
+
+	vouchers := []BatchVoucher{}
+	for rows.Next() {
+		var v BatchVoucher
+		var createdAt int64
+		if err := rows.Scan(&v.GUID, &v.Serial, &v.Model, &v.Customer, &v.BatchID, &v.LotNumber, &v.Hash, &createdAt, &v.ReceiptVerification); err != nil {
+			return nil, fmt.Errorf("failed to read batch voucher: %w", err)
+		}
+		v.CreatedAt = time.Unix(createdAt, 0)
+		vouchers = append(vouchers, v)
+	}
+	return vouchers, rows.Err()
+}
+
+// LotReport summarizes a lot, or returns nil if no batch used the lot number
+func (b *BatchService) LotReport(ctx context.Context, lot string) (*LotReport, error) {
+	batches, err := b.List(ctx, lot)
+	if err != nil || len(batches) == 0 {
+		return nil, err
+	}
+	vouchers, err := b.Vouchers(ctx, "", lot)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &LotReport{LotNumber: lot, Batches: batches, VoucherCount: len(vouchers), Models: []string{}}
+	seen := make(map[string]bool)
+	for _, v := range vouchers {
+		if !seen[v.Model] {
+			seen[v.Model] = true
+			report.Models = append(report.Models, v.Model)
+		}
+		if report.FirstVoucher.IsZero() || v.CreatedAt.Before(report.FirstVoucher) {
+			report.FirstVoucher = v.CreatedAt
+		}
+		if v.CreatedAt.After(report.LastVoucher) {
+			report.LastVoucher = v.CreatedAt
+		}
+	}
+	return report, nil
+}
+
+// OpenHandler serves POST /api/batches
+func (b *BatchService) OpenHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req OpenBatchRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeOperatorError(w, r, http.StatusBadRequest, fmt.Sprintf("invalid request: %v", err), "request.invalid", "detail", err.Error())
+			return
+		}
+		if req.LotNumber == "" {
+			writeOperatorError(w, r, http.StatusBadRequest, "lot_number is required", "batch.lot_required")
+			return
+		}
+		batch, err := b.Open(r.Context(), req)
+		if errors.Is(err, ErrOperatorAuth) {
+			// Don't tell the client which part of the credentials was wrong
+			writeOperatorError(w, r, http.StatusUnauthorized, ErrOperatorAuth.Error(), "operator.auth_failed")
+			return
+		}
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusCreated, batch)
+	})
+}
+
+// CloseHandler serves POST /api/batches/{id}/close
+func (b *BatchService) CloseHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		batch, err := b.Close(r.Context(), r.PathValue("id"))
+		if errors.Is(err, ErrBatchNotOpen) {
+			writeOperatorError(w, r, http.StatusConflict, err.Error(), "batch.not_open", "batch", r.PathValue("id"))
+			return
+		}
+		if err != nil {
+			writeJSONError(w, http.StatusConflict, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, batch)
+	})
+}
+
+// CurrentHandler serves GET /api/batches/current
+func (b *BatchService) CurrentHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		batch, err := b.Current(r.Context())
+		if err != nil && !errors.Is(err, ErrNoOpenBatch) {
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if batch == nil {
+			writeOperatorError(w, r, http.StatusNotFound, ErrNoOpenBatch.Error(), "batch.none_open")
+			return
+		}
+		writeJSON(w, http.StatusOK, batch)
+	})
+}
+
+// ListHandler serves GET /api/batches with the list parameters of batchListSpec
+func (b *BatchService) ListHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q, err := parseListQuery(r, &batchListSpec)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		batches, next, err := b.Page(r.Context(), q)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSONList(w, r, batches, next)
+	})
+}
+
+// GetHandler serves GET /api/batches/{id}
+func (b *BatchService) GetHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		batch, err := b.Get(r.Context(), r.PathValue("id"))
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if batch == nil {
+			writeOperatorError(w, r, http.StatusNotFound, "batch not found", "batch.not_found")
+			return
+		}
+		writeJSON(w, http.StatusOK, batch)
+	})
+}
+
+// VouchersHandler serves GET /api/vouchers with the list parameters of voucherListSpec
+func (b *BatchService) VouchersHandler() http.Handler {
+	return b.voucherListHandler(func(q *listQuery) {})
+}
+
+// BatchVouchersHandler serves GET /api/batches/{id}/vouchers?format=json|csv
+func (b *BatchService) BatchVouchersHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		if r.URL.Query().Get("format") == "csv" {
+			b.exportVouchers(w, r, "batch-"+id, id, "")
+			return
+		}
+		b.voucherListHandler(func(q *listQuery) { q.filter("v.batch_id", id) }).ServeHTTP(w, r)
+	})
+}
+
+// LotHandler serves GET /api/lots/{lot}
+func (b *BatchService) LotHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report, err := b.LotReport(r.Context(), r.PathValue("lot"))
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if report == nil {
+			writeOperatorError(w, r, http.StatusNotFound, "lot not found", "batch.lot_not_found")
+			return
+		}
+		writeJSON(w, http.StatusOK, report)
+	})
+}
+
+// LotVouchersHandler serves GET /api/lots/{lot}/vouchers?format=json|csv
+func (b *BatchService) LotVouchersHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lot := r.PathValue("lot")
+		if r.URL.Query().Get("format") == "csv" {
+			b.exportVouchers(w, r, "lot-"+lot, "", lot)
+			return
+		}
+		b.voucherListHandler(func(q *listQuery) { q.filter("b.lot_number", lot) }).ServeHTTP(w, r)
+	})
+}
+
+// voucherListHandler serves one page of vouchers; scope adds the endpoint's fixed filters
+func (b *BatchService) voucherListHandler(scope func(q *listQuery)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q, err := parseListQuery(r, &voucherListSpec)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		scope(q)
+		vouchers, next, err := b.VoucherPage(r.Context(), q)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSONList(w, r, vouchers, next)
+	})
+}
+
+// exportVouchers writes every voucher of a batch or lot as CSV; exports are not paginated
+func (b *BatchService) exportVouchers(w http.ResponseWriter, r *http.Request, name, batchID, lot string) {
+	vouchers, err := b.Vouchers(r.Context(), batchID, lot)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+".csv"))
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"guid", "serial", "model", "customer", "batch_id", "lot_number", "created_at", "voucher_sha256"})
+	for _, v := range vouchers {
+		_ = cw.Write([]string{v.GUID, v.Serial, v.Model, v.Customer, v.BatchID, v.LotNumber, v.CreatedAt.UTC().Format(time.RFC3339), v.Hash})
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		fmt.Printf("⚠️  Failed to write voucher export: %v\n", err)
+	}
+}
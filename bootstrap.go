@@ -0,0 +1,473 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/x509"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// BootstrapAnswers are the questions "bootstrap" asks. An answers file gives
+// them in this form, so a new station can be set up unattended; anything it
+// leaves out takes the default.
+type BootstrapAnswers struct {
+	StationID string `yaml:"station_id"`
+	SiteCode  string `yaml:"site_code"`
+	LineID    string `yaml:"line_id"`
+	Addr      string `yaml:"addr"`
+	ExtAddr   string `yaml:"ext_addr"`
+	Database  string `yaml:"database"`
+
+	// Who signs vouchers over
+	KeyBackend                string `yaml:"key_backend"`                  // "internal" | "hsm" | "external"
+	OwnerKeyType              string `yaml:"owner_key_type"`               // internal: key type of the station's owner key
+	SigningCommand            string `yaml:"signing_command"`              // hsm/external: voucher_signing.external_command
+	ManufacturerPublicKeyFile string `yaml:"manufacturer_public_key_file"` // external: PEM public key of the HSM's manufacturer key
+
+	// Rendezvous servers as URLs, e.g. "https://rv.example.com:8041"
+	Rendezvous []string `yaml:"rendezvous"`
+
+	// Who vouchers are signed over to
+	Owner        string `yaml:"owner"`          // "none" | "static" | "did" | "dynamic"
+	OwnerKeyFile string `yaml:"owner_key_file"` // static: PEM public key or certificate chain
+	OwnerDID     string `yaml:"owner_did"`      // did: the owner's DID
+	OwnerCommand string `yaml:"owner_command"`  // dynamic: owner key command
+
+	// Where vouchers go
+	Upload        string `yaml:"upload"`         // "none" | "http" | "command"
+	UploadURL     string `yaml:"upload_url"`     // http: voucher recipient
+	UploadCommand string `yaml:"upload_command"` // command: upload command
+	SaveDirectory string `yaml:"save_directory"` // Also save vouchers here (empty = don't)
+}
+
+// defaultBootstrapAnswers are the answers a station gets out of the box
+func defaultBootstrapAnswers() *BootstrapAnswers {
+	defaults := DefaultConfig()
+	return &BootstrapAnswers{
+		StationID:    defaults.Station.StationID,
+		Addr:         defaults.Server.Addr,
+		Database:     defaults.Database.Path,
+		KeyBackend:   defaults.VoucherManagement.VoucherSigning.Mode,
+		OwnerKeyType: defaults.VoucherManagement.VoucherSigning.OwnerKeyType,
+		Owner:        "none",
+		Upload:       "none",
+	}
+}
+
+// runBootstrap implements "bootstrap [-answers file] [-out file] [-force]
+// [-skip-checks]": it asks for the station's key backend, rendezvous servers,
+// owner signover and voucher upload, or reads them from an answers file,
+// checks that what they name is reachable, and writes a complete config
+func runBootstrap(args []string) error {
+	fs := flag.NewFlagSet("bootstrap", flag.ContinueOnError)
+	answersFile := fs.String("answers", "", "YAML answers file; without one the questions are asked interactively")
+	out := fs.String("out", *configPath, "Config file to write")
+	force := fs.Bool("force", false, "Overwrite an existing config file")
+	skipChecks := fs.Bool("skip-checks", false, "Write the config even if rendezvous, owner or upload checks fail")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf("usage: bootstrap [-answers file] [-out file] [-force] [-skip-checks]")
+	}
+	if _, err := os.Stat(*out); err == nil && !*force {
+		return fmt.Errorf("%s already exists; use -force to overwrite it", *out)
+	}
+
+	answers := defaultBootstrapAnswers()
+	if *answersFile != "" {
+		data, err := os.ReadFile(*answersFile)
+		if err != nil {
+			return fmt.Errorf("failed to read answers file: %w", err)
+		}
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(answers); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("failed to parse answers file %s: %w", *answersFile, err)
+		}
+	} else if err := askBootstrapQuestions(answers, bufio.NewReader(os.Stdin)); err != nil {
+		return err
+	}
+
+	cfg, err := bootstrapConfig(answers)
+	if err != nil {
+		return err
+	}
+
+	t := &selfTest{}
+	t.run("config", func() (string, error) {
+		return *out, validateConfig(cfg)
+	})
+	bootstrapChecks(context.Background(), t, cfg, answers)
+	t.print()
+	if t.results[0].Status == SelfTestFail {
+		return fmt.Errorf("the answers don't make a valid config")
+	}
+	if failed := t.failed(); failed > 0 && !*skipChecks {
+		return fmt.Errorf("%d of %d checks failed; fix the answers or use -skip-checks", failed, len(t.results))
+	}
+
+	if err := SaveConfig(cfg, *out); err != nil {
+		return err
+	}
+	if dir := cfg.VoucherManagement.SaveToDisk.Directory; dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("wrote %s but failed to create save directory %s: %w", *out, dir, err)
+		}
+	}
+	fmt.Printf("✅ Wrote %s; check it with \"-config %s selftest\" once the station is initialized\n", *out, *out)
+	return nil
+}
+
+// askBootstrapQuestions asks for each answer on the terminal, offering the
+// current answer as the default
+func askBootstrapQuestions(a *BootstrapAnswers, in *bufio.Reader) error {
+	var readErr error
+	ask := func(question string, answer *string) {
+		if readErr != nil {
+			return
+		}
+		if *answer != "" {
+			fmt.Printf("%s [%s]: ", question, *answer)
+		} else {
+			fmt.Printf("%s: ", question)
+		}
+		line, err := in.ReadString('\n')
+		if err != nil && (!errors.Is(err, io.EOF) || line == "") {
+			readErr = fmt.Errorf("no answer to %q: %w", question, err)
+			return
+		}
+		if line = strings.TrimSpace(line); line != "" {
+			*answer = line
+		}
+	}
+	choose := func(question string, answer *string, choices ...string) {
+		for readErr == nil {
+			ask(question+" ("+strings.Join(choices, "/")+")", answer)
+			for _, choice := range choices {
+				if *answer == choice {
+					return
+				}
+			}
+			fmt.Printf("⚠️  Answer one of %s\n", strings.Join(choices, ", "))
+		}
+	}
+
+	fmt.Println("📇 Station")
+	ask("Station ID", &a.StationID)
+	ask("Site code", &a.SiteCode)
+	ask("Line ID", &a.LineID)
+	ask("Listen address for DI", &a.Addr)
+	ask("External address devices use (empty = listen address)", &a.ExtAddr)
+	ask("FDO database", &a.Database)
+
+	fmt.Println("🔒 Voucher signing")
+	choose("Key backend", &a.KeyBackend, "internal", "hsm", "external")
+	switch a.KeyBackend {
+	case "internal":
+		ask("Owner key type", &a.OwnerKeyType)
+	case "external":
+		ask("Signing command", &a.SigningCommand)
+		ask("Manufacturer public key file (empty = from the database)", &a.ManufacturerPublicKeyFile)
+	default:
+		ask("Signing command", &a.SigningCommand)
+	}
+
+	fmt.Println("📡 Rendezvous")
+	rendezvous := strings.Join(a.Rendezvous, ", ")
+	ask("Rendezvous server URLs, comma separated (empty = none)", &rendezvous)
+	a.Rendezvous = nil
+	for _, rv := range strings.Split(rendezvous, ",") {
+		if rv = strings.TrimSpace(rv); rv != "" {
+			a.Rendezvous = append(a.Rendezvous, rv)
+		}
+	}
+
+	fmt.Println("🔑 Owner signover")
+	choose("Sign vouchers over to", &a.Owner, "none", "static", "did", "dynamic")
+	switch a.Owner {
+	case "static":
+		ask("Owner public key PEM file", &a.OwnerKeyFile)
+	case "did":
+		ask("Owner DID", &a.OwnerDID)
+	case "dynamic":
+		ask("Owner key command", &a.OwnerCommand)
+	}
+
+	fmt.Println("📤 Voucher delivery")
+	choose("Upload vouchers with", &a.Upload, "none", "http", "command")
+	switch a.Upload {
+	case "http":
+		ask("Voucher recipient URL (empty = the owner DID's voucherRecipientURL)", &a.UploadURL)
+	case "command":
+		ask("Upload command", &a.UploadCommand)
+	}
+	ask("Also save vouchers to directory (empty = don't)", &a.SaveDirectory)
+	return readErr
+}
+
+// bootstrapConfig builds a config from the defaults and the answers
+func bootstrapConfig(a *BootstrapAnswers) (*Config, error) {
+	cfg := DefaultConfig()
+	cfg.Station = StationConfig{SiteCode: a.SiteCode, LineID: a.LineID, StationID: a.StationID}
+	cfg.Server.Addr = a.Addr
+	cfg.Server.ExtAddr = a.ExtAddr
+	cfg.Database.Path = a.Database
+	cfg.Manufacturing.FirstTimeInit = true
+
+	vm := &cfg.VoucherManagement
+	switch a.KeyBackend {
+	case "internal":
+		vm.VoucherSigning.OwnerKeyType = a.OwnerKeyType
+	case "hsm", "external":
+		if a.SigningCommand == "" {
+			return nil, fmt.Errorf("key backend %s needs signing_command", a.KeyBackend)
+		}
+		vm.VoucherSigning.ExternalCommand = a.SigningCommand
+		vm.VoucherSigning.ManufacturerPublicKeyFile = a.ManufacturerPublicKeyFile
+	default:
+		return nil, fmt.Errorf("key_backend: %q is not internal, hsm or external", a.KeyBackend)
+	}
+	vm.VoucherSigning.Mode = a.KeyBackend
+
+	for _, rv := range a.Rendezvous {
+		entry, err := parseRendezvousURL(rv)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Rendezvous.Entries = append(cfg.Rendezvous.Entries, entry)
+	}
+
+	switch a.Owner {
+	case "none":
+	case "static":
+		if a.OwnerKeyFile == "" {
+			return nil, fmt.Errorf("owner static needs owner_key_file")
+		}
+		pemKey, err := os.ReadFile(a.OwnerKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read owner key: %w", err)
+		}
+		vm.OwnerSignover.Mode = "static"
+		vm.OwnerSignover.StaticPublicKey = string(pemKey)
+	case "did":
+		if a.OwnerDID == "" {
+			return nil, fmt.Errorf("owner did needs owner_did")
+		}
+		vm.OwnerSignover.Mode = "static"
+		vm.OwnerSignover.StaticDID = a.OwnerDID
+		vm.DIDCache.Enabled = true // DIDs are only resolved with the DID cache on
+	case "dynamic":
+		if a.OwnerCommand == "" {
+			return nil, fmt.Errorf("owner dynamic needs owner_command")
+		}
+		vm.OwnerSignover.Mode = "dynamic"
+		vm.OwnerSignover.ExternalCommand = a.OwnerCommand
+		vm.DIDCache.Enabled = true // The command may answer with a DID
+	default:
+		return nil, fmt.Errorf("owner: %q is not none, static, did or dynamic", a.Owner)
+	}
+
+	switch a.Upload {
+	case "none":
+	case "http":
+		if a.UploadURL == "" && a.Owner != "did" && a.Owner != "dynamic" {
+			return nil, fmt.Errorf("upload http needs upload_url unless the owner is a DID")
+		}
+		vm.VoucherUpload.Enabled = true
+		vm.VoucherUpload.Mode = "http"
+		vm.VoucherUpload.URL = a.UploadURL
+	case "command":
+		if a.UploadCommand == "" {
+			return nil, fmt.Errorf("upload command needs upload_command")
+		}
+		vm.VoucherUpload.Enabled = true
+		vm.VoucherUpload.Mode = "command"
+		vm.VoucherUpload.ExternalCommand = a.UploadCommand
+	default:
+		return nil, fmt.Errorf("upload: %q is not none, http or command", a.Upload)
+	}
+	vm.SaveToDisk.Directory = a.SaveDirectory
+	return cfg, nil
+}
+
+// parseRendezvousURL turns "https://rv.example.com:8041" into a rendezvous
+// entry; the port defaults to that of the scheme
+func parseRendezvousURL(rv string) (RendezvousEntry, error) {
+	u, err := url.Parse(rv)
+	if err != nil || u.Hostname() == "" {
+		return RendezvousEntry{}, fmt.Errorf("rendezvous %q is not a URL such as https://rv.example.com:8041", rv)
+	}
+	entry := RendezvousEntry{Host: u.Hostname(), Scheme: u.Scheme}
+	switch u.Scheme {
+	case "http":
+		entry.Port = 80
+	case "https":
+		entry.Port = 443
+	default:
+		return RendezvousEntry{}, fmt.Errorf("rendezvous %q: scheme must be http or https", rv)
+	}
+	if u.Port() != "" {
+		if entry.Port, err = strconv.Atoi(u.Port()); err != nil {
+			return RendezvousEntry{}, fmt.Errorf("rendezvous %q: invalid port", rv)
+		}
+	}
+	return entry, nil
+}
+
+// bootstrapChecks checks that what the answers name can be reached from the
+// station: rendezvous servers, commands, the owner key and the upload
+// recipient. Nothing is signed or uploaded.
+func bootstrapChecks(ctx context.Context, t *selfTest, cfg *Config, a *BootstrapAnswers) {
+	vm := &cfg.VoucherManagement
+
+	for _, rv := range cfg.Rendezvous.Entries {
+		addr := net.JoinHostPort(rv.Host, strconv.Itoa(rv.Port))
+		t.run("rendezvous "+addr, func() (string, error) {
+			conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+			if err != nil {
+				return "", err
+			}
+			_ = conn.Close()
+			return "reachable", nil
+		})
+	}
+
+	t.run("signing command", func() (string, error) {
+		if vm.VoucherSigning.Mode == "internal" {
+			return "internal signing", errSelfTestSkipped
+		}
+		return lookCommand(vm.VoucherSigning.ExternalCommand)
+	})
+	if vm.VoucherSigning.ManufacturerPublicKeyFile != "" {
+		t.run("manufacturer public key", func() (string, error) {
+			key, err := LoadManufacturerPublicKey(vm.VoucherSigning.ManufacturerPublicKeyFile)
+			if err != nil {
+				return "", err
+			}
+			pub, err := protocolPublicKeyToCrypto(&key)
+			if err != nil {
+				return "", fmt.Errorf("failed to decode manufacturer key: %w", err)
+			}
+			if chain, ok := pub.([]*x509.Certificate); ok && len(chain) > 0 {
+				pub = chain[0].PublicKey
+			}
+			return fmt.Sprintf("%s %s", describeKey(pub), ownerKeySHA256(pub)), nil
+		})
+	}
+
+	t.run("owner key", func() (string, error) {
+		owner := vm.OwnerSignover.StaticPublicKey
+		switch a.Owner {
+		case "none":
+			return "no owner signover", errSelfTestSkipped
+		case "dynamic":
+			return lookCommand(vm.OwnerSignover.ExternalCommand)
+		case "did":
+			owner = vm.OwnerSignover.StaticDID
+		}
+		ownerKeys := NewOwnerKeyService(nil, &vm.OwnerSignover, &vm.DIDCache, &cfg.Rollouts, nil)
+		result, err := ownerKeys.ResolveOwner(ctx, owner, "")
+		if err != nil {
+			return "", err
+		}
+		detail := fmt.Sprintf("%s %s", describeKey(result.PublicKey), ownerKeySHA256(result.PublicKey))
+		if result.DIDURL != "" {
+			detail += ", recipient " + result.DIDURL
+		}
+		return detail, nil
+	})
+
+	t.run("upload", func() (string, error) {
+		switch {
+		case !vm.VoucherUpload.Enabled:
+			return "voucher_upload disabled", errSelfTestSkipped
+		case vm.VoucherUpload.Mode == "command":
+			return lookCommand(vm.VoucherUpload.ExternalCommand)
+		case vm.VoucherUpload.URL == "":
+			return "recipient comes from the owner's DID", errSelfTestSkipped
+		}
+		status, err := NewVoucherHTTPUploader(vm, cfg.Station.StationID).Probe(ctx, vm.VoucherUpload.URL, "")
+		return fmt.Sprintf("%s HTTP %d", vm.VoucherUpload.URL, status), err
+	})
+
+	t.run("save directories", func() (string, error) {
+		return bootstrapDirectories(vm)
+	})
+}
+
+// bootstrapDirectories checks that each save_to_disk directory exists, or
+// that its nearest existing parent is a directory it can be created in. The
+// directories are only created once the config is written.
+func bootstrapDirectories(vm *VoucherConfig) (string, error) {
+	var dirs []string
+	if vm.SaveToDisk.Directory != "" {
+		dirs = append(dirs, vm.SaveToDisk.Directory)
+	}
+	for _, dest := range vm.SaveToDisk.Destinations {
+		if dest.Directory != "" {
+			dirs = append(dirs, dest.Directory)
+		}
+	}
+	if len(dirs) == 0 {
+		return "no save_to_disk directories", errSelfTestSkipped
+	}
+	missing := 0
+	for _, dir := range dirs {
+		existing := filepath.Clean(dir)
+		info, err := os.Stat(existing)
+		for errors.Is(err, fs.ErrNotExist) && filepath.Dir(existing) != existing {
+			existing = filepath.Dir(existing)
+			info, err = os.Stat(existing)
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to check %s: %w", dir, err)
+		}
+		if !info.IsDir() {
+			return "", fmt.Errorf("%s is not a directory", existing)
+		}
+		if runtime.GOOS != "windows" && info.Mode().Perm()&0o222 == 0 { // Windows has ACLs, not mode bits
+			return "", fmt.Errorf("%s is not writable (mode %04o)", existing, info.Mode().Perm())
+		}
+		if existing != filepath.Clean(dir) {
+			missing++
+		}
+	}
+	if missing > 0 {
+		return fmt.Sprintf("%d writable, %d created with the config", len(dirs)-missing, missing), nil
+	}
+	return fmt.Sprintf("%d writable", len(dirs)), nil
+}
+
+// lookCommand checks that the program a command line runs can be found
+func lookCommand(command string) (string, error) {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return "", fmt.Errorf("no command configured")
+	}
+	path, err := exec.LookPath(fields[0])
+	if err != nil {
+		return "", err
+	}
+	return path, nil
+}
@@ -0,0 +1,633 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// diagMaxDepth bounds nesting so a hostile message can't exhaust the stack
+const diagMaxDepth = 64
+
+// ErrVoucherNotFound marks a GUID no voucher store holds
+var ErrVoucherNotFound = errors.New("voucher not found")
+
+// diagSchema names the parts of a CBOR item in its diagnostic rendering
+type diagSchema struct {
+	Name    string
+	Items   []*diagSchema    // Array elements by position
+	Each    *diagSchema      // Array elements past Items
+	Keys    map[int64]string // Names of integer map keys
+	Values  map[int64]string // Names of integer values
+	Wrapped bool             // A byte string holding CBOR of this schema
+}
+
+// named returns a copy of s under another name
+func named(name string, s *diagSchema) *diagSchema {
+	c := *s
+	c.Name = name
+	return &c
+}
+
+// FDO 1.1 structures, as the station stores and exchanges them
+var (
+	diagHashTypes = map[int64]string{-16: "SHA256", -43: "SHA384", 5: "HMAC-SHA256", 6: "HMAC-SHA384"}
+	diagHash      = &diagSchema{Items: []*diagSchema{{Name: "hashtype", Values: diagHashTypes}, {Name: "hash"}}}
+	diagPublicKey = &diagSchema{Items: []*diagSchema{
+		{Name: "pkType", Values: map[int64]string{1: "RSA2048RESTR", 5: "RSAPKCS", 6: "RSAPSS", 10: "SECP256R1", 11: "SECP384R1"}},
+		{Name: "pkEnc", Values: map[int64]string{0: "Crypto", 1: "X509", 2: "X5CHAIN", 3: "COSEKEY"}},
+		{Name: "pkBody"},
+	}}
+	diagCOSEHeaders = &diagSchema{
+		Keys: map[int64]string{1: "alg", 3: "content type", 4: "kid", 256: "CUPHNonce", 257: "CUPHOwnerPubKey", -17760701: "EUPHNonce"},
+	}
+	diagOVHeader = &diagSchema{Wrapped: true, Items: []*diagSchema{
+		{Name: "OVHProtVer"},
+		{Name: "OVGuid"},
+		{Name: "OVRVInfo"},
+		{Name: "OVDeviceInfo"},
+		named("OVPubKey", diagPublicKey),
+		named("OVDevCertChainHash", diagHash),
+	}}
+	diagOVEntry = &diagSchema{Items: []*diagSchema{
+		named("protected", &diagSchema{Wrapped: true, Keys: diagCOSEHeaders.Keys}),
+		named("unprotected", diagCOSEHeaders),
+		{Name: "payload", Wrapped: true, Items: []*diagSchema{
+			named("OVEHashPrevEntry", diagHash),
+			named("OVEHashHdrInfo", diagHash),
+			{Name: "OVEExtra", Wrapped: true},
+			named("OVEPubKey", diagPublicKey),
+		}},
+		{Name: "signature"},
+	}}
+	diagVoucher = &diagSchema{Name: "OwnershipVoucher", Items: []*diagSchema{
+		{Name: "OVProtVer"},
+		named("OVHeader", diagOVHeader),
+		named("OVHeaderHMac", diagHash),
+		{Name: "OVDevCertChain", Each: &diagSchema{Name: "certificate"}},
+		{Name: "OVEntries", Each: named("OVEntry", diagOVEntry)},
+	}}
+
+	// diagMessages are the DI messages by type
+	diagMessages = map[int]*diagSchema{
+		10: {Name: "DI.AppStart", Items: []*diagSchema{{Name: "DeviceMfgInfo", Wrapped: true, Items: []*diagSchema{
+			{Name: "KeyType"}, {Name: "KeyEncoding"}, {Name: "KeyHashAlg"}, {Name: "SerialNumber"}, {Name: "DeviceInfo"}, {Name: "CertInfo"},
+		}}}},
+		11:  {Name: "DI.SetCredentials", Items: []*diagSchema{named("OVHeader", diagOVHeader)}},
+		12:  {Name: "DI.SetHMAC", Items: []*diagSchema{named("Hmac", diagHash)}},
+		13:  {Name: "DI.Done"},
+		255: {Name: "Error", Items: []*diagSchema{{Name: "EMErrorCode"}, {Name: "EMPrevMsgID"}, {Name: "EMErrorStr"}, {Name: "EMErrorTs"}, {Name: "EMErrorCID"}}},
+	}
+)
+
+// renderCBORDiag renders CBOR as diagnostic notation (EDN), with the names of
+// schema's fields as comments. Byte strings the schema marks as holding CBOR
+// are rendered as embedded CBOR (<< >>) when they decode. A CBOR sequence is
+// rendered one item per line.
+func renderCBORDiag(data []byte, schema *diagSchema) (string, error) {
+	var out strings.Builder
+	d := &diagDecoder{data: data}
+	for d.pos < len(d.data) {
+		text, err := d.item(schema, 0, 0)
+		if err != nil {
+			return "", fmt.Errorf("at offset %d: %w", d.pos, err)
+		}
+		out.WriteString(diagComment(schema) + text + "\n")
+	}
+	return out.String(), nil
+}
+
+// diagDecoder walks CBOR data, producing the diagnostic text
+type diagDecoder struct {
+	data []byte
+	pos  int
+}
+
+// errDiagBreak is returned for the break code ending an indefinite length item
+var errDiagBreak = errors.New("unexpected break")
+
+// head reads an initial byte and its argument. Indefinite length heads return
+// indefinite = true.
+func (d *diagDecoder) head() (major, info byte, arg uint64, indefinite bool, err error) {
+	if d.pos >= len(d.data) {
+		return 0, 0, 0, false, io.ErrUnexpectedEOF
+	}
+	b := d.data[d.pos]
+	d.pos++
+	major, info = b>>5, b&0x1f
+	switch {
+	case info < 24:
+		return major, info, uint64(info), false, nil
+	case info <= 27:
+		n := 1 << (info - 24)
+		if len(d.data)-d.pos < n {
+			return 0, 0, 0, false, io.ErrUnexpectedEOF
+		}
+		var buf [8]byte
+		copy(buf[8-n:], d.data[d.pos:d.pos+n])
+		d.pos += n
+		return major, info, binary.BigEndian.Uint64(buf[:]), false, nil
+	case info == 31 && major == 7:
+		return major, info, 0, false, errDiagBreak
+	case info == 31 && major >= 2 && major <= 5:
+		return major, info, 0, true, nil
+	default:
+		return 0, 0, 0, false, fmt.Errorf("invalid initial byte 0x%02x", b)
+	}
+}
+
+// item renders the next data item at the given indentation
+func (d *diagDecoder) item(s *diagSchema, indent, depth int) (string, error) {
+	if depth > diagMaxDepth {
+		return "", fmt.Errorf("nested more than %d levels", diagMaxDepth)
+	}
+	if s == nil {
+		s = &diagSchema{}
+	}
+	major, info, arg, indefinite, err := d.head()
+	if err != nil {
+		return "", err
+	}
+	switch major {
+	case 0:
+		return strconv.FormatUint(arg, 10) + diagValue(s, int64(min(arg, math.MaxInt64))), nil
+	case 1:
+		if arg == math.MaxUint64 {
+			return "-18446744073709551616", nil
+		}
+		text := "-" + strconv.FormatUint(arg+1, 10)
+		if arg < math.MaxInt64 {
+			text += diagValue(s, -1-int64(arg))
+		}
+		return text, nil
+	case 2, 3:
+		if indefinite {
+			return d.chunks(major, depth)
+		}
+		b, err := d.bytes(arg)
+		if err != nil {
+			return "", err
+		}
+		if major == 3 {
+			return strconv.Quote(string(b)), nil
+		}
+		if s.Wrapped && len(b) > 0 {
+			inner := &diagDecoder{data: b}
+			if text, err := inner.item(s, indent, depth+1); err == nil && inner.pos == len(b) {
+				return "<< " + text + " >>", nil
+			}
+		}
+		return "h'" + hex.EncodeToString(b) + "'", nil
+	case 4:
+		return d.array(s, arg, indefinite, indent, depth)
+	case 5:
+		return d.dict(s, arg, indefinite, indent, depth)
+	case 6:
+		text, err := d.item(s, indent, depth+1)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatUint(arg, 10) + "(" + text + ")", nil
+	default:
+		return diagSimple(info, arg), nil
+	}
+}
+
+// bytes returns the next n bytes
+func (d *diagDecoder) bytes(n uint64) ([]byte, error) {
+	if n > uint64(len(d.data)-d.pos) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	b := d.data[d.pos : d.pos+int(n)]
+	d.pos += int(n)
+	return b, nil
+}
+
+// chunks renders an indefinite length byte or text string as (_ chunk, ...)
+func (d *diagDecoder) chunks(major byte, depth int) (string, error) {
+	var parts []string
+	for {
+		text, err := d.item(nil, 0, depth+1)
+		if errors.Is(err, errDiagBreak) {
+			return "(_ " + strings.Join(parts, ", ") + ")", nil
+		}
+		if err != nil {
+			return "", err
+		}
+		if (major == 2) != strings.HasPrefix(text, "h'") {
+			return "", fmt.Errorf("indefinite length string with a chunk of another type")
+		}
+		parts = append(parts, text)
+	}
+}
+
+// array renders an array, on one line if it is short and has no named elements
+func (d *diagDecoder) array(s *diagSchema, n uint64, indefinite bool, indent, depth int) (string, error) {
+	if !indefinite && n > uint64(len(d.data)-d.pos) {
+		return "", io.ErrUnexpectedEOF
+	}
+	var lines []string
+	for i := 0; indefinite || uint64(i) < n; i++ {
+		child := s.Each
+		if i < len(s.Items) {
+			child = s.Items[i]
+		}
+		text, err := d.item(child, indent+2, depth+1)
+		if indefinite && errors.Is(err, errDiagBreak) {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		lines = append(lines, diagComment(child)+text)
+	}
+	open := "["
+	if indefinite {
+		open = "[_ "
+	}
+	return diagJoin(open, "]", lines, indent), nil
+}
+
+// dict renders a map, naming integer keys the schema knows
+func (d *diagDecoder) dict(s *diagSchema, n uint64, indefinite bool, indent, depth int) (string, error) {
+	if !indefinite && n > uint64(len(d.data)-d.pos)/2 {
+		return "", io.ErrUnexpectedEOF
+	}
+	var lines []string
+	for i := 0; indefinite || uint64(i) < n; i++ {
+		start := d.pos
+		key, err := d.item(nil, indent+2, depth+1)
+		if indefinite && errors.Is(err, errDiagBreak) {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		if k, err := strconv.ParseInt(key, 10, 64); err == nil && d.data[start]>>5 < 2 {
+			if name, ok := s.Keys[k]; ok {
+				key += " / " + name + " /"
+			}
+		}
+		value, err := d.item(nil, indent+2, depth+1)
+		if err != nil {
+			return "", err
+		}
+		lines = append(lines, key+": "+value)
+	}
+	open := "{"
+	if indefinite {
+		open = "{_ "
+	}
+	return diagJoin(open, "}", lines, indent), nil
+}
+
+// diagJoin lays out the elements of an array or map, one per line unless they
+// are few and short enough for one
+func diagJoin(open, close string, lines []string, indent int) string {
+	width := 0
+	for _, line := range lines {
+		width += len(line) + 2
+		if strings.Contains(line, "\n") || strings.HasPrefix(line, "/ ") {
+			width = math.MaxInt
+			break
+		}
+	}
+	if width <= 60 {
+		return open + strings.Join(lines, ", ") + close
+	}
+	pad := strings.Repeat(" ", indent+2)
+	return open + "\n" + pad + strings.Join(lines, ",\n"+pad) + "\n" + strings.Repeat(" ", indent) + close
+}
+
+// diagComment returns the name comment of a schema, if it has a name
+func diagComment(s *diagSchema) string {
+	if s == nil || s.Name == "" {
+		return ""
+	}
+	return "/ " + s.Name + " / "
+}
+
+// diagValue returns the comment naming an integer value, if the schema knows it
+func diagValue(s *diagSchema, v int64) string {
+	if name, ok := s.Values[v]; ok {
+		return " / " + name + " /"
+	}
+	return ""
+}
+
+// diagSimple renders a simple value or float
+func diagSimple(info byte, arg uint64) string {
+	var f float64
+	switch info {
+	case 20:
+		return "false"
+	case 21:
+		return "true"
+	case 22:
+		return "null"
+	case 23:
+		return "undefined"
+	case 25:
+		f = halfFloat(uint16(arg))
+	case 26:
+		f = float64(math.Float32frombits(uint32(arg)))
+	case 27:
+		f = math.Float64frombits(arg)
+	default:
+		return fmt.Sprintf("simple(%d)", arg)
+	}
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "Infinity"
+	case math.IsInf(f, -1):
+		return "-Infinity"
+	}
+	text := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(text, ".e") {
+		text += ".0"
+	}
+	return text
+}
+
+// halfFloat converts an IEEE 754 half precision value
+func halfFloat(h uint16) float64 {
+	exp, mant := int(h>>10)&0x1f, float64(h&0x3ff)
+	var f float64
+	switch exp {
+	case 0:
+		f = math.Ldexp(mant, -24)
+	case 31:
+		f = math.Inf(1)
+		if mant != 0 {
+			f = math.NaN()
+		}
+	default:
+		f = math.Ldexp(mant+1024, exp-25)
+	}
+	if h&0x8000 != 0 {
+		f = -f
+	}
+	return f
+}
+
+// renderVoucherDiag renders a voucher, raw CBOR or an OWNERSHIP VOUCHER PEM file
+func renderVoucherDiag(data []byte) (string, error) {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("-----BEGIN")) {
+		var err error
+		if data, err = decodeVoucherFile(data); err != nil {
+			return "", err
+		}
+	}
+	return renderCBORDiag(data, diagVoucher)
+}
+
+// findStoredVoucher returns the voucher of a GUID from the first store that
+// has it: the station database tables, then the go-fdo database
+func findStoredVoucher(ctx context.Context, cfg *Config, stationDB *StationDB, guid string) ([]byte, string, error) {
+	guid = strings.ToLower(guid)
+	var found []byte
+	var foundErr error
+	for _, table := range stationVoucherSources {
+		err := scanVouchers(ctx, stationDB.db, table.query+` WHERE t.guid = ?`, func(_, hash string, data []byte) {
+			if found != nil || foundErr != nil {
+				return
+			}
+			if table.source == IntegritySourceQueue && hash == "" {
+				data, foundErr = decodeVoucherFile(data)
+			}
+			found = data
+		}, guid)
+		if err != nil && !strings.Contains(err.Error(), "no such table") {
+			return nil, "", fmt.Errorf("failed to read %s: %w", table.source, err)
+		}
+		if foundErr != nil {
+			return nil, "", fmt.Errorf("failed to read %s: %w", table.source, foundErr)
+		}
+		if found != nil {
+			return found, table.source, nil
+		}
+	}
+	if cfg.Database.Password == "" {
+		if err := checkFDOVouchers(ctx, cfg.Database.Path, guid, func(_, _ string, data []byte) {
+			if found == nil {
+				found = data
+			}
+		}); err != nil {
+			return nil, "", fmt.Errorf("failed to read %s: %w", IntegritySourceFDO, err)
+		}
+		if found != nil {
+			return found, IntegritySourceFDO, nil
+		}
+	}
+	return nil, "", fmt.Errorf("%w: %s", ErrVoucherNotFound, guid)
+}
+
+// renderCaptureDiag renders every DI message of a debug capture file, each
+// after the capture line that introduced it. Other capture lines are dropped.
+func renderCaptureDiag(r io.Reader, w io.Writer) error {
+	var header string
+	var msg []byte
+	flush := func() {
+		if header == "" {
+			return
+		}
+		fmt.Fprintln(w, header)
+		schema := &diagSchema{}
+		if _, args, ok := strings.Cut(header, " msg="); ok {
+			typ, _, _ := strings.Cut(args, " ")
+			if n, err := strconv.Atoi(typ); err == nil && diagMessages[n] != nil {
+				schema = diagMessages[n]
+			}
+		}
+		if strings.Contains(header, " status=") && !strings.Contains(header, " status=200") {
+			schema = diagMessages[255]
+		}
+		if len(msg) == 0 {
+			fmt.Fprintf(w, "%s(empty)\n\n", diagComment(schema))
+		} else if text, err := renderCBORDiag(msg, schema); err != nil {
+			fmt.Fprintf(w, "/ not CBOR: %v /\n%s\n", err, hex.Dump(msg))
+		} else {
+			fmt.Fprintln(w, text)
+		}
+		header, msg = "", nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.Contains(line, "] → request msg=") || strings.Contains(line, "] ← response msg=") {
+			flush()
+			header = line
+			continue
+		}
+		if header == "" {
+			continue
+		}
+		if b, ok := parseHexDumpLine(line); ok {
+			msg = append(msg, b...)
+		} else {
+			flush()
+		}
+	}
+	flush()
+	return scanner.Err()
+}
+
+// parseHexDumpLine returns the bytes of one line of hex.Dump output
+func parseHexDumpLine(line string) ([]byte, bool) {
+	if len(line) < 12 || line[8:10] != "  " {
+		return nil, false
+	}
+	if _, err := strconv.ParseUint(line[:8], 16, 32); err != nil {
+		return nil, false
+	}
+	area := line[10:]
+	if i := strings.Index(area, "  |"); i >= 0 {
+		area = area[:i]
+	}
+	var b []byte
+	for _, field := range strings.Fields(area) {
+		v, err := strconv.ParseUint(field, 16, 8)
+		if err != nil || len(field) != 2 {
+			return nil, false
+		}
+		b = append(b, byte(v))
+	}
+	return b, len(b) > 0
+}
+
+// CBORDiag serves diagnostic renderings of stored vouchers and debug captures
+type CBORDiag struct {
+	cfg       *Config
+	stationDB *StationDB
+}
+
+// NewCBORDiag creates the diagnostic rendering endpoints
+func NewCBORDiag(cfg *Config, stationDB *StationDB) *CBORDiag {
+	return &CBORDiag{cfg: cfg, stationDB: stationDB}
+}
+
+// VoucherHandler serves GET /api/vouchers/{guid}/diag
+func (c *CBORDiag) VoucherHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, source, err := findStoredVoucher(r.Context(), c.cfg, c.stationDB, r.PathValue("guid"))
+		if errors.Is(err, ErrVoucherNotFound) {
+			writeJSONError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		text, err := renderCBORDiag(data, diagVoucher)
+		if err != nil {
+			writeJSONError(w, http.StatusUnprocessableEntity, fmt.Sprintf("stored voucher (%s) is not valid CBOR: %v", source, err))
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("X-Voucher-Source", source)
+		_, _ = io.WriteString(w, text)
+	})
+}
+
+// CaptureHandler serves GET /api/captures/{file}/diag for a file of the
+// debug capture directory
+func (c *CBORDiag) CaptureHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("file")
+		if name != filepath.Base(name) || !strings.HasSuffix(name, ".log") {
+			writeJSONError(w, http.StatusBadRequest, "file must be the name of a capture log")
+			return
+		}
+		dir := c.cfg.DebugCapture.Directory
+		if dir == "" {
+			dir = "debug-capture"
+		}
+		file, err := os.Open(filepath.Join(dir, name))
+		if errors.Is(err, os.ErrNotExist) {
+			writeJSONError(w, http.StatusNotFound, fmt.Sprintf("no capture %s", name))
+			return
+		}
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		defer file.Close()
+		var buf bytes.Buffer
+		if err := renderCaptureDiag(file, &buf); err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = w.Write(buf.Bytes())
+	})
+}
+
+// runVoucherDiag implements "voucher diag <file|guid>": a voucher file (PEM,
+// raw CBOR, or either gzipped), or the stored voucher of a GUID, printed as
+// annotated CBOR diagnostic notation
+func runVoucherDiag(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: voucher diag <file|guid>")
+	}
+	if _, err := os.Stat(args[0]); err == nil {
+		data, err := readVoucherArtifact(args[0])
+		if err != nil {
+			return err
+		}
+		text, err := renderVoucherDiag(data)
+		if err != nil {
+			return err
+		}
+		fmt.Print(text)
+		return nil
+	}
+
+	stationDB, err := OpenStationDBReadOnly(stationDBPath(config))
+	if err != nil {
+		return err
+	}
+	defer stationDB.Close()
+	data, source, err := findStoredVoucher(context.Background(), config, stationDB, args[0])
+	if err != nil {
+		return err
+	}
+	text, err := renderCBORDiag(data, diagVoucher)
+	if err != nil {
+		return fmt.Errorf("stored voucher (%s) is not valid CBOR: %w", source, err)
+	}
+	fmt.Printf("/ from %s /\n%s", source, text)
+	return nil
+}
+
+// runCaptureDiag implements "capture diag <file>": the DI messages of a debug
+// capture file printed as annotated CBOR diagnostic notation
+func runCaptureDiag(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: capture diag <file>")
+	}
+	file, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return renderCaptureDiag(file, os.Stdout)
+}
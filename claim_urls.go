@@ -0,0 +1,177 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const defaultClaimURLTimeout = 10 * time.Second
+
+// DeviceLabel is the data printed on a device label: the device identity and
+// the claim URL to encode as a QR code, with the label's captions in the
+// locale the label is printed in
+type DeviceLabel struct {
+	GUID      string            `json:"guid"`
+	Serial    string            `json:"serial"`
+	Model     string            `json:"model,omitempty"`
+	Customer  string            `json:"customer,omitempty"`
+	ClaimURL  string            `json:"claim_url"`
+	CreatedAt time.Time         `json:"created_at"`
+	Locale    string            `json:"locale"`
+	Captions  map[string]string `json:"captions"` // guid, serial, model, customer and claim_prompt
+}
+
+// labelCaptions are the label fields that carry a caption
+var labelCaptions = []string{"guid", "serial", "model", "customer", "claim_prompt"}
+
+// ClaimURLs generates an owner-facing claim URL for each voucher, so a
+// customer can bind the physical device to their cloud account by scanning a
+// code on its label. The URL is stored with the GUID, served as label data and
+// sent with the signover anomaly webhook.
+type ClaimURLs struct {
+	config    *ClaimURLConfig
+	db        *StationDB
+	stationID string
+}
+
+// NewClaimURLs creates the claim URL generator, or returns nil if it is disabled
+func NewClaimURLs(config *ClaimURLConfig, db *StationDB, stationID string) *ClaimURLs {
+	if !config.Enabled {
+		return nil
+	}
+	return &ClaimURLs{config: config, db: db, stationID: stationID}
+}
+
+// Initialize creates the claim_urls table if it doesn't exist
+func (c *ClaimURLs) Initialize(ctx context.Context) error {
+	if c == nil {
+		return nil
+	}
+	_, err := c.db.db.ExecContext(ctx, `
+	CREATE TABLE IF NOT EXISTS claim_urls (
+		guid TEXT PRIMARY KEY,
+		serial TEXT NOT NULL,
+		model TEXT NOT NULL,
+		customer TEXT NOT NULL,
+		claim_url TEXT NOT NULL,
+		created_at INTEGER NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create claim_urls table: %w", err)
+	}
+	return nil
+}
+
+// Generate builds and stores the claim URL of a voucher. It returns "" when
+// claim URLs are disabled.
+func (c *ClaimURLs) Generate(ctx context.Context, guid, serial, model, customer string) (string, error) {
+	if c == nil {
+		return "", nil
+	}
+	variables := map[string]string{
+		"guid":     guid,
+		"serialno": serial,
+		"model":    model,
+		"customer": customer,
+		"station":  c.stationID,
+	}
+
+	var claimURL string
+	if c.config.ExternalCommand != "" {
+		timeout := c.config.Timeout
+		if timeout <= 0 {
+			timeout = defaultClaimURLTimeout
+		}
+		output, err := NewExternalCommandExecutor(CommandClaimURL, c.config.ExternalCommand, timeout).Execute(ctx, variables)
+		if err != nil {
+			return "", fmt.Errorf("claim URL command failed: %w", err)
+		}
+		claimURL = strings.TrimSpace(output)
+	} else {
+		template := c.config.Template
+		if t, ok := c.config.Templates[customer]; ok {
+			template = t
+		}
+		claimURL = expandClaimTemplate(template, variables)
+	}
+	if u, err := url.Parse(claimURL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return "", fmt.Errorf("invalid claim URL %q for %s", claimURL, guid)
+	}
+
+	if _, err := c.db.db.ExecContext(ctx, `
+	INSERT OR REPLACE INTO claim_urls (guid, serial, model, customer, claim_url, created_at)
+	VALUES (?, ?, ?, ?, ?, ?)`, guid, serial, model, customer, claimURL, time.Now().Unix()); err != nil {
+		return "", fmt.Errorf("failed to store claim URL: %w", err)
+	}
+	fmt.Printf("🏷️  Stored claim URL for %s\n", guid)
+	return claimURL, nil
+}
+
+// expandClaimTemplate substitutes the variables of a claim URL template, each
+// escaped so it stays within its path segment or query value
+func expandClaimTemplate(template string, variables map[string]string) string {
+	for key, value := range variables {
+		template = strings.ReplaceAll(template, "{"+key+"}", url.PathEscape(value))
+	}
+	return template
+}
+
+// Label returns the label data of a voucher
+func (c *ClaimURLs) Label(ctx context.Context, guid string) (*DeviceLabel, error) {
+	var label DeviceLabel
+	var createdAt int64
+	err := c.db.db.QueryRowContext(ctx, `
+	SELECT guid, serial, model, customer, claim_url, created_at FROM claim_urls WHERE guid = ?`,
+		strings.ToLower(guid)).Scan(&label.GUID, &label.Serial, &label.Model, &label.Customer, &label.ClaimURL, &createdAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrVoucherNotFound, guid)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read claim URL: %w", err)
+	}
+	label.CreatedAt = time.Unix(createdAt, 0).UTC()
+	return &label, nil
+}
+
+// LabelHandler serves GET /api/vouchers/{guid}/label
+func (c *ClaimURLs) LabelHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if c == nil {
+			writeJSONError(w, http.StatusNotFound, "claim URLs are disabled")
+			return
+		}
+		label, err := c.Label(r.Context(), r.PathValue("guid"))
+		if errors.Is(err, ErrVoucherNotFound) {
+			writeJSONError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		label.Locale = stationMessages.Locale(r)
+		label.Captions = map[string]string{}
+		for _, field := range labelCaptions {
+			label.Captions[field] = stationMessages.Text(label.Locale, "label."+field)
+		}
+		writeJSON(w, http.StatusOK, label)
+	})
+}
+
+// validateClaimURLs checks that enabled claim URLs have a way to build them
+func validateClaimURLs(config *ClaimURLConfig) error {
+	if config.Enabled && config.Template == "" && len(config.Templates) == 0 && config.ExternalCommand == "" {
+		return fmt.Errorf("claim_urls needs a template, templates or an external_command")
+	}
+	return nil
+}
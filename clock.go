@@ -0,0 +1,64 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells the time to the code paths whose behavior depends on it: DID
+// cache freshness and purging, DID pins and rotations, audit timestamps,
+// command log retention, certificate validity, approval and policy override
+// expiry, owner key proofs, quota periods, batch expiry, shift windows,
+// signover anomaly windows and voucher store timestamps
+type Clock interface {
+	Now() time.Time
+}
+
+// stationClock is the clock those code paths read. Tests and "voucher replay
+// -at" replace it with a FrozenClock; timers and measured durations stay on
+// the wall clock.
+var stationClock Clock = systemClock{}
+
+// systemClock is the wall clock
+type systemClock struct{}
+
+// Now returns the current time
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// FrozenClock is a clock that only moves when told to
+type FrozenClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewFrozenClock creates a clock stopped at t
+func NewFrozenClock(t time.Time) *FrozenClock {
+	return &FrozenClock{now: t}
+}
+
+// Now returns the time the clock is stopped at
+func (c *FrozenClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward by d
+func (c *FrozenClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// Set stops the clock at t
+func (c *FrozenClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = t
+}
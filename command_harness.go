@@ -0,0 +1,646 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"fdo-manufacturing-station/fdotest"
+)
+
+// Partners write the owner key, OVE extra data and upload commands the line
+// runs. "command scaffold" writes a reference implementation of a command's
+// contract, in shell and in Go, to start from; "command check" runs a
+// partner's command the way the station would and checks what it prints
+// against the contract, so a broken script is found before it is deployed.
+
+// harnessCommand is the contract of one external command
+type harnessCommand struct {
+	template string // external_command line for the reference implementation
+	shell    string
+	golang   string
+}
+
+// harnessCommands are the contracts "command scaffold" and "command check" know
+var harnessCommands = map[string]harnessCommand{
+	CommandOwnerSignover: {
+		template: "{serialno} {model} {lot}",
+		shell:    ownerSignoverShell,
+		golang:   ownerSignoverGo,
+	},
+	CommandOVEExtraData: {
+		template: "{serial} {model}",
+		shell:    oveExtraDataShell,
+		golang:   oveExtraDataGo,
+	},
+	CommandVoucherUpload: {
+		template: "{voucherfile} {guid} {serialno} {voucher_sha256}",
+		shell:    voucherUploadShell,
+		golang:   voucherUploadGo,
+	},
+}
+
+// harnessCommandNames lists the known contracts for usage messages
+func harnessCommandNames() string {
+	names := make([]string, 0, len(harnessCommands))
+	for name := range harnessCommands {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return strings.Join(names, ", ")
+}
+
+// runCommandScaffold implements "command scaffold [-out dir] [-force] <command>":
+// it writes <command>.sh and <command>.go, reference implementations of the
+// command's contract, and prints the external_command lines that run them
+func runCommandScaffold(args []string) error {
+	fs := flag.NewFlagSet("command scaffold", flag.ContinueOnError)
+	outDir := fs.String("out", ".", "Directory to write the reference implementations to")
+	force := fs.Bool("force", false, "Overwrite existing files")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: command scaffold [-out dir] [-force] <command>, one of %s", harnessCommandNames())
+	}
+	name := fs.Arg(0)
+	contract, ok := harnessCommands[name]
+	if !ok {
+		return fmt.Errorf("no reference implementation for %q; one of %s", name, harnessCommandNames())
+	}
+
+	if err := os.MkdirAll(*outDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", *outDir, err)
+	}
+	files := []struct {
+		path    string
+		content string
+		mode    os.FileMode
+	}{
+		{filepath.Join(*outDir, name+".sh"), contract.shell, 0o755},
+		{filepath.Join(*outDir, name+".go"), contract.golang, 0o644},
+	}
+	for _, f := range files {
+		if _, err := os.Stat(f.path); err == nil && !*force {
+			return fmt.Errorf("%s exists; use -force to overwrite it", f.path)
+		}
+		if err := os.WriteFile(f.path, []byte(f.content), f.mode); err != nil {
+			return fmt.Errorf("failed to write %s: %w", f.path, err)
+		}
+		fmt.Printf("📝 Wrote %s\n", f.path)
+	}
+	fmt.Printf("\nexternal_command: \"sh %s %s\"\n", files[0].path, contract.template)
+	fmt.Printf("external_command: \"go run %s %s\"\n", files[1].path, contract.template)
+	fmt.Printf("\nCheck it before deploying:\n  command check %s \"sh %s %s\"\n", name, files[0].path, contract.template)
+	return nil
+}
+
+// runCommandCheck implements "command check [-serial S] [-model M] [-lot L]
+// <command> [external_command]": it runs a partner's command, or the one
+// configured for the contract, with a sample device and checks the result
+// against the contract. Nothing is signed or delivered; the upload command is
+// given a fixture voucher made with a published test key.
+func runCommandCheck(args []string) error {
+	fs := flag.NewFlagSet("command check", flag.ContinueOnError)
+	serial := fs.String("serial", "CHECK-0001", "Serial number of the sample device")
+	model := fs.String("model", "check", "Model of the sample device; use one the command knows")
+	lot := fs.String("lot", "", "Lot (customer order) of the sample device")
+	timeout := fs.Duration("timeout", 0, "Timeout of each run (default: the command's configured timeout, or 30s)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() < 1 || fs.NArg() > 2 {
+		return fmt.Errorf("usage: command check [-serial S] [-model M] [-lot L] [-timeout D] <command> [external_command]")
+	}
+	name := fs.Arg(0)
+	if _, ok := harnessCommands[name]; !ok {
+		return fmt.Errorf("no contract for %q; one of %s", name, harnessCommandNames())
+	}
+
+	vm := config.VoucherManagement
+	template, configured := "", time.Duration(0)
+	switch name {
+	case CommandOwnerSignover:
+		template, configured = vm.OwnerSignover.ExternalCommand, vm.OwnerSignover.Timeout
+	case CommandOVEExtraData:
+		template, configured = vm.OVEExtraData.ExternalCommand, vm.OVEExtraData.Timeout
+	case CommandVoucherUpload:
+		template, configured = vm.VoucherUpload.ExternalCommand, vm.VoucherUpload.Timeout
+	}
+	if fs.NArg() == 2 {
+		template = fs.Arg(1)
+	}
+	if template == "" {
+		return fmt.Errorf("no external_command configured for %s; give one to check", name)
+	}
+	if *timeout == 0 {
+		*timeout = configured
+	}
+	if *timeout <= 0 {
+		*timeout = 30 * time.Second
+	}
+
+	fmt.Printf("Checking %s: %s\n\n", name, template)
+	ctx := context.Background()
+	executor := NewExternalCommandExecutor(name, template, *timeout)
+	t := &selfTest{}
+	switch name {
+	case CommandOwnerSignover:
+		checkOwnerSignoverCommand(ctx, t, executor, *serial, *model, *lot)
+	case CommandOVEExtraData:
+		checkOVEExtraDataCommand(ctx, t, template, *timeout, *serial, *model)
+	case CommandVoucherUpload:
+		checkVoucherUploadCommand(ctx, t, executor, *serial, *model)
+	}
+
+	fmt.Println()
+	t.print()
+	if failed := t.failed(); failed > 0 {
+		return fmt.Errorf("%d of %d checks failed", failed, len(t.results))
+	}
+	fmt.Printf("\n✅ %s conforms\n", name)
+	return nil
+}
+
+// checkOwnerSignoverCommand checks an owner key command: one JSON object
+// naming the owner, with only the members the station reads, and the same
+// owner for the same device on a second run, since results are cached by lot
+func checkOwnerSignoverCommand(ctx context.Context, t *selfTest, executor *ExternalCommandExecutor, serial, model, lot string) {
+	variables := map[string]string{"serialno": serial, "model": model, "lot": lot, "guid": ""}
+	var output string
+	t.run("runs", func() (string, error) {
+		var err error
+		output, err = executor.Execute(ctx, variables)
+		return fmt.Sprintf("%d bytes on stdout", len(output)), err
+	})
+	if t.failed() > 0 {
+		t.skip("response", "command failed")
+		return
+	}
+
+	var response OwnerKeyResponse
+	t.run("response", func() (string, error) {
+		decoder := json.NewDecoder(strings.NewReader(output))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&response); err != nil {
+			return "", fmt.Errorf("not an owner key response: %w", err)
+		}
+		if decoder.More() {
+			return "", fmt.Errorf("more than one JSON value on stdout")
+		}
+		return "one JSON object", nil
+	})
+	if t.failed() > 0 {
+		return
+	}
+	if response.Error != "" {
+		t.run("owner", func() (string, error) {
+			return fmt.Sprintf("device refused: %s; check a device the command knows with -serial and -model", response.Error), errSelfTestSkipped
+		})
+		return
+	}
+
+	t.run("owner", func() (string, error) {
+		switch {
+		case response.OwnerKeyPEM == "" && response.OwnerDID == "" && response.OwnerKeyURL == "":
+			return "", fmt.Errorf("none of owner_key_pem, owner_did or owner_key_url is set")
+		case response.OwnerDID != "" && response.OwnerKeyURL != "":
+			return "", fmt.Errorf("owner_did and owner_key_url are both set")
+		case response.OwnerDID != "" && !strings.HasPrefix(response.OwnerDID, "did:"):
+			return "", fmt.Errorf("owner_did %q is not a DID", response.OwnerDID)
+		case response.OwnerKeyURL != "":
+			if _, err := ownerKeyURLDomain(response.OwnerKeyURL); err != nil {
+				return "", err
+			}
+		case response.OwnerKeyKID != "":
+			return "", fmt.Errorf("owner_key_kid needs an owner_key_url")
+		}
+		if response.OwnerKeyKID != "" && strings.Contains(response.OwnerKeyURL, "#") {
+			return "", fmt.Errorf("owner_key_kid needs an owner_key_url without a #kid")
+		}
+		if response.OwnerKeyPEM == "" {
+			return "names " + response.OwnerDID + response.OwnerKeyURL + " (not resolved)", nil
+		}
+		key, err := parsePublicKeyFromPEM([]byte(response.OwnerKeyPEM))
+		if err != nil {
+			return "", fmt.Errorf("owner_key_pem: %w", err)
+		}
+		chain, err := parseCertificateChainPEM([]byte(response.OwnerKeyPEM))
+		if err != nil {
+			return "", fmt.Errorf("owner_key_pem: %w", err)
+		}
+		detail := fmt.Sprintf("%s key %s", describeKey(key), ownerKeySHA256(key))
+		if len(chain) > 0 {
+			detail += fmt.Sprintf(", %d certificates", len(chain))
+		}
+		return detail, nil
+	})
+	t.run("key_encoding", func() (string, error) {
+		if response.KeyEncoding == "" {
+			return "", errSelfTestSkipped
+		}
+		return response.KeyEncoding, validateOwnerKeyEncoding(response.KeyEncoding)
+	})
+	t.run("upload_auth_profile", func() (string, error) {
+		if response.UploadAuthProfile == "" {
+			return "", errSelfTestSkipped
+		}
+		if _, ok := config.VoucherManagement.UploadAuthProfiles[response.UploadAuthProfile]; !ok {
+			return "", fmt.Errorf("profile %q is not in upload_auth_profiles", response.UploadAuthProfile)
+		}
+		return response.UploadAuthProfile, nil
+	})
+	t.run("stable", func() (string, error) {
+		again, err := executor.Execute(ctx, variables)
+		if err != nil {
+			return "", fmt.Errorf("second run failed: %w", err)
+		}
+		var second OwnerKeyResponse
+		if err := json.Unmarshal([]byte(again), &second); err != nil {
+			return "", fmt.Errorf("second run: %w", err)
+		}
+		if second.OwnerKeyPEM != response.OwnerKeyPEM || second.OwnerDID != response.OwnerDID || second.OwnerKeyURL != response.OwnerKeyURL {
+			return "", fmt.Errorf("a second run for the same device named a different owner")
+		}
+		return "same owner on a second run", nil
+	})
+}
+
+// checkOVEExtraDataCommand runs an OVE extra data command through the same
+// parsing and ove_extra_data.validation as the station
+func checkOVEExtraDataCommand(ctx context.Context, t *selfTest, template string, timeout time.Duration, serial, model string) {
+	oveConfig := config.VoucherManagement.OVEExtraData
+	oveConfig.Enabled = true
+	oveConfig.ExternalCommand = template
+	oveConfig.Timeout = timeout
+	oveConfig.IncludeStationInfo = false
+	service := NewOVEExtraDataService(&oveConfig, NewExternalCommandExecutor(CommandOVEExtraData, template, timeout), BuildInfo{})
+
+	t.run("extra data", func() (string, error) {
+		extraData, err := service.GetOVEExtraData(ctx, serial, model)
+		if err != nil {
+			return "", err
+		}
+		if len(extraData) == 0 {
+			return "no extra data", nil
+		}
+		size := 0
+		for _, value := range extraData {
+			size += len(value)
+		}
+		return fmt.Sprintf("%d entries, %d bytes of CBOR", len(extraData), size), nil
+	})
+}
+
+// checkVoucherUploadCommand hands an upload command a fixture voucher twice:
+// the station retries with the same GUID, so a voucher already delivered must
+// succeed again. The command must leave the voucher file as it found it.
+func checkVoucherUploadCommand(ctx context.Context, t *selfTest, executor *ExternalCommandExecutor, serial, model string) {
+	dir, err := os.MkdirTemp("", "fdo-command-check-*")
+	if err != nil {
+		t.run("voucher", func() (string, error) { return "", err })
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	var data []byte
+	var variables map[string]string
+	t.run("voucher", func() (string, error) {
+		ov, err := fdotest.SignedVoucher(fdotest.OwnerP384)
+		if err != nil {
+			return "", err
+		}
+		if data, err = fdotest.VoucherCBOR(ov); err != nil {
+			return "", err
+		}
+		path := filepath.Join(dir, "voucher.cbor")
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			return "", err
+		}
+		variables = map[string]string{
+			"serialno":       serial,
+			"model":          model,
+			"voucherfile":    path,
+			"guid":           fdotest.GUID,
+			"did_url":        "",
+			"voucher_sha256": voucherHash(data),
+		}
+		return "fixture voucher " + fdotest.GUID + " (test key, not deliverable)", nil
+	})
+	if t.failed() > 0 {
+		return
+	}
+
+	upload := func(check string, wantDuplicate bool) {
+		t.run(check, func() (string, error) {
+			output, err := executor.Execute(ctx, variables)
+			if err != nil {
+				return "", err
+			}
+			trimmed := strings.TrimSpace(output)
+			if trimmed == "" || !strings.HasPrefix(trimmed, "{") {
+				return "exit 0, no receipt printed", nil
+			}
+			var response UploadResponse
+			if err := json.Unmarshal([]byte(trimmed), &response); err != nil {
+				return "", fmt.Errorf("stdout looks like JSON but is not an upload response: %w", err)
+			}
+			detail := fmt.Sprintf("status %q, receipt %q", response.Status, response.receiptID())
+			if wantDuplicate && !response.isDuplicate() {
+				detail += "; not reported as a duplicate"
+			}
+			return detail, nil
+		})
+	}
+	upload("upload", false)
+	upload("retry", true)
+
+	t.run("voucher file", func() (string, error) {
+		after, err := os.ReadFile(variables["voucherfile"])
+		if errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("the command deleted {voucherfile}; the station removes it itself")
+		}
+		if err != nil {
+			return "", err
+		}
+		if !bytes.Equal(after, data) {
+			return "", fmt.Errorf("the command modified {voucherfile}")
+		}
+		return "unchanged", nil
+	})
+}
+
+const ownerSignoverShell = `#!/bin/sh
+# Reference owner_signover command for the FDO manufacturing station.
+#
+#   external_command: "sh owner_signover.sh {serialno} {model} {lot}"
+#
+# Prints one JSON object naming the owner the device's voucher is signed over
+# to: "owner_key_pem" (a PEM public key, or a certificate chain leaf first),
+# "owner_did", or "owner_key_url" with an optional "owner_key_kid". Optional
+# members: "key_encoding" (x509, x5chain or cosekey), "upload_auth_profile",
+# "customer" and "no_cache" (don't reuse the owner for the rest of the lot).
+# To refuse the device, print {"error": "reason"} and exit 0. Exit non-zero
+# only when the command itself fails.
+#
+# Check it before deploying:
+#   fdo-manufacturing-station command check owner_signover "sh owner_signover.sh {serialno} {model} {lot}"
+set -eu
+
+serial="${1:?usage: owner_signover.sh <serial> <model> [lot]}"
+model="${2:-}"
+lot="${3:-}"
+
+# Replace this lookup with the call to your ERP or key service
+OWNER_KEY_FILE="${OWNER_KEY_FILE:-/etc/fdo/owner_key.pem}"
+if [ ! -r "$OWNER_KEY_FILE" ]; then
+	printf '{"error": "no owner for model %s"}\n' "$model"
+	exit 0
+fi
+
+# JSON strings can't hold raw newlines
+pem=$(awk '{ printf "%s\\n", $0 }' "$OWNER_KEY_FILE")
+printf '{"owner_key_pem": "%s"}\n' "$pem"
+`
+
+const ownerSignoverGo = `//go:build ignore
+
+// Reference owner_signover command for the FDO manufacturing station.
+//
+//	external_command: "go run owner_signover.go {serialno} {model} {lot}"
+//
+// Build it with "go build owner_signover.go" to avoid compiling on every
+// device. It prints one JSON object naming the owner the device's voucher is
+// signed over to, or {"error": "reason"} to refuse the device. Exit non-zero
+// only when the command itself fails.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// ownerKeyResponse is what the station reads from stdout. Set one of
+// OwnerKeyPEM, OwnerDID or OwnerKeyURL.
+type ownerKeyResponse struct {
+	OwnerKeyPEM       string ` + "`json:\"owner_key_pem,omitempty\"`" + `       // PEM public key, or certificate chain leaf first
+	OwnerDID          string ` + "`json:\"owner_did,omitempty\"`" + `           // e.g. did:web:owner.example.com
+	OwnerKeyURL       string ` + "`json:\"owner_key_url,omitempty\"`" + `       // https:// URL serving PEM or a JWKS
+	OwnerKeyKID       string ` + "`json:\"owner_key_kid,omitempty\"`" + `       // kid of the key in the owner_key_url JWKS
+	KeyEncoding       string ` + "`json:\"key_encoding,omitempty\"`" + `        // x509, x5chain or cosekey
+	UploadAuthProfile string ` + "`json:\"upload_auth_profile,omitempty\"`" + ` // Named upload auth profile of the owner
+	Customer          string ` + "`json:\"customer,omitempty\"`" + `            // Customer ID, used for quotas
+	NoCache           bool   ` + "`json:\"no_cache,omitempty\"`" + `            // Don't reuse the owner for the rest of the lot
+	Error             string ` + "`json:\"error,omitempty\"`" + `               // Refuse the device
+}
+
+func main() {
+	if len(os.Args) < 3 {
+		fmt.Fprintln(os.Stderr, "usage: owner_signover <serial> <model> [lot]")
+		os.Exit(2)
+	}
+	lot := ""
+	if len(os.Args) > 3 {
+		lot = os.Args[3]
+	}
+	response := lookup(os.Args[1], os.Args[2], lot)
+	if err := json.NewEncoder(os.Stdout).Encode(response); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// lookup finds the owner of a device; replace it with the call to your ERP or key service
+func lookup(serial, model, lot string) ownerKeyResponse {
+	path := os.Getenv("OWNER_KEY_FILE")
+	if path == "" {
+		path = "/etc/fdo/owner_key.pem"
+	}
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return ownerKeyResponse{Error: fmt.Sprintf("no owner for model %s", model)}
+	}
+	return ownerKeyResponse{OwnerKeyPEM: string(pem)}
+}
+`
+
+const oveExtraDataShell = `#!/bin/sh
+# Reference ove_extra_data command for the FDO manufacturing station.
+#
+#   external_command: "sh ove_extra_data.sh {serial} {model}"
+#
+# Prints one JSON object with the extra data for the voucher's entry, or
+# nothing for none. Numeric keys are used as OVEExtraInfoType values, other
+# keys are hashed to one. Values are strings, booleans, numbers (sent as
+# strings) or objects one level deep, and are checked against
+# ove_extra_data.validation. Exit non-zero only when the command fails.
+#
+# Check it before deploying:
+#   fdo-manufacturing-station command check ove_extra_data "sh ove_extra_data.sh {serial} {model}"
+set -eu
+
+serial="${1:?usage: ove_extra_data.sh <serial> <model>}"
+model="${2:-}"
+
+# Replace with the data your owners expect; keep values free of quotes
+printf '{"serial": "%s", "model": "%s", "build_date": "%s"}\n' "$serial" "$model" "$(date -u +%Y-%m-%d)"
+`
+
+const oveExtraDataGo = `//go:build ignore
+
+// Reference ove_extra_data command for the FDO manufacturing station.
+//
+//	external_command: "go run ove_extra_data.go {serial} {model}"
+//
+// Prints one JSON object with the extra data for the voucher's entry, or
+// nothing for none. Numeric keys are used as OVEExtraInfoType values, other
+// keys are hashed to one. Values are strings, booleans, numbers (sent as
+// strings) or objects one level deep.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+func main() {
+	if len(os.Args) < 3 {
+		fmt.Fprintln(os.Stderr, "usage: ove_extra_data <serial> <model>")
+		os.Exit(2)
+	}
+	data := extraData(os.Args[1], os.Args[2])
+	if len(data) == 0 {
+		return
+	}
+	if err := json.NewEncoder(os.Stdout).Encode(data); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// extraData returns the extra data of a device; replace it with the data your owners expect
+func extraData(serial, model string) map[string]any {
+	return map[string]any{
+		"serial":     serial,
+		"model":      model,
+		"build_date": time.Now().UTC().Format("2006-01-02"),
+	}
+}
+`
+
+const voucherUploadShell = `#!/bin/sh
+# Reference voucher_upload command for the FDO manufacturing station.
+#
+#   external_command: "sh voucher_upload.sh {voucherfile} {guid} {serialno} {voucher_sha256}"
+#
+# {voucherfile} is the signed voucher, CBOR, in a temp file the station
+# deletes afterwards; don't modify or remove it. Exit 0 once the voucher is
+# delivered. The station retries a failed upload with the same GUID, so a
+# voucher that was already delivered must succeed again. Optionally print the
+# recipient's response, {"status": "accepted" or "duplicate", "receipt_id":
+# "..."}, to have its receipt recorded.
+#
+# Check it before deploying:
+#   fdo-manufacturing-station command check voucher_upload "sh voucher_upload.sh {voucherfile} {guid} {serialno} {voucher_sha256}"
+set -eu
+
+voucherfile="${1:?usage: voucher_upload.sh <voucherfile> <guid> [serial] [sha256]}"
+guid="${2:?usage: voucher_upload.sh <voucherfile> <guid> [serial] [sha256]}"
+
+# Replace the copy with the delivery to your voucher service
+OUTBOX="${VOUCHER_OUTBOX:-/var/lib/fdo/outbox}"
+mkdir -p "$OUTBOX"
+status=accepted
+if [ -e "$OUTBOX/$guid.cbor" ]; then
+	status=duplicate
+fi
+cp "$voucherfile" "$OUTBOX/$guid.cbor.tmp"
+mv "$OUTBOX/$guid.cbor.tmp" "$OUTBOX/$guid.cbor"
+printf '{"status": "%s", "receipt_id": "%s"}\n' "$status" "$guid"
+`
+
+const voucherUploadGo = `//go:build ignore
+
+// Reference voucher_upload command for the FDO manufacturing station.
+//
+//	external_command: "go run voucher_upload.go {voucherfile} {guid} {serialno} {voucher_sha256}"
+//
+// The voucher file is CBOR in a temp file the station deletes afterwards;
+// don't modify or remove it. Exit 0 once the voucher is delivered; a voucher
+// that was already delivered must succeed again, since the station retries
+// with the same GUID. The JSON printed on stdout is recorded as the receipt.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// uploadResponse is the receipt the station reads from stdout
+type uploadResponse struct {
+	Status    string ` + "`json:\"status\"`" + `     // "accepted", or "duplicate" for a voucher already delivered
+	ReceiptID string ` + "`json:\"receipt_id\"`" + ` // Recipient's ID for the delivery
+}
+
+func main() {
+	if len(os.Args) < 3 {
+		fmt.Fprintln(os.Stderr, "usage: voucher_upload <voucherfile> <guid> [serial] [sha256]")
+		os.Exit(2)
+	}
+	voucher, err := os.ReadFile(os.Args[1])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	response, err := deliver(os.Args[2], voucher)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := json.NewEncoder(os.Stdout).Encode(response); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// deliver stores the voucher in an outbox directory; replace it with the
+// delivery to your voucher service
+func deliver(guid string, voucher []byte) (uploadResponse, error) {
+	outbox := os.Getenv("VOUCHER_OUTBOX")
+	if outbox == "" {
+		outbox = "/var/lib/fdo/outbox"
+	}
+	if err := os.MkdirAll(outbox, 0o755); err != nil {
+		return uploadResponse{}, err
+	}
+	path := filepath.Join(outbox, guid+".cbor")
+	status := "accepted"
+	if _, err := os.Stat(path); err == nil {
+		status = "duplicate"
+	}
+	if err := os.WriteFile(path+".tmp", voucher, 0o644); err != nil {
+		return uploadResponse{}, err
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return uploadResponse{}, err
+	}
+	return uploadResponse{Status: status, ReceiptID: guid}, nil
+}
+`
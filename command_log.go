@@ -0,0 +1,422 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Command log defaults
+const (
+	defaultCommandLogRetention = 30 * 24 * time.Hour
+	defaultCommandLogMaxOutput = 4096
+	maxCommandLogFile          = 1 << 20 // Input files larger than this are not kept for replay
+	commandLogPruneInterval    = time.Hour
+	redactedValue              = "[REDACTED]"
+)
+
+// secretCommandVariables are command variables that are always redacted
+var secretCommandVariables = []string{"password", "token", "hmac_key", "totp_secret"}
+
+// commandLog is set at startup when external_commands.log is enabled. It is
+// nil in offline tools, whose commands are not recorded.
+var commandLog *CommandLog
+
+// CommandInvocation is one recorded external command execution
+type CommandInvocation struct {
+	ID         int64             `json:"id"`
+	Name       string            `json:"name"`     // Command, e.g. CommandVoucherUpload
+	Template   string            `json:"template"` // Command template as configured
+	Command    string            `json:"command"`  // Command line as run, with redacted variables masked
+	Variables  map[string]string `json:"variables"`
+	Files      []string          `json:"files,omitempty"` // Variables whose input file is kept for replay
+	Serial     string            `json:"serial,omitempty"`
+	GUID       string            `json:"guid,omitempty"`
+	ExitCode   int               `json:"exit_code"` // -1 if the command didn't exit on its own (not started, timed out)
+	Error      string            `json:"error,omitempty"`
+	Stdout     string            `json:"stdout"`
+	Stderr     string            `json:"stderr"`
+	Truncated  bool              `json:"truncated"` // Output longer than external_commands.log.max_output was cut
+	DurationMS int64             `json:"duration_ms"`
+	Timeout    time.Duration     `json:"-"`
+	StartedAt  time.Time         `json:"started_at"`
+	files      map[string][]byte // Input file contents by variable
+}
+
+// CommandLog records every external command execution in the station
+// database, with its redacted variables, exit code, duration and truncated
+// output, so a failed callback can be inspected and replayed exactly as the
+// station ran it. Rows older than the retention are pruned.
+type CommandLog struct {
+	config *CommandLogConfig
+	db     *StationDB
+}
+
+// NewCommandLog creates the command log, or returns nil if it is disabled
+func NewCommandLog(config *CommandLogConfig, db *StationDB) *CommandLog {
+	if !config.Enabled {
+		return nil
+	}
+	return &CommandLog{config: config, db: db}
+}
+
+// Initialize creates the command_invocations table if it doesn't exist
+func (l *CommandLog) Initialize(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	_, err := l.db.db.ExecContext(ctx, `
+	CREATE TABLE IF NOT EXISTS command_invocations (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		template TEXT NOT NULL,
+		command TEXT NOT NULL,
+		variables TEXT NOT NULL,
+		files BLOB,
+		serial TEXT NOT NULL,
+		guid TEXT NOT NULL,
+		exit_code INTEGER NOT NULL,
+		error TEXT NOT NULL,
+		stdout TEXT NOT NULL,
+		stderr TEXT NOT NULL,
+		truncated INTEGER NOT NULL,
+		duration_ms INTEGER NOT NULL,
+		timeout_ms INTEGER NOT NULL,
+		started_at INTEGER NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create command_invocations table: %w", err)
+	}
+	return nil
+}
+
+// Run prunes invocations older than the retention until ctx is done
+func (l *CommandLog) Run(ctx context.Context) {
+	ticker := time.NewTicker(commandLogPruneInterval)
+	defer ticker.Stop()
+	for {
+		if err := l.prune(ctx); err != nil {
+			fmt.Printf("⚠️  %v\n", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (l *CommandLog) prune(ctx context.Context) error {
+	retention := l.config.Retention
+	if retention <= 0 {
+		retention = defaultCommandLogRetention
+	}
+	result, err := l.db.db.ExecContext(ctx, `DELETE FROM command_invocations WHERE started_at < ?`, stationClock.Now().Add(-retention).Unix())
+	if err != nil {
+		return fmt.Errorf("failed to prune command log: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n > 0 {
+		fmt.Printf("🧹 Pruned %d command log entries older than %s\n", n, retention)
+	}
+	return nil
+}
+
+// redactCommandVariables masks the variables named in
+// external_commands.log.redact, returning the masked copy and the values to
+// hide in the command line
+func (l *CommandLog) redactCommandVariables(variables map[string]string) (map[string]string, []string) {
+	redacted := make(map[string]string, len(variables))
+	var secrets []string
+	for key, value := range variables {
+		if value != "" && (slices.Contains(l.config.Redact, key) || slices.Contains(secretCommandVariables, key)) {
+			redacted[key] = redactedValue
+			secrets = append(secrets, value)
+			continue
+		}
+		redacted[key] = value
+	}
+	return redacted, secrets
+}
+
+// Record stores one execution. variables are the values substituted into
+// the template, after pseudonymization. Failures are logged and don't affect
+// the command's result.
+func (l *CommandLog) Record(ctx context.Context, inv *CommandInvocation) {
+	if l == nil {
+		return
+	}
+	variables, secrets := l.redactCommandVariables(inv.Variables)
+	for _, secret := range secrets {
+		inv.Command = strings.ReplaceAll(inv.Command, secret, redactedValue)
+	}
+	inv.Variables = variables
+
+	// Input files are gone once the session ends; keep them so replay can recreate them
+	inv.files = map[string][]byte{}
+	for key, value := range variables {
+		if !strings.HasSuffix(key, "file") || value == "" || value == redactedValue {
+			continue
+		}
+		if info, err := os.Stat(value); err != nil || info.Size() > maxCommandLogFile {
+			continue
+		}
+		if data, err := os.ReadFile(value); err == nil {
+			inv.files[key] = data
+		}
+	}
+
+	maxOutput := l.config.MaxOutput
+	if maxOutput <= 0 {
+		maxOutput = defaultCommandLogMaxOutput
+	}
+	var cut bool
+	inv.Stdout, cut = truncateOutput(inv.Stdout, maxOutput)
+	inv.Truncated = cut
+	inv.Stderr, cut = truncateOutput(inv.Stderr, maxOutput)
+	inv.Truncated = inv.Truncated || cut
+
+	varsJSON, err := json.Marshal(inv.Variables)
+	if err != nil {
+		fmt.Printf("⚠️  Failed to record command %s: %v\n", inv.Name, err)
+		return
+	}
+	var files []byte
+	if len(inv.files) > 0 {
+		if files, err = json.Marshal(inv.files); err != nil {
+			fmt.Printf("⚠️  Failed to record command %s: %v\n", inv.Name, err)
+			return
+		}
+	}
+	// Recorded after the command ran, so a cancelled session still gets its entry
+	if _, err := l.db.db.ExecContext(context.WithoutCancel(ctx), `
+	INSERT INTO command_invocations (name, template, command, variables, files, serial, guid, exit_code, error,
+		stdout, stderr, truncated, duration_ms, timeout_ms, started_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		inv.Name, inv.Template, inv.Command, string(varsJSON), files, inv.Variables["serialno"], inv.Variables["guid"],
+		inv.ExitCode, inv.Error, inv.Stdout, inv.Stderr, inv.Truncated, inv.DurationMS, inv.Timeout.Milliseconds(),
+		inv.StartedAt.Unix()); err != nil {
+		fmt.Printf("⚠️  Failed to record command %s: %v\n", inv.Name, err)
+	}
+}
+
+// truncateOutput cuts s to limit bytes and reports whether it was cut
+func truncateOutput(s string, limit int) (string, bool) {
+	if len(s) <= limit {
+		return s, false
+	}
+	return s[:limit], true
+}
+
+const commandInvocationColumns = `id, name, template, command, variables, files, serial, guid, exit_code, error,
+	stdout, stderr, truncated, duration_ms, timeout_ms, started_at`
+
+// scanCommandInvocation reads one command_invocations row
+func scanCommandInvocation(row interface{ Scan(...any) error }) (*CommandInvocation, error) {
+	var inv CommandInvocation
+	var varsJSON string
+	var files []byte
+	var timeoutMS, startedAt int64
+	if err := row.Scan(&inv.ID, &inv.Name, &inv.Template, &inv.Command, &varsJSON, &files, &inv.Serial, &inv.GUID,
+		&inv.ExitCode, &inv.Error, &inv.Stdout, &inv.Stderr, &inv.Truncated, &inv.DurationMS, &timeoutMS, &startedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(varsJSON), &inv.Variables); err != nil {
+		return nil, fmt.Errorf("invalid variables of command invocation %d: %w", inv.ID, err)
+	}
+	if len(files) > 0 {
+		if err := json.Unmarshal(files, &inv.files); err != nil {
+			return nil, fmt.Errorf("invalid files of command invocation %d: %w", inv.ID, err)
+		}
+		for key := range inv.files {
+			inv.Files = append(inv.Files, key)
+		}
+		slices.Sort(inv.Files)
+	}
+	inv.Timeout = time.Duration(timeoutMS) * time.Millisecond
+	inv.StartedAt = time.Unix(startedAt, 0)
+	return &inv, nil
+}
+
+var commandInvocationListSpec = listSpec{
+	Key:         "id",
+	Sorts:       map[string]string{"id": "id", "started_at": "started_at", "duration_ms": "duration_ms"},
+	DefaultSort: "-id",
+	Filters:     map[string]string{"name": "name", "serial": "serial", "guid": "guid", "exit_code": "exit_code"},
+}
+
+// Page returns one page of command invocations
+func (l *CommandLog) Page(ctx context.Context, q *listQuery) ([]CommandInvocation, string, error) {
+	clause, args := q.sql()
+	rows, err := l.db.db.QueryContext(ctx, `SELECT `+commandInvocationColumns+` FROM command_invocations`+clause, args...)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to query command log: %w", err)
+	}
+	defer rows.Close()
+	invocations := []CommandInvocation{}
+	for rows.Next() {
+		inv, err := scanCommandInvocation(rows)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read command log: %w", err)
+		}
+		invocations = append(invocations, *inv)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+	invocations, next := listPage(q, invocations, func(inv CommandInvocation, column string) any {
+		switch column {
+		case "started_at":
+			return inv.StartedAt.Unix()
+		case "duration_ms":
+			return inv.DurationMS
+		default:
+			return inv.ID
+		}
+	})
+	return invocations, next, nil
+}
+
+// ListHandler serves GET /api/executors/invocations with the list parameters of commandInvocationListSpec
+func (l *CommandLog) ListHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if l == nil {
+			writeJSONError(w, http.StatusNotFound, "external command log is disabled")
+			return
+		}
+		q, err := parseListQuery(r, &commandInvocationListSpec)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		invocations, next, err := l.Page(r.Context(), q)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSONList(w, r, invocations, next)
+	})
+}
+
+// runCommandReplay implements "command replay [-dry-run] <id>", which runs a
+// recorded external command again with the variables and input files it had,
+// and compares the outcome with the recorded one
+func runCommandReplay(args []string) error {
+	fs := flag.NewFlagSet("command replay", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "Print the command without running it")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: command replay [-dry-run] <id>")
+	}
+	id, err := strconv.ParseInt(fs.Arg(0), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid invocation id %q", fs.Arg(0))
+	}
+
+	stationDB, err := OpenStationDBReadOnly(stationDBPath(config))
+	if err != nil {
+		return err
+	}
+	defer stationDB.Close()
+	inv, err := scanCommandInvocation(stationDB.db.QueryRow(`SELECT `+commandInvocationColumns+` FROM command_invocations WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("no command invocation %d", id)
+	}
+	if err != nil {
+		return err
+	}
+
+	if name, ok := pluginName(inv.Template); ok {
+		return fmt.Errorf("invocation %d was served by plugin %s; only commands can be replayed", id, name)
+	}
+	if name, ok := extensionName(inv.Template); ok {
+		return fmt.Errorf("invocation %d was served by extension %s; only commands can be replayed", id, name)
+	}
+
+	// Recreate the input files and point their variables at the copies
+	dir, err := os.MkdirTemp("", "fdo-command-replay-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	variables := inv.Variables
+	for key, data := range inv.files {
+		path := filepath.Join(dir, key+filepath.Ext(variables[key]))
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			return err
+		}
+		variables[key] = path
+	}
+	command := inv.Template
+	for key, value := range variables {
+		if value == redactedValue {
+			fmt.Fprintf(os.Stderr, "⚠️  {%s} was redacted when recorded; the replay won't match\n", key)
+		}
+		command = strings.ReplaceAll(command, "{"+key+"}", value)
+	}
+	for key := range variables {
+		if strings.HasSuffix(key, "file") && !slices.Contains(inv.Files, key) && strings.Contains(inv.Template, "{"+key+"}") {
+			fmt.Fprintf(os.Stderr, "⚠️  {%s} was not kept; the command gets the original path\n", key)
+		}
+	}
+
+	fmt.Printf("Invocation %d: %s at %s, exit %d after %dms\n", inv.ID, inv.Name, inv.StartedAt.UTC().Format(time.RFC3339), inv.ExitCode, inv.DurationMS)
+	fmt.Printf("$ %s\n", command)
+	if *dryRun {
+		return nil
+	}
+
+	timeout := inv.Timeout
+	if timeout <= 0 {
+		timeout = time.Minute
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	started := time.Now()
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	stdout, runErr := cmd.Output()
+	exitCode := commandExitCode(runErr)
+
+	fmt.Printf("--- stdout ---\n%s\n--- stderr ---\n%s\n", stdout, stderr.String())
+	fmt.Printf("exit %d after %dms (recorded: exit %d after %dms)\n", exitCode, time.Since(started).Milliseconds(), inv.ExitCode, inv.DurationMS)
+	sameOutput := string(stdout) == inv.Stdout || (inv.Truncated && strings.HasPrefix(string(stdout), inv.Stdout))
+	if exitCode == inv.ExitCode && sameOutput {
+		fmt.Println("✅ Same exit code and output as recorded")
+	} else if exitCode == inv.ExitCode {
+		fmt.Println("ℹ️  Same exit code, different output than recorded")
+	} else {
+		fmt.Println("⚠️  Exit code differs from the recorded run")
+	}
+	return nil
+}
+
+// commandExitCode is the exit code of a finished command, or -1 if it
+// didn't exit on its own
+func commandExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() >= 0 {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
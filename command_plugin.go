@@ -0,0 +1,469 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// pluginPrefix marks an external_command that is served by a plugin, as in
+// "plugin:keyservice"
+const pluginPrefix = "plugin:"
+
+const (
+	defaultPluginHealthInterval = 30 * time.Second
+	defaultPluginHealthTimeout  = 5 * time.Second
+	maxPluginRestartBackoff     = time.Minute
+	maxPluginMessageSize        = 16 << 20
+)
+
+// ErrPluginUnavailable is returned for calls to a plugin that isn't running
+var ErrPluginUnavailable = errors.New("plugin unavailable")
+
+// commandPlugins is set at startup. It is nil when no plugins are configured.
+var commandPlugins *CommandPlugins
+
+// CommandPlugins runs the long-running callback processes of
+// external_commands.plugins. Instead of a process per device, a plugin is
+// started once and answers JSON-RPC 2.0 requests, one JSON object per line,
+// on its stdin and stdout. The method is the command name (owner_signover,
+// voucher_upload, ...), the params are the command's variables and the result
+// is the text the command would have printed. Plugins are health-checked and
+// restarted when they exit or stop answering.
+type CommandPlugins struct {
+	plugins map[string]*commandPlugin
+}
+
+// PluginStatus is the state of one plugin, served by GET /api/executors/plugins
+type PluginStatus struct {
+	Name       string     `json:"name"`
+	Command    string     `json:"command"`
+	Running    bool       `json:"running"`
+	PID        int        `json:"pid,omitempty"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	Restarts   uint64     `json:"restarts"`
+	Calls      uint64     `json:"calls"`
+	Failures   uint64     `json:"failures"`
+	LastHealth *time.Time `json:"last_health,omitempty"` // Last successful health check
+	LastError  string     `json:"last_error,omitempty"`
+}
+
+// commandPlugin is one plugin process and the calls waiting for its answers
+type commandPlugin struct {
+	name   string
+	config PluginConfig
+
+	mu      sync.Mutex
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	ready   chan struct{} // Closed while the process is up
+	nextID  uint64
+	pending map[uint64]chan pluginResponse
+	status  PluginStatus
+
+	writeMu sync.Mutex
+}
+
+// pluginRequest is a JSON-RPC 2.0 request
+type pluginRequest struct {
+	JSONRPC string            `json:"jsonrpc"`
+	ID      uint64            `json:"id"`
+	Method  string            `json:"method"`
+	Params  map[string]string `json:"params"`
+}
+
+// pluginResponse is a JSON-RPC 2.0 response
+type pluginResponse struct {
+	ID     uint64          `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *pluginError    `json:"error"`
+}
+
+// pluginError is the error object of a JSON-RPC 2.0 response
+type pluginError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// NewCommandPlugins creates the configured plugins, or returns nil if there are none
+func NewCommandPlugins(config *ExternalCommandsConfig) *CommandPlugins {
+	if len(config.Plugins) == 0 {
+		return nil
+	}
+	p := &CommandPlugins{plugins: map[string]*commandPlugin{}}
+	for name, pc := range config.Plugins {
+		p.plugins[name] = &commandPlugin{
+			name:    name,
+			config:  pc,
+			ready:   make(chan struct{}),
+			pending: map[uint64]chan pluginResponse{},
+			status:  PluginStatus{Name: name, Command: pc.Command},
+		}
+	}
+	return p
+}
+
+// Start starts every plugin and keeps it running until ctx is done
+func (p *CommandPlugins) Start(ctx context.Context) {
+	if p == nil {
+		return
+	}
+	for _, plugin := range p.plugins {
+		go plugin.supervise(ctx)
+	}
+}
+
+// Call sends a request to the named plugin and returns its result
+func (p *CommandPlugins) Call(ctx context.Context, name, method string, params map[string]string) (string, error) {
+	if p == nil {
+		return "", fmt.Errorf("%w: %s (no plugins running)", ErrPluginUnavailable, name)
+	}
+	plugin, ok := p.plugins[name]
+	if !ok {
+		return "", fmt.Errorf("%w: %s is not configured", ErrPluginUnavailable, name)
+	}
+	return plugin.call(ctx, method, params)
+}
+
+// Stats returns the state of every plugin, sorted by name
+func (p *CommandPlugins) Stats() []PluginStatus {
+	stats := []PluginStatus{}
+	if p == nil {
+		return stats
+	}
+	for _, plugin := range p.plugins {
+		plugin.mu.Lock()
+		stats = append(stats, plugin.status)
+		plugin.mu.Unlock()
+	}
+	slices.SortFunc(stats, func(a, b PluginStatus) int { return cmp.Compare(a.Name, b.Name) })
+	return stats
+}
+
+// Handler serves GET /api/executors/plugins
+func (p *CommandPlugins) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, p.Stats())
+	})
+}
+
+// supervise runs the plugin process, health-checks it and restarts it when it
+// exits, backing off while it keeps failing
+func (c *commandPlugin) supervise(ctx context.Context) {
+	backoff := time.Second
+	for {
+		started := time.Now()
+		exited, err := c.start(ctx)
+		if err != nil {
+			c.fail(err)
+			fmt.Printf("❌ Plugin %s failed to start: %v\n", c.name, err)
+		} else {
+			c.healthCheck(ctx, exited)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(started) > maxPluginRestartBackoff {
+			backoff = time.Second
+		}
+		fmt.Printf("🔁 Restarting plugin %s in %s\n", c.name, backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, maxPluginRestartBackoff)
+
+		c.mu.Lock()
+		c.status.Restarts++
+		c.mu.Unlock()
+	}
+}
+
+// start starts the plugin process and returns a channel closed when it exits
+func (c *commandPlugin) start(ctx context.Context) (<-chan struct{}, error) {
+	cmd := exec.CommandContext(ctx, "sh", "-c", c.config.Command)
+	cmd.Stderr = &pluginLogWriter{name: c.name}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	c.mu.Lock()
+	c.cmd, c.stdin = cmd, stdin
+	c.status.Running = true
+	c.status.PID = cmd.Process.Pid
+	c.status.StartedAt = &now
+	close(c.ready)
+	c.mu.Unlock()
+	fmt.Printf("🔌 Plugin %s started (pid %d)\n", c.name, cmd.Process.Pid)
+
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		c.read(stdout)
+		err := cmd.Wait()
+		if err == nil {
+			err = fmt.Errorf("exited")
+		}
+		c.stopped(err)
+	}()
+	return exited, nil
+}
+
+// read delivers the responses the plugin writes to the calls waiting for them
+func (c *commandPlugin) read(stdout io.Reader) {
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), maxPluginMessageSize)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var resp pluginResponse
+		if err := json.Unmarshal(line, &resp); err != nil {
+			fmt.Printf("⚠️  Plugin %s wrote a line that is not a JSON-RPC response: %v\n", c.name, err)
+			continue
+		}
+		c.mu.Lock()
+		ch, ok := c.pending[resp.ID]
+		delete(c.pending, resp.ID)
+		c.mu.Unlock()
+		if ok {
+			ch <- resp
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		fmt.Printf("⚠️  Plugin %s output unreadable: %v\n", c.name, err)
+		c.kill()
+	}
+}
+
+// stopped fails the calls still waiting on a process that has exited
+func (c *commandPlugin) stopped(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cmd, c.stdin = nil, nil
+	c.ready = make(chan struct{})
+	c.status.Running = false
+	c.status.PID = 0
+	c.status.LastError = fmt.Sprintf("process stopped: %v", err)
+	for id, ch := range c.pending {
+		ch <- pluginResponse{ID: id, Error: &pluginError{Message: c.status.LastError}}
+		delete(c.pending, id)
+	}
+	fmt.Printf("⚠️  Plugin %s %s\n", c.name, c.status.LastError)
+}
+
+// healthCheck calls the plugin's "health" method every health_interval until
+// the process exits, killing it when a check fails
+func (c *commandPlugin) healthCheck(ctx context.Context, exited <-chan struct{}) {
+	interval := c.config.HealthInterval
+	if interval <= 0 {
+		interval = defaultPluginHealthInterval
+	}
+	timeout := c.config.HealthTimeout
+	if timeout <= 0 {
+		timeout = defaultPluginHealthTimeout
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-exited:
+			return
+		case <-ctx.Done():
+			<-exited
+			return
+		case <-ticker.C:
+		}
+		checkCtx, cancel := context.WithTimeout(ctx, timeout)
+		_, err := c.send(checkCtx, "health", map[string]string{})
+		cancel()
+		if err != nil {
+			c.fail(fmt.Errorf("health check failed: %w", err))
+			fmt.Printf("❌ Plugin %s failed its health check: %v\n", c.name, err)
+			c.kill()
+			<-exited
+			return
+		}
+		now := time.Now().UTC()
+		c.mu.Lock()
+		c.status.LastHealth = &now
+		c.mu.Unlock()
+	}
+}
+
+// call sends a command request, counting it in the plugin's status
+func (c *commandPlugin) call(ctx context.Context, method string, params map[string]string) (string, error) {
+	result, err := c.send(ctx, method, params)
+	c.mu.Lock()
+	c.status.Calls++
+	if err != nil {
+		c.status.Failures++
+		c.status.LastError = err.Error()
+	}
+	c.mu.Unlock()
+	return result, err
+}
+
+// send writes a request, waiting for the process to be up, and waits for its response
+func (c *commandPlugin) send(ctx context.Context, method string, params map[string]string) (string, error) {
+	c.mu.Lock()
+	for c.stdin == nil {
+		ready := c.ready
+		c.mu.Unlock()
+		select {
+		case <-ready:
+		case <-ctx.Done():
+			return "", fmt.Errorf("%w: %s is not running: %w", ErrPluginUnavailable, c.name, ctx.Err())
+		}
+		c.mu.Lock()
+	}
+	c.nextID++
+	id := c.nextID
+	ch := make(chan pluginResponse, 1)
+	c.pending[id] = ch
+	stdin := c.stdin
+	c.mu.Unlock()
+
+	data, err := json.Marshal(pluginRequest{JSONRPC: "2.0", ID: id, Method: method, Params: params})
+	if err != nil {
+		c.forget(id)
+		return "", err
+	}
+	c.writeMu.Lock()
+	_, err = stdin.Write(append(data, '\n'))
+	c.writeMu.Unlock()
+	if err != nil {
+		c.forget(id)
+		return "", fmt.Errorf("failed to write to plugin %s: %w", c.name, err)
+	}
+
+	select {
+	case resp := <-ch:
+		if resp.Error != nil && resp.Error.Code == 0 {
+			return "", fmt.Errorf("plugin %s: %s", c.name, resp.Error.Message)
+		}
+		if resp.Error != nil {
+			return "", fmt.Errorf("plugin %s: %s (code %d)", c.name, resp.Error.Message, resp.Error.Code)
+		}
+		var result string
+		if len(resp.Result) > 0 && string(resp.Result) != "null" {
+			if err := json.Unmarshal(resp.Result, &result); err != nil {
+				return "", fmt.Errorf("plugin %s: result is not a string: %w", c.name, err)
+			}
+		}
+		return result, nil
+	case <-ctx.Done():
+		c.forget(id)
+		return "", fmt.Errorf("plugin %s did not answer: %w", c.name, ctx.Err())
+	}
+}
+
+// forget drops a call that is no longer waiting for its response
+func (c *commandPlugin) forget(id uint64) {
+	c.mu.Lock()
+	delete(c.pending, id)
+	c.mu.Unlock()
+}
+
+// fail records the last error of the plugin
+func (c *commandPlugin) fail(err error) {
+	c.mu.Lock()
+	c.status.LastError = err.Error()
+	c.mu.Unlock()
+}
+
+// kill stops the plugin process; the supervisor starts a new one
+func (c *commandPlugin) kill() {
+	c.mu.Lock()
+	cmd := c.cmd
+	c.mu.Unlock()
+	if cmd != nil && cmd.Process != nil {
+		cmd.Process.Kill()
+	}
+}
+
+// pluginLogWriter prints a plugin's stderr, one line at a time, with its name
+type pluginLogWriter struct {
+	name string
+	buf  []byte
+}
+
+func (w *pluginLogWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		fmt.Printf("🔌 [%s] %s\n", w.name, w.buf[:i])
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// pluginName returns the plugin an external_command names, if it names one
+func pluginName(commandTemplate string) (string, bool) {
+	name, ok := strings.CutPrefix(strings.TrimSpace(commandTemplate), pluginPrefix)
+	return strings.TrimSpace(name), ok
+}
+
+// validatePlugins checks the plugin definitions and that every command served
+// by a plugin names one that is defined
+func validatePlugins(cfg *Config) error {
+	for name, plugin := range cfg.ExternalCommands.Plugins {
+		if strings.TrimSpace(plugin.Command) == "" {
+			return fmt.Errorf("external_commands.plugins.%s needs a command", name)
+		}
+	}
+	for field, template := range externalCommandFields(cfg) {
+		name, ok := pluginName(template)
+		if !ok {
+			continue
+		}
+		if _, defined := cfg.ExternalCommands.Plugins[name]; !defined {
+			return fmt.Errorf("%s: plugin %q is not defined in external_commands.plugins", field, name)
+		}
+	}
+	return nil
+}
+
+// externalCommandFields returns the external commands of the pipeline, by config path
+func externalCommandFields(cfg *Config) map[string]string {
+	fields := map[string]string{
+		"voucher_management.owner_signover.external_command":  cfg.VoucherManagement.OwnerSignover.ExternalCommand,
+		"voucher_management.voucher_upload.external_command":  cfg.VoucherManagement.VoucherUpload.ExternalCommand,
+		"voucher_management.voucher_signing.external_command": cfg.VoucherManagement.VoucherSigning.ExternalCommand,
+		"voucher_management.ove_extra_data.external_command":  cfg.VoucherManagement.OVEExtraData.ExternalCommand,
+		"claim_urls.external_command":                         cfg.ClaimURLs.ExternalCommand,
+	}
+	for _, dest := range cfg.VoucherManagement.SaveToDisk.Destinations {
+		fields["voucher_management.save_to_disk.destinations."+dest.Name+".command"] = dest.Command
+	}
+	return fields
+}
@@ -0,0 +1,190 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime"
+	"slices"
+	"sync"
+	"time"
+)
+
+// External command names, used as keys of external_commands.commands
+const (
+	CommandOwnerSignover = "owner_signover"
+	CommandVoucherUpload = "voucher_upload"
+	CommandVoucherSign   = "voucher_signing"
+	CommandOVEExtraData  = "ove_extra_data"
+	CommandAndon         = "andon"
+	CommandClaimURL      = "claim_url"
+	CommandSaveToDisk    = "save_to_disk"
+)
+
+// Errors returned instead of running a command when its pool is saturated
+var (
+	ErrCommandQueueFull    = errors.New("external command queue is full")
+	ErrCommandQueueTimeout = errors.New("timed out waiting for an external command slot")
+)
+
+// commandPools is set at startup. It is nil in offline tools such as voucher
+// replay, where commands run without limits.
+var commandPools *CommandPools
+
+// CommandPools limits how many copies of each external command run at once.
+// At high line rates one process per device would otherwise fork without
+// bound; callers over the limit queue until a slot frees up or the queue
+// timeout passes.
+type CommandPools struct {
+	config *ExternalCommandsConfig
+
+	mu    sync.Mutex
+	pools map[string]*commandPool
+}
+
+// commandPool is the slots and counters of one command
+type commandPool struct {
+	slots        chan struct{}
+	maxQueue     int
+	queueTimeout time.Duration
+
+	mu    sync.Mutex
+	stats CommandPoolStats
+}
+
+// CommandPoolStats is the state of one command's pool, served by GET /api/executors
+type CommandPoolStats struct {
+	Command       string  `json:"command"`
+	MaxConcurrent int     `json:"max_concurrent"`
+	Running       int     `json:"running"`
+	Queued        int     `json:"queued"`
+	Started       uint64  `json:"started"`     // Calls that got a slot
+	Rejected      uint64  `json:"rejected"`    // Calls refused because the queue was full
+	TimedOut      uint64  `json:"timed_out"`   // Calls that gave up waiting for a slot
+	WaitAvgMillis float64 `json:"wait_avg_ms"` // Mean queue wait of started calls
+	WaitMaxMillis float64 `json:"wait_max_ms"` // Longest queue wait of a started call
+	waitTotal     time.Duration
+}
+
+// NewCommandPools creates the per-command pools
+func NewCommandPools(config *ExternalCommandsConfig) *CommandPools {
+	return &CommandPools{config: config, pools: map[string]*commandPool{}}
+}
+
+// pool returns the pool of a command, creating it on first use
+func (p *CommandPools) pool(name string) *commandPool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if pool, ok := p.pools[name]; ok {
+		return pool
+	}
+
+	limit := p.config.Commands[name]
+	maxConcurrent := firstPositive(limit.MaxConcurrent, p.config.MaxConcurrent, 4*runtime.NumCPU())
+	pool := &commandPool{
+		slots:        make(chan struct{}, maxConcurrent),
+		maxQueue:     firstPositive(limit.MaxQueue, p.config.MaxQueue),
+		queueTimeout: time.Duration(firstPositive(int(limit.QueueTimeout), int(p.config.QueueTimeout), int(30*time.Second))),
+		stats:        CommandPoolStats{Command: name, MaxConcurrent: maxConcurrent},
+	}
+	p.pools[name] = pool
+	return pool
+}
+
+// acquire waits for a slot of the named command and returns the function that
+// frees it. A nil *CommandPools never waits.
+func (p *CommandPools) acquire(ctx context.Context, name string) (func(), error) {
+	if p == nil {
+		return func() {}, nil
+	}
+	pool := p.pool(name)
+
+	pool.mu.Lock()
+	if pool.maxQueue > 0 && pool.stats.Queued >= pool.maxQueue && len(pool.slots) == cap(pool.slots) {
+		pool.stats.Rejected++
+		pool.mu.Unlock()
+		return nil, fmt.Errorf("%w: %s has %d calls waiting", ErrCommandQueueFull, name, pool.maxQueue)
+	}
+	pool.stats.Queued++
+	pool.mu.Unlock()
+
+	start := time.Now()
+	timer := time.NewTimer(pool.queueTimeout)
+	defer timer.Stop()
+	var err error
+	select {
+	case pool.slots <- struct{}{}:
+	case <-timer.C:
+		err = fmt.Errorf("%w: %s waited %s", ErrCommandQueueTimeout, name, pool.queueTimeout)
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	wait := time.Since(start)
+
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	pool.stats.Queued--
+	if err != nil {
+		pool.stats.TimedOut++
+		return nil, err
+	}
+	pool.stats.Running++
+	pool.stats.Started++
+	pool.stats.waitTotal += wait
+	pool.stats.WaitMaxMillis = max(pool.stats.WaitMaxMillis, float64(wait.Microseconds())/1000)
+	return func() {
+		pool.mu.Lock()
+		pool.stats.Running--
+		pool.mu.Unlock()
+		<-pool.slots
+	}, nil
+}
+
+// Stats returns the state of every command that has run, sorted by name
+func (p *CommandPools) Stats() []CommandPoolStats {
+	stats := []CommandPoolStats{}
+	if p == nil {
+		return stats
+	}
+	p.mu.Lock()
+	pools := make([]*commandPool, 0, len(p.pools))
+	for _, pool := range p.pools {
+		pools = append(pools, pool)
+	}
+	p.mu.Unlock()
+
+	for _, pool := range pools {
+		pool.mu.Lock()
+		s := pool.stats
+		pool.mu.Unlock()
+		if s.Started > 0 {
+			s.WaitAvgMillis = float64(s.waitTotal.Microseconds()) / 1000 / float64(s.Started)
+		}
+		stats = append(stats, s)
+	}
+	slices.SortFunc(stats, func(a, b CommandPoolStats) int { return cmp.Compare(a.Command, b.Command) })
+	return stats
+}
+
+// Handler serves GET /api/executors
+func (p *CommandPools) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, p.Stats())
+	})
+}
+
+// firstPositive returns the first value above zero, or 0
+func firstPositive(values ...int) int {
+	for _, v := range values {
+		if v > 0 {
+			return v
+		}
+	}
+	return 0
+}
@@ -0,0 +1,92 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Compression of voucher artifacts, on disk and as HTTP Content-Encoding
+const (
+	CompressionNone = ""     // Stored and sent as is
+	CompressionGzip = "gzip" // Files get a .gz suffix; uploads are sent with Content-Encoding: gzip
+	CompressionZstd = "zstd" // Not built in: this station has no zstd codec
+)
+
+// validateCompressions checks every compression setting of the voucher config
+func validateCompressions(config *VoucherConfig) error {
+	if err := validateCompression(config.SaveToDisk.Compression); err != nil {
+		return fmt.Errorf("save_to_disk.compression: %w", err)
+	}
+	if err := validateCompression(config.VoucherUpload.ContentEncoding); err != nil {
+		return fmt.Errorf("voucher_upload.content_encoding: %w", err)
+	}
+	for name, profile := range config.UploadAuthProfiles {
+		if err := validateCompression(profile.ContentEncoding); err != nil {
+			return fmt.Errorf("upload_auth_profiles.%s.content_encoding: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func validateCompression(compression string) error {
+	switch compression {
+	case CompressionNone, "none", CompressionGzip:
+		return nil
+	case CompressionZstd:
+		return fmt.Errorf("zstd is not available in this build (use gzip)")
+	}
+	return fmt.Errorf("unsupported compression %q (want gzip or none)", compression)
+}
+
+// compress encodes data with the named compression
+func compress(compression string, data []byte) ([]byte, error) {
+	if compression != CompressionGzip {
+		return data, nil
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, fmt.Errorf("failed to compress: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// compressedSuffix returns the file suffix of the named compression
+func compressedSuffix(compression string) string {
+	if compression == CompressionGzip {
+		return ".gz"
+	}
+	return ""
+}
+
+// readVoucherArtifact reads a voucher file, decompressing it by its suffix
+func readVoucherArtifact(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(path, ".gz") {
+		return data, nil
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress %s: %w", path, err)
+	}
+	defer zr.Close()
+	data, err = io.ReadAll(zr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress %s: %w", path, err)
+	}
+	return data, nil
+}
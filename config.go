@@ -17,18 +17,20 @@ type Config struct {
 	// Basic configuration
 	Debug bool `yaml:"debug"`
 
+	// Where this station sits in the manufacturing network
+	Station StationConfig `yaml:"station"`
+
+	// FDO protocol versions accepted for DI
+	Protocol ProtocolConfig `yaml:"protocol"`
+
 	// Server configuration
-	Server struct {
-		Addr        string `yaml:"addr"`
-		ExtAddr     string `yaml:"ext_addr"`
-		UseTLS      bool   `yaml:"use_tls"`
-		InsecureTLS bool   `yaml:"insecure_tls"`
-	} `yaml:"server"`
+	Server ServerConfig `yaml:"server"`
 
 	// Database configuration
 	Database struct {
-		Path     string `yaml:"path"`
-		Password string `yaml:"password"`
+		Path        string `yaml:"path"`
+		Password    string `yaml:"password" secret:"true"`
+		StationPath string `yaml:"station_path"` // Station bookkeeping DB (default: <path>-station.db)
 	} `yaml:"database"`
 
 	// Manufacturing configuration
@@ -46,6 +48,576 @@ type Config struct {
 
 	// Voucher management configuration
 	VoucherManagement VoucherConfig `yaml:"voucher_management"`
+
+	// Critical failure notifications
+	Notifications NotificationConfig `yaml:"notifications"`
+
+	// Additional log output targets
+	Logging LoggingConfig `yaml:"logging"`
+
+	// Verbose per-session capture for selected serials
+	DebugCapture DebugCaptureConfig `yaml:"debug_capture"`
+
+	// Admin/reporting HTTP API
+	Admin AdminConfig `yaml:"admin"`
+
+	// Manufacturing quotas per customer/model
+	Quotas QuotaConfig `yaml:"quotas"`
+
+	// Time windows during which manufacturing is permitted
+	Schedule ScheduleConfig `yaml:"schedule"`
+
+	// Operator sign-in required to open a batch before DI is accepted
+	OperatorGate OperatorGateConfig `yaml:"operator_gate"`
+
+	// Production batch/lot tracking
+	Batches BatchConfig `yaml:"batches"`
+
+	// Central management agent (pulls config/routing/revocations from a control plane)
+	Management ManagementConfig `yaml:"management"`
+
+	// Test-only fault injection into pipeline stages
+	FaultInjection FaultInjectionConfig `yaml:"fault_injection"`
+
+	// Modbus/TCP status registers for legacy factory monitoring
+	Modbus ModbusConfig `yaml:"modbus"`
+
+	// Line signal tower driven by failure thresholds
+	Andon AndonConfig `yaml:"andon"`
+
+	// Station-to-station voucher export and import
+	Transfer TransferConfig `yaml:"transfer"`
+
+	// Cold-standby failover
+	Standby StandbyConfig `yaml:"standby"`
+
+	// Concurrency limits of external commands
+	ExternalCommands ExternalCommandsConfig `yaml:"external_commands"`
+
+	// Serial/model extraction for vendor-specific DeviceMfgInfo layouts
+	DeviceInfo DeviceInfoConfig `yaml:"device_info"`
+
+	// Serial/model normalization and pseudonymization
+	SerialRules SerialRulesConfig `yaml:"serial_rules"`
+
+	// Free disk space and database size monitoring
+	DiskMonitor DiskMonitorConfig `yaml:"disk_monitor"`
+
+	// Periodic re-verification of stored vouchers
+	VoucherIntegrity VoucherIntegrityConfig `yaml:"voucher_integrity"`
+
+	// Periodic cleanup of what interrupted work leaves behind
+	GC GCConfig `yaml:"gc"`
+
+	// Periodic anchoring of the audit log's hash chain outside the station
+	AuditAnchor AuditAnchorConfig `yaml:"audit_anchor"`
+
+	// Alerts on vouchers extended to never-before-seen owner keys
+	SignoverAnomaly SignoverAnomalyConfig `yaml:"signover_anomaly"`
+
+	// Staged rollout of new pipeline behaviors by model or share of devices
+	Rollouts RolloutsConfig `yaml:"rollouts"`
+
+	// Language of operator-facing messages, label captions and andon text
+	Localization LocalizationConfig `yaml:"localization"`
+
+	// Owner-facing claim URL generated for each voucher
+	ClaimURLs ClaimURLConfig `yaml:"claim_urls"`
+
+	// GUIDs reserved ahead of manufacturing for pre-printed labels
+	GUIDReservations GUIDReservationConfig `yaml:"guid_reservations"`
+
+	// Admin-issued tokens that let named serials through policy checks
+	OverrideTokens OverrideTokenConfig `yaml:"override_tokens"`
+
+	// Owner keys signed over to only after their owner proves possession
+	OwnerKeyProofs OwnerKeyProofConfig `yaml:"owner_key_proofs"`
+
+	// Tell fixtures to hold off starting devices while the station's queues are backed up
+	Backpressure BackpressureConfig `yaml:"backpressure"`
+
+	// Run the voucher pipeline without keeping or delivering anything, for bring-up and training
+	DryRun DryRunConfig `yaml:"dry_run"`
+
+	// Dropping root after binding listeners, the umask, and the startup permission audit
+	Privileges PrivilegesConfig `yaml:"privileges"`
+
+	// Signed entitlement that unlocks premium features in licensed builds
+	Entitlements EntitlementConfig `yaml:"entitlements"`
+
+	// Settings of compiled-in extensions, by extension name
+	Extensions map[string]map[string]string `yaml:"extensions"`
+}
+
+// GUIDReservationConfig hands out GUIDs before their devices are built
+type GUIDReservationConfig struct {
+	Enabled  bool `yaml:"enabled"`
+	Required bool `yaml:"required"` // Refuse DI for devices without a reserved GUID
+}
+
+// OverrideTokenConfig enables override tokens, which an admin issues to let
+// named serials through policy checks for a limited time
+type OverrideTokenConfig struct {
+	Enabled bool          `yaml:"enabled"`
+	KeyFile string        `yaml:"key_file"` // HMAC key that signs the tokens, at least 32 bytes
+	MaxTTL  time.Duration `yaml:"max_ttl"`  // Longest validity of a token (default 8h)
+}
+
+// OwnerKeyProofConfig requires owner keys to be registered, and proven by
+// signing a station-issued challenge, before devices are signed over to them
+type OwnerKeyProofConfig struct {
+	Enabled      bool          `yaml:"enabled"`
+	ChallengeTTL time.Duration `yaml:"challenge_ttl"` // How long the owner has to sign (default 24h)
+}
+
+// BackpressureConfig sets the queue depths at which DI.AppStart is answered
+// with 503 and Retry-After
+type BackpressureConfig struct {
+	Enabled          bool          `yaml:"enabled"`
+	UploadQueueDepth int           `yaml:"upload_queue_depth"` // Vouchers waiting for batch upload (0 = not checked)
+	CommandQueue     int           `yaml:"command_queue"`      // Calls waiting for a voucher_signing or voucher_upload command slot (0 = not checked)
+	RetryAfter       time.Duration `yaml:"retry_after"`        // Wait suggested to fixtures (default 10s)
+}
+
+// DryRunConfig selects the devices whose voucher pipeline runs as a dry run
+type DryRunConfig struct {
+	Enabled         bool     `yaml:"enabled"`          // Every device is a dry run
+	Profiles        []string `yaml:"profiles"`         // Devices whose owner names these upload auth profiles
+	ReportDirectory string   `yaml:"report_directory"` // Per-device JSON reports (default "dry-run-reports")
+}
+
+// DeviceInfoConfig maps vendor-specific DeviceMfgInfo layouts to a serial number and model
+type DeviceInfoConfig struct {
+	Mappings []DeviceInfoMapping `yaml:"mappings"` // First match wins; devices matching none are used as reported
+}
+
+// DeviceInfoMapping extracts the serial and model from one firmware's layout
+type DeviceInfoMapping struct {
+	Match     string          `yaml:"match"`     // Regexp on the reported DeviceInfo selecting the firmware
+	Source    string          `yaml:"source"`    // Field holding the layout: "device_info" (default) | "serial_number"
+	Format    string          `yaml:"format"`    // "delimited" | "fixed" | "json" | "regex"
+	Delimiter string          `yaml:"delimiter"` // delimited: field separator
+	Pattern   string          `yaml:"pattern"`   // regex: with (?P<serial>...) and/or (?P<model>...) groups
+	Serial    DeviceInfoField `yaml:"serial"`
+	Model     DeviceInfoField `yaml:"model"`
+}
+
+// DeviceInfoField locates one value in a layout; an empty field keeps the reported value
+type DeviceInfoField struct {
+	Field  int    `yaml:"field"`  // delimited: field number, from 1
+	Offset int    `yaml:"offset"` // fixed: first byte, from 0
+	Length int    `yaml:"length"` // fixed: number of bytes
+	Name   string `yaml:"name"`   // json: key, dotted for nested objects
+}
+
+// SerialRulesConfig normalizes reported serials and models, and keeps sensitive ones
+// out of logs, filenames and external commands
+type SerialRulesConfig struct {
+	SerialCase       string   `yaml:"serial_case"`        // "upper" | "lower" | "" (as reported)
+	ModelCase        string   `yaml:"model_case"`         // "upper" | "lower" | "" (as reported)
+	StripPrefixes    []string `yaml:"strip_prefixes"`     // Removed from the start of serials; the first match wins
+	Pseudonymize     []string `yaml:"pseudonymize"`       // Fields ("serial", "model") shown as a keyed hash
+	PseudonymKeyFile string   `yaml:"pseudonym_key_file"` // HMAC key file, required with pseudonymize
+}
+
+// DiskMonitorConfig watches free space for the databases and save_to_disk directory
+type DiskMonitorConfig struct {
+	Enabled             bool          `yaml:"enabled"`
+	Interval            time.Duration `yaml:"interval"`              // Between checks (default 30s)
+	WarnFreeMB          int           `yaml:"warn_free_mb"`          // Warn below this (default 2048)
+	CriticalFreeMB      int           `yaml:"critical_free_mb"`      // Critical floor (default 512)
+	MaxDBSizeMB         int           `yaml:"max_db_size_mb"`        // Warn when a database grows above this (0 = off)
+	RefuseBelowCritical bool          `yaml:"refuse_below_critical"` // Refuse new DI sessions below the critical floor
+}
+
+// VoucherIntegrityConfig schedules the stored voucher verification job
+type VoucherIntegrityConfig struct {
+	Enabled  bool          `yaml:"enabled"`
+	Interval time.Duration `yaml:"interval"` // Between checks (default 24h); the first runs at startup
+}
+
+// GCConfig finds what interrupted work leaves behind on a long-running
+// station: voucher blobs nothing references, upload queue rows whose voucher
+// is gone, and reserved GUIDs and temp dirs of DI sessions that never finished
+type GCConfig struct {
+	Enabled    bool          `yaml:"enabled"`
+	Interval   time.Duration `yaml:"interval"`    // Between runs (default 24h); the first runs at startup
+	Repair     bool          `yaml:"repair"`      // Repair or remove what is found; false = report only
+	StaleAfter time.Duration `yaml:"stale_after"` // Age at which an unfinished DI session is abandoned (default 24h)
+}
+
+// AuditAnchorConfig periodically copies the head of the audit log's hash
+// chain out of the station, so history rewritten afterwards no longer matches
+// what was anchored
+type AuditAnchorConfig struct {
+	Enabled         bool                       `yaml:"enabled"`
+	Interval        time.Duration              `yaml:"interval"` // Between checkpoints (default 1h); the first is taken at startup
+	Sinks           []string                   `yaml:"sinks"`    // "checkpoint_file" | "transparency_log" | "voucher"
+	CheckpointFile  AuditCheckpointFileConfig  `yaml:"checkpoint_file"`
+	TransparencyLog AuditTransparencyLogConfig `yaml:"transparency_log"`
+}
+
+// AuditCheckpointFileConfig writes each checkpoint as a signed file
+type AuditCheckpointFileConfig struct {
+	Directory      string `yaml:"directory"`        // Default "audit-checkpoints"; put it on storage the station can't rewrite
+	SigningKeyFile string `yaml:"signing_key_file"` // PEM private key (default transfer.signing_key_file)
+}
+
+// AuditTransparencyLogConfig submits each checkpoint to an external append-only log
+type AuditTransparencyLogConfig struct {
+	URL     string            `yaml:"url"`     // Receives each checkpoint as a JSON POST
+	Headers map[string]string `yaml:"headers"` // e.g. Authorization
+	Timeout time.Duration     `yaml:"timeout"` // Default 10s
+}
+
+// SignoverAnomalyConfig tracks which owner keys and DIDs each customer/model
+// is signed over to and alerts when a new one appears
+type SignoverAnomalyConfig struct {
+	Enabled        bool              `yaml:"enabled"`
+	MinVouchers    int               `yaml:"min_vouchers"`    // Vouchers a customer/model needs before a new key alerts (default 1)
+	WebhookURL     string            `yaml:"webhook_url"`     // Receives each anomaly as a JSON POST; empty = none
+	WebhookHeaders map[string]string `yaml:"webhook_headers"` // Extra webhook request headers
+	WebhookTimeout time.Duration     `yaml:"webhook_timeout"` // Default 10s
+}
+
+// ClaimURLConfig generates the URL a customer scans to bind a device to its
+// cloud account. Templates take {guid}, {serialno}, {model}, {customer} and
+// {station}; external_command, if set, prints the URL instead.
+type ClaimURLConfig struct {
+	Enabled         bool              `yaml:"enabled"`
+	Template        string            `yaml:"template"`         // e.g. "https://claim.example.com/d/{guid}"
+	Templates       map[string]string `yaml:"templates"`        // Per-customer templates, overriding template
+	ExternalCommand string            `yaml:"external_command"` // Prints the claim URL; same variables
+	Timeout         time.Duration     `yaml:"timeout"`          // external_command timeout (default 10s)
+}
+
+// RolloutsConfig gates new pipeline behaviors, so they can be trialed on one
+// SKU or a share of devices before the whole line uses them
+type RolloutsConfig struct {
+	DIDSignover RolloutRule `yaml:"did_signover"` // Dynamic owner entries naming a DID use it instead of owner_key_pem
+	HTTPUpload  RolloutRule `yaml:"http_upload"`  // voucher_upload.mode http instead of the external command
+}
+
+// RolloutRule selects the devices that get a gated behavior. A disabled rule
+// gives it to every device, as without a rollout.
+type RolloutRule struct {
+	Enabled bool     `yaml:"enabled"`
+	Models  []string `yaml:"models"`  // Model globs (e.g. "SKU42*") whose devices all get the behavior
+	Percent int      `yaml:"percent"` // Share (0-100) of other devices that get it, picked by serial
+}
+
+// ExternalCommandsConfig limits how many copies of each external command run
+// at once. Limits apply per command; calls over the limit wait in a queue.
+type ExternalCommandsConfig struct {
+	MaxConcurrent int                             `yaml:"max_concurrent"` // Per command (default 4 per CPU)
+	MaxQueue      int                             `yaml:"max_queue"`      // Waiting calls per command before new calls fail (0 = unlimited)
+	QueueTimeout  time.Duration                   `yaml:"queue_timeout"`  // Longest wait for a slot (default 30s)
+	Commands      map[string]ExternalCommandLimit `yaml:"commands"`       // Overrides by command: owner_signover, voucher_upload, voucher_signing, ove_extra_data, andon, claim_url, save_to_disk
+	Log           CommandLogConfig                `yaml:"log"`            // Record every execution for audit and replay
+	Plugins       map[string]PluginConfig         `yaml:"plugins"`        // Long-running processes named by "plugin:<name>" commands
+}
+
+// PluginConfig starts a long-running callback process that answers JSON-RPC
+// requests on its stdin and stdout instead of a command per device
+type PluginConfig struct {
+	Command        string        `yaml:"command"`         // Shell command starting the plugin
+	HealthInterval time.Duration `yaml:"health_interval"` // Between health checks (default 30s)
+	HealthTimeout  time.Duration `yaml:"health_timeout"`  // Longest wait for a health check answer (default 5s)
+}
+
+// CommandLogConfig records external command executions in the station database
+type CommandLogConfig struct {
+	Enabled   bool          `yaml:"enabled"`
+	Retention time.Duration `yaml:"retention"`  // How long executions are kept (default 720h)
+	MaxOutput int           `yaml:"max_output"` // Bytes of stdout and stderr kept (default 4096)
+	Redact    []string      `yaml:"redact"`     // Variables recorded as [REDACTED]; token, password and hmac_key always are
+}
+
+// ExternalCommandLimit overrides the limits of one external command
+type ExternalCommandLimit struct {
+	MaxConcurrent int           `yaml:"max_concurrent"`
+	MaxQueue      int           `yaml:"max_queue"`
+	QueueTimeout  time.Duration `yaml:"queue_timeout"`
+}
+
+// StandbyConfig pairs a primary station with a cold standby that keeps copies
+// of its databases and takes over when promoted
+type StandbyConfig struct {
+	ServeSnapshots bool          `yaml:"serve_snapshots"`     // Primary: serve database snapshots (includes keys) on the admin API
+	Primary        string        `yaml:"primary"`             // Standby: primary's admin API base URL, e.g. http://10.1.2.3:8080
+	Token          string        `yaml:"token" secret:"true"` // Standby: primary's admin token
+	Interval       time.Duration `yaml:"interval"`            // Standby: time between syncs (default 30s)
+}
+
+// TransferConfig moves vouchers between stations, e.g. from a contract manufacturer to an OEM hub
+type TransferConfig struct {
+	Enabled         bool             `yaml:"enabled"`          // Keep built vouchers for export; serve /api/transfer
+	SigningKeyFile  string           `yaml:"signing_key_file"` // PEM private key that signs exports (empty = no exports)
+	TrustedStations []TrustedStation `yaml:"trusted_stations"` // Stations imports are accepted from
+}
+
+// TrustedStation is a station whose exports this station imports
+type TrustedStation struct {
+	StationID     string `yaml:"station_id"`
+	PublicKeyFile string `yaml:"public_key_file"` // PEM public key matching the station's signing_key_file
+}
+
+// LocalizationConfig selects the language of operator-facing messages. Requests
+// pick their own with ?lang= or Accept-Language; this is the default.
+type LocalizationConfig struct {
+	Locale    string `yaml:"locale"`    // Station default, e.g. "es" or "pl-PL" (default "en")
+	Directory string `yaml:"directory"` // <locale>.json bundles that add locales or override built-in messages
+}
+
+// PrivilegesConfig lets a station started as root bind low ports and read
+// root-only keys, then continue as an unprivileged user
+type PrivilegesConfig struct {
+	User            string `yaml:"user"`             // Switch to this user once the listeners are bound (root only)
+	Group           string `yaml:"group"`            // Default: the user's primary group
+	Umask           string `yaml:"umask"`            // Octal, e.g. "027", set before any file is created (empty = inherited)
+	PermissionAudit string `yaml:"permission_audit"` // "warn" (default) | "fail" | "off"
+}
+
+// EntitlementConfig points to the entitlement file issued for this site
+type EntitlementConfig struct {
+	File string `yaml:"file"` // Signed entitlement JSON, verified offline against the key built into the binary
+}
+
+// AndonConfig signals the line's light tower when DI keeps failing or uploads back up
+type AndonConfig struct {
+	Enabled     bool          `yaml:"enabled"`
+	Interval    time.Duration `yaml:"interval"`     // How often thresholds are checked (default 15s)
+	Window      time.Duration `yaml:"window"`       // Failure rate window, at most 1h (default 10m)
+	MinSessions int           `yaml:"min_sessions"` // DI sessions in the window before the rate counts (default 5)
+	FailureRate float64       `yaml:"failure_rate"` // Percent of failed DI sessions that raises the alarm; 0 = off
+	QueueDepth  int           `yaml:"queue_depth"`  // Vouchers waiting for batch upload that raise the alarm; 0 = off
+	Actions     []AndonAction `yaml:"actions"`
+}
+
+// AndonAction is one way to drive the tower; every action runs on each state change
+type AndonAction struct {
+	Type     string            `yaml:"type"`                   // "http" | "command" | "mqtt"
+	URL      string            `yaml:"url"`                    // http: receives the event as a JSON POST
+	Headers  map[string]string `yaml:"headers"`                // http: extra request headers
+	Command  string            `yaml:"command"`                // command: {state}, {reason}, {message}, {failure_rate}, {queue_depth}, {station}
+	Broker   string            `yaml:"broker"`                 // mqtt: host:port
+	TLS      bool              `yaml:"tls"`                    // mqtt: connect with TLS
+	Topic    string            `yaml:"topic"`                  // mqtt: the event is published here as retained JSON
+	ClientID string            `yaml:"client_id"`              // mqtt (default "fdo-station-<station_id>")
+	Username string            `yaml:"username"`               // mqtt
+	Password string            `yaml:"password" secret:"true"` // mqtt
+	Timeout  time.Duration     `yaml:"timeout"`                // Per-action timeout (default 10s)
+}
+
+// ModbusConfig exposes the station status as Modbus/TCP registers
+type ModbusConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Addr    string `yaml:"addr"`    // Listen address (default ":1502"; port 502 needs root)
+	UnitID  uint8  `yaml:"unit_id"` // Answer only this unit ID; 0 = answer every unit
+}
+
+// FaultInjectionConfig injects delays and failures into pipeline stages for
+// resilience testing. Never enable it on a production station.
+type FaultInjectionConfig struct {
+	Enabled bool        `yaml:"enabled"`
+	Faults  []FaultRule `yaml:"faults"`
+}
+
+// FaultRule delays and/or fails one pipeline stage
+type FaultRule struct {
+	Stage   string        `yaml:"stage"`   // "did_resolution" | "owner_key" | "ove_extra_data" | "signing" | "upload"
+	Rate    float64       `yaml:"rate"`    // Percent of calls that fail (0-100)
+	Delay   time.Duration `yaml:"delay"`   // Added before every call
+	Status  int           `yaml:"status"`  // upload: HTTP status reported (default 500)
+	Message string        `yaml:"message"` // Error message (default the stage name)
+}
+
+// ManagementConfig configures the central management agent
+type ManagementConfig struct {
+	Enabled       bool          `yaml:"enabled"`
+	URL           string        `yaml:"url"`                 // Control plane base URL; <url>/bundle and <url>/status
+	Token         string        `yaml:"token" secret:"true"` // Bearer token for the control plane (optional)
+	PublicKeyFile string        `yaml:"public_key_file"`     // PEM key that verifies bundle signatures
+	Interval      time.Duration `yaml:"interval"`            // Poll interval (default 5m)
+	Timeout       time.Duration `yaml:"timeout"`             // Request timeout (default 30s)
+}
+
+// ProtocolConfig restricts the FDO protocol versions accepted, e.g. during staged rollouts
+type ProtocolConfig struct {
+	Versions []string `yaml:"versions"` // e.g. ["101"]; empty = every version the station supports
+}
+
+// BatchConfig controls production batches (lots) that vouchers are linked to
+type BatchConfig struct {
+	Required    bool          `yaml:"required"`     // Refuse DI while no batch is open (implied by operator_gate)
+	MaxDuration time.Duration `yaml:"max_duration"` // Open batches close automatically after this (default 12h)
+}
+
+// OperatorGateConfig requires an authenticated operator to open a batch before DI
+type OperatorGateConfig struct {
+	Enabled   bool                 `yaml:"enabled"`
+	Operators []OperatorCredential `yaml:"operators"`
+}
+
+// OperatorCredential lists how one operator may authenticate; either method is accepted
+type OperatorCredential struct {
+	ID          string `yaml:"id"`
+	TOTPSecret  string `yaml:"totp_secret" secret:"true"` // Base32 RFC 6238 secret (SHA-1, 6 digits, 30s)
+	BadgeSHA256 string `yaml:"badge_sha256"`              // Hex SHA-256 of the badge token
+}
+
+// ScheduleConfig restricts DI to configured shift windows
+type ScheduleConfig struct {
+	Timezone string           `yaml:"timezone"` // IANA name, e.g. "Europe/Warsaw" (default: station local time)
+	Windows  []ScheduleWindow `yaml:"windows"`
+}
+
+// ScheduleWindow permits manufacturing for matching devices during a daily time range.
+// A device matched by no window is unrestricted; otherwise it needs one open window.
+type ScheduleWindow struct {
+	Name     string   `yaml:"name"`
+	Customer string   `yaml:"customer"` // Customer named by the owner signover; empty = any
+	Model    string   `yaml:"model"`    // Model glob pattern; empty = any
+	Days     []string `yaml:"days"`     // "mon".."sun"; empty = every day
+	Start    string   `yaml:"start"`    // "HH:MM"
+	End      string   `yaml:"end"`      // "HH:MM"; earlier than start for windows spanning midnight
+}
+
+// StationConfig identifies the site, line and station that vouchers originate from
+type StationConfig struct {
+	SiteCode  string `yaml:"site_code"`  // e.g. "MTY" for the Monterrey plant
+	LineID    string `yaml:"line_id"`    // Production line within the site
+	StationID string `yaml:"station_id"` // Station name, sent to HSM/upload services (default "factory-01")
+}
+
+// ServerConfig is where the station listens. Devices reach the DI endpoint
+// on addr; the admin API is served there too unless admin.addr gives it a
+// listener of its own, e.g. on the management network while addr is on the
+// line VLAN.
+type ServerConfig struct {
+	Addr        string `yaml:"addr"`
+	ExtAddr     string `yaml:"ext_addr"`
+	UseTLS      bool   `yaml:"use_tls"`
+	InsecureTLS bool   `yaml:"insecure_tls"`
+	CertFile    string `yaml:"cert_file"` // PEM certificate served when use_tls is set
+	KeyFile     string `yaml:"key_file"`  // PEM private key of cert_file
+
+	// Separate listener for the admin API, with TLS settings of its own
+	Admin AdminListenerConfig `yaml:"admin"`
+}
+
+// AdminListenerConfig is the listener of the admin API when it doesn't share server.addr
+type AdminListenerConfig struct {
+	Addr         string `yaml:"addr"` // e.g. "10.20.0.5:8443"; empty = the admin API shares server.addr
+	UseTLS       bool   `yaml:"use_tls"`
+	CertFile     string `yaml:"cert_file"`
+	KeyFile      string `yaml:"key_file"`
+	ClientCAFile string `yaml:"client_ca_file"` // Require client certificates signed by this CA; empty = none
+	GRPCAddr     string `yaml:"grpc_addr"`      // Also serve the admin API over gRPC here, with these TLS settings; empty = no gRPC
+}
+
+// AdminConfig enables the admin API under /api/
+type AdminConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Token   string `yaml:"token" secret:"true"` // Bearer token required on admin requests; empty = no auth
+	GraphQL bool   `yaml:"graphql"`             // Serve the read-only GraphQL reporting endpoint at /api/graphql
+
+	// Named admins with tokens of their own, for dual control and the audit trail
+	Users       []AdminUser       `yaml:"users"`
+	DualControl DualControlConfig `yaml:"dual_control"`
+
+	// Response headers, CORS and source address allowlists of the admin API
+	HTTP AdminHTTPConfig `yaml:"http"`
+}
+
+// AdminHTTPConfig hardens the admin API for access from outside the line,
+// e.g. from the factory office VLAN
+type AdminHTTPConfig struct {
+	CORS            AdminCORSConfig     `yaml:"cors"`
+	SecurityHeaders map[string]string   `yaml:"security_headers"` // Added to or replacing the strict defaults; "" drops a default
+	AllowCIDRs      []string            `yaml:"allow_cidrs"`      // Source addresses that may use the admin API; empty = any
+	GroupCIDRs      map[string][]string `yaml:"group_cidrs"`      // Per API group (the path segment after /api/), replacing allow_cidrs
+}
+
+// AdminCORSConfig lets a browser dashboard on another origin call the admin API
+type AdminCORSConfig struct {
+	AllowedOrigins []string      `yaml:"allowed_origins"` // e.g. "https://dashboard.factory.example"; "*" = any; empty = CORS off
+	MaxAge         time.Duration `yaml:"max_age"`         // How long browsers cache a preflight (default 10m)
+}
+
+// AdminUser is one named admin identity
+type AdminUser struct {
+	Name      string   `yaml:"name"`
+	Token     string   `yaml:"token" secret:"true"` // Bearer token identifying this admin
+	Customers []string `yaml:"customers"`           // Only provision entries serving these customers; empty = any
+}
+
+// DualControlConfig makes changes to signover targets wait for a second admin
+type DualControlConfig struct {
+	Enabled bool          `yaml:"enabled"`
+	TTL     time.Duration `yaml:"ttl"` // How long a change waits for approval (default 24h)
+}
+
+// QuotaConfig lists manufacturing quotas enforced at DI time
+type QuotaConfig struct {
+	Rules []QuotaRule `yaml:"rules"`
+}
+
+// QuotaRule limits how many devices may be manufactured for a customer and/or model per period
+type QuotaRule struct {
+	Name     string `yaml:"name"`
+	Customer string `yaml:"customer"` // Customer named by the owner signover; empty = any
+	Model    string `yaml:"model"`    // Model glob pattern (e.g. "GW-*"); empty = any
+	Limit    int    `yaml:"limit"`
+	Period   string `yaml:"period"`  // "day" | "month" (default) | "year" | "total", in UTC
+	WarnAt   int    `yaml:"warn_at"` // Percent of the limit that logs a warning (default 90)
+	Soft     bool   `yaml:"soft"`    // Allow devices past the limit, only logging them
+}
+
+// DebugCaptureConfig enables full protocol/command capture for matching serials
+type DebugCaptureConfig struct {
+	SerialPatterns []string `yaml:"serial_patterns"` // Glob patterns (e.g. "SKU42-*"); empty = disabled
+	Directory      string   `yaml:"directory"`       // Where capture files go (default "debug-capture")
+}
+
+// LoggingConfig lists extra log sinks; stdout logging is always kept
+type LoggingConfig struct {
+	Sinks []LogSinkConfig `yaml:"sinks"`
+}
+
+// LogSinkConfig describes one log sink
+type LogSinkConfig struct {
+	Type string `yaml:"type"` // "syslog" | "eventlog" (Windows only)
+
+	// syslog (RFC 5424)
+	Network            string `yaml:"network"`  // "udp" (default) | "tcp" | "tls"
+	Address            string `yaml:"address"`  // host:port of the collector
+	Facility           string `yaml:"facility"` // Default "local0"
+	AppName            string `yaml:"app_name"` // Default "fdo-manufacturing-station"
+	CAFile             string `yaml:"ca_file"`  // tls: CA bundle for the collector certificate
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+
+	// eventlog
+	Source string `yaml:"source"` // Event source name, default "fdo-manufacturing-station"
+}
+
+// NotificationConfig configures paging for critical conditions
+type NotificationConfig struct {
+	SMTP             SMTPConfig    `yaml:"smtp"`
+	AggregateWindow  time.Duration `yaml:"aggregate_window"`  // Events within this window go out in one email
+	Throttle         time.Duration `yaml:"throttle"`          // Minimum time between emails about the same condition
+	FailureThreshold int           `yaml:"failure_threshold"` // Consecutive failures before a repeated failure is critical
+}
+
+// SMTPConfig describes the mail server and recipients for notifications
+type SMTPConfig struct {
+	Enabled  bool     `yaml:"enabled"`
+	Host     string   `yaml:"host"`
+	Port     int      `yaml:"port"`     // 587 (STARTTLS) by default; 465 uses implicit TLS
+	Username string   `yaml:"username"` // Empty = no SMTP AUTH
+	Password string   `yaml:"password" secret:"true"`
+	From     string   `yaml:"from"`
+	To       []string `yaml:"to"`
 }
 
 // RendezvousEntry represents a single rendezvous endpoint
@@ -59,20 +631,19 @@ type RendezvousEntry struct {
 func DefaultConfig() *Config {
 	return &Config{
 		Debug: false,
-		Server: struct {
-			Addr        string `yaml:"addr"`
-			ExtAddr     string `yaml:"ext_addr"`
-			UseTLS      bool   `yaml:"use_tls"`
-			InsecureTLS bool   `yaml:"insecure_tls"`
-		}{
+		Station: StationConfig{
+			StationID: "factory-01",
+		},
+		Server: ServerConfig{
 			Addr:        "localhost:8080",
 			ExtAddr:     "",
 			UseTLS:      false,
 			InsecureTLS: false,
 		},
 		Database: struct {
-			Path     string `yaml:"path"`
-			Password string `yaml:"password"`
+			Path        string `yaml:"path"`
+			Password    string `yaml:"password" secret:"true"`
+			StationPath string `yaml:"station_path"`
 		}{
 			Path:     "manufacturing.db",
 			Password: "",
@@ -102,25 +673,16 @@ func DefaultConfig() *Config {
 				ExternalCommand: "",               // for hsm mode
 				ExternalTimeout: 30 * time.Second, // for hsm mode
 			},
-			OwnerSignover: struct {
-				Mode            string        `yaml:"mode"`              // "static" or "dynamic"
-				StaticPublicKey string        `yaml:"static_public_key"` // PEM-encoded public key for static mode
-				StaticDID       string        `yaml:"static_did"`        // DID URI for static mode
-				ExternalCommand string        `yaml:"external_command"`  // Command for dynamic mode
-				Timeout         time.Duration `yaml:"timeout"`
-			}{
-				Mode:            "static", // Default to static mode
-				StaticPublicKey: "",       // Empty means no owner signover
-				StaticDID:       "",       // Empty means no DID signover
+			OwnerSignover: OwnerSignoverConfig{
+				Mode:            "none", // No owner signover until a static or dynamic owner is configured
+				StaticPublicKey: "",
+				StaticDID:       "",
 				ExternalCommand: "",
 				Timeout:         10 * time.Second,
 			},
-			VoucherUpload: struct {
-				Enabled         bool          `yaml:"enabled"`
-				ExternalCommand string        `yaml:"external_command"`
-				Timeout         time.Duration `yaml:"timeout"`
-			}{
+			VoucherUpload: VoucherUploadConfig{
 				Enabled:         false,
+				Mode:            "command",
 				ExternalCommand: "",
 				Timeout:         30 * time.Second,
 			},
@@ -133,6 +695,18 @@ func DefaultConfig() *Config {
 				PurgeOnStartup:  false,              // Don't purge on startup by default
 			},
 		},
+		Notifications: NotificationConfig{
+			SMTP: SMTPConfig{
+				Enabled: false,
+				Port:    587,
+			},
+			AggregateWindow:  5 * time.Minute,
+			Throttle:         1 * time.Hour,
+			FailureThreshold: 3,
+		},
+		Batches: BatchConfig{
+			MaxDuration: 12 * time.Hour,
+		},
 	}
 }
 
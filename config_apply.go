@@ -0,0 +1,695 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// restartOnlyConfigPaths are settings that services consume at startup. Changes
+// to them are saved to the config file but only take effect after a restart;
+// everything else is read from the running config on each use.
+var restartOnlyConfigPaths = []string{
+	"debug",
+	"station",
+	"protocol",
+	"server",
+	"database",
+	"manufacturing",
+	"logging",
+	"debug_capture",
+	"admin.enabled",
+	"quotas",
+	"schedule",
+	"operator_gate",
+	"batches",
+	"management",
+	"fault_injection",
+	"modbus",
+	"andon",
+	"transfer",
+	"standby",
+	"external_commands",
+	"extensions",
+	"device_info",
+	"serial_rules",
+	"disk_monitor",
+	"voucher_integrity",
+	"gc.enabled",
+	"gc.interval",
+	"audit_anchor",
+	"localization",
+	"privileges",
+	"entitlements",
+	"signover_anomaly.enabled",
+	"claim_urls.enabled",
+	"guid_reservations.enabled",
+	"override_tokens",
+	"owner_key_proofs",
+	"backpressure.enabled",
+	"notifications.smtp.enabled",
+	"voucher_management.hash_algorithm",
+	"voucher_management.temp_directory",
+	"voucher_management.save_to_disk.manifest",
+	"voucher_management.voucher_signing.fallback",
+	"voucher_management.tls_trust",
+	"voucher_management.voucher_signing",
+	"voucher_management.did_cache",
+	"voucher_management.ove_extra_data.external_command",
+	"voucher_management.ove_extra_data.timeout",
+	"voucher_management.owner_signover.external_command",
+	"voucher_management.owner_signover.timeout",
+	"voucher_management.voucher_upload.mode",
+	"voucher_management.voucher_upload.external_command",
+	"voucher_management.voucher_upload.timeout",
+	"voucher_management.voucher_upload.batch",
+}
+
+// secretConfigPaths are redacted in diffs: the settings tagged secret:"true",
+// with * for a map key. A list holding a secret is redacted as a whole, since
+// diffs treat lists as single values.
+var secretConfigPaths = collectSecretConfigPaths(reflect.TypeOf(Config{}), "")
+
+// configMu guards the running config against a config apply. DI and admin
+// requests hold the read lock while they are served, and background jobs for
+// one round of work; an apply takes the write lock only to copy the changed
+// settings in.
+var configMu sync.RWMutex
+
+// configReadKey marks the context of a request that holds the config read lock
+type configReadKey struct{}
+
+// readingConfig serves each request holding the config read lock
+func readingConfig(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		configMu.RLock()
+		defer configMu.RUnlock()
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), configReadKey{}, true)))
+	})
+}
+
+// withConfigRead runs one round of a background job holding the config read lock
+func withConfigRead(fn func()) {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	fn()
+}
+
+// releaseConfigRead gives up the read lock of a request that is about to
+// change the config, so the change can take the write lock. The returned
+// function takes the read lock back.
+func releaseConfigRead(ctx context.Context) func() {
+	if held, _ := ctx.Value(configReadKey{}).(bool); !held {
+		return func() {}
+	}
+	configMu.RUnlock()
+	return configMu.RLock
+}
+
+// ConfigChange is one setting that differs between the running and submitted config
+type ConfigChange struct {
+	Path            string `json:"path"`
+	Old             any    `json:"old"`
+	New             any    `json:"new"`
+	RestartRequired bool   `json:"restart_required"`
+}
+
+// ConfigDiff is the response of the config diff/apply endpoints
+type ConfigDiff struct {
+	Changes         []ConfigChange `json:"changes"`
+	RestartRequired bool           `json:"restart_required"`
+	Applied         bool           `json:"applied"`
+}
+
+// ConfigManager applies partial config documents pushed by central management
+// tooling. A document is overlaid on the running config, validated with the same
+// checks as startup, saved to the config file and then applied in one step, so
+// either all of it takes effect or none of it does.
+type ConfigManager struct {
+	mu           sync.Mutex // Serializes changes; the running config is only written under configMu as well
+	running      *Config
+	path         string
+	auditLog     *AuditLog
+	destinations *UploadDestinationCatalog // Upload destinations naming the auth profiles; nil unless http upload mode
+}
+
+// NewConfigManager creates a config manager for the running config loaded from path
+func NewConfigManager(running *Config, path string, auditLog *AuditLog, destinations *UploadDestinationCatalog) *ConfigManager {
+	return &ConfigManager{running: running, path: path, auditLog: auditLog, destinations: destinations}
+}
+
+// Diff validates a partial config document and returns how it would change the running config
+func (m *ConfigManager) Diff(ctx context.Context, partial []byte) (*ConfigDiff, error) {
+	defer releaseConfigRead(ctx)()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, diff, err := m.prepare(partial)
+	return diff, err
+}
+
+// Apply validates a partial config document, saves the result to the config file
+// and applies the settings that can change at runtime
+func (m *ConfigManager) Apply(ctx context.Context, partial []byte) (*ConfigDiff, error) {
+	defer releaseConfigRead(ctx)()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	next, diff, err := m.prepare(partial)
+	if err != nil || len(diff.Changes) == 0 {
+		return diff, err
+	}
+	return diff, m.commit(next, diff)
+}
+
+// Update is Apply for a change made in code instead of a partial document;
+// with dryRun it only diffs
+func (m *ConfigManager) Update(ctx context.Context, change func(*Config) error, dryRun bool) (*ConfigDiff, error) {
+	defer releaseConfigRead(ctx)()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	next, diff, err := m.prepareWith(change)
+	if err != nil || dryRun || len(diff.Changes) == 0 {
+		return diff, err
+	}
+	return diff, m.commit(next, diff)
+}
+
+// commit saves a prepared config to the config file and applies the settings
+// that can change at runtime
+func (m *ConfigManager) commit(next *Config, diff *ConfigDiff) error {
+	if err := writeConfigFile(next, m.path); err != nil {
+		return err
+	}
+
+	live, err := liveConfig(m.running, next, diff)
+	if err != nil {
+		return err
+	}
+	configMu.Lock()
+	defer configMu.Unlock()
+	running, changed := reflect.ValueOf(m.running).Elem(), reflect.ValueOf(live).Elem()
+	for _, change := range diff.Changes {
+		if !change.RestartRequired {
+			setLiveSetting(running, changed, strings.Split(change.Path, "."))
+		}
+	}
+	diff.Applied = true
+	return nil
+}
+
+// setLiveSetting copies the setting at a path from live to running, down to
+// the first map or list, so an apply writes only the settings it changes
+func setLiveSetting(running, live reflect.Value, keys []string) {
+	for _, key := range keys {
+		if running.Kind() != reflect.Struct {
+			break
+		}
+		field := -1
+		for i := 0; i < running.NumField(); i++ {
+			if yamlFieldName(running.Type().Field(i)) == key {
+				field = i
+				break
+			}
+		}
+		if field < 0 {
+			break
+		}
+		running, live = running.Field(field), live.Field(field)
+	}
+	running.Set(live)
+}
+
+// prepare overlays the partial document on a copy of the running config and validates it
+func (m *ConfigManager) prepare(partial []byte) (*Config, *ConfigDiff, error) {
+	return m.prepareWith(func(next *Config) error {
+		if err := yaml.Unmarshal(partial, next); err != nil {
+			return fmt.Errorf("invalid config document: %w", err)
+		}
+		return nil
+	})
+}
+
+// prepareWith changes a copy of the running config and validates it
+func (m *ConfigManager) prepareWith(change func(*Config) error) (*Config, *ConfigDiff, error) {
+	next, err := cloneConfig(m.running)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := change(next); err != nil {
+		return nil, nil, err
+	}
+	if err := validateConfig(next); err != nil {
+		return nil, nil, fmt.Errorf("invalid config: %w", err)
+	}
+	diff, err := diffConfig(m.running, next)
+	if err != nil {
+		return nil, nil, err
+	}
+	return next, diff, nil
+}
+
+// validateConfig runs the startup checks that don't need the databases
+func validateConfig(cfg *Config) error {
+	if cfg.Database.Path == "" {
+		return fmt.Errorf("database path must be specified")
+	}
+	if _, err := NewProtocolVersionGate(&cfg.Protocol, nil); err != nil {
+		return err
+	}
+	if _, err := NewManufacturingSchedule(&cfg.Schedule); err != nil {
+		return err
+	}
+	if _, err := NewOperatorGate(&cfg.OperatorGate, nil); err != nil {
+		return err
+	}
+	if err := NewQuotaService(&cfg.Quotas, nil, nil).validate(); err != nil {
+		return err
+	}
+	if err := validateOwnerKeyEncodings(&cfg.VoucherManagement); err != nil {
+		return err
+	}
+	if err := validateCompressions(&cfg.VoucherManagement); err != nil {
+		return err
+	}
+	if err := validateDiskDestinations(&cfg.VoucherManagement); err != nil {
+		return err
+	}
+	if err := validateSignoverPolicies(&cfg.VoucherManagement); err != nil {
+		return err
+	}
+	if err := validateOwnerSignover(&cfg.VoucherManagement.OwnerSignover); err != nil {
+		return err
+	}
+	if err := validateJWKSKeySelection(&cfg.VoucherManagement.DIDCache); err != nil {
+		return err
+	}
+	if err := validateUniversalResolver(&cfg.VoucherManagement.DIDCache); err != nil {
+		return err
+	}
+	if err := validateFallbackSigner(&cfg.VoucherManagement.VoucherSigning); err != nil {
+		return err
+	}
+	if err := validateDryRun(cfg); err != nil {
+		return err
+	}
+	if err := validateDualControl(&cfg.Admin); err != nil {
+		return err
+	}
+	if err := validateAdminHTTP(&cfg.Admin); err != nil {
+		return err
+	}
+	if err := validateServerListeners(&cfg.Server); err != nil {
+		return err
+	}
+	if err := validateAuditAnchor(&cfg.AuditAnchor); err != nil {
+		return err
+	}
+	if err := validateRollouts(cfg); err != nil {
+		return err
+	}
+	if err := validateClaimURLs(&cfg.ClaimURLs); err != nil {
+		return err
+	}
+	if err := validateLocalization(&cfg.Localization); err != nil {
+		return err
+	}
+	if err := validatePrivileges(&cfg.Privileges); err != nil {
+		return err
+	}
+	if err := checkEntitlements(cfg); err != nil {
+		return err
+	}
+	if err := validateRateLimit(&cfg.VoucherManagement.VoucherUpload.RateLimit); err != nil {
+		return err
+	}
+	if err := validateTLSTrust(&cfg.VoucherManagement.TLSTrust); err != nil {
+		return err
+	}
+	if err := validatePlugins(cfg); err != nil {
+		return err
+	}
+	if err := validateExtensions(cfg); err != nil {
+		return err
+	}
+	if _, err := NewVoucherHashPolicy(&cfg.VoucherManagement); err != nil {
+		return err
+	}
+	if _, err := newOVEExtraValidator(&cfg.VoucherManagement.OVEExtraData.Validation); err != nil {
+		return err
+	}
+	if _, err := NewDeviceInfoMapper(&cfg.DeviceInfo); err != nil {
+		return err
+	}
+	if _, err := NewSerialRules(&cfg.SerialRules); err != nil {
+		return err
+	}
+	if _, err := NewDiskMonitor(&cfg.DiskMonitor, cfg, nil, nil); err != nil {
+		return err
+	}
+	return nil
+}
+
+// cloneConfig deep-copies a config through its YAML form
+func cloneConfig(cfg *Config) (*Config, error) {
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling config: %w", err)
+	}
+	clone := &Config{}
+	if err := yaml.Unmarshal(data, clone); err != nil {
+		return nil, fmt.Errorf("error copying config: %w", err)
+	}
+	return clone, nil
+}
+
+// configTree returns the config as nested YAML maps
+func configTree(cfg *Config) (map[string]any, error) {
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling config: %w", err)
+	}
+	tree := map[string]any{}
+	if err := yaml.Unmarshal(data, &tree); err != nil {
+		return nil, fmt.Errorf("error marshaling config: %w", err)
+	}
+	return tree, nil
+}
+
+// flattenConfig maps each dotted setting path to its value; lists are single values
+func flattenConfig(prefix string, node any, out map[string]any) {
+	m, ok := node.(map[string]any)
+	if !ok {
+		out[prefix] = node
+		return
+	}
+	for key, value := range m {
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+		flattenConfig(path, value, out)
+	}
+}
+
+// diffConfig lists the settings that differ between two configs
+func diffConfig(running, next *Config) (*ConfigDiff, error) {
+	oldTree, err := configTree(running)
+	if err != nil {
+		return nil, err
+	}
+	newTree, err := configTree(next)
+	if err != nil {
+		return nil, err
+	}
+	oldFlat, newFlat := map[string]any{}, map[string]any{}
+	flattenConfig("", oldTree, oldFlat)
+	flattenConfig("", newTree, newFlat)
+
+	paths := make([]string, 0, len(newFlat))
+	for path := range newFlat {
+		paths = append(paths, path)
+	}
+	for path := range oldFlat {
+		if _, ok := newFlat[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	diff := &ConfigDiff{Changes: []ConfigChange{}}
+	for _, path := range paths {
+		oldValue, newValue := oldFlat[path], newFlat[path]
+		if reflect.DeepEqual(oldValue, newValue) {
+			continue
+		}
+		if isSecretConfigPath(path) {
+			oldValue, newValue = "***", "***"
+		}
+		change := ConfigChange{Path: path, Old: oldValue, New: newValue, RestartRequired: isRestartOnlyConfigPath(path)}
+		diff.RestartRequired = diff.RestartRequired || change.RestartRequired
+		diff.Changes = append(diff.Changes, change)
+	}
+	return diff, nil
+}
+
+// liveConfig returns the running config with the runtime-changeable settings of the diff applied
+func liveConfig(running, next *Config, diff *ConfigDiff) (*Config, error) {
+	tree, err := configTree(running)
+	if err != nil {
+		return nil, err
+	}
+	newTree, err := configTree(next)
+	if err != nil {
+		return nil, err
+	}
+	for _, change := range diff.Changes {
+		if change.RestartRequired {
+			continue
+		}
+		keys := strings.Split(change.Path, ".")
+		value, ok := lookupConfigPath(newTree, keys)
+		setConfigPath(tree, keys, value, ok)
+	}
+
+	data, err := yaml.Marshal(tree)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling config: %w", err)
+	}
+	live := &Config{}
+	if err := yaml.Unmarshal(data, live); err != nil {
+		return nil, fmt.Errorf("error applying config: %w", err)
+	}
+	return live, nil
+}
+
+// lookupConfigPath returns the value at a path of a config tree
+func lookupConfigPath(tree map[string]any, keys []string) (any, bool) {
+	var node any = tree
+	for _, key := range keys {
+		m, ok := node.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if node, ok = m[key]; !ok {
+			return nil, false
+		}
+	}
+	return node, true
+}
+
+// setConfigPath sets (or, if !ok, deletes) the value at a path of a config tree
+func setConfigPath(tree map[string]any, keys []string, value any, ok bool) {
+	for _, key := range keys[:len(keys)-1] {
+		child, isMap := tree[key].(map[string]any)
+		if !isMap {
+			child = map[string]any{}
+			tree[key] = child
+		}
+		tree = child
+	}
+	if ok {
+		tree[keys[len(keys)-1]] = value
+	} else {
+		delete(tree, keys[len(keys)-1])
+	}
+}
+
+func isRestartOnlyConfigPath(path string) bool {
+	return slices.ContainsFunc(restartOnlyConfigPaths, func(prefix string) bool {
+		return path == prefix || strings.HasPrefix(path, prefix+".")
+	})
+}
+
+func isSecretConfigPath(path string) bool {
+	keys := strings.Split(path, ".")
+	return slices.ContainsFunc(secretConfigPaths, func(secret string) bool {
+		pattern := strings.Split(secret, ".")
+		if len(keys) < len(pattern) {
+			return false
+		}
+		for i, key := range pattern {
+			if key != "*" && key != keys[i] {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// collectSecretConfigPaths lists the paths of the secret settings of a config struct
+func collectSecretConfigPaths(t reflect.Type, prefix string) []string {
+	var paths []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := yamlFieldName(field)
+		if name == "" {
+			continue
+		}
+		path := name
+		if prefix != "" {
+			path = prefix + "." + name
+		}
+		if field.Tag.Get("secret") == "true" {
+			paths = append(paths, path)
+			continue
+		}
+		paths = append(paths, secretPathsOf(field.Type, path)...)
+	}
+	return paths
+}
+
+// secretPathsOf lists the secret paths below a setting of type t
+func secretPathsOf(t reflect.Type, path string) []string {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return secretPathsOf(t.Elem(), path)
+	case reflect.Struct:
+		return collectSecretConfigPaths(t, path)
+	case reflect.Map:
+		return secretPathsOf(t.Elem(), path+".*")
+	case reflect.Slice, reflect.Array:
+		if len(secretPathsOf(t.Elem(), path)) > 0 {
+			return []string{path}
+		}
+	}
+	return nil
+}
+
+// yamlFieldName is the key of a struct field in the config file, or "" if it has none
+func yamlFieldName(field reflect.StructField) string {
+	if !field.IsExported() {
+		return ""
+	}
+	name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return strings.ToLower(field.Name)
+	}
+	return name
+}
+
+// writeConfigFile replaces the config file atomically so a crash never leaves it half written
+func writeConfigFile(cfg *Config, path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".config-*.yaml")
+	if err != nil {
+		return fmt.Errorf("error writing config file %q: %w", path, err)
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error writing config file %q: %w", path, err)
+	}
+	if err := SaveConfig(cfg, tmp.Name()); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("error writing config file %q: %w", path, err)
+	}
+	return nil
+}
+
+// DiffHandler serves POST /api/config/diff
+func (m *ConfigManager) DiffHandler() http.Handler {
+	return m.handler(false)
+}
+
+// ApplyHandler serves POST /api/config/apply
+func (m *ConfigManager) ApplyHandler() http.Handler {
+	return m.handler(true)
+}
+
+// handler reads a partial YAML (or JSON) config document and diffs or applies it
+func (m *ConfigManager) handler(apply bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		partial, err := io.ReadAll(io.LimitReader(r.Body, 1024*1024))
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("failed to read config document: %v", err))
+			return
+		}
+
+		var diff *ConfigDiff
+		if apply {
+			// The config serves every customer
+			if err := checkAdminCustomers(r.Context(), "the station config", nil, true); err != nil {
+				writeJSONError(w, http.StatusForbidden, err.Error())
+				return
+			}
+			diff, err = m.Apply(r.Context(), partial)
+		} else {
+			diff, err = m.Diff(r.Context(), partial)
+		}
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		if diff.Applied {
+			m.recordApplied(r.Context(), diff, "admin API from "+r.RemoteAddr)
+		}
+		writeJSON(w, http.StatusOK, diff)
+	})
+}
+
+// recordApplied logs and audits an applied config change
+func (m *ConfigManager) recordApplied(ctx context.Context, diff *ConfigDiff, source string) {
+	paths := configChangePaths(diff)
+	fmt.Printf("⚙️  Config updated via %s: %s\n", source, paths)
+	m.auditLog.Record(ctx, AuditEvent{
+		Event:  "config_applied",
+		Detail: fmt.Sprintf("%s (restart required: %v)", paths, diff.RestartRequired),
+	})
+}
+
+// describeApply files config pushes that change signover or admin settings
+// for a second admin's approval
+func (m *ConfigManager) describeApply(r *http.Request, body []byte) (*ChangeRequest, error) {
+	if err := checkAdminCustomers(r.Context(), "the station config", nil, true); err != nil {
+		return nil, err
+	}
+	diff, err := m.Diff(r.Context(), body)
+	if err != nil || !needsDualControl(diff) {
+		return nil, err
+	}
+	return &ChangeRequest{Target: configChangePaths(diff), Changes: diff.Changes}, nil
+}
+
+// applyApproved applies an approved config push
+func (m *ConfigManager) applyApproved(ctx context.Context, change *ChangeRequest) (any, error) {
+	if err := checkAdminCustomers(ctx, "the station config", nil, true); err != nil {
+		return nil, err
+	}
+	diff, err := m.Apply(ctx, change.payload)
+	if err != nil {
+		return nil, err
+	}
+	if diff.Applied {
+		m.recordApplied(ctx, diff, "approved change "+change.ID)
+	}
+	return diff, nil
+}
+
+// configChangePaths lists the paths a diff changes
+func configChangePaths(diff *ConfigDiff) string {
+	paths := make([]string, len(diff.Changes))
+	for i, change := range diff.Changes {
+		paths[i] = change.Path
+	}
+	return strings.Join(paths, ", ")
+}
@@ -0,0 +1,115 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestConfigApplyWhileReading applies config changes, from a request as the
+// admin API does, while other requests and a background job read the running
+// config. Run it with -race.
+func TestConfigApplyWhileReading(t *testing.T) {
+	running := DefaultConfig()
+	m := NewConfigManager(running, filepath.Join(t.TempDir(), "config.yaml"), nil, nil)
+	reader := readingConfig(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, running.Notifications.Throttle)
+	}))
+	applier := readingConfig(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		partial, _ := io.ReadAll(r.Body)
+		if _, err := m.Apply(r.Context(), partial); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+	}))
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				if i == 0 {
+					withConfigRead(func() { _ = running.Notifications.Throttle })
+					continue
+				}
+				reader.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+			}
+		}()
+	}
+
+	for i := 1; i <= 20; i++ {
+		recorder := httptest.NewRecorder()
+		partial := fmt.Sprintf("notifications:\n  throttle: %dm\n", i)
+		applier.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(partial)))
+		if recorder.Code != http.StatusOK {
+			t.Errorf("apply %d: HTTP %d: %s", i, recorder.Code, recorder.Body)
+			break
+		}
+	}
+	close(stop)
+	wg.Wait()
+
+	if running.Notifications.Throttle != 20*time.Minute {
+		t.Errorf("running notifications.throttle is %v, want 20m", running.Notifications.Throttle)
+	}
+}
+
+// TestConfigDiffRedactsSecrets checks that a changed upload auth token, and a
+// list holding admin tokens, are redacted in a config diff
+func TestConfigDiffRedactsSecrets(t *testing.T) {
+	running := DefaultConfig()
+	running.VoucherManagement.UploadAuthProfiles = map[string]UploadAuthProfile{
+		"acme": {Type: "bearer", Token: "old-upload-token"},
+	}
+	running.Admin.Users = []AdminUser{{Name: "alice", Token: "old-admin-token"}}
+	next, err := cloneConfig(running)
+	if err != nil {
+		t.Fatal(err)
+	}
+	profile := next.VoucherManagement.UploadAuthProfiles["acme"]
+	profile.Token = "new-upload-token"
+	next.VoucherManagement.UploadAuthProfiles["acme"] = profile
+	next.Admin.Users[0].Token = "new-admin-token"
+	next.Admin.Users[0].Customers = []string{"acme"}
+
+	diff, err := diffConfig(running, next)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changed := map[string]ConfigChange{}
+	for _, change := range diff.Changes {
+		changed[change.Path] = change
+		if text := fmt.Sprint(change.Old, change.New); strings.Contains(text, "-token") {
+			t.Errorf("%s: secret shown in diff: %s", change.Path, text)
+		}
+	}
+	for _, path := range []string{"voucher_management.upload_auth_profiles.acme.token", "admin.users"} {
+		change, ok := changed[path]
+		if !ok {
+			t.Errorf("%s: change missing from diff %+v", path, diff.Changes)
+			continue
+		}
+		if change.Old != "***" || change.New != "***" {
+			t.Errorf("%s: got %v -> %v, want both redacted", path, change.Old, change.New)
+		}
+	}
+	if isSecretConfigPath("voucher_management.upload_auth_profiles.acme.hmac_header") {
+		t.Error("hmac_header is not a secret")
+	}
+}
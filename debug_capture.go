@@ -0,0 +1,255 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// debugCaptureIdle is how long an unfinished captured session is kept open
+const debugCaptureIdle = 10 * time.Minute
+
+// DebugCapture records full DI protocol messages and external command I/O, but
+// only for sessions whose device serial matches one of the configured patterns.
+// The serial is only known once DI.AppStart has been decoded, so the capture is
+// started from the DeviceInfo callback (Tag) and then follows the session token.
+type DebugCapture struct {
+	config *DebugCaptureConfig
+
+	mu       sync.Mutex
+	sessions map[string]*captureSession // keyed by session token
+}
+
+// captureSession is the capture file for one matched DI session
+type captureSession struct {
+	serial   string
+	mu       sync.Mutex
+	file     *os.File
+	lastSeen time.Time
+}
+
+// captureRequest is attached to each request context so callbacks and external
+// commands running inside the request can find the session's capture
+type captureRequest struct {
+	capture *DebugCapture
+	session *captureSession
+}
+
+type captureRequestKey struct{}
+
+// NewDebugCapture creates a debug capture, or returns nil if no serial patterns are configured
+func NewDebugCapture(config *DebugCaptureConfig) *DebugCapture {
+	if len(config.SerialPatterns) == 0 {
+		return nil
+	}
+	return &DebugCapture{
+		config:   config,
+		sessions: make(map[string]*captureSession),
+	}
+}
+
+// Middleware wraps the FDO message handler to record exchanges of captured sessions
+func (d *DebugCapture) Middleware(next http.Handler) http.Handler {
+	if d == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqBody, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "failed to read request", http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(reqBody))
+
+		token := r.Header.Get("Authorization")
+		creq := &captureRequest{capture: d, session: d.lookup(token)}
+		alreadyTracked := creq.session != nil
+
+		rec := &captureResponseWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r.WithContext(context.WithValue(r.Context(), captureRequestKey{}, creq)))
+
+		session := creq.session
+		if session == nil {
+			return
+		}
+
+		msg := r.PathValue("msg")
+		session.write("→ request msg=%s\n%s", msg, hex.Dump(reqBody))
+		session.write("← response msg=%s status=%d\n%s", rec.Header().Get("Message-Type"), rec.status, hex.Dump(rec.body.Bytes()))
+
+		// DI.AppStart returns the session token in the response
+		if !alreadyTracked {
+			if respToken := rec.Header().Get("Authorization"); respToken != "" {
+				token = respToken
+			}
+			d.track(token, session)
+		}
+
+		// DI.Done (13) or an error ends the session
+		if msg == "13" || rec.status >= 400 {
+			d.finish(token, session)
+		}
+	})
+}
+
+// Tag starts a capture for the current session if the serial matches a pattern
+func (d *DebugCapture) Tag(ctx context.Context, serial string) {
+	if d == nil {
+		return
+	}
+	creq, ok := ctx.Value(captureRequestKey{}).(*captureRequest)
+	if !ok || creq.session != nil || !d.matches(serial) {
+		return
+	}
+
+	session, err := d.open(serial)
+	if err != nil {
+		fmt.Printf("⚠️  Failed to start debug capture for %s: %v\n", serialRules.Serial(serial), err)
+		return
+	}
+	creq.session = session
+}
+
+// debugCaptureCommand records external command I/O if the context belongs to a captured session
+func debugCaptureCommand(ctx context.Context, command string, stdout, stderr []byte, err error) {
+	creq, ok := ctx.Value(captureRequestKey{}).(*captureRequest)
+	if !ok || creq.session == nil {
+		return
+	}
+	result := "ok"
+	if err != nil {
+		result = err.Error()
+	}
+	creq.session.write("$ %s\n--- stdout ---\n%s\n--- stderr ---\n%s\n--- result: %s\n", command, stdout, stderr, result)
+}
+
+// matches reports whether a serial matches any configured pattern
+func (d *DebugCapture) matches(serial string) bool {
+	for _, pattern := range d.config.SerialPatterns {
+		if ok, _ := path.Match(pattern, serial); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// open creates the capture file for a session
+func (d *DebugCapture) open(serial string) (*captureSession, error) {
+	dir := d.config.Directory
+	if dir == "" {
+		dir = "debug-capture"
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+
+	// Serials come from the device, so keep only filename-safe characters
+	safe := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.' {
+			return r
+		}
+		return '_'
+	}, serialRules.Serial(serial))
+	name := filepath.Join(dir, fmt.Sprintf("%s-%s.log", safe, time.Now().UTC().Format("20060102T150405.000000000")))
+
+	file, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("🔬 Debug capture started for serial %s: %s\n", serialRules.Serial(serial), name)
+
+	session := &captureSession{serial: serial, file: file, lastSeen: time.Now()}
+	session.write("debug capture for serial %s\n", serialRules.Serial(serial))
+	return session, nil
+}
+
+// lookup returns the capture session for a token, if any
+func (d *DebugCapture) lookup(token string) *captureSession {
+	if token == "" {
+		return nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.sessions[token]
+}
+
+// track associates a session with its token and closes captures abandoned mid-session
+func (d *DebugCapture) track(token string, session *captureSession) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for t, s := range d.sessions {
+		if time.Since(s.lastSeen) > debugCaptureIdle {
+			s.write("capture abandoned (session idle)\n")
+			s.close()
+			delete(d.sessions, t)
+		}
+	}
+	if token != "" {
+		d.sessions[token] = session
+	}
+}
+
+// finish closes a session's capture
+func (d *DebugCapture) finish(token string, session *captureSession) {
+	d.mu.Lock()
+	delete(d.sessions, token)
+	d.mu.Unlock()
+
+	session.write("capture complete\n")
+	session.close()
+	fmt.Printf("🔬 Debug capture finished for serial %s\n", serialRules.Serial(session.serial))
+}
+
+// write appends a timestamped entry to the capture file
+func (s *captureSession) write(format string, args ...any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return
+	}
+	s.lastSeen = time.Now()
+	fmt.Fprintf(s.file, "[%s] ", s.lastSeen.UTC().Format(time.RFC3339Nano))
+	fmt.Fprintf(s.file, format, args...)
+	fmt.Fprintln(s.file)
+}
+
+// close closes the capture file
+func (s *captureSession) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file != nil {
+		_ = s.file.Close()
+		s.file = nil
+	}
+}
+
+// captureResponseWriter keeps a copy of the response for the capture
+type captureResponseWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *captureResponseWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *captureResponseWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
@@ -0,0 +1,172 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Device info mapping formats
+const (
+	DeviceInfoFormatDelimited = "delimited" // Fields separated by a delimiter
+	DeviceInfoFormatFixed     = "fixed"     // Fixed-width fields at byte offsets
+	DeviceInfoFormatJSON      = "json"      // A JSON object
+	DeviceInfoFormatRegex     = "regex"     // A regexp with "serial" and "model" groups
+)
+
+// DeviceInfoMapper extracts the serial number and model from DeviceMfgInfo
+// layouts that don't follow the standard one, e.g. firmware that packs both
+// into the DeviceInfo string and leaves SerialNumber empty. The first mapping
+// whose match pattern matches the raw DeviceInfo is used. A nil
+// *DeviceInfoMapper leaves device info as reported.
+type DeviceInfoMapper struct {
+	mappings []deviceInfoMapping
+}
+
+// deviceInfoMapping is a compiled DeviceInfoMapping
+type deviceInfoMapping struct {
+	config  *DeviceInfoMapping
+	match   *regexp.Regexp
+	pattern *regexp.Regexp
+}
+
+// NewDeviceInfoMapper compiles the configured mappings, or returns nil if there are none
+func NewDeviceInfoMapper(config *DeviceInfoConfig) (*DeviceInfoMapper, error) {
+	if len(config.Mappings) == 0 {
+		return nil, nil
+	}
+	m := &DeviceInfoMapper{}
+	for i := range config.Mappings {
+		mapping := &config.Mappings[i]
+		compiled := deviceInfoMapping{config: mapping}
+		var err error
+		if compiled.match, err = regexp.Compile(mapping.Match); err != nil {
+			return nil, fmt.Errorf("device_info mapping %d: invalid match: %w", i, err)
+		}
+		switch mapping.Source {
+		case "", "device_info", "serial_number":
+		default:
+			return nil, fmt.Errorf("device_info mapping %d: source must be device_info or serial_number", i)
+		}
+		switch mapping.Format {
+		case DeviceInfoFormatDelimited:
+			if mapping.Delimiter == "" {
+				return nil, fmt.Errorf("device_info mapping %d: delimited format needs a delimiter", i)
+			}
+		case DeviceInfoFormatFixed, DeviceInfoFormatJSON:
+		case DeviceInfoFormatRegex:
+			if compiled.pattern, err = regexp.Compile(mapping.Pattern); err != nil {
+				return nil, fmt.Errorf("device_info mapping %d: invalid pattern: %w", i, err)
+			}
+			if compiled.pattern.SubexpIndex("serial") < 0 && compiled.pattern.SubexpIndex("model") < 0 {
+				return nil, fmt.Errorf("device_info mapping %d: pattern has no (?P<serial>...) or (?P<model>...) group", i)
+			}
+		default:
+			return nil, fmt.Errorf("device_info mapping %d: format must be delimited, fixed, json or regex", i)
+		}
+		m.mappings = append(m.mappings, compiled)
+	}
+	return m, nil
+}
+
+// Map returns the serial number and model of a device. Fields a mapping
+// doesn't extract, or can't find in the payload, keep their reported value.
+func (m *DeviceInfoMapper) Map(serial, deviceInfo string) (string, string) {
+	if m == nil {
+		return serial, deviceInfo
+	}
+	for i, mapping := range m.mappings {
+		if !mapping.match.MatchString(deviceInfo) {
+			continue
+		}
+		source := deviceInfo
+		if mapping.config.Source == "serial_number" {
+			source = serial
+		}
+		mappedSerial, mappedModel, err := mapping.extract(source)
+		if err != nil {
+			fmt.Printf("⚠️  Device info mapping %d failed for %q: %v\n", i, source, err)
+			return serial, deviceInfo
+		}
+		if mappedSerial != "" {
+			serial = mappedSerial
+		}
+		if mappedModel != "" {
+			deviceInfo = mappedModel
+		}
+		return serial, deviceInfo
+	}
+	return serial, deviceInfo
+}
+
+// extract reads the serial and model out of a payload; "" means not mapped
+func (m *deviceInfoMapping) extract(source string) (string, string, error) {
+	serialField, modelField := m.config.Serial, m.config.Model
+	switch m.config.Format {
+	case DeviceInfoFormatDelimited:
+		fields := strings.Split(source, m.config.Delimiter)
+		field := func(f DeviceInfoField) string {
+			if f.Field < 1 || f.Field > len(fields) {
+				return ""
+			}
+			return strings.TrimSpace(fields[f.Field-1])
+		}
+		return field(serialField), field(modelField), nil
+
+	case DeviceInfoFormatFixed:
+		field := func(f DeviceInfoField) string {
+			if f.Length <= 0 || f.Offset < 0 || f.Offset >= len(source) {
+				return ""
+			}
+			return strings.TrimSpace(source[f.Offset:min(f.Offset+f.Length, len(source))])
+		}
+		return field(serialField), field(modelField), nil
+
+	case DeviceInfoFormatJSON:
+		var object map[string]any
+		if err := json.Unmarshal([]byte(source), &object); err != nil {
+			return "", "", fmt.Errorf("not a JSON object: %w", err)
+		}
+		field := func(f DeviceInfoField) string {
+			if f.Name == "" {
+				return ""
+			}
+			var value any = object
+			for _, key := range strings.Split(f.Name, ".") {
+				parent, ok := value.(map[string]any)
+				if !ok {
+					return ""
+				}
+				value = parent[key]
+			}
+			switch v := value.(type) {
+			case string:
+				return strings.TrimSpace(v)
+			case float64:
+				return strconv.FormatFloat(v, 'f', -1, 64)
+			}
+			return ""
+		}
+		return field(serialField), field(modelField), nil
+
+	case DeviceInfoFormatRegex:
+		groups := m.pattern.FindStringSubmatch(source)
+		if groups == nil {
+			return "", "", fmt.Errorf("pattern does not match")
+		}
+		group := func(name string) string {
+			if i := m.pattern.SubexpIndex(name); i >= 0 {
+				return strings.TrimSpace(groups[i])
+			}
+			return ""
+		}
+		return group("serial"), group("model"), nil
+	}
+	return "", "", nil
+}
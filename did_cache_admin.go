@@ -0,0 +1,147 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// DIDCacheStatus is a cached DID resolution as the admin API reports it
+type DIDCacheStatus struct {
+	DID                string     `json:"did"`
+	KeySHA256          string     `json:"key_sha256"` // Hex SHA-256 of the cached key's SubjectPublicKeyInfo
+	DIDURL             string     `json:"did_url,omitempty"`
+	CachedAt           time.Time  `json:"cached_at"`
+	LastUsed           time.Time  `json:"last_used"`
+	LastRefreshAttempt *time.Time `json:"last_refresh_attempt,omitempty"`
+	LastRefreshError   string     `json:"last_refresh_error,omitempty"`
+}
+
+// DIDCachePurgeRequest is the body of POST /api/did/cache/purge
+type DIDCachePurgeRequest struct {
+	All bool `json:"all"` // Every entry, not just those unused for did_cache.purge_unused
+}
+
+// DIDCachePurgeResult is the response of POST /api/did/cache/purge
+type DIDCachePurgeResult struct {
+	Purged int `json:"purged"`
+}
+
+// CacheStatus returns the cache entry of a DID, or nil if it is not cached
+func (r *DIDResolver) CacheStatus(ctx context.Context, didURI string) (*DIDCacheStatus, error) {
+	entry, err := r.getFromCache(ctx, didURI)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the DID cache: %w", err)
+	}
+	status := &DIDCacheStatus{
+		DID:              entry.DIDURI,
+		DIDURL:           entry.DIDURL,
+		CachedAt:         entry.Timestamp,
+		LastUsed:         entry.LastUsed,
+		LastRefreshError: entry.LastRefreshError,
+	}
+	if key, err := r.deserializePublicKey(entry.PublicKey); err == nil {
+		status.KeySHA256 = ownerKeySHA256(key)
+	}
+	if !entry.LastRefreshAttempt.IsZero() {
+		status.LastRefreshAttempt = &entry.LastRefreshAttempt
+	}
+	return status, nil
+}
+
+// Evict removes a DID from the cache, so the next device resolves it again.
+// It reports whether the DID was cached.
+func (r *DIDResolver) Evict(ctx context.Context, didURI string) (bool, error) {
+	state, ok := r.sessionState.(interface {
+		exec(context.Context, string, map[string]any) (int64, error)
+	})
+	if !ok {
+		return false, fmt.Errorf("session state does not support database operations")
+	}
+	n, err := state.exec(ctx, "DELETE FROM did_cache WHERE did_uri = :did_uri", map[string]any{"did_uri": didURI})
+	if err != nil {
+		return false, fmt.Errorf("failed to evict %s from the DID cache: %w", didURI, err)
+	}
+	return n > 0, nil
+}
+
+// CacheStatusHandler serves GET /api/did/cache/{did}
+func (r *DIDResolver) CacheStatusHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if r == nil {
+			writeJSONError(w, http.StatusNotFound, "the DID cache is disabled")
+			return
+		}
+		didURI := req.PathValue("did")
+		status, err := r.CacheStatus(req.Context(), didURI)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if status == nil {
+			writeJSONError(w, http.StatusNotFound, fmt.Sprintf("%s is not cached", didURI))
+			return
+		}
+		writeJSON(w, http.StatusOK, status)
+	})
+}
+
+// EvictHandler serves DELETE /api/did/cache/{did}
+func (r *DIDResolver) EvictHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if r == nil {
+			writeJSONError(w, http.StatusNotFound, "the DID cache is disabled")
+			return
+		}
+		didURI := req.PathValue("did")
+		found, err := r.Evict(req.Context(), didURI)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if !found {
+			writeJSONError(w, http.StatusNotFound, fmt.Sprintf("%s is not cached", didURI))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// PurgeHandler serves POST /api/did/cache/purge, the admin API counterpart of
+// -purge-did-cache-expired and -purge-did-cache-all for a running station
+func (r *DIDResolver) PurgeHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if r == nil {
+			writeJSONError(w, http.StatusNotFound, "the DID cache is disabled")
+			return
+		}
+		var body DIDCachePurgeRequest
+		if req.ContentLength != 0 {
+			if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+				writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid purge request: %v", err))
+				return
+			}
+		}
+		purge := r.PurgeExpired
+		if body.All {
+			purge = r.PurgeAll
+		}
+		n, err := purge(req.Context())
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, DIDCachePurgeResult{Purged: n})
+	})
+}
@@ -0,0 +1,59 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// parseDIDJWK decodes the public key of a did:jwk URI: the base64url JSON of
+// a JWK. EC (P-256, P-384), RSA and OKP Ed25519 keys are decoded; a JWK that
+// carries private key members is refused. A fragment (#0) is ignored.
+func (r *DIDResolver) parseDIDJWK(didURI string) (crypto.PublicKey, error) {
+	value, ok := strings.CutPrefix(didURI, "did:jwk:")
+	if !ok {
+		return nil, fmt.Errorf("not a did:jwk: %s", didURI)
+	}
+	value, _, _ = strings.Cut(value, "#")
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64url value: %w", err)
+	}
+	var jwk map[string]any
+	if err := json.Unmarshal(data, &jwk); err != nil {
+		return nil, fmt.Errorf("invalid JWK: %w", err)
+	}
+	for _, private := range []string{"d", "p", "q", "dp", "dq", "qi"} {
+		if _, ok := jwk[private]; ok {
+			return nil, fmt.Errorf("did:jwk contains a private key")
+		}
+	}
+
+	var publicKey crypto.PublicKey
+	if kty, _ := jwk["kty"].(string); kty == "OKP" {
+		if crv, _ := jwk["crv"].(string); crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported OKP curve %q", crv)
+		}
+		x, err := jwkMember(jwk, "x")
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("Ed25519 key is %d bytes, expected %d", len(x), ed25519.PublicKeySize)
+		}
+		publicKey = ed25519.PublicKey(x)
+	} else if publicKey, err = r.parseJWKPublicKey(jwk); err != nil {
+		return nil, err
+	}
+	if err := checkJWKAlgorithm(jwk, publicKey); err != nil {
+		return nil, err
+	}
+	return publicKey, nil
+}
@@ -0,0 +1,120 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/multiformats/go-multibase"
+)
+
+// Multicodec codes of the public key types did:key carries
+// (https://github.com/multiformats/multicodec/blob/master/table.csv)
+const (
+	multicodecSecp256k1Pub = 0xe7
+	multicodecEd25519Pub   = 0xed
+	multicodecP256Pub      = 0x1200
+	multicodecP384Pub      = 0x1201
+)
+
+// parseDIDKey decodes the public key of a did:key URI: a base58btc multibase
+// value ("z...") holding a varint multicodec code and the raw key bytes.
+// EC keys are compressed points. A fragment (#...) is ignored; did:key only
+// ever names its one key.
+func parseDIDKey(didURI string) (crypto.PublicKey, error) {
+	value, ok := strings.CutPrefix(didURI, "did:key:")
+	if !ok {
+		return nil, fmt.Errorf("not a did:key: %s", didURI)
+	}
+	value, _, _ = strings.Cut(value, "#")
+	return parseMultikey(value)
+}
+
+// parseMultikey decodes a multibase public key with a multicodec prefix, the
+// value of a did:key and of publicKeyMultibase in DID documents
+// (Multikey, Ed25519VerificationKey2020)
+func parseMultikey(value string) (crypto.PublicKey, error) {
+	encoding, data, err := multibase.Decode(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid multibase value: %w", err)
+	}
+	if encoding != multibase.Base58BTC {
+		return nil, fmt.Errorf("multikey must be base58btc encoded (z...), got multibase %q", string(rune(encoding)))
+	}
+	code, n := binary.Uvarint(data)
+	if n <= 0 {
+		return nil, fmt.Errorf("invalid multicodec prefix")
+	}
+	raw := data[n:]
+
+	switch code {
+	case multicodecP256Pub:
+		return compressedECDSAKey(elliptic.P256(), raw)
+	case multicodecP384Pub:
+		return compressedECDSAKey(elliptic.P384(), raw)
+	case multicodecEd25519Pub:
+		if len(raw) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("Ed25519 key is %d bytes, expected %d", len(raw), ed25519.PublicKeySize)
+		}
+		return ed25519.PublicKey(raw), nil
+	case multicodecSecp256k1Pub:
+		key, err := secp256k1.ParsePubKey(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid secp256k1 key: %w", err)
+		}
+		return key.ToECDSA(), nil
+	}
+	return nil, fmt.Errorf("unsupported multicodec 0x%x (expected P-256, P-384, Ed25519 or secp256k1)", code)
+}
+
+// parseBase58Key decodes the deprecated publicKeyBase58 of a verification
+// method: raw key bytes without a multicodec prefix, so the key type comes
+// from the method type, or for NIST curves from the length of the point
+func parseBase58Key(value, methodType string) (crypto.PublicKey, error) {
+	_, raw, err := multibase.Decode("z" + value) // base58btc without its multibase prefix
+	if err != nil {
+		return nil, fmt.Errorf("invalid base58 value: %w", err)
+	}
+	switch methodType {
+	case "Ed25519VerificationKey2018", "Ed25519VerificationKey2020":
+		if len(raw) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("Ed25519 key is %d bytes, expected %d", len(raw), ed25519.PublicKeySize)
+		}
+		return ed25519.PublicKey(raw), nil
+	case "EcdsaSecp256k1VerificationKey2019":
+		key, err := secp256k1.ParsePubKey(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid secp256k1 key: %w", err)
+		}
+		return key.ToECDSA(), nil
+	}
+	switch len(raw) {
+	case 33:
+		return compressedECDSAKey(elliptic.P256(), raw)
+	case 49:
+		return compressedECDSAKey(elliptic.P384(), raw)
+	case 65:
+		return ecdsa.ParseUncompressedPublicKey(elliptic.P256(), raw)
+	case 97:
+		return ecdsa.ParseUncompressedPublicKey(elliptic.P384(), raw)
+	}
+	return nil, fmt.Errorf("unsupported base58 key of %d bytes for verification method type %q", len(raw), methodType)
+}
+
+// compressedECDSAKey decodes a compressed NIST curve point
+func compressedECDSAKey(curve elliptic.Curve, raw []byte) (*ecdsa.PublicKey, error) {
+	x, y := elliptic.UnmarshalCompressed(curve, raw)
+	if x == nil {
+		return nil, fmt.Errorf("invalid compressed %s key", curve.Params().Name)
+	}
+	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+}
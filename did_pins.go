@@ -0,0 +1,307 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DID pin defaults
+const (
+	defaultMaxDIDPinDuration = 72 * time.Hour
+	didPinCheckInterval      = time.Minute
+)
+
+// ErrDIDNotResolved marks a pin request for a DID the station holds no resolved key of
+var ErrDIDNotResolved = errors.New("DID has no resolved key to pin")
+
+// DIDPin holds a DID to one resolved key until it expires
+type DIDPin struct {
+	DID       string           `json:"did"`
+	KeySHA256 string           `json:"key_sha256"` // Hex SHA-256 of the pinned key's SubjectPublicKeyInfo
+	DIDURL    string           `json:"did_url,omitempty"`
+	PinnedBy  string           `json:"pinned_by,omitempty"`
+	Reason    string           `json:"reason,omitempty"`
+	PinnedAt  time.Time        `json:"pinned_at"`
+	ExpiresAt time.Time        `json:"expires_at"`
+	key       crypto.PublicKey // The pinned key
+}
+
+// DIDPinRequest is the body of PUT /api/did/pins/{did}
+type DIDPinRequest struct {
+	KeySHA256 string `json:"key_sha256"` // Key to pin; must be the DID's last resolved key. Empty = that key.
+	Duration  string `json:"duration"`   // How long to pin, e.g. "6h"; at most did_cache.max_pin_duration
+	Reason    string `json:"reason"`
+}
+
+// DIDPins lets an admin hold a DID to the key it last resolved to for a
+// bounded time, e.g. while the owner's web server is flapping. A pinned DID is
+// not fetched: devices are signed over to the pinned key until the pin
+// expires or is removed. Only a key the station has already resolved and
+// accepted for the DID can be pinned, so a pin can't redirect vouchers to a
+// new owner. Pins are kept in the station database, expire on their own, and
+// are audited as did_pinned and did_unpinned.
+type DIDPins struct {
+	config    *DIDCache
+	db        *StationDB
+	rotations *DIDRotations
+	auditLog  *AuditLog
+
+	mu   sync.Mutex
+	pins map[string]*DIDPin // Keyed by DID URI
+}
+
+// NewDIDPins creates the pin store
+func NewDIDPins(config *DIDCache, db *StationDB, rotations *DIDRotations, auditLog *AuditLog) *DIDPins {
+	return &DIDPins{
+		config:    config,
+		db:        db,
+		rotations: rotations,
+		auditLog:  auditLog,
+		pins:      make(map[string]*DIDPin),
+	}
+}
+
+// Initialize creates the did_pins table if it doesn't exist and loads the stored pins
+func (p *DIDPins) Initialize(ctx context.Context) error {
+	_, err := p.db.db.ExecContext(ctx, `
+	CREATE TABLE IF NOT EXISTS did_pins (
+		did TEXT PRIMARY KEY,
+		public_key BLOB NOT NULL,
+		did_url TEXT NOT NULL,
+		pinned_by TEXT NOT NULL,
+		reason TEXT NOT NULL,
+		pinned_at INTEGER NOT NULL,
+		expires_at INTEGER NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create did_pins table: %w", err)
+	}
+
+	rows, err := p.db.db.QueryContext(ctx, `
+	SELECT did, public_key, did_url, pinned_by, reason, pinned_at, expires_at FROM did_pins`)
+	if err != nil {
+		return fmt.Errorf("failed to read DID pins: %w", err)
+	}
+	defer rows.Close()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	resolver := NewDIDResolver(nil, p.config)
+	for rows.Next() {
+		var pin DIDPin
+		var der []byte
+		var pinnedAt, expiresAt int64
+		if err := rows.Scan(&pin.DID, &der, &pin.DIDURL, &pin.PinnedBy, &pin.Reason, &pinnedAt, &expiresAt); err != nil {
+			return fmt.Errorf("failed to read DID pin: %w", err)
+		}
+		if pin.key, err = resolver.deserializePublicKey(der); err != nil {
+			return fmt.Errorf("failed to read pinned key of %s: %w", pin.DID, err)
+		}
+		pin.KeySHA256 = ownerKeySHA256(pin.key)
+		pin.PinnedAt, pin.ExpiresAt = time.Unix(pinnedAt, 0), time.Unix(expiresAt, 0)
+		p.pins[pin.DID] = &pin
+	}
+	return rows.Err()
+}
+
+// Run removes expired pins until ctx is done
+func (p *DIDPins) Run(ctx context.Context) {
+	ticker := time.NewTicker(didPinCheckInterval)
+	defer ticker.Stop()
+	for {
+		p.expire(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Lookup returns the pinned key of a DID, if it has an unexpired pin
+func (p *DIDPins) Lookup(didURI string) (crypto.PublicKey, string, bool) {
+	if p == nil {
+		return nil, "", false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pin, ok := p.pins[didURI]
+	if !ok || !stationClock.Now().Before(pin.ExpiresAt) {
+		return nil, "", false
+	}
+	return pin.key, pin.DIDURL, true
+}
+
+// Pin holds a DID to its last resolved key for duration. keySHA256, if set,
+// must name that key.
+func (p *DIDPins) Pin(ctx context.Context, didURI, keySHA256 string, duration time.Duration, reason, identity string) (*DIDPin, error) {
+	key, didURL, ok := p.rotations.current(didURI)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrDIDNotResolved, didURI)
+	}
+	if keySHA256 != "" && !strings.EqualFold(keySHA256, ownerKeySHA256(key)) {
+		return nil, fmt.Errorf("%w: %s last resolved to %s, not %s", ErrDIDNotResolved, didURI, ownerKeySHA256(key), keySHA256)
+	}
+	der, err := serializePublicKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize key: %w", err)
+	}
+
+	now := stationClock.Now()
+	pin := &DIDPin{
+		DID:       didURI,
+		KeySHA256: ownerKeySHA256(key),
+		DIDURL:    didURL,
+		PinnedBy:  identity,
+		Reason:    reason,
+		PinnedAt:  now,
+		ExpiresAt: now.Add(duration),
+		key:       key,
+	}
+	if _, err := p.db.db.ExecContext(ctx, `
+	INSERT OR REPLACE INTO did_pins (did, public_key, did_url, pinned_by, reason, pinned_at, expires_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?)`, didURI, der, didURL, identity, reason, now.Unix(), pin.ExpiresAt.Unix()); err != nil {
+		return nil, fmt.Errorf("failed to store DID pin: %w", err)
+	}
+	p.mu.Lock()
+	p.pins[didURI] = pin
+	p.mu.Unlock()
+
+	fmt.Printf("📌 %s pinned to key %s until %s\n", didURI, pin.KeySHA256, pin.ExpiresAt.Format(time.RFC3339))
+	p.auditLog.Record(ctx, AuditEvent{
+		Event:  "did_pinned",
+		Detail: fmt.Sprintf("%s pinned to key %s until %s by %s: %s", didURI, pin.KeySHA256, pin.ExpiresAt.UTC().Format(time.RFC3339), identity, reason),
+	})
+	return pin, nil
+}
+
+// Unpin removes the pin of a DID and reports whether there was one
+func (p *DIDPins) Unpin(ctx context.Context, didURI, why string) (bool, error) {
+	result, err := p.db.db.ExecContext(ctx, `DELETE FROM did_pins WHERE did = ?`, didURI)
+	if err != nil {
+		return false, fmt.Errorf("failed to remove DID pin: %w", err)
+	}
+	p.mu.Lock()
+	delete(p.pins, didURI)
+	p.mu.Unlock()
+	if n, _ := result.RowsAffected(); n == 0 {
+		return false, nil
+	}
+
+	fmt.Printf("📌 %s unpinned (%s)\n", didURI, why)
+	p.auditLog.Record(ctx, AuditEvent{
+		Event:  "did_unpinned",
+		Detail: fmt.Sprintf("%s unpinned: %s", didURI, why),
+	})
+	return true, nil
+}
+
+// List returns the current pins, soonest to expire first
+func (p *DIDPins) List() []DIDPin {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pins := []DIDPin{}
+	for _, pin := range p.pins {
+		pins = append(pins, *pin)
+	}
+	slices.SortFunc(pins, func(a, b DIDPin) int { return a.ExpiresAt.Compare(b.ExpiresAt) })
+	return pins
+}
+
+// expire removes the pins whose time is up
+func (p *DIDPins) expire(ctx context.Context) {
+	now := stationClock.Now()
+	var expired []string
+	p.mu.Lock()
+	for didURI, pin := range p.pins {
+		if !now.Before(pin.ExpiresAt) {
+			expired = append(expired, didURI)
+		}
+	}
+	p.mu.Unlock()
+	for _, didURI := range expired {
+		if _, err := p.Unpin(ctx, didURI, "expired"); err != nil {
+			fmt.Printf("⚠️  Failed to remove expired pin of %s: %v\n", didURI, err)
+		}
+	}
+}
+
+func (p *DIDPins) maxDuration() time.Duration {
+	if p.config.MaxPinDuration > 0 {
+		return p.config.MaxPinDuration
+	}
+	return defaultMaxDIDPinDuration
+}
+
+// ListHandler serves GET /api/did/pins
+func (p *DIDPins) ListHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, p.List())
+	})
+}
+
+// PinHandler serves PUT /api/did/pins/{did}
+func (p *DIDPins) PinHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req DIDPinRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid pin request: %v", err))
+			return
+		}
+		duration, err := time.ParseDuration(req.Duration)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid duration: %v", err))
+			return
+		}
+		if duration <= 0 || duration > p.maxDuration() {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("duration must be positive and at most %s", p.maxDuration()))
+			return
+		}
+		if req.Reason == "" {
+			writeJSONError(w, http.StatusBadRequest, "reason is required")
+			return
+		}
+		pin, err := p.Pin(r.Context(), r.PathValue("did"), req.KeySHA256, duration, req.Reason, adminIdentity(r.Context()))
+		if errors.Is(err, ErrDIDNotResolved) {
+			writeJSONError(w, http.StatusConflict, err.Error())
+			return
+		}
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, pin)
+	})
+}
+
+// UnpinHandler serves DELETE /api/did/pins/{did}
+func (p *DIDPins) UnpinHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		didURI := r.PathValue("did")
+		identity := adminIdentity(r.Context())
+		if identity == "" {
+			identity = "admin API"
+		}
+		found, err := p.Unpin(r.Context(), didURI, "removed by "+identity)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if !found {
+			writeJSONError(w, http.StatusNotFound, fmt.Sprintf("%s is not pinned", didURI))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
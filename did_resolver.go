@@ -9,13 +9,17 @@ import (
 	"crypto"
 	"crypto/ecdsa"
 	"crypto/elliptic"
-	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math/big"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
@@ -24,6 +28,9 @@ import (
 	"github.com/nuts-foundation/go-did/did"
 )
 
+// ErrDIDNotFound marks a DID whose document does not exist (HTTP 404 or 410)
+var ErrDIDNotFound = errors.New("DID not found")
+
 // DIDCacheEntry represents a cached DID resolution
 type DIDCacheEntry struct {
 	DIDURI             string    `db:"did_uri"`
@@ -40,42 +47,109 @@ type DIDResolver struct {
 	sessionState interface{}
 	config       *DIDCache
 	httpClient   *http.Client
+	guard        *SSRFGuard
+	rotations    *DIDRotations // nil = rotation hints ignored
+	pins         *DIDPins      // nil = no DIDs pinned
+	jwks         *JWKSCache    // nil = owner key URLs fetched on every resolution
+
+	// Client for did_cache.universal_resolver_url, which is trusted config and
+	// may be on a private network, so it isn't subject to the SSRF guard
+	resolverClient *http.Client
 }
 
 // NewDIDResolver creates a new DID resolver
 func NewDIDResolver(sessionState interface{}, config *DIDCache) *DIDResolver {
+	guard := NewSSRFGuard(config.AllowPrivateCIDRs)
+
+	// Every connection the resolver makes goes through the SSRF guard. No
+	// proxy: the guard would only see the proxy's address, not the target's.
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = guard.Dialer(30 * time.Second).DialContext
+	tlsTrust.wrapTransport(transport)
+
+	resolverTransport := http.DefaultTransport.(*http.Transport).Clone()
+	tlsTrust.wrapTransport(resolverTransport)
+
 	return &DIDResolver{
 		sessionState: sessionState,
 		config:       config,
 		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout:   30 * time.Second,
+			Transport: transport,
+		},
+		guard: guard,
+		resolverClient: &http.Client{
+			Timeout:   30 * time.Second,
+			Transport: resolverTransport,
 		},
 	}
 }
 
+// supportedDIDMethods lists the DID methods ResolveDIDKey can turn into an owner key
+var supportedDIDMethods = []string{"did:web", "did:key", "did:jwk"}
+
 // ResolveDIDKey resolves a DID URI to a public key and optional DID URL
 func (r *DIDResolver) ResolveDIDKey(ctx context.Context, didURI string) (crypto.PublicKey, string, error) {
 	if !r.config.Enabled {
 		return nil, "", fmt.Errorf("DID cache is disabled")
 	}
+	ctx, cancel := budgetStage(ctx, BudgetStageDIDResolution)
+	defer cancel()
+	if err := faultInjector.Inject(ctx, FaultStageDIDResolution); err != nil {
+		return nil, "", err
+	}
 
 	// Handle did:key directly (no caching)
 	if strings.HasPrefix(didURI, "did:key:") {
 		return r.resolveDIDKeyDirect(ctx, didURI)
 	}
 
-	// Handle did:web with caching
-	if strings.HasPrefix(didURI, "did:web:") {
-		return r.resolveDIDWebCached(ctx, didURI)
+	// did:jwk carries its key in the DID as well
+	if strings.HasPrefix(didURI, "did:jwk:") {
+		publicKey, err := r.parseDIDJWK(didURI)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to extract public key from did:jwk: %w", err)
+		}
+		return publicKey, "", nil
+	}
+
+	// Handle did:web, and owner keys at well-known HTTPS URLs, with caching
+	if strings.HasPrefix(didURI, "did:web:") || isOwnerKeyURL(didURI) {
+		// Domain policy is checked before the cache and network so a denied
+		// domain can never become a signover target
+		if err := r.checkDomainPolicy(didURI); err != nil {
+			return nil, "", err
+		}
+		// A pinned DID isn't fetched until its pin expires
+		if publicKey, didURL, ok := r.pins.Lookup(didURI); ok {
+			return publicKey, didURL, nil
+		}
+		publicKey, didURL, err := r.resolveDIDWebCached(ctx, didURI)
+		if err != nil {
+			// During an announced rotation the previous key stays in use
+			return r.rotations.fallback(didURI, err)
+		}
+		return publicKey, didURL, nil
+	}
+
+	// Other methods are left to a Universal Resolver, if one is configured
+	if r.usesUniversalResolver(didURI) {
+		if publicKey, didURL, ok := r.pins.Lookup(didURI); ok {
+			return publicKey, didURL, nil
+		}
+		publicKey, didURL, err := r.resolveDIDWebCached(ctx, didURI)
+		if err != nil {
+			return r.rotations.fallback(didURI, err)
+		}
+		return publicKey, didURL, nil
 	}
 
 	return nil, "", fmt.Errorf("unsupported DID method: %s", strings.Split(didURI, ":")[1])
 }
 
-// resolveDIDKeyDirect resolves did:key without caching
+// resolveDIDKeyDirect resolves did:key without caching: the key is the DID itself
 func (r *DIDResolver) resolveDIDKeyDirect(ctx context.Context, didURI string) (crypto.PublicKey, string, error) {
-	// For did:key, we need to extract the public key directly from the multibase format
-	// This is a simplified implementation - in practice you'd want to use a proper did:key resolver
 	publicKey, err := r.extractPublicKeyFromDIDKey(didURI)
 	if err != nil {
 		return nil, "", fmt.Errorf("failed to extract public key from did:key: %w", err)
@@ -85,9 +159,10 @@ func (r *DIDResolver) resolveDIDKeyDirect(ctx context.Context, didURI string) (c
 	return publicKey, "", nil
 }
 
-// resolveDIDWebCached resolves did:web with caching
+// resolveDIDWebCached resolves did:web, owner key URLs and DIDs resolved by
+// the Universal Resolver with caching
 func (r *DIDResolver) resolveDIDWebCached(ctx context.Context, didURI string) (crypto.PublicKey, string, error) {
-	now := time.Now()
+	now := stationClock.Now()
 
 	// Try to get from cache first
 	cached, err := r.getFromCache(ctx, didURI)
@@ -118,12 +193,64 @@ func (r *DIDResolver) resolveDIDWebCached(ctx context.Context, didURI string) (c
 	return r.refreshFromNetwork(ctx, didURI)
 }
 
-// extractPublicKeyFromDIDKey extracts public key from did:key format
+// checkDomainPolicy enforces the configured did:web domain allowlist and
+// denylist, which also cover the hosts of owner key URLs
+func (r *DIDResolver) checkDomainPolicy(didURI string) error {
+	domain, err := didWebDomain(didURI)
+	if isOwnerKeyURL(didURI) {
+		domain, err = ownerKeyURLDomain(didURI)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, pattern := range r.config.DeniedDomains {
+		if matchDomainPattern(pattern, domain) {
+			return fmt.Errorf("%w: did:web domain %s is denied by did_cache.denied_domains (%s)", ErrOwnerKeyPolicy, domain, pattern)
+		}
+	}
+
+	// An empty allowlist means any domain not explicitly denied is acceptable
+	if len(r.config.AllowedDomains) == 0 {
+		return nil
+	}
+	for _, pattern := range r.config.AllowedDomains {
+		if matchDomainPattern(pattern, domain) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: did:web domain %s is not in did_cache.allowed_domains", ErrOwnerKeyPolicy, domain)
+}
+
+// didWebDomain extracts the lowercased host name (without port) from a did:web URI
+func didWebDomain(didURI string) (string, error) {
+	id, _ := splitDIDFragment(didURI)
+	domain := strings.Split(strings.TrimPrefix(id, "did:web:"), ":")[0]
+	if unescaped, err := url.PathUnescape(domain); err == nil {
+		domain = unescaped
+	}
+	if host, _, err := net.SplitHostPort(domain); err == nil {
+		domain = host
+	}
+	if domain == "" {
+		return "", fmt.Errorf("invalid did:web format: %s", didURI)
+	}
+	return strings.ToLower(domain), nil
+}
+
+// matchDomainPattern matches a domain against an exact name or a "*.example.com"
+// pattern, which matches any subdomain of example.com but not example.com itself
+func matchDomainPattern(pattern, domain string) bool {
+	pattern = strings.ToLower(strings.TrimSpace(pattern))
+	if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+		return strings.HasSuffix(domain, "."+suffix)
+	}
+	return pattern == domain
+}
+
+// extractPublicKeyFromDIDKey extracts the public key of a did:key (see parseDIDKey)
 func (r *DIDResolver) extractPublicKeyFromDIDKey(didKey string) (crypto.PublicKey, error) {
-	// This is a simplified implementation
-	// In practice, you'd want to use a proper did:key library to handle multicodec decoding
-	// For now, we'll return an error to indicate this needs proper implementation
-	return nil, fmt.Errorf("did:key resolution not yet implemented - need proper multicodec decoding")
+	return parseDIDKey(didKey)
 }
 
 // shouldRefresh determines if a cache entry should be refreshed
@@ -149,13 +276,23 @@ func (r *DIDResolver) shouldRefresh(cached *DIDCacheEntry, now time.Time) bool {
 
 // refreshFromNetwork fetches DID from network and updates cache
 func (r *DIDResolver) refreshFromNetwork(ctx context.Context, didURI string) (crypto.PublicKey, string, error) {
-	now := time.Now()
+	now := stationClock.Now()
 
 	// For did:web, fetch DID document from HTTP
 	if strings.HasPrefix(didURI, "did:web:") {
 		return r.fetchDIDWeb(ctx, didURI, now)
 	}
 
+	// For an owner key URL, fetch the PEM or JWKS it serves
+	if isOwnerKeyURL(didURI) {
+		return r.fetchOwnerKeyURL(ctx, didURI, now)
+	}
+
+	// For other methods, ask the Universal Resolver
+	if r.usesUniversalResolver(didURI) {
+		return r.fetchUniversalResolver(ctx, didURI, now)
+	}
+
 	// For did:key, extract directly
 	if strings.HasPrefix(didURI, "did:key:") {
 		publicKey, err := r.extractPublicKeyFromDIDKey(didURI)
@@ -196,7 +333,8 @@ func (r *DIDResolver) fetchDIDWeb(ctx context.Context, didURI string, now time.T
 	// Convert did:web to URL
 	// did:web:example.com:owner -> https://example.com/.well-known/did.json/owner
 	// did:web:example.com -> https://example.com/.well-known/did.json
-	parts := strings.Split(strings.TrimPrefix(didURI, "did:web:"), ":")
+	id, fragment := splitDIDFragment(didURI)
+	parts := strings.Split(strings.TrimPrefix(id, "did:web:"), ":")
 	if len(parts) == 0 {
 		r.updateCacheError(ctx, didURI, now, "invalid did:web format")
 		return nil, "", fmt.Errorf("invalid did:web format")
@@ -208,10 +346,20 @@ func (r *DIDResolver) fetchDIDWeb(ctx context.Context, didURI string, now time.T
 		path = "/" + strings.Join(parts[1:], ":")
 	}
 
-	url := fmt.Sprintf("https://%s/.well-known/did.json%s", domain, path)
+	// Reject IP literals in blocked ranges before touching the network
+	host := domain
+	if unescaped, err := url.PathUnescape(domain); err == nil {
+		host = unescaped
+	}
+	if err := r.guard.CheckHost(host); err != nil {
+		r.updateCacheError(ctx, didURI, now, err.Error())
+		return nil, "", err
+	}
+
+	docURL := fmt.Sprintf("https://%s/.well-known/did.json%s", domain, path)
 
 	// Fetch DID document
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", docURL, nil)
 	if err != nil {
 		r.updateCacheError(ctx, didURI, now, fmt.Sprintf("failed to create request: %v", err))
 		return nil, "", fmt.Errorf("failed to create request: %w", err)
@@ -226,6 +374,9 @@ func (r *DIDResolver) fetchDIDWeb(ctx context.Context, didURI string, now time.T
 
 	if resp.StatusCode != http.StatusOK {
 		r.updateCacheError(ctx, didURI, now, fmt.Sprintf("HTTP %d when fetching DID document", resp.StatusCode))
+		if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+			return nil, "", fmt.Errorf("%w: HTTP %d when fetching DID document", ErrDIDNotFound, resp.StatusCode)
+		}
 		return nil, "", fmt.Errorf("HTTP %d when fetching DID document", resp.StatusCode)
 	}
 
@@ -243,7 +394,7 @@ func (r *DIDResolver) fetchDIDWeb(ctx context.Context, didURI string, now time.T
 	}
 
 	// Extract public key from verification method
-	publicKey, err := r.extractPublicKey(doc)
+	publicKey, err := r.extractPublicKey(doc, fragment)
 	if err != nil {
 		r.updateCacheError(ctx, didURI, now, fmt.Sprintf("failed to extract public key: %v", err))
 		return nil, "", fmt.Errorf("failed to extract public key: %w", err)
@@ -252,8 +403,15 @@ func (r *DIDResolver) fetchDIDWeb(ctx context.Context, didURI string, now time.T
 	// Extract DID URL from FDO extension
 	didURL := r.extractDIDURL(doc)
 
+	// A key that replaces the DID's previous one is checked before it is used
+	publicKey, didURL, err = r.rotations.admit(ctx, didURI, publicKey, didURL, parseNextRotation(body))
+	if err != nil {
+		r.updateCacheError(ctx, didURI, now, err.Error())
+		return nil, "", err
+	}
+
 	// Serialize public key for storage
-	publicKeyBytes, err := x509.MarshalPKIXPublicKey(publicKey)
+	publicKeyBytes, err := serializePublicKey(publicKey)
 	if err != nil {
 		r.updateCacheError(ctx, didURI, now, fmt.Sprintf("failed to serialize public key: %v", err))
 		return nil, "", fmt.Errorf("failed to serialize public key: %w", err)
@@ -279,15 +437,14 @@ func (r *DIDResolver) fetchDIDWeb(ctx context.Context, didURI string, now time.T
 	return publicKey, didURL, nil
 }
 
-// extractPublicKey extracts the first public key from DID document
-func (r *DIDResolver) extractPublicKey(doc *did.Document) (crypto.PublicKey, error) {
-	if len(doc.VerificationMethod) == 0 {
-		return nil, fmt.Errorf("no verification methods found in DID document")
+// extractPublicKey extracts the owner's public key from a DID document; the
+// fragment of the DID URL, if any, names its verification method
+func (r *DIDResolver) extractPublicKey(doc *did.Document, fragment string) (crypto.PublicKey, error) {
+	vm, err := selectVerificationMethod(doc, fragment)
+	if err != nil {
+		return nil, err
 	}
 
-	// Use the first verification method
-	vm := doc.VerificationMethod[0]
-
 	// Handle JWK format
 	if vm.PublicKeyJwk != nil {
 		return r.parseJWK(vm.PublicKeyJwk)
@@ -300,12 +457,50 @@ func (r *DIDResolver) extractPublicKey(doc *did.Document) (crypto.PublicKey, err
 
 	// Handle deprecated PublicKeyBase58 format
 	if vm.PublicKeyBase58 != "" {
-		return r.parseBase58(vm.PublicKeyBase58)
+		return r.parseBase58(vm.PublicKeyBase58, string(vm.Type))
 	}
 
 	return nil, fmt.Errorf("no supported public key format found in verification method")
 }
 
+// selectVerificationMethod picks the verification method vouchers are signed
+// over to: the one a DID URL fragment names, else the first assertionMethod,
+// where an owner publishing several keys lists its signing key, else the
+// first verification method
+func selectVerificationMethod(doc *did.Document, fragment string) (*did.VerificationMethod, error) {
+	if fragment != "" {
+		for _, vm := range doc.VerificationMethod {
+			if vm.ID.Fragment == fragment {
+				return vm, nil
+			}
+		}
+		// assertionMethod may embed a key that isn't in verificationMethod
+		for _, rel := range doc.AssertionMethod {
+			if rel.VerificationMethod != nil && rel.ID.Fragment == fragment {
+				return rel.VerificationMethod, nil
+			}
+		}
+		return nil, fmt.Errorf("no verification method #%s found in DID document", fragment)
+	}
+
+	for _, rel := range doc.AssertionMethod {
+		if rel.VerificationMethod != nil {
+			return rel.VerificationMethod, nil
+		}
+	}
+	if len(doc.VerificationMethod) == 0 {
+		return nil, fmt.Errorf("no verification methods found in DID document")
+	}
+	return doc.VerificationMethod[0], nil
+}
+
+// splitDIDFragment splits a DID URL such as did:web:example.com#key-2 into
+// the DID and the fragment naming one of its keys
+func splitDIDFragment(didURI string) (string, string) {
+	id, fragment, _ := strings.Cut(didURI, "#")
+	return id, fragment
+}
+
 // parseJWK parses a JSON Web Key to crypto.PublicKey
 func (r *DIDResolver) parseJWK(jwkData map[string]interface{}) (crypto.PublicKey, error) {
 	// Get key type
@@ -314,6 +509,11 @@ func (r *DIDResolver) parseJWK(jwkData map[string]interface{}) (crypto.PublicKey
 		return nil, fmt.Errorf("missing or invalid kty in JWK")
 	}
 
+	// A key published with its certificate chain is used as the chain
+	if x5c, ok := jwkData["x5c"].([]interface{}); ok && len(x5c) > 0 {
+		return r.parseX5C(x5c)
+	}
+
 	// Handle EC keys
 	if kty == "EC" {
 		return r.parseECJWK(jwkData)
@@ -327,60 +527,90 @@ func (r *DIDResolver) parseJWK(jwkData map[string]interface{}) (crypto.PublicKey
 	return nil, fmt.Errorf("unsupported JWK key type: %s", kty)
 }
 
-// parseECJWK parses an EC JWK to crypto.PublicKey
+// parseECJWK decodes the base64url x and y coordinates of a P-256 or P-384
+// JWK. A point that is not on the curve is rejected.
 func (r *DIDResolver) parseECJWK(jwkData map[string]interface{}) (crypto.PublicKey, error) {
-	// Get curve
-	crv, ok := jwkData["crv"].(string)
-	if !ok {
-		return nil, fmt.Errorf("missing or invalid crv in EC JWK")
-	}
-
-	// For testing, we'll generate a test key instead of parsing the coordinates
-	// In a real implementation, you'd decode the base64url coordinates and create the key
-	// We don't need to validate x/y for this test implementation
-
 	var curve elliptic.Curve
-	switch crv {
+	switch crv, _ := jwkData["crv"].(string); crv {
 	case "P-256":
 		curve = elliptic.P256()
 	case "P-384":
 		curve = elliptic.P384()
 	default:
-		return nil, fmt.Errorf("unsupported EC curve: %s", crv)
+		return nil, fmt.Errorf("unsupported EC curve %q", crv)
 	}
-
-	// Generate a test key for the specified curve
-	privateKey, err := ecdsa.GenerateKey(curve, rand.Reader)
+	x, err := jwkMember(jwkData, "x")
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate test EC key: %w", err)
+		return nil, err
 	}
-
-	return privateKey.Public(), nil
+	y, err := jwkMember(jwkData, "y")
+	if err != nil {
+		return nil, err
+	}
+	size := (curve.Params().BitSize + 7) / 8
+	if len(x) != size || len(y) != size {
+		return nil, fmt.Errorf("JWK coordinates are not %d bytes", size)
+	}
+	key, err := ecdsa.ParseUncompressedPublicKey(curve, append(append([]byte{4}, x...), y...))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s JWK: %w", curve.Params().Name, err)
+	}
+	return key, nil
 }
 
-// parseRSAJWK parses an RSA JWK to crypto.PublicKey
+// parseRSAJWK decodes the modulus and exponent of an RSA JWK of at least 2048 bits
 func (r *DIDResolver) parseRSAJWK(jwkData map[string]interface{}) (crypto.PublicKey, error) {
-	// For testing, generate a test RSA key
-	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	n, err := jwkMember(jwkData, "n")
+	if err != nil {
+		return nil, err
+	}
+	e, err := jwkMember(jwkData, "e")
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate test RSA key: %w", err)
+		return nil, err
+	}
+	exponent := new(big.Int).SetBytes(e)
+	if !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+		return nil, fmt.Errorf("invalid RSA exponent")
 	}
+	key := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}
+	if key.N.BitLen() < 2048 {
+		return nil, fmt.Errorf("RSA key of %d bits is too small", key.N.BitLen())
+	}
+	if key.N.Bit(0) == 0 || exponent.Bit(0) == 0 {
+		return nil, fmt.Errorf("invalid RSA key: modulus and exponent must be odd")
+	}
+	return key, nil
+}
 
-	return privateKey.Public(), nil
+// parseX5C parses a JWK x5c member (base64 DER certificates, leaf first) into a certificate chain
+func (r *DIDResolver) parseX5C(x5c []interface{}) (crypto.PublicKey, error) {
+	chain := make([]*x509.Certificate, 0, len(x5c))
+	for i, entry := range x5c {
+		encoded, ok := entry.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid x5c entry %d in JWK", i)
+		}
+		der, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode x5c entry %d: %w", i, err)
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse x5c entry %d: %w", i, err)
+		}
+		chain = append(chain, cert)
+	}
+	return chain, nil
 }
 
-// parseMultibase parses a multibase-encoded public key
+// parseMultibase parses a publicKeyMultibase value (see parseMultikey)
 func (r *DIDResolver) parseMultibase(multibase string) (crypto.PublicKey, error) {
-	// For now, we'll need to implement multibase parsing
-	// This is a simplified version - in practice you'd want to use a proper multibase library
-	return nil, fmt.Errorf("multibase parsing not yet implemented")
+	return parseMultikey(multibase)
 }
 
-// parseBase58 parses a base58-encoded public key
-func (r *DIDResolver) parseBase58(base58 string) (crypto.PublicKey, error) {
-	// For now, we'll need to implement base58 parsing
-	// This is a simplified version - in practice you'd want to use a proper base58 library
-	return nil, fmt.Errorf("base58 parsing not yet implemented")
+// parseBase58 parses a publicKeyBase58 value (see parseBase58Key)
+func (r *DIDResolver) parseBase58(base58, methodType string) (crypto.PublicKey, error) {
+	return parseBase58Key(base58, methodType)
 }
 
 // extractDIDURL extracts voucherRecipientURL from FDO extension
@@ -424,9 +654,28 @@ func (r *DIDResolver) extractDIDURL(doc *did.Document) string {
 	return ""
 }
 
+// serializePublicKey converts a public key (PKIX) or certificate chain (concatenated DER) to bytes for the cache
+func serializePublicKey(publicKey crypto.PublicKey) ([]byte, error) {
+	if chain, ok := publicKey.([]*x509.Certificate); ok {
+		var der []byte
+		for _, cert := range chain {
+			der = append(der, cert.Raw...)
+		}
+		return der, nil
+	}
+	return x509.MarshalPKIXPublicKey(publicKey)
+}
+
 // deserializePublicKey converts stored bytes back to crypto.PublicKey
 func (r *DIDResolver) deserializePublicKey(keyBytes []byte) (crypto.PublicKey, error) {
-	return x509.ParsePKIXPublicKey(keyBytes)
+	if publicKey, err := x509.ParsePKIXPublicKey(keyBytes); err == nil {
+		return publicKey, nil
+	}
+	chain, err := x509.ParseCertificates(keyBytes)
+	if err != nil || len(chain) == 0 {
+		return nil, fmt.Errorf("cached key is neither a public key nor a certificate chain")
+	}
+	return chain, nil
 }
 
 // Cache database operations
@@ -465,8 +714,9 @@ func (r *DIDResolver) getFromCache(ctx context.Context, didURI string) (*DIDCach
 
 // updateCache updates or inserts a DID cache entry
 func (r *DIDResolver) updateCache(ctx context.Context, entry *DIDCacheEntry) error {
+	// A resolver without session state is uncached on purpose
 	if r.sessionState == nil {
-		return fmt.Errorf("no session state available")
+		return nil
 	}
 
 	// Type assert to get database access
@@ -557,7 +807,7 @@ func (r *DIDResolver) PurgeExpired(ctx context.Context) (int, error) {
 		return 0, fmt.Errorf("session state does not support database operations")
 	}
 
-	cutoff := time.Now().Add(-r.config.PurgeUnused)
+	cutoff := stationClock.Now().Add(-r.config.PurgeUnused)
 	where := map[string]any{"last_used_lt": cutoff}
 
 	result, err := state.exec(ctx, "DELETE FROM did_cache WHERE last_used < :last_used_lt", where)
@@ -0,0 +1,281 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DID rotation defaults
+const (
+	defaultDIDRotationDelay = time.Minute
+	defaultDIDRotationRetry = time.Minute
+	defaultDIDRotationGrace = time.Hour
+)
+
+// DIDRotations follows planned owner key rotations. An owner announces one
+// with a "nextRotation" time in the fido-device-onboarding extension of its
+// DID document. Just after that time the station re-resolves the DID on its
+// own, without waiting for a device, and checks the new key against the hash
+// policy and owner revocations before any voucher is extended to it. While
+// the new key can't be fetched or fails those checks, devices keep going to
+// the previous key for rotation_grace, so a planned rotation never stops the
+// line. Re-resolution after a rotation is throttled to one attempt per
+// rotation_retry. A nil *DIDRotations ignores rotation hints.
+type DIDRotations struct {
+	config      *DIDCache
+	hashPolicy  *VoucherHashPolicy
+	revocations *OwnerRevocations
+	notifier    *Notifier
+	auditLog    *AuditLog
+
+	mu      sync.Mutex
+	entries map[string]*didRotation // Keyed by DID URI
+	wake    chan struct{}           // Signals Run that a refresh was scheduled
+}
+
+// didRotation is the last validated key of a DID and its announced rotation
+type didRotation struct {
+	key    crypto.PublicKey
+	didURL string
+	next   time.Time // Announced rotation; zero if none
+	due    time.Time // Next background re-resolution; zero if none
+}
+
+// NewDIDRotations creates the rotation tracker
+func NewDIDRotations(config *DIDCache, hashPolicy *VoucherHashPolicy, revocations *OwnerRevocations, notifier *Notifier, auditLog *AuditLog) *DIDRotations {
+	return &DIDRotations{
+		config:      config,
+		hashPolicy:  hashPolicy,
+		revocations: revocations,
+		notifier:    notifier,
+		auditLog:    auditLog,
+		entries:     make(map[string]*didRotation),
+		wake:        make(chan struct{}, 1),
+	}
+}
+
+// Run re-resolves DIDs just after their announced rotations until ctx is done
+func (d *DIDRotations) Run(ctx context.Context) {
+	if d == nil {
+		return
+	}
+	timer := time.NewTimer(d.untilDue())
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+			withConfigRead(func() { d.refreshDue(ctx) })
+		case <-d.wake:
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+		}
+		timer.Reset(d.untilDue())
+	}
+}
+
+// admit checks a freshly resolved key before it is used. A key that differs
+// from the DID's last validated key must pass the hash policy and owner
+// revocations. An accepted key becomes the DID's current key and its
+// announced rotation, if any, is scheduled.
+func (d *DIDRotations) admit(ctx context.Context, didURI string, key crypto.PublicKey, didURL string, next time.Time) (crypto.PublicKey, string, error) {
+	if d == nil {
+		return key, didURL, nil
+	}
+
+	d.mu.Lock()
+	var previous crypto.PublicKey
+	entry, known := d.entries[didURI]
+	if known {
+		previous = entry.key
+	}
+	d.mu.Unlock()
+
+	changed := known && ownerKeySHA256(previous) != ownerKeySHA256(key)
+	if changed {
+		if err := d.validate(key); err != nil {
+			d.auditLog.Record(ctx, AuditEvent{
+				Event:  "did_rotation_rejected",
+				Detail: fmt.Sprintf("%s: new owner key %s: %v", didURI, ownerKeySHA256(key), err),
+			})
+			return nil, "", fmt.Errorf("new key of %s failed validation: %w", didURI, err)
+		}
+		fmt.Printf("🔄 Owner key of %s rotated to %s\n", didURI, ownerKeySHA256(key))
+		d.auditLog.Record(ctx, AuditEvent{
+			Event:  "did_key_rotated",
+			Detail: fmt.Sprintf("%s: owner key %s replaced by %s", didURI, ownerKeySHA256(previous), ownerKeySHA256(key)),
+		})
+	}
+
+	now := stationClock.Now()
+	d.mu.Lock()
+	entry, known = d.entries[didURI]
+	if !known {
+		entry = &didRotation{}
+		d.entries[didURI] = entry
+	}
+	entry.key, entry.didURL = key, didURL
+	entry.due = time.Time{}
+	if next.After(now) {
+		if !next.Equal(entry.next) {
+			fmt.Printf("📅 %s announced a key rotation at %s\n", didURI, next.Format(time.RFC3339))
+		}
+		entry.due = next.Add(d.delay())
+	}
+	if !next.IsZero() || changed {
+		entry.next = next
+	}
+	scheduled := !entry.due.IsZero()
+	d.mu.Unlock()
+
+	if scheduled {
+		select {
+		case d.wake <- struct{}{}:
+		default:
+		}
+	}
+	d.notifier.RecordSuccess("did_rotation:" + didURI)
+	return key, didURL, nil
+}
+
+// fallback returns the previous key of a DID that failed to resolve during its
+// rotation grace period, or err if the DID is not rotating
+func (d *DIDRotations) fallback(didURI string, err error) (crypto.PublicKey, string, error) {
+	if d == nil {
+		return nil, "", err
+	}
+	d.mu.Lock()
+	entry, ok := d.entries[didURI]
+	var current didRotation
+	if ok {
+		current = *entry
+	}
+	d.mu.Unlock()
+	if !ok || !d.rotating(&current, stationClock.Now()) {
+		return nil, "", err
+	}
+	d.notifier.RecordFailure("did_rotation:"+didURI, fmt.Sprintf("rotation of %s not completed, still signing over to the previous key: %v", didURI, err))
+	fmt.Printf("⚠️  Rotation of %s not completed, using the previous key: %v\n", didURI, err)
+	return current.key, current.didURL, nil
+}
+
+// current returns the last key accepted for a DID
+func (d *DIDRotations) current(didURI string) (crypto.PublicKey, string, bool) {
+	if d == nil {
+		return nil, "", false
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	entry, ok := d.entries[didURI]
+	if !ok {
+		return nil, "", false
+	}
+	return entry.key, entry.didURL, true
+}
+
+// refreshDue re-resolves every DID whose rotation refresh is due
+func (d *DIDRotations) refreshDue(ctx context.Context) {
+	now := stationClock.Now()
+	var due []string
+	d.mu.Lock()
+	for didURI, entry := range d.entries {
+		if entry.due.IsZero() || entry.due.After(now) {
+			continue
+		}
+		if d.rotating(entry, now) || entry.next.After(now) {
+			entry.due = now.Add(d.retry()) // Cleared by admit once the new key is accepted
+			due = append(due, didURI)
+		} else {
+			entry.due = time.Time{}
+			d.notifier.Critical("did_rotation:"+didURI, fmt.Sprintf("rotation of %s did not complete within %s; signover to it now fails", didURI, d.grace()))
+		}
+	}
+	d.mu.Unlock()
+
+	for _, didURI := range due {
+		resolver := NewDIDResolver(nil, d.config)
+		resolver.rotations = d
+		if _, _, err := resolver.ResolveDIDKey(ctx, didURI); err != nil {
+			fmt.Printf("⚠️  Re-resolving %s after its rotation failed: %v\n", didURI, err)
+		}
+	}
+}
+
+// untilDue returns the time until the earliest scheduled refresh
+func (d *DIDRotations) untilDue() time.Duration {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	wait := time.Hour
+	for _, entry := range d.entries {
+		if !entry.due.IsZero() {
+			wait = min(wait, max(entry.due.Sub(stationClock.Now()), 0))
+		}
+	}
+	return wait
+}
+
+// rotating reports whether a DID's announced rotation is past but within the grace period
+func (d *DIDRotations) rotating(entry *didRotation, now time.Time) bool {
+	return !entry.next.IsZero() && !now.Before(entry.next) && now.Before(entry.next.Add(d.grace()))
+}
+
+// validate checks a rotated owner key against the hash policy and revocations
+func (d *DIDRotations) validate(key crypto.PublicKey) error {
+	if err := d.hashPolicy.CheckOwnerKey(key); err != nil {
+		return err
+	}
+	return d.revocations.Check("", "", key)
+}
+
+func (d *DIDRotations) delay() time.Duration {
+	if d.config.RotationDelay > 0 {
+		return d.config.RotationDelay
+	}
+	return defaultDIDRotationDelay
+}
+
+func (d *DIDRotations) retry() time.Duration {
+	if d.config.RotationRetry > 0 {
+		return d.config.RotationRetry
+	}
+	return defaultDIDRotationRetry
+}
+
+func (d *DIDRotations) grace() time.Duration {
+	if d.config.RotationGrace > 0 {
+		return d.config.RotationGrace
+	}
+	return defaultDIDRotationGrace
+}
+
+// parseNextRotation reads the nextRotation hint (RFC 3339) from the
+// fido-device-onboarding extension of a raw DID document; zero if absent
+func parseNextRotation(body []byte) time.Time {
+	var doc struct {
+		FDO struct {
+			NextRotation string `json:"nextRotation"`
+		} `json:"fido-device-onboarding"`
+	}
+	if err := json.Unmarshal(body, &doc); err != nil || doc.FDO.NextRotation == "" {
+		return time.Time{}
+	}
+	next, err := time.Parse(time.RFC3339, doc.FDO.NextRotation)
+	if err != nil {
+		fmt.Printf("⚠️  Ignoring invalid nextRotation %q: %v\n", doc.FDO.NextRotation, err)
+		return time.Time{}
+	}
+	return next
+}
@@ -8,15 +8,27 @@ import (
 	"context"
 	"crypto"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/elliptic"
 	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/multiformats/go-multibase"
 	"github.com/nuts-foundation/go-did/did"
 )
 
@@ -65,7 +77,7 @@ func (r *TestDIDResolver) resolveDIDFile(ctx context.Context, didURI string) (cr
 	data, err := os.ReadFile(filePath)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return nil, "", fmt.Errorf("DID file not found (404): %s", filePath)
+			return nil, "", fmt.Errorf("%w: DID file %s does not exist", ErrDIDNotFound, filePath)
 		}
 		return nil, "", fmt.Errorf("failed to read DID file: %w", err)
 	}
@@ -77,7 +89,7 @@ func (r *TestDIDResolver) resolveDIDFile(ctx context.Context, didURI string) (cr
 	}
 
 	// Extract public key
-	publicKey, err := r.extractPublicKey(doc)
+	publicKey, err := r.extractPublicKey(doc, "")
 	if err != nil {
 		return nil, "", fmt.Errorf("failed to extract public key: %w", err)
 	}
@@ -266,8 +278,8 @@ func TestDIDIntegration(t *testing.T) {
 			t.Fatal("Expected error for non-existent file")
 		}
 
-		if !strings.Contains(err.Error(), "not found") {
-			t.Errorf("Expected 'not found' error, got: %v", err)
+		if !errors.Is(err, ErrDIDNotFound) {
+			t.Errorf("Expected ErrDIDNotFound, got: %v", err)
 		}
 
 		t.Logf("✅ File not found handling successful")
@@ -287,3 +299,476 @@ func TestDIDIntegrationWithVoucher(t *testing.T) {
 	// For now, we'll just create a placeholder
 	t.Log("📋 DID voucher integration tests not yet implemented")
 }
+
+// TestSSRFGuard tests the outbound address blocking used by the DID resolver
+func TestSSRFGuard(t *testing.T) {
+	guard := NewSSRFGuard([]string{"10.20.0.0/16", "192.168.1.7"})
+
+	tests := []struct {
+		host    string
+		blocked bool
+	}{
+		{"127.0.0.1", true},
+		{"[::1]:443", true},
+		{"169.254.169.254", true},
+		{"fe80::1", true},
+		{"10.0.0.5", true},
+		{"172.16.4.4", true},
+		{"::ffff:192.168.0.1", true},
+		{"0.0.0.0", true},
+		{"0.1.2.3", true},
+		{"100.64.0.1", true},
+		{"100.127.255.254", true},
+		{"::ffff:100.100.100.200", true},
+		{"100.63.255.255", false},
+		{"100.128.0.1", false},
+		{"10.20.3.4", false},
+		{"192.168.1.7", false},
+		{"8.8.8.8", false},
+		{"example.com", false},
+	}
+
+	for _, tt := range tests {
+		err := guard.CheckHost(tt.host)
+		if tt.blocked && err == nil {
+			t.Errorf("expected %s to be blocked", tt.host)
+		}
+		if !tt.blocked && err != nil {
+			t.Errorf("expected %s to be allowed, got: %v", tt.host, err)
+		}
+	}
+
+	// Dial-time check sees the resolved address
+	if err := guard.Control("tcp", "127.0.0.1:443", nil); err == nil {
+		t.Error("expected dial to loopback to be blocked")
+	}
+
+	// A proxy such as HTTPS_PROXY would hide the target from the dial-time check
+	resolver := NewDIDResolver(nil, &DIDCache{Enabled: true})
+	if resolver.httpClient.Transport.(*http.Transport).Proxy != nil {
+		t.Error("guarded resolver transport uses a proxy")
+	}
+}
+
+// TestDIDRecipientURLGuarded tests that the voucherRecipientURL of an owner's
+// DID document is uploaded to through the SSRF guard
+func TestDIDRecipientURLGuarded(t *testing.T) {
+	var uploads atomic.Int32
+	recipient := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uploads.Add(1)
+	}))
+	defer recipient.Close()
+
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	document := fmt.Sprintf(`{"id": "did:example:owner", "verificationMethod": [
+		{"id": "did:example:owner#key-1", "type": "JsonWebKey2020", "controller": "did:example:owner",
+		 "publicKeyJwk": {"kty": "EC", "crv": "P-256", "x": %q, "y": %q}}
+	], "fido-device-onboarding": {"voucherRecipientURL": %q}}`,
+		base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+		base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+		recipient.URL+"/api/vouchers")
+	universalResolver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(document))
+	}))
+	defer universalResolver.Close()
+
+	config := &VoucherConfig{DIDCache: DIDCache{Enabled: true, UniversalResolverURL: universalResolver.URL}}
+	config.VoucherUpload.Timeout = 5 * time.Second
+	_, recipientURL, err := NewDIDResolver(nil, &config.DIDCache).ResolveDIDKey(context.Background(), "did:example:owner")
+	if err != nil {
+		t.Fatalf("failed to resolve owner DID: %v", err)
+	}
+	if recipientURL != recipient.URL+"/api/vouchers" {
+		t.Fatalf("voucherRecipientURL %q, want the 127.0.0.1 recipient", recipientURL)
+	}
+
+	_, err = NewVoucherHTTPUploader(config, "station-1").Upload(context.Background(), recipientURL, "", "SN-1", "model", "guid-1", []byte("voucher"))
+	if err == nil || !strings.Contains(err.Error(), "blocked") {
+		t.Errorf("expected the upload to 127.0.0.1 to be blocked, got: %v", err)
+	}
+	if uploads.Load() != 0 {
+		t.Error("the recipient on 127.0.0.1 received an upload")
+	}
+
+	// The DID resolver's allowlist lets a private recipient through
+	config.DIDCache.AllowPrivateCIDRs = []string{"127.0.0.1"}
+	if _, err := NewVoucherHTTPUploader(config, "station-1").Upload(context.Background(), recipientURL, "", "SN-1", "model", "guid-1", []byte("voucher")); err != nil {
+		t.Errorf("upload to an allowlisted recipient failed: %v", err)
+	}
+	if uploads.Load() != 1 {
+		t.Errorf("recipient received %d uploads, want 1", uploads.Load())
+	}
+}
+
+// TestDIDDomainPolicy tests the did:web domain allowlist and denylist
+func TestDIDDomainPolicy(t *testing.T) {
+	resolver := NewDIDResolver(nil, &DIDCache{
+		Enabled:        true,
+		AllowedDomains: []string{"example.com", "*.acme.com"},
+		DeniedDomains:  []string{"bad.acme.com"},
+	})
+
+	tests := []struct {
+		didURI  string
+		allowed bool
+	}{
+		{"did:web:example.com", true},
+		{"did:web:example.com:owner", true},
+		{"did:web:example.com%3A8443:owner", true},
+		{"did:web:example.com#key-2", true},
+		{"did:web:vouchers.acme.com", true},
+		{"did:web:acme.com", false},
+		{"did:web:bad.acme.com", false},
+		{"did:web:evil.com", false},
+		{"did:web:example.com.evil.com", false},
+		{"https://example.com/.well-known/fdo-owner-key", true},
+		{"https://vouchers.acme.com:8443/owner.pem", true},
+		{"https://bad.acme.com/owner.pem", false},
+		{"https://evil.com/example.com", false},
+	}
+
+	for _, tt := range tests {
+		err := resolver.checkDomainPolicy(tt.didURI)
+		if tt.allowed && err != nil {
+			t.Errorf("expected %s to be allowed, got: %v", tt.didURI, err)
+		}
+		if !tt.allowed && !errors.Is(err, ErrOwnerKeyPolicy) {
+			t.Errorf("expected %s to be rejected by policy, got: %v", tt.didURI, err)
+		}
+	}
+}
+
+// TestHTTPCacheExpiry tests how long owner key documents are kept
+func TestHTTPCacheExpiry(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		header   http.Header
+		fresh    time.Duration // 0 = must revalidate
+		storable bool
+	}{
+		{http.Header{}, 0, true},
+		{http.Header{"Cache-Control": {"public, max-age=300"}}, 300 * time.Second, true},
+		{http.Header{"Cache-Control": {"max-age=300"}, "Age": {"100"}}, 200 * time.Second, true},
+		{http.Header{"Cache-Control": {"no-cache"}}, 0, true},
+		{http.Header{"Cache-Control": {"no-store, max-age=300"}}, 0, false},
+		{http.Header{
+			"Date":    {"Thu, 01 Oct 2026 11:00:00 GMT"},
+			"Expires": {"Thu, 01 Oct 2026 11:10:00 GMT"},
+		}, 10 * time.Minute, true},
+		{http.Header{"Expires": {"0"}}, 0, true},
+	}
+
+	for _, tt := range tests {
+		expires, storable := httpCacheExpiry(tt.header, now)
+		var fresh time.Duration
+		if !expires.IsZero() {
+			fresh = expires.Sub(now)
+		}
+		if fresh != tt.fresh || storable != tt.storable {
+			t.Errorf("%v: got fresh %v storable %v, want %v %v", tt.header, fresh, storable, tt.fresh, tt.storable)
+		}
+	}
+}
+
+// TestDIDPinExpiry tests that a pin stops applying when its time is up
+func TestDIDPinExpiry(t *testing.T) {
+	clock := NewFrozenClock(time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC))
+	stationClock = clock
+	defer func() { stationClock = systemClock{} }()
+
+	pins := NewDIDPins(&DIDCache{}, nil, nil, nil)
+	pins.pins["did:web:owner.example.com"] = &DIDPin{
+		DID:       "did:web:owner.example.com",
+		PinnedAt:  clock.Now(),
+		ExpiresAt: clock.Now().Add(time.Hour),
+		key:       "pinned",
+	}
+
+	if _, _, ok := pins.Lookup("did:web:owner.example.com"); !ok {
+		t.Errorf("expected the pin to apply before it expires")
+	}
+	clock.Advance(59 * time.Minute)
+	if _, _, ok := pins.Lookup("did:web:owner.example.com"); !ok {
+		t.Errorf("expected the pin to apply a minute before it expires")
+	}
+	clock.Advance(time.Minute)
+	if _, _, ok := pins.Lookup("did:web:owner.example.com"); ok {
+		t.Errorf("expected the pin not to apply once it expired")
+	}
+}
+
+// TestJWKSKeySelection tests picking the owner key of a JWKS
+func TestJWKSKeySelection(t *testing.T) {
+	keys := []map[string]any{
+		{"kid": "enc", "use": "enc"},
+		{"kid": "a", "use": "sig"},
+		{"kid": "b"},
+	}
+	tests := []struct {
+		kid, selection, want string
+	}{
+		{"", JWKSSelectFirst, "a"},
+		{"b", JWKSSelectFirst, "b"},
+		{"b", JWKSSelectSingle, "b"},
+		{"", JWKSSelectSingle, ""},
+		{"enc", JWKSSelectFirst, ""},
+		{"missing", JWKSSelectFirst, ""},
+	}
+
+	for _, tt := range tests {
+		jwk, err := selectJWK(keys, tt.kid, tt.selection)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("kid %q, %s: expected an error, got key %v", tt.kid, tt.selection, jwk["kid"])
+			}
+			continue
+		}
+		if err != nil || jwk["kid"] != tt.want {
+			t.Errorf("kid %q, %s: got %v (%v), want %s", tt.kid, tt.selection, jwk["kid"], err, tt.want)
+		}
+	}
+}
+
+func TestDIDKeyDecoding(t *testing.T) {
+	p256, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	p384, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	edPub, _, _ := ed25519.GenerateKey(rand.Reader)
+	k1, _ := secp256k1.GeneratePrivateKey()
+
+	didKey := func(code uint64, raw []byte) string {
+		value, err := multibase.Encode(multibase.Base58BTC, append(binary.AppendUvarint(nil, code), raw...))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return "did:key:" + value
+	}
+	tests := []struct {
+		name, did, keyType string
+	}{
+		{"P-256", didKey(multicodecP256Pub, elliptic.MarshalCompressed(elliptic.P256(), p256.X, p256.Y)), "ec256"},
+		{"P-384", didKey(multicodecP384Pub, elliptic.MarshalCompressed(elliptic.P384(), p384.X, p384.Y)) + "#key-1", "ec384"},
+		{"Ed25519", didKey(multicodecEd25519Pub, edPub), "ed25519"},
+		{"secp256k1", didKey(multicodecSecp256k1Pub, k1.PubKey().SerializeCompressed()), "secp256k1"},
+		{"truncated P-256", didKey(multicodecP256Pub, []byte{0x02, 0x01}), ""},
+		{"unknown codec", didKey(0x1205, make([]byte, 33)), ""},
+		{"not base58btc", "did:key:f" + strings.Repeat("00", 34), ""},
+	}
+
+	for _, tt := range tests {
+		key, err := parseDIDKey(tt.did)
+		if tt.keyType == "" {
+			if err == nil {
+				t.Errorf("%s: expected an error, got a %T", tt.name, key)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got := ownerKeyType(key); got != tt.keyType {
+			t.Errorf("%s: key type %q, want %q", tt.name, got, tt.keyType)
+		}
+	}
+	if key, err := parseDIDKey(tests[0].did); err != nil || !key.(*ecdsa.PublicKey).Equal(&p256.PublicKey) {
+		t.Errorf("P-256 key does not round-trip: %v", err)
+	}
+}
+
+// TestBase58KeyDecoding checks publicKeyBase58 keys, whose type comes from the
+// verification method type or the point length
+func TestBase58KeyDecoding(t *testing.T) {
+	p384, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	edPub, _, _ := ed25519.GenerateKey(rand.Reader)
+	base58 := func(raw []byte) string {
+		value, err := multibase.Encode(multibase.Base58BTC, raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return value[1:]
+	}
+	tests := []struct {
+		name, value, methodType, keyType string
+	}{
+		{"Ed25519", base58(edPub), "Ed25519VerificationKey2018", "ed25519"},
+		{"compressed P-384", base58(elliptic.MarshalCompressed(elliptic.P384(), p384.X, p384.Y)), "JsonWebKey2020", "ec384"},
+		{"uncompressed P-384", base58(elliptic.Marshal(elliptic.P384(), p384.X, p384.Y)), "EcdsaSecp384r1VerificationKey2019", "ec384"},
+		{"short Ed25519", base58(edPub[:31]), "Ed25519VerificationKey2018", ""},
+		{"unknown length", base58(make([]byte, 40)), "JsonWebKey2020", ""},
+	}
+	for _, tt := range tests {
+		key, err := parseBase58Key(tt.value, tt.methodType)
+		if tt.keyType == "" {
+			if err == nil {
+				t.Errorf("%s: expected an error, got a %T", tt.name, key)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got := ownerKeyType(key); got != tt.keyType {
+			t.Errorf("%s: key type %q, want %q", tt.name, got, tt.keyType)
+		}
+	}
+}
+
+// TestECJWKDecoding checks that an EC JWK decodes to the key its coordinates name
+func TestECJWKDecoding(t *testing.T) {
+	resolver := NewDIDResolver(nil, &DIDCache{})
+	key, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	coordinate := func(n []byte) string { return base64.RawURLEncoding.EncodeToString(n) }
+	x, y := key.X.FillBytes(make([]byte, 48)), key.Y.FillBytes(make([]byte, 48))
+	offCurve := append([]byte(nil), y...)
+	offCurve[47] ^= 1
+
+	parsed, err := resolver.parseJWK(map[string]interface{}{"kty": "EC", "crv": "P-384", "x": coordinate(x), "y": coordinate(y)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !parsed.(*ecdsa.PublicKey).Equal(&key.PublicKey) {
+		t.Errorf("decoded key differs from the JWK's coordinates")
+	}
+
+	for name, jwk := range map[string]map[string]interface{}{
+		"off curve":   {"kty": "EC", "crv": "P-384", "x": coordinate(x), "y": coordinate(offCurve)},
+		"wrong curve": {"kty": "EC", "crv": "P-256", "x": coordinate(x), "y": coordinate(y)},
+		"missing y":   {"kty": "EC", "crv": "P-384", "x": coordinate(x)},
+	} {
+		if _, err := resolver.parseJWK(jwk); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+// TestRSAJWKDecoding checks that an RSA JWK decodes to the key its modulus and exponent name
+func TestRSAJWKDecoding(t *testing.T) {
+	resolver := NewDIDResolver(nil, &DIDCache{})
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encode := func(n *big.Int) string { return base64.RawURLEncoding.EncodeToString(n.Bytes()) }
+	n, e := encode(key.N), encode(big.NewInt(int64(key.E)))
+
+	parsed, err := resolver.parseJWK(map[string]interface{}{"kty": "RSA", "n": n, "e": e})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !parsed.(*rsa.PublicKey).Equal(&key.PublicKey) {
+		t.Errorf("decoded key differs from the JWK's modulus and exponent")
+	}
+
+	small, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, jwk := range map[string]map[string]interface{}{
+		"1024 bits":     {"kty": "RSA", "n": encode(small.N), "e": e},
+		"even modulus":  {"kty": "RSA", "n": encode(new(big.Int).Add(key.N, big.NewInt(1))), "e": e},
+		"even exponent": {"kty": "RSA", "n": n, "e": encode(big.NewInt(65536))},
+		"missing e":     {"kty": "RSA", "n": n},
+	} {
+		if _, err := resolver.parseJWK(jwk); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+// TestDIDJWKDecoding checks did:jwk owners decode to their embedded key
+func TestDIDJWKDecoding(t *testing.T) {
+	resolver := NewDIDResolver(nil, &DIDCache{})
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	edPub, _, _ := ed25519.GenerateKey(rand.Reader)
+	coordinate := func(n []byte) string { return base64.RawURLEncoding.EncodeToString(n) }
+	didJWK := func(jwk map[string]string) string {
+		data, err := json.Marshal(jwk)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return "did:jwk:" + base64.RawURLEncoding.EncodeToString(data)
+	}
+	p256 := map[string]string{"kty": "EC", "crv": "P-256", "x": coordinate(key.X.FillBytes(make([]byte, 32))), "y": coordinate(key.Y.FillBytes(make([]byte, 32)))}
+	withPrivate := map[string]string{"d": coordinate(key.D.FillBytes(make([]byte, 32)))}
+	for k, v := range p256 {
+		withPrivate[k] = v
+	}
+
+	tests := []struct {
+		name, did, keyType string
+	}{
+		{"P-256", didJWK(p256) + "#0", "ec256"},
+		{"Ed25519", didJWK(map[string]string{"kty": "OKP", "crv": "Ed25519", "x": coordinate(edPub)}), "ed25519"},
+		{"private key", didJWK(withPrivate), ""},
+		{"wrong alg", didJWK(map[string]string{"kty": "EC", "crv": "P-256", "alg": "RS256", "x": p256["x"], "y": p256["y"]}), ""},
+		{"not base64url", "did:jwk:eyJ!!", ""},
+	}
+	for _, tt := range tests {
+		publicKey, err := resolver.parseDIDJWK(tt.did)
+		if tt.keyType == "" {
+			if err == nil {
+				t.Errorf("%s: expected an error", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got := ownerKeyType(publicKey); got != tt.keyType {
+			t.Errorf("%s: key type %q, want %q", tt.name, got, tt.keyType)
+		}
+	}
+	if publicKey, err := resolver.parseDIDJWK(tests[0].did); err != nil || !publicKey.(*ecdsa.PublicKey).Equal(&key.PublicKey) {
+		t.Errorf("P-256 key does not round-trip: %v", err)
+	}
+}
+
+// TestVerificationMethodSelection checks that a DID URL fragment picks its
+// key, and that otherwise an assertionMethod key is preferred
+func TestVerificationMethodSelection(t *testing.T) {
+	resolver := NewDIDResolver(nil, &DIDCache{})
+	jwk := func(key *ecdsa.PrivateKey) string {
+		return fmt.Sprintf(`{"kty":"EC","crv":"P-256","x":%q,"y":%q}`,
+			base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+			base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))))
+	}
+	encryption, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	signing, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	embedded, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	methods := fmt.Sprintf(`"verificationMethod": [
+		{"id": "did:web:example.com#key-1", "type": "JsonWebKey2020", "controller": "did:web:example.com", "publicKeyJwk": %s},
+		{"id": "#key-2", "type": "JsonWebKey2020", "controller": "did:web:example.com", "publicKeyJwk": %s}
+	]`, jwk(encryption), jwk(signing))
+	embeddedMethod := fmt.Sprintf(`{"id": "did:web:example.com#key-3", "type": "JsonWebKey2020", "controller": "did:web:example.com", "publicKeyJwk": %s}`, jwk(embedded))
+
+	tests := []struct {
+		name     string
+		document string
+		fragment string
+		want     *ecdsa.PrivateKey // nil = error
+	}{
+		{"first method", `{"id": "did:web:example.com", ` + methods + `}`, "", encryption},
+		{"assertion method", `{"id": "did:web:example.com", ` + methods + `, "keyAgreement": ["#key-1"], "assertionMethod": ["did:web:example.com#key-2"]}`, "", signing},
+		{"fragment", `{"id": "did:web:example.com", ` + methods + `, "assertionMethod": ["#key-2"]}`, "key-1", encryption},
+		{"relative id fragment", `{"id": "did:web:example.com", ` + methods + `}`, "key-2", signing},
+		{"embedded assertion method", `{"id": "did:web:example.com", ` + methods + `, "assertionMethod": [` + embeddedMethod + `]}`, "key-3", embedded},
+		{"unknown fragment", `{"id": "did:web:example.com", ` + methods + `}`, "key-9", nil},
+	}
+	for _, tt := range tests {
+		doc, err := did.ParseDocument(tt.document)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		key, err := resolver.extractPublicKey(doc, tt.fragment)
+		switch {
+		case tt.want == nil && err == nil:
+			t.Errorf("%s: expected an error", tt.name)
+		case tt.want != nil && err != nil:
+			t.Errorf("%s: %v", tt.name, err)
+		case tt.want != nil && !key.(*ecdsa.PublicKey).Equal(&tt.want.PublicKey):
+			t.Errorf("%s: selected the wrong key", tt.name)
+		}
+	}
+}
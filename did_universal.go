@@ -0,0 +1,151 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/nuts-foundation/go-did/did"
+)
+
+// didResolutionAccept asks a Universal Resolver for the DID resolution
+// result, which wraps the DID document with metadata
+const didResolutionAccept = `application/ld+json;profile="https://w3id.org/did-resolution"`
+
+// usesUniversalResolver reports whether a DID is resolved through
+// did_cache.universal_resolver_url: any DID method the station doesn't
+// resolve itself, when a Universal Resolver is configured
+func (r *DIDResolver) usesUniversalResolver(didURI string) bool {
+	if r.config.UniversalResolverURL == "" || !strings.HasPrefix(didURI, "did:") {
+		return false
+	}
+	for _, method := range supportedDIDMethods {
+		if strings.HasPrefix(didURI, method+":") {
+			return false
+		}
+	}
+	return true
+}
+
+// fetchUniversalResolver resolves a DID through the Universal Resolver
+// (GET <url>/1.0/identifiers/<did>) and caches the key like a did:web. The
+// document it returns must be that of the DID asked for.
+func (r *DIDResolver) fetchUniversalResolver(ctx context.Context, didURI string, now time.Time) (crypto.PublicKey, string, error) {
+	id, fragment := splitDIDFragment(didURI)
+	resolveURL := strings.TrimSuffix(r.config.UniversalResolverURL, "/") + "/1.0/identifiers/" + url.PathEscape(id)
+	req, err := http.NewRequestWithContext(ctx, "GET", resolveURL, nil)
+	if err != nil {
+		r.updateCacheError(ctx, didURI, now, fmt.Sprintf("failed to create request: %v", err))
+		return nil, "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", didResolutionAccept)
+
+	resp, err := r.resolverClient.Do(req)
+	if err != nil {
+		r.updateCacheError(ctx, didURI, now, fmt.Sprintf("failed to reach the Universal Resolver: %v", err))
+		return nil, "", fmt.Errorf("failed to reach the Universal Resolver: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		r.updateCacheError(ctx, didURI, now, fmt.Sprintf("HTTP %d from the Universal Resolver", resp.StatusCode))
+		if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+			return nil, "", fmt.Errorf("%w: HTTP %d from the Universal Resolver", ErrDIDNotFound, resp.StatusCode)
+		}
+		return nil, "", fmt.Errorf("HTTP %d from the Universal Resolver", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxOwnerKeyDocument+1))
+	if err == nil && len(body) > maxOwnerKeyDocument {
+		err = fmt.Errorf("larger than %d bytes", maxOwnerKeyDocument)
+	}
+	if err != nil {
+		r.updateCacheError(ctx, didURI, now, fmt.Sprintf("failed to read response body: %v", err))
+		return nil, "", fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	docJSON := didResolutionDocument(body)
+	doc, err := did.ParseDocument(string(docJSON))
+	if err != nil {
+		r.updateCacheError(ctx, didURI, now, fmt.Sprintf("failed to parse DID document: %v", err))
+		return nil, "", fmt.Errorf("failed to parse DID document: %w", err)
+	}
+	if doc.ID.String() != id {
+		r.updateCacheError(ctx, didURI, now, fmt.Sprintf("the Universal Resolver returned the document of %s", doc.ID))
+		return nil, "", fmt.Errorf("the Universal Resolver returned the document of %s, not %s", doc.ID, id)
+	}
+
+	publicKey, err := r.extractPublicKey(doc, fragment)
+	if err != nil {
+		r.updateCacheError(ctx, didURI, now, fmt.Sprintf("failed to extract public key: %v", err))
+		return nil, "", fmt.Errorf("failed to extract public key: %w", err)
+	}
+
+	var ext struct {
+		FDO struct {
+			VoucherRecipientURL string `json:"voucherRecipientURL"`
+		} `json:"fido-device-onboarding"`
+	}
+	_ = json.Unmarshal(docJSON, &ext)
+	didURL := ext.FDO.VoucherRecipientURL
+
+	// A key that replaces the DID's previous one is checked before it is used
+	publicKey, didURL, err = r.rotations.admit(ctx, didURI, publicKey, didURL, parseNextRotation(docJSON))
+	if err != nil {
+		r.updateCacheError(ctx, didURI, now, err.Error())
+		return nil, "", err
+	}
+
+	publicKeyBytes, err := serializePublicKey(publicKey)
+	if err != nil {
+		r.updateCacheError(ctx, didURI, now, fmt.Sprintf("failed to serialize public key: %v", err))
+		return nil, "", fmt.Errorf("failed to serialize public key: %w", err)
+	}
+	entry := &DIDCacheEntry{
+		DIDURI:             didURI,
+		PublicKey:          publicKeyBytes,
+		DIDURL:             didURL,
+		Timestamp:          now,
+		LastRefreshAttempt: now,
+		LastRefreshError:   "",
+		LastUsed:           now,
+	}
+	if err := r.updateCache(ctx, entry); err != nil {
+		fmt.Printf("⚠️  Failed to update DID cache: %v\n", err)
+	}
+	return publicKey, didURL, nil
+}
+
+// didResolutionDocument returns the DID document of a DID resolution result,
+// or the body itself when the resolver answered with a bare document
+func didResolutionDocument(body []byte) []byte {
+	var result struct {
+		DIDDocument json.RawMessage `json:"didDocument"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil || len(result.DIDDocument) == 0 || string(result.DIDDocument) == "null" {
+		return body
+	}
+	return result.DIDDocument
+}
+
+// validateUniversalResolver checks did_cache.universal_resolver_url
+func validateUniversalResolver(config *DIDCache) error {
+	if config.UniversalResolverURL == "" {
+		return nil
+	}
+	u, err := url.Parse(config.UniversalResolverURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("voucher_management.did_cache.universal_resolver_url: %q is not an http or https URL", config.UniversalResolverURL)
+	}
+	return nil
+}
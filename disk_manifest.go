@@ -0,0 +1,265 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DiskManifestFormat identifies the disk manifest format
+const DiskManifestFormat = "fdo-station-disk-manifest/1"
+
+// diskManifestDirectory is where manifests are written in each save_to_disk directory
+const diskManifestDirectory = "manifests"
+
+// DiskManifest lists every voucher a batch, or a day of unbatched production,
+// saved to one directory, so downstream ingestion can check it received all
+// of them. It is signed with the station key; the base64 signature is written
+// next to it with a .sig suffix.
+type DiskManifest struct {
+	Format     string              `json:"format"`
+	Station    string              `json:"station"`
+	InstanceID string              `json:"instance_id"`
+	BatchID    string              `json:"batch_id,omitempty"`
+	LotNumber  string              `json:"lot_number,omitempty"`
+	Day        string              `json:"day,omitempty"` // UTC day of unbatched vouchers
+	CreatedAt  time.Time           `json:"created_at"`
+	Count      int                 `json:"count"`
+	Vouchers   []DiskManifestEntry `json:"vouchers"`
+}
+
+// DiskManifestEntry is one saved voucher file
+type DiskManifestEntry struct {
+	Serial  string    `json:"serial"`
+	GUID    string    `json:"guid"`
+	File    string    `json:"file"`   // Relative to the directory
+	SHA256  string    `json:"sha256"` // Of the file as written
+	SavedAt time.Time `json:"saved_at"`
+}
+
+// DiskManifests records the vouchers saved to disk and writes a signed
+// manifest per directory once their batch is closed, or for unbatched
+// vouchers once their UTC day is over. A nil *DiskManifests records nothing.
+type DiskManifests struct {
+	config    *DiskManifestConfig
+	db        *StationDB
+	signer    crypto.Signer
+	buildInfo BuildInfo
+}
+
+// NewDiskManifests creates the manifest writer, or returns nil if it is
+// disabled. Without a signing key of its own it signs with the transfer key.
+func NewDiskManifests(config *DiskManifestConfig, transferKeyFile string, db *StationDB, buildInfo BuildInfo) (*DiskManifests, error) {
+	if !config.Enabled {
+		return nil, nil
+	}
+	keyFile := config.SigningKeyFile
+	if keyFile == "" {
+		keyFile = transferKeyFile
+	}
+	if keyFile == "" {
+		return nil, fmt.Errorf("save_to_disk.manifest needs a signing_key_file (or transfer.signing_key_file)")
+	}
+	signer, err := loadPrivateKeyFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("manifest signing key: %w", err)
+	}
+	return &DiskManifests{config: config, db: db, signer: signer, buildInfo: buildInfo}, nil
+}
+
+// Initialize creates the manifest tables if they don't exist
+func (m *DiskManifests) Initialize(ctx context.Context) error {
+	if m == nil {
+		return nil
+	}
+	if _, err := m.db.db.ExecContext(ctx, `
+	CREATE TABLE IF NOT EXISTS saved_vouchers (
+		manifest TEXT NOT NULL,
+		directory TEXT NOT NULL,
+		file TEXT NOT NULL,
+		guid TEXT NOT NULL,
+		serial TEXT NOT NULL,
+		batch_id TEXT NOT NULL,
+		lot_number TEXT NOT NULL,
+		sha256 TEXT NOT NULL,
+		saved_at INTEGER NOT NULL,
+		PRIMARY KEY (directory, file)
+	)`); err != nil {
+		return fmt.Errorf("failed to create saved_vouchers table: %w", err)
+	}
+	if _, err := m.db.db.ExecContext(ctx,
+		`CREATE INDEX IF NOT EXISTS saved_vouchers_manifest ON saved_vouchers (manifest, directory)`); err != nil {
+		return fmt.Errorf("failed to create saved_vouchers index: %w", err)
+	}
+	if _, err := m.db.db.ExecContext(ctx, `
+	CREATE TABLE IF NOT EXISTS disk_manifests (
+		manifest TEXT NOT NULL,
+		directory TEXT NOT NULL,
+		count INTEGER NOT NULL,
+		written_at INTEGER NOT NULL,
+		PRIMARY KEY (manifest, directory)
+	)`); err != nil {
+		return fmt.Errorf("failed to create disk_manifests table: %w", err)
+	}
+	return nil
+}
+
+// Record adds a saved voucher file to the manifest of its batch, or of the day
+func (m *DiskManifests) Record(ctx context.Context, target DiskTarget, directory, file, sum string) error {
+	if m == nil {
+		return nil
+	}
+	now := time.Now()
+	manifest := "day-" + now.UTC().Format(time.DateOnly)
+	if target.BatchID != "" {
+		manifest = "batch-" + target.BatchID
+	}
+	if _, err := m.db.db.ExecContext(ctx, `
+	INSERT OR REPLACE INTO saved_vouchers (manifest, directory, file, guid, serial, batch_id, lot_number, sha256, saved_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		manifest, directory, file, target.GUID, serialRules.Serial(target.Serial), target.BatchID, target.LotNumber, sum, now.Unix()); err != nil {
+		return fmt.Errorf("failed to record saved voucher: %w", err)
+	}
+	return nil
+}
+
+// Run writes the manifests that are due every minute until ctx is done
+func (m *DiskManifests) Run(ctx context.Context) {
+	if m == nil {
+		return
+	}
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		withConfigRead(func() {
+			if err := m.writeDue(ctx); err != nil {
+				fmt.Printf("⚠️  Disk manifests: %v\n", err)
+			}
+		})
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// writeDue writes the manifests whose batch is closed or whose day is over
+func (m *DiskManifests) writeDue(ctx context.Context) error {
+	rows, err := m.db.db.QueryContext(ctx, `
+	SELECT DISTINCT s.manifest, s.directory, s.batch_id FROM saved_vouchers s
+	WHERE NOT EXISTS (SELECT 1 FROM disk_manifests d WHERE d.manifest = s.manifest AND d.directory = s.directory)`)
+	if err != nil {
+		return fmt.Errorf("failed to list pending manifests: %w", err)
+	}
+	type pending struct{ manifest, directory, batchID string }
+	var due []pending
+	for rows.Next() {
+		var p pending
+		if err := rows.Scan(&p.manifest, &p.directory, &p.batchID); err != nil {
+			rows.Close()
+			return err
+		}
+		due = append(due, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	today := "day-" + time.Now().UTC().Format(time.DateOnly)
+	for _, p := range due {
+		if p.batchID != "" {
+			var status string
+			if err := m.db.db.QueryRowContext(ctx, `SELECT status FROM batches WHERE id = ?`, p.batchID).Scan(&status); err != nil || status == "open" {
+				continue
+			}
+		} else if p.manifest >= today {
+			continue
+		}
+		if _, err := m.Write(ctx, p.manifest, p.directory); err != nil {
+			fmt.Printf("⚠️  Failed to write manifest %s in %s: %v\n", p.manifest, p.directory, err)
+		}
+	}
+	return nil
+}
+
+// Write writes and signs the manifest of one batch or day in one directory,
+// replacing an earlier one, and returns its path
+func (m *DiskManifests) Write(ctx context.Context, manifest, directory string) (string, error) {
+	rows, err := m.db.db.QueryContext(ctx, `
+	SELECT file, guid, serial, batch_id, lot_number, sha256, saved_at FROM saved_vouchers
+	WHERE manifest = ? AND directory = ? ORDER BY saved_at, file`, manifest, directory)
+	if err != nil {
+		return "", fmt.Errorf("failed to read saved vouchers: %w", err)
+	}
+	doc := DiskManifest{
+		Format:     DiskManifestFormat,
+		Station:    m.buildInfo.StationID,
+		InstanceID: m.buildInfo.InstanceID,
+		CreatedAt:  time.Now().UTC(),
+		Vouchers:   []DiskManifestEntry{},
+	}
+	for rows.Next() {
+		var e DiskManifestEntry
+		var savedAt int64
+		if err := rows.Scan(&e.File, &e.GUID, &e.Serial, &doc.BatchID, &doc.LotNumber, &e.SHA256, &savedAt); err != nil {
+			rows.Close()
+			return "", err
+		}
+		e.SavedAt = time.Unix(savedAt, 0).UTC()
+		doc.Vouchers = append(doc.Vouchers, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	if len(doc.Vouchers) == 0 {
+		return "", fmt.Errorf("no vouchers saved for %s in %s", manifest, directory)
+	}
+	if day, ok := strings.CutPrefix(manifest, "day-"); ok {
+		doc.Day = day
+	}
+	doc.Count = len(doc.Vouchers)
+
+	body, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	body = append(body, '\n')
+	signature, err := signBundle(m.signer, body)
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(directory, diskManifestDirectory, "manifest-"+manifest+".json")
+	if err := writeViaSessionTemp(ctx, path, body, 0o644); err != nil {
+		return "", fmt.Errorf("failed to write manifest: %w", err)
+	}
+	if err := writeViaSessionTemp(ctx, path+".sig", []byte(signature+"\n"), 0o644); err != nil {
+		return "", fmt.Errorf("failed to write manifest signature: %w", err)
+	}
+	if _, err := m.db.db.ExecContext(ctx, `
+	INSERT OR REPLACE INTO disk_manifests (manifest, directory, count, written_at) VALUES (?, ?, ?, ?)`,
+		manifest, directory, doc.Count, time.Now().Unix()); err != nil {
+		return "", fmt.Errorf("failed to record manifest: %w", err)
+	}
+	fmt.Printf("📜 Wrote manifest of %d vouchers: %s\n", doc.Count, path)
+	return path, nil
+}
+
+// sha256Sidecar is the content of a <file>.sha256 sidecar, in sha256sum format
+func sha256Sidecar(data []byte, file string) (string, []byte) {
+	sum := sha256.Sum256(data)
+	hexSum := hex.EncodeToString(sum[:])
+	return hexSum, []byte(hexSum + "  " + filepath.Base(file) + "\n")
+}
@@ -0,0 +1,265 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Disk levels, from best to worst
+const (
+	DiskLevelOK       = "ok"
+	DiskLevelWarning  = "warning"
+	DiskLevelCritical = "critical"
+)
+
+// Disk monitor defaults
+const (
+	defaultDiskCheckInterval = 30 * time.Second
+	defaultDiskWarnFreeMB    = 2048
+	defaultDiskCriticalMB    = 512
+)
+
+// DiskMonitor watches free space on the volumes holding the databases and the
+// save_to_disk directories, and the size of the databases. Below the critical
+// floor it can refuse new DI sessions, so devices are turned away at
+// DI.AppStart instead of failing mid-voucher on a write that doesn't fit.
+// A nil *DiskMonitor monitors nothing and refuses nothing.
+type DiskMonitor struct {
+	config   *DiskMonitorConfig
+	paths    []diskPath
+	notifier *Notifier
+	auditLog *AuditLog
+
+	mu     sync.Mutex
+	status []DiskStatus
+	level  string
+}
+
+// diskPath is one monitored location
+type diskPath struct {
+	name  string
+	path  string
+	files []string // Database files whose size is reported
+}
+
+// DiskStatus is the last check of one monitored location
+type DiskStatus struct {
+	Name       string    `json:"name"` // "database", "station_database", "save_to_disk" or "save_to_disk.<destination>"
+	Path       string    `json:"path"`
+	FreeBytes  uint64    `json:"free_bytes"`
+	TotalBytes uint64    `json:"total_bytes"`
+	SizeBytes  int64     `json:"size_bytes,omitempty"` // Database and WAL size
+	Level      string    `json:"level"`
+	Error      string    `json:"error,omitempty"`
+	CheckedAt  time.Time `json:"checked_at"`
+}
+
+// DiskReport is served by GET /api/disk
+type DiskReport struct {
+	Level    string       `json:"level"`    // Worst level of all locations
+	Refusing bool         `json:"refusing"` // New DI sessions are being refused
+	Paths    []DiskStatus `json:"paths"`
+}
+
+// NewDiskMonitor creates the disk monitor, or returns nil if it is disabled
+func NewDiskMonitor(config *DiskMonitorConfig, cfg *Config, notifier *Notifier, auditLog *AuditLog) (*DiskMonitor, error) {
+	if !config.Enabled {
+		return nil, nil
+	}
+	if config.CriticalFreeMB < 0 || config.WarnFreeMB < 0 || config.MaxDBSizeMB < 0 {
+		return nil, fmt.Errorf("disk_monitor: sizes must not be negative")
+	}
+	if firstPositive(config.CriticalFreeMB, defaultDiskCriticalMB) > firstPositive(config.WarnFreeMB, defaultDiskWarnFreeMB) {
+		return nil, fmt.Errorf("disk_monitor: critical_free_mb is above warn_free_mb")
+	}
+
+	m := &DiskMonitor{config: config, notifier: notifier, auditLog: auditLog, level: DiskLevelOK}
+	dbPath := cfg.Database.Path
+	m.paths = append(m.paths, diskPath{name: "database", path: dbPath, files: []string{dbPath, dbPath + "-wal"}})
+	stationPath := stationDBPath(cfg)
+	m.paths = append(m.paths, diskPath{name: "station_database", path: stationPath, files: []string{stationPath, stationPath + "-wal"}})
+	if dir := cfg.VoucherManagement.SaveToDisk.Directory; dir != "" {
+		m.paths = append(m.paths, diskPath{name: "save_to_disk", path: dir})
+	}
+	for _, dest := range cfg.VoucherManagement.SaveToDisk.Destinations {
+		if dest.Directory != "" {
+			m.paths = append(m.paths, diskPath{name: "save_to_disk." + dest.Name, path: dest.Directory})
+		}
+	}
+	return m, nil
+}
+
+// Run checks the disks every interval until ctx is done
+func (m *DiskMonitor) Run(ctx context.Context) {
+	if m == nil {
+		return
+	}
+	interval := m.config.Interval
+	if interval <= 0 {
+		interval = defaultDiskCheckInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.Check()
+		}
+	}
+}
+
+// Check measures every location and reports level changes
+func (m *DiskMonitor) Check() {
+	if m == nil {
+		return
+	}
+	warnBytes := uint64(firstPositive(m.config.WarnFreeMB, defaultDiskWarnFreeMB)) << 20
+	criticalBytes := uint64(firstPositive(m.config.CriticalFreeMB, defaultDiskCriticalMB)) << 20
+	maxDBBytes := int64(m.config.MaxDBSizeMB) << 20
+
+	now := time.Now()
+	status := make([]DiskStatus, 0, len(m.paths))
+	level := DiskLevelOK
+	var reasons []string
+	for _, p := range m.paths {
+		s := DiskStatus{Name: p.name, Path: p.path, Level: DiskLevelOK, CheckedAt: now}
+		free, total, err := diskFree(existingDir(p.path))
+		if err != nil {
+			s.Level = DiskLevelWarning
+			s.Error = err.Error()
+			reasons = append(reasons, fmt.Sprintf("%s: %v", p.name, err))
+		} else {
+			s.FreeBytes, s.TotalBytes = free, total
+			switch {
+			case free < criticalBytes:
+				s.Level = DiskLevelCritical
+				reasons = append(reasons, fmt.Sprintf("%s: %d MB free, below the %d MB floor", p.name, free>>20, criticalBytes>>20))
+			case free < warnBytes:
+				s.Level = DiskLevelWarning
+				reasons = append(reasons, fmt.Sprintf("%s: %d MB free", p.name, free>>20))
+			}
+		}
+		for _, file := range p.files {
+			if info, err := os.Stat(file); err == nil {
+				s.SizeBytes += info.Size()
+			}
+		}
+		if maxDBBytes > 0 && s.SizeBytes > maxDBBytes {
+			if s.Level == DiskLevelOK {
+				s.Level = DiskLevelWarning
+			}
+			reasons = append(reasons, fmt.Sprintf("%s: %d MB, above max_db_size_mb %d", p.name, s.SizeBytes>>20, m.config.MaxDBSizeMB))
+		}
+		level = worseDiskLevel(level, s.Level)
+		status = append(status, s)
+	}
+
+	m.mu.Lock()
+	previous := m.level
+	m.status, m.level = status, level
+	m.mu.Unlock()
+	if level == previous {
+		return
+	}
+
+	switch level {
+	case DiskLevelCritical:
+		message := fmt.Sprintf("disk space critical: %v", reasons)
+		if m.config.RefuseBelowCritical {
+			message += "; refusing new DI sessions"
+		}
+		m.notifier.Critical("disk_space", message)
+		m.auditLog.Record(context.Background(), AuditEvent{Event: "disk_space_critical", Detail: message})
+	case DiskLevelWarning:
+		fmt.Printf("⚠️  Disk space low: %v\n", reasons)
+	default:
+		fmt.Printf("✅ Disk space back above thresholds\n")
+		if previous == DiskLevelCritical {
+			m.auditLog.Record(context.Background(), AuditEvent{Event: "disk_space_recovered"})
+		}
+	}
+}
+
+// refusing reports whether new DI sessions are refused
+func (m *DiskMonitor) refusing() bool {
+	if m == nil || !m.config.RefuseBelowCritical {
+		return false
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.level == DiskLevelCritical
+}
+
+// Report returns the last check of every location
+func (m *DiskMonitor) Report() DiskReport {
+	if m == nil {
+		return DiskReport{Level: DiskLevelOK, Paths: []DiskStatus{}}
+	}
+	refusing := m.refusing()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return DiskReport{Level: m.level, Refusing: refusing, Paths: append([]DiskStatus{}, m.status...)}
+}
+
+// Middleware refuses DI.AppStart while disk space is critical; sessions
+// already past AppStart are allowed to finish
+func (m *DiskMonitor) Middleware(next http.Handler) http.Handler {
+	if m == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("msg") == "10" && m.refusing() {
+			reason := "station disk space is below the critical floor"
+			m.auditLog.Record(r.Context(), AuditEvent{Event: "di_rejected_disk_space", Detail: fmt.Sprintf("%s; from %s", reason, r.RemoteAddr)})
+			writeFDOError(w, r.PathValue("msg"), reason)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Handler serves GET /api/disk
+func (m *DiskMonitor) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, m.Report())
+	})
+}
+
+// worseDiskLevel returns the worse of two levels
+func worseDiskLevel(a, b string) string {
+	rank := map[string]int{DiskLevelOK: 0, DiskLevelWarning: 1, DiskLevelCritical: 2}
+	if rank[b] > rank[a] {
+		return b
+	}
+	return a
+}
+
+// existingDir returns the nearest existing directory holding path, so
+// locations that haven't been created yet are measured on their volume
+func existingDir(path string) string {
+	dir := path
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		dir = filepath.Dir(dir)
+	}
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir
+		}
+		dir = parent
+	}
+}
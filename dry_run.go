@@ -0,0 +1,239 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/fido-device-onboard/go-fdo"
+	"github.com/fido-device-onboard/go-fdo/cbor"
+	"github.com/fido-device-onboard/go-fdo/protocol"
+)
+
+// defaultDryRunReportDirectory is where dry-run reports go when none is configured
+const defaultDryRunReportDirectory = "dry-run-reports"
+
+// dryRunPendingTTL bounds how long a dry-run GUID waits for the FDO database
+// write it suppresses; DI sessions that fail after the pipeline never make it
+const dryRunPendingTTL = time.Hour
+
+// DryRun runs the voucher pipeline of selected devices for line bring-up and
+// operator training. DI completes and every stage runs with the real owner
+// key command, policies and signer, but nothing is kept or delivered: the
+// upload goes to a stub, save_to_disk to a temp dir, the voucher isn't stored
+// in the FDO database, and no quota, batch, transfer, claim URL or session
+// record is written. Each device gets a JSON report instead. The config is
+// read live, so dry runs can be switched on and off without a restart.
+type DryRun struct {
+	config   *DryRunConfig
+	auditLog *AuditLog
+
+	mu      sync.Mutex
+	pending map[protocol.GUID]time.Time // Dry-run vouchers the FDO database must not store
+}
+
+// DryRunReport is written for every dry-run device
+type DryRunReport struct {
+	GUID          string        `json:"guid"`
+	Serial        string        `json:"serial"`
+	Model         string        `json:"model"`
+	Customer      string        `json:"customer,omitempty"`
+	Profile       string        `json:"profile,omitempty"` // Upload auth profile named by the owner entry
+	StartedAt     time.Time     `json:"started_at"`
+	TookMS        int64         `json:"took_ms"`
+	Stages        []DryRunStage `json:"stages"`
+	OwnerKey      string        `json:"owner_key,omitempty"` // Key type and SHA-256
+	Entries       int           `json:"entries"`             // Voucher entries after signover
+	VoucherSHA256 string        `json:"voucher_sha256,omitempty"`
+	VoucherFile   string        `json:"voucher_file,omitempty"` // Temp copy of the voucher
+	Error         string        `json:"error,omitempty"`
+	startTime     time.Time     // Monotonic start, for TookMS
+}
+
+// DryRunStage is one pipeline stage of a dry run and what it did
+type DryRunStage struct {
+	Stage  string `json:"stage"`
+	Detail string `json:"detail"`
+}
+
+// NewDryRun creates the dry-run mode
+func NewDryRun(config *DryRunConfig, auditLog *AuditLog) *DryRun {
+	if config.Enabled {
+		fmt.Printf("🧪 Dry-run mode: no voucher will be kept or delivered\n")
+	}
+	return &DryRun{config: config, auditLog: auditLog, pending: map[protocol.GUID]time.Time{}}
+}
+
+// Applies reports whether a device whose owner names the upload auth profile
+// is a dry run. A nil *DryRun applies to no device.
+func (d *DryRun) Applies(profile string) bool {
+	if d == nil {
+		return false
+	}
+	return d.config.Enabled || (profile != "" && slices.Contains(d.config.Profiles, profile))
+}
+
+// Start begins the report of a dry-run device
+func (d *DryRun) Start(serial, model, guid, customer, profile string) *DryRunReport {
+	fmt.Printf("🧪 Dry run for %s: nothing will be kept or delivered\n", serialRules.Serial(serial))
+	return &DryRunReport{
+		GUID:      guid,
+		Serial:    serial,
+		Model:     model,
+		Customer:  customer,
+		Profile:   profile,
+		StartedAt: stationClock.Now().UTC(),
+		startTime: time.Now(),
+		Stages:    []DryRunStage{},
+	}
+}
+
+// stage adds a stage to the report
+func (r *DryRunReport) stage(stage, format string, args ...any) {
+	r.Stages = append(r.Stages, DryRunStage{Stage: stage, Detail: fmt.Sprintf(format, args...)})
+}
+
+// StubUpload stands in for the voucher upload and records where it would have gone
+func (d *DryRun) StubUpload(report *DryRunReport, config *VoucherConfig, recipientURL, profile string) {
+	switch {
+	case !config.VoucherUpload.Enabled:
+		report.stage("upload", "upload disabled")
+	case config.VoucherUpload.Mode == "http":
+		if recipientURL == "" {
+			recipientURL = config.VoucherUpload.URL
+		}
+		if profile == "" {
+			profile = config.VoucherUpload.AuthProfile
+		}
+		report.stage("upload", "stub: would POST to %s with auth profile %q", recipientURL, profile)
+	default:
+		report.stage("upload", "stub: would run %s", config.VoucherUpload.ExternalCommand)
+	}
+}
+
+// SaveToTemp saves the voucher to a temp dir instead of the save_to_disk destinations
+func (d *DryRun) SaveToTemp(report *DryRunReport, ov *fdo.Voucher) error {
+	text, err := formatVoucherFile(ov)
+	if err != nil {
+		return fmt.Errorf("failed to format voucher: %w", err)
+	}
+	dir, err := os.MkdirTemp("", "fdo-dry-run-"+report.GUID+"-*")
+	if err != nil {
+		return fmt.Errorf("failed to create dry-run directory: %w", err)
+	}
+	report.VoucherFile = filepath.Join(dir, report.GUID+".fdoov")
+	if err := os.WriteFile(report.VoucherFile, []byte(text), 0o644); err != nil {
+		return fmt.Errorf("failed to save dry-run voucher: %w", err)
+	}
+	report.stage("save_to_disk", "saved to %s instead of save_to_disk", report.VoucherFile)
+	return nil
+}
+
+// Finish completes the report of a dry-run device, writes it and, when the
+// pipeline succeeded, keeps the voucher out of the FDO database
+func (d *DryRun) Finish(ctx context.Context, report *DryRunReport, ov *fdo.Voucher, pipelineErr error) {
+	report.TookMS = time.Since(report.startTime).Milliseconds()
+	report.Entries = len(ov.Entries)
+	if data, err := cbor.Marshal(ov); err == nil {
+		report.VoucherSHA256 = voucherHash(data)
+	}
+	if pipelineErr != nil {
+		report.Error = pipelineErr.Error()
+	} else {
+		d.mu.Lock()
+		now := stationClock.Now()
+		for guid, marked := range d.pending {
+			if now.Sub(marked) > dryRunPendingTTL {
+				delete(d.pending, guid)
+			}
+		}
+		d.pending[ov.Header.Val.GUID] = now
+		d.mu.Unlock()
+	}
+
+	path, err := d.writeReport(report)
+	if err != nil {
+		fmt.Printf("⚠️  %v\n", err)
+	}
+	detail := fmt.Sprintf("dry run in %dms, report %s", report.TookMS, path)
+	if pipelineErr != nil {
+		detail = fmt.Sprintf("dry run failed: %v, report %s", pipelineErr, path)
+		fmt.Printf("🧪 Dry run for %s failed: %v (report %s)\n", serialRules.Serial(report.Serial), pipelineErr, path)
+	} else {
+		fmt.Printf("🧪 Dry run for %s complete (report %s)\n", serialRules.Serial(report.Serial), path)
+	}
+	d.auditLog.Record(ctx, AuditEvent{
+		Event:    "di_dry_run",
+		Serial:   report.Serial,
+		GUID:     report.GUID,
+		Customer: report.Customer,
+		Model:    report.Model,
+		Detail:   detail,
+	})
+}
+
+// writeReport writes a report as <report_directory>/<guid>.json
+func (d *DryRun) writeReport(report *DryRunReport) (string, error) {
+	dir := d.config.ReportDirectory
+	if dir == "" {
+		dir = defaultDryRunReportDirectory
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create dry-run report directory: %w", err)
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode dry-run report: %w", err)
+	}
+	path := filepath.Join(dir, report.GUID+".json")
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return "", fmt.Errorf("failed to write dry-run report: %w", err)
+	}
+	return path, nil
+}
+
+// validateDryRun checks that dry_run.profiles names configured upload auth profiles
+func validateDryRun(config *Config) error {
+	for _, profile := range config.DryRun.Profiles {
+		if _, ok := config.VoucherManagement.UploadAuthProfiles[profile]; !ok {
+			return fmt.Errorf("dry_run.profiles: unknown upload auth profile %q", profile)
+		}
+	}
+	return nil
+}
+
+// dryRunVouchers keeps the vouchers of dry-run devices out of the FDO database
+type dryRunVouchers struct {
+	fdo.ManufacturerVoucherPersistentState
+	dryRun *DryRun
+}
+
+// Vouchers wraps the FDO voucher store so dry-run vouchers are not stored
+func (d *DryRun) Vouchers(store fdo.ManufacturerVoucherPersistentState) fdo.ManufacturerVoucherPersistentState {
+	if d == nil {
+		return store
+	}
+	return &dryRunVouchers{ManufacturerVoucherPersistentState: store, dryRun: d}
+}
+
+// NewVoucher stores a voucher unless it belongs to a dry run
+func (s *dryRunVouchers) NewVoucher(ctx context.Context, ov *fdo.Voucher) error {
+	s.dryRun.mu.Lock()
+	_, dry := s.dryRun.pending[ov.Header.Val.GUID]
+	delete(s.dryRun.pending, ov.Header.Val.GUID)
+	s.dryRun.mu.Unlock()
+	if dry {
+		fmt.Printf("🧪 Dry run: voucher %x not stored\n", ov.Header.Val.GUID[:])
+		return nil
+	}
+	return s.ManufacturerVoucherPersistentState.NewVoucher(ctx, ov)
+}
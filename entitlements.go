@@ -0,0 +1,291 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+)
+
+// Premium features an entitlement can grant
+const (
+	EntitlementHSM         = "hsm"          // voucher_signing.mode hsm or external
+	EntitlementStandby     = "standby"      // Cold standby, database snapshots and the read-only replica
+	EntitlementMultiTenant = "multi_tenant" // Per-customer save_to_disk destinations, upload destinations, quota rules and admins
+)
+
+// entitlementFeatures lists every feature an entitlement can grant
+var entitlementFeatures = []string{EntitlementHSM, EntitlementStandby, EntitlementMultiTenant}
+
+// EntitlementPublicKey verifies entitlement files. Licensed builds set it at
+// link time to the base64 DER (PKIX) public key of the issuer:
+//
+//	go build -ldflags "-X main.EntitlementPublicKey=$(openssl pkey -pubin -in issuer.pub -outform DER | base64 -w0)"
+//
+// Builds without it are unrestricted.
+var EntitlementPublicKey = ""
+
+// Entitlement status reported in /version
+const (
+	EntitlementUnrestricted = "unrestricted" // Build without an issuer key: every feature is available
+	EntitlementValid        = "valid"
+	EntitlementExpired      = "expired"
+	EntitlementMissing      = "missing" // No entitlements.file
+	EntitlementInvalid      = "invalid" // Unreadable, badly signed, or issued for another site or station
+)
+
+// stationEntitlements gates premium features. main replaces it with the
+// entitlement loaded from entitlements.file.
+var stationEntitlements = &Entitlements{status: EntitlementUnrestricted}
+
+// Entitlement is the signed grant of premium features to a site. It is
+// verified offline against the key built into the binary.
+type Entitlement struct {
+	Licensee   string    `json:"licensee"`
+	SiteCode   string    `json:"site_code,omitempty"`   // Only valid for station.site_code; empty = any site
+	StationIDs []string  `json:"station_ids,omitempty"` // Only valid for these station.station_id values; empty = any
+	Features   []string  `json:"features"`
+	IssuedAt   time.Time `json:"issued_at"`
+	NotAfter   time.Time `json:"not_after,omitzero"` // Zero = no expiry
+}
+
+// entitlementFile is an entitlement as issued: the signature covers the
+// entitlement member byte for byte
+type entitlementFile struct {
+	Entitlement json.RawMessage `json:"entitlement"`
+	Signature   string          `json:"signature"` // Base64; ECDSA or RSA over SHA-256, or Ed25519
+}
+
+// Entitlements is the entitlement of this station and the result of checking it
+type Entitlements struct {
+	status      string
+	entitlement *Entitlement
+	problem     string // Why the entitlement is missing, invalid or expired
+}
+
+// EntitlementInfo reports the station's entitlement in /version
+type EntitlementInfo struct {
+	Status   string     `json:"status"` // "unrestricted" | "valid" | "expired" | "missing" | "invalid"
+	Licensee string     `json:"licensee,omitempty"`
+	SiteCode string     `json:"site_code,omitempty"`
+	Features []string   `json:"features"` // Premium features this station may use
+	NotAfter *time.Time `json:"not_after,omitempty"`
+	Problem  string     `json:"problem,omitempty"`
+}
+
+// LoadEntitlements verifies entitlements.file against the built-in issuer
+// key. Problems don't fail startup: they leave the premium features locked,
+// and a config that uses one is then refused by checkEntitlements.
+func LoadEntitlements(config *EntitlementConfig, station *StationConfig) *Entitlements {
+	if EntitlementPublicKey == "" {
+		return &Entitlements{status: EntitlementUnrestricted}
+	}
+	if config.File == "" {
+		return &Entitlements{status: EntitlementMissing, problem: "no entitlements.file configured"}
+	}
+	entitlement, err := readEntitlement(config.File)
+	if err != nil {
+		return &Entitlements{status: EntitlementInvalid, problem: err.Error()}
+	}
+	if entitlement.SiteCode != "" && entitlement.SiteCode != station.SiteCode {
+		return &Entitlements{status: EntitlementInvalid, entitlement: entitlement,
+			problem: fmt.Sprintf("entitlement is for site %q, not %q", entitlement.SiteCode, station.SiteCode)}
+	}
+	if len(entitlement.StationIDs) > 0 && !slices.Contains(entitlement.StationIDs, station.StationID) {
+		return &Entitlements{status: EntitlementInvalid, entitlement: entitlement,
+			problem: fmt.Sprintf("entitlement does not list station %q", station.StationID)}
+	}
+	if !entitlement.NotAfter.IsZero() && stationClock.Now().After(entitlement.NotAfter) {
+		return &Entitlements{status: EntitlementExpired, entitlement: entitlement,
+			problem: fmt.Sprintf("entitlement expired %s", entitlement.NotAfter.UTC().Format(time.RFC3339))}
+	}
+	return &Entitlements{status: EntitlementValid, entitlement: entitlement}
+}
+
+// readEntitlement reads an entitlement file and verifies its signature
+func readEntitlement(path string) (*Entitlement, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read entitlement file: %w", err)
+	}
+	var file entitlementFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid entitlement file: %w", err)
+	}
+	publicKey, err := entitlementIssuerKey()
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyBundleSignature(publicKey, file.Entitlement, file.Signature); err != nil {
+		return nil, fmt.Errorf("entitlement file: %w", err)
+	}
+	var entitlement Entitlement
+	if err := json.Unmarshal(file.Entitlement, &entitlement); err != nil {
+		return nil, fmt.Errorf("invalid entitlement: %w", err)
+	}
+	return &entitlement, nil
+}
+
+// entitlementIssuerKey decodes EntitlementPublicKey
+func entitlementIssuerKey() (crypto.PublicKey, error) {
+	der, err := base64.StdEncoding.DecodeString(EntitlementPublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid built-in entitlement key: %w", err)
+	}
+	publicKey, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("invalid built-in entitlement key: %w", err)
+	}
+	return publicKey, nil
+}
+
+// Allowed reports whether the station may use a premium feature
+func (e *Entitlements) Allowed(feature string) bool {
+	if e.status == EntitlementUnrestricted {
+		return true
+	}
+	return e.status == EntitlementValid && slices.Contains(e.entitlement.Features, feature)
+}
+
+// Require returns an error naming what needs a feature the station is not entitled to
+func (e *Entitlements) Require(feature, what string) error {
+	if e.Allowed(feature) {
+		return nil
+	}
+	if e.problem != "" {
+		return fmt.Errorf("%s needs the %q entitlement: %s", what, feature, e.problem)
+	}
+	return fmt.Errorf("%s needs the %q entitlement, which %s's entitlement does not include", what, feature, e.entitlement.Licensee)
+}
+
+// Info reports the entitlement for /version
+func (e *Entitlements) Info() EntitlementInfo {
+	info := EntitlementInfo{Status: e.status, Problem: e.problem, Features: []string{}}
+	if e.entitlement != nil {
+		info.Licensee = e.entitlement.Licensee
+		info.SiteCode = e.entitlement.SiteCode
+		if !e.entitlement.NotAfter.IsZero() {
+			notAfter := e.entitlement.NotAfter.UTC()
+			info.NotAfter = &notAfter
+		}
+	}
+	for _, feature := range entitlementFeatures {
+		if e.Allowed(feature) {
+			info.Features = append(info.Features, feature)
+		}
+	}
+	return info
+}
+
+// WarnExpiring prints a warning at startup when the entitlement ends within 30 days
+func (e *Entitlements) WarnExpiring() {
+	if e.status != EntitlementValid || e.entitlement.NotAfter.IsZero() {
+		return
+	}
+	if left := e.entitlement.NotAfter.Sub(stationClock.Now()); left < 30*24*time.Hour {
+		fmt.Printf("⚠️  Entitlement of %s expires %s; premium features stop at the next restart after that\n",
+			e.entitlement.Licensee, e.entitlement.NotAfter.UTC().Format(time.RFC3339))
+	}
+}
+
+// checkEntitlements refuses a config that uses a premium feature the station
+// is not entitled to. Upload destinations assigned to an owner live in the
+// catalog, not the config: the catalog checks them when they are stored and
+// again when an upload is routed through one.
+func checkEntitlements(cfg *Config) error {
+	e := stationEntitlements
+	switch cfg.VoucherManagement.VoucherSigning.Mode {
+	case "hsm", "external":
+		if err := e.Require(EntitlementHSM, "voucher_management.voucher_signing.mode "+cfg.VoucherManagement.VoucherSigning.Mode); err != nil {
+			return err
+		}
+	}
+	if cfg.Standby.ServeSnapshots {
+		if err := e.Require(EntitlementStandby, "standby.serve_snapshots"); err != nil {
+			return err
+		}
+	}
+	for _, dest := range cfg.VoucherManagement.SaveToDisk.Destinations {
+		if len(dest.Customers) > 0 {
+			if err := e.Require(EntitlementMultiTenant, "save_to_disk destination "+dest.Name+" selected by customer"); err != nil {
+				return err
+			}
+		}
+	}
+	for _, user := range cfg.Admin.Users {
+		if len(user.Customers) > 0 {
+			if err := e.Require(EntitlementMultiTenant, "admin user "+user.Name+" limited to customers"); err != nil {
+				return err
+			}
+		}
+	}
+	for _, rule := range cfg.Quotas.Rules {
+		if rule.Customer != "" {
+			if err := e.Require(EntitlementMultiTenant, "quota rule "+rule.Name+" for customer "+rule.Customer); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// runEntitlementSign implements "entitlement sign -key <key.pem> -out <file>
+// <entitlement.json>": it signs an entitlement with the issuer's private key
+func runEntitlementSign(args []string) error {
+	fs := flag.NewFlagSet("entitlement sign", flag.ContinueOnError)
+	keyFile := fs.String("key", "", "Issuer private key (PEM)")
+	outFile := fs.String("out", "", "Write the signed entitlement file here")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 || *keyFile == "" || *outFile == "" {
+		return fmt.Errorf("usage: entitlement sign -key <key.pem> -out <file> <entitlement.json>")
+	}
+	data, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("failed to read entitlement: %w", err)
+	}
+	var entitlement Entitlement
+	if err := json.Unmarshal(data, &entitlement); err != nil {
+		return fmt.Errorf("invalid entitlement: %w", err)
+	}
+	for _, feature := range entitlement.Features {
+		if !slices.Contains(entitlementFeatures, feature) {
+			return fmt.Errorf("unknown feature %q (known: %v)", feature, entitlementFeatures)
+		}
+	}
+	if entitlement.IssuedAt.IsZero() {
+		entitlement.IssuedAt = stationClock.Now().UTC().Truncate(time.Second)
+	}
+	body, err := json.Marshal(entitlement)
+	if err != nil {
+		return fmt.Errorf("failed to encode entitlement: %w", err)
+	}
+	signer, err := loadPrivateKeyFile(*keyFile)
+	if err != nil {
+		return err
+	}
+	signature, err := signBundle(signer, body)
+	if err != nil {
+		return err
+	}
+	// Not indented: that would reformat the signed entitlement member
+	out, err := json.Marshal(entitlementFile{Entitlement: body, Signature: signature})
+	if err != nil {
+		return fmt.Errorf("failed to encode entitlement file: %w", err)
+	}
+	if err := os.WriteFile(*outFile, append(out, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write entitlement file: %w", err)
+	}
+	fmt.Printf("📜 Wrote entitlement for %s (%v) to %s\n", entitlement.Licensee, entitlement.Features, *outFile)
+	return nil
+}
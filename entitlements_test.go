@@ -0,0 +1,57 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+// TestOwnerRoutingNeedsMultiTenant checks that an upload destination assigned
+// to an owner can neither be stored nor route vouchers without multi_tenant
+func TestOwnerRoutingNeedsMultiTenant(t *testing.T) {
+	ctx := context.Background()
+	db, err := OpenStationDB(filepath.Join(t.TempDir(), "station.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	catalog := NewUploadDestinationCatalog(&VoucherConfig{}, db)
+	if err := catalog.Initialize(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// Stored while the station was unrestricted, or by an older build
+	if _, err := db.db.ExecContext(ctx,
+		`INSERT INTO upload_destinations (name, url, owner) VALUES ('acme', 'https://acme.example/vouchers', 'acme')`); err != nil {
+		t.Fatal(err)
+	}
+
+	saved := stationEntitlements
+	defer func() { stationEntitlements = saved }()
+	stationEntitlements = &Entitlements{status: EntitlementValid,
+		entitlement: &Entitlement{Licensee: "Acme", Features: []string{EntitlementHSM}}}
+
+	if _, err := catalog.Resolve(ctx, "acme", "https://owner.example/vouchers", ""); err == nil {
+		t.Error("voucher routed to the owner's destination without multi_tenant")
+	}
+	if _, err := catalog.Put(ctx, &UploadDestinationRequest{Name: "globex", URL: "https://globex.example/vouchers", Owner: "globex"}); err == nil {
+		t.Error("destination for an owner stored without multi_tenant")
+	}
+	if _, err := catalog.DiffRouting(ctx, &RoutingTable{Version: routingTableVersion,
+		Destinations: []UploadDestinationRequest{{Name: "globex", URL: "https://globex.example/vouchers", Owner: "globex"}}}); err == nil {
+		t.Error("routing table with a destination for an owner accepted without multi_tenant")
+	}
+	// Destinations not assigned to an owner are not gated
+	if d, err := catalog.Resolve(ctx, "", "https://owner.example/vouchers", ""); err != nil || d.URL != "https://owner.example/vouchers" {
+		t.Errorf("unassigned destination: %+v, %v", d, err)
+	}
+
+	stationEntitlements.entitlement.Features = append(stationEntitlements.entitlement.Features, EntitlementMultiTenant)
+	d, err := catalog.Resolve(ctx, "acme", "https://owner.example/vouchers", "")
+	if err != nil || d.Name != "acme" {
+		t.Errorf("entitled owner routing: %+v, %v", d, err)
+	}
+}
@@ -0,0 +1,339 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fido-device-onboard/go-fdo"
+	"github.com/fido-device-onboard/go-fdo/cbor"
+	"github.com/fido-device-onboard/go-fdo/protocol"
+	"github.com/fido-device-onboard/go-fdo/sqlite"
+)
+
+// ExtendFailure is the context of a failed voucher extension, saved so it can
+// be replayed offline with "voucher debug-extend"
+type ExtendFailure struct {
+	Time        time.Time      `json:"time"`
+	Serial      string         `json:"serial"`
+	Model       string         `json:"model"`
+	GUID        string         `json:"guid"`
+	Mode        string         `json:"mode"` // Voucher signing mode at the time of failure
+	Error       string         `json:"error"`
+	Diagnostics []string       `json:"diagnostics"`
+	Voucher     []byte         `json:"voucher"`    // CBOR voucher before extension
+	NextOwner   string         `json:"next_owner"` // PEM public key or certificate chain
+	ExtraData   map[int][]byte `json:"extra_data,omitempty"`
+}
+
+// extendVoucherTo extends a voucher to nextOwner, which may be an ECDSA or RSA
+// public key or a certificate chain
+func extendVoucherTo(ov *fdo.Voucher, owner crypto.Signer, nextOwner crypto.PublicKey, extraData map[int][]byte) (*fdo.Voucher, error) {
+	switch key := nextOwner.(type) {
+	case *ecdsa.PublicKey:
+		if key.Curve != elliptic.P256() && key.Curve != elliptic.P384() {
+			return nil, fmt.Errorf("nextOwner is a %s key; FDO vouchers can only be extended to P-256, P-384 or RSA keys", key.Curve.Params().Name)
+		}
+		return fdo.ExtendVoucher(ov, owner, key, extraData)
+	case ed25519.PublicKey:
+		return nil, fmt.Errorf("nextOwner is an Ed25519 key; FDO vouchers can only be extended to P-256, P-384 or RSA keys")
+	case *rsa.PublicKey:
+		return fdo.ExtendVoucher(ov, owner, key, extraData)
+	case []*x509.Certificate:
+		return fdo.ExtendVoucher(ov, owner, key, extraData)
+	default:
+		return nil, fmt.Errorf("unsupported nextOwner key type: %T", nextOwner)
+	}
+}
+
+// diagnoseExtend looks for the usual reasons an extension fails: a voucher that
+// doesn't round-trip through CBOR, a signer that isn't the current owner, a next
+// owner key of a different type than the current one, and a broken owner chain.
+// owner may be nil when the signing key is held elsewhere (e.g. an HSM).
+func diagnoseExtend(ov *fdo.Voucher, owner crypto.Signer, nextOwner crypto.PublicKey) []string {
+	var notes []string
+	if ov == nil {
+		return []string{"no voucher to extend"}
+	}
+
+	if data, err := cbor.Marshal(ov); err != nil {
+		notes = append(notes, fmt.Sprintf("CBOR: voucher does not encode: %v", err))
+	} else {
+		var decoded fdo.Voucher
+		if err := cbor.Unmarshal(data, &decoded); err != nil {
+			notes = append(notes, fmt.Sprintf("CBOR: voucher does not decode after encoding (%d bytes): %v", len(data), err))
+		}
+	}
+
+	// The current owner is the last entry's key, or the manufacturer key if there are no entries
+	current := ov.Header.Val.ManufacturerKey
+	source := "manufacturer key"
+	if n := len(ov.Entries); n > 0 {
+		current = ov.Entries[n-1].Payload.Val.PublicKey
+		source = fmt.Sprintf("entry %d key", n-1)
+	}
+	notes = append(notes, fmt.Sprintf("voucher has %d entries; current owner is the %s (%v, %v encoding)", len(ov.Entries), source, current.Type, current.Encoding))
+
+	currentPub, err := current.Public()
+	if err != nil {
+		notes = append(notes, fmt.Sprintf("current owner key cannot be decoded: %v", err))
+	}
+
+	if owner != nil && currentPub != nil {
+		if pub, ok := owner.Public().(interface{ Equal(crypto.PublicKey) bool }); !ok || !pub.Equal(currentPub) {
+			notes = append(notes, fmt.Sprintf("signing key (%s) is not the current owner key (%s)", describeKey(owner.Public()), describeKey(currentPub)))
+		}
+	}
+
+	nextKey := nextOwner
+	if chain, ok := nextOwner.([]*x509.Certificate); ok {
+		notes = append(notes, diagnoseChain(chain)...)
+		if len(chain) > 0 {
+			nextKey = chain[0].PublicKey
+		}
+	}
+	if nextOwner == nil {
+		notes = append(notes, "no next owner key")
+	} else if currentPub != nil && describeKey(nextKey) != describeKey(currentPub) {
+		notes = append(notes, fmt.Sprintf("next owner key %s does not match current owner key type %s; every entry must use the same key type",
+			describeKey(nextKey), describeKey(currentPub)))
+	}
+	return notes
+}
+
+// diagnoseChain checks that each certificate of an owner chain is in date and signed by the next one
+func diagnoseChain(chain []*x509.Certificate) []string {
+	if len(chain) == 0 {
+		return []string{"next owner certificate chain is empty"}
+	}
+	var notes []string
+	now := time.Now()
+	for i, cert := range chain {
+		if now.Before(cert.NotBefore) || now.After(cert.NotAfter) {
+			notes = append(notes, fmt.Sprintf("chain[%d] %q is not valid now (%s to %s)", i, cert.Subject, cert.NotBefore.Format(time.RFC3339), cert.NotAfter.Format(time.RFC3339)))
+		}
+		if i+1 < len(chain) {
+			if err := cert.CheckSignatureFrom(chain[i+1]); err != nil {
+				notes = append(notes, fmt.Sprintf("chain[%d] %q is not signed by chain[%d] %q: %v", i, cert.Subject, i+1, chain[i+1].Subject, err))
+			}
+		}
+	}
+	return notes
+}
+
+// describeKey names a key's algorithm and size, e.g. "P-384" or "RSA-3072"
+func describeKey(pub crypto.PublicKey) string {
+	switch key := pub.(type) {
+	case *ecdsa.PublicKey:
+		return key.Curve.Params().Name
+	case *rsa.PublicKey:
+		return fmt.Sprintf("RSA-%d", key.N.BitLen())
+	default:
+		return fmt.Sprintf("%T", pub)
+	}
+}
+
+// saveExtendFailure writes the failure context to dir and returns the file name
+func saveExtendFailure(dir string, failure *ExtendFailure) (string, error) {
+	if dir == "" {
+		dir = "extend-failures"
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("failed to create extend failure directory: %w", err)
+	}
+	data, err := json.MarshalIndent(failure, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode extend failure: %w", err)
+	}
+	name := filepath.Join(dir, fmt.Sprintf("%s-%s.json", failure.GUID, failure.Time.UTC().Format("20060102T150405")))
+	if err := os.WriteFile(name, data, 0o600); err != nil {
+		return "", fmt.Errorf("failed to write extend failure: %w", err)
+	}
+	return name, nil
+}
+
+// recordExtendFailure prints diagnostics for a failed extension and saves its context for replay
+func recordExtendFailure(dir, mode, serial, model, guid string, ov *fdo.Voucher, nextOwner crypto.PublicKey, extraData map[int][]byte, extendErr error) {
+	failure := &ExtendFailure{
+		Time:        time.Now(),
+		Serial:      serial,
+		Model:       model,
+		GUID:        guid,
+		Mode:        mode,
+		Error:       extendErr.Error(),
+		Diagnostics: diagnoseExtend(ov, nil, nextOwner),
+		ExtraData:   extraData,
+	}
+	fmt.Printf("🚨 Voucher extension failed for %s: %v\n", serialRules.Serial(serial), extendErr)
+	for _, note := range failure.Diagnostics {
+		fmt.Printf("   🔎 %s\n", note)
+	}
+
+	var err error
+	if failure.Voucher, err = cbor.Marshal(ov); err != nil {
+		fmt.Printf("⚠️  Failed to encode voucher for extend failure dump: %v\n", err)
+	}
+	if nextOwner != nil {
+		if failure.NextOwner, err = encodeNextOwnerPEM(nextOwner); err != nil {
+			fmt.Printf("⚠️  Failed to encode next owner for extend failure dump: %v\n", err)
+		}
+	}
+
+	name, err := saveExtendFailure(dir, failure)
+	if err != nil {
+		fmt.Printf("⚠️  %v\n", err)
+		return
+	}
+	fmt.Printf("💾 Saved extension context to %s (replay with: voucher debug-extend %s)\n", name, name)
+}
+
+// encodeNextOwnerPEM encodes a public key or certificate chain as PEM
+func encodeNextOwnerPEM(nextOwner crypto.PublicKey) (string, error) {
+	chain, ok := nextOwner.([]*x509.Certificate)
+	if !ok {
+		return encodePublicKeyToPEM(nextOwner)
+	}
+	var buf bytes.Buffer
+	for _, cert := range chain {
+		if err := pem.Encode(&buf, &pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}); err != nil {
+			return "", err
+		}
+	}
+	return buf.String(), nil
+}
+
+// runVoucherDebugExtend implements "voucher debug-extend [-key owner.pem] [-out file.fdoov] <failure.json>".
+// It replays a saved extension with the station's internal manufacturer key, or
+// with -key when the voucher was signed elsewhere, and prints the diagnostics.
+func runVoucherDebugExtend(args []string) error {
+	fs := flag.NewFlagSet("voucher debug-extend", flag.ContinueOnError)
+	keyFile := fs.String("key", "", "PEM private key of the current owner (default: internal manufacturer key from the database)")
+	outFile := fs.String("out", "", "Write the extended voucher to this .fdoov file on success")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: voucher debug-extend [-key owner.pem] [-out file.fdoov] <failure.json>")
+	}
+
+	data, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("failed to read extend failure: %w", err)
+	}
+	var failure ExtendFailure
+	if err := json.Unmarshal(data, &failure); err != nil {
+		return fmt.Errorf("failed to parse extend failure: %w", err)
+	}
+	fmt.Printf("Replaying extension for serial %s (GUID %s, mode %s) that failed at %s:\n  %s\n",
+		failure.Serial, failure.GUID, failure.Mode, failure.Time.Format(time.RFC3339), failure.Error)
+
+	var ov fdo.Voucher
+	if err := cbor.Unmarshal(failure.Voucher, &ov); err != nil {
+		return fmt.Errorf("CBOR: saved voucher does not decode: %w", err)
+	}
+
+	var nextOwner crypto.PublicKey
+	chain, err := parseCertificateChainPEM([]byte(failure.NextOwner))
+	switch {
+	case err != nil:
+		return fmt.Errorf("bad next owner chain: %w", err)
+	case len(chain) > 0:
+		nextOwner = chain
+	case failure.NextOwner != "":
+		if nextOwner, err = parseStaticPublicKey(failure.NextOwner); err != nil {
+			return fmt.Errorf("failed to parse next owner key: %w", err)
+		}
+	}
+
+	owner, err := debugExtendSigner(*keyFile)
+	if err != nil {
+		return err
+	}
+
+	for _, note := range diagnoseExtend(&ov, owner, nextOwner) {
+		fmt.Printf("  🔎 %s\n", note)
+	}
+
+	extended, err := extendVoucherTo(&ov, owner, nextOwner, failure.ExtraData)
+	if err != nil {
+		return fmt.Errorf("extension still fails: %w", err)
+	}
+	fmt.Printf("✅ Extension succeeded; voucher now has %d entries\n", len(extended.Entries))
+
+	if *outFile != "" {
+		text, err := formatVoucherFile(extended)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(*outFile, []byte(text), 0o644); err != nil {
+			return fmt.Errorf("failed to write voucher: %w", err)
+		}
+		fmt.Printf("💾 Wrote extended voucher to %s\n", *outFile)
+	}
+	return nil
+}
+
+// debugExtendSigner loads the current owner key from a PEM file, or the internal
+// manufacturer key (as used by internal signing) from the station database
+func debugExtendSigner(keyFile string) (crypto.Signer, error) {
+	if keyFile == "" {
+		state, err := sqlite.Open(config.Database.Path, config.Database.Password)
+		if err != nil {
+			return nil, fmt.Errorf("error opening database: %w", err)
+		}
+		defer state.Close()
+		key, _, err := state.ManufacturerKey(context.Background(), protocol.Secp384r1KeyType, 0)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get manufacturer key: %w", err)
+		}
+		return key, nil
+	}
+
+	return loadPrivateKeyFile(keyFile)
+}
+
+// loadPrivateKeyFile reads a PEM private key (SEC 1, PKCS #1 or PKCS #8)
+func loadPrivateKeyFile(keyFile string) (crypto.Signer, error) {
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("failed to decode PEM block from %s", keyFile)
+	}
+	var key any
+	switch {
+	case strings.Contains(block.Type, "EC PRIVATE KEY"):
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case strings.Contains(block.Type, "RSA PRIVATE KEY"):
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type: %T", key)
+	}
+	return signer, nil
+}
@@ -0,0 +1,116 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+// Package extension is the API for compiled-in extensions of the
+// manufacturing station. An extension is a Go package that registers itself
+// from an init function and is linked into the station with a blank import in
+// extensions.go. Any external_command of the station can then name it, as in
+// "extension:acme-keys", instead of a command line: the extension gets the
+// same variables the command would have, and returns what the command would
+// have printed.
+//
+// The API is versioned. APIVersion changes only when this package changes
+// incompatibly, and the station accepts extensions built against any version
+// from MinAPIVersion on, so an extension keeps working across station
+// releases until the version it was written for is retired.
+package extension
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+const (
+	// APIVersion is the version of this API
+	APIVersion = 1
+
+	// MinAPIVersion is the oldest API version the station still accepts
+	MinAPIVersion = 1
+)
+
+// Pipeline hooks an extension can serve; they match the external command names
+const (
+	HookOwnerSignover = "owner_signover"  // Prints the owner key of a device
+	HookVoucherUpload = "voucher_upload"  // Delivers a voucher
+	HookVoucherSign   = "voucher_signing" // Signs with the manufacturer key
+	HookOVEExtraData  = "ove_extra_data"  // Prints the extra data for a voucher
+	HookAndon         = "andon"           // Runs an andon action
+	HookClaimURL      = "claim_url"       // Prints the claim URL of a voucher
+	HookSaveToDisk    = "save_to_disk"    // Stores a voucher at a save_to_disk destination
+)
+
+// Request is one call of a pipeline hook
+type Request struct {
+	Hook      string            // One of the Hook constants
+	Variables map[string]string // As given to the external command, e.g. "serialno"
+}
+
+// Extension is implemented by every extension. Call must be safe for
+// concurrent use; the station bounds the calls per hook with the limits of
+// external_commands, and ctx carries the hook's timeout.
+type Extension interface {
+	Name() string    // Unique name used in "extension:<name>"
+	APIVersion() int // APIVersion at the time the extension was written
+	Call(ctx context.Context, req Request) (string, error)
+}
+
+// Configurable is implemented by extensions that take settings. Configure is
+// called once at startup with the extension's entry of the station's
+// extensions config section, before any call.
+type Configurable interface {
+	Configure(settings map[string]string) error
+}
+
+var (
+	mu         sync.RWMutex
+	extensions = map[string]Extension{}
+)
+
+// Register makes an extension available by name. It is meant to be called from
+// init and panics if the name is empty or taken, or the extension's API
+// version is not supported.
+func Register(ext Extension) {
+	if err := check(ext); err != nil {
+		panic(err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if _, dup := extensions[ext.Name()]; dup {
+		panic(fmt.Sprintf("extension: %q registered twice", ext.Name()))
+	}
+	extensions[ext.Name()] = ext
+}
+
+// check rejects extensions the station can't load
+func check(ext Extension) error {
+	if ext == nil || ext.Name() == "" {
+		return fmt.Errorf("extension: register needs a named extension")
+	}
+	if v := ext.APIVersion(); v < MinAPIVersion || v > APIVersion {
+		return fmt.Errorf("extension: %q needs API version %d; this station supports %d to %d", ext.Name(), v, MinAPIVersion, APIVersion)
+	}
+	return nil
+}
+
+// Lookup returns the extension registered under name
+func Lookup(name string) (Extension, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	ext, ok := extensions[name]
+	return ext, ok
+}
+
+// Names returns the names of the registered extensions, sorted
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, 0, len(extensions))
+	for name := range extensions {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
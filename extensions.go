@@ -0,0 +1,85 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"fdo-manufacturing-station/extension"
+	// Compiled-in extensions: add a blank import of each extension package
+	// here, e.g.
+	//	_ "example.com/acme/fdo-station-keys"
+)
+
+// extensionPrefix marks an external_command that is served by a compiled-in
+// extension, as in "extension:acme-keys"
+const extensionPrefix = "extension:"
+
+// extensionName returns the extension an external_command names, if it names one
+func extensionName(commandTemplate string) (string, bool) {
+	name, ok := strings.CutPrefix(strings.TrimSpace(commandTemplate), extensionPrefix)
+	return strings.TrimSpace(name), ok
+}
+
+// callExtension runs a hook of a compiled-in extension
+func callExtension(ctx context.Context, name, hook string, variables map[string]string) (string, error) {
+	ext, ok := extension.Lookup(name)
+	if !ok {
+		return "", fmt.Errorf("extension %q is not compiled in", name)
+	}
+	output, err := ext.Call(ctx, extension.Request{Hook: hook, Variables: variables})
+	if err != nil {
+		return "", fmt.Errorf("extension %s: %w", name, err)
+	}
+	return output, nil
+}
+
+// configureExtensions hands each extension that takes settings its entry of
+// the extensions config section
+func configureExtensions(cfg *Config) error {
+	for _, name := range extension.Names() {
+		ext, _ := extension.Lookup(name)
+		configurable, ok := ext.(extension.Configurable)
+		if !ok {
+			continue
+		}
+		if err := configurable.Configure(cfg.Extensions[name]); err != nil {
+			return fmt.Errorf("extension %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// compiledExtensions lists the compiled-in extensions as name@v<api version>
+func compiledExtensions() []string {
+	names := []string{}
+	for _, name := range extension.Names() {
+		ext, _ := extension.Lookup(name)
+		names = append(names, fmt.Sprintf("%s@v%d", name, ext.APIVersion()))
+	}
+	return names
+}
+
+// validateExtensions checks that every command served by an extension, and
+// every extension given settings, names one that is compiled in
+func validateExtensions(cfg *Config) error {
+	for name := range cfg.Extensions {
+		if _, ok := extension.Lookup(name); !ok {
+			return fmt.Errorf("extensions.%s: extension is not compiled in (have %v)", name, extension.Names())
+		}
+	}
+	for field, template := range externalCommandFields(cfg) {
+		name, ok := extensionName(template)
+		if !ok {
+			continue
+		}
+		if _, ok := extension.Lookup(name); !ok {
+			return fmt.Errorf("%s: extension %q is not compiled in (have %v)", field, name, extension.Names())
+		}
+	}
+	return nil
+}
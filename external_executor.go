@@ -6,6 +6,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os/exec"
 	"strings"
@@ -14,39 +15,117 @@ import (
 
 // ExternalCommandExecutor handles execution of external commands with variable substitution
 type ExternalCommandExecutor struct {
+	name            string // Pool the command runs in, e.g. CommandOwnerSignover
 	commandTemplate string
 	timeout         time.Duration
 }
 
-// NewExternalCommandExecutor creates a new external command executor
-func NewExternalCommandExecutor(commandTemplate string, timeout time.Duration) *ExternalCommandExecutor {
+// NewExternalCommandExecutor creates a new external command executor. Calls
+// share the concurrency limit of the named command.
+func NewExternalCommandExecutor(name, commandTemplate string, timeout time.Duration) *ExternalCommandExecutor {
 	return &ExternalCommandExecutor{
+		name:            name,
 		commandTemplate: commandTemplate,
 		timeout:         timeout,
 	}
 }
 
-// Execute runs the external command with variable substitution
+// Execute runs the external command with variable substitution, or sends the
+// variables to the plugin a "plugin:<name>" command or the compiled-in
+// extension an "extension:<name>" command names. Every run,
+// including one that never got a slot, is recorded in the command log.
 func (e *ExternalCommandExecutor) Execute(ctx context.Context, variables map[string]string) (string, error) {
-	// Prepare command with variable substitution
+	// Prepare command with variable substitution; sensitive serials and models are pseudonymized
 	command := e.commandTemplate
+	applied := make(map[string]string, len(variables))
 	for key, value := range variables {
+		switch key {
+		case "serialno", "serial":
+			value = serialRules.Serial(value)
+		case "model":
+			value = serialRules.Model(value)
+		}
+		applied[key] = value
 		command = strings.ReplaceAll(command, "{"+key+"}", value)
 	}
 
 	fmt.Printf(" DEBUG: ExternalExecutor.Execute command=%s\n", command)
 
+	invocation := &CommandInvocation{
+		Name:      e.name,
+		Template:  e.commandTemplate,
+		Command:   command,
+		Variables: applied,
+		Timeout:   e.timeout,
+		StartedAt: time.Now(),
+	}
+	var output, stderr []byte
+	var err error
+	if plugin, ok := pluginName(e.commandTemplate); ok {
+		output, err = e.call(ctx, pluginPrefix+plugin, func(ctx context.Context) (string, error) {
+			return commandPlugins.Call(ctx, plugin, e.name, applied)
+		})
+	} else if ext, ok := extensionName(e.commandTemplate); ok {
+		output, err = e.call(ctx, extensionPrefix+ext, func(ctx context.Context) (string, error) {
+			return callExtension(ctx, ext, e.name, applied)
+		})
+	} else {
+		output, stderr, err = e.run(ctx, command)
+	}
+	invocation.DurationMS = time.Since(invocation.StartedAt).Milliseconds()
+	invocation.ExitCode = commandExitCode(err)
+	invocation.Stdout, invocation.Stderr = string(output), string(stderr)
+	if err != nil {
+		invocation.Error = err.Error()
+	}
+	commandLog.Record(ctx, invocation)
+
+	if err != nil {
+		fmt.Printf(" DEBUG: External command failed: %v, output: %s\n", err, string(output))
+		return "", fmt.Errorf("external command failed: %w, output: %s", err, string(output))
+	}
+
+	fmt.Printf(" DEBUG: External command success, output: %s\n", string(output))
+	return string(output), nil
+}
+
+// run runs a command line in a slot of the command's pool, with its timeout
+func (e *ExternalCommandExecutor) run(ctx context.Context, command string) ([]byte, []byte, error) {
+	// Wait for a slot so a burst of devices can't fork without bound
+	release, err := commandPools.acquire(ctx, e.name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("external command not started: %w", err)
+	}
+	defer release()
+
 	// Execute command with timeout
 	ctx, cancel := context.WithTimeout(ctx, e.timeout)
 	defer cancel()
 
 	cmd := exec.CommandContext(ctx, "sh", "-c", command)
 	output, err := cmd.Output()
+
+	var stderr []byte
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		stderr = exitErr.Stderr
+	}
+	debugCaptureCommand(ctx, command, output, stderr, err)
+	return output, stderr, err
+}
+
+// call runs a plugin or extension call in a slot of the command's pool, with its timeout
+func (e *ExternalCommandExecutor) call(ctx context.Context, target string, fn func(context.Context) (string, error)) ([]byte, error) {
+	release, err := commandPools.acquire(ctx, e.name)
 	if err != nil {
-		fmt.Printf(" DEBUG: External command failed: %v, output: %s\n", err, string(output))
-		return "", fmt.Errorf("external command failed: %w, output: %s", err, string(output))
+		return nil, fmt.Errorf("external command not started: %w", err)
 	}
+	defer release()
 
-	fmt.Printf(" DEBUG: External command success, output: %s\n", string(output))
-	return string(output), nil
+	ctx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+
+	output, err := fn(ctx)
+	debugCaptureCommand(ctx, target+" "+e.name, []byte(output), nil, err)
+	return []byte(output), err
 }
@@ -11,26 +11,32 @@ import (
 	"crypto/rsa"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"strconv"
 	"time"
 )
 
 // ExternalHSMSigner implements crypto.Signer by delegating to an external HSM
 type ExternalHSMSigner struct {
+	ctx       context.Context // Bounds the HSM calls; crypto.Signer has no context of its own
 	publicKey crypto.PublicKey
 	executor  *ExternalCommandExecutor
 	config    *VoucherSigningConfig
+	session   *HSMSession
 	stationID string
 }
 
 // NewExternalHSMSigner creates a new external HSM signer
-func NewExternalHSMSigner(publicKey crypto.PublicKey, executor *ExternalCommandExecutor, config *VoucherSigningConfig, stationID string) *ExternalHSMSigner {
+func NewExternalHSMSigner(ctx context.Context, publicKey crypto.PublicKey, executor *ExternalCommandExecutor, config *VoucherSigningConfig, session *HSMSession, stationID string) *ExternalHSMSigner {
 	return &ExternalHSMSigner{
+		ctx:       ctx,
 		publicKey: publicKey,
 		executor:  executor,
 		config:    config,
+		session:   session,
 		stationID: stationID,
 	}
 }
@@ -40,17 +46,52 @@ func (s *ExternalHSMSigner) Public() crypto.PublicKey {
 	return s.publicKey
 }
 
-// Sign implements crypto.Signer by delegating to external HSM
+// Sign implements crypto.Signer by delegating to external HSM. When the HSM
+// reports an invalid session or key handle, or can't be reached, the signer
+// asks it to reopen the session and retries with backoff.
 func (s *ExternalHSMSigner) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
-	// Generate unique request ID for tracing
-	requestID := fmt.Sprintf("req-%d-%d", time.Now().UnixNano(), time.Now().UnixNano()/1000)
-
 	// Debug: Check if publicKey is nil
 	if s.publicKey == nil {
 		return nil, fmt.Errorf("external signer has nil public key - this should not happen")
 	}
 	fmt.Printf("🔧 DEBUG: External HSM signer called with key type: %T\n", s.publicKey)
 
+	attempts := s.session.attempts()
+	for attempt := 0; ; attempt++ {
+		handle, reopen := s.session.current()
+		signature, response, err := s.signOnce(digest, opts, handle, reopen)
+		if err == nil {
+			if err := checkHSMPublicKey(response.PublicKey, s.publicKey); err != nil {
+				s.session.invalidate()
+				return nil, fmt.Errorf("%w: %w", ErrSignerUnavailable, err)
+			}
+			s.session.opened(response.KeyHandle)
+			return signature, nil
+		}
+		if !errors.Is(err, ErrHSMSessionInvalid) && !errors.Is(err, ErrSignerUnavailable) {
+			return nil, err
+		}
+		s.session.invalidate()
+		if attempt == attempts {
+			return nil, fmt.Errorf("%w: HSM did not recover after %d reconnect attempts: %w", ErrSignerUnavailable, attempts, err)
+		}
+		fmt.Printf("🔌 HSM session lost (%v); reconnecting (attempt %d/%d)\n", err, attempt+1, attempts)
+		if err := s.session.wait(s.ctx, attempt+1); err != nil {
+			return nil, fmt.Errorf("%w: HSM reconnect abandoned: %w", ErrSignerUnavailable, err)
+		}
+	}
+}
+
+// signOnce sends one signing request to the HSM, with the key handle to use
+// and whether the session must be opened again first
+func (s *ExternalHSMSigner) signOnce(digest []byte, opts crypto.SignerOpts, handle string, reopen bool) ([]byte, *HSMSigningResponse, error) {
+	// Generate unique request ID for tracing
+	requestID := fmt.Sprintf("req-%d-%d", time.Now().UnixNano(), time.Now().UnixNano()/1000)
+
+	if err := faultInjector.Inject(s.ctx, FaultStageSigning); err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrSignerUnavailable, err)
+	}
+
 	// Create signing request for HSM
 	hashFunc := "unknown"
 	if opts != nil {
@@ -66,28 +107,30 @@ func (s *ExternalHSMSigner) Sign(rand io.Reader, digest []byte, opts crypto.Sign
 			"hash":     hashFunc,
 			"key_type": keyTypeToString(s.publicKey),
 		},
+		KeyHandle: handle,
+		Reopen:    reopen,
 	}
 
 	// Marshal request to JSON
 	requestData, err := json.Marshal(request)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal HSM signing request: %w", err)
+		return nil, nil, fmt.Errorf("failed to marshal HSM signing request: %w", err)
 	}
 
 	// Write request to temporary file
-	requestFile, err := os.CreateTemp("", "hsm-signing-request-*.json")
+	requestFile, err := createSessionTemp(s.ctx, "hsm-signing-request-*.json")
 	if err != nil {
-		return nil, fmt.Errorf("failed to create temp request file: %w", err)
+		return nil, nil, fmt.Errorf("failed to create temp request file: %w", err)
 	}
 	defer func() {
 		_ = os.Remove(requestFile.Name())
 	}()
 
 	if _, err := requestFile.Write(requestData); err != nil {
-		return nil, fmt.Errorf("failed to write request file: %w", err)
+		return nil, nil, fmt.Errorf("failed to write request file: %w", err)
 	}
 	if err := requestFile.Close(); err != nil {
-		return nil, fmt.Errorf("failed to close request file: %w", err)
+		return nil, nil, fmt.Errorf("failed to close request file: %w", err)
 	}
 
 	// Call external HSM
@@ -95,34 +138,39 @@ func (s *ExternalHSMSigner) Sign(rand io.Reader, digest []byte, opts crypto.Sign
 		"requestfile": requestFile.Name(),
 		"requestid":   requestID,
 		"station":     s.stationID,
+		"keyhandle":   handle,
+		"reopen":      strconv.FormatBool(reopen),
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), s.config.ExternalTimeout)
+	ctx, cancel := context.WithTimeout(s.ctx, s.config.ExternalTimeout)
 	defer cancel()
 
 	output, err := s.executor.Execute(ctx, variables)
 	if err != nil {
-		return nil, fmt.Errorf("HSM signing failed: %w", err)
+		return nil, nil, fmt.Errorf("%w: HSM signing failed: %w", ErrSignerUnavailable, err)
 	}
 
 	// Parse HSM response
 	var response HSMSigningResponse
 	if err := json.Unmarshal([]byte(output), &response); err != nil {
-		return nil, fmt.Errorf("failed to parse HSM response: %w", err)
+		return nil, nil, fmt.Errorf("failed to parse HSM response: %w", err)
 	}
 
+	if isHSMSessionError(response.ErrorCode, response.Error) {
+		return nil, nil, fmt.Errorf("%w: %s %s", ErrHSMSessionInvalid, response.ErrorCode, response.Error)
+	}
 	if response.Error != "" {
-		return nil, fmt.Errorf("HSM signing error: %s", response.Error)
+		return nil, nil, fmt.Errorf("HSM signing error: %s", response.Error)
 	}
 
 	// Decode signature from base64
 	signature, err := base64.StdEncoding.DecodeString(response.Signature)
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode HSM signature: %w", err)
+		return nil, nil, fmt.Errorf("failed to decode HSM signature: %w", err)
 	}
 
 	fmt.Printf("✅ HSM signed digest: %s (%d bytes)\n", requestID, len(signature))
-	return signature, nil
+	return signature, &response, nil
 }
 
 // HSMSigningRequest represents a request to external HSM for signing
@@ -132,6 +180,8 @@ type HSMSigningRequest struct {
 	Timestamp            time.Time              `json:"timestamp"`
 	ManufacturingStation string                 `json:"manufacturing_station"`
 	SigningOptions       map[string]interface{} `json:"signing_options"`
+	KeyHandle            string                 `json:"key_handle,omitempty"` // Handle from the last signature; empty = look the key up
+	Reopen               bool                   `json:"reopen,omitempty"`     // The last session was invalidated; log in again first
 }
 
 // HSMSigningResponse represents a response from external HSM
@@ -140,6 +190,9 @@ type HSMSigningResponse struct {
 	RequestID string                 `json:"request_id"`
 	HSMInfo   map[string]interface{} `json:"hsm_info"`
 	Error     string                 `json:"error"`
+	ErrorCode string                 `json:"error_code"` // e.g. "session_invalid" or a PKCS#11 CKR_ name
+	KeyHandle string                 `json:"key_handle"` // Handle to reuse for the next signature
+	PublicKey string                 `json:"public_key"` // PEM public key of the handle, checked after a reopen
 }
 
 // keyTypeToString converts a public key to a string representation
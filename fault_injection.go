@@ -0,0 +1,101 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// Pipeline stages faults can be injected into
+const (
+	FaultStageDIDResolution = "did_resolution" // DIDResolver.ResolveDIDKey
+	FaultStageOwnerKey      = "owner_key"      // Dynamic owner key callback
+	FaultStageOVEExtraData  = "ove_extra_data" // OVEExtra data callback
+	FaultStageSigning       = "signing"        // External HSM signing
+	FaultStageUpload        = "upload"         // HTTP and batch voucher upload
+)
+
+var faultStages = []string{FaultStageDIDResolution, FaultStageOwnerKey, FaultStageOVEExtraData, FaultStageSigning, FaultStageUpload}
+
+// ErrInjectedFault marks failures produced by the fault injector
+var ErrInjectedFault = errors.New("injected fault")
+
+// faultInjector is set at startup when fault_injection is enabled. It is a
+// global so the hooks reach the resolver, signer and uploaders without
+// threading a test-only dependency through every constructor.
+var faultInjector *FaultInjector
+
+// FaultInjector delays and fails pipeline stages on purpose, so retry, queueing
+// and alerting can be exercised before they are needed in production. Never
+// enable it on a production station.
+type FaultInjector struct {
+	rules map[string]FaultRule
+}
+
+// NewFaultInjector creates the fault injector, or returns nil if it is disabled
+func NewFaultInjector(config *FaultInjectionConfig) (*FaultInjector, error) {
+	if !config.Enabled {
+		return nil, nil
+	}
+	rules := make(map[string]FaultRule, len(config.Faults))
+	for _, rule := range config.Faults {
+		if !slices.Contains(faultStages, rule.Stage) {
+			return nil, fmt.Errorf("fault_injection: unknown stage %q (want one of %v)", rule.Stage, faultStages)
+		}
+		if rule.Rate < 0 || rule.Rate > 100 {
+			return nil, fmt.Errorf("fault_injection: stage %s: rate must be between 0 and 100", rule.Stage)
+		}
+		if _, dup := rules[rule.Stage]; dup {
+			return nil, fmt.Errorf("fault_injection: stage %s is listed twice", rule.Stage)
+		}
+		rules[rule.Stage] = rule
+	}
+	for _, rule := range rules {
+		fmt.Printf("🧪 FAULT INJECTION ENABLED: %s fails %.0f%% of the time, delay %s\n", rule.Stage, rule.Rate, rule.Delay)
+	}
+	return &FaultInjector{rules: rules}, nil
+}
+
+// Inject applies the stage's configured delay, then fails it at the configured rate
+func (f *FaultInjector) Inject(ctx context.Context, stage string) error {
+	if f == nil {
+		return nil
+	}
+	rule, ok := f.rules[stage]
+	if !ok {
+		return nil
+	}
+
+	if rule.Delay > 0 {
+		fmt.Printf("🧪 Delaying %s by %s\n", stage, rule.Delay)
+		select {
+		case <-time.After(rule.Delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if rule.Rate <= 0 || rand.Float64()*100 >= rule.Rate {
+		return nil
+	}
+	fmt.Printf("🧪 Injecting fault into %s\n", stage)
+	if stage == FaultStageUpload {
+		status := rule.Status
+		if status == 0 {
+			status = http.StatusInternalServerError
+		}
+		return fmt.Errorf("%w: voucher recipient returned HTTP %d: %w", ErrUploadRejected, status, ErrInjectedFault)
+	}
+	if rule.Message != "" {
+		return fmt.Errorf("%s: %w", rule.Message, ErrInjectedFault)
+	}
+	return fmt.Errorf("%s: %w", stage, ErrInjectedFault)
+}
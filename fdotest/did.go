@@ -0,0 +1,70 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package fdotest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// Fixture DID documents, one per key format
+const (
+	DIDP256      = "owner-p256"      // EC P-256 publicKeyJwk
+	DIDP384      = "owner-p384"      // EC P-384 publicKeyJwk
+	DIDRSA       = "owner-rsa"       // RSA publicKeyJwk
+	DIDX5C       = "owner-x5c"       // P-384 publicKeyJwk with an x5c certificate chain
+	DIDMultibase = "owner-multibase" // owner-p256 as a publicKeyMultibase Multikey
+	DIDNoFDO     = "owner-no-fdo"    // No fido-device-onboarding member, so no voucherRecipientURL
+)
+
+// DIDNames lists the fixture DID documents
+func DIDNames() []string {
+	return []string{DIDP256, DIDP384, DIDRSA, DIDX5C, DIDMultibase, DIDNoFDO}
+}
+
+// DIDDocument returns a fixture DID document by name
+func DIDDocument(name string) ([]byte, error) {
+	data, err := files.ReadFile(path.Join("testdata", "did", name+".json"))
+	if err != nil {
+		return nil, fmt.Errorf("no fixture DID document %q", name)
+	}
+	return data, nil
+}
+
+// DIDHandler serves the fixture DID documents where the station fetches
+// did:web documents: did:web:owner.example.com:p256 from
+// /.well-known/did.json/p256. Behind an httptest TLS server, a DID names the
+// server with its host and port in place of owner.example.com.
+func DIDHandler() http.Handler {
+	docs := map[string][]byte{}
+	entries, _ := fs.ReadDir(files, "testdata/did")
+	for _, entry := range entries {
+		data, err := files.ReadFile(path.Join("testdata", "did", entry.Name()))
+		if err != nil {
+			continue
+		}
+		var doc struct {
+			ID string `json:"id"`
+		}
+		if json.Unmarshal(data, &doc) != nil {
+			continue
+		}
+		parts := strings.Split(strings.TrimPrefix(doc.ID, "did:web:"), ":")
+		docs["/.well-known/did.json/"+strings.Join(parts[1:], ":")] = data
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := docs[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/did+json")
+		w.Write(data)
+	})
+}
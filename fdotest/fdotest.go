@@ -0,0 +1,143 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+// Package fdotest provides deterministic fixtures for unit testing hooks and
+// external services against the manufacturing station: fixed manufacturer and
+// owner keys, ownership vouchers built from them, DID documents in each key
+// format the station reads, and the outputs the station is known to produce
+// for them. Everything is read from the testdata directory of this package,
+// which is embedded, so the fixtures are the same on every machine and in
+// every run.
+//
+// The keys are test keys. They are published with the source and must never
+// be trusted outside of tests.
+package fdotest
+
+import (
+	"crypto"
+	"crypto/x509"
+	"embed"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"path"
+)
+
+//go:embed testdata
+var files embed.FS
+
+// Fixture keys
+const (
+	ManufacturerKey = "manufacturer" // P-384, the key every fixture voucher is made with
+	OwnerP256       = "owner-p256"   // P-256 owner key
+	OwnerP384       = "owner-p384"   // P-384 owner key
+	OwnerRSA        = "owner-rsa"    // RSA-2048 owner key
+	OwnerChain      = "owner-chain"  // OwnerP384 with a two-certificate chain
+)
+
+// Expected holds the known-good outputs for the fixtures, from testdata/expected.json
+type Expected struct {
+	Voucher ExpectedVoucher        `json:"voucher"`
+	DIDs    map[string]ExpectedDID `json:"dids"` // By DID document name
+}
+
+// ExpectedVoucher describes the fixture vouchers
+type ExpectedVoucher struct {
+	GUID                  string            `json:"guid"` // Lowercase hex, as in the station's logs and file names
+	DeviceInfo            string            `json:"device_info"`
+	ManufacturerKey       string            `json:"manufacturer_key"`
+	ManufacturerKeySHA256 string            `json:"manufacturer_key_sha256"`
+	OwnerKeySHA256        map[string]string `json:"owner_key_sha256"` // Of a voucher signed over to each owner
+}
+
+// ExpectedDID describes what resolving a fixture DID document yields
+type ExpectedDID struct {
+	DID                 string `json:"did"`
+	Key                 string `json:"key,omitempty"`        // e.g. "P-384" or "RSA-2048"
+	KeySHA256           string `json:"key_sha256,omitempty"` // SHA-256 of the PKIX key (of the leaf, for a chain)
+	ChainLength         int    `json:"chain_length,omitempty"`
+	VoucherRecipientURL string `json:"voucher_recipient_url,omitempty"`
+	Error               string `json:"error,omitempty"` // Set when the station rejects the document
+}
+
+// LoadExpected returns the known-good outputs for the fixtures
+func LoadExpected() (*Expected, error) {
+	data, err := files.ReadFile("testdata/expected.json")
+	if err != nil {
+		return nil, err
+	}
+	var expected Expected
+	if err := json.Unmarshal(data, &expected); err != nil {
+		return nil, fmt.Errorf("failed to parse expected.json: %w", err)
+	}
+	return &expected, nil
+}
+
+// Signer returns a fixture private key by name. OwnerChain returns the key of
+// the chain's leaf certificate.
+func Signer(name string) (crypto.Signer, error) {
+	if name == OwnerChain {
+		name = OwnerP384
+	}
+	data, err := files.ReadFile(path.Join("testdata", "keys", name+".pem"))
+	if err != nil {
+		return nil, fmt.Errorf("no fixture key %q", name)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("fixture key %q is not PEM", name)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse fixture key %q: %w", name, err)
+	}
+	return key.(crypto.Signer), nil
+}
+
+// PublicKey returns the public half of a fixture key; for OwnerChain it is
+// the certificate chain, leaf first, as the station passes chains around
+func PublicKey(name string) (crypto.PublicKey, error) {
+	if name == OwnerChain {
+		return Chain()
+	}
+	signer, err := Signer(name)
+	if err != nil {
+		return nil, err
+	}
+	return signer.Public(), nil
+}
+
+// Chain returns the OwnerChain certificates, leaf first
+func Chain() ([]*x509.Certificate, error) {
+	data, err := files.ReadFile("testdata/keys/owner-chain.pem")
+	if err != nil {
+		return nil, err
+	}
+	var chain []*x509.Certificate
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse owner chain: %w", err)
+		}
+		chain = append(chain, cert)
+	}
+	return chain, nil
+}
+
+// PublicKeyPEM returns a fixture public key as the owner_signover command
+// prints it: a PUBLIC KEY block, or the CERTIFICATE blocks of OwnerChain
+func PublicKeyPEM(name string) ([]byte, error) {
+	if name == OwnerChain {
+		return files.ReadFile("testdata/keys/owner-chain.pem")
+	}
+	pub, err := PublicKey(name)
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), nil
+}
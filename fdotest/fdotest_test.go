@@ -0,0 +1,166 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package fdotest
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fido-device-onboard/go-fdo"
+	"github.com/fido-device-onboard/go-fdo/cbor"
+)
+
+func keySHA256(t *testing.T, key crypto.PublicKey) string {
+	t.Helper()
+	if chain, ok := key.([]*x509.Certificate); ok {
+		key = chain[0].PublicKey
+	}
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatalf("failed to encode key: %v", err)
+	}
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:])
+}
+
+// TestExpectedKeys checks expected.json against the fixture keys
+func TestExpectedKeys(t *testing.T) {
+	expected, err := LoadExpected()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mfgKey, err := PublicKey(ManufacturerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := keySHA256(t, mfgKey); got != expected.Voucher.ManufacturerKeySHA256 {
+		t.Errorf("manufacturer key SHA-256 = %s, expected %s", got, expected.Voucher.ManufacturerKeySHA256)
+	}
+	for owner, want := range expected.Voucher.OwnerKeySHA256 {
+		key, err := PublicKey(owner)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := keySHA256(t, key); got != want {
+			t.Errorf("%s key SHA-256 = %s, expected %s", owner, got, want)
+		}
+	}
+	chain, err := Chain()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chain) != 2 || chain[0].CheckSignatureFrom(chain[1]) != nil {
+		t.Errorf("owner chain is not a leaf signed by its CA")
+	}
+}
+
+// TestDIDDocuments checks that every fixture DID document has an expected
+// outcome and is served at its did:web path
+func TestDIDDocuments(t *testing.T) {
+	expected, err := LoadExpected()
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(DIDHandler())
+	defer server.Close()
+
+	for _, name := range DIDNames() {
+		want, ok := expected.DIDs[name]
+		if !ok {
+			t.Errorf("%s: no expected outcome", name)
+			continue
+		}
+		data, err := DIDDocument(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var doc struct {
+			ID  string `json:"id"`
+			FDO *struct {
+				VoucherRecipientURL string `json:"voucherRecipientURL"`
+			} `json:"fido-device-onboarding"`
+		}
+		if err := json.Unmarshal(data, &doc); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if doc.ID != want.DID {
+			t.Errorf("%s: id = %s, expected %s", name, doc.ID, want.DID)
+		}
+		if doc.FDO != nil && doc.FDO.VoucherRecipientURL != want.VoucherRecipientURL && want.Error == "" {
+			t.Errorf("%s: voucherRecipientURL = %s, expected %s", name, doc.FDO.VoucherRecipientURL, want.VoucherRecipientURL)
+		}
+
+		path := want.DID[len("did:web:owner.example.com:"):]
+		resp, err := server.Client().Get(fmt.Sprintf("%s/.well-known/did.json/%s", server.URL, path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != 200 {
+			t.Errorf("%s: served with HTTP %d", name, resp.StatusCode)
+		}
+	}
+}
+
+// TestVouchers checks the fixture vouchers against expected.json
+func TestVouchers(t *testing.T) {
+	expected, err := LoadExpected()
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := Voucher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := Voucher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, _ := VoucherCBOR(first)
+	b, _ := VoucherCBOR(second)
+	if string(a) != string(b) {
+		t.Errorf("unsigned fixture voucher is not deterministic")
+	}
+	if got := hex.EncodeToString(first.Header.Val.GUID[:]); got != expected.Voucher.GUID {
+		t.Errorf("GUID = %s, expected %s", got, expected.Voucher.GUID)
+	}
+
+	for owner, want := range expected.Voucher.OwnerKeySHA256 {
+		ov, err := SignedVoucher(owner)
+		if err != nil {
+			t.Fatalf("%s: %v", owner, err)
+		}
+		data, err := VoucherCBOR(ov)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded fdo.Voucher
+		if err := cbor.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("%s: voucher does not decode: %v", owner, err)
+		}
+		if err := decoded.VerifyEntries(); err != nil {
+			t.Errorf("%s: %v", owner, err)
+		}
+		if len(decoded.Entries) != 1 {
+			t.Fatalf("%s: %d entries, expected 1", owner, len(decoded.Entries))
+		}
+		ownerKey, err := decoded.Entries[0].Payload.Val.PublicKey.Public()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := keySHA256(t, ownerKey); got != want {
+			t.Errorf("%s: owner key SHA-256 = %s, expected %s", owner, got, want)
+		}
+	}
+	if _, err := SignedVoucher(OwnerRSA); err == nil {
+		t.Errorf("signing over to an RSA owner should fail")
+	}
+}
@@ -0,0 +1,109 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package fdotest
+
+import (
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+
+	"github.com/fido-device-onboard/go-fdo"
+	"github.com/fido-device-onboard/go-fdo/cbor"
+	"github.com/fido-device-onboard/go-fdo/cose"
+	"github.com/fido-device-onboard/go-fdo/protocol"
+)
+
+// Fixture voucher contents
+const (
+	GUID       = "0f1e2d3c4b5a69788796a5b4c3d2e1f0"
+	DeviceInfo = "fdotest-device"
+	Serial     = "FDOTEST-0001" // Serial number to pass to hooks alongside the voucher
+)
+
+// hmacSecret stands in for the device's HMAC secret
+var hmacSecret = []byte("fdotest device hmac secret")
+
+// Voucher returns the fixture voucher as it leaves DI: made with
+// ManufacturerKey, with an HMAC-SHA384 header MAC and no entries. Its header
+// is identical in every call.
+func Voucher() (*fdo.Voucher, error) {
+	mfgKey, err := Signer(ManufacturerKey)
+	if err != nil {
+		return nil, err
+	}
+	mfgPublic, err := protocol.NewPublicKey(protocol.Secp384r1KeyType, mfgKey.Public().(*ecdsa.PublicKey), false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode manufacturer key: %w", err)
+	}
+	header := fdo.VoucherHeader{
+		Version:         101,
+		RvInfo:          [][]protocol.RvInstruction{},
+		DeviceInfo:      DeviceInfo,
+		ManufacturerKey: *mfgPublic,
+	}
+	guid, _ := hex.DecodeString(GUID)
+	copy(header.GUID[:], guid)
+
+	headerBytes, err := cbor.Marshal(header)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode voucher header: %w", err)
+	}
+	mac := hmac.New(sha512.New384, hmacSecret)
+	mac.Write(headerBytes)
+
+	return &fdo.Voucher{
+		Version: 101,
+		Header:  *cbor.NewBstr(header),
+		Hmac:    protocol.Hmac{Algorithm: protocol.HmacSha384Hash, Value: mac.Sum(nil)},
+		Entries: []cose.Sign1Tag[fdo.VoucherEntryPayload, []byte]{},
+	}, nil
+}
+
+// SignedVoucher returns the fixture voucher signed over by ManufacturerKey to
+// an owner key, as the station uploads it. Only owners of the manufacturer
+// key's type can be signed over to: OwnerP384 and OwnerChain. Entry
+// signatures differ between calls; the rest of the voucher does not.
+func SignedVoucher(owner string) (*fdo.Voucher, error) {
+	if owner != OwnerP384 && owner != OwnerChain {
+		return nil, fmt.Errorf("fixture vouchers are made with a P-384 key and cannot be signed over to %q", owner)
+	}
+	ov, err := Voucher()
+	if err != nil {
+		return nil, err
+	}
+	mfgKey, err := Signer(ManufacturerKey)
+	if err != nil {
+		return nil, err
+	}
+	if owner == OwnerChain {
+		chain, err := Chain()
+		if err != nil {
+			return nil, err
+		}
+		return fdo.ExtendVoucher(ov, mfgKey, chain, nil)
+	}
+	ownerKey, err := Signer(owner)
+	if err != nil {
+		return nil, err
+	}
+	return fdo.ExtendVoucher(ov, mfgKey, ownerKey.Public().(*ecdsa.PublicKey), nil)
+}
+
+// VoucherCBOR returns a voucher encoded as the station stores it
+func VoucherCBOR(ov *fdo.Voucher) ([]byte, error) {
+	return cbor.Marshal(ov)
+}
+
+// VoucherPEM returns a voucher as the station saves it to disk and uploads it
+func VoucherPEM(ov *fdo.Voucher) ([]byte, error) {
+	data, err := cbor.Marshal(ov)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode voucher: %w", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "OWNERSHIP VOUCHER", Bytes: data}), nil
+}
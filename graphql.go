@@ -0,0 +1,919 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// The GraphQL endpoint is a read-only view of the station database for
+// reporting tools that want nested data in one round trip, e.g.
+//
+//	{ vouchers(lot: "L-42") { items { serial batch { status } upload { status receipt_id }
+//	    owner { customer destinations { items { name breaker_state } } } } next_cursor } }
+//
+// It implements the query subset of GraphQL that such reports need: named or
+// anonymous queries, variables, aliases, arguments and nested selections.
+// Fragments, directives, mutations and introspection are not supported. Every
+// list is a connection { items next_cursor } that takes the same limit, sort,
+// cursor and filter arguments as the matching REST list endpoint.
+const maxGraphQLDepth = 10
+
+// maxGraphQLRequest bounds the body of POST /api/graphql
+const maxGraphQLRequest = 1024 * 1024
+
+// graphqlRequest is the body of POST /api/graphql
+type graphqlRequest struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+}
+
+// graphqlResponse is the {data, errors} response of a GraphQL query
+type graphqlResponse struct {
+	Data   any            `json:"data,omitempty"`
+	Errors []graphqlError `json:"errors,omitempty"`
+}
+
+type graphqlError struct {
+	Message string `json:"message"`
+	Path    []any  `json:"path,omitempty"`
+}
+
+// GraphQLService answers read-only GraphQL queries over vouchers, batches,
+// audit events, upload receipts and upload destinations
+type GraphQLService struct {
+	query *gqlType
+}
+
+// gqlType is a GraphQL object type. Scalar fields are read from the JSON
+// encoding of the Go value; edges resolve to other objects.
+type gqlType struct {
+	name    string
+	scalars map[string]bool
+	edges   map[string]*gqlEdge
+}
+
+// gqlEdge is an object-valued field of a type
+type gqlEdge struct {
+	typ     *gqlType
+	args    []string
+	resolve func(ctx context.Context, parent any, args url.Values) (any, error)
+}
+
+// gqlConnection is one page of a list field
+type gqlConnection struct {
+	Items      any    `json:"-"`
+	NextCursor string `json:"next_cursor"`
+}
+
+// gqlOwner is the customer a voucher was built for
+type gqlOwner struct {
+	Customer string `json:"customer"`
+}
+
+// NewGraphQLService creates the GraphQL schema over the station's stores
+func NewGraphQLService(batches *BatchService, auditLog *AuditLog, receipts *UploadReceiptStore, destinations *UploadDestinationCatalog) *GraphQLService {
+	voucher := newGQLType("Voucher", BatchVoucher{})
+	batch := newGQLType("Batch", Batch{})
+	event := newGQLType("AuditEvent", AuditEvent{})
+	receipt := newGQLType("UploadReceipt", UploadReceipt{})
+	destination := newGQLType("UploadDestination", UploadDestination{})
+	owner := newGQLType("Owner", gqlOwner{})
+	query := newGQLType("Query", nil)
+
+	vouchers := func(ctx context.Context, args url.Values, scope func(q *listQuery)) (any, error) {
+		q, err := parseListParams(args, &voucherListSpec)
+		if err != nil {
+			return nil, err
+		}
+		if scope != nil {
+			scope(q)
+		}
+		items, next, err := batches.VoucherPage(ctx, q)
+		if err != nil {
+			return nil, err
+		}
+		return &gqlConnection{Items: items, NextCursor: next}, nil
+	}
+	voucherByGUID := func(ctx context.Context, guid string) (any, error) {
+		if guid == "" {
+			return nil, nil
+		}
+		q, err := parseListParams(url.Values{"guid": {guid}, "limit": {"1"}}, &voucherListSpec)
+		if err != nil {
+			return nil, err
+		}
+		items, _, err := batches.VoucherPage(ctx, q)
+		if err != nil || len(items) == 0 {
+			return nil, err
+		}
+		return &items[0], nil
+	}
+	auditEvents := func(ctx context.Context, args url.Values, scope func(q *listQuery)) (any, error) {
+		q, err := parseListParams(args, &auditListSpec)
+		if err != nil {
+			return nil, err
+		}
+		if scope != nil {
+			scope(q)
+		}
+		items, next, err := auditLog.List(ctx, q)
+		if err != nil {
+			return nil, err
+		}
+		return &gqlConnection{Items: items, NextCursor: next}, nil
+	}
+	uploads := func(ctx context.Context, args url.Values, scope func(q *listQuery)) (any, error) {
+		q, err := parseListParams(args, &uploadListSpec)
+		if err != nil {
+			return nil, err
+		}
+		if scope != nil {
+			scope(q)
+		}
+		items, next, err := receipts.Page(ctx, q)
+		if err != nil {
+			return nil, err
+		}
+		return &gqlConnection{Items: items, NextCursor: next}, nil
+	}
+	destinationList := func(ctx context.Context, args url.Values, scope func(q *listQuery)) (any, error) {
+		q, err := parseListParams(args, &destinationListSpec)
+		if err != nil {
+			return nil, err
+		}
+		if scope != nil {
+			scope(q)
+		}
+		items, next, err := destinations.Page(ctx, q)
+		if err != nil {
+			return nil, err
+		}
+		return &gqlConnection{Items: items, NextCursor: next}, nil
+	}
+
+	query.edges = map[string]*gqlEdge{
+		"vouchers": {typ: gqlConnectionType(voucher), args: gqlListArgs(&voucherListSpec),
+			resolve: func(ctx context.Context, _ any, args url.Values) (any, error) {
+				return vouchers(ctx, args, nil)
+			}},
+		"voucher": {typ: voucher, args: []string{"guid"},
+			resolve: func(ctx context.Context, _ any, args url.Values) (any, error) {
+				return voucherByGUID(ctx, args.Get("guid"))
+			}},
+		"batches": {typ: gqlConnectionType(batch), args: gqlListArgs(&batchListSpec),
+			resolve: func(ctx context.Context, _ any, args url.Values) (any, error) {
+				q, err := parseListParams(args, &batchListSpec)
+				if err != nil {
+					return nil, err
+				}
+				items, next, err := batches.Page(ctx, q)
+				if err != nil {
+					return nil, err
+				}
+				return &gqlConnection{Items: items, NextCursor: next}, nil
+			}},
+		"batch": {typ: batch, args: []string{"id"},
+			resolve: func(ctx context.Context, _ any, args url.Values) (any, error) {
+				return batches.Get(ctx, args.Get("id"))
+			}},
+		"audit_events": {typ: gqlConnectionType(event), args: gqlListArgs(&auditListSpec),
+			resolve: func(ctx context.Context, _ any, args url.Values) (any, error) {
+				return auditEvents(ctx, args, nil)
+			}},
+		"uploads": {typ: gqlConnectionType(receipt), args: gqlListArgs(&uploadListSpec),
+			resolve: func(ctx context.Context, _ any, args url.Values) (any, error) {
+				return uploads(ctx, args, nil)
+			}},
+		"upload": {typ: receipt, args: []string{"guid"},
+			resolve: func(ctx context.Context, _ any, args url.Values) (any, error) {
+				return receipts.Get(ctx, args.Get("guid"))
+			}},
+		"destinations": {typ: gqlConnectionType(destination), args: gqlListArgs(&destinationListSpec),
+			resolve: func(ctx context.Context, _ any, args url.Values) (any, error) {
+				return destinationList(ctx, args, nil)
+			}},
+		"destination": {typ: destination, args: []string{"name"},
+			resolve: func(ctx context.Context, _ any, args url.Values) (any, error) {
+				if destinations == nil {
+					return nil, nil
+				}
+				return destinations.Get(ctx, args.Get("name"))
+			}},
+	}
+
+	voucher.edges = map[string]*gqlEdge{
+		"batch": {typ: batch, resolve: func(ctx context.Context, parent any, _ url.Values) (any, error) {
+			return batches.Get(ctx, parent.(*BatchVoucher).BatchID)
+		}},
+		"upload": {typ: receipt, resolve: func(ctx context.Context, parent any, _ url.Values) (any, error) {
+			return receipts.Get(ctx, parent.(*BatchVoucher).GUID)
+		}},
+		"audit_events": {typ: gqlConnectionType(event), args: gqlListArgs(&auditListSpec),
+			resolve: func(ctx context.Context, parent any, args url.Values) (any, error) {
+				return auditEvents(ctx, args, func(q *listQuery) { q.filter("guid", parent.(*BatchVoucher).GUID) })
+			}},
+		"owner": {typ: owner, resolve: func(_ context.Context, parent any, _ url.Values) (any, error) {
+			if customer := parent.(*BatchVoucher).Customer; customer != "" {
+				return &gqlOwner{Customer: customer}, nil
+			}
+			return nil, nil
+		}},
+	}
+	owner.edges = map[string]*gqlEdge{
+		"destinations": {typ: gqlConnectionType(destination), args: gqlListArgs(&destinationListSpec),
+			resolve: func(ctx context.Context, parent any, args url.Values) (any, error) {
+				return destinationList(ctx, args, func(q *listQuery) { q.filter("owner", parent.(*gqlOwner).Customer) })
+			}},
+		"vouchers": {typ: gqlConnectionType(voucher), args: gqlListArgs(&voucherListSpec),
+			resolve: func(ctx context.Context, parent any, args url.Values) (any, error) {
+				return vouchers(ctx, args, func(q *listQuery) { q.filter("v.customer", parent.(*gqlOwner).Customer) })
+			}},
+	}
+	batch.edges = map[string]*gqlEdge{
+		"vouchers": {typ: gqlConnectionType(voucher), args: gqlListArgs(&voucherListSpec),
+			resolve: func(ctx context.Context, parent any, args url.Values) (any, error) {
+				return vouchers(ctx, args, func(q *listQuery) { q.filter("v.batch_id", parent.(*Batch).ID) })
+			}},
+	}
+	event.edges = map[string]*gqlEdge{
+		"voucher": {typ: voucher, resolve: func(ctx context.Context, parent any, _ url.Values) (any, error) {
+			return voucherByGUID(ctx, parent.(*AuditEvent).GUID)
+		}},
+	}
+	receipt.edges = map[string]*gqlEdge{
+		"voucher": {typ: voucher, resolve: func(ctx context.Context, parent any, _ url.Values) (any, error) {
+			return voucherByGUID(ctx, parent.(*UploadReceipt).GUID)
+		}},
+		"destination": {typ: destination, resolve: func(ctx context.Context, parent any, _ url.Values) (any, error) {
+			if destinations == nil {
+				return nil, nil
+			}
+			return destinations.getWhere(ctx, "url", parent.(*UploadReceipt).RecipientURL)
+		}},
+	}
+	destination.edges = map[string]*gqlEdge{
+		"uploads": {typ: gqlConnectionType(receipt), args: gqlListArgs(&uploadListSpec),
+			resolve: func(ctx context.Context, parent any, args url.Values) (any, error) {
+				return uploads(ctx, args, func(q *listQuery) { q.filter("recipient_url", parent.(*UploadDestination).URL) })
+			}},
+	}
+
+	return &GraphQLService{query: query}
+}
+
+// newGQLType creates an object type whose scalar fields are the JSON fields of sample
+func newGQLType(name string, sample any) *gqlType {
+	t := &gqlType{name: name, scalars: map[string]bool{}, edges: map[string]*gqlEdge{}}
+	if sample == nil {
+		return t
+	}
+	st := reflect.TypeOf(sample)
+	for i := range st.NumField() {
+		tag, _, _ := strings.Cut(st.Field(i).Tag.Get("json"), ",")
+		if tag != "" && tag != "-" {
+			t.scalars[tag] = true
+		}
+	}
+	return t
+}
+
+// gqlConnectionType creates the connection type of a list of item
+func gqlConnectionType(item *gqlType) *gqlType {
+	t := newGQLType(item.name+"Connection", gqlConnection{})
+	t.edges["items"] = &gqlEdge{typ: item, resolve: func(_ context.Context, parent any, _ url.Values) (any, error) {
+		return parent.(*gqlConnection).Items, nil
+	}}
+	return t
+}
+
+// gqlListArgs returns the arguments of a list field: the REST list parameters of spec
+func gqlListArgs(spec *listSpec) []string {
+	args := []string{"limit", "sort", "cursor"}
+	for name := range spec.Filters {
+		args = append(args, name)
+	}
+	return args
+}
+
+// Handler serves GET and POST /api/graphql
+func (g *GraphQLService) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req graphqlRequest
+		if r.Method == http.MethodGet {
+			req.Query = r.URL.Query().Get("query")
+			req.OperationName = r.URL.Query().Get("operationName")
+			if s := r.URL.Query().Get("variables"); s != "" {
+				if err := decodeJSONExact(strings.NewReader(s), &req.Variables); err != nil {
+					writeJSON(w, http.StatusBadRequest, graphqlResponse{Errors: []graphqlError{{Message: "invalid variables: " + err.Error()}}})
+					return
+				}
+			}
+		} else if err := decodeJSONExact(http.MaxBytesReader(w, r.Body, maxGraphQLRequest), &req); err != nil {
+			writeJSON(w, http.StatusBadRequest, graphqlResponse{Errors: []graphqlError{{Message: "invalid request body: " + err.Error()}}})
+			return
+		}
+
+		op, err := parseGraphQL(req.Query)
+		if err == nil {
+			err = g.query.validate(op.selections, 1)
+		}
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, graphqlResponse{Errors: []graphqlError{{Message: err.Error()}}})
+			return
+		}
+		vars, err := op.variables(req.Variables)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, graphqlResponse{Errors: []graphqlError{{Message: err.Error()}}})
+			return
+		}
+
+		exec := &gqlExecutor{ctx: r.Context(), vars: vars}
+		data := exec.object(g.query, nil, op.selections, nil)
+		writeJSON(w, http.StatusOK, graphqlResponse{Data: data, Errors: exec.errors})
+	})
+}
+
+// decodeJSONExact decodes JSON keeping numbers exact
+func decodeJSONExact(r io.Reader, v any) error {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+// validate checks a selection set against the type before anything is queried
+func (t *gqlType) validate(selections []*gqlField, depth int) error {
+	if depth > maxGraphQLDepth {
+		return fmt.Errorf("query is nested deeper than %d levels", maxGraphQLDepth)
+	}
+	for _, f := range selections {
+		if f.name == "__typename" || t.scalars[f.name] {
+			if len(f.args) > 0 {
+				return fmt.Errorf("field %q on type %q takes no arguments", f.name, t.name)
+			}
+			if f.selections != nil {
+				return fmt.Errorf("field %q on type %q is a scalar and cannot have a selection", f.name, t.name)
+			}
+			continue
+		}
+		edge, ok := t.edges[f.name]
+		if !ok {
+			return fmt.Errorf("cannot query field %q on type %q", f.name, t.name)
+		}
+		for _, arg := range f.args {
+			if !slices.Contains(edge.args, arg.name) {
+				return fmt.Errorf("unknown argument %q on field %q of type %q", arg.name, f.name, t.name)
+			}
+		}
+		if f.selections == nil {
+			return fmt.Errorf("field %q of type %q must have a selection of subfields", f.name, edge.typ.name)
+		}
+		if err := edge.typ.validate(f.selections, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// gqlExecutor runs one validated query, collecting field errors as it goes
+type gqlExecutor struct {
+	ctx    context.Context
+	vars   map[string]any
+	errors []graphqlError
+}
+
+// object resolves the selections of one object. A field that fails is null
+// in the result and its error is reported with the field's path.
+func (e *gqlExecutor) object(t *gqlType, value any, selections []*gqlField, path []any) gqlResult {
+	var fields map[string]any
+	result := gqlResult{}
+	for _, f := range selections {
+		key := f.responseKey()
+		if result.has(key) {
+			continue
+		}
+		fieldPath := append(slices.Clip(path), key)
+		switch {
+		case f.name == "__typename":
+			result = append(result, gqlEntry{key, t.name})
+		case t.scalars[f.name]:
+			if fields == nil {
+				var err error
+				if fields, err = gqlFields(value); err != nil {
+					e.fail(err, fieldPath)
+					return result
+				}
+			}
+			result = append(result, gqlEntry{key, fields[f.name]})
+		default:
+			edge := t.edges[f.name]
+			args, err := f.arguments(e.vars)
+			var child any
+			if err == nil {
+				child, err = edge.resolve(e.ctx, value, args)
+			}
+			if err != nil {
+				e.fail(err, fieldPath)
+				result = append(result, gqlEntry{key, nil})
+				continue
+			}
+			result = append(result, gqlEntry{key, e.complete(edge.typ, child, f.selections, fieldPath)})
+		}
+	}
+	return result
+}
+
+// complete resolves an edge's value, which is nil, an object or a slice of objects
+func (e *gqlExecutor) complete(t *gqlType, value any, selections []*gqlField, path []any) any {
+	v := reflect.ValueOf(value)
+	switch {
+	case value == nil, v.Kind() == reflect.Pointer && v.IsNil():
+		return nil
+	case v.Kind() == reflect.Slice:
+		items := make([]any, v.Len())
+		for i := range items {
+			item := v.Index(i)
+			if item.Kind() != reflect.Pointer {
+				item = item.Addr()
+			}
+			items[i] = e.complete(t, item.Interface(), selections, append(slices.Clip(path), i))
+		}
+		return items
+	default:
+		return e.object(t, value, selections, path)
+	}
+}
+
+func (e *gqlExecutor) fail(err error, path []any) {
+	e.errors = append(e.errors, graphqlError{Message: err.Error(), Path: path})
+}
+
+// gqlFields returns the JSON fields of a value
+func gqlFields(value any) (map[string]any, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]any
+	err = decodeJSONExact(bytes.NewReader(data), &fields)
+	return fields, err
+}
+
+// gqlResult is a JSON object that keeps the order of the query's fields
+type gqlResult []gqlEntry
+
+type gqlEntry struct {
+	key   string
+	value any
+}
+
+func (r gqlResult) has(key string) bool {
+	return slices.ContainsFunc(r, func(e gqlEntry) bool { return e.key == key })
+}
+
+// MarshalJSON encodes the fields in selection order
+func (r gqlResult) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, e := range r {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(e.key)
+		value, err := json.Marshal(e.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// gqlOperation is a parsed query
+type gqlOperation struct {
+	vars       []gqlVariable
+	selections []*gqlField
+}
+
+type gqlVariable struct {
+	name     string
+	required bool
+	def      *gqlValue
+}
+
+type gqlField struct {
+	alias      string
+	name       string
+	args       []gqlArgument
+	selections []*gqlField // nil for a scalar selection
+}
+
+type gqlArgument struct {
+	name  string
+	value gqlValue
+}
+
+// gqlValue is a literal or a reference to a variable
+type gqlValue struct {
+	variable string
+	literal  any // string, json.Number, bool or nil
+}
+
+func (f *gqlField) responseKey() string {
+	if f.alias != "" {
+		return f.alias
+	}
+	return f.name
+}
+
+// variables applies the declared defaults to the request's variables
+func (op *gqlOperation) variables(provided map[string]any) (map[string]any, error) {
+	vars := map[string]any{}
+	for _, v := range op.vars {
+		value, ok := provided[v.name]
+		switch {
+		case ok:
+		case v.def != nil:
+			value = v.def.literal
+		case v.required:
+			return nil, fmt.Errorf("variable $%s is required", v.name)
+		}
+		if value == nil && v.required {
+			return nil, fmt.Errorf("variable $%s must not be null", v.name)
+		}
+		vars[v.name] = value
+	}
+	return vars, nil
+}
+
+// arguments returns the field's arguments as list parameters. Null arguments are left out.
+func (f *gqlField) arguments(vars map[string]any) (url.Values, error) {
+	args := url.Values{}
+	for _, arg := range f.args {
+		value := arg.value.literal
+		if arg.value.variable != "" {
+			var ok bool
+			if value, ok = vars[arg.value.variable]; !ok {
+				return nil, fmt.Errorf("variable $%s is not defined", arg.value.variable)
+			}
+		}
+		switch v := value.(type) {
+		case nil:
+		case string:
+			args.Set(arg.name, v)
+		case json.Number:
+			args.Set(arg.name, v.String())
+		case bool:
+			args.Set(arg.name, strconv.FormatBool(v))
+		default:
+			return nil, fmt.Errorf("argument %q must be a string, number or boolean", arg.name)
+		}
+	}
+	return args, nil
+}
+
+// parseGraphQL parses a document holding a single query operation
+func parseGraphQL(src string) (*gqlOperation, error) {
+	tokens, err := tokenizeGraphQL(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &gqlParser{tokens: tokens}
+	op := &gqlOperation{}
+
+	if p.peek().kind == "name" {
+		switch keyword := p.next().text; keyword {
+		case "query":
+		case "mutation", "subscription":
+			return nil, fmt.Errorf("%s operations are not supported; the GraphQL endpoint is read-only", keyword)
+		case "fragment":
+			return nil, fmt.Errorf("fragments are not supported")
+		default:
+			return nil, fmt.Errorf("unexpected %q at start of document", keyword)
+		}
+		if p.peek().kind == "name" {
+			p.next()
+		}
+		if p.peek().kind == "(" {
+			if op.vars, err = p.variableDefinitions(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if op.selections, err = p.selectionSet(); err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != "" {
+		return nil, fmt.Errorf("only one operation per document is supported (found %q after the query)", t.text)
+	}
+	if err := op.checkVariables(op.selections); err != nil {
+		return nil, err
+	}
+	return op, nil
+}
+
+// checkVariables reports a variable used in the selections but not declared by the operation
+func (op *gqlOperation) checkVariables(selections []*gqlField) error {
+	for _, f := range selections {
+		for _, arg := range f.args {
+			if name := arg.value.variable; name != "" &&
+				!slices.ContainsFunc(op.vars, func(v gqlVariable) bool { return v.name == name }) {
+				return fmt.Errorf("variable $%s is not defined", name)
+			}
+		}
+		if err := op.checkVariables(f.selections); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type gqlToken struct {
+	kind string // "name", "string", "number", a punctuator, or "" at the end
+	text string
+}
+
+// tokenizeGraphQL splits a document into tokens, dropping whitespace, commas and comments
+func tokenizeGraphQL(src string) ([]gqlToken, error) {
+	var tokens []gqlToken
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			i++
+		case strings.HasPrefix(src[i:], "\ufeff"):
+			i += len("\ufeff")
+		case c == '#':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "..."):
+			tokens = append(tokens, gqlToken{kind: "...", text: "..."})
+			i += 3
+		case strings.IndexByte("{}():$!=[]@", c) >= 0:
+			tokens = append(tokens, gqlToken{kind: string(c), text: string(c)})
+			i++
+		case c == '_' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z':
+			j := i + 1
+			for j < len(src) && (src[j] == '_' || src[j] >= 'A' && src[j] <= 'Z' || src[j] >= 'a' && src[j] <= 'z' || src[j] >= '0' && src[j] <= '9') {
+				j++
+			}
+			tokens = append(tokens, gqlToken{kind: "name", text: src[i:j]})
+			i = j
+		case c == '-' || c >= '0' && c <= '9':
+			j := i + 1
+			for j < len(src) && strings.IndexByte("0123456789.eE+-", src[j]) >= 0 {
+				j++
+			}
+			if _, err := strconv.ParseFloat(src[i:j], 64); err != nil {
+				return nil, fmt.Errorf("invalid number %q", src[i:j])
+			}
+			tokens = append(tokens, gqlToken{kind: "number", text: src[i:j]})
+			i = j
+		case c == '"':
+			if strings.HasPrefix(src[i:], `"""`) {
+				return nil, fmt.Errorf("block strings are not supported")
+			}
+			j := i + 1
+			for j < len(src) && src[j] != '"' && src[j] != '\n' {
+				if src[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(src) || src[j] != '"' {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			// GraphQL string escapes are the same as JSON's
+			var s string
+			if err := json.Unmarshal([]byte(src[i:j+1]), &s); err != nil {
+				return nil, fmt.Errorf("invalid string %s", src[i:j+1])
+			}
+			tokens = append(tokens, gqlToken{kind: "string", text: s})
+			i = j + 1
+		default:
+			return nil, fmt.Errorf("unexpected character %q", c)
+		}
+	}
+	return tokens, nil
+}
+
+type gqlParser struct {
+	tokens []gqlToken
+	pos    int
+	depth  int // selection sets and list types being parsed
+}
+
+// nest enters a selection set or list type, refusing documents nested
+// deeper than maxGraphQLDepth before the recursion gets there
+func (p *gqlParser) nest() error {
+	p.depth++
+	if p.depth > maxGraphQLDepth {
+		return fmt.Errorf("query is nested deeper than %d levels", maxGraphQLDepth)
+	}
+	return nil
+}
+
+func (p *gqlParser) peek() gqlToken {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return gqlToken{}
+}
+
+func (p *gqlParser) next() gqlToken {
+	t := p.peek()
+	if p.pos < len(p.tokens) {
+		p.pos++
+	}
+	return t
+}
+
+func (p *gqlParser) expect(kind string) (gqlToken, error) {
+	t := p.next()
+	if t.kind != kind {
+		if t.kind == "" {
+			return t, fmt.Errorf("expected %q, found end of document", kind)
+		}
+		return t, fmt.Errorf("expected %q, found %q", kind, t.text)
+	}
+	return t, nil
+}
+
+// variableDefinitions parses ($name: Type = default, ...)
+func (p *gqlParser) variableDefinitions() ([]gqlVariable, error) {
+	p.next()
+	var vars []gqlVariable
+	for p.peek().kind != ")" {
+		if _, err := p.expect("$"); err != nil {
+			return nil, err
+		}
+		name, err := p.expect("name")
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		required, err := p.typeRef()
+		if err != nil {
+			return nil, err
+		}
+		v := gqlVariable{name: name.text, required: required}
+		if p.peek().kind == "=" {
+			p.next()
+			def, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			if def.variable != "" {
+				return nil, fmt.Errorf("default of $%s cannot be a variable", name.text)
+			}
+			v.def = &def
+		}
+		vars = append(vars, v)
+	}
+	p.next()
+	return vars, nil
+}
+
+// typeRef parses a variable type, reporting whether it is non-null
+func (p *gqlParser) typeRef() (bool, error) {
+	if p.peek().kind == "[" {
+		p.next()
+		if err := p.nest(); err != nil {
+			return false, err
+		}
+		if _, err := p.typeRef(); err != nil {
+			return false, err
+		}
+		if _, err := p.expect("]"); err != nil {
+			return false, err
+		}
+		p.depth--
+	} else if _, err := p.expect("name"); err != nil {
+		return false, err
+	}
+	if p.peek().kind == "!" {
+		p.next()
+		return true, nil
+	}
+	return false, nil
+}
+
+// selectionSet parses { field field ... }
+func (p *gqlParser) selectionSet() ([]*gqlField, error) {
+	if _, err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	if err := p.nest(); err != nil {
+		return nil, err
+	}
+	selections := []*gqlField{}
+	for p.peek().kind != "}" {
+		switch p.peek().kind {
+		case "...":
+			return nil, fmt.Errorf("fragments are not supported")
+		case "@":
+			return nil, fmt.Errorf("directives are not supported")
+		}
+		f, err := p.field()
+		if err != nil {
+			return nil, err
+		}
+		selections = append(selections, f)
+	}
+	p.next()
+	p.depth--
+	if len(selections) == 0 {
+		return nil, fmt.Errorf("selection set must not be empty")
+	}
+	return selections, nil
+}
+
+// field parses [alias:] name [(args)] [{selections}]
+func (p *gqlParser) field() (*gqlField, error) {
+	name, err := p.expect("name")
+	if err != nil {
+		return nil, err
+	}
+	f := &gqlField{name: name.text}
+	if p.peek().kind == ":" {
+		p.next()
+		if name, err = p.expect("name"); err != nil {
+			return nil, err
+		}
+		f.alias, f.name = f.name, name.text
+	}
+	if p.peek().kind == "(" {
+		p.next()
+		for p.peek().kind != ")" {
+			arg, err := p.expect("name")
+			if err != nil {
+				return nil, err
+			}
+			if _, err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			value, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			f.args = append(f.args, gqlArgument{name: arg.text, value: value})
+		}
+		p.next()
+	}
+	if p.peek().kind == "@" {
+		return nil, fmt.Errorf("directives are not supported")
+	}
+	if p.peek().kind == "{" {
+		if f.selections, err = p.selectionSet(); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// value parses an argument value. Enum values are taken as strings.
+func (p *gqlParser) value() (gqlValue, error) {
+	t := p.next()
+	switch t.kind {
+	case "$":
+		name, err := p.expect("name")
+		return gqlValue{variable: name.text}, err
+	case "string":
+		return gqlValue{literal: t.text}, nil
+	case "number":
+		return gqlValue{literal: json.Number(t.text)}, nil
+	case "name":
+		switch t.text {
+		case "true", "false":
+			return gqlValue{literal: t.text == "true"}, nil
+		case "null":
+			return gqlValue{}, nil
+		}
+		return gqlValue{literal: t.text}, nil
+	case "[", "{":
+		return gqlValue{}, errors.New("list and object arguments are not supported")
+	case "":
+		return gqlValue{}, errors.New("expected a value, found end of document")
+	}
+	return gqlValue{}, fmt.Errorf("expected a value, found %q", t.text)
+}
@@ -0,0 +1,322 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/reflection"
+	reflectionv1 "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// adminGRPCPackage is the protobuf package of the gRPC admin service
+const adminGRPCPackage = "fdo.station.admin.v1"
+
+// adminGRPCOperation is the REST route behind one gRPC admin method
+type adminGRPCOperation struct {
+	method string   // HTTP method, e.g. "GET"
+	path   string   // Path template, e.g. "/api/batches/{id}"
+	params []string // Path parameters, filled from request fields of the same name
+}
+
+var adminGRPCPathParam = regexp.MustCompile(`\{([^}]+)\}`)
+
+// adminGRPCOperations reads the methods of the gRPC admin service from the
+// OpenAPI document: one per REST operation, named after its operationId
+func adminGRPCOperations() (map[string]adminGRPCOperation, error) {
+	var doc struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(openAPIDocument, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse openapi.json: %w", err)
+	}
+	ops := make(map[string]adminGRPCOperation)
+	for path, methods := range doc.Paths {
+		for method, raw := range methods {
+			var op struct {
+				OperationID string `json:"operationId"`
+			}
+			if json.Unmarshal(raw, &op) != nil || op.OperationID == "" {
+				continue
+			}
+			name := strings.ToUpper(op.OperationID[:1]) + op.OperationID[1:]
+			var params []string
+			for _, match := range adminGRPCPathParam.FindAllStringSubmatch(path, -1) {
+				params = append(params, match[1])
+			}
+			ops[name] = adminGRPCOperation{method: strings.ToUpper(method), path: path, params: params}
+		}
+	}
+	return ops, nil
+}
+
+// adminGRPCFile builds the descriptor of the StationAdmin service. Every
+// method takes and returns a google.protobuf.Struct, so clients need no
+// generated code, and reflection describes the service to grpcurl and friends.
+func adminGRPCFile(names []string) (protoreflect.FileDescriptor, error) {
+	structType := "." + string((&structpb.Struct{}).ProtoReflect().Descriptor().FullName())
+	service := &descriptorpb.ServiceDescriptorProto{Name: proto.String("StationAdmin")}
+	for _, name := range names {
+		service.Method = append(service.Method, &descriptorpb.MethodDescriptorProto{
+			Name:       proto.String(name),
+			InputType:  proto.String(structType),
+			OutputType: proto.String(structType),
+		})
+	}
+	file := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("fdo/station/admin/v1/admin.proto"),
+		Package:    proto.String(adminGRPCPackage),
+		Dependency: []string{structpb.File_google_protobuf_struct_proto.Path()},
+		Service:    []*descriptorpb.ServiceDescriptorProto{service},
+		Syntax:     proto.String("proto3"),
+	}
+	return protodesc.NewFile(file, protoregistry.GlobalFiles)
+}
+
+// adminGRPCResolver finds descriptors for reflection: the StationAdmin file,
+// then everything linked into the binary
+type adminGRPCResolver struct {
+	files *protoregistry.Files
+}
+
+func (r adminGRPCResolver) FindFileByPath(path string) (protoreflect.FileDescriptor, error) {
+	if file, err := r.files.FindFileByPath(path); err == nil {
+		return file, nil
+	}
+	return protoregistry.GlobalFiles.FindFileByPath(path)
+}
+
+func (r adminGRPCResolver) FindDescriptorByName(name protoreflect.FullName) (protoreflect.Descriptor, error) {
+	if desc, err := r.files.FindDescriptorByName(name); err == nil {
+		return desc, nil
+	}
+	return protoregistry.GlobalFiles.FindDescriptorByName(name)
+}
+
+// newAdminGRPCServer serves the admin API over gRPC. Each call becomes a
+// request to handler, the admin mux, so it passes the same admin.http
+// allowlists, admin token check, customer scope and dual control as REST.
+// The admin token goes in the "authorization" metadata as "Bearer <token>".
+func newAdminGRPCServer(handler http.Handler, opts ...grpc.ServerOption) (*grpc.Server, error) {
+	ops, err := adminGRPCOperations()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(ops))
+	for name := range ops {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	file, err := adminGRPCFile(names)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build gRPC admin service: %w", err)
+	}
+	files := new(protoregistry.Files)
+	if err := files.RegisterFile(file); err != nil {
+		return nil, fmt.Errorf("failed to register gRPC admin service: %w", err)
+	}
+
+	service := file.Services().Get(0)
+	desc := grpc.ServiceDesc{
+		ServiceName: string(service.FullName()),
+		HandlerType: (*any)(nil),
+		Metadata:    file.Path(),
+	}
+	for _, name := range names {
+		op := ops[name]
+		desc.Methods = append(desc.Methods, grpc.MethodDesc{
+			MethodName: name,
+			Handler: func(_ any, ctx context.Context, dec func(any) error, _ grpc.UnaryServerInterceptor) (any, error) {
+				in := new(structpb.Struct)
+				if err := dec(in); err != nil {
+					return nil, err
+				}
+				return callAdminHandler(ctx, handler, op, in)
+			},
+		})
+	}
+
+	srv := grpc.NewServer(opts...)
+	srv.RegisterService(&desc, struct{}{})
+	options := reflection.ServerOptions{Services: srv, DescriptorResolver: adminGRPCResolver{files}}
+	reflectionv1.RegisterServerReflectionServer(srv, reflection.NewServerV1(options))
+	return srv, nil
+}
+
+// adminGRPCServer creates the gRPC server of the admin API on
+// server.admin.grpc_addr, or returns nil when it is not set
+func adminGRPCServer(config *ServerConfig, handler http.Handler) (*grpc.Server, error) {
+	admin := &config.Admin
+	if admin.GRPCAddr == "" {
+		return nil, nil
+	}
+	tlsConfig, err := listenerTLS(admin.UseTLS, admin.CertFile, admin.KeyFile, admin.ClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("server.admin: %w", err)
+	}
+	var opts []grpc.ServerOption
+	if tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+	return newAdminGRPCServer(readingConfig(handler), opts...)
+}
+
+// callAdminHandler makes the REST request of one gRPC call. Path parameters
+// come from the request fields of the same name. A lone string field "body"
+// is sent as is, for YAML and binary request bodies. Otherwise the other
+// fields are the query of a GET or DELETE, or the JSON body of a POST or PUT.
+func callAdminHandler(ctx context.Context, handler http.Handler, op adminGRPCOperation, in *structpb.Struct) (*structpb.Struct, error) {
+	fields := in.AsMap()
+	path := op.path
+	for _, param := range op.params {
+		value, ok := fields[param]
+		if !ok {
+			return nil, status.Errorf(codes.InvalidArgument, "missing %s", param)
+		}
+		path = strings.Replace(path, "{"+param+"}", url.PathEscape(adminGRPCField(value)), 1)
+		delete(fields, param)
+	}
+
+	var body io.Reader
+	if raw, ok := fields["body"].(string); ok && len(fields) == 1 {
+		body = strings.NewReader(raw)
+	} else if op.method == http.MethodPost || op.method == http.MethodPut {
+		data, err := json.Marshal(fields)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid request: %v", err)
+		}
+		body = bytes.NewReader(data)
+	} else if len(fields) > 0 {
+		query := url.Values{}
+		for key, value := range fields {
+			query.Set(key, adminGRPCField(value))
+		}
+		path += "?" + query.Encode()
+	}
+
+	r, err := http.NewRequestWithContext(ctx, op.method, path, body)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid request: %v", err)
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if auth := md.Get("authorization"); len(auth) > 0 {
+			r.Header.Set("Authorization", auth[0])
+		}
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		r.RemoteAddr = p.Addr.String()
+	}
+	w := &adminGRPCResponse{header: make(http.Header), status: http.StatusOK}
+	handler.ServeHTTP(w, r)
+	return w.result()
+}
+
+// adminGRPCField formats a request field as a path parameter or query value
+func adminGRPCField(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+}
+
+// adminGRPCResponse records the REST response of a gRPC call
+type adminGRPCResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *adminGRPCResponse) Header() http.Header         { return w.header }
+func (w *adminGRPCResponse) WriteHeader(status int)      { w.status = status }
+func (w *adminGRPCResponse) Write(b []byte) (int, error) { return w.body.Write(b) }
+
+// result turns the REST response into the gRPC reply. A JSON object is the
+// reply; other JSON, such as a list, is under "result", with the cursor of
+// the next page in "next_cursor". Text and binary bodies are under "body",
+// with their "content_type", binary ones base64 encoded.
+func (w *adminGRPCResponse) result() (*structpb.Struct, error) {
+	var value any
+	isJSON := json.Unmarshal(w.body.Bytes(), &value) == nil
+	if w.status >= http.StatusBadRequest {
+		message := strings.TrimSpace(w.body.String())
+		if object, ok := value.(map[string]any); ok {
+			if text, ok := object["error"].(string); ok {
+				message = text
+			}
+		}
+		return nil, status.Error(adminGRPCCode(w.status), message)
+	}
+	if object, ok := value.(map[string]any); ok {
+		return structpb.NewStruct(object)
+	}
+	if isJSON {
+		reply := map[string]any{"result": value}
+		if next := w.header.Get("X-Next-Cursor"); next != "" {
+			reply["next_cursor"] = next
+		}
+		return structpb.NewStruct(reply)
+	}
+	reply := map[string]any{"content_type": w.header.Get("Content-Type")}
+	if utf8.Valid(w.body.Bytes()) {
+		reply["body"] = w.body.String()
+	} else {
+		reply["body"] = base64.StdEncoding.EncodeToString(w.body.Bytes())
+		reply["encoding"] = "base64"
+	}
+	return structpb.NewStruct(reply)
+}
+
+// adminGRPCCode maps the HTTP status of a failed admin request to a gRPC code
+func adminGRPCCode(httpStatus int) codes.Code {
+	switch httpStatus {
+	case http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		return codes.Unimplemented
+	case http.StatusConflict:
+		return codes.FailedPrecondition
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	default:
+		return codes.Internal
+	}
+}
@@ -0,0 +1,112 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	reflectionv1 "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// TestAdminGRPC calls the destination handlers of the admin API over gRPC,
+// through the admin token check and the customer scope of the caller
+func TestAdminGRPC(t *testing.T) {
+	ctx := context.Background()
+	db, err := OpenStationDB(filepath.Join(t.TempDir(), "station.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	catalog := NewUploadDestinationCatalog(&VoucherConfig{}, db)
+	if err := catalog.Initialize(ctx); err != nil {
+		t.Fatal(err)
+	}
+	admin := &AdminConfig{Enabled: true, Users: []AdminUser{
+		{Name: "ops", Token: "ops-token"},
+		{Name: "acme-admin", Token: "acme-token", Customers: []string{"acme"}},
+	}}
+	mux := http.NewServeMux()
+	mux.Handle("GET /api/destinations/{name}", adminAuth(admin, catalog.GetHandler()))
+	mux.Handle("PUT /api/destinations/{name}", adminAuth(admin, catalog.PutHandler()))
+
+	srv, err := newAdminGRPCServer(mux)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() { _ = srv.Serve(lis) }()
+	defer srv.Stop()
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	call := func(token, method string, fields map[string]any) (*structpb.Struct, error) {
+		in, err := structpb.NewStruct(fields)
+		if err != nil {
+			t.Fatal(err)
+		}
+		callCtx := ctx
+		if token != "" {
+			callCtx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+		}
+		out := new(structpb.Struct)
+		return out, conn.Invoke(callCtx, "/"+adminGRPCPackage+".StationAdmin/"+method, in, out)
+	}
+	destination := map[string]any{"name": "vault", "url": "https://vault.example/vouchers"}
+	if _, err := call("", "PutDestination", destination); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("without a token: got %v, want Unauthenticated", err)
+	}
+	if _, err := call("acme-token", "PutDestination", destination); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("destination for every customer by an acme admin: got %v, want PermissionDenied", err)
+	}
+	if _, err := call("ops-token", "PutDestination", destination); err != nil {
+		t.Fatalf("put destination: %v", err)
+	}
+	got, err := call("ops-token", "GetDestination", map[string]any{"name": "vault"})
+	if err != nil {
+		t.Fatalf("get destination: %v", err)
+	}
+	if url := got.Fields["url"].GetStringValue(); url != "https://vault.example/vouchers" {
+		t.Errorf("destination url is %q", url)
+	}
+	if _, err := call("ops-token", "GetDestination", map[string]any{"name": "missing"}); status.Code(err) != codes.NotFound {
+		t.Errorf("unknown destination: got %v, want NotFound", err)
+	}
+
+	// Reflection describes the service to clients without the proto
+	stream, err := reflectionv1.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.Send(&reflectionv1.ServerReflectionRequest{
+		MessageRequest: &reflectionv1.ServerReflectionRequest_FileContainingSymbol{
+			FileContainingSymbol: adminGRPCPackage + ".StationAdmin.GetDestination",
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.GetFileDescriptorResponse() == nil {
+		t.Errorf("reflection did not find StationAdmin.GetDestination: %v", resp.GetErrorResponse())
+	}
+}
@@ -0,0 +1,475 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/fido-device-onboard/go-fdo"
+	"github.com/fido-device-onboard/go-fdo/protocol"
+	"github.com/fido-device-onboard/go-fdo/sqlite"
+)
+
+// Largest number of GUIDs one reservation request may ask for
+const maxGUIDReservation = 10000
+
+// GUID reservation states
+const (
+	GUIDReserved = "reserved" // Waiting for its device
+	GUIDAssigned = "assigned" // Given to a DI session that hasn't finished
+	GUIDUsed     = "used"     // In a persisted voucher
+	GUIDReleased = "released" // Cancelled before use
+)
+
+// ErrNoGUIDReservation is returned for a device without a reserved GUID when reservations are required
+var ErrNoGUIDReservation = errors.New("no GUID reserved for device")
+
+// GUIDReservation is a GUID handed out ahead of manufacturing, e.g. for a
+// pre-printed label
+type GUIDReservation struct {
+	GUID      string     `json:"guid"`
+	Serial    string     `json:"serial,omitempty"` // Device the GUID is bound to; unbound GUIDs are not used
+	Model     string     `json:"model,omitempty"`
+	BatchID   string     `json:"batch_id,omitempty"`
+	Status    string     `json:"status"`
+	CreatedAt time.Time  `json:"created_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+}
+
+// ReserveGUIDsRequest is the body of POST /api/guids/reserve. With serials, one
+// GUID is reserved and bound to each; otherwise count unbound GUIDs are
+// reserved, to be bound when their labels are applied.
+type ReserveGUIDsRequest struct {
+	Count   int      `json:"count"`
+	Serials []string `json:"serials"`
+	Model   string   `json:"model"`
+	BatchID string   `json:"batch_id"`
+}
+
+// BindGUIDRequest is the body of POST /api/guids/{guid}/bind
+type BindGUIDRequest struct {
+	Serial string `json:"serial"`
+}
+
+// GUIDReservations hands out GUIDs before their devices are built. A DI
+// session for a serial with a reserved GUID gets that GUID instead of a random
+// one, so a label printed in advance matches the voucher.
+type GUIDReservations struct {
+	config   *GUIDReservationConfig
+	db       *StationDB
+	batches  *BatchService
+	auditLog *AuditLog
+}
+
+// NewGUIDReservations creates the reservation service, or returns nil if it is disabled
+func NewGUIDReservations(config *GUIDReservationConfig, db *StationDB, batches *BatchService, auditLog *AuditLog) *GUIDReservations {
+	if !config.Enabled {
+		return nil
+	}
+	return &GUIDReservations{config: config, db: db, batches: batches, auditLog: auditLog}
+}
+
+// Initialize creates the guid_reservations table if it doesn't exist
+func (g *GUIDReservations) Initialize(ctx context.Context) error {
+	if g == nil {
+		return nil
+	}
+	if _, err := g.db.db.ExecContext(ctx, `
+	CREATE TABLE IF NOT EXISTS guid_reservations (
+		guid TEXT PRIMARY KEY,
+		serial TEXT NOT NULL,
+		model TEXT NOT NULL,
+		batch_id TEXT NOT NULL,
+		status TEXT NOT NULL,
+		created_at INTEGER NOT NULL,
+		used_at INTEGER
+	)`); err != nil {
+		return fmt.Errorf("failed to create guid_reservations table: %w", err)
+	}
+	if _, err := g.db.db.ExecContext(ctx,
+		`CREATE INDEX IF NOT EXISTS guid_reservations_serial ON guid_reservations (serial, status)`); err != nil {
+		return fmt.Errorf("failed to create guid_reservations index: %w", err)
+	}
+	return g.db.addColumnIfMissing(ctx, "guid_reservations", "assigned_at", "INTEGER")
+}
+
+// Reserve creates GUIDs for the devices of a request
+func (g *GUIDReservations) Reserve(ctx context.Context, req ReserveGUIDsRequest) ([]GUIDReservation, error) {
+	count := req.Count
+	if len(req.Serials) > 0 {
+		count = len(req.Serials)
+	}
+	if count <= 0 || count > maxGUIDReservation {
+		return nil, fmt.Errorf("count must be between 1 and %d", maxGUIDReservation)
+	}
+	if req.BatchID != "" {
+		batch, err := g.batches.Get(ctx, req.BatchID)
+		if err != nil {
+			return nil, err
+		}
+		if batch == nil {
+			return nil, fmt.Errorf("batch %s not found", req.BatchID)
+		}
+	}
+
+	tx, err := g.db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin reservation: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	now := time.Now()
+	reservations := make([]GUIDReservation, 0, count)
+	for i := range count {
+		var guid [16]byte
+		if _, err := rand.Read(guid[:]); err != nil {
+			return nil, fmt.Errorf("failed to generate GUID: %w", err)
+		}
+		r := GUIDReservation{GUID: hex.EncodeToString(guid[:]), Model: req.Model, BatchID: req.BatchID, Status: GUIDReserved, CreatedAt: now}
+		if len(req.Serials) > 0 {
+			r.Serial = g.normalize(req.Serials[i], req.Model)
+			if err := g.checkUnbound(ctx, tx, r.Serial); err != nil {
+				return nil, err
+			}
+		}
+		if _, err := tx.ExecContext(ctx, `
+		INSERT INTO guid_reservations (guid, serial, model, batch_id, status, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+			r.GUID, r.Serial, r.Model, r.BatchID, r.Status, now.Unix()); err != nil {
+			return nil, fmt.Errorf("failed to reserve GUID: %w", err)
+		}
+		reservations = append(reservations, r)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit reservation: %w", err)
+	}
+	g.auditLog.Record(ctx, AuditEvent{
+		Event:  "guids_reserved",
+		Model:  req.Model,
+		Detail: fmt.Sprintf("%d GUIDs reserved (batch %q, %d bound to serials)", count, req.BatchID, len(req.Serials)),
+	})
+	return reservations, nil
+}
+
+// normalize applies the serial rules devices' serials go through at DI
+func (g *GUIDReservations) normalize(serial, model string) string {
+	serial, _ = serialRules.Normalize(strings.TrimSpace(serial), model)
+	return serial
+}
+
+// checkUnbound refuses a serial that already has a GUID waiting for it
+func (g *GUIDReservations) checkUnbound(ctx context.Context, tx *sql.Tx, serial string) error {
+	if serial == "" {
+		return fmt.Errorf("empty serial")
+	}
+	var guid string
+	err := tx.QueryRowContext(ctx, `
+	SELECT guid FROM guid_reservations WHERE serial = ? AND status IN (?, ?)`, serial, GUIDReserved, GUIDAssigned).Scan(&guid)
+	if err == nil {
+		return fmt.Errorf("serial %s already has GUID %s reserved", serial, guid)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("failed to read reservations: %w", err)
+	}
+	return nil
+}
+
+// Bind binds an unbound reserved GUID to a serial
+func (g *GUIDReservations) Bind(ctx context.Context, guid, serial string) (*GUIDReservation, error) {
+	r, err := g.Get(ctx, guid)
+	if err != nil {
+		return nil, err
+	}
+	if r.Status != GUIDReserved || r.Serial != "" {
+		return nil, fmt.Errorf("GUID %s is %s and can't be bound", r.GUID, describeGUIDState(r))
+	}
+	tx, err := g.db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin binding: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	serial = g.normalize(serial, r.Model)
+	if err := g.checkUnbound(ctx, tx, serial); err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE guid_reservations SET serial = ? WHERE guid = ? AND serial = ''`, serial, r.GUID); err != nil {
+		return nil, fmt.Errorf("failed to bind GUID: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit binding: %w", err)
+	}
+	g.auditLog.Record(ctx, AuditEvent{Event: "guid_bound", Serial: serial, GUID: r.GUID, Model: r.Model})
+	return g.Get(ctx, r.GUID)
+}
+
+// Release cancels a GUID that no device has used
+func (g *GUIDReservations) Release(ctx context.Context, guid string) (*GUIDReservation, error) {
+	r, err := g.Get(ctx, guid)
+	if err != nil {
+		return nil, err
+	}
+	if r.Status != GUIDReserved {
+		return nil, fmt.Errorf("GUID %s is %s and can't be released", r.GUID, describeGUIDState(r))
+	}
+	if _, err := g.db.db.ExecContext(ctx, `UPDATE guid_reservations SET status = ? WHERE guid = ? AND status = ?`, GUIDReleased, r.GUID, GUIDReserved); err != nil {
+		return nil, fmt.Errorf("failed to release GUID: %w", err)
+	}
+	g.auditLog.Record(ctx, AuditEvent{Event: "guid_released", Serial: r.Serial, GUID: r.GUID, Model: r.Model})
+	return g.Get(ctx, r.GUID)
+}
+
+// describeGUIDState names a reservation's state for error messages
+func describeGUIDState(r *GUIDReservation) string {
+	if r.Status == GUIDReserved && r.Serial != "" {
+		return "bound to " + r.Serial
+	}
+	return r.Status
+}
+
+// Claim returns the GUID reserved for a device and marks it assigned. A
+// device retrying DI gets the GUID it was assigned before. Without a
+// reservation it returns false, or ErrNoGUIDReservation if reservations are
+// required.
+func (g *GUIDReservations) Claim(ctx context.Context, serial, model string) (protocol.GUID, bool, error) {
+	var guid protocol.GUID
+	if g == nil || serial == "" {
+		return guid, false, nil
+	}
+	var r GUIDReservation
+	err := g.db.db.QueryRowContext(ctx, `
+	SELECT guid, model, batch_id FROM guid_reservations WHERE serial = ? AND status IN (?, ?)`,
+		serial, GUIDReserved, GUIDAssigned).Scan(&r.GUID, &r.Model, &r.BatchID)
+	if errors.Is(err, sql.ErrNoRows) {
+		if g.config.Required {
+			return guid, false, fmt.Errorf("%w %s", ErrNoGUIDReservation, serialRules.Serial(serial))
+		}
+		return guid, false, nil
+	}
+	if err != nil {
+		return guid, false, fmt.Errorf("failed to read GUID reservation: %w", err)
+	}
+
+	// The GUID belongs to a model and batch when it was reserved for them
+	if r.Model != "" && r.Model != model {
+		return guid, false, fmt.Errorf("GUID %s is reserved for model %s, device reports %s", r.GUID, r.Model, model)
+	}
+	if r.BatchID != "" {
+		batch, err := g.batches.Current(ctx)
+		if err != nil {
+			return guid, false, err
+		}
+		if batch == nil || batch.ID != r.BatchID {
+			return guid, false, fmt.Errorf("GUID %s is reserved for batch %s, which is not open", r.GUID, r.BatchID)
+		}
+	}
+
+	raw, err := hex.DecodeString(r.GUID)
+	if err != nil || len(raw) != len(guid) {
+		return guid, false, fmt.Errorf("invalid reserved GUID %q", r.GUID)
+	}
+	copy(guid[:], raw)
+	if _, err := g.db.db.ExecContext(ctx, `UPDATE guid_reservations SET status = ?, assigned_at = ? WHERE guid = ?`, GUIDAssigned, time.Now().Unix(), r.GUID); err != nil {
+		return guid, false, fmt.Errorf("failed to assign GUID: %w", err)
+	}
+	fmt.Printf("🏷️  Using reserved GUID %s for %s\n", r.GUID, serialRules.Serial(serial))
+	return guid, true, nil
+}
+
+// Complete marks the reserved GUID of a persisted voucher as used; other GUIDs are ignored
+func (g *GUIDReservations) Complete(ctx context.Context, guid protocol.GUID) {
+	if g == nil {
+		return
+	}
+	if _, err := g.db.db.ExecContext(ctx, `
+	UPDATE guid_reservations SET status = ?, used_at = ? WHERE guid = ? AND status = ?`,
+		GUIDUsed, time.Now().Unix(), hex.EncodeToString(guid[:]), GUIDAssigned); err != nil {
+		fmt.Printf("⚠️  Failed to mark reserved GUID %x used: %v\n", guid[:], err)
+	}
+}
+
+// Get returns a reservation by GUID
+func (g *GUIDReservations) Get(ctx context.Context, guid string) (*GUIDReservation, error) {
+	reservations, err := g.query(ctx, ` WHERE guid = ?`, strings.ToLower(strings.ReplaceAll(guid, "-", "")))
+	if err != nil {
+		return nil, err
+	}
+	if len(reservations) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrVoucherNotFound, guid)
+	}
+	return &reservations[0], nil
+}
+
+var guidReservationListSpec = listSpec{
+	Key:         "guid",
+	Sorts:       map[string]string{"created_at": "created_at", "guid": "guid", "serial": "serial"},
+	DefaultSort: "created_at",
+	Filters:     map[string]string{"status": "status", "serial": "serial", "model": "model", "batch_id": "batch_id"},
+}
+
+// Page returns one page of reservations
+func (g *GUIDReservations) Page(ctx context.Context, q *listQuery) ([]GUIDReservation, string, error) {
+	clause, args := q.sql()
+	reservations, err := g.query(ctx, clause, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	reservations, next := listPage(q, reservations, func(r GUIDReservation, column string) any {
+		switch column {
+		case "created_at":
+			return r.CreatedAt.Unix()
+		case "serial":
+			return r.Serial
+		default:
+			return r.GUID
+		}
+	})
+	return reservations, next, nil
+}
+
+// query returns the reservations selected by clause (WHERE, ORDER BY and LIMIT)
+func (g *GUIDReservations) query(ctx context.Context, clause string, args ...any) ([]GUIDReservation, error) {
+	rows, err := g.db.db.QueryContext(ctx, `
+	SELECT guid, serial, model, batch_id, status, created_at, used_at FROM guid_reservations`+clause, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query GUID reservations: %w", err)
+	}
+	defer rows.Close()
+
+	reservations := []GUIDReservation{}
+	for rows.Next() {
+		var r GUIDReservation
+		var createdAt int64
+		var usedAt sql.NullInt64
+		if err := rows.Scan(&r.GUID, &r.Serial, &r.Model, &r.BatchID, &r.Status, &createdAt, &usedAt); err != nil {
+			return nil, fmt.Errorf("failed to read GUID reservation: %w", err)
+		}
+		r.CreatedAt = time.Unix(createdAt, 0)
+		if usedAt.Valid {
+			t := time.Unix(usedAt.Int64, 0)
+			r.UsedAt = &t
+		}
+		reservations = append(reservations, r)
+	}
+	return reservations, rows.Err()
+}
+
+// ReserveHandler serves POST /api/guids/reserve
+func (g *GUIDReservations) ReserveHandler() http.Handler {
+	return g.handler(func(w http.ResponseWriter, r *http.Request) {
+		var req ReserveGUIDsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid request: %v", err))
+			return
+		}
+		reservations, err := g.Reserve(r.Context(), req)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusCreated, reservations)
+	})
+}
+
+// ListHandler serves GET /api/guids with the list parameters of guidReservationListSpec
+func (g *GUIDReservations) ListHandler() http.Handler {
+	return g.handler(func(w http.ResponseWriter, r *http.Request) {
+		q, err := parseListQuery(r, &guidReservationListSpec)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		reservations, next, err := g.Page(r.Context(), q)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSONList(w, r, reservations, next)
+	})
+}
+
+// BindHandler serves POST /api/guids/{guid}/bind
+func (g *GUIDReservations) BindHandler() http.Handler {
+	return g.handler(func(w http.ResponseWriter, r *http.Request) {
+		var req BindGUIDRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid request: %v", err))
+			return
+		}
+		reservation, err := g.Bind(r.Context(), r.PathValue("guid"), req.Serial)
+		writeGUIDResult(w, reservation, err)
+	})
+}
+
+// ReleaseHandler serves DELETE /api/guids/{guid}
+func (g *GUIDReservations) ReleaseHandler() http.Handler {
+	return g.handler(func(w http.ResponseWriter, r *http.Request) {
+		reservation, err := g.Release(r.Context(), r.PathValue("guid"))
+		writeGUIDResult(w, reservation, err)
+	})
+}
+
+// handler answers 404 while reservations are disabled
+func (g *GUIDReservations) handler(fn http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if g == nil {
+			writeJSONError(w, http.StatusNotFound, "GUID reservations are disabled")
+			return
+		}
+		fn(w, r)
+	})
+}
+
+// writeGUIDResult writes a reservation, or the error of changing it
+func writeGUIDResult(w http.ResponseWriter, reservation *GUIDReservation, err error) {
+	switch {
+	case errors.Is(err, ErrVoucherNotFound):
+		writeJSONError(w, http.StatusNotFound, err.Error())
+	case err != nil:
+		writeJSONError(w, http.StatusConflict, err.Error())
+	default:
+		writeJSON(w, http.StatusOK, reservation)
+	}
+}
+
+// guidReservingSession gives DI sessions of devices with a reserved GUID that
+// GUID, refusing devices without one when reservations are required unless an
+// override waives it. The DI server stores the voucher header with a random GUID before it
+// sends the header to the device; the GUID is swapped in as the header is
+// stored, so the device, its HMAC and the voucher all carry the reserved one.
+type guidReservingSession struct {
+	*sqlite.DB
+	reservations *GUIDReservations
+	overrides    *PolicyOverrides
+}
+
+// SetIncompleteVoucherHeader replaces the header's GUID with the device's reserved GUID, if it has one
+func (s *guidReservingSession) SetIncompleteVoucherHeader(ctx context.Context, ovh *fdo.VoucherHeader) error {
+	if s.reservations == nil {
+		return s.DB.SetIncompleteVoucherHeader(ctx, ovh)
+	}
+	info, err := s.DeviceSelfInfo(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read device info: %w", err)
+	}
+	guid, ok, err := s.reservations.Claim(ctx, info.SerialNumber, info.DeviceInfo)
+	if errors.Is(err, ErrNoGUIDReservation) && s.overrides.Allow(ctx, OverrideGUIDReservation, info.SerialNumber, hex.EncodeToString(ovh.GUID[:]), err) {
+		err = nil
+	}
+	if err != nil {
+		return err
+	}
+	if ok {
+		ovh.GUID = guid
+	}
+	return s.DB.SetIncompleteVoucherHeader(ctx, ovh)
+}
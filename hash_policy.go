@@ -0,0 +1,148 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"errors"
+	"fmt"
+
+	"github.com/fido-device-onboard/go-fdo"
+	"github.com/fido-device-onboard/go-fdo/protocol"
+)
+
+// ErrHashPolicy is returned when a device or key does not use the configured voucher hash
+var ErrHashPolicy = errors.New("voucher hash policy violation")
+
+// VoucherHashPolicy pins the hash used for voucher header hashes and the device
+// HMAC to SHA-256 or SHA-384, and refuses keys whose strength calls for the
+// other one. A nil *VoucherHashPolicy accepts whatever the device negotiates.
+type VoucherHashPolicy struct {
+	name    string
+	hash    protocol.HashAlg
+	hmac    protocol.HashAlg
+	ecBits  int // EC curve size that pairs with the hash
+	rsaBits int // RSA modulus size that pairs with the hash
+}
+
+// NewVoucherHashPolicy creates the hash policy, returning nil if no hash algorithm
+// is configured. The configured owner and manufacturer keys are checked here so
+// that a mismatch stops the station at startup rather than failing every DI.
+func NewVoucherHashPolicy(config *VoucherConfig) (*VoucherHashPolicy, error) {
+	var policy *VoucherHashPolicy
+	switch config.HashAlgorithm {
+	case "":
+		return nil, nil
+	case "sha256":
+		policy = &VoucherHashPolicy{name: "sha256", hash: protocol.Sha256Hash, hmac: protocol.HmacSha256Hash, ecBits: 256, rsaBits: 2048}
+	case "sha384":
+		policy = &VoucherHashPolicy{name: "sha384", hash: protocol.Sha384Hash, hmac: protocol.HmacSha384Hash, ecBits: 384, rsaBits: 3072}
+	default:
+		return nil, fmt.Errorf("unsupported voucher hash algorithm %q (want sha256 or sha384)", config.HashAlgorithm)
+	}
+
+	if keyType := config.VoucherSigning.OwnerKeyType; keyType != "" {
+		if err := policy.checkKeyName("voucher_signing.owner_key_type", keyType); err != nil {
+			return nil, err
+		}
+	}
+	if config.OwnerSignover.Mode == "static" && config.OwnerSignover.StaticPublicKey != "" {
+		pub, err := parseStaticPublicKey(config.OwnerSignover.StaticPublicKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse static public key: %w", err)
+		}
+		if err := policy.CheckOwnerKey(pub); err != nil {
+			return nil, fmt.Errorf("owner_signover.static_public_key: %w", err)
+		}
+	}
+	if config.VoucherSigning.Mode == "external" && config.VoucherSigning.ManufacturerPublicKeyFile != "" {
+		mfgPubKey, err := LoadManufacturerPublicKey(config.VoucherSigning.ManufacturerPublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		if err := policy.CheckKeyType(mfgPubKey.Type); err != nil {
+			return nil, fmt.Errorf("voucher_signing.manufacturer_public_key_file: %w", err)
+		}
+	}
+	return policy, nil
+}
+
+// CheckKeyType checks the manufacturer key type a device asked for during DI
+func (p *VoucherHashPolicy) CheckKeyType(keyType protocol.KeyType) error {
+	if p == nil {
+		return nil
+	}
+	switch keyType {
+	case protocol.Secp256r1KeyType:
+		return p.checkBits(fmt.Sprint(keyType), 256)
+	case protocol.Rsa2048RestrKeyType:
+		return p.checkBits(fmt.Sprint(keyType), 2048)
+	case protocol.Secp384r1KeyType:
+		return p.checkBits(fmt.Sprint(keyType), 384)
+	case protocol.RsaPkcsKeyType, protocol.RsaPssKeyType:
+		// Manufacturer RSA keys other than RSA2048RESTR are generated at 3072 bits
+		return p.checkBits(fmt.Sprint(keyType), 3072)
+	default:
+		return fmt.Errorf("%w: unsupported key type %s", ErrHashPolicy, keyType)
+	}
+}
+
+// CheckOwnerKey checks a next-owner public key or certificate chain
+func (p *VoucherHashPolicy) CheckOwnerKey(pub crypto.PublicKey) error {
+	if p == nil {
+		return nil
+	}
+	switch key := pub.(type) {
+	case *ecdsa.PublicKey:
+		return p.checkBits("owner key", key.Curve.Params().BitSize)
+	case *rsa.PublicKey:
+		return p.checkBits("owner key", key.N.BitLen())
+	case []*x509.Certificate:
+		if len(key) == 0 {
+			return fmt.Errorf("%w: empty owner certificate chain", ErrHashPolicy)
+		}
+		return p.CheckOwnerKey(key[0].PublicKey)
+	default:
+		return fmt.Errorf("%w: unsupported owner key type %T", ErrHashPolicy, pub)
+	}
+}
+
+// CheckVoucher checks the device HMAC and certificate chain hash of a new voucher
+func (p *VoucherHashPolicy) CheckVoucher(ov *fdo.Voucher) error {
+	if p == nil {
+		return nil
+	}
+	if ov.Hmac.Algorithm != p.hmac {
+		return fmt.Errorf("%w: device HMAC uses %v, station requires %v", ErrHashPolicy, ov.Hmac.Algorithm, p.hmac)
+	}
+	if hash := ov.Header.Val.CertChainHash; hash != nil && hash.Algorithm != p.hash {
+		return fmt.Errorf("%w: device certificate chain hash uses %v, station requires %v", ErrHashPolicy, hash.Algorithm, p.hash)
+	}
+	return nil
+}
+
+// checkKeyName checks a key type as written in the config ("ec256", "rsa3072", ...)
+func (p *VoucherHashPolicy) checkKeyName(field, keyType string) error {
+	bits := map[string]int{"ec256": 256, "ec384": 384, "rsa2048": 2048, "rsa3072": 3072}[keyType]
+	if bits == 0 {
+		return fmt.Errorf("%s: unsupported key type %q", field, keyType)
+	}
+	if err := p.checkBits(keyType, bits); err != nil {
+		return fmt.Errorf("%s: %w", field, err)
+	}
+	return nil
+}
+
+// checkBits checks that a key of the given size pairs with the policy hash:
+// P-256 and RSA-2048 with SHA-256, P-384 and RSA-3072 with SHA-384
+func (p *VoucherHashPolicy) checkBits(what string, bits int) error {
+	if bits != p.ecBits && bits != p.rsaBits {
+		return fmt.Errorf("%w: %s (%d bits) does not match hash_algorithm %s", ErrHashPolicy, what, bits, p.name)
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrHSMSessionInvalid marks an HSM reply saying its session or key handle is
+// no longer valid, e.g. because the PKCS#11 or KMS backend restarted
+var ErrHSMSessionInvalid = errors.New("HSM session or key handle invalid")
+
+// HSM reconnect defaults
+const (
+	defaultHSMReconnectAttempts = 3
+	defaultHSMReconnectBackoff  = time.Second
+)
+
+// hsmSessionErrors are the error codes and PKCS#11 return values that mean the
+// session or key handle must be opened again
+var hsmSessionErrors = []string{
+	"session_invalid", "handle_invalid", "not_logged_in",
+	"CKR_SESSION_HANDLE_INVALID", "CKR_SESSION_CLOSED", "CKR_OBJECT_HANDLE_INVALID",
+	"CKR_KEY_HANDLE_INVALID", "CKR_USER_NOT_LOGGED_IN", "CKR_DEVICE_REMOVED",
+	"CKR_TOKEN_NOT_PRESENT", "CKR_CRYPTOKI_NOT_INITIALIZED",
+}
+
+// HSMSession remembers the key handle the HSM command last opened, so each
+// signature can reuse it, and whether the command must log in and look the
+// key up again. When the backend restarts, the next signature fails with an
+// invalid session or handle; the signer then asks the command to reopen and
+// retries a bounded number of times instead of failing every voucher until
+// the station restarts. A reopened handle is checked against the
+// manufacturer public key when the command reports the key it found.
+type HSMSession struct {
+	config *VoucherSigningConfig
+
+	mu     sync.Mutex
+	handle string // Key handle from the last successful signature, "" if none
+	reopen bool   // The session was invalidated; the command must open it again
+}
+
+// NewHSMSession creates the session state of an external HSM
+func NewHSMSession(config *VoucherSigningConfig) *HSMSession {
+	return &HSMSession{config: config}
+}
+
+// current returns the key handle to sign with and whether to reopen first
+func (h *HSMSession) current() (string, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.handle, h.reopen
+}
+
+// invalidate drops the key handle after the HSM rejected it
+func (h *HSMSession) invalidate() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.handle, h.reopen = "", true
+}
+
+// opened records the key handle of a successful signature
+func (h *HSMSession) opened(handle string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.reopen {
+		fmt.Printf("✅ HSM session re-established\n")
+	}
+	h.handle, h.reopen = handle, false
+}
+
+// attempts returns how many times a signature is retried after reconnecting
+func (h *HSMSession) attempts() int {
+	if h.config.ReconnectAttempts > 0 {
+		return h.config.ReconnectAttempts
+	}
+	return defaultHSMReconnectAttempts
+}
+
+// backoff returns the wait before reconnect attempt n (1-based), doubling each time
+func (h *HSMSession) backoff(n int) time.Duration {
+	backoff := h.config.ReconnectBackoff
+	if backoff <= 0 {
+		backoff = defaultHSMReconnectBackoff
+	}
+	return backoff << (n - 1)
+}
+
+// wait sleeps before reconnect attempt n, or returns early when ctx is done
+func (h *HSMSession) wait(ctx context.Context, n int) error {
+	timer := time.NewTimer(h.backoff(n))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// isHSMSessionError reports whether an HSM reply says the session or handle is gone
+func isHSMSessionError(code, message string) bool {
+	for _, e := range hsmSessionErrors {
+		if strings.EqualFold(code, e) || strings.Contains(message, e) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkHSMPublicKey verifies that the key a reopened handle points at is the
+// manufacturer key; an HSM that reports no key is trusted
+func checkHSMPublicKey(reported string, expected crypto.PublicKey) error {
+	if reported == "" {
+		return nil
+	}
+	block, _ := pem.Decode([]byte(reported))
+	if block == nil {
+		return fmt.Errorf("HSM reported an unparsable public key")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("HSM reported an unparsable public key: %w", err)
+	}
+	k, ok := key.(interface{ Equal(crypto.PublicKey) bool })
+	if !ok || !k.Equal(expected) {
+		return fmt.Errorf("HSM key handle refers to a different key than the manufacturer key")
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+)
+
+// listenerTLS builds the TLS config of a listener, or nil when it serves plain
+// HTTP. With a client CA, clients must present a certificate it signed.
+func listenerTLS(useTLS bool, certFile, keyFile, clientCAFile string) (*tls.Config, error) {
+	if !useTLS {
+		return nil, nil
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	config := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+	}
+	if clientCAFile != "" {
+		data, err := os.ReadFile(clientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates in client CA %s", clientCAFile)
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
+
+// diServer creates the server of the device-facing listener (server.addr).
+// Both listeners serve requests under the config read lock.
+func diServer(config *ServerConfig, handler http.Handler) (*http.Server, error) {
+	tlsConfig, err := listenerTLS(config.UseTLS, config.CertFile, config.KeyFile, "")
+	if err != nil {
+		return nil, fmt.Errorf("server: %w", err)
+	}
+	return &http.Server{
+		Addr:              config.Addr,
+		Handler:           readingConfig(handler),
+		TLSConfig:         tlsConfig,
+		ReadHeaderTimeout: 3 * time.Second,
+	}, nil
+}
+
+// adminServer creates the server of the admin listener (server.admin.addr),
+// or returns nil when the admin API shares the device-facing listener
+func adminServer(config *ServerConfig, handler http.Handler) (*http.Server, error) {
+	if config.Admin.Addr == "" {
+		return nil, nil
+	}
+	admin := &config.Admin
+	tlsConfig, err := listenerTLS(admin.UseTLS, admin.CertFile, admin.KeyFile, admin.ClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("server.admin: %w", err)
+	}
+	return &http.Server{
+		Addr:              admin.Addr,
+		Handler:           readingConfig(handler),
+		TLSConfig:         tlsConfig,
+		ReadHeaderTimeout: 3 * time.Second,
+	}, nil
+}
+
+// serve serves a listener, over TLS when the server has a TLS config
+func serve(srv *http.Server, lis net.Listener) error {
+	if srv.TLSConfig != nil {
+		return srv.ServeTLS(lis, "", "")
+	}
+	return srv.Serve(lis)
+}
+
+// listenerScheme is the URL scheme a server is reached with
+func listenerScheme(srv *http.Server) string {
+	if srv.TLSConfig != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// validateServerListeners checks the TLS settings of the listeners and that
+// they don't collide
+func validateServerListeners(config *ServerConfig) error {
+	if config.UseTLS && (config.CertFile == "" || config.KeyFile == "") {
+		return fmt.Errorf("server.use_tls requires server.cert_file and server.key_file")
+	}
+	admin := config.Admin
+	if admin.GRPCAddr != "" {
+		if _, _, err := net.SplitHostPort(admin.GRPCAddr); err != nil {
+			return fmt.Errorf("server.admin.grpc_addr: %w", err)
+		}
+		if admin.GRPCAddr == config.Addr || admin.GRPCAddr == admin.Addr {
+			return fmt.Errorf("server.admin.grpc_addr must differ from server.addr and server.admin.addr")
+		}
+	}
+	if admin.Addr == "" && admin.GRPCAddr == "" {
+		if admin.UseTLS || admin.CertFile != "" || admin.KeyFile != "" || admin.ClientCAFile != "" {
+			return fmt.Errorf("server.admin TLS settings require server.admin.addr; without it the admin API shares server.addr")
+		}
+		return nil
+	}
+	if admin.Addr != "" {
+		if _, _, err := net.SplitHostPort(admin.Addr); err != nil {
+			return fmt.Errorf("server.admin.addr: %w", err)
+		}
+		if admin.Addr == config.Addr {
+			return fmt.Errorf("server.admin.addr must differ from server.addr; leave it empty to share the listener")
+		}
+	}
+	if admin.UseTLS && (admin.CertFile == "" || admin.KeyFile == "") {
+		return fmt.Errorf("server.admin.use_tls requires server.admin.cert_file and server.admin.key_file")
+	}
+	if admin.ClientCAFile != "" && !admin.UseTLS {
+		return fmt.Errorf("server.admin.client_ca_file requires server.admin.use_tls")
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// defaultLocale is the locale of the built-in messages every other bundle falls back to
+const defaultLocale = "en"
+
+// builtinBundles holds the message bundles shipped with the station, one
+// <locale>.json file of message keys and texts each
+//
+//go:embed locales/*.json
+var builtinBundles embed.FS
+
+// stationMessages is the message catalog operator-facing text is taken from.
+// main replaces it with the configured catalog at startup.
+var stationMessages = mustBuiltinMessages()
+
+// Messages is the catalog of operator-facing text: API errors meant for the
+// people on the line, label captions and andon messages. Texts carry
+// {name} placeholders so translators can reorder them. A key missing from a
+// locale falls back to English, and an unknown key to the key itself.
+type Messages struct {
+	locale  string                       // Station default
+	bundles map[string]map[string]string // Locale → key → text
+}
+
+// NewMessages loads the built-in bundles and overlays the bundles of
+// localization.directory, which may add locales or replace single messages
+func NewMessages(config *LocalizationConfig) (*Messages, error) {
+	m, err := loadBuiltinMessages()
+	if err != nil {
+		return nil, err
+	}
+	if config.Directory != "" {
+		files, err := filepath.Glob(filepath.Join(config.Directory, "*.json"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list message bundles: %w", err)
+		}
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read message bundle: %w", err)
+			}
+			if err := m.add(strings.TrimSuffix(filepath.Base(file), ".json"), data); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if config.Locale != "" {
+		locale := normalizeLocale(config.Locale)
+		if m.match(locale) == "" {
+			return nil, fmt.Errorf("localization.locale: no message bundle for %q", config.Locale)
+		}
+		m.locale = locale
+	}
+	return m, nil
+}
+
+// loadBuiltinMessages loads the bundles embedded in the binary
+func loadBuiltinMessages() (*Messages, error) {
+	m := &Messages{locale: defaultLocale, bundles: map[string]map[string]string{}}
+	entries, err := builtinBundles.ReadDir("locales")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list built-in message bundles: %w", err)
+	}
+	for _, entry := range entries {
+		data, err := builtinBundles.ReadFile("locales/" + entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read built-in message bundle: %w", err)
+		}
+		if err := m.add(strings.TrimSuffix(entry.Name(), ".json"), data); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// mustBuiltinMessages loads the embedded bundles; they are part of the build, so an error is a bug
+func mustBuiltinMessages() *Messages {
+	m, err := loadBuiltinMessages()
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// add merges a bundle into the catalog, replacing the messages it redefines
+func (m *Messages) add(locale string, data []byte) error {
+	var bundle map[string]string
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		return fmt.Errorf("invalid message bundle %s: %w", locale, err)
+	}
+	locale = normalizeLocale(locale)
+	if m.bundles[locale] == nil {
+		m.bundles[locale] = map[string]string{}
+	}
+	for key, text := range bundle {
+		m.bundles[locale][key] = text
+	}
+	return nil
+}
+
+// normalizeLocale lowercases a language tag and uses - as separator: es_MX → es-mx
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
+
+// match returns the bundle that serves a locale: the exact tag, else its
+// language (es-mx → es), or "" if there is none
+func (m *Messages) match(locale string) string {
+	if _, ok := m.bundles[locale]; ok {
+		return locale
+	}
+	if language, _, ok := strings.Cut(locale, "-"); ok {
+		if _, ok := m.bundles[language]; ok {
+			return language
+		}
+	}
+	return ""
+}
+
+// Locale picks the locale of a request: the lang query parameter, else the
+// first Accept-Language tag with a bundle, else the station default
+func (m *Messages) Locale(r *http.Request) string {
+	if lang := r.URL.Query().Get("lang"); lang != "" {
+		if locale := m.match(normalizeLocale(lang)); locale != "" {
+			return locale
+		}
+	}
+	for _, tag := range strings.Split(r.Header.Get("Accept-Language"), ",") {
+		tag, _, _ = strings.Cut(tag, ";") // Browsers already list tags by preference
+		if locale := m.match(normalizeLocale(tag)); locale != "" {
+			return locale
+		}
+	}
+	return m.locale
+}
+
+// Text returns a message in a locale with its placeholders filled from
+// name, value pairs
+func (m *Messages) Text(locale, key string, args ...string) string {
+	text, ok := m.bundles[m.match(locale)][key]
+	if !ok {
+		text, ok = m.bundles[defaultLocale][key]
+	}
+	if !ok {
+		return key
+	}
+	if len(args) == 0 {
+		return text
+	}
+	pairs := make([]string, 0, len(args))
+	for i := 0; i+1 < len(args); i += 2 {
+		pairs = append(pairs, "{"+args[i]+"}", args[i+1])
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
+
+// StationText returns a message in the station's default locale, for text
+// that reaches the line without a request, like andon messages
+func (m *Messages) StationText(key string, args ...string) string {
+	return m.Text(m.locale, key, args...)
+}
+
+// Locales lists the locales that have a bundle
+func (m *Messages) Locales() []string {
+	locales := make([]string, 0, len(m.bundles))
+	for locale := range m.bundles {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// MessageBundle is the message catalog of one locale, English filling the gaps
+type MessageBundle struct {
+	Locale   string            `json:"locale"`
+	Default  string            `json:"default"` // Station default locale
+	Locales  []string          `json:"locales"` // Every locale with a bundle
+	Messages map[string]string `json:"messages"`
+}
+
+// Bundle returns the complete catalog of a locale
+func (m *Messages) Bundle(locale string) MessageBundle {
+	messages := map[string]string{}
+	for key, text := range m.bundles[defaultLocale] {
+		messages[key] = text
+	}
+	for key, text := range m.bundles[m.match(locale)] {
+		messages[key] = text
+	}
+	return MessageBundle{Locale: locale, Default: m.locale, Locales: m.Locales(), Messages: messages}
+}
+
+// Handler serves GET /api/messages: the catalog in the request's locale, for
+// dashboards that show operators the station's messages in their language
+func (m *Messages) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, m.Bundle(m.Locale(r)))
+	})
+}
+
+// writeOperatorError writes an error meant for an operator. "error" keeps the
+// message the API has always returned, so scripts keep working; "code" is the
+// message key and "message" its text in the request's locale.
+func writeOperatorError(w http.ResponseWriter, r *http.Request, status int, message, key string, args ...string) {
+	writeJSON(w, status, map[string]string{
+		"error":   message,
+		"code":    key,
+		"message": stationMessages.Text(stationMessages.Locale(r), key, args...),
+	})
+}
+
+// validateLocalization checks the station locale and the override bundles by loading them
+func validateLocalization(config *LocalizationConfig) error {
+	if config.Directory != "" {
+		if info, err := os.Stat(config.Directory); err != nil || !info.IsDir() {
+			return fmt.Errorf("localization.directory %s is not a directory", config.Directory)
+		}
+	}
+	_, err := NewMessages(config)
+	return err
+}
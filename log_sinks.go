@@ -0,0 +1,292 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Syslog severities (RFC 5424 section 6.2.1)
+const (
+	severityCritical = 2
+	severityError    = 3
+	severityWarning  = 4
+	severityInfo     = 6
+	severityDebug    = 7
+)
+
+// logSink is an additional destination for station log lines
+type logSink interface {
+	WriteLog(severity int, msg string) error
+	Close() error
+}
+
+// installLogSinks tees everything the station writes to stdout/stderr into the
+// configured sinks. The station logs with fmt.Printf and slog on stdout, so
+// capturing the streams covers every message without touching call sites.
+// Call it before the slog handlers are created.
+func installLogSinks(cfg *LoggingConfig, station *StationConfig) error {
+	var sinks []logSink
+	for _, sinkConfig := range cfg.Sinks {
+		sink, err := newLogSink(sinkConfig, station)
+		if err != nil {
+			for _, s := range sinks {
+				_ = s.Close()
+			}
+			return err
+		}
+		sinks = append(sinks, sink)
+	}
+	if len(sinks) == 0 {
+		return nil
+	}
+
+	stdout, err := teeStream(&os.Stdout, sinks, severityInfo)
+	if err != nil {
+		return err
+	}
+	if _, err := teeStream(&os.Stderr, sinks, severityError); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "📝 Forwarding logs to %d additional sink(s)\n", len(sinks))
+	return nil
+}
+
+// newLogSink creates a sink from its configuration
+func newLogSink(cfg LogSinkConfig, station *StationConfig) (logSink, error) {
+	switch cfg.Type {
+	case "syslog":
+		return newSyslogSink(cfg, station)
+	case "eventlog":
+		source := cfg.Source
+		if source == "" {
+			source = "fdo-manufacturing-station"
+		}
+		return newEventLogSink(source)
+	default:
+		return nil, fmt.Errorf("unsupported log sink type: %s", cfg.Type)
+	}
+}
+
+// teeStream replaces *stream with a pipe and copies each line to the original
+// file and to the sinks. It returns the original file.
+func teeStream(stream **os.File, sinks []logSink, defaultSeverity int) (*os.File, error) {
+	original := *stream
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create log pipe: %w", err)
+	}
+	*stream = w
+
+	go func() {
+		reader := bufio.NewReader(r)
+		for {
+			line, err := reader.ReadString('\n')
+			if line != "" {
+				_, _ = io.WriteString(original, line)
+				msg := strings.TrimRight(line, "\r\n")
+				if strings.TrimSpace(msg) != "" {
+					severity := lineSeverity(msg, defaultSeverity)
+					for _, sink := range sinks {
+						if sinkErr := sink.WriteLog(severity, msg); sinkErr != nil {
+							fmt.Fprintf(original, "⚠️  Log sink write failed: %v\n", sinkErr)
+						}
+					}
+				}
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return original, nil
+}
+
+// lineSeverity maps the station's log prefixes and slog levels to a syslog severity
+func lineSeverity(msg string, defaultSeverity int) int {
+	trimmed := strings.TrimSpace(msg)
+	switch {
+	case strings.HasPrefix(trimmed, "🚨"), strings.Contains(msg, "CRITICAL"):
+		return severityCritical
+	case strings.HasPrefix(trimmed, "❌"), strings.HasPrefix(trimmed, "Error"), strings.Contains(msg, "level=ERROR"):
+		return severityError
+	case strings.HasPrefix(trimmed, "⚠️"), strings.HasPrefix(trimmed, "Warning"), strings.Contains(msg, "level=WARN"):
+		return severityWarning
+	case strings.Contains(msg, "DEBUG"), strings.Contains(msg, "level=DEBUG"):
+		return severityDebug
+	}
+	return defaultSeverity
+}
+
+// syslogSink sends RFC 5424 messages over UDP, TCP or TLS. Stream transports
+// use octet-counting framing (RFC 6587) and reconnect on write failure.
+type syslogSink struct {
+	cfg      LogSinkConfig
+	facility int
+	hostname string
+	appName  string
+	sd       string // Structured data carrying the site/line/station, or "-"
+	tlsConf  *tls.Config
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// syslogFacilities maps facility names to RFC 5424 facility codes
+var syslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "daemon": 3, "auth": 4, "syslog": 5, "authpriv": 10,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// newSyslogSink creates a syslog sink and connects to the collector
+func newSyslogSink(cfg LogSinkConfig, station *StationConfig) (*syslogSink, error) {
+	if cfg.Address == "" {
+		return nil, fmt.Errorf("syslog sink requires an address")
+	}
+	if cfg.Network == "" {
+		cfg.Network = "udp"
+	}
+	if cfg.Network != "udp" && cfg.Network != "tcp" && cfg.Network != "tls" {
+		return nil, fmt.Errorf("unsupported syslog network: %s", cfg.Network)
+	}
+
+	facilityName := cfg.Facility
+	if facilityName == "" {
+		facilityName = "local0"
+	}
+	facility, ok := syslogFacilities[facilityName]
+	if !ok {
+		return nil, fmt.Errorf("unknown syslog facility: %s", facilityName)
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+	appName := cfg.AppName
+	if appName == "" {
+		appName = "fdo-manufacturing-station"
+	}
+
+	s := &syslogSink{cfg: cfg, facility: facility, hostname: hostname, appName: appName, sd: stationStructuredData(station)}
+
+	if cfg.Network == "tls" {
+		host, _, _ := net.SplitHostPort(cfg.Address)
+		s.tlsConf = &tls.Config{ServerName: host, MinVersion: tls.VersionTLS12, InsecureSkipVerify: cfg.InsecureSkipVerify}
+		if cfg.CAFile != "" {
+			pem, err := os.ReadFile(cfg.CAFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read syslog CA file: %w", err)
+			}
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates found in syslog CA file %s", cfg.CAFile)
+			}
+			s.tlsConf.RootCAs = pool
+		}
+	}
+
+	if err := s.connect(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// connect dials the collector; callers hold s.mu (or own s exclusively)
+func (s *syslogSink) connect() error {
+	var conn net.Conn
+	var err error
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	switch s.cfg.Network {
+	case "tls":
+		conn, err = tls.DialWithDialer(dialer, "tcp", s.cfg.Address, s.tlsConf)
+	default:
+		conn, err = dialer.Dial(s.cfg.Network, s.cfg.Address)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to syslog %s://%s: %w", s.cfg.Network, s.cfg.Address, err)
+	}
+	s.conn = conn
+	return nil
+}
+
+// WriteLog sends one RFC 5424 message
+func (s *syslogSink) WriteLog(severity int, msg string) error {
+	frame := s.format(severity, msg, time.Now())
+	if s.cfg.Network != "udp" {
+		frame = fmt.Sprintf("%d %s", len(frame), frame)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn != nil {
+		if _, err := io.WriteString(s.conn, frame); err == nil {
+			return nil
+		}
+		_ = s.conn.Close()
+		s.conn = nil
+	}
+	// Retry once on a fresh connection (collector restarted, idle timeout, ...)
+	if err := s.connect(); err != nil {
+		return err
+	}
+	_, err := io.WriteString(s.conn, frame)
+	return err
+}
+
+// format renders an RFC 5424 message: <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID SD MSG
+func (s *syslogSink) format(severity int, msg string, now time.Time) string {
+	return fmt.Sprintf("<%d>1 %s %s %s %d - %s %s",
+		s.facility*8+severity,
+		now.UTC().Format("2006-01-02T15:04:05.000000Z07:00"),
+		s.hostname, s.appName, os.Getpid(), s.sd, msg)
+}
+
+// stationStructuredData renders the site/line/station as an RFC 5424 SD element
+// (e.g. [station@32473 site="MTY" line="L3" id="factory-01"]) so collectors can
+// label messages by origin, or "-" if none is configured
+func stationStructuredData(station *StationConfig) string {
+	if station == nil {
+		return "-"
+	}
+	escape := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+	var params []string
+	for _, param := range []struct{ name, value string }{
+		{"site", station.SiteCode},
+		{"line", station.LineID},
+		{"id", station.StationID},
+	} {
+		if param.value != "" {
+			params = append(params, fmt.Sprintf(`%s="%s"`, param.name, escape.Replace(param.value)))
+		}
+	}
+	if len(params) == 0 {
+		return "-"
+	}
+	return "[station@32473 " + strings.Join(params, " ") + "]"
+}
+
+// Close closes the connection to the collector
+func (s *syslogSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
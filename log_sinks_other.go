@@ -0,0 +1,14 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+//go:build !windows
+
+package main
+
+import "fmt"
+
+// newEventLogSink is only available on Windows
+func newEventLogSink(source string) (logSink, error) {
+	return nil, fmt.Errorf("eventlog sink %q is only supported on Windows", source)
+}
@@ -0,0 +1,87 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+// Windows event types for ReportEventW
+const (
+	eventlogErrorType       = 0x0001
+	eventlogWarningType     = 0x0002
+	eventlogInformationType = 0x0004
+)
+
+var (
+	advapi32                  = syscall.NewLazyDLL("advapi32.dll")
+	procRegisterEventSourceW  = advapi32.NewProc("RegisterEventSourceW")
+	procDeregisterEventSource = advapi32.NewProc("DeregisterEventSource")
+	procReportEventW          = advapi32.NewProc("ReportEventW")
+)
+
+// eventLogSink writes log lines to the Windows Event Log (Application log)
+type eventLogSink struct {
+	handle uintptr
+}
+
+// newEventLogSink registers the event source. The source doesn't need a message
+// file; Event Viewer shows the text as the event's insertion string.
+func newEventLogSink(source string) (logSink, error) {
+	sourcePtr, err := syscall.UTF16PtrFromString(source)
+	if err != nil {
+		return nil, err
+	}
+	handle, _, callErr := procRegisterEventSourceW.Call(0, uintptr(unsafe.Pointer(sourcePtr)))
+	if handle == 0 {
+		return nil, fmt.Errorf("failed to register event source %s: %w", source, callErr)
+	}
+	return &eventLogSink{handle: handle}, nil
+}
+
+// WriteLog reports one event
+func (e *eventLogSink) WriteLog(severity int, msg string) error {
+	eventType := eventlogInformationType
+	switch {
+	case severity <= severityError:
+		eventType = eventlogErrorType
+	case severity == severityWarning:
+		eventType = eventlogWarningType
+	}
+
+	msgPtr, err := syscall.UTF16PtrFromString(msg)
+	if err != nil {
+		return err
+	}
+	insertStrings := []*uint16{msgPtr}
+	ret, _, callErr := procReportEventW.Call(
+		e.handle,
+		uintptr(eventType),
+		0, // category
+		1, // event ID
+		0, // user SID
+		1, // number of strings
+		0, // raw data size
+		uintptr(unsafe.Pointer(&insertStrings[0])),
+		0, // raw data
+	)
+	if ret == 0 {
+		return fmt.Errorf("ReportEventW failed: %w", callErr)
+	}
+	return nil
+}
+
+// Close deregisters the event source
+func (e *eventLogSink) Close() error {
+	ret, _, callErr := procDeregisterEventSource.Call(e.handle)
+	if ret == 0 {
+		return fmt.Errorf("DeregisterEventSource failed: %w", callErr)
+	}
+	return nil
+}
@@ -29,6 +29,7 @@ import (
 	transport "github.com/fido-device-onboard/go-fdo/http"
 	"github.com/fido-device-onboard/go-fdo/protocol"
 	"github.com/fido-device-onboard/go-fdo/sqlite"
+	"google.golang.org/grpc"
 )
 
 func init() {
@@ -47,6 +48,9 @@ var (
 	purgeDIDCacheExpired   = flag.Bool("purge-did-cache-expired", false, "Purge expired DID cache entries then exit")
 	purgeDIDCacheAll       = flag.Bool("purge-did-cache-all", false, "Purge ALL DID cache entries then exit")
 	purgeDIDCacheOnStartup = flag.Bool("purge-did-cache-on-startup", false, "Purge expired DID cache entries on startup then continue")
+	showVersion            = flag.Bool("version", false, "Print version and build info then exit")
+	replica                = flag.Bool("replica", false, "Serve only the read-only admin/reporting API from the station database (no DI)")
+	standby                = flag.Bool("standby", false, "Sync the databases from standby.primary until promoted, then run as the station")
 )
 
 func main() {
@@ -55,12 +59,190 @@ func main() {
 	// Load configuration
 	var err error
 	config, err = LoadConfig(*configPath)
-	fmt.Printf("DEBUG: Config loaded: %+v\n", config)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error loading configuration: %v\n", err)
 		os.Exit(1)
 	}
 
+	// Premium features this site is entitled to, verified offline
+	stationEntitlements = LoadEntitlements(&config.Entitlements, &config.Station)
+
+	if *showVersion {
+		fmt.Print(currentBuildInfo(config, existingInstanceID()))
+		os.Exit(0)
+	}
+
+	// "bootstrap" writes the config of a new station from its answers
+	if flag.NArg() >= 1 && flag.Arg(0) == "bootstrap" {
+		if err := runBootstrap(flag.Args()[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "bootstrap: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	// "voucher debug-extend" replays a saved voucher extension failure
+	if flag.NArg() >= 2 && flag.Arg(0) == "voucher" && flag.Arg(1) == "debug-extend" {
+		if err := runVoucherDebugExtend(flag.Args()[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "voucher debug-extend: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	// "voucher replay" reruns the voucher pipeline for recorded DI sessions
+	if flag.NArg() >= 2 && flag.Arg(0) == "voucher" && flag.Arg(1) == "replay" {
+		if err := runVoucherReplay(flag.Args()[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "voucher replay: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	// "voucher verify" re-verifies every stored voucher once
+	if flag.NArg() >= 2 && flag.Arg(0) == "voucher" && flag.Arg(1) == "verify" {
+		if err := runVoucherVerify(flag.Args()[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "voucher verify: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	// "voucher diag" prints a voucher as annotated CBOR diagnostic notation
+	if flag.NArg() >= 2 && flag.Arg(0) == "voucher" && flag.Arg(1) == "diag" {
+		if err := runVoucherDiag(flag.Args()[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "voucher diag: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	// "voucher diff" compares two vouchers field by field
+	if flag.NArg() >= 2 && flag.Arg(0) == "voucher" && flag.Arg(1) == "diff" {
+		if err := runVoucherDiff(flag.Args()[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "voucher diff: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	// "voucher check" checks a voucher against the profile that should have produced it
+	if flag.NArg() >= 2 && flag.Arg(0) == "voucher" && flag.Arg(1) == "check" {
+		if err := runVoucherCheck(flag.Args()[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "voucher check: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	// "capture diag" prints the DI messages of a debug capture the same way
+	if flag.NArg() >= 2 && flag.Arg(0) == "capture" && flag.Arg(1) == "diag" {
+		if err := runCaptureDiag(flag.Args()[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "capture diag: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	// "routing export|import" dumps or replaces the upload routing table
+	if flag.NArg() >= 1 && flag.Arg(0) == "routing" {
+		if err := runRouting(flag.Args()[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "routing: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	// "command replay" runs a recorded external command again
+	if flag.NArg() >= 2 && flag.Arg(0) == "command" && flag.Arg(1) == "replay" {
+		if err := runCommandReplay(flag.Args()[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "command replay: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	// "command scaffold" writes a reference implementation of an external command contract
+	if flag.NArg() >= 2 && flag.Arg(0) == "command" && flag.Arg(1) == "scaffold" {
+		if err := runCommandScaffold(flag.Args()[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "command scaffold: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	// "command check" checks a partner's external command against its contract
+	if flag.NArg() >= 2 && flag.Arg(0) == "command" && flag.Arg(1) == "check" {
+		if err := runCommandCheck(flag.Args()[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "command check: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	// "trust pin|list" pins the certificate of a factory-internal endpoint
+	if flag.NArg() >= 1 && flag.Arg(0) == "trust" {
+		if err := runTrust(flag.Args()[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "trust: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	// "standby promote" makes a cold standby take over from a failed primary
+	if flag.NArg() >= 2 && flag.Arg(0) == "standby" && flag.Arg(1) == "promote" {
+		if err := runStandbyPromote(flag.Args()[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "standby promote: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	// "signing encrypt-fallback-key" writes the key file of voucher_signing.fallback
+	if flag.NArg() >= 2 && flag.Arg(0) == "signing" && flag.Arg(1) == "encrypt-fallback-key" {
+		if err := runSigningEncryptFallbackKey(flag.Args()[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "signing encrypt-fallback-key: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	// "entitlement sign" issues an entitlement file for a site
+	if flag.NArg() >= 2 && flag.Arg(0) == "entitlement" && flag.Arg(1) == "sign" {
+		if err := runEntitlementSign(flag.Args()[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "entitlement sign: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	// "health" prints the health of a running station through its admin API
+	if flag.NArg() >= 1 && flag.Arg(0) == "health" {
+		if err := runHealth(flag.Args()[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "health: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	// "did-cache show|evict|purge" manages the DID cache of a running station
+	if flag.NArg() >= 1 && flag.Arg(0) == "did-cache" {
+		if err := runDIDCache(flag.Args()[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "did-cache: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	// "selftest" runs a throwaway device through every configured dependency
+	if flag.NArg() >= 1 && flag.Arg(0) == "selftest" {
+		if err := runSelfTest(flag.Args()[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "selftest: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
 	// Handle DID cache purging flags
 	if *purgeDIDCacheExpired || *purgeDIDCacheAll || *purgeDIDCacheOnStartup {
 		if err := handleDIDCachePurge(); err != nil {
@@ -75,6 +257,12 @@ func main() {
 		}
 	}
 
+	// Forward logs to syslog / Windows Event Log if configured
+	if err := installLogSinks(&config.Logging, &config.Station); err != nil {
+		fmt.Fprintf(os.Stderr, "Error configuring log sinks: %v\n", err)
+		os.Exit(1)
+	}
+
 	// Configure logging based on debug mode
 	if *debug || config.Debug {
 		slog.SetDefault(slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug})))
@@ -134,6 +322,26 @@ func main() {
 	}
 
 	ctx := context.Background()
+	if *replica || *standby {
+		if err := stationEntitlements.Require(EntitlementStandby, "-replica and -standby"); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if *replica {
+		if err := runReplica(ctx); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if *standby {
+		if err := runStandby(ctx); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
 	if err := runManufacturingStation(ctx); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
@@ -222,12 +430,16 @@ func (h *noopHandler) WithGroup(name string) slog.Handler {
 }
 
 func runManufacturingStation(ctx context.Context) error {
+	// Umask first, so the databases and everything after get its permissions
+	if err := applyUmask(&config.Privileges); err != nil {
+		return err
+	}
+
 	// Check if database exists
 	_, dbStatErr := os.Stat(config.Database.Path)
 
 	// Open database
 	state, err := sqlite.Open(config.Database.Path, config.Database.Password)
-	fmt.Printf("DEBUG: Config loaded: %+v\n", config)
 	if err != nil {
 		return fmt.Errorf("error opening database: %w", err)
 	}
@@ -253,6 +465,13 @@ func runManufacturingStation(ctx context.Context) error {
 		fmt.Println("DID cache initialization completed")
 	}
 
+	// Open station bookkeeping database (upload receipts etc.)
+	stationDB, err := OpenStationDB(stationDBPath(config))
+	if err != nil {
+		return err
+	}
+	defer stationDB.Close()
+
 	// Generate keys if first-time init or database doesn't exist
 	if config.Manufacturing.FirstTimeInit || errors.Is(dbStatErr, fs.ErrNotExist) {
 		fmt.Println("Initializing manufacturing station keys...")
@@ -267,29 +486,48 @@ func runManufacturingStation(ctx context.Context) error {
 		return nil
 	}
 
+	// Key files, databases and voucher directories other users can get at
+	if err := auditPermissions(config); err != nil {
+		return err
+	}
+
 	// Start DI server
-	return startDIServer(ctx, state)
+	return startDIServer(ctx, state, stationDB)
+}
+
+// existingInstanceID reads the instance ID for --version without creating a station database
+func existingInstanceID() string {
+	path := stationDBPath(config)
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+	stationDB, err := OpenStationDB(path)
+	if err != nil {
+		return ""
+	}
+	defer stationDB.Close()
+	instanceID, err := stationDB.InstanceID(context.Background())
+	if err != nil {
+		return ""
+	}
+	return instanceID
 }
 
 func generateManufacturingKeys(state *sqlite.DB) error {
 	// Generate manufacturing component keys (these act as the Device CA)
 	rsa2048MfgKey, err := rsa.GenerateKey(rand.Reader, 2048)
-	fmt.Printf("DEBUG: Config loaded: %+v\n", config)
 	if err != nil {
 		return err
 	}
 	rsa3072MfgKey, err := rsa.GenerateKey(rand.Reader, 3072)
-	fmt.Printf("DEBUG: Config loaded: %+v\n", config)
 	if err != nil {
 		return err
 	}
 	ec256MfgKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
-	fmt.Printf("DEBUG: Config loaded: %+v\n", config)
 	if err != nil {
 		return err
 	}
 	ec384MfgKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
-	fmt.Printf("DEBUG: Config loaded: %+v\n", config)
 	if err != nil {
 		return err
 	}
@@ -299,18 +537,16 @@ func generateManufacturingKeys(state *sqlite.DB) error {
 		template := &x509.Certificate{
 			SerialNumber:          big.NewInt(1),
 			Subject:               pkix.Name{CommonName: "Manufacturing Station CA"},
-			NotBefore:             time.Now(),
-			NotAfter:              time.Now().Add(30 * 365 * 24 * time.Hour),
+			NotBefore:             stationClock.Now(),
+			NotAfter:              stationClock.Now().Add(30 * 365 * 24 * time.Hour),
 			BasicConstraintsValid: true,
 			IsCA:                  true,
 		}
 		der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
-		fmt.Printf("DEBUG: Config loaded: %+v\n", config)
 		if err != nil {
 			return nil, err
 		}
 		cert, err := x509.ParseCertificate(der)
-		fmt.Printf("DEBUG: Config loaded: %+v\n", config)
 		if err != nil {
 			return nil, err
 		}
@@ -318,22 +554,18 @@ func generateManufacturingKeys(state *sqlite.DB) error {
 	}
 
 	rsa2048Chain, err := generateCA(rsa2048MfgKey)
-	fmt.Printf("DEBUG: Config loaded: %+v\n", config)
 	if err != nil {
 		return err
 	}
 	rsa3072Chain, err := generateCA(rsa3072MfgKey)
-	fmt.Printf("DEBUG: Config loaded: %+v\n", config)
 	if err != nil {
 		return err
 	}
 	ec256Chain, err := generateCA(ec256MfgKey)
-	fmt.Printf("DEBUG: Config loaded: %+v\n", config)
 	if err != nil {
 		return err
 	}
 	ec384Chain, err := generateCA(ec384MfgKey)
-	fmt.Printf("DEBUG: Config loaded: %+v\n", config)
 	if err != nil {
 		return err
 	}
@@ -359,44 +591,375 @@ func generateManufacturingKeys(state *sqlite.DB) error {
 	return nil
 }
 
-func startDIServer(ctx context.Context, state *sqlite.DB) error {
+func startDIServer(ctx context.Context, state *sqlite.DB, stationDB *StationDB) error {
 	// Normalize address
 	extAddr := config.Server.ExtAddr
 	if extAddr == "" {
 		extAddr = config.Server.Addr
 	}
 
+	instanceID, err := stationDB.InstanceID(ctx)
+	if err != nil {
+		return err
+	}
+	buildInfo := currentBuildInfo(config, instanceID)
+	fmt.Printf("🏷️  Station %s, instance %s\n", buildInfo.Version, instanceID)
+
+	// Test-only fault injection (nil unless fault_injection.enabled)
+	faultInjector, err = NewFaultInjector(&config.FaultInjection)
+	if err != nil {
+		return err
+	}
+
+	// Serial normalization and pseudonymization (nil when no rules are configured)
+	serialRules, err = NewSerialRules(&config.SerialRules)
+	if err != nil {
+		return err
+	}
+
+	// Critical failure notifications (nil when SMTP is disabled)
+	notifier := NewNotifier(&config.Notifications, config.Station.StationID)
+	go notifier.Run(ctx)
+
 	// Use Manufacturer key as device certificate authority
 	fmt.Printf("🔍 DEBUG: Getting manufacturer key...\n")
 	deviceCAKey, deviceCAChain, err := state.ManufacturerKey(ctx, protocol.Secp384r1KeyType, 0)
 	if err != nil {
+		notifier.Critical("manufacturer_key", fmt.Sprintf("device CA key unavailable: %v", err))
+		notifier.Flush()
 		return fmt.Errorf("error getting manufacturer key for device certificate authority: %w", err)
 	}
 	fmt.Printf("🔍 DEBUG: Manufacturer key retrieved successfully\n")
 
 	// Initialize voucher management services
-	ownerKeyExecutor := NewExternalCommandExecutor(config.VoucherManagement.OwnerSignover.ExternalCommand, config.VoucherManagement.OwnerSignover.Timeout)
-	ownerKeyService := NewOwnerKeyService(ownerKeyExecutor)
-
-	voucherUploadExecutor := NewExternalCommandExecutor(config.VoucherManagement.VoucherUpload.ExternalCommand, config.VoucherManagement.VoucherUpload.Timeout)
-	voucherUploadService := NewVoucherUploadService(voucherUploadExecutor)
+	if err := initSessionTempDirs(config.VoucherManagement.TempDirectory); err != nil {
+		return err
+	}
+	commandPools = NewCommandPools(&config.ExternalCommands)
+	commandLog = NewCommandLog(&config.ExternalCommands.Log, stationDB)
+	if err := commandLog.Initialize(ctx); err != nil {
+		return err
+	}
+	if commandLog != nil {
+		go commandLog.Run(ctx)
+	}
+	commandPlugins = NewCommandPlugins(&config.ExternalCommands)
+	commandPlugins.Start(ctx)
+	if err := configureExtensions(config); err != nil {
+		return err
+	}
+	tlsTrust = NewTLSTrust(&config.VoucherManagement.TLSTrust)
+	go tlsTrust.Run(ctx)
+	ownerKeyExecutor := NewExternalCommandExecutor(CommandOwnerSignover, config.VoucherManagement.OwnerSignover.ExternalCommand, config.VoucherManagement.OwnerSignover.Timeout)
+	ownerKeyService := NewOwnerKeyService(ownerKeyExecutor, &config.VoucherManagement.OwnerSignover, &config.VoucherManagement.DIDCache, &config.Rollouts, notifier)
+
+	voucherUploadExecutor := NewExternalCommandExecutor(CommandVoucherUpload, config.VoucherManagement.VoucherUpload.ExternalCommand, config.VoucherManagement.VoucherUpload.Timeout)
+	voucherHTTPUploader := NewVoucherHTTPUploader(&config.VoucherManagement, config.Station.StationID)
+	if err := NewVoucherStore(stationDB).Initialize(ctx); err != nil {
+		return err
+	}
+	uploadReceipts := NewUploadReceiptStore(stationDB)
+	if err := uploadReceipts.Initialize(ctx); err != nil {
+		return err
+	}
+	var uploadDestinations *UploadDestinationCatalog
+	if config.VoucherManagement.VoucherUpload.Mode == "http" {
+		uploadDestinations = NewUploadDestinationCatalog(&config.VoucherManagement, stationDB)
+		if err := uploadDestinations.Initialize(ctx); err != nil {
+			return err
+		}
+	}
+	var voucherBatcher *VoucherBatchUploader
+	if config.VoucherManagement.VoucherUpload.Mode == "http" && config.VoucherManagement.VoucherUpload.Batch.Enabled {
+		voucherBatcher = NewVoucherBatchUploader(&config.VoucherManagement, voucherHTTPUploader, stationDB, uploadReceipts, uploadDestinations, notifier)
+		if err := voucherBatcher.Initialize(ctx); err != nil {
+			return err
+		}
+		go voucherBatcher.Run(ctx)
+	}
+	voucherUploadService := NewVoucherUploadService(&config.VoucherManagement, voucherUploadExecutor, voucherHTTPUploader, voucherBatcher, uploadReceipts, uploadDestinations, &config.Rollouts, notifier)
 
 	// Initialize voucher signing service
 	voucherSigningService := NewVoucherSigningService(
 		&config.VoucherManagement.VoucherSigning,
-		NewExternalCommandExecutor(config.VoucherManagement.VoucherSigning.ExternalCommand, config.VoucherManagement.VoucherSigning.ExternalTimeout),
-		"factory-01", // TODO: Make configurable
+		NewExternalCommandExecutor(CommandVoucherSign, config.VoucherManagement.VoucherSigning.ExternalCommand, config.VoucherManagement.VoucherSigning.ExternalTimeout),
+		config.Station.StationID,
 	)
+	fallbackSigner, err := loadFallbackSigner(&config.VoucherManagement.VoucherSigning)
+	if err != nil {
+		return err
+	}
+	voucherSigningService.SetFallbackSigner(fallbackSigner, notifier)
 
 	// Initialize voucher disk service
-	voucherDiskService := NewVoucherDiskService(&config.VoucherManagement)
+	diskManifests, err := NewDiskManifests(&config.VoucherManagement.SaveToDisk.Manifest, config.Transfer.SigningKeyFile, stationDB, buildInfo)
+	if err != nil {
+		return err
+	}
+	if err := diskManifests.Initialize(ctx); err != nil {
+		return err
+	}
+	go diskManifests.Run(ctx)
+	voucherDiskService := NewVoucherDiskService(&config.VoucherManagement, diskManifests)
 
 	// Initialize OVEExtra data service
 	oveExtraDataService := NewOVEExtraDataService(
 		&config.VoucherManagement.OVEExtraData,
-		NewExternalCommandExecutor(config.VoucherManagement.OVEExtraData.ExternalCommand, config.VoucherManagement.OVEExtraData.Timeout),
+		NewExternalCommandExecutor(CommandOVEExtraData, config.VoucherManagement.OVEExtraData.ExternalCommand, config.VoucherManagement.OVEExtraData.Timeout),
+		buildInfo,
 	)
 
+	auditLog := NewAuditLog(stationDB, &config.Station)
+	if err := auditLog.Initialize(ctx); err != nil {
+		return err
+	}
+
+	// Shift windows (nil when manufacturing is unrestricted)
+	schedule, err := NewManufacturingSchedule(&config.Schedule)
+	if err != nil {
+		return err
+	}
+
+	// Operator batch sign-in (nil when the operator gate is disabled)
+	operatorGate, err := NewOperatorGate(&config.OperatorGate, auditLog)
+	if err != nil {
+		return err
+	}
+
+	// Production batches/lots
+	batchService := NewBatchService(&config.Batches, stationDB, operatorGate, auditLog)
+	if err := batchService.Initialize(ctx); err != nil {
+		return err
+	}
+
+	// GUIDs reserved for pre-printed labels (nil when disabled)
+	guidReservations := NewGUIDReservations(&config.GUIDReservations, stationDB, batchService, auditLog)
+	if err := guidReservations.Initialize(ctx); err != nil {
+		return err
+	}
+
+	// Admin-issued tokens that waive policy checks for named serials (nil when disabled)
+	policyOverrides, err := NewPolicyOverrides(&config.OverrideTokens, stationDB, operatorGate, auditLog)
+	if err != nil {
+		return err
+	}
+	if err := policyOverrides.Initialize(ctx); err != nil {
+		return err
+	}
+
+	// Owner keys registered with proof of possession (nil when not required)
+	ownerKeyProofs := NewOwnerKeyProofs(&config.OwnerKeyProofs, stationDB, ownerKeyService, auditLog)
+	if err := ownerKeyProofs.Initialize(ctx); err != nil {
+		return err
+	}
+
+	// Manufacturing quotas (nil when no quota rules are configured)
+	quotaService := NewQuotaService(&config.Quotas, stationDB, notifier)
+	if err := quotaService.Initialize(ctx); err != nil {
+		return err
+	}
+
+	if err := validateOwnerKeyEncodings(&config.VoucherManagement); err != nil {
+		return err
+	}
+	if err := validateCompressions(&config.VoucherManagement); err != nil {
+		return err
+	}
+	if err := validateDiskDestinations(&config.VoucherManagement); err != nil {
+		return err
+	}
+	if err := validateSignoverPolicies(&config.VoucherManagement); err != nil {
+		return err
+	}
+	if err := validateOwnerSignover(&config.VoucherManagement.OwnerSignover); err != nil {
+		return err
+	}
+	if config.VoucherManagement.OwnerSignover.Mode == "none" {
+		fmt.Printf("⚠️  Owner signover mode is none: vouchers are delivered WITHOUT being extended to an owner\n")
+	}
+	if err := validateJWKSKeySelection(&config.VoucherManagement.DIDCache); err != nil {
+		return err
+	}
+	if err := validateFallbackSigner(&config.VoucherManagement.VoucherSigning); err != nil {
+		return err
+	}
+	if err := validateDryRun(config); err != nil {
+		return err
+	}
+	if err := validateDualControl(&config.Admin); err != nil {
+		return err
+	}
+	if err := validateAdminHTTP(&config.Admin); err != nil {
+		return err
+	}
+	if err := validateServerListeners(&config.Server); err != nil {
+		return err
+	}
+	if err := validateAuditAnchor(&config.AuditAnchor); err != nil {
+		return err
+	}
+	if err := validateRollouts(config); err != nil {
+		return err
+	}
+	if err := validateClaimURLs(&config.ClaimURLs); err != nil {
+		return err
+	}
+	if err := validateLocalization(&config.Localization); err != nil {
+		return err
+	}
+	if err := validatePrivileges(&config.Privileges); err != nil {
+		return err
+	}
+	if err := checkEntitlements(config); err != nil {
+		return err
+	}
+	stationEntitlements.WarnExpiring()
+	if err := validateRateLimit(&config.VoucherManagement.VoucherUpload.RateLimit); err != nil {
+		return err
+	}
+	if err := validateTLSTrust(&config.VoucherManagement.TLSTrust); err != nil {
+		return err
+	}
+	if err := validatePlugins(config); err != nil {
+		return err
+	}
+	if err := validateExtensions(config); err != nil {
+		return err
+	}
+	if _, err := newOVEExtraValidator(&config.VoucherManagement.OVEExtraData.Validation); err != nil {
+		return err
+	}
+
+	// Voucher hash/HMAC algorithm (nil when not pinned); checked against the configured keys now
+	hashPolicy, err := NewVoucherHashPolicy(&config.VoucherManagement)
+	if err != nil {
+		return err
+	}
+
+	// Owners revoked by the central management plane
+	ownerRevocations := NewOwnerRevocations(stationDB)
+	if err := ownerRevocations.Initialize(ctx); err != nil {
+		return err
+	}
+
+	// Owner-announced DID key rotations, checked before devices are signed over to the new key
+	didRotations := NewDIDRotations(&config.VoucherManagement.DIDCache, hashPolicy, ownerRevocations, notifier, auditLog)
+	go didRotations.Run(ctx)
+
+	// Admin pins of DIDs to their last resolved key
+	didPins := NewDIDPins(&config.VoucherManagement.DIDCache, stationDB, didRotations, auditLog)
+	if err := didPins.Initialize(ctx); err != nil {
+		return err
+	}
+	go didPins.Run(ctx)
+	ownerKeyService.SetDIDRotations(didRotations, didPins)
+
+	// Recorded DI sessions for "voucher replay"
+	sessionRecorder := NewSessionRecorder(&config.VoucherManagement, stationDB)
+	if err := sessionRecorder.Initialize(ctx); err != nil {
+		return err
+	}
+
+	// Database snapshots for a cold standby (nil unless standby.serve_snapshots)
+	standbySnapshots, err := NewStandbySnapshots(&config.Standby, config)
+	if err != nil {
+		return err
+	}
+
+	// Vouchers kept for export to, and imported from, other stations (nil when disabled)
+	voucherTransfers, err := NewVoucherTransferService(&config.Transfer, stationDB, auditLog, buildInfo)
+	if err != nil {
+		return err
+	}
+	if err := voucherTransfers.Initialize(ctx); err != nil {
+		return err
+	}
+
+	// DI outcomes and upload queue depth for legacy factory monitoring
+	stationStatus := NewStationStatus(voucherBatcher)
+	modbusServer := NewModbusStatusServer(&config.Modbus, stationStatus)
+	modbusLis, err := modbusServer.Listen()
+	if err != nil {
+		return err
+	}
+	go func() {
+		if err := modbusServer.Run(ctx, modbusLis); err != nil {
+			fmt.Printf("❌ Modbus status server stopped: %v\n", err)
+		}
+	}()
+
+	// Free disk space and database size (nil when disabled)
+	diskMonitor, err := NewDiskMonitor(&config.DiskMonitor, config, notifier, auditLog)
+	if err != nil {
+		return err
+	}
+	diskMonitor.Check() // Before serving, so a full disk refuses the first device
+	go diskMonitor.Run(ctx)
+
+	// Hold fixtures off while the upload or signing queues are backed up
+	backpressure := NewBackpressure(&config.Backpressure, voucherBatcher, commandPools, auditLog)
+
+	// Periodic re-verification of stored vouchers (nil when disabled)
+	voucherIntegrity := NewVoucherIntegrity(&config.VoucherIntegrity, config, stationDB, auditLog, notifier)
+	go voucherIntegrity.Run(ctx)
+
+	// One status for line monitoring, from the checks above
+	stationHealth := NewStationHealth(stationDB, diskMonitor, backpressure, voucherIntegrity)
+
+	// DID cache entries for the admin API (nil when the cache is disabled)
+	var didCache *DIDResolver
+	if config.VoucherManagement.DIDCache.Enabled {
+		didCache = NewDIDResolver(state, &config.VoucherManagement.DIDCache)
+	}
+
+	// Cleanup of what interrupted work leaves behind (nil when disabled)
+	stationGC := NewStationGC(&config.GC, config, stationDB, auditLog)
+	go stationGC.Run(ctx)
+
+	// Anchoring of the audit chain outside the station (nil when disabled)
+	auditAnchor, err := NewAuditAnchor(&config.AuditAnchor, config.Transfer.SigningKeyFile, stationDB, auditLog, buildInfo)
+	if err != nil {
+		return err
+	}
+	if err := auditAnchor.Initialize(ctx); err != nil {
+		return err
+	}
+	oveExtraDataService.SetAuditAnchor(auditAnchor)
+	go auditAnchor.Run(ctx)
+
+	// Owner key history per customer/model (nil when disabled)
+	signoverAnomalies := NewSignoverAnomalyDetector(&config.SignoverAnomaly, stationDB, auditLog, notifier, stationStatus, config.Station.StationID)
+	if err := signoverAnomalies.Initialize(ctx); err != nil {
+		return err
+	}
+	claimURLs := NewClaimURLs(&config.ClaimURLs, stationDB, config.Station.StationID)
+	if err := claimURLs.Initialize(ctx); err != nil {
+		return err
+	}
+
+	// Operator-facing messages in the station's locale
+	messages, err := NewMessages(&config.Localization)
+	if err != nil {
+		return err
+	}
+	stationMessages = messages
+
+	// Line signal tower (nil when disabled)
+	andon, err := NewAndon(&config.Andon, stationStatus, config.Station.StationID)
+	if err != nil {
+		return err
+	}
+	go andon.Run(ctx)
+
+	// Devices whose pipeline runs without keeping or delivering anything
+	dryRun := NewDryRun(&config.DryRun, auditLog)
+
+	// How far each GUID's pipeline got, so a session retried after a crash resumes
+	voucherPipeline := NewVoucherPipeline(stationDB)
+	if err := voucherPipeline.Initialize(ctx); err != nil {
+		return err
+	}
+
 	voucherCallbackService := NewVoucherCallbackService(
 		&config.VoucherManagement,
 		ownerKeyService,
@@ -404,17 +967,91 @@ func startDIServer(ctx context.Context, state *sqlite.DB) error {
 		voucherUploadService,
 		voucherDiskService,
 		oveExtraDataService,
+		quotaService,
+		schedule,
+		auditLog,
+		batchService,
+		hashPolicy,
+		ownerRevocations,
+		sessionRecorder,
+		stationStatus,
+		voucherTransfers,
+		signoverAnomalies,
+		claimURLs,
+		policyOverrides,
+		ownerKeyProofs,
+		dryRun,
+		voucherPipeline,
 		deviceCAKey, // Use device CA key for signing vouchers
 	)
 
+	// FDO protocol versions accepted for DI
+	protocolGate, err := NewProtocolVersionGate(&config.Protocol, auditLog)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("🔢 Accepting FDO protocol versions %v\n", acceptedProtocolVersions(&config.Protocol))
+
+	// Config changes pushed through the admin API or the management agent
+	configManager := NewConfigManager(config, *configPath, auditLog, uploadDestinations)
+
+	// Dual control: signover and routing changes made through the admin API wait for a second admin
+	approvals := NewApprovalService(&config.Admin, stationDB, auditLog)
+	if err := approvals.Initialize(ctx); err != nil {
+		return err
+	}
+	approvals.Register(ApprovalKindConfig, configManager.applyApproved)
+	approvals.Register(ApprovalKindDestinationPut, uploadDestinations.applyPut)
+	approvals.Register(ApprovalKindDestinationDelete, uploadDestinations.applyDelete)
+	approvals.Register(ApprovalKindRoutingImport, uploadDestinations.applyImport(auditLog))
+	approvals.Register(ApprovalKindResourcePut, configManager.applyResourcePut)
+	approvals.Register(ApprovalKindResourceDelete, configManager.applyResourceDelete)
+
+	// Central management agent (nil when disabled)
+	managementAgent, err := NewManagementAgent(&config.Management, buildInfo, configManager, uploadDestinations, ownerRevocations, auditLog)
+	if err != nil {
+		return err
+	}
+	go managementAgent.Run(ctx)
+
+	// Per-serial debug capture (nil when no serial patterns are configured)
+	debugCapture := NewDebugCapture(&config.DebugCapture)
+	cborDiag := NewCBORDiag(config, stationDB)
+
+	// Serial/model extraction for vendor-specific DI payloads (nil when no mappings are configured)
+	deviceInfoMapper, err := NewDeviceInfoMapper(&config.DeviceInfo)
+	if err != nil {
+		return err
+	}
+
 	// Create DI-only handler with minimal required components
 	handler := &transport.Handler{
 		Tokens: state,
 		DIResponder: &fdo.DIServer[custom.DeviceMfgInfo]{
-			Session:               state,
-			Vouchers:              state,
+			Session:               &guidReservingSession{DB: state, reservations: guidReservations, overrides: policyOverrides},
+			Vouchers:              dryRun.Vouchers(state),
 			SignDeviceCertificate: custom.SignDeviceCertificate(deviceCAKey, deviceCAChain),
 			DeviceInfo: func(ctx context.Context, info *custom.DeviceMfgInfo, chain []*x509.Certificate) (string, protocol.PublicKey, error) {
+				// Read the serial and model out of vendor-specific layouts before anything uses them
+				mapped := *info
+				mapped.SerialNumber, mapped.DeviceInfo = deviceInfoMapper.Map(info.SerialNumber, info.DeviceInfo)
+				mapped.SerialNumber, mapped.DeviceInfo = serialRules.Normalize(mapped.SerialNumber, mapped.DeviceInfo)
+				info = &mapped
+
+				// Start verbose capture if this serial is being debugged
+				debugCapture.Tag(ctx, info.SerialNumber)
+
+				// Refuse DI until a batch is open, if batches are required
+				if _, err := batchService.Current(ctx); err != nil {
+					return "", protocol.PublicKey{}, err
+				}
+
+				// Refuse manufacturer key types that don't pair with the configured voucher hash
+				if err := hashPolicy.CheckKeyType(info.KeyType); err != nil {
+					auditLog.Record(ctx, AuditEvent{Event: "di_rejected_hash", Serial: info.SerialNumber, Model: info.DeviceInfo, Detail: err.Error()})
+					return "", protocol.PublicKey{}, err
+				}
+
 				// Store full device info (including serial) in session for later use
 				if err := state.SetDeviceSelfInfo(ctx, info); err != nil {
 					return "", protocol.PublicKey{}, fmt.Errorf("failed to store device info: %w", err)
@@ -436,6 +1073,7 @@ func startDIServer(ctx context.Context, state *sqlite.DB) error {
 					// Use traditional database-stored manufacturer key
 					mfgKey, mfgChain, err := state.ManufacturerKey(ctx, info.KeyType, 3072)
 					if err != nil {
+						notifier.Critical("manufacturer_key", fmt.Sprintf("manufacturer key (%s) unavailable: %v", info.KeyType, err))
 						return "", protocol.PublicKey{}, err
 					}
 					encodedPubKey, err := encodePublicKey(info.KeyType, info.KeyEncoding, mfgKey.Public(), mfgChain)
@@ -453,106 +1091,15 @@ func startDIServer(ctx context.Context, state *sqlite.DB) error {
 			BeforeVoucherPersist: func(ctx context.Context, voucher *fdo.Voucher) error {
 				{
 					_, err := voucherCallbackService.BeforeVoucherPersist(ctx, state, voucher)
+					if err == nil {
+						guidReservations.Complete(ctx, voucher.Header.Val.GUID)
+					}
 					return err
 				}
 			},
 			AfterVoucherPersist: func(ctx context.Context, voucher fdo.Voucher) error { return nil },
 			RvInfo: func(ctx context.Context, voucher *fdo.Voucher) ([][]protocol.RvInstruction, error) {
-				// If no entries configured, return nil (no rendezvous info)
-				if len(config.Rendezvous.Entries) == 0 {
-					return nil, nil
-				}
-
-				// Convert each entry to protocol.RvInstruction format
-				var allDirectives [][]protocol.RvInstruction
-
-				for i, entry := range config.Rendezvous.Entries {
-					// Validate entry
-					if entry.Host == "" {
-						return nil, fmt.Errorf("rendezvous entry %d: host is required", i+1)
-					}
-					if entry.Port <= 0 || entry.Port > 65535 {
-						return nil, fmt.Errorf("rendezvous entry %d: invalid port: %d", i+1, entry.Port)
-					}
-					if entry.Scheme != "http" && entry.Scheme != "https" {
-						return nil, fmt.Errorf("rendezvous entry %d: scheme must be 'http' or 'https', got: %s", i+1, entry.Scheme)
-					}
-
-					// Convert to protocol.RvInstruction format
-					var rvInstructions []protocol.RvInstruction
-
-					// Determine if host is IP address or DNS name
-					if ip := net.ParseIP(entry.Host); ip != nil {
-						// It's an IP address - encode as CBOR byte array with 0x50 prefix
-						ipBytes := []byte(ip)
-						cborIP := make([]byte, 1+len(ipBytes))
-						cborIP[0] = 0x50 // CBOR byte array prefix
-						copy(cborIP[1:], ipBytes)
-						rvInstructions = append(rvInstructions, protocol.RvInstruction{
-							Variable: protocol.RVIPAddress,
-							Value:    cborIP, // CBOR byte array with 0x50 prefix
-						})
-					} else {
-						// It's a DNS name
-						rvInstructions = append(rvInstructions, protocol.RvInstruction{
-							Variable: protocol.RVDns,
-							Value:    []byte(entry.Host),
-						})
-					}
-
-					// Add port - use RVDevPort for device and encode as CBOR integer
-					var portBytes []byte
-					if entry.Port <= 23 {
-						// Single byte for small integers
-						portBytes = []byte{byte(entry.Port)}
-					} else if entry.Port <= 0xFF {
-						// Two bytes: major type 0, additional info 24, followed by value
-						portBytes = []byte{0x18, byte(entry.Port)}
-					} else if entry.Port <= 0xFFFF {
-						// Three bytes: major type 0, additional info 25, followed by 2-byte value
-						portBytes = []byte{0x19, byte(entry.Port >> 8), byte(entry.Port)}
-					} else {
-						// Four bytes: major type 0, additional info 26, followed by 4-byte value
-						portBytes = []byte{0x1A,
-							byte(entry.Port >> 24),
-							byte(entry.Port >> 16),
-							byte(entry.Port >> 8),
-							byte(entry.Port)}
-					}
-					rvInstructions = append(rvInstructions, protocol.RvInstruction{
-						Variable: protocol.RVDevPort, // Fix: Use RVDevPort (3) instead of RVOwnerPort (4)
-						Value:    portBytes,          // Fix: Proper CBOR integer encoding
-					})
-
-					// Add protocol - encode as CBOR unsigned integer, not ASCII string
-					var protocolValue int
-					if entry.Scheme == "http" {
-						protocolValue = 1 // HTTP (RVProtHTTP = 1)
-					} else {
-						protocolValue = 2 // HTTPS (RVProtHTTPS = 2)
-					}
-
-					// Encode protocol as CBOR unsigned integer
-					var protocolBytes []byte
-					if protocolValue <= 23 {
-						// Single byte for small integers
-						protocolBytes = []byte{byte(protocolValue)}
-					} else {
-						// For larger values (not needed for 2 or 3)
-						protocolBytes = []byte{0x18, byte(protocolValue)}
-					}
-
-					rvInstructions = append(rvInstructions, protocol.RvInstruction{
-						Variable: protocol.RVProtocol,
-						Value:    protocolBytes, // Fix: CBOR unsigned integer, not ASCII string
-					})
-
-					// Add this directive to the list
-					allDirectives = append(allDirectives, rvInstructions)
-				}
-
-				// Return all directives (array of arrays)
-				return allDirectives, nil
+				return rendezvousInfo(config.Rendezvous.Entries)
 			},
 		},
 		// Include empty TO0/TO1/TO2 responders to prevent panics, but they won't be used for DI
@@ -572,23 +1119,154 @@ func startDIServer(ctx context.Context, state *sqlite.DB) error {
 		},
 	}
 
+	// Report the effective configuration before the first device arrives
+	startupReport := NewStartupReport(ctx, config, buildInfo, uploadDestinations)
+	startupReport.Print()
+
 	// Set up HTTP server
 	mux := http.NewServeMux()
-	mux.Handle("POST /fdo/{fdoVer}/msg/{msg}", handler)
+	mux.Handle("POST /fdo/{fdoVer}/msg/{msg}", protocolGate.Middleware(diskMonitor.Middleware(backpressure.Middleware(debugCapture.Middleware(handler)))))
+	mux.Handle("GET /version", versionHandler(buildInfo))
+	// The admin API goes on the DI listener unless it has one of its own
+	adminMux := mux
+	if config.Server.Admin.Addr != "" {
+		adminMux = http.NewServeMux()
+		adminMux.Handle("GET /version", versionHandler(buildInfo))
+	}
+	if config.Admin.Enabled {
+		if config.Admin.Token == "" && len(config.Admin.Users) == 0 {
+			fmt.Printf("⚠️  Admin API is enabled without a token; restrict access to the station port\n")
+		}
+		adminMux.Handle("GET /api/openapi.json", openAPIHandler())
+		adminMux.Handle("GET /api/startup", adminAuth(&config.Admin, startupReport.Handler()))
+		adminMux.Handle("GET /api/health", adminAuth(&config.Admin, stationHealth.Handler()))
+		adminMux.Handle("GET /api/messages", adminAuth(&config.Admin, stationMessages.Handler()))
+		adminMux.Handle("GET /api/audit", adminAuth(&config.Admin, auditLog.Handler()))
+		adminMux.Handle("GET /api/audit/verify", adminAuth(&config.Admin, auditLog.VerifyHandler()))
+		adminMux.Handle("POST /api/audit/anchor", adminAuth(&config.Admin, auditAnchor.Handler()))
+		adminMux.Handle("GET /api/guids", adminAuth(&config.Admin, guidReservations.ListHandler()))
+		adminMux.Handle("POST /api/guids/reserve", adminAuth(&config.Admin, guidReservations.ReserveHandler()))
+		adminMux.Handle("POST /api/guids/{guid}/bind", adminAuth(&config.Admin, guidReservations.BindHandler()))
+		adminMux.Handle("DELETE /api/guids/{guid}", adminAuth(&config.Admin, guidReservations.ReleaseHandler()))
+		adminMux.Handle("GET /api/overrides", adminAuth(&config.Admin, policyOverrides.ListHandler()))
+		adminMux.Handle("POST /api/overrides", adminAuth(&config.Admin, policyOverrides.IssueHandler()))
+		adminMux.Handle("POST /api/overrides/apply", adminAuth(&config.Admin, policyOverrides.ApplyHandler()))
+		adminMux.Handle("DELETE /api/overrides/{id}", adminAuth(&config.Admin, policyOverrides.RevokeHandler()))
+		adminMux.Handle("GET /api/owner-keys", adminAuth(&config.Admin, ownerKeyProofs.ListHandler()))
+		adminMux.Handle("POST /api/owner-keys", adminAuth(&config.Admin, ownerKeyProofs.RegisterHandler()))
+		adminMux.Handle("POST /api/owner-keys/{id}/proof", adminAuth(&config.Admin, ownerKeyProofs.ProveHandler()))
+		adminMux.Handle("DELETE /api/owner-keys/{id}", adminAuth(&config.Admin, ownerKeyProofs.DeleteHandler()))
+		adminMux.Handle("GET /api/batches", adminAuth(&config.Admin, batchService.ListHandler()))
+		adminMux.Handle("POST /api/batches", adminAuth(&config.Admin, batchService.OpenHandler()))
+		adminMux.Handle("GET /api/batches/current", adminAuth(&config.Admin, batchService.CurrentHandler()))
+		adminMux.Handle("GET /api/batches/{id}", adminAuth(&config.Admin, batchService.GetHandler()))
+		adminMux.Handle("POST /api/batches/{id}/close", adminAuth(&config.Admin, batchService.CloseHandler()))
+		adminMux.Handle("GET /api/batches/{id}/vouchers", adminAuth(&config.Admin, batchService.BatchVouchersHandler()))
+		adminMux.Handle("GET /api/lots/{lot}", adminAuth(&config.Admin, batchService.LotHandler()))
+		adminMux.Handle("GET /api/lots/{lot}/vouchers", adminAuth(&config.Admin, batchService.LotVouchersHandler()))
+		adminMux.Handle("GET /api/vouchers", adminAuth(&config.Admin, batchService.VouchersHandler()))
+		adminMux.Handle("GET /api/vouchers/{guid}/diag", adminAuth(&config.Admin, cborDiag.VoucherHandler()))
+		adminMux.Handle("GET /api/vouchers/{guid}/chain", adminAuth(&config.Admin, cborDiag.ChainHandler()))
+		adminMux.Handle("POST /api/vouchers/{guid}/diff", adminAuth(&config.Admin, cborDiag.DiffHandler()))
+		adminMux.Handle("GET /api/vouchers/{guid}/check", adminAuth(&config.Admin, cborDiag.CheckHandler()))
+		adminMux.Handle("GET /api/vouchers/{guid}/label", adminAuth(&config.Admin, claimURLs.LabelHandler()))
+		adminMux.Handle("GET /api/captures/{file}/diag", adminAuth(&config.Admin, cborDiag.CaptureHandler()))
+		adminMux.Handle("GET /api/uploads", adminAuth(&config.Admin, uploadReceipts.ListHandler()))
+		adminMux.Handle("GET /api/quotas", adminAuth(&config.Admin, quotaService.StatusHandler()))
+		adminMux.Handle("POST /api/quotas/{name}/override", adminAuth(&config.Admin, quotaService.OverrideHandler()))
+		adminMux.Handle("GET /api/destinations", adminAuth(&config.Admin, uploadDestinations.ListHandler()))
+		adminMux.Handle("POST /api/destinations", adminAuth(&config.Admin, approvals.Gate(ApprovalKindDestinationPut, uploadDestinations.describePut, uploadDestinations.PutHandler())))
+		adminMux.Handle("GET /api/destinations/{name}", adminAuth(&config.Admin, uploadDestinations.GetHandler()))
+		adminMux.Handle("PUT /api/destinations/{name}", adminAuth(&config.Admin, approvals.Gate(ApprovalKindDestinationPut, uploadDestinations.describePut, uploadDestinations.PutHandler())))
+		adminMux.Handle("DELETE /api/destinations/{name}", adminAuth(&config.Admin, approvals.Gate(ApprovalKindDestinationDelete, uploadDestinations.describeDelete, uploadDestinations.DeleteHandler())))
+		adminMux.Handle("POST /api/destinations/{name}/reset", adminAuth(&config.Admin, uploadDestinations.ResetHandler()))
+		adminMux.Handle("GET /api/routing", adminAuth(&config.Admin, uploadDestinations.ExportHandler()))
+		adminMux.Handle("PUT /api/routing", adminAuth(&config.Admin, approvals.Gate(ApprovalKindRoutingImport, uploadDestinations.describeImport, uploadDestinations.ImportHandler(auditLog))))
+		adminMux.Handle("POST /api/routing/diff", adminAuth(&config.Admin, uploadDestinations.DiffHandler()))
+		adminMux.Handle("POST /api/config/diff", adminAuth(&config.Admin, configManager.DiffHandler()))
+		adminMux.Handle("POST /api/config/apply", adminAuth(&config.Admin, approvals.Gate(ApprovalKindConfig, configManager.describeApply, configManager.ApplyHandler())))
+		for _, res := range provisionedResources {
+			adminMux.Handle("GET /api/"+res.kind, adminAuth(&config.Admin, configManager.ResourceListHandler(res)))
+			adminMux.Handle("GET /api/"+res.kind+"/{name}", adminAuth(&config.Admin, configManager.ResourceGetHandler(res)))
+			adminMux.Handle("PUT /api/"+res.kind+"/{name}", adminAuth(&config.Admin, approvals.Gate(ApprovalKindResourcePut, configManager.describeResourcePut(res), configManager.ResourcePutHandler(res))))
+			adminMux.Handle("DELETE /api/"+res.kind+"/{name}", adminAuth(&config.Admin, approvals.Gate(ApprovalKindResourceDelete, configManager.describeResourceDelete(res), configManager.ResourceDeleteHandler(res))))
+		}
+		adminMux.Handle("GET /api/approvals", adminAuth(&config.Admin, approvals.ListHandler()))
+		adminMux.Handle("GET /api/approvals/{id}", adminAuth(&config.Admin, approvals.GetHandler()))
+		adminMux.Handle("POST /api/approvals/{id}/approve", adminAuth(&config.Admin, approvals.ApproveHandler()))
+		adminMux.Handle("POST /api/approvals/{id}/reject", adminAuth(&config.Admin, approvals.RejectHandler()))
+		adminMux.Handle("GET /api/executors", adminAuth(&config.Admin, commandPools.Handler()))
+		adminMux.Handle("GET /api/executors/invocations", adminAuth(&config.Admin, commandLog.ListHandler()))
+		adminMux.Handle("GET /api/executors/plugins", adminAuth(&config.Admin, commandPlugins.Handler()))
+		adminMux.Handle("GET /api/disk", adminAuth(&config.Admin, diskMonitor.Handler()))
+		adminMux.Handle("GET /api/backpressure", adminAuth(&config.Admin, backpressure.Handler()))
+		adminMux.Handle("GET /api/integrity", adminAuth(&config.Admin, voucherIntegrity.Handler()))
+		adminMux.Handle("GET /api/gc", adminAuth(&config.Admin, stationGC.Handler()))
+		adminMux.Handle("POST /api/gc", adminAuth(&config.Admin, stationGC.Handler()))
+		adminMux.Handle("GET /api/metrics", adminAuth(&config.Admin, NewMetrics(stationStatus, quotaService, uploadDestinations.Throttle()).Handler()))
+		adminMux.Handle("GET /api/signover/targets", adminAuth(&config.Admin, signoverAnomalies.ListHandler()))
+		adminMux.Handle("GET /api/did/pins", adminAuth(&config.Admin, didPins.ListHandler()))
+		adminMux.Handle("PUT /api/did/pins/{did}", adminAuth(&config.Admin, didPins.PinHandler()))
+		adminMux.Handle("DELETE /api/did/pins/{did}", adminAuth(&config.Admin, didPins.UnpinHandler()))
+		adminMux.Handle("GET /api/did/cache/{did}", adminAuth(&config.Admin, didCache.CacheStatusHandler()))
+		adminMux.Handle("DELETE /api/did/cache/{did}", adminAuth(&config.Admin, didCache.EvictHandler()))
+		adminMux.Handle("POST /api/did/cache/purge", adminAuth(&config.Admin, didCache.PurgeHandler()))
+		adminMux.Handle("GET /api/standby/snapshot/{db}", adminAuth(&config.Admin, standbySnapshots.Handler()))
+		adminMux.Handle("GET /api/transfer/export", adminAuth(&config.Admin, voucherTransfers.ExportHandler()))
+		adminMux.Handle("POST /api/transfer/import", adminAuth(&config.Admin, voucherTransfers.ImportHandler()))
+		if config.Admin.GraphQL {
+			graphQL := NewGraphQLService(batchService, auditLog, uploadReceipts, uploadDestinations).Handler()
+			adminMux.Handle("GET /api/graphql", adminAuth(&config.Admin, graphQL))
+			adminMux.Handle("POST /api/graphql", adminAuth(&config.Admin, graphQL))
+		}
+	}
 
-	srv := &http.Server{
-		Addr:              config.Server.Addr,
-		Handler:           mux,
-		ReadHeaderTimeout: 3 * time.Second,
+	srv, err := diServer(&config.Server, adminHTTP(&config.Admin.HTTP, mux))
+	if err != nil {
+		return err
+	}
+	adminSrv, err := adminServer(&config.Server, adminHTTP(&config.Admin.HTTP, adminMux))
+	if err != nil {
+		return err
+	}
+	var grpcSrv *grpc.Server
+	if config.Admin.Enabled {
+		grpcSrv, err = adminGRPCServer(&config.Server, adminHTTP(&config.Admin.HTTP, adminMux))
+		if err != nil {
+			return err
+		}
 	}
 
 	// Listen and serve
 	lis, err := net.Listen("tcp", config.Server.Addr)
-	fmt.Printf("DEBUG: Config loaded: %+v\n", config)
 	if err != nil {
 		return fmt.Errorf("error listening on %s: %w", config.Server.Addr, err)
 	}
 	defer func() { _ = lis.Close() }()
+	var adminLis net.Listener
+	if adminSrv != nil {
+		adminLis, err = net.Listen("tcp", config.Server.Admin.Addr)
+		if err != nil {
+			return fmt.Errorf("error listening on %s: %w", config.Server.Admin.Addr, err)
+		}
+		defer func() { _ = adminLis.Close() }()
+	}
+	var grpcLis net.Listener
+	if grpcSrv != nil {
+		grpcLis, err = net.Listen("tcp", config.Server.Admin.GRPCAddr)
+		if err != nil {
+			return fmt.Errorf("error listening on %s: %w", config.Server.Admin.GRPCAddr, err)
+		}
+		defer func() { _ = grpcLis.Close() }()
+	}
+
+	// Every port is bound; a station started as root continues as privileges.user
+	if err := dropPrivileges(config); err != nil {
+		return fmt.Errorf("failed to drop privileges: %w", err)
+	}
+	if err := checkWritable(config); err != nil {
+		return err
+	}
 
 	fmt.Printf("🔍 DEBUG: About to start server on %s\n", lis.Addr().String())
 	slog.Info("FDO Manufacturing Station starting",
@@ -596,24 +1274,44 @@ func startDIServer(ctx context.Context, state *sqlite.DB) error {
 		"external", extAddr,
 		"mode", "DI-only")
 	fmt.Printf("🔍 DEBUG: Server started successfully\n")
+	if adminSrv != nil {
+		fmt.Printf("🔐 DI on %s://%s, admin API on %s://%s\n", listenerScheme(srv), lis.Addr(), listenerScheme(adminSrv), adminLis.Addr())
+	}
+	if grpcSrv != nil {
+		fmt.Printf("🔐 Admin API over gRPC on %s\n", grpcLis.Addr())
+	}
 
-	// Start server in goroutine to monitor context cancellation
-	errChan := make(chan error, 1)
+	// Start servers in goroutines to monitor context cancellation
+	errChan := make(chan error, 3)
 	go func() {
-		if config.Server.InsecureTLS {
-			// TODO: Implement TLS support
-			errChan <- srv.Serve(lis)
-		} else {
-			errChan <- srv.Serve(lis)
-		}
+		errChan <- serve(srv, lis)
 	}()
+	if adminSrv != nil {
+		go func() {
+			errChan <- serve(adminSrv, adminLis)
+		}()
+	}
+	if grpcSrv != nil {
+		go func() {
+			errChan <- grpcSrv.Serve(grpcLis)
+		}()
+	}
 
 	// Wait for context cancellation or server error
 	select {
 	case <-ctx.Done():
 		slog.Info("Shutting down manufacturing station...")
+		stationStatus.SetDraining()
 		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer shutdownCancel()
+		if adminSrv != nil {
+			if err := adminSrv.Shutdown(shutdownCtx); err != nil {
+				slog.Error("Admin server shutdown error", "error", err)
+			}
+		}
+		if grpcSrv != nil {
+			grpcSrv.GracefulStop()
+		}
 		if err := srv.Shutdown(shutdownCtx); err != nil {
 			slog.Error("Server shutdown error", "error", err)
 			return err
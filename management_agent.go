@@ -0,0 +1,365 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// ManagementBundle is what the control plane serves at <url>/bundle. The body
+// is signed; the base64 signature over the raw body is sent in X-FDO-Signature.
+type ManagementBundle struct {
+	Version     string                     `json:"version"`
+	Config      string                     `json:"config,omitempty"`      // Partial config document (YAML or JSON)
+	Routes      []UploadDestinationRequest `json:"routes,omitempty"`      // Upload destinations to create or update
+	Revocations *RevocationList            `json:"revocations,omitempty"` // Replaces the revocation list when present
+}
+
+// ManagementStatus is reported to the control plane at <url>/status after every sync
+type ManagementStatus struct {
+	BuildInfo
+	BundleVersion string               `json:"bundle_version"`
+	LastSync      time.Time            `json:"last_sync"`
+	LastError     string               `json:"last_error,omitempty"`
+	Destinations  []*UploadDestination `json:"destinations"`
+	Revocations   RevocationList       `json:"revocations"`
+}
+
+// ManagementAgent polls a central management URL for signed config, upload
+// routing and owner revocations. A bundle is applied completely or not at all:
+// everything is validated first, and the routing and revocation changes are
+// rolled back if the config can't be applied.
+type ManagementAgent struct {
+	config        *ManagementConfig
+	buildInfo     BuildInfo
+	configManager *ConfigManager
+	destinations  *UploadDestinationCatalog
+	revocations   *OwnerRevocations
+	auditLog      *AuditLog
+	client        *http.Client
+	publicKey     crypto.PublicKey
+
+	bundleVersion string
+	lastSync      time.Time
+	lastError     string
+}
+
+// NewManagementAgent creates the management agent, or returns nil if it is disabled
+func NewManagementAgent(config *ManagementConfig, buildInfo BuildInfo, configManager *ConfigManager, destinations *UploadDestinationCatalog, revocations *OwnerRevocations, auditLog *AuditLog) (*ManagementAgent, error) {
+	if !config.Enabled {
+		return nil, nil
+	}
+	if config.URL == "" {
+		return nil, fmt.Errorf("management agent enabled but no url configured")
+	}
+	if config.PublicKeyFile == "" {
+		return nil, fmt.Errorf("management agent enabled but no public_key_file configured to verify bundles")
+	}
+	pemData, err := os.ReadFile(config.PublicKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read management public key: %w", err)
+	}
+	publicKey, err := parseStaticPublicKey(string(pemData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse management public key: %w", err)
+	}
+
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	return &ManagementAgent{
+		config:        config,
+		buildInfo:     buildInfo,
+		configManager: configManager,
+		destinations:  destinations,
+		revocations:   revocations,
+		auditLog:      auditLog,
+		client:        &http.Client{Timeout: timeout},
+		publicKey:     publicKey,
+	}, nil
+}
+
+// Run syncs with the control plane every interval until ctx is cancelled
+func (a *ManagementAgent) Run(ctx context.Context) {
+	if a == nil {
+		return
+	}
+	interval := a.config.Interval
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		a.Sync(ctx)
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Sync pulls and applies the current bundle, then reports status upstream
+func (a *ManagementAgent) Sync(ctx context.Context) {
+	a.lastSync = time.Now()
+	if err := a.pull(ctx); err != nil {
+		a.lastError = err.Error()
+		fmt.Printf("⚠️  Management sync failed: %v\n", err)
+	} else {
+		a.lastError = ""
+	}
+	if err := a.report(ctx); err != nil {
+		fmt.Printf("⚠️  Management status report failed: %v\n", err)
+	}
+}
+
+// pull fetches, verifies and applies the bundle if its version changed
+func (a *ManagementAgent) pull(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.endpoint("bundle"), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create bundle request: %w", err)
+	}
+	a.setHeaders(req)
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("bundle request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4*1024*1024))
+	if err != nil {
+		return fmt.Errorf("failed to read bundle: %w", err)
+	}
+	if resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusNotModified {
+		return nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("control plane returned HTTP %d: %s", resp.StatusCode, string(body))
+	}
+
+	if err := verifyBundleSignature(a.publicKey, body, resp.Header.Get("X-FDO-Signature")); err != nil {
+		return err
+	}
+	var bundle ManagementBundle
+	if err := json.Unmarshal(body, &bundle); err != nil {
+		return fmt.Errorf("invalid management bundle: %w", err)
+	}
+	if bundle.Version != "" && bundle.Version == a.bundleVersion {
+		return nil
+	}
+
+	if err := a.apply(ctx, &bundle); err != nil {
+		a.auditLog.Record(ctx, AuditEvent{
+			Event:  "management_bundle_rejected",
+			Detail: fmt.Sprintf("version %s: %v", bundle.Version, err),
+		})
+		return fmt.Errorf("bundle %s not applied: %w", bundle.Version, err)
+	}
+	a.bundleVersion = bundle.Version
+	a.auditLog.Record(ctx, AuditEvent{
+		Event:  "management_bundle_applied",
+		Detail: fmt.Sprintf("version %s: %d routes, config %v, revocations %v", bundle.Version, len(bundle.Routes), bundle.Config != "", bundle.Revocations != nil),
+	})
+	fmt.Printf("🛰️  Applied management bundle %s\n", bundle.Version)
+	return nil
+}
+
+// apply validates the whole bundle, then applies revocations, routes and config,
+// restoring the previous revocations and routes if a later step fails
+func (a *ManagementAgent) apply(ctx context.Context, bundle *ManagementBundle) (err error) {
+	// Validate everything before changing anything
+	if bundle.Config != "" {
+		if _, err := a.configManager.Diff(ctx, []byte(bundle.Config)); err != nil {
+			return err
+		}
+	}
+	if len(bundle.Routes) > 0 && a.destinations == nil {
+		return fmt.Errorf("routes require voucher_upload.mode http")
+	}
+	for i := range bundle.Routes {
+		if err := a.destinations.validate(&bundle.Routes[i]); err != nil {
+			return fmt.Errorf("route %q: %w", bundle.Routes[i].Name, err)
+		}
+	}
+
+	var rollback []func()
+	defer func() {
+		if err != nil {
+			for i := len(rollback) - 1; i >= 0; i-- {
+				rollback[i]()
+			}
+		}
+	}()
+
+	if bundle.Revocations != nil {
+		previous := a.revocations.List()
+		if err := a.revocations.Replace(ctx, *bundle.Revocations); err != nil {
+			return err
+		}
+		rollback = append(rollback, func() {
+			if err := a.revocations.Replace(context.Background(), previous); err != nil {
+				fmt.Printf("⚠️  Failed to roll back owner revocations: %v\n", err)
+			}
+		})
+	}
+
+	for i := range bundle.Routes {
+		route := &bundle.Routes[i]
+		previous, err := a.destinations.Get(ctx, route.Name)
+		if err != nil {
+			return err
+		}
+		if _, err := a.destinations.Put(ctx, route); err != nil {
+			return err
+		}
+		rollback = append(rollback, func() { a.restoreDestination(route.Name, previous) })
+	}
+
+	if bundle.Config != "" {
+		if _, err := a.configManager.Apply(ctx, []byte(bundle.Config)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restoreDestination puts a destination back the way it was before a failed bundle
+func (a *ManagementAgent) restoreDestination(name string, previous *UploadDestination) {
+	ctx := context.Background()
+	var err error
+	if previous == nil {
+		_, err = a.destinations.Delete(ctx, name)
+	} else {
+		enabled := previous.Enabled
+		_, err = a.destinations.Put(ctx, &UploadDestinationRequest{
+			Name:        previous.Name,
+			URL:         previous.URL,
+			AuthProfile: previous.AuthProfile,
+			Owner:       previous.Owner,
+			Enabled:     &enabled,
+		})
+	}
+	if err != nil {
+		fmt.Printf("⚠️  Failed to roll back upload destination %q: %v\n", name, err)
+	}
+}
+
+// report posts the station status to the control plane
+func (a *ManagementAgent) report(ctx context.Context) error {
+	destinations, err := a.destinations.List(ctx)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(ManagementStatus{
+		BuildInfo:     a.buildInfo,
+		BundleVersion: a.bundleVersion,
+		LastSync:      a.lastSync,
+		LastError:     a.lastError,
+		Destinations:  destinations,
+		Revocations:   a.revocations.List(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode status: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.endpoint("status"), bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create status request: %w", err)
+	}
+	a.setHeaders(req)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("status request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("control plane returned HTTP %d for status", resp.StatusCode)
+	}
+	return nil
+}
+
+// endpoint returns the URL of a control plane resource
+func (a *ManagementAgent) endpoint(resource string) string {
+	return strings.TrimSuffix(a.config.URL, "/") + "/" + resource
+}
+
+// setHeaders identifies the station to the control plane
+func (a *ManagementAgent) setHeaders(req *http.Request) {
+	req.Header.Set("X-FDO-Client-ID", a.buildInfo.StationID)
+	req.Header.Set("X-FDO-Instance-ID", a.buildInfo.InstanceID)
+	if a.bundleVersion != "" {
+		req.Header.Set("If-None-Match", a.bundleVersion)
+	}
+	if a.config.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+a.config.Token)
+	}
+}
+
+// signBundle returns the base64 signature over a bundle body that
+// verifyBundleSignature checks
+func signBundle(signer crypto.Signer, body []byte) (string, error) {
+	var sig []byte
+	var err error
+	if _, ok := signer.Public().(ed25519.PublicKey); ok {
+		sig, err = signer.Sign(rand.Reader, body, crypto.Hash(0))
+	} else {
+		digest := sha256.Sum256(body)
+		sig, err = signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to sign bundle: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(sig), nil
+}
+
+// verifyBundleSignature checks the base64 signature over the bundle body
+// (ECDSA or RSA PKCS#1 v1.5 over SHA-256, or Ed25519)
+func verifyBundleSignature(publicKey crypto.PublicKey, body []byte, signature string) error {
+	if signature == "" {
+		return fmt.Errorf("bundle is not signed")
+	}
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("invalid bundle signature encoding: %w", err)
+	}
+	digest := sha256.Sum256(body)
+
+	var ok bool
+	switch key := publicKey.(type) {
+	case *ecdsa.PublicKey:
+		ok = ecdsa.VerifyASN1(key, digest[:], sig)
+	case *rsa.PublicKey:
+		ok = rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig) == nil
+	case ed25519.PublicKey:
+		ok = ed25519.Verify(key, body, sig)
+	default:
+		return fmt.Errorf("unsupported bundle public key type %T", publicKey)
+	}
+	if !ok {
+		return errors.New("bundle signature verification failed")
+	}
+	return nil
+}
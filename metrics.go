@@ -0,0 +1,139 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Metrics serves the station status in the Prometheus text exposition format.
+// DI throughput, failures and pipeline latency are labeled by customer
+// (tenant), upload auth profile and model, and every quota is exported with
+// its customer and model, so a dashboard can break line performance down per
+// product instead of showing station-wide totals only. Models are shown as
+// in logs, so serial_rules.pseudonymize applies.
+type Metrics struct {
+	status   *StationStatus
+	quotas   *QuotaService
+	throttle *UploadThrottle // nil = uploads not rate limited
+}
+
+// NewMetrics creates the metrics endpoint
+func NewMetrics(status *StationStatus, quotas *QuotaService, throttle *UploadThrottle) *Metrics {
+	return &Metrics{status: status, quotas: quotas, throttle: throttle}
+}
+
+// Handler serves GET /api/metrics
+func (m *Metrics) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var buf bytes.Buffer
+		snapshot := m.status.Snapshot(r.Context())
+		up := 0
+		if snapshot.Up {
+			up = 1
+		}
+		writeMetric(&buf, "fdo_station_up", "gauge", "1 while the station accepts DI, 0 while it shuts down")
+		fmt.Fprintf(&buf, "fdo_station_up %d\n", up)
+		writeMetric(&buf, "fdo_voucher_queue_depth", "gauge", "Vouchers waiting for batch upload")
+		fmt.Fprintf(&buf, "fdo_voucher_queue_depth %d\n", snapshot.QueueDepth)
+		writeMetric(&buf, "fdo_signover_anomalies_total", "counter", "Vouchers extended to a never-before-seen owner key")
+		fmt.Fprintf(&buf, "fdo_signover_anomalies_total %d\n", snapshot.Anomalies)
+		writeMetric(&buf, "fdo_vouchers_not_extended_total", "counter", "Vouchers delivered without owner signover (owner_signover mode none)")
+		fmt.Fprintf(&buf, "fdo_vouchers_not_extended_total %d\n", snapshot.Unextended)
+
+		products := m.status.Products()
+		labels := make([]ProductLabels, 0, len(products))
+		for l := range products {
+			labels = append(labels, l)
+		}
+		slices.SortFunc(labels, func(a, b ProductLabels) int {
+			return strings.Compare(a.Customer+"\x00"+a.Profile+"\x00"+a.Model, b.Customer+"\x00"+b.Profile+"\x00"+b.Model)
+		})
+
+		writeMetric(&buf, "fdo_di_sessions_total", "counter", "DI sessions whose voucher pipeline finished, by result")
+		for _, l := range labels {
+			p := products[l]
+			fmt.Fprintf(&buf, "fdo_di_sessions_total{%s,result=\"completed\"} %d\n", productLabelSet(l), p.Completed)
+			fmt.Fprintf(&buf, "fdo_di_sessions_total{%s,result=\"failed\"} %d\n", productLabelSet(l), p.Failed)
+		}
+		writeMetric(&buf, "fdo_di_pipeline_seconds", "histogram", "Time from DI voucher callback to persistence decision")
+		for _, l := range labels {
+			p := products[l]
+			var cumulative uint64
+			for i, bound := range latencyBuckets {
+				cumulative += p.Latency[i]
+				fmt.Fprintf(&buf, "fdo_di_pipeline_seconds_bucket{%s,le=\"%s\"} %d\n", productLabelSet(l), strconv.FormatFloat(bound, 'g', -1, 64), cumulative)
+			}
+			cumulative += p.Latency[len(latencyBuckets)]
+			fmt.Fprintf(&buf, "fdo_di_pipeline_seconds_bucket{%s,le=\"+Inf\"} %d\n", productLabelSet(l), cumulative)
+			fmt.Fprintf(&buf, "fdo_di_pipeline_seconds_sum{%s} %s\n", productLabelSet(l), strconv.FormatFloat(p.Seconds, 'g', -1, 64))
+			fmt.Fprintf(&buf, "fdo_di_pipeline_seconds_count{%s} %d\n", productLabelSet(l), cumulative)
+		}
+
+		statuses, err := m.quotas.Status(r.Context())
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		for _, metric := range []struct {
+			name, help string
+			value      func(QuotaStatus) int
+		}{
+			{"fdo_quota_used", "Units counted against the quota in its current period", func(s QuotaStatus) int { return s.Used }},
+			{"fdo_quota_limit", "Quota limit including override units", func(s QuotaStatus) int { return s.Limit + s.Extra }},
+			{"fdo_quota_remaining", "Units left in the current period; negative when a soft quota is exceeded", func(s QuotaStatus) int { return s.Remaining }},
+		} {
+			writeMetric(&buf, metric.name, "gauge", metric.help)
+			for _, s := range statuses {
+				fmt.Fprintf(&buf, "%s{quota=%s,customer=%s,model=%s,period=%s} %d\n", metric.name,
+					metricLabel(s.Name), metricLabel(s.Customer), metricLabel(serialRules.Model(s.Model)), metricLabel(s.Period), metric.value(s))
+			}
+		}
+
+		throttled := m.throttle.Stats()
+		for _, metric := range []struct {
+			name, kind, help string
+			value            func(ThrottleStats) string
+		}{
+			{"fdo_upload_throttled_total", "counter", "Uploads that waited for their turn under the destination's rate limit",
+				func(s ThrottleStats) string { return strconv.FormatUint(s.Delayed, 10) }},
+			{"fdo_upload_throttle_rejected_total", "counter", "Uploads refused because their turn was more than max_wait away",
+				func(s ThrottleStats) string { return strconv.FormatUint(s.Rejected, 10) }},
+			{"fdo_upload_throttle_wait_seconds_total", "counter", "Time uploads spent waiting under the rate limit",
+				func(s ThrottleStats) string { return strconv.FormatFloat(s.WaitSeconds, 'g', -1, 64) }},
+			{"fdo_upload_throttle_waiting", "gauge", "Uploads waiting for their turn now",
+				func(s ThrottleStats) string { return strconv.Itoa(s.Waiting) }},
+		} {
+			writeMetric(&buf, metric.name, metric.kind, metric.help)
+			for _, s := range throttled {
+				fmt.Fprintf(&buf, "%s{destination=%s} %s\n", metric.name, metricLabel(s.Destination), metric.value(s))
+			}
+		}
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_, _ = w.Write(buf.Bytes())
+	})
+}
+
+// writeMetric writes the HELP and TYPE lines of a metric family
+func writeMetric(buf *bytes.Buffer, name, kind, help string) {
+	fmt.Fprintf(buf, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// productLabelSet formats the per-product labels of a sample
+func productLabelSet(l ProductLabels) string {
+	return fmt.Sprintf("customer=%s,profile=%s,model=%s", metricLabel(l.Customer), metricLabel(l.Profile), metricLabel(serialRules.Model(l.Model)))
+}
+
+// metricLabel quotes a label value, escaping backslashes, quotes and newlines
+func metricLabel(value string) string {
+	value = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+	return `"` + value + `"`
+}
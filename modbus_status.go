@@ -0,0 +1,178 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// Modbus/TCP register map of the station status. The same values are served
+// as holding registers (function 3) and input registers (function 4).
+// 32-bit values take two registers, high word first.
+const (
+	ModbusRegUp              = 0 // 1 while the station accepts DI, 0 while it shuts down
+	ModbusRegDevicesLastHour = 1 // Devices built in the last hour
+	ModbusRegErrors          = 2 // DI failures since start (32-bit, registers 2-3)
+	ModbusRegQueueDepth      = 4 // Vouchers waiting for batch upload
+	ModbusRegDevicesTotal    = 5 // Devices built since start (32-bit, registers 5-6)
+	ModbusRegAnomalies       = 7 // Signover anomalies since start (32-bit, registers 7-8)
+	modbusRegisterCount      = 9
+)
+
+// Modbus function and exception codes
+const (
+	modbusReadHoldingRegisters = 0x03
+	modbusReadInputRegisters   = 0x04
+	modbusIllegalFunction      = 0x01
+	modbusIllegalDataAddress   = 0x02
+	modbusIllegalDataValue     = 0x03
+	modbusMaxReadRegisters     = 125
+	modbusIdleTimeout          = 2 * time.Minute
+)
+
+// ModbusStatusServer answers Modbus/TCP register reads with the station status,
+// for andon systems and PLCs that can't consume HTTP
+type ModbusStatusServer struct {
+	config *ModbusConfig
+	status *StationStatus
+}
+
+// NewModbusStatusServer creates the Modbus status server, or returns nil if it is disabled
+func NewModbusStatusServer(config *ModbusConfig, status *StationStatus) *ModbusStatusServer {
+	if !config.Enabled {
+		return nil
+	}
+	return &ModbusStatusServer{config: config, status: status}
+}
+
+// Listen binds the Modbus port. It is separate from Run so a station started
+// as root can bind port 502 before it drops privileges.
+func (m *ModbusStatusServer) Listen() (net.Listener, error) {
+	if m == nil {
+		return nil, nil
+	}
+	addr := m.config.Addr
+	if addr == "" {
+		addr = ":1502"
+	}
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for Modbus on %s: %w", addr, err)
+	}
+	fmt.Printf("📟 Modbus/TCP status registers on %s\n", lis.Addr())
+	return lis, nil
+}
+
+// Run serves lis until ctx is done
+func (m *ModbusStatusServer) Run(ctx context.Context, lis net.Listener) error {
+	if m == nil {
+		return nil
+	}
+	go func() {
+		<-ctx.Done()
+		_ = lis.Close()
+	}()
+
+	for {
+		conn, err := lis.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("modbus accept failed: %w", err)
+		}
+		go m.serve(ctx, conn)
+	}
+}
+
+// serve answers requests on one connection until the client hangs up or goes idle
+func (m *ModbusStatusServer) serve(ctx context.Context, conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+
+	header := make([]byte, 7)
+	for {
+		_ = conn.SetReadDeadline(time.Now().Add(modbusIdleTimeout))
+		// MBAP header: transaction ID, protocol ID (0), length, unit ID
+		if _, err := io.ReadFull(conn, header); err != nil {
+			if !errors.Is(err, io.EOF) && ctx.Err() == nil {
+				fmt.Printf("⚠️  Modbus connection from %s closed: %v\n", conn.RemoteAddr(), err)
+			}
+			return
+		}
+		length := binary.BigEndian.Uint16(header[4:6])
+		if binary.BigEndian.Uint16(header[2:4]) != 0 || length < 2 || length > 254 {
+			fmt.Printf("⚠️  Modbus connection from %s sent an invalid frame\n", conn.RemoteAddr())
+			return
+		}
+		pdu := make([]byte, length-1)
+		if _, err := io.ReadFull(conn, pdu); err != nil {
+			return
+		}
+		unit := header[6]
+		if m.config.UnitID != 0 && unit != m.config.UnitID {
+			continue // Addressed to another unit behind the same gateway
+		}
+
+		response := m.handle(ctx, pdu)
+		frame := make([]byte, 7, 7+len(response))
+		copy(frame, header[:4])
+		binary.BigEndian.PutUint16(frame[4:6], uint16(len(response)+1))
+		frame[6] = unit
+		if _, err := conn.Write(append(frame, response...)); err != nil {
+			return
+		}
+	}
+}
+
+// handle answers one request PDU
+func (m *ModbusStatusServer) handle(ctx context.Context, pdu []byte) []byte {
+	function := pdu[0]
+	if function != modbusReadHoldingRegisters && function != modbusReadInputRegisters {
+		return []byte{function | 0x80, modbusIllegalFunction}
+	}
+	if len(pdu) != 5 {
+		return []byte{function | 0x80, modbusIllegalDataValue}
+	}
+	start := int(binary.BigEndian.Uint16(pdu[1:3]))
+	count := int(binary.BigEndian.Uint16(pdu[3:5]))
+	if count < 1 || count > modbusMaxReadRegisters {
+		return []byte{function | 0x80, modbusIllegalDataValue}
+	}
+	if start+count > modbusRegisterCount {
+		return []byte{function | 0x80, modbusIllegalDataAddress}
+	}
+
+	registers := modbusRegisters(m.status.Snapshot(ctx))
+	response := []byte{function, byte(2 * count)}
+	for _, value := range registers[start : start+count] {
+		response = binary.BigEndian.AppendUint16(response, value)
+	}
+	return response
+}
+
+// modbusRegisters lays out a status snapshot in the register map
+func modbusRegisters(s StatusSnapshot) []uint16 {
+	registers := make([]uint16, modbusRegisterCount)
+	if s.Up {
+		registers[ModbusRegUp] = 1
+	}
+	registers[ModbusRegDevicesLastHour] = uint16(min(s.DevicesLastHour, 0xffff))
+	registers[ModbusRegErrors] = uint16(s.Errors >> 16)
+	registers[ModbusRegErrors+1] = uint16(s.Errors)
+	registers[ModbusRegQueueDepth] = uint16(min(s.QueueDepth, 0xffff))
+	registers[ModbusRegDevicesTotal] = uint16(s.DevicesTotal >> 16)
+	registers[ModbusRegDevicesTotal+1] = uint16(s.DevicesTotal)
+	registers[ModbusRegAnomalies] = uint16(s.Anomalies >> 16)
+	registers[ModbusRegAnomalies+1] = uint16(s.Anomalies)
+	return registers
+}
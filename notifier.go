@@ -0,0 +1,246 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Notifier emails operators about critical conditions (manufacturer key unavailable,
+// uploads failing, owner DIDs not resolving). Events are aggregated for
+// aggregate_window and each condition is throttled so a stuck line pages once, not
+// once per device. A nil *Notifier is valid and only logs.
+type Notifier struct {
+	config    *NotificationConfig
+	stationID string
+
+	mu       sync.Mutex
+	pending  map[string]*notifyEvent // keyed by condition
+	lastSent map[string]time.Time
+	failures map[string]int // consecutive failures per condition
+}
+
+// notifyEvent aggregates occurrences of one condition between emails
+type notifyEvent struct {
+	message     string // most recent message
+	count       int
+	first, last time.Time
+}
+
+// NewNotifier creates a notifier, or returns nil if SMTP notifications are disabled
+func NewNotifier(config *NotificationConfig, stationID string) *Notifier {
+	if !config.SMTP.Enabled {
+		return nil
+	}
+	return &Notifier{
+		config:    config,
+		stationID: stationID,
+		pending:   make(map[string]*notifyEvent),
+		lastSent:  make(map[string]time.Time),
+		failures:  make(map[string]int),
+	}
+}
+
+// Critical records a critical condition to be included in the next notification email
+func (n *Notifier) Critical(key, message string) {
+	fmt.Printf("🚨 CRITICAL [%s]: %s\n", key, message)
+	if n == nil {
+		return
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	now := time.Now()
+	event, ok := n.pending[key]
+	if !ok {
+		event = &notifyEvent{first: now}
+		n.pending[key] = event
+	}
+	event.message = message
+	event.count++
+	event.last = now
+}
+
+// RecordFailure counts a failure of a repeated operation and raises a critical
+// condition once failure_threshold consecutive failures have been seen
+func (n *Notifier) RecordFailure(key, message string) {
+	if n == nil {
+		return
+	}
+
+	n.mu.Lock()
+	n.failures[key]++
+	count := n.failures[key]
+	n.mu.Unlock()
+
+	if count >= n.failureThreshold() {
+		n.Critical(key, fmt.Sprintf("%d consecutive failures: %s", count, message))
+	}
+}
+
+// RecordSuccess resets the consecutive failure count for an operation
+func (n *Notifier) RecordSuccess(key string) {
+	if n == nil {
+		return
+	}
+
+	n.mu.Lock()
+	delete(n.failures, key)
+	n.mu.Unlock()
+}
+
+// Run sends aggregated notifications every aggregate_window until ctx is cancelled
+func (n *Notifier) Run(ctx context.Context) {
+	if n == nil {
+		return
+	}
+
+	ticker := time.NewTicker(n.aggregateWindow())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			withConfigRead(n.Flush)
+		case <-ctx.Done():
+			withConfigRead(n.Flush)
+			return
+		}
+	}
+}
+
+// Flush emails all pending conditions that aren't throttled. Throttled conditions
+// keep aggregating until their throttle period ends.
+func (n *Notifier) Flush() {
+	if n == nil {
+		return
+	}
+
+	n.mu.Lock()
+	now := time.Now()
+	var keys []string
+	for key := range n.pending {
+		if last, ok := n.lastSent[key]; ok && now.Sub(last) < n.config.Throttle {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		n.mu.Unlock()
+		return
+	}
+	sort.Strings(keys)
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "Critical conditions reported by FDO manufacturing station %s:\n\n", n.stationID)
+	for _, key := range keys {
+		event := n.pending[key]
+		fmt.Fprintf(&body, "[%s] %s\n", key, event.message)
+		fmt.Fprintf(&body, "    occurrences: %d, first: %s, last: %s\n\n",
+			event.count, event.first.Format(time.RFC3339), event.last.Format(time.RFC3339))
+		delete(n.pending, key)
+		n.lastSent[key] = now
+	}
+	n.mu.Unlock()
+
+	subject := fmt.Sprintf("[FDO %s] %d critical condition(s)", n.stationID, len(keys))
+	if err := n.sendMail(subject, body.String()); err != nil {
+		fmt.Printf("⚠️  Failed to send notification email: %v\n", err)
+		return
+	}
+	fmt.Printf("📧 Sent notification email for %d condition(s)\n", len(keys))
+}
+
+// sendMail delivers a plain-text email. Port 465 uses implicit TLS; other ports
+// use STARTTLS when the server offers it.
+func (n *Notifier) sendMail(subject, body string) error {
+	smtpConfig := n.config.SMTP
+	if smtpConfig.Host == "" || smtpConfig.From == "" || len(smtpConfig.To) == 0 {
+		return fmt.Errorf("smtp requires host, from and to")
+	}
+	port := smtpConfig.Port
+	if port == 0 {
+		port = 587
+	}
+	addr := net.JoinHostPort(smtpConfig.Host, strconv.Itoa(port))
+
+	var auth smtp.Auth
+	if smtpConfig.Username != "" {
+		auth = smtp.PlainAuth("", smtpConfig.Username, smtpConfig.Password, smtpConfig.Host)
+	}
+
+	msg := []byte("From: " + smtpConfig.From + "\r\n" +
+		"To: " + strings.Join(smtpConfig.To, ", ") + "\r\n" +
+		"Subject: " + subject + "\r\n" +
+		"Date: " + time.Now().Format(time.RFC1123Z) + "\r\n" +
+		"Content-Type: text/plain; charset=utf-8\r\n" +
+		"\r\n" + strings.ReplaceAll(body, "\n", "\r\n"))
+
+	if port != 465 {
+		return smtp.SendMail(addr, auth, smtpConfig.From, smtpConfig.To, msg)
+	}
+
+	conn, err := tls.Dial("tcp", addr, &tls.Config{ServerName: smtpConfig.Host, MinVersion: tls.VersionTLS12})
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", addr, err)
+	}
+	client, err := smtp.NewClient(conn, smtpConfig.Host)
+	if err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("failed to start SMTP session: %w", err)
+	}
+	defer client.Close()
+
+	if auth != nil {
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("smtp auth failed: %w", err)
+		}
+	}
+	if err := client.Mail(smtpConfig.From); err != nil {
+		return err
+	}
+	for _, to := range smtpConfig.To {
+		if err := client.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// aggregateWindow returns how long events are collected before an email is sent
+func (n *Notifier) aggregateWindow() time.Duration {
+	if n.config.AggregateWindow > 0 {
+		return n.config.AggregateWindow
+	}
+	return 5 * time.Minute
+}
+
+// failureThreshold returns the consecutive failure count that makes a failure critical
+func (n *Notifier) failureThreshold() int {
+	if n.config.FailureThreshold > 0 {
+		return n.config.FailureThreshold
+	}
+	return 3
+}
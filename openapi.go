@@ -0,0 +1,25 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// openAPIDocument describes the admin API. Keep it in step with the routes
+// registered in startDIServer and runReplica; the client package is built from it.
+//
+//go:embed openapi.json
+var openAPIDocument []byte
+
+// openAPIHandler serves GET /api/openapi.json. It needs no admin token: the
+// document only describes the API, and integrators fetch it before they have one.
+func openAPIHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(openAPIDocument)
+	})
+}
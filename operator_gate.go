@@ -0,0 +1,155 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrOperatorAuth is returned when an operator's OTP or badge is not accepted
+var ErrOperatorAuth = errors.New("operator authentication failed")
+
+// OperatorGate authenticates operators by OTP or badge before they may open a
+// batch. A nil *OperatorGate lets batches be opened without authentication.
+type OperatorGate struct {
+	config   *OperatorGateConfig
+	auditLog *AuditLog
+
+	mu      sync.Mutex
+	lastOTP map[string]int64 // last accepted TOTP step per operator, to prevent replay
+}
+
+// OperatorCredentials are the sign-in fields of a batch open request
+type OperatorCredentials struct {
+	OperatorID string `json:"operator_id"`
+	OTP        string `json:"otp"`   // 6-digit TOTP code
+	Badge      string `json:"badge"` // Badge token as read by the badge reader
+}
+
+// NewOperatorGate creates the operator gate, or returns nil if it is disabled
+func NewOperatorGate(config *OperatorGateConfig, auditLog *AuditLog) (*OperatorGate, error) {
+	if !config.Enabled {
+		return nil, nil
+	}
+	if len(config.Operators) == 0 {
+		return nil, fmt.Errorf("operator gate enabled but no operators configured")
+	}
+	for _, op := range config.Operators {
+		if op.ID == "" {
+			return nil, fmt.Errorf("operator gate: operator id is required")
+		}
+		if op.TOTPSecret == "" && op.BadgeSHA256 == "" {
+			return nil, fmt.Errorf("operator %s: totp_secret or badge_sha256 is required", op.ID)
+		}
+		if op.TOTPSecret != "" {
+			if _, err := decodeTOTPSecret(op.TOTPSecret); err != nil {
+				return nil, fmt.Errorf("operator %s: invalid totp_secret: %w", op.ID, err)
+			}
+		}
+	}
+	return &OperatorGate{
+		config:   config,
+		auditLog: auditLog,
+		lastOTP:  make(map[string]int64),
+	}, nil
+}
+
+// Authenticate checks an operator's OTP or badge; successes and failures are audited
+func (g *OperatorGate) Authenticate(ctx context.Context, creds OperatorCredentials) error {
+	if g == nil {
+		return nil
+	}
+	method, err := g.authenticate(creds)
+	if err != nil {
+		g.auditLog.Record(ctx, AuditEvent{Event: "operator_auth_failed", Detail: fmt.Sprintf("operator %q: %v", creds.OperatorID, err)})
+		return fmt.Errorf("%w: %v", ErrOperatorAuth, err)
+	}
+	g.auditLog.Record(ctx, AuditEvent{Event: "operator_authenticated", Detail: fmt.Sprintf("operator %s (%s)", creds.OperatorID, method)})
+	return nil
+}
+
+// authenticate checks the operator's OTP or badge, returning the method used
+func (g *OperatorGate) authenticate(req OperatorCredentials) (string, error) {
+	var op *OperatorCredential
+	for i := range g.config.Operators {
+		if g.config.Operators[i].ID == req.OperatorID {
+			op = &g.config.Operators[i]
+			break
+		}
+	}
+	if op == nil {
+		return "", fmt.Errorf("unknown operator")
+	}
+
+	switch {
+	case req.OTP != "" && op.TOTPSecret != "":
+		step, ok := verifyTOTP(op.TOTPSecret, req.OTP, time.Now())
+		if !ok {
+			return "", fmt.Errorf("invalid OTP")
+		}
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		if step <= g.lastOTP[op.ID] {
+			return "", fmt.Errorf("OTP already used")
+		}
+		g.lastOTP[op.ID] = step
+		return "otp", nil
+
+	case req.Badge != "" && op.BadgeSHA256 != "":
+		sum := sha256.Sum256([]byte(req.Badge))
+		expected, err := hex.DecodeString(op.BadgeSHA256)
+		if err != nil || subtle.ConstantTimeCompare(sum[:], expected) != 1 {
+			return "", fmt.Errorf("invalid badge")
+		}
+		return "badge", nil
+
+	default:
+		return "", fmt.Errorf("no usable credential supplied")
+	}
+}
+
+// verifyTOTP checks an RFC 6238 code, allowing one 30s step of clock skew, and returns the matching step
+func verifyTOTP(secret, code string, now time.Time) (int64, bool) {
+	key, err := decodeTOTPSecret(secret)
+	if err != nil || len(code) != 6 {
+		return 0, false
+	}
+	current := now.Unix() / 30
+	for _, step := range []int64{current - 1, current, current + 1} {
+		if subtle.ConstantTimeCompare([]byte(totpCode(key, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// totpCode computes the 6-digit HOTP value (RFC 4226) for a time step
+func totpCode(key []byte, step int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%06d", value%1000000)
+}
+
+func decodeTOTPSecret(secret string) ([]byte, error) {
+	secret = strings.ToUpper(strings.ReplaceAll(secret, " ", ""))
+	return base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(strings.TrimRight(secret, "="))
+}
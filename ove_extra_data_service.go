@@ -14,34 +14,52 @@ import (
 
 // OVEExtraDataService handles fetching and encoding OVEExtra data
 type OVEExtraDataService struct {
-	config   *OVEExtraDataConfig
-	executor *ExternalCommandExecutor
+	config    *OVEExtraDataConfig
+	executor  *ExternalCommandExecutor
+	buildInfo BuildInfo
+
+	auditAnchor *AuditAnchor // nil = vouchers carry no audit checkpoint
 }
 
 // NewOVEExtraDataService creates a new OVEExtra data service
-func NewOVEExtraDataService(config *OVEExtraDataConfig, executor *ExternalCommandExecutor) *OVEExtraDataService {
+func NewOVEExtraDataService(config *OVEExtraDataConfig, executor *ExternalCommandExecutor, buildInfo BuildInfo) *OVEExtraDataService {
 	return &OVEExtraDataService{
-		config:   config,
-		executor: executor,
+		config:    config,
+		executor:  executor,
+		buildInfo: buildInfo,
 	}
 }
 
 // GetOVEExtraData fetches OVEExtra data from external script and returns as CBOR-encoded map
 func (s *OVEExtraDataService) GetOVEExtraData(ctx context.Context, serial, model string) (map[int][]byte, error) {
 	if !s.config.Enabled {
+		if s.config.IncludeStationInfo {
+			return s.addStationInfo(make(map[int][]byte))
+		}
 		return nil, nil // Disabled, return nil
 	}
 
 	// Call external script to get JSON data
+	if err := faultInjector.Inject(ctx, FaultStageOVEExtraData); err != nil {
+		return nil, fmt.Errorf("failed to fetch extra data: %w", err)
+	}
 	jsonData, err := s.fetchExtraData(ctx, serial, model)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch extra data: %w", err)
 	}
 
 	if jsonData == "" {
+		if s.config.IncludeStationInfo {
+			return s.addStationInfo(make(map[int][]byte))
+		}
 		return nil, nil // No data returned
 	}
 
+	validator, err := newOVEExtraValidator(&s.config.Validation)
+	if err != nil {
+		return nil, err
+	}
+
 	// Parse JSON
 	var rawData map[string]interface{}
 	if err := json.Unmarshal([]byte(jsonData), &rawData); err != nil {
@@ -83,17 +101,75 @@ func (s *OVEExtraDataService) GetOVEExtraData(ctx context.Context, serial, model
 			valueToEncode = value
 		}
 
-		valueBytes, err := cbor.Marshal(valueToEncode)
+		// Keep the entry only if owner services can parse it
+		valueBytes, err := validator.entry(key, keyInt, valueToEncode)
 		if err != nil {
-			return nil, fmt.Errorf("failed to marshal extra data value: %w", err)
+			return nil, err
 		}
+		if valueBytes != nil {
+			extraData[keyInt] = valueBytes
+		}
+	}
+	extraData, err = validator.total(extraData)
+	if err != nil {
+		return nil, err
+	}
 
-		extraData[keyInt] = valueBytes
+	if s.config.IncludeStationInfo {
+		return s.addStationInfo(extraData)
 	}
+	return extraData, nil
+}
 
+// addStationInfo stamps the station instance ID, version and site/line/station into the extra data,
+// keyed like a "fdo_station" string key from the external script
+func (s *OVEExtraDataService) addStationInfo(extraData map[int][]byte) (map[int][]byte, error) {
+	info := map[string]string{
+		"instance_id": s.buildInfo.InstanceID,
+		"version":     s.buildInfo.Version,
+	}
+	// Site, line and station let multi-site manufacturers attribute the voucher to its origin
+	for key, value := range map[string]string{
+		"site_code":  s.buildInfo.SiteCode,
+		"line_id":    s.buildInfo.LineID,
+		"station_id": s.buildInfo.StationID,
+	} {
+		if value != "" {
+			info[key] = value
+		}
+	}
+	valueBytes, err := cbor.Marshal(info)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal station info: %w", err)
+	}
+	extraData[hashString("fdo_station")] = valueBytes
+	return extraData, nil
+}
+
+// addOperator stamps the ID of the operator who opened the current batch into the
+// extra data, keyed like a "fdo_operator" string key from the external script
+func (s *OVEExtraDataService) addOperator(extraData map[int][]byte, operatorID string) (map[int][]byte, error) {
+	valueBytes, err := cbor.Marshal(operatorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal operator ID: %w", err)
+	}
+	if extraData == nil {
+		extraData = make(map[int][]byte)
+	}
+	extraData[hashString("fdo_operator")] = valueBytes
 	return extraData, nil
 }
 
+// SetAuditAnchor makes vouchers carry the audit checkpoints of the voucher sink
+func (s *OVEExtraDataService) SetAuditAnchor(anchor *AuditAnchor) {
+	s.auditAnchor = anchor
+}
+
+// addAuditAnchor stamps the latest audit chain checkpoint into the extra data
+func (s *OVEExtraDataService) addAuditAnchor(extraData map[int][]byte) (map[int][]byte, error) {
+	return s.auditAnchor.addToExtraData(extraData)
+}
+
 // fetchExtraData calls external script to get JSON data
 func (s *OVEExtraDataService) fetchExtraData(ctx context.Context, serial, model string) (string, error) {
 	// Create timeout context
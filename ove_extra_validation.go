@@ -0,0 +1,160 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"unicode/utf8"
+
+	"github.com/fido-device-onboard/go-fdo/cbor"
+)
+
+// ErrOVEExtraInvalid marks extra data refused under the "reject" policy
+var ErrOVEExtraInvalid = errors.New("invalid OVEExtra data")
+
+// OVEExtra validation policies
+const (
+	OVEExtraPolicyReject   = "reject"   // Fail the device's voucher
+	OVEExtraPolicyDrop     = "drop"     // Leave out the entries that break a rule
+	OVEExtraPolicyTruncate = "truncate" // Shorten oversized strings, leave out the rest
+)
+
+// Validation defaults
+const (
+	defaultOVEExtraMinKey       = 1
+	defaultOVEExtraMaxKey       = 65535
+	defaultOVEExtraMaxEntrySize = 1024
+	defaultOVEExtraMaxTotalSize = 8192
+)
+
+// stationOVEExtraKeys are the keys the station writes itself; providers may not use them
+var stationOVEExtraKeys = []string{"fdo_station", "fdo_operator"}
+
+// oveExtraValidator keeps provider extra data within what owner services can
+// parse: keys in the allowed range and not reserved by the station, values
+// that round-trip through CBOR, and entries and totals within size limits
+type oveExtraValidator struct {
+	policy       string
+	minKey       int
+	maxKey       int
+	maxEntrySize int
+	maxTotalSize int
+}
+
+// newOVEExtraValidator applies the defaults to the validation config
+func newOVEExtraValidator(config *OVEExtraValidationConfig) (*oveExtraValidator, error) {
+	v := &oveExtraValidator{
+		policy:       config.Policy,
+		minKey:       config.MinKey,
+		maxKey:       config.MaxKey,
+		maxEntrySize: firstPositive(config.MaxEntrySize, defaultOVEExtraMaxEntrySize),
+		maxTotalSize: firstPositive(config.MaxTotalSize, defaultOVEExtraMaxTotalSize),
+	}
+	switch v.policy {
+	case "":
+		v.policy = OVEExtraPolicyDrop
+	case OVEExtraPolicyReject, OVEExtraPolicyDrop, OVEExtraPolicyTruncate:
+	default:
+		return nil, fmt.Errorf("ove_extra_data.validation.policy must be reject, drop or truncate, not %q", v.policy)
+	}
+	if v.minKey == 0 {
+		v.minKey = defaultOVEExtraMinKey
+	}
+	if v.maxKey == 0 {
+		v.maxKey = defaultOVEExtraMaxKey
+	}
+	if v.minKey > v.maxKey {
+		return nil, fmt.Errorf("ove_extra_data.validation: min_key %d is above max_key %d", v.minKey, v.maxKey)
+	}
+	return v, nil
+}
+
+// entry validates one provider value and returns its CBOR encoding, or
+// nil if the entry is dropped. Under the reject policy a violation is an error.
+func (v *oveExtraValidator) entry(name string, key int, value any) ([]byte, error) {
+	if key < v.minKey || key > v.maxKey {
+		return nil, v.violation(name, fmt.Sprintf("key %d is outside the allowed range %d-%d", key, v.minKey, v.maxKey))
+	}
+	for _, reserved := range stationOVEExtraKeys {
+		if key == hashString(reserved) {
+			return nil, v.violation(name, fmt.Sprintf("key %d is reserved for the station's %s entry", key, reserved))
+		}
+	}
+
+	data, err := cbor.Marshal(value)
+	if err != nil {
+		return nil, v.violation(name, fmt.Sprintf("value can't be CBOR encoded: %v", err))
+	}
+	var decoded any
+	if err := cbor.Unmarshal(data, &decoded); err != nil {
+		return nil, v.violation(name, fmt.Sprintf("value doesn't round-trip through CBOR: %v", err))
+	}
+
+	if len(data) > v.maxEntrySize {
+		if s, ok := value.(string); ok && v.policy == OVEExtraPolicyTruncate {
+			truncated, err := truncateCBORString(s, v.maxEntrySize)
+			if err != nil {
+				return nil, err
+			}
+			fmt.Printf("⚠️  OVEExtra entry %q truncated from %d to %d bytes\n", name, len(data), len(truncated))
+			return truncated, nil
+		}
+		return nil, v.violation(name, fmt.Sprintf("value is %d bytes, above max_entry_size %d", len(data), v.maxEntrySize))
+	}
+	return data, nil
+}
+
+// total enforces the total size limit. Under the drop and truncate policies the
+// entries with the highest keys are left out until the rest fit.
+func (v *oveExtraValidator) total(extraData map[int][]byte) (map[int][]byte, error) {
+	size := 0
+	for _, data := range extraData {
+		size += len(data)
+	}
+	if size <= v.maxTotalSize {
+		return extraData, nil
+	}
+	if v.policy == OVEExtraPolicyReject {
+		return nil, fmt.Errorf("%w: %d bytes in total, above max_total_size %d", ErrOVEExtraInvalid, size, v.maxTotalSize)
+	}
+	keys := slices.Sorted(maps.Keys(extraData))
+	for i := len(keys) - 1; i >= 0 && size > v.maxTotalSize; i-- {
+		size -= len(extraData[keys[i]])
+		delete(extraData, keys[i])
+		fmt.Printf("⚠️  OVEExtra entry %d left out: total size above max_total_size %d\n", keys[i], v.maxTotalSize)
+	}
+	return extraData, nil
+}
+
+// violation reports a broken rule: an error under the reject policy, else a
+// warning and the entry is dropped
+func (v *oveExtraValidator) violation(name, reason string) error {
+	if v.policy == OVEExtraPolicyReject {
+		return fmt.Errorf("%w: entry %q: %s", ErrOVEExtraInvalid, name, reason)
+	}
+	fmt.Printf("⚠️  OVEExtra entry %q left out: %s\n", name, reason)
+	return nil
+}
+
+// truncateCBORString shortens a string, on a UTF-8 boundary, until its CBOR
+// encoding fits in limit bytes
+func truncateCBORString(s string, limit int) ([]byte, error) {
+	for {
+		data, err := cbor.Marshal(s)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal truncated extra data value: %w", err)
+		}
+		if len(data) <= limit || s == "" {
+			return data, nil
+		}
+		s = s[:max(0, len(s)-(len(data)-limit))]
+		for len(s) > 0 && !utf8.ValidString(s) {
+			s = s[:len(s)-1]
+		}
+	}
+}
@@ -0,0 +1,101 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+
+	"github.com/fido-device-onboard/go-fdo"
+	"github.com/fido-device-onboard/go-fdo/protocol"
+)
+
+// Owner public key encodings for the extended voucher entry
+const (
+	OwnerKeyEncodingX509    = "x509"    // SubjectPublicKeyInfo
+	OwnerKeyEncodingX5Chain = "x5chain" // Owner certificate chain
+	OwnerKeyEncodingCOSEKey = "cosekey" // COSE_Key
+)
+
+var ownerKeyEncodings = map[string]protocol.KeyEncoding{
+	OwnerKeyEncodingX509:    protocol.X509KeyEnc,
+	OwnerKeyEncodingX5Chain: protocol.X5ChainKeyEnc,
+	OwnerKeyEncodingCOSEKey: protocol.CoseKeyEnc,
+}
+
+// validateOwnerKeyEncodings checks the owner key encodings named in the static
+// owner and upload auth profiles, so typos stop the station at startup
+func validateOwnerKeyEncodings(config *VoucherConfig) error {
+	if err := validateOwnerKeyEncoding(config.OwnerSignover.KeyEncoding); err != nil {
+		return fmt.Errorf("owner_signover.key_encoding: %w", err)
+	}
+	for name, profile := range config.UploadAuthProfiles {
+		if err := validateOwnerKeyEncoding(profile.OwnerKeyEncoding); err != nil {
+			return fmt.Errorf("upload_auth_profiles.%s.owner_key_encoding: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func validateOwnerKeyEncoding(encoding string) error {
+	if _, ok := ownerKeyEncodings[encoding]; encoding != "" && !ok {
+		return fmt.Errorf("unsupported owner key encoding %q (want x509, x5chain or cosekey)", encoding)
+	}
+	return nil
+}
+
+// selectNextOwner returns the form of the next owner key to extend the voucher
+// to: the certificate chain for x5chain, otherwise the bare public key
+func selectNextOwner(key crypto.PublicKey, chain []*x509.Certificate, encoding string) (crypto.PublicKey, error) {
+	if err := validateOwnerKeyEncoding(encoding); err != nil {
+		return nil, err
+	}
+	switch encoding {
+	case OwnerKeyEncodingX5Chain:
+		if len(chain) == 0 {
+			return nil, fmt.Errorf("%w: owner key encoding x5chain requires the owner to supply a certificate chain", ErrOwnerKeyPolicy)
+		}
+		return chain, nil
+	default:
+		return key, nil
+	}
+}
+
+// checkOwnerKeyEncoding verifies that the newest voucher entry carries the
+// owner key in the requested encoding, so an owner service that parses only
+// one encoding never receives a voucher it cannot read
+func checkOwnerKeyEncoding(ov *fdo.Voucher, encoding string) error {
+	if encoding == "" || len(ov.Entries) == 0 {
+		return nil
+	}
+	got := ov.Entries[len(ov.Entries)-1].Payload.Val.PublicKey.Encoding
+	if want := ownerKeyEncodings[encoding]; got != want {
+		return fmt.Errorf("%w: voucher entry encodes the owner key as %v, owner requires %v", ErrOwnerKeyPolicy, got, want)
+	}
+	return nil
+}
+
+// parseCertificateChainPEM returns the certificates in a PEM bundle, leaf first,
+// or nil if it holds none
+func parseCertificateChainPEM(data []byte) ([]*x509.Certificate, error) {
+	var chain []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return chain, nil
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse certificate: %w", err)
+		}
+		chain = append(chain, cert)
+	}
+}
@@ -0,0 +1,205 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"crypto"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// JWKS key selection when an owner key URL names no kid
+const (
+	JWKSSelectFirst  = "first"  // The first signing key (default)
+	JWKSSelectSingle = "single" // The only signing key; more than one is an error
+)
+
+// jwkAlgorithms are the JWK "alg" values FDO can sign over to, per key type
+var jwkAlgorithms = map[string][]string{
+	"ec256":   {"ES256"},
+	"ec384":   {"ES384"},
+	"rsa2048": {"RS256", "RS384", "PS256", "PS384"},
+	"rsa3072": {"RS256", "RS384", "PS256", "PS384"},
+	"rsa4096": {"RS256", "RS384", "PS256", "PS384"},
+}
+
+// JWKSCache keeps fetched owner key documents under the HTTP caching rules of
+// their responses: a fresh document (Cache-Control max-age or Expires) is
+// reused without a request, a stale one is revalidated with its ETag or
+// Last-Modified, and no-store responses are never kept.
+type JWKSCache struct {
+	maxAge time.Duration // Longest a document is fresh, whatever its headers say (0 = no cap)
+
+	mu      sync.Mutex
+	entries map[string]jwksCacheEntry
+}
+
+// jwksCacheEntry is a cached owner key document
+type jwksCacheEntry struct {
+	body         []byte
+	etag         string
+	lastModified string
+	expires      time.Time // Fresh until then; zero = revalidate on every use
+}
+
+// NewJWKSCache creates an owner key document cache
+func NewJWKSCache(maxAge time.Duration) *JWKSCache {
+	return &JWKSCache{maxAge: maxAge, entries: map[string]jwksCacheEntry{}}
+}
+
+// lookup returns the cached document for a URL
+func (c *JWKSCache) lookup(url string) (jwksCacheEntry, bool) {
+	if c == nil {
+		return jwksCacheEntry{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[url]
+	return entry, ok
+}
+
+// store caches the document of a response, if its headers allow it
+func (c *JWKSCache) store(url string, entry jwksCacheEntry, header http.Header, now time.Time) {
+	if c == nil {
+		return
+	}
+	expires, storable := httpCacheExpiry(header, now)
+	if c.maxAge > 0 && expires.After(now.Add(c.maxAge)) {
+		expires = now.Add(c.maxAge)
+	}
+	if etag := header.Get("ETag"); etag != "" {
+		entry.etag = etag
+	}
+	if lastModified := header.Get("Last-Modified"); lastModified != "" {
+		entry.lastModified = lastModified
+	}
+	entry.expires = expires
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !storable || (expires.IsZero() && entry.etag == "" && entry.lastModified == "") {
+		delete(c.entries, url)
+		return
+	}
+	c.entries[url] = entry
+}
+
+// forget drops the document of a URL
+func (c *JWKSCache) forget(url string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, url)
+}
+
+// httpCacheExpiry returns until when a response is fresh (zero = it must be
+// revalidated before reuse) and whether it may be stored at all
+func httpCacheExpiry(header http.Header, now time.Time) (time.Time, bool) {
+	maxAge := -1
+	for _, directive := range strings.Split(strings.ToLower(strings.Join(header.Values("Cache-Control"), ",")), ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch name {
+		case "no-store":
+			return time.Time{}, false
+		case "no-cache":
+			return time.Time{}, true
+		case "max-age":
+			if seconds, err := strconv.Atoi(strings.Trim(value, `"`)); err == nil && seconds >= 0 {
+				maxAge = seconds
+			}
+		}
+	}
+	if maxAge >= 0 {
+		// Time the response already spent in caches on the way counts against it
+		age, _ := strconv.Atoi(header.Get("Age"))
+		if age >= maxAge {
+			return time.Time{}, true
+		}
+		return now.Add(time.Duration(maxAge-age) * time.Second), true
+	}
+	if expires := header.Get("Expires"); expires != "" {
+		at, err := http.ParseTime(expires)
+		if err != nil {
+			return time.Time{}, true
+		}
+		// Expires is relative to the server's clock
+		if date, err := http.ParseTime(header.Get("Date")); err == nil {
+			at = now.Add(at.Sub(date))
+		}
+		if !at.After(now) {
+			return time.Time{}, true
+		}
+		return at, true
+	}
+	return time.Time{}, true
+}
+
+// selectJWK picks the owner key from the keys of a JWKS: the one with the
+// given kid, or else the one the configured selection names
+func selectJWK(keys []map[string]any, kid, selection string) (map[string]any, error) {
+	var signing []map[string]any
+	for _, jwk := range keys {
+		// Keys for encryption only are not owner keys
+		use, _ := jwk["use"].(string)
+		ops, _ := jwk["key_ops"].([]any)
+		if (use != "" && use != "sig") || (ops != nil && !slices.Contains(ops, any("verify"))) {
+			if id, _ := jwk["kid"].(string); kid != "" && id == kid {
+				return nil, fmt.Errorf("JWKS key %q is not a signing key", kid)
+			}
+			continue
+		}
+		signing = append(signing, jwk)
+	}
+
+	if kid != "" {
+		for _, jwk := range signing {
+			if id, _ := jwk["kid"].(string); id == kid {
+				return jwk, nil
+			}
+		}
+		return nil, fmt.Errorf("JWKS has no key %q", kid)
+	}
+	if len(signing) == 0 {
+		return nil, fmt.Errorf("JWKS has no signing key")
+	}
+	if selection == JWKSSelectSingle && len(signing) > 1 {
+		return nil, fmt.Errorf("JWKS has %d signing keys; name one with #kid in the owner key URL", len(signing))
+	}
+	return signing[0], nil
+}
+
+// checkJWKAlgorithm refuses a JWK whose declared alg is not one FDO supports
+// for its key, so a key meant for another algorithm is never signed over to
+func checkJWKAlgorithm(jwk map[string]any, publicKey crypto.PublicKey) error {
+	alg, _ := jwk["alg"].(string)
+	if alg == "" {
+		return nil
+	}
+	keyType := ownerKeyType(publicKey)
+	if !slices.Contains(jwkAlgorithms[keyType], alg) {
+		if keyType == "" {
+			keyType = "unsupported"
+		}
+		return fmt.Errorf("%w: JWK alg %s does not fit its %s key", ErrOwnerKeyPolicy, alg, keyType)
+	}
+	return nil
+}
+
+// validateJWKSKeySelection checks did_cache.jwks_key_selection
+func validateJWKSKeySelection(config *DIDCache) error {
+	switch config.JWKSKeySelection {
+	case "", JWKSSelectFirst, JWKSSelectSingle:
+		return nil
+	}
+	return fmt.Errorf("voucher_management.did_cache.jwks_key_selection: unsupported value %q (want %s or %s)",
+		config.JWKSKeySelection, JWKSSelectFirst, JWKSSelectSingle)
+}
@@ -0,0 +1,511 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Statuses of a registered owner key
+const (
+	OwnerKeyPending = "pending" // Waiting for the owner to sign the challenge
+	OwnerKeyActive  = "active"  // Proven; devices can be signed over to it
+)
+
+// ownerKeyChallengePrefix starts every challenge and versions its format
+const ownerKeyChallengePrefix = "fdo-owner-key-proof.v1:"
+
+// defaultOwnerKeyChallengeTTL is how long an owner has to sign a challenge
+const defaultOwnerKeyChallengeTTL = 24 * time.Hour
+
+// maxOwnerKeyProofAttempts is how many wrong signatures a challenge takes
+// before the owner key must be registered again
+const maxOwnerKeyProofAttempts = 5
+
+// ErrOwnerKeyProof is returned for a proof that is wrong, late or not expected
+var ErrOwnerKeyProof = errors.New("owner key proof of possession failed")
+
+// RegisterOwnerKeyRequest is the body of POST /api/owner-keys
+type RegisterOwnerKeyRequest struct {
+	Customer string `json:"customer"` // Customer the key signs for ("" = devices without a customer)
+	Owner    string `json:"owner"`    // DID, https:// owner key URL or PEM public key
+}
+
+// OwnerKeyProofRequest is the body of POST /api/owner-keys/{id}/proof
+type OwnerKeyProofRequest struct {
+	Signature string `json:"signature"` // Base64 signature of the challenge by the owner's private key
+}
+
+// OwnerKeyRegistration is an owner key registered for a customer. It becomes
+// active once the owner proves possession of the private key by signing the
+// challenge.
+type OwnerKeyRegistration struct {
+	ID           string     `json:"id"`
+	Customer     string     `json:"customer"`
+	Owner        string     `json:"owner"`
+	KeySHA256    string     `json:"key_sha256"`
+	KeyType      string     `json:"key_type"`
+	Status       string     `json:"status"`
+	Challenge    string     `json:"challenge"` // The exact string to sign
+	ExpiresAt    time.Time  `json:"expires_at"`
+	Attempts     int        `json:"attempts"` // Wrong signatures so far
+	RegisteredBy string     `json:"registered_by"`
+	RegisteredAt time.Time  `json:"registered_at"`
+	ProvenAt     *time.Time `json:"proven_at,omitempty"`
+
+	publicKey []byte // PKIX; the key the challenge is checked with
+}
+
+// OwnerKeyProofs refuses signover to owner keys whose owner hasn't proven it
+// holds the private key. A key is registered for a customer, the station
+// issues a nonce, and the key becomes active when the owner signs it, so a
+// mistyped or hijacked key registration never receives a voucher.
+type OwnerKeyProofs struct {
+	config    *OwnerKeyProofConfig
+	db        *StationDB
+	ownerKeys *OwnerKeyService
+	auditLog  *AuditLog
+
+	mu     sync.RWMutex
+	active map[string]bool // customer + "\x00" + key SHA-256 of active keys
+}
+
+// NewOwnerKeyProofs creates the owner key registry, or returns nil if owner
+// keys need no proof
+func NewOwnerKeyProofs(config *OwnerKeyProofConfig, db *StationDB, ownerKeys *OwnerKeyService, auditLog *AuditLog) *OwnerKeyProofs {
+	if !config.Enabled {
+		return nil
+	}
+	return &OwnerKeyProofs{config: config, db: db, ownerKeys: ownerKeys, auditLog: auditLog}
+}
+
+// Initialize creates the owner_key_registrations table if it doesn't exist and loads the active keys
+func (p *OwnerKeyProofs) Initialize(ctx context.Context) error {
+	if p == nil {
+		return nil
+	}
+	if _, err := p.db.db.ExecContext(ctx, `
+	CREATE TABLE IF NOT EXISTS owner_key_registrations (
+		id TEXT PRIMARY KEY,
+		customer TEXT NOT NULL,
+		owner TEXT NOT NULL,
+		key_sha256 TEXT NOT NULL,
+		key_type TEXT NOT NULL,
+		public_key BLOB NOT NULL,
+		status TEXT NOT NULL,
+		challenge TEXT NOT NULL,
+		expires_at INTEGER NOT NULL,
+		attempts INTEGER NOT NULL DEFAULT 0,
+		registered_by TEXT NOT NULL,
+		registered_at INTEGER NOT NULL,
+		proven_at INTEGER
+	)`); err != nil {
+		return fmt.Errorf("failed to create owner_key_registrations table: %w", err)
+	}
+	if _, err := p.db.db.ExecContext(ctx,
+		`CREATE INDEX IF NOT EXISTS idx_owner_key_registrations_key ON owner_key_registrations (customer, key_sha256)`); err != nil {
+		return fmt.Errorf("failed to create owner_key_registrations index: %w", err)
+	}
+	return p.load(ctx)
+}
+
+// load reads the active keys into memory
+func (p *OwnerKeyProofs) load(ctx context.Context) error {
+	registrations, err := p.query(ctx, ` WHERE status = ?`, OwnerKeyActive)
+	if err != nil {
+		return err
+	}
+	active := map[string]bool{}
+	for _, reg := range registrations {
+		active[reg.Customer+"\x00"+reg.KeySHA256] = true
+	}
+	p.mu.Lock()
+	p.active = active
+	p.mu.Unlock()
+	return nil
+}
+
+// challengeTTL is how long a challenge can be signed
+func (p *OwnerKeyProofs) challengeTTL() time.Duration {
+	if p.config.ChallengeTTL > 0 {
+		return p.config.ChallengeTTL
+	}
+	return defaultOwnerKeyChallengeTTL
+}
+
+// Register resolves an owner the way signover does and issues the challenge
+// its private key must sign. A pending registration of the same key for the
+// customer is replaced.
+func (p *OwnerKeyProofs) Register(ctx context.Context, req RegisterOwnerKeyRequest) (*OwnerKeyRegistration, error) {
+	req.Customer, req.Owner = strings.TrimSpace(req.Customer), strings.TrimSpace(req.Owner)
+	if req.Owner == "" {
+		return nil, fmt.Errorf("owner is required (a DID, https:// owner key URL or PEM public key)")
+	}
+	result, err := p.ownerKeys.ResolveOwner(ctx, req.Owner, "")
+	if err != nil {
+		return nil, err
+	}
+	publicKey := result.PublicKey
+	if len(result.CertChain) > 0 {
+		publicKey = result.CertChain[0].PublicKey
+	}
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported owner key: %w", err)
+	}
+	fp := ownerKeySHA256(publicKey)
+	if p.IsActive(req.Customer, fp) {
+		return nil, fmt.Errorf("owner key %s is already active for customer %q", fp, req.Customer)
+	}
+
+	id, err := newUUID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate registration ID: %w", err)
+	}
+	nonce := make([]byte, 32)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate challenge: %w", err)
+	}
+	now := stationClock.Now().UTC().Truncate(time.Second)
+	reg := &OwnerKeyRegistration{
+		ID:           id,
+		Customer:     req.Customer,
+		Owner:        req.Owner,
+		KeySHA256:    fp,
+		KeyType:      ownerKeyType(publicKey),
+		Status:       OwnerKeyPending,
+		Challenge:    ownerKeyChallengePrefix + strings.Join([]string{id, req.Customer, fp, base64.RawURLEncoding.EncodeToString(nonce)}, ":"),
+		ExpiresAt:    now.Add(p.challengeTTL()),
+		RegisteredBy: adminIdentity(ctx),
+		RegisteredAt: now,
+	}
+
+	tx, err := p.db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to register owner key: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	if _, err := tx.ExecContext(ctx, `DELETE FROM owner_key_registrations WHERE customer = ? AND key_sha256 = ? AND status = ?`,
+		reg.Customer, reg.KeySHA256, OwnerKeyPending); err != nil {
+		return nil, fmt.Errorf("failed to register owner key: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+	INSERT INTO owner_key_registrations (id, customer, owner, key_sha256, key_type, public_key, status, challenge, expires_at, registered_by, registered_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		reg.ID, reg.Customer, reg.Owner, reg.KeySHA256, reg.KeyType, der, reg.Status, reg.Challenge,
+		reg.ExpiresAt.Unix(), reg.RegisteredBy, reg.RegisteredAt.Unix()); err != nil {
+		return nil, fmt.Errorf("failed to register owner key: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to register owner key: %w", err)
+	}
+
+	p.auditLog.Record(ctx, AuditEvent{
+		Event:    "owner_key_registered",
+		Customer: reg.Customer,
+		Detail:   fmt.Sprintf("owner key %s (%s) registered by %s, pending proof until %s", fp, req.Owner, reg.RegisteredBy, reg.ExpiresAt.Format(time.RFC3339)),
+	})
+	return reg, nil
+}
+
+// Prove activates a pending registration if the signature is the owner key's
+// signature of its challenge
+func (p *OwnerKeyProofs) Prove(ctx context.Context, id string, req OwnerKeyProofRequest) (*OwnerKeyRegistration, error) {
+	registrations, err := p.query(ctx, ` WHERE id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(registrations) == 0 {
+		return nil, fmt.Errorf("%w: owner key registration %s not found", ErrVoucherNotFound, id)
+	}
+	reg := &registrations[0]
+	switch {
+	case reg.Status != OwnerKeyPending:
+		return nil, fmt.Errorf("%w: owner key %s is already %s", ErrOwnerKeyProof, reg.KeySHA256, reg.Status)
+	case stationClock.Now().After(reg.ExpiresAt):
+		return nil, fmt.Errorf("%w: challenge expired at %s, register the key again", ErrOwnerKeyProof, reg.ExpiresAt.Format(time.RFC3339))
+	case reg.Attempts >= maxOwnerKeyProofAttempts:
+		return nil, fmt.Errorf("%w: too many wrong signatures, register the key again", ErrOwnerKeyProof)
+	}
+
+	if err := verifyOwnerKeyProof(reg.publicKey, reg.Challenge, req.Signature); err != nil {
+		if _, dbErr := p.db.db.ExecContext(ctx, `UPDATE owner_key_registrations SET attempts = attempts + 1 WHERE id = ?`, id); dbErr != nil {
+			fmt.Printf("⚠️  Failed to count owner key proof attempt: %v\n", dbErr)
+		}
+		p.auditLog.Record(ctx, AuditEvent{
+			Event:    "owner_key_proof_failed",
+			Customer: reg.Customer,
+			Detail:   fmt.Sprintf("owner key %s (%s): %v", reg.KeySHA256, reg.Owner, err),
+		})
+		return nil, fmt.Errorf("%w: %v", ErrOwnerKeyProof, err)
+	}
+
+	now := stationClock.Now().UTC().Truncate(time.Second)
+	result, err := p.db.db.ExecContext(ctx, `UPDATE owner_key_registrations SET status = ?, proven_at = ? WHERE id = ? AND status = ?`,
+		OwnerKeyActive, now.Unix(), id, OwnerKeyPending)
+	if err != nil {
+		return nil, fmt.Errorf("failed to activate owner key: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil, fmt.Errorf("%w: owner key %s is no longer pending", ErrOwnerKeyProof, reg.KeySHA256)
+	}
+	reg.Status, reg.ProvenAt = OwnerKeyActive, &now
+	p.mu.Lock()
+	p.active[reg.Customer+"\x00"+reg.KeySHA256] = true
+	p.mu.Unlock()
+
+	p.auditLog.Record(ctx, AuditEvent{
+		Event:    "owner_key_proven",
+		Customer: reg.Customer,
+		Detail:   fmt.Sprintf("owner key %s (%s) proven, registered by %s", reg.KeySHA256, reg.Owner, reg.RegisteredBy),
+	})
+	fmt.Printf("🔑 Owner key %s proven for customer %q\n", reg.KeySHA256, reg.Customer)
+	return reg, nil
+}
+
+// Delete removes a registration; an active key stops receiving vouchers
+func (p *OwnerKeyProofs) Delete(ctx context.Context, id string) error {
+	registrations, err := p.query(ctx, ` WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if len(registrations) == 0 {
+		return fmt.Errorf("%w: owner key registration %s not found", ErrVoucherNotFound, id)
+	}
+	if _, err := p.db.db.ExecContext(ctx, `DELETE FROM owner_key_registrations WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete owner key registration: %w", err)
+	}
+	reg := registrations[0]
+	p.auditLog.Record(ctx, AuditEvent{
+		Event:    "owner_key_deregistered",
+		Customer: reg.Customer,
+		Detail:   fmt.Sprintf("%s owner key %s (%s) deregistered by %s", reg.Status, reg.KeySHA256, reg.Owner, adminIdentity(ctx)),
+	})
+	return p.load(ctx)
+}
+
+// IsActive reports whether an owner key is proven for a customer
+func (p *OwnerKeyProofs) IsActive(customer, keySHA256 string) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.active[customer+"\x00"+keySHA256]
+}
+
+// Check returns an error if a device would be signed over to an owner key not
+// proven for its customer
+func (p *OwnerKeyProofs) Check(customer string, ownerKey crypto.PublicKey) error {
+	if p == nil {
+		return nil
+	}
+	fp := ownerKeySHA256(ownerKey)
+	if fp == "" || p.IsActive(customer, fp) {
+		return nil
+	}
+	return fmt.Errorf("%w: owner key %s has no proof of possession for customer %q", ErrOwnerKeyPolicy, fp, customer)
+}
+
+// verifyOwnerKeyProof checks a base64 signature of the challenge: ECDSA (ASN.1
+// or raw r||s) with SHA-256 for P-256 and SHA-384 for P-384, or RSA
+// PKCS#1 v1.5 or PSS with SHA-256 or SHA-384
+func verifyOwnerKeyProof(publicKey []byte, challenge, signature string) error {
+	pub, err := x509.ParsePKIXPublicKey(publicKey)
+	if err != nil {
+		return fmt.Errorf("failed to parse owner key: %w", err)
+	}
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		sig, err = base64.RawURLEncoding.DecodeString(strings.TrimRight(signature, "="))
+	}
+	if err != nil || len(sig) == 0 {
+		return fmt.Errorf("signature is not base64")
+	}
+	sum256 := sha256.Sum256([]byte(challenge))
+	sum384 := sha512.Sum384([]byte(challenge))
+
+	switch key := pub.(type) {
+	case *ecdsa.PublicKey:
+		digest := sum256[:]
+		if key.Curve.Params().BitSize > 256 {
+			digest = sum384[:]
+		}
+		if ecdsa.VerifyASN1(key, digest, sig) {
+			return nil
+		}
+		size := (key.Curve.Params().BitSize + 7) / 8
+		if len(sig) == 2*size {
+			r, s := new(big.Int).SetBytes(sig[:size]), new(big.Int).SetBytes(sig[size:])
+			if ecdsa.Verify(key, digest, r, s) {
+				return nil
+			}
+		}
+	case *rsa.PublicKey:
+		for _, h := range []struct {
+			hash   crypto.Hash
+			digest []byte
+		}{{crypto.SHA256, sum256[:]}, {crypto.SHA384, sum384[:]}} {
+			if rsa.VerifyPKCS1v15(key, h.hash, h.digest, sig) == nil || rsa.VerifyPSS(key, h.hash, h.digest, sig, nil) == nil {
+				return nil
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported owner key type %T", pub)
+	}
+	return fmt.Errorf("signature does not verify with the registered key")
+}
+
+var ownerKeyRegistrationListSpec = listSpec{
+	Key:         "id",
+	Sorts:       map[string]string{"registered_at": "registered_at", "expires_at": "expires_at"},
+	DefaultSort: "-registered_at",
+	Filters:     map[string]string{"customer": "customer", "status": "status", "key_sha256": "key_sha256"},
+}
+
+// Page returns one page of owner key registrations
+func (p *OwnerKeyProofs) Page(ctx context.Context, q *listQuery) ([]OwnerKeyRegistration, string, error) {
+	clause, args := q.sql()
+	registrations, err := p.query(ctx, clause, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	registrations, next := listPage(q, registrations, func(reg OwnerKeyRegistration, column string) any {
+		switch column {
+		case "registered_at":
+			return reg.RegisteredAt.Unix()
+		case "expires_at":
+			return reg.ExpiresAt.Unix()
+		default:
+			return reg.ID
+		}
+	})
+	return registrations, next, nil
+}
+
+// query returns the registrations selected by clause (WHERE, ORDER BY and LIMIT)
+func (p *OwnerKeyProofs) query(ctx context.Context, clause string, args ...any) ([]OwnerKeyRegistration, error) {
+	rows, err := p.db.db.QueryContext(ctx, `
+	SELECT id, customer, owner, key_sha256, key_type, public_key, status, challenge, expires_at, attempts, registered_by, registered_at, proven_at
+	FROM owner_key_registrations`+clause, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query owner key registrations: %w", err)
+	}
+	defer rows.Close()
+
+	registrations := []OwnerKeyRegistration{}
+	for rows.Next() {
+		var reg OwnerKeyRegistration
+		var expiresAt, registeredAt int64
+		var provenAt sql.NullInt64
+		if err := rows.Scan(&reg.ID, &reg.Customer, &reg.Owner, &reg.KeySHA256, &reg.KeyType, &reg.publicKey, &reg.Status,
+			&reg.Challenge, &expiresAt, &reg.Attempts, &reg.RegisteredBy, &registeredAt, &provenAt); err != nil {
+			return nil, fmt.Errorf("failed to read owner key registration: %w", err)
+		}
+		reg.ExpiresAt, reg.RegisteredAt = time.Unix(expiresAt, 0), time.Unix(registeredAt, 0)
+		if provenAt.Valid {
+			t := time.Unix(provenAt.Int64, 0)
+			reg.ProvenAt = &t
+		}
+		registrations = append(registrations, reg)
+	}
+	return registrations, rows.Err()
+}
+
+// RegisterHandler serves POST /api/owner-keys
+func (p *OwnerKeyProofs) RegisterHandler() http.Handler {
+	return p.handler(func(w http.ResponseWriter, r *http.Request) {
+		var req RegisterOwnerKeyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid request: %v", err))
+			return
+		}
+		reg, err := p.Register(r.Context(), req)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusCreated, reg)
+	})
+}
+
+// ProveHandler serves POST /api/owner-keys/{id}/proof
+func (p *OwnerKeyProofs) ProveHandler() http.Handler {
+	return p.handler(func(w http.ResponseWriter, r *http.Request) {
+		var req OwnerKeyProofRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid request: %v", err))
+			return
+		}
+		reg, err := p.Prove(r.Context(), r.PathValue("id"), req)
+		switch {
+		case errors.Is(err, ErrVoucherNotFound):
+			writeJSONError(w, http.StatusNotFound, err.Error())
+		case errors.Is(err, ErrOwnerKeyProof):
+			writeJSONError(w, http.StatusForbidden, err.Error())
+		case err != nil:
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+		default:
+			writeJSON(w, http.StatusOK, reg)
+		}
+	})
+}
+
+// ListHandler serves GET /api/owner-keys with the list parameters of ownerKeyRegistrationListSpec
+func (p *OwnerKeyProofs) ListHandler() http.Handler {
+	return p.handler(func(w http.ResponseWriter, r *http.Request) {
+		q, err := parseListQuery(r, &ownerKeyRegistrationListSpec)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		registrations, next, err := p.Page(r.Context(), q)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSONList(w, r, registrations, next)
+	})
+}
+
+// DeleteHandler serves DELETE /api/owner-keys/{id}
+func (p *OwnerKeyProofs) DeleteHandler() http.Handler {
+	return p.handler(func(w http.ResponseWriter, r *http.Request) {
+		err := p.Delete(r.Context(), r.PathValue("id"))
+		switch {
+		case errors.Is(err, ErrVoucherNotFound):
+			writeJSONError(w, http.StatusNotFound, err.Error())
+		case err != nil:
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+}
+
+// handler answers 404 while owner key proofs are disabled
+func (p *OwnerKeyProofs) handler(fn http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if p == nil {
+			writeJSONError(w, http.StatusNotFound, "owner key proofs are disabled")
+			return
+		}
+		fn(w, r)
+	})
+}
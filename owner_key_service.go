@@ -9,87 +9,284 @@ import (
 	"crypto/x509"
 	"encoding/json"
 	"encoding/pem"
+	"errors"
 	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
 )
 
+// ErrOwnerKeyPolicy marks an owner refused by policy: the owner key callback,
+// the did:web domain lists, owner revocations or the required key encoding
+var ErrOwnerKeyPolicy = errors.New("owner key refused by policy")
+
 // OwnerKeyResponse is the expected JSON response from owner key service
 type OwnerKeyResponse struct {
-	OwnerKeyPEM string `json:"owner_key_pem"` // Existing PEM support
-	OwnerDID    string `json:"owner_did"`     // NEW: DID URI support
-	Error       string `json:"error"`
+	OwnerKeyPEM       string `json:"owner_key_pem"`       // Existing PEM support
+	OwnerDID          string `json:"owner_did"`           // NEW: DID URI support
+	OwnerKeyURL       string `json:"owner_key_url"`       // HTTPS URL serving the owner key as PEM or JWKS, for owners without a DID
+	OwnerKeyKID       string `json:"owner_key_kid"`       // kid of the owner key in the owner_key_url JWKS
+	UploadAuthProfile string `json:"upload_auth_profile"` // Named upload auth profile for this owner
+	Customer          string `json:"customer"`            // Customer/licensee ID, used for quotas
+	KeyEncoding       string `json:"key_encoding"`        // Owner key encoding: "x509" | "x5chain" | "cosekey"
+	NoCache           bool   `json:"no_cache"`            // The key is for this device only; don't reuse it for its lot
+	Error             string `json:"error"`
 }
 
 // OwnerKeyService handles retrieval of owner keys for voucher sign-over
 type OwnerKeyService struct {
-	executor *ExternalCommandExecutor
+	executor  *ExternalCommandExecutor
+	config    *OwnerSignoverConfig
+	didConfig *DIDCache
+	rollouts  *RolloutsConfig
+	notifier  *Notifier
+	rotations *DIDRotations // nil = DID rotation hints ignored
+	pins      *DIDPins      // nil = no DIDs pinned
+	jwks      *JWKSCache    // Owner key URL documents, kept as their HTTP caching headers allow
+
+	// Results reused for every device of a model in one lot (customer order),
+	// so a 10k-unit run doesn't exec the command 10k times
+	mu    sync.Mutex
+	cache map[ownerKeyCacheKey]ownerKeyCacheEntry
+}
+
+// ownerKeyCacheKey identifies the devices that share an owner key
+type ownerKeyCacheKey struct {
+	model string
+	lot   string
+	did   bool // The device is in the did_signover rollout
+}
+
+// ownerKeyCacheEntry is a cached owner key lookup
+type ownerKeyCacheEntry struct {
+	result  OwnerKeyResult
+	expires time.Time
 }
 
 // NewOwnerKeyService creates a new owner key service
-func NewOwnerKeyService(executor *ExternalCommandExecutor) *OwnerKeyService {
+func NewOwnerKeyService(executor *ExternalCommandExecutor, config *OwnerSignoverConfig, didConfig *DIDCache, rollouts *RolloutsConfig, notifier *Notifier) *OwnerKeyService {
 	return &OwnerKeyService{
-		executor: executor,
+		executor:  executor,
+		config:    config,
+		didConfig: didConfig,
+		jwks:      NewJWKSCache(didConfig.MaxAge),
+		rollouts:  rollouts,
+		notifier:  notifier,
+		cache:     map[ownerKeyCacheKey]ownerKeyCacheEntry{},
 	}
 }
 
+// SetDIDRotations makes DID resolution follow owner-announced key rotations
+// and admin pins
+func (o *OwnerKeyService) SetDIDRotations(rotations *DIDRotations, pins *DIDPins) {
+	o.rotations = rotations
+	o.pins = pins
+}
+
 // OwnerKeyResult contains the result of owner key resolution
 type OwnerKeyResult struct {
-	PublicKey any    // The resolved public key
-	DIDURL    string // The DID URL (voucherRecipientURL) if available
+	PublicKey         any                 // The resolved public key
+	CertChain         []*x509.Certificate // Owner certificate chain, if one was supplied
+	KeyEncoding       string              // Owner key encoding named by the owner entry or declared by its DID
+	DIDURL            string              // The DID URL (voucherRecipientURL) if available
+	UploadAuthProfile string              // Upload auth profile named by the owner entry, if any
+	Customer          string              // Customer/licensee ID named by the owner entry, if any
+}
+
+// GetOwnerKey retrieves an owner key for the given device. With a cache TTL,
+// a key looked up for a model in a lot is reused for the rest of the lot.
+func (o *OwnerKeyService) GetOwnerKey(ctx context.Context, serial, model, lot string) (*OwnerKeyResult, error) {
+	useDID := o.rollouts == nil || o.rollouts.DIDSignover.Includes(RolloutDIDSignover, serial, model)
+	key := ownerKeyCacheKey{model: model, lot: lot, did: useDID}
+	if result, ok := o.cached(key); ok {
+		fmt.Printf("🔑 Owner key for model %s, lot %s from cache\n", serialRules.Model(model), lot)
+		return result, nil
+	}
+
+	result, noCache, err := o.lookup(ctx, serial, model, lot, useDID)
+	if err != nil {
+		return nil, err
+	}
+	if !noCache {
+		o.store(key, result)
+	}
+	return result, nil
+}
+
+// cached returns an unexpired cached result. Devices outside a lot are never cached.
+func (o *OwnerKeyService) cached(key ownerKeyCacheKey) (*OwnerKeyResult, bool) {
+	if o.config == nil || o.config.CacheTTL <= 0 || key.lot == "" {
+		return nil, false
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	entry, ok := o.cache[key]
+	if !ok || stationClock.Now().After(entry.expires) {
+		return nil, false
+	}
+	result := entry.result
+	return &result, true
+}
+
+// store caches a result and drops expired entries
+func (o *OwnerKeyService) store(key ownerKeyCacheKey, result *OwnerKeyResult) {
+	if o.config == nil || o.config.CacheTTL <= 0 || key.lot == "" {
+		return
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	now := stationClock.Now()
+	for k, entry := range o.cache {
+		if now.After(entry.expires) {
+			delete(o.cache, k)
+		}
+	}
+	o.cache[key] = ownerKeyCacheEntry{result: *result, expires: now.Add(o.config.CacheTTL)}
 }
 
-// GetOwnerKey retrieves an owner key for the given device
-func (o *OwnerKeyService) GetOwnerKey(ctx context.Context, serial, model string) (*OwnerKeyResult, error) {
+// lookup runs the owner key command and reports whether the result must not be
+// cached. A device outside the did_signover rollout (useDID false) is signed
+// over to owner_key_pem even if the entry names a DID.
+func (o *OwnerKeyService) lookup(ctx context.Context, serial, model, lot string, useDID bool) (*OwnerKeyResult, bool, error) {
 	variables := map[string]string{
 		"serialno": serial,
 		"model":    model,
+		"lot":      lot,
 		"guid":     "", // Not used for owner key retrieval
 	}
 
+	if err := faultInjector.Inject(ctx, FaultStageOwnerKey); err != nil {
+		return nil, false, fmt.Errorf("failed to execute owner key command: %w", err)
+	}
 	output, err := o.executor.Execute(ctx, variables)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute owner key command: %w", err)
+		return nil, false, fmt.Errorf("failed to execute owner key command: %w", err)
 	}
 
 	// Parse JSON response
 	var response OwnerKeyResponse
 	if err := json.Unmarshal([]byte(output), &response); err != nil {
-		return nil, fmt.Errorf("failed to parse owner key response: %w", err)
+		return nil, false, fmt.Errorf("failed to parse owner key response: %w", err)
 	}
 
 	if response.Error != "" {
-		return nil, fmt.Errorf("owner key service error: %s", response.Error)
+		return nil, false, fmt.Errorf("%w: owner key service error: %s", ErrOwnerKeyPolicy, response.Error)
+	}
+
+	if response.OwnerDID != "" && !useDID {
+		if response.OwnerKeyPEM == "" {
+			return nil, false, fmt.Errorf("%w: owner entry names only DID %s, but model %s is outside the did_signover rollout",
+				ErrOwnerKeyPolicy, response.OwnerDID, serialRules.Model(model))
+		}
+		fmt.Printf("🚦 Model %s outside the did_signover rollout, using owner_key_pem instead of %s\n", serialRules.Model(model), response.OwnerDID)
 	}
 
-	// Handle DID response
-	if response.OwnerDID != "" {
-		return o.handleDIDResponse(ctx, response.OwnerDID)
+	// Handle DID response, or an owner key URL, which is resolved like a DID
+	// but isn't part of the did_signover rollout
+	if response.OwnerKeyURL != "" && !isOwnerKeyURL(response.OwnerKeyURL) {
+		return nil, false, fmt.Errorf("%w: owner_key_url %s is not an https:// URL", ErrOwnerKeyPolicy, response.OwnerKeyURL)
+	}
+	if response.OwnerKeyKID != "" {
+		if response.OwnerKeyURL == "" || strings.Contains(response.OwnerKeyURL, "#") {
+			return nil, false, fmt.Errorf("%w: owner_key_kid needs an owner_key_url without a #kid", ErrOwnerKeyPolicy)
+		}
+		response.OwnerKeyURL += "#" + url.PathEscape(response.OwnerKeyKID)
+	}
+	remoteOwner := response.OwnerKeyURL
+	if response.OwnerDID != "" && useDID {
+		remoteOwner = response.OwnerDID
+	}
+	if remoteOwner != "" {
+		result, err := o.handleDIDResponse(ctx, remoteOwner)
+		if err != nil {
+			return nil, false, err
+		}
+		result.UploadAuthProfile = response.UploadAuthProfile
+		result.Customer = response.Customer
+		if response.KeyEncoding != "" {
+			result.KeyEncoding = response.KeyEncoding
+		}
+		return result, response.NoCache, nil
 	}
 
 	// Handle PEM response (existing logic)
 	if response.OwnerKeyPEM == "" {
-		return nil, fmt.Errorf("no owner key returned")
+		return nil, false, fmt.Errorf("no owner key returned")
 	}
 
 	publicKey, err := parsePublicKeyFromPEM([]byte(response.OwnerKeyPEM))
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse PEM key: %w", err)
+		return nil, false, fmt.Errorf("failed to parse PEM key: %w", err)
+	}
+	chain, err := parseCertificateChainPEM([]byte(response.OwnerKeyPEM))
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to parse PEM certificate chain: %w", err)
 	}
 
 	return &OwnerKeyResult{
-		PublicKey: publicKey,
-		DIDURL:    "", // PEM keys don't have DID URLs
-	}, nil
+		PublicKey:         publicKey,
+		CertChain:         chain,
+		KeyEncoding:       response.KeyEncoding,
+		DIDURL:            "", // PEM keys don't have DID URLs
+		UploadAuthProfile: response.UploadAuthProfile,
+		Customer:          response.Customer,
+	}, response.NoCache, nil
+}
+
+// ResolveOwner resolves an owner named by a signover policy: a DID or owner
+// key URL, resolved like one returned by the owner key command, or a PEM
+// public key or chain
+func (o *OwnerKeyService) ResolveOwner(ctx context.Context, owner, keyEncoding string) (*OwnerKeyResult, error) {
+	if strings.HasPrefix(owner, "did:") || isOwnerKeyURL(owner) {
+		result, err := o.handleDIDResponse(ctx, owner)
+		if err != nil {
+			return nil, err
+		}
+		if keyEncoding != "" {
+			result.KeyEncoding = keyEncoding
+		}
+		return result, nil
+	}
+	publicKey, err := parseStaticPublicKey(owner)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse policy owner key: %w", err)
+	}
+	chain, err := parseCertificateChainPEM([]byte(owner))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse policy owner certificate chain: %w", err)
+	}
+	return &OwnerKeyResult{PublicKey: publicKey, CertChain: chain, KeyEncoding: keyEncoding}, nil
 }
 
 // handleDIDResponse handles a DID response from the callback
 func (o *OwnerKeyService) handleDIDResponse(ctx context.Context, didURI string) (*OwnerKeyResult, error) {
-	// Create a DID resolver (without caching for dynamic callbacks)
-	resolver := NewDIDResolver(nil, &DIDCache{Enabled: false})
+	// A resolver without session state never reads or writes the did_cache
+	// table, so a DID from the callback is fetched on every signover and a
+	// rotated owner key is used at once. Owner key URL documents are kept only
+	// as long as their own HTTP caching headers allow. The configured SSRF
+	// guard and domain policy still apply.
+	resolver := NewDIDResolver(nil, o.didConfig)
+	resolver.rotations = o.rotations
+	resolver.pins = o.pins
+	resolver.jwks = o.jwks
 
 	publicKey, didURL, err := resolver.ResolveDIDKey(ctx, didURI)
 	if err != nil {
+		o.notifier.RecordFailure("did_resolution:"+didURI, err.Error())
 		return nil, fmt.Errorf("failed to resolve DID %s: %w", didURI, err)
 	}
+	o.notifier.RecordSuccess("did_resolution:" + didURI)
+
+	// A DID whose key carries an x5c chain declares that the owner expects X5CHAIN
+	if chain, ok := publicKey.([]*x509.Certificate); ok {
+		return &OwnerKeyResult{
+			PublicKey:   chain[0].PublicKey,
+			CertChain:   chain,
+			KeyEncoding: OwnerKeyEncodingX5Chain,
+			DIDURL:      didURL,
+		}, nil
+	}
 
 	return &OwnerKeyResult{
 		PublicKey: publicKey,
@@ -135,3 +332,32 @@ func parseCertificatePublicKey(data []byte) (any, error) {
 	}
 	return cert.PublicKey, nil
 }
+
+// validateOwnerSignover checks that owner_signover names a mode and what the
+// mode needs. Vouchers are left unextended only with mode "none", never
+// because a key is missing.
+func validateOwnerSignover(config *OwnerSignoverConfig) error {
+	switch config.Mode {
+	case "static":
+		if config.StaticPublicKey == "" && config.StaticDID == "" {
+			return fmt.Errorf("voucher_management.owner_signover: mode static needs static_public_key or static_did; use mode none to deliver vouchers without owner signover")
+		}
+		if config.StaticPublicKey != "" {
+			if _, err := parseStaticPublicKey(config.StaticPublicKey); err != nil {
+				return fmt.Errorf("voucher_management.owner_signover.static_public_key: %w", err)
+			}
+		}
+	case "dynamic":
+		if config.ExternalCommand == "" {
+			return fmt.Errorf("voucher_management.owner_signover: mode dynamic needs external_command")
+		}
+	case "none":
+		// "none" is also the default, so an owner configured without a mode is a mistake
+		if config.StaticPublicKey != "" || config.StaticDID != "" || config.ExternalCommand != "" {
+			return fmt.Errorf("voucher_management.owner_signover: an owner is configured but mode is none; set mode static or dynamic")
+		}
+	default:
+		return fmt.Errorf("voucher_management.owner_signover.mode: %q is not static, dynamic or none", config.Mode)
+	}
+	return nil
+}
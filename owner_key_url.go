@@ -0,0 +1,218 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// maxOwnerKeyDocument bounds the size of a fetched owner key document
+const maxOwnerKeyDocument = 1 << 20
+
+// Owners that can't host a DID document can publish their key at an HTTPS
+// URL instead, e.g. https://acme.com/.well-known/fdo-owner-key. The URL is
+// used wherever a DID is: it is returned as owner_key_url by the owner key
+// command or named as a policy owner, and it is resolved by the DID resolver
+// with the same domain lists, SSRF guard, cache, pins and rotation checks.
+// The URL serves either PEM (a public key or a certificate chain, leaf first)
+// or a JWKS, whose key is named by the URL's fragment (https://.../jwks#kid)
+// or picked by did_cache.jwks_key_selection. A JWKS can carry the
+// fido-device-onboarding extension of a DID document, with
+// voucherRecipientURL and nextRotation.
+
+// isOwnerKeyURL reports whether an owner is named by a well-known HTTPS URL
+func isOwnerKeyURL(owner string) bool {
+	return strings.HasPrefix(owner, "https://")
+}
+
+// ownerKeyURLDomain returns the lowercased host name of an owner key URL
+func ownerKeyURLDomain(ownerURL string) (string, error) {
+	u, err := url.Parse(ownerURL)
+	if err != nil || u.Scheme != "https" || u.Hostname() == "" || u.User != nil {
+		return "", fmt.Errorf("invalid owner key URL %s: want https://host/path", ownerURL)
+	}
+	return strings.ToLower(u.Hostname()), nil
+}
+
+// fetchOwnerKeyURL fetches and parses the owner key at a well-known URL
+func (r *DIDResolver) fetchOwnerKeyURL(ctx context.Context, ownerURL string, now time.Time) (crypto.PublicKey, string, error) {
+	host, err := ownerKeyURLDomain(ownerURL)
+	if err != nil {
+		r.updateCacheError(ctx, ownerURL, now, err.Error())
+		return nil, "", err
+	}
+	// Reject IP literals in blocked ranges before touching the network
+	if err := r.guard.CheckHost(host); err != nil {
+		r.updateCacheError(ctx, ownerURL, now, err.Error())
+		return nil, "", err
+	}
+
+	// The fragment names the key of a JWKS; it is not part of the request
+	fetchURL, kid, _ := strings.Cut(ownerURL, "#")
+	if unescaped, err := url.PathUnescape(kid); err == nil {
+		kid = unescaped
+	}
+	body, err := r.fetchOwnerKeyDocument(ctx, fetchURL, now)
+	if err != nil {
+		r.updateCacheError(ctx, ownerURL, now, err.Error())
+		return nil, "", err
+	}
+
+	publicKey, didURL, next, err := r.parseOwnerKeyDocument(body, kid)
+	if err != nil {
+		r.updateCacheError(ctx, ownerURL, now, fmt.Sprintf("failed to parse owner key: %v", err))
+		return nil, "", fmt.Errorf("failed to parse owner key from %s: %w", ownerURL, err)
+	}
+
+	// A key that replaces the URL's previous one is checked before it is used
+	publicKey, didURL, err = r.rotations.admit(ctx, ownerURL, publicKey, didURL, next)
+	if err != nil {
+		r.updateCacheError(ctx, ownerURL, now, err.Error())
+		return nil, "", err
+	}
+
+	publicKeyBytes, err := serializePublicKey(publicKey)
+	if err != nil {
+		r.updateCacheError(ctx, ownerURL, now, fmt.Sprintf("failed to serialize public key: %v", err))
+		return nil, "", fmt.Errorf("failed to serialize public key: %w", err)
+	}
+	entry := &DIDCacheEntry{
+		DIDURI:             ownerURL,
+		PublicKey:          publicKeyBytes,
+		DIDURL:             didURL,
+		Timestamp:          now,
+		LastRefreshAttempt: now,
+		LastRefreshError:   "",
+		LastUsed:           now,
+	}
+	if err := r.updateCache(ctx, entry); err != nil {
+		fmt.Printf("⚠️  Failed to update DID cache: %v\n", err)
+	}
+	return publicKey, didURL, nil
+}
+
+// fetchOwnerKeyDocument GETs an owner key document, reusing or revalidating
+// the copy in the JWKS cache as its HTTP caching headers allow
+func (r *DIDResolver) fetchOwnerKeyDocument(ctx context.Context, fetchURL string, now time.Time) ([]byte, error) {
+	cached, ok := r.jwks.lookup(fetchURL)
+	if ok && now.Before(cached.expires) {
+		return cached.body, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", fetchURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/jwk-set+json, application/x-pem-file, */*")
+	if ok && cached.etag != "" {
+		req.Header.Set("If-None-Match", cached.etag)
+	}
+	if ok && cached.lastModified != "" {
+		req.Header.Set("If-Modified-Since", cached.lastModified)
+	}
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch owner key: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && ok:
+		r.jwks.store(fetchURL, cached, resp.Header, now)
+		return cached.body, nil
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		r.jwks.forget(fetchURL)
+		return nil, fmt.Errorf("%w: HTTP %d when fetching owner key", ErrDIDNotFound, resp.StatusCode)
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("HTTP %d when fetching owner key", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxOwnerKeyDocument+1))
+	if err == nil && len(body) > maxOwnerKeyDocument {
+		err = fmt.Errorf("larger than %d bytes", maxOwnerKeyDocument)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read owner key: %w", err)
+	}
+	r.jwks.store(fetchURL, jwksCacheEntry{body: body}, resp.Header, now)
+	return body, nil
+}
+
+// parseOwnerKeyDocument reads a PEM or JWKS owner key document, returning the
+// key (or certificate chain), and from a JWKS the voucherRecipientURL and
+// announced rotation. kid names the key of a JWKS; PEM has no key IDs.
+func (r *DIDResolver) parseOwnerKeyDocument(body []byte, kid string) (crypto.PublicKey, string, time.Time, error) {
+	if bytes.HasPrefix(bytes.TrimSpace(body), []byte("-----BEGIN")) {
+		if kid != "" {
+			return nil, "", time.Time{}, fmt.Errorf("PEM owner key has no key %q", kid)
+		}
+		chain, err := parseCertificateChainPEM(body)
+		if err != nil {
+			return nil, "", time.Time{}, err
+		}
+		if len(chain) > 0 {
+			return chain, "", time.Time{}, nil
+		}
+		publicKey, err := parsePublicKeyFromPEM(body)
+		return publicKey, "", time.Time{}, err
+	}
+
+	var doc struct {
+		Keys []map[string]any `json:"keys"`
+		FDO  struct {
+			VoucherRecipientURL string `json:"voucherRecipientURL"`
+		} `json:"fido-device-onboarding"`
+	}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil, "", time.Time{}, fmt.Errorf("neither PEM nor a JWKS: %w", err)
+	}
+	jwk, err := selectJWK(doc.Keys, kid, r.config.JWKSKeySelection)
+	if err != nil {
+		return nil, "", time.Time{}, err
+	}
+	publicKey, err := r.parseJWKPublicKey(jwk)
+	if err != nil {
+		return nil, "", time.Time{}, err
+	}
+	if err := checkJWKAlgorithm(jwk, publicKey); err != nil {
+		return nil, "", time.Time{}, err
+	}
+	return publicKey, doc.FDO.VoucherRecipientURL, parseNextRotation(body), nil
+}
+
+// parseJWKPublicKey decodes an EC (P-256, P-384) or RSA JWK, or the
+// certificate chain in its x5c member
+func (r *DIDResolver) parseJWKPublicKey(jwk map[string]any) (crypto.PublicKey, error) {
+	if x5c, ok := jwk["x5c"].([]any); ok && len(x5c) > 0 {
+		return r.parseX5C(x5c)
+	}
+	switch kty, _ := jwk["kty"].(string); kty {
+	case "EC":
+		return r.parseECJWK(jwk)
+	case "RSA":
+		return r.parseRSAJWK(jwk)
+	default:
+		return nil, fmt.Errorf("unsupported JWK key type %q", kty)
+	}
+}
+
+// jwkMember decodes a base64url member of a JWK
+func jwkMember(jwk map[string]any, name string) ([]byte, error) {
+	s, _ := jwk[name].(string)
+	value, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil || len(value) == 0 {
+		return nil, fmt.Errorf("JWK has no valid %q", name)
+	}
+	return value, nil
+}
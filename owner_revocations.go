@@ -0,0 +1,168 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// RevocationList names owners that must no longer receive vouchers
+type RevocationList struct {
+	Customers     []string `json:"customers"`      // Customer IDs
+	RecipientURLs []string `json:"recipient_urls"` // Owner voucherRecipientURLs
+	KeySHA256     []string `json:"key_sha256"`     // Hex SHA-256 of the owner's SubjectPublicKeyInfo
+}
+
+// OwnerRevocations refuses signover to revoked owners. The list is pushed by
+// the central management agent and kept in the station database so it still
+// applies after a restart without the control plane.
+type OwnerRevocations struct {
+	db *StationDB
+
+	mu   sync.RWMutex
+	list RevocationList
+}
+
+// NewOwnerRevocations creates an owner revocation list
+func NewOwnerRevocations(db *StationDB) *OwnerRevocations {
+	return &OwnerRevocations{db: db}
+}
+
+// Initialize creates the owner_revocations table if it doesn't exist and loads the stored list
+func (r *OwnerRevocations) Initialize(ctx context.Context) error {
+	_, err := r.db.db.ExecContext(ctx, `
+	CREATE TABLE IF NOT EXISTS owner_revocations (
+		kind TEXT NOT NULL,
+		value TEXT NOT NULL,
+		PRIMARY KEY (kind, value)
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create owner_revocations table: %w", err)
+	}
+
+	rows, err := r.db.db.QueryContext(ctx, `SELECT kind, value FROM owner_revocations ORDER BY kind, value`)
+	if err != nil {
+		return fmt.Errorf("failed to read owner revocations: %w", err)
+	}
+	defer rows.Close()
+	var list RevocationList
+	for rows.Next() {
+		var kind, value string
+		if err := rows.Scan(&kind, &value); err != nil {
+			return fmt.Errorf("failed to read owner revocations: %w", err)
+		}
+		switch kind {
+		case "customer":
+			list.Customers = append(list.Customers, value)
+		case "recipient_url":
+			list.RecipientURLs = append(list.RecipientURLs, value)
+		case "key_sha256":
+			list.KeySHA256 = append(list.KeySHA256, value)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read owner revocations: %w", err)
+	}
+
+	r.mu.Lock()
+	r.list = list
+	r.mu.Unlock()
+	return nil
+}
+
+// List returns the current revocation list
+func (r *OwnerRevocations) List() RevocationList {
+	if r == nil {
+		return RevocationList{}
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.list
+}
+
+// Replace stores a new revocation list in place of the current one
+func (r *OwnerRevocations) Replace(ctx context.Context, list RevocationList) error {
+	for i, fp := range list.KeySHA256 {
+		list.KeySHA256[i] = strings.ToLower(fp)
+	}
+
+	tx, err := r.db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to store owner revocations: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM owner_revocations`); err != nil {
+		return fmt.Errorf("failed to store owner revocations: %w", err)
+	}
+	for kind, values := range map[string][]string{
+		"customer":      list.Customers,
+		"recipient_url": list.RecipientURLs,
+		"key_sha256":    list.KeySHA256,
+	} {
+		for _, value := range values {
+			if _, err := tx.ExecContext(ctx,
+				`INSERT OR IGNORE INTO owner_revocations (kind, value) VALUES (?, ?)`, kind, value); err != nil {
+				return fmt.Errorf("failed to store owner revocations: %w", err)
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to store owner revocations: %w", err)
+	}
+
+	r.mu.Lock()
+	r.list = list
+	r.mu.Unlock()
+	return nil
+}
+
+// Check returns an error if the owner is revoked by customer, recipient URL or key
+func (r *OwnerRevocations) Check(customer, recipientURL string, ownerKey crypto.PublicKey) error {
+	if r == nil {
+		return nil
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if customer != "" && slices.Contains(r.list.Customers, customer) {
+		return fmt.Errorf("%w: customer %s is revoked", ErrOwnerKeyPolicy, customer)
+	}
+	if recipientURL != "" && slices.Contains(r.list.RecipientURLs, recipientURL) {
+		return fmt.Errorf("%w: voucher recipient %s is revoked", ErrOwnerKeyPolicy, recipientURL)
+	}
+	if fp := ownerKeySHA256(ownerKey); fp != "" && slices.Contains(r.list.KeySHA256, fp) {
+		return fmt.Errorf("%w: owner key %s is revoked", ErrOwnerKeyPolicy, fp)
+	}
+	return nil
+}
+
+// ownerKeySHA256 returns the hex SHA-256 of an owner key's SubjectPublicKeyInfo
+// (the leaf's, for a certificate chain), or "" if there is no key
+func ownerKeySHA256(key crypto.PublicKey) string {
+	if chain, ok := key.([]*x509.Certificate); ok {
+		if len(chain) == 0 {
+			return ""
+		}
+		key = chain[0].PublicKey
+	}
+	if key == nil {
+		return ""
+	}
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,541 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Policy expressions are a small subset of CEL (the Common Expression
+// Language), so a "when" condition reads the same as it would in CEL:
+//
+//	model.startsWith('GW-') && customer in ['ACME', 'ACME-EU']
+//	!serial.matches('^TEST') || size(lot) > 0
+//
+// Every variable is a string. There are string, int and bool literals, lists
+// of literals, the operators ! && || == != < <= > >= and in, the string
+// methods startsWith, endsWith, contains, matches, lowerAscii, upperAscii and
+// size, and the function size. Expressions are type checked when they are
+// compiled, so a policy that loads can't fail while a device waits.
+
+// Types of policy expression values
+const (
+	policyString     = "string"
+	policyInt        = "int"
+	policyBool       = "bool"
+	policyStringList = "list(string)"
+	policyIntList    = "list(int)"
+)
+
+// policyExpr is a compiled, type checked policy expression
+type policyExpr struct {
+	typ      string
+	eval     func(vars map[string]string) any
+	constant bool // A literal
+}
+
+// compiledPolicyExprs caches compiled conditions by source; policies are read
+// from the running config on each DI, so they are compiled on first use
+var compiledPolicyExprs sync.Map
+
+// compilePolicyCondition compiles a condition over the given variables. It
+// must be a bool; the empty condition is always true.
+func compilePolicyCondition(source string, variables []string) (*policyExpr, error) {
+	key := source + "\x00" + strings.Join(variables, ",")
+	if expr, ok := compiledPolicyExprs.Load(key); ok {
+		return expr.(*policyExpr), nil
+	}
+	expr := &policyExpr{typ: policyBool, eval: func(map[string]string) any { return true }}
+	if strings.TrimSpace(source) != "" {
+		var err error
+		if expr, err = compilePolicyExpr(source, variables); err != nil {
+			return nil, err
+		}
+		if expr.typ != policyBool {
+			return nil, fmt.Errorf("condition is a %s, not a bool", expr.typ)
+		}
+	}
+	compiledPolicyExprs.Store(key, expr)
+	return expr, nil
+}
+
+// compilePolicyExpr parses and type checks an expression
+func compilePolicyExpr(source string, variables []string) (*policyExpr, error) {
+	tokens, err := lexPolicyExpr(source)
+	if err != nil {
+		return nil, err
+	}
+	p := &policyParser{tokens: tokens, variables: variables}
+	expr, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != policyTokenEnd {
+		return nil, p.errorf(t, "unexpected %q", t.text)
+	}
+	return expr, nil
+}
+
+// Policy expression token kinds
+const (
+	policyTokenEnd = iota
+	policyTokenIdent
+	policyTokenString
+	policyTokenInt
+	policyTokenOp
+)
+
+type policyToken struct {
+	kind int
+	text string // Operator or identifier; the value of string literals
+	pos  int    // 1-based column
+}
+
+// policyOperators are matched longest first
+var policyOperators = []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "(", ")", "[", "]", ",", "."}
+
+// lexPolicyExpr splits an expression into tokens
+func lexPolicyExpr(source string) ([]policyToken, error) {
+	var tokens []policyToken
+	for i := 0; i < len(source); {
+		c := source[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '\'' || c == '"':
+			value, n, err := lexPolicyString(source[i:])
+			if err != nil {
+				return nil, fmt.Errorf("col %d: %w", i+1, err)
+			}
+			tokens = append(tokens, policyToken{kind: policyTokenString, text: value, pos: i + 1})
+			i += n
+		case c >= '0' && c <= '9':
+			j := i
+			for j < len(source) && source[j] >= '0' && source[j] <= '9' {
+				j++
+			}
+			tokens = append(tokens, policyToken{kind: policyTokenInt, text: source[i:j], pos: i + 1})
+			i = j
+		case isPolicyIdentByte(c):
+			j := i
+			for j < len(source) && (isPolicyIdentByte(source[j]) || source[j] >= '0' && source[j] <= '9') {
+				j++
+			}
+			tokens = append(tokens, policyToken{kind: policyTokenIdent, text: source[i:j], pos: i + 1})
+			i = j
+		default:
+			op := ""
+			for _, candidate := range policyOperators {
+				if strings.HasPrefix(source[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("col %d: unexpected character %q", i+1, c)
+			}
+			tokens = append(tokens, policyToken{kind: policyTokenOp, text: op, pos: i + 1})
+			i += len(op)
+		}
+	}
+	return append(tokens, policyToken{kind: policyTokenEnd, text: "end of expression", pos: len(source) + 1}), nil
+}
+
+// isPolicyIdentByte reports whether c can start an identifier
+func isPolicyIdentByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// lexPolicyString reads a quoted string literal and returns its value and length
+func lexPolicyString(source string) (string, int, error) {
+	quote := source[0]
+	var value strings.Builder
+	for i := 1; i < len(source); i++ {
+		switch c := source[i]; c {
+		case quote:
+			return value.String(), i + 1, nil
+		case '\\':
+			i++
+			if i == len(source) {
+				break
+			}
+			switch e := source[i]; e {
+			case '\\', '\'', '"':
+				value.WriteByte(e)
+			case 'n':
+				value.WriteByte('\n')
+			case 't':
+				value.WriteByte('\t')
+			default:
+				return "", 0, fmt.Errorf("unknown escape \\%c", e)
+			}
+		default:
+			value.WriteByte(c)
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string")
+}
+
+// policyParser is a recursive descent parser that type checks as it goes
+type policyParser struct {
+	tokens    []policyToken
+	next      int
+	variables []string
+}
+
+func (p *policyParser) peek() policyToken { return p.tokens[p.next] }
+
+func (p *policyParser) take() policyToken {
+	t := p.tokens[p.next]
+	if t.kind != policyTokenEnd {
+		p.next++
+	}
+	return t
+}
+
+// accept consumes the next token if it is the given operator
+func (p *policyParser) accept(op string) bool {
+	if t := p.peek(); t.kind == policyTokenOp && t.text == op {
+		p.next++
+		return true
+	}
+	return false
+}
+
+func (p *policyParser) expect(op string) error {
+	if !p.accept(op) {
+		t := p.peek()
+		return p.errorf(t, "expected %q, found %q", op, t.text)
+	}
+	return nil
+}
+
+func (p *policyParser) errorf(t policyToken, format string, args ...any) error {
+	return fmt.Errorf("col %d: %s", t.pos, fmt.Sprintf(format, args...))
+}
+
+// or := and ("||" and)*
+func (p *policyParser) or() (*policyExpr, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		if !p.accept("||") {
+			return left, nil
+		}
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		if left.typ != policyBool || right.typ != policyBool {
+			return nil, p.errorf(t, "|| needs bools, not %s and %s", left.typ, right.typ)
+		}
+		l, r := left.eval, right.eval
+		left = &policyExpr{typ: policyBool, eval: func(vars map[string]string) any { return l(vars).(bool) || r(vars).(bool) }}
+	}
+}
+
+// and := relation ("&&" relation)*
+func (p *policyParser) and() (*policyExpr, error) {
+	left, err := p.relation()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		if !p.accept("&&") {
+			return left, nil
+		}
+		right, err := p.relation()
+		if err != nil {
+			return nil, err
+		}
+		if left.typ != policyBool || right.typ != policyBool {
+			return nil, p.errorf(t, "&& needs bools, not %s and %s", left.typ, right.typ)
+		}
+		l, r := left.eval, right.eval
+		left = &policyExpr{typ: policyBool, eval: func(vars map[string]string) any { return l(vars).(bool) && r(vars).(bool) }}
+	}
+}
+
+// relation := unary [("==" | "!=" | "<" | "<=" | ">" | ">=" | "in") unary]
+func (p *policyParser) relation() (*policyExpr, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	t := p.peek()
+	op := t.text
+	switch {
+	case t.kind == policyTokenOp && slices.Contains([]string{"==", "!=", "<", "<=", ">", ">="}, op):
+	case t.kind == policyTokenIdent && op == "in":
+	default:
+		return left, nil
+	}
+	p.take()
+	right, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	l, r := left.eval, right.eval
+
+	if op == "in" {
+		if right.typ != "list("+left.typ+")" {
+			return nil, p.errorf(t, "in needs a list(%s), not %s", left.typ, right.typ)
+		}
+		return &policyExpr{typ: policyBool, eval: func(vars map[string]string) any {
+			return slices.Contains(r(vars).([]any), l(vars))
+		}}, nil
+	}
+	if left.typ != right.typ {
+		return nil, p.errorf(t, "cannot compare %s %s %s", left.typ, op, right.typ)
+	}
+	if op == "==" || op == "!=" {
+		if left.typ != policyString && left.typ != policyInt && left.typ != policyBool {
+			return nil, p.errorf(t, "cannot compare %s values", left.typ)
+		}
+		equal := op == "=="
+		return &policyExpr{typ: policyBool, eval: func(vars map[string]string) any { return (l(vars) == r(vars)) == equal }}, nil
+	}
+	if left.typ != policyString && left.typ != policyInt {
+		return nil, p.errorf(t, "cannot order %s values", left.typ)
+	}
+	return &policyExpr{typ: policyBool, eval: func(vars map[string]string) any {
+		var c int
+		if left.typ == policyInt {
+			c = cmp.Compare(l(vars).(int64), r(vars).(int64))
+		} else {
+			c = strings.Compare(l(vars).(string), r(vars).(string))
+		}
+		switch op {
+		case "<":
+			return c < 0
+		case "<=":
+			return c <= 0
+		case ">":
+			return c > 0
+		default:
+			return c >= 0
+		}
+	}}, nil
+}
+
+// unary := "!" unary | postfix
+func (p *policyParser) unary() (*policyExpr, error) {
+	t := p.peek()
+	if !p.accept("!") {
+		return p.postfix()
+	}
+	operand, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	if operand.typ != policyBool {
+		return nil, p.errorf(t, "! needs a bool, not %s", operand.typ)
+	}
+	o := operand.eval
+	return &policyExpr{typ: policyBool, eval: func(vars map[string]string) any { return !o(vars).(bool) }}, nil
+}
+
+// postfix := primary ("." method "(" [args] ")")*
+func (p *policyParser) postfix() (*policyExpr, error) {
+	expr, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept(".") {
+		t := p.take()
+		if t.kind != policyTokenIdent {
+			return nil, p.errorf(t, "expected a method name, found %q", t.text)
+		}
+		args, err := p.args()
+		if err != nil {
+			return nil, err
+		}
+		if expr, err = p.method(t, expr, args); err != nil {
+			return nil, err
+		}
+	}
+	return expr, nil
+}
+
+// args := "(" [or ("," or)*] ")"
+func (p *policyParser) args() ([]*policyExpr, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	var args []*policyExpr
+	for !p.accept(")") {
+		if len(args) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		arg, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	return args, nil
+}
+
+// method type checks a string method call
+func (p *policyParser) method(t policyToken, target *policyExpr, args []*policyExpr) (*policyExpr, error) {
+	if t.text == "size" && len(args) == 0 {
+		return p.size(t, target)
+	}
+	if target.typ != policyString {
+		return nil, p.errorf(t, "%s has no method %s", target.typ, t.text)
+	}
+	s := target.eval
+	switch t.text {
+	case "lowerAscii", "upperAscii":
+		if len(args) != 0 {
+			return nil, p.errorf(t, "%s takes no arguments", t.text)
+		}
+		convert := strings.ToLower
+		if t.text == "upperAscii" {
+			convert = strings.ToUpper
+		}
+		return &policyExpr{typ: policyString, eval: func(vars map[string]string) any { return convert(s(vars).(string)) }}, nil
+	case "startsWith", "endsWith", "contains", "matches":
+	default:
+		return nil, p.errorf(t, "unknown method %s", t.text)
+	}
+	if len(args) != 1 || args[0].typ != policyString {
+		return nil, p.errorf(t, "%s takes one string", t.text)
+	}
+	a := args[0].eval
+	var test func(s, arg string) bool
+	switch t.text {
+	case "startsWith":
+		test = strings.HasPrefix
+	case "endsWith":
+		test = strings.HasSuffix
+	case "contains":
+		test = strings.Contains
+	case "matches":
+		// The pattern must be a literal, so it is compiled (and checked) here
+		if !args[0].constant {
+			return nil, p.errorf(t, "matches takes a string literal")
+		}
+		re, err := regexp.Compile(a(nil).(string))
+		if err != nil {
+			return nil, p.errorf(t, "invalid pattern: %v", err)
+		}
+		return &policyExpr{typ: policyBool, eval: func(vars map[string]string) any { return re.MatchString(s(vars).(string)) }}, nil
+	}
+	return &policyExpr{typ: policyBool, eval: func(vars map[string]string) any { return test(s(vars).(string), a(vars).(string)) }}, nil
+}
+
+// size is the length of a string, in characters, or of a list
+func (p *policyParser) size(t policyToken, target *policyExpr) (*policyExpr, error) {
+	v := target.eval
+	switch target.typ {
+	case policyString:
+		return &policyExpr{typ: policyInt, eval: func(vars map[string]string) any { return int64(len([]rune(v(vars).(string)))) }}, nil
+	case policyStringList, policyIntList:
+		return &policyExpr{typ: policyInt, eval: func(vars map[string]string) any { return int64(len(v(vars).([]any))) }}, nil
+	}
+	return nil, p.errorf(t, "size needs a string or list, not %s", target.typ)
+}
+
+// primary := literal | variable | "size" args | "(" or ")" | "[" [literal ("," literal)*] "]"
+func (p *policyParser) primary() (*policyExpr, error) {
+	t := p.take()
+	switch t.kind {
+	case policyTokenString:
+		value := t.text
+		return &policyExpr{typ: policyString, eval: func(map[string]string) any { return value }, constant: true}, nil
+	case policyTokenInt:
+		value, err := strconv.ParseInt(t.text, 10, 64)
+		if err != nil {
+			return nil, p.errorf(t, "invalid number %s", t.text)
+		}
+		return &policyExpr{typ: policyInt, eval: func(map[string]string) any { return value }}, nil
+	case policyTokenIdent:
+		switch t.text {
+		case "true", "false":
+			value := t.text == "true"
+			return &policyExpr{typ: policyBool, eval: func(map[string]string) any { return value }}, nil
+		case "size":
+			args, err := p.args()
+			if err != nil {
+				return nil, err
+			}
+			if len(args) != 1 {
+				return nil, p.errorf(t, "size takes one argument")
+			}
+			return p.size(t, args[0])
+		}
+		if !slices.Contains(p.variables, t.text) {
+			return nil, p.errorf(t, "unknown variable %s (have %s)", t.text, strings.Join(p.variables, ", "))
+		}
+		name := t.text
+		return &policyExpr{typ: policyString, eval: func(vars map[string]string) any { return vars[name] }}, nil
+	case policyTokenOp:
+		switch t.text {
+		case "(":
+			expr, err := p.or()
+			if err != nil {
+				return nil, err
+			}
+			return expr, p.expect(")")
+		case "[":
+			return p.list(t)
+		}
+	}
+	return nil, p.errorf(t, "unexpected %q", t.text)
+}
+
+// list is a list of string or int literals; the "[" is already taken
+func (p *policyParser) list(t policyToken) (*policyExpr, error) {
+	var values []any
+	typ := ""
+	for !p.accept("]") {
+		if len(values) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		element := p.take()
+		var value any
+		switch element.kind {
+		case policyTokenString:
+			value = element.text
+			if typ == "" {
+				typ = policyStringList
+			}
+		case policyTokenInt:
+			n, err := strconv.ParseInt(element.text, 10, 64)
+			if err != nil {
+				return nil, p.errorf(element, "invalid number %s", element.text)
+			}
+			value = n
+			if typ == "" {
+				typ = policyIntList
+			}
+		default:
+			return nil, p.errorf(element, "lists hold string or int literals, not %q", element.text)
+		}
+		if (typ == policyStringList) != (element.kind == policyTokenString) {
+			return nil, p.errorf(element, "list mixes strings and ints")
+		}
+		values = append(values, value)
+	}
+	if typ == "" {
+		return nil, p.errorf(t, "empty list")
+	}
+	return &policyExpr{typ: typ, eval: func(map[string]string) any { return values }}, nil
+}
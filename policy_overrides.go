@@ -0,0 +1,485 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Checks an override token can waive for the serials it names
+const (
+	OverrideQuota           = "quota"            // A hard quota is exhausted; the device is counted anyway
+	OverrideSchedule        = "schedule"         // The manufacturing window is closed
+	OverrideSignoverPolicy  = "signover_policy"  // A signover policy rule rejects the device
+	OverrideGUIDReservation = "guid_reservation" // Reservations are required and the serial has none
+)
+
+var overrideChecks = []string{OverrideQuota, OverrideSchedule, OverrideSignoverPolicy, OverrideGUIDReservation}
+
+// overrideTokenPrefix starts every override token and versions its format
+const overrideTokenPrefix = "fdo-override.v1."
+
+// defaultOverrideMaxTTL bounds how long an override token is valid
+const defaultOverrideMaxTTL = 8 * time.Hour
+
+// ErrOverrideToken is returned for a token that is forged, expired or already applied
+var ErrOverrideToken = errors.New("invalid override token")
+
+// OverrideClaims is what an override token grants: the checks it waives for
+// the named serials until it expires
+type OverrideClaims struct {
+	ID        string    `json:"id"`
+	Serials   []string  `json:"serials"`
+	Checks    []string  `json:"checks"`
+	Reason    string    `json:"reason"`
+	IssuedBy  string    `json:"issued_by"`
+	IssuedAt  time.Time `json:"issued_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// IssueOverrideRequest is the body of POST /api/overrides
+type IssueOverrideRequest struct {
+	Serials []string `json:"serials"`
+	Checks  []string `json:"checks"`
+	Reason  string   `json:"reason"`
+	TTL     string   `json:"ttl"` // Go duration, e.g. "2h" (default and maximum: override_tokens.max_ttl)
+}
+
+// IssuedOverride is a new override token with its claims
+type IssuedOverride struct {
+	Token string `json:"token"`
+	OverrideClaims
+}
+
+// ApplyOverrideRequest is the body of POST /api/overrides/apply. The operator
+// signs in as for opening a batch.
+type ApplyOverrideRequest struct {
+	Token string `json:"token"`
+	OperatorCredentials
+}
+
+// PolicyOverride is an applied override token
+type PolicyOverride struct {
+	OverrideClaims
+	AppliedBy string     `json:"applied_by"`
+	AppliedAt time.Time  `json:"applied_at"`
+	Uses      int        `json:"uses"` // Times a check was waived under it
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// PolicyOverrides lets devices through policy checks on an admin's word. An
+// admin issues a token, signed with the station's override key, that waives
+// some checks for some serials for a limited time; an operator applies it at
+// the station. Every issue, application and waived check is audited. A nil
+// *PolicyOverrides waives nothing.
+type PolicyOverrides struct {
+	config       *OverrideTokenConfig
+	key          []byte
+	db           *StationDB
+	operatorGate *OperatorGate
+	auditLog     *AuditLog
+}
+
+// NewPolicyOverrides creates the override service, or returns nil if override
+// tokens are disabled
+func NewPolicyOverrides(config *OverrideTokenConfig, db *StationDB, operatorGate *OperatorGate, auditLog *AuditLog) (*PolicyOverrides, error) {
+	if !config.Enabled {
+		return nil, nil
+	}
+	if config.KeyFile == "" {
+		return nil, fmt.Errorf("override_tokens needs a key_file")
+	}
+	key, err := os.ReadFile(config.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read override key: %w", err)
+	}
+	key = []byte(strings.TrimSpace(string(key)))
+	if len(key) < 32 {
+		return nil, fmt.Errorf("override_tokens: key must be at least 32 bytes")
+	}
+	return &PolicyOverrides{config: config, key: key, db: db, operatorGate: operatorGate, auditLog: auditLog}, nil
+}
+
+// Initialize creates the policy_overrides table if it doesn't exist
+func (o *PolicyOverrides) Initialize(ctx context.Context) error {
+	if o == nil {
+		return nil
+	}
+	if _, err := o.db.db.ExecContext(ctx, `
+	CREATE TABLE IF NOT EXISTS policy_overrides (
+		id TEXT PRIMARY KEY,
+		serials TEXT NOT NULL,
+		checks TEXT NOT NULL,
+		reason TEXT NOT NULL,
+		issued_by TEXT NOT NULL,
+		issued_at INTEGER NOT NULL,
+		expires_at INTEGER NOT NULL,
+		applied_by TEXT NOT NULL,
+		applied_at INTEGER NOT NULL,
+		uses INTEGER NOT NULL DEFAULT 0,
+		revoked_at INTEGER
+	)`); err != nil {
+		return fmt.Errorf("failed to create policy_overrides table: %w", err)
+	}
+	return nil
+}
+
+// maxTTL is the longest validity of a token
+func (o *PolicyOverrides) maxTTL() time.Duration {
+	if o.config.MaxTTL > 0 {
+		return o.config.MaxTTL
+	}
+	return defaultOverrideMaxTTL
+}
+
+// Issue creates a signed override token for the admin who asked for it
+func (o *PolicyOverrides) Issue(ctx context.Context, req IssueOverrideRequest) (*IssuedOverride, error) {
+	if len(req.Serials) == 0 {
+		return nil, fmt.Errorf("serials are required")
+	}
+	if len(req.Checks) == 0 {
+		return nil, fmt.Errorf("checks are required (any of %s)", strings.Join(overrideChecks, ", "))
+	}
+	for _, check := range req.Checks {
+		if !slices.Contains(overrideChecks, check) {
+			return nil, fmt.Errorf("unknown check %q (want %s)", check, strings.Join(overrideChecks, ", "))
+		}
+	}
+	if strings.TrimSpace(req.Reason) == "" {
+		return nil, fmt.Errorf("reason is required")
+	}
+	ttl := o.maxTTL()
+	if req.TTL != "" {
+		d, err := time.ParseDuration(req.TTL)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid ttl %q", req.TTL)
+		}
+		if d > ttl {
+			return nil, fmt.Errorf("ttl %s exceeds override_tokens.max_ttl %s", d, ttl)
+		}
+		ttl = d
+	}
+	id, err := newUUID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate override ID: %w", err)
+	}
+
+	now := stationClock.Now().UTC().Truncate(time.Second)
+	claims := OverrideClaims{
+		ID:        id,
+		Checks:    req.Checks,
+		Reason:    req.Reason,
+		IssuedBy:  adminIdentity(ctx),
+		IssuedAt:  now,
+		ExpiresAt: now.Add(ttl),
+	}
+	for _, serial := range req.Serials {
+		serial, _ = serialRules.Normalize(strings.TrimSpace(serial), "")
+		if serial == "" {
+			return nil, fmt.Errorf("serials must not be empty")
+		}
+		claims.Serials = append(claims.Serials, serial)
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode override: %w", err)
+	}
+	body := base64.RawURLEncoding.EncodeToString(payload)
+	token := overrideTokenPrefix + body + "." + base64.RawURLEncoding.EncodeToString(o.mac(body))
+
+	o.auditLog.Record(ctx, AuditEvent{
+		Event:  "override_issued",
+		Detail: fmt.Sprintf("override %s by %s waives %s for %s until %s: %s", id, claims.IssuedBy, strings.Join(claims.Checks, ", "), o.serials(claims.Serials), claims.ExpiresAt.Format(time.RFC3339), claims.Reason),
+	})
+	return &IssuedOverride{Token: token, OverrideClaims: claims}, nil
+}
+
+// mac signs the encoded claims of a token
+func (o *PolicyOverrides) mac(body string) []byte {
+	mac := hmac.New(sha256.New, o.key)
+	mac.Write([]byte(overrideTokenPrefix + body))
+	return mac.Sum(nil)
+}
+
+// verify checks a token's signature and expiry and returns its claims
+func (o *PolicyOverrides) verify(token string) (*OverrideClaims, error) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(token), overrideTokenPrefix)
+	if !ok {
+		return nil, fmt.Errorf("%w: not an override token", ErrOverrideToken)
+	}
+	body, signature, ok := strings.Cut(rest, ".")
+	if !ok {
+		return nil, fmt.Errorf("%w: malformed", ErrOverrideToken)
+	}
+	sum, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(sum, o.mac(body)) {
+		return nil, fmt.Errorf("%w: bad signature", ErrOverrideToken)
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed", ErrOverrideToken)
+	}
+	var claims OverrideClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("%w: malformed claims: %v", ErrOverrideToken, err)
+	}
+	if !stationClock.Now().Before(claims.ExpiresAt) {
+		return nil, fmt.Errorf("%w: expired at %s", ErrOverrideToken, claims.ExpiresAt.Format(time.RFC3339))
+	}
+	return &claims, nil
+}
+
+// Apply signs the operator in and puts a token into effect. A token is applied once.
+func (o *PolicyOverrides) Apply(ctx context.Context, req ApplyOverrideRequest) (*PolicyOverride, error) {
+	claims, err := o.verify(req.Token)
+	if err != nil {
+		o.auditLog.Record(ctx, AuditEvent{Event: "override_rejected", Detail: err.Error()})
+		return nil, err
+	}
+	if err := o.operatorGate.Authenticate(ctx, req.OperatorCredentials); err != nil {
+		return nil, err
+	}
+	appliedBy := req.OperatorID
+	if appliedBy == "" {
+		appliedBy = adminIdentity(ctx)
+	}
+
+	serials, _ := json.Marshal(claims.Serials)
+	checks, _ := json.Marshal(claims.Checks)
+	now := stationClock.Now()
+	result, err := o.db.db.ExecContext(ctx, `
+	INSERT OR IGNORE INTO policy_overrides (id, serials, checks, reason, issued_by, issued_at, expires_at, applied_by, applied_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		claims.ID, string(serials), string(checks), claims.Reason, claims.IssuedBy, claims.IssuedAt.Unix(), claims.ExpiresAt.Unix(), appliedBy, now.Unix())
+	if err != nil {
+		return nil, fmt.Errorf("failed to store override: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil, fmt.Errorf("%w: override %s is already applied", ErrOverrideToken, claims.ID)
+	}
+
+	o.auditLog.Record(ctx, AuditEvent{
+		Event:  "override_applied",
+		Detail: fmt.Sprintf("override %s applied by %s: waives %s for %s until %s", claims.ID, appliedBy, strings.Join(claims.Checks, ", "), o.serials(claims.Serials), claims.ExpiresAt.Format(time.RFC3339)),
+	})
+	fmt.Printf("🎫 Override %s applied by %s: %s for %s\n", claims.ID, appliedBy, strings.Join(claims.Checks, ", "), o.serials(claims.Serials))
+	return &PolicyOverride{OverrideClaims: *claims, AppliedBy: appliedBy, AppliedAt: now}, nil
+}
+
+// Revoke ends an applied override before it expires
+func (o *PolicyOverrides) Revoke(ctx context.Context, id string) (*PolicyOverride, error) {
+	result, err := o.db.db.ExecContext(ctx,
+		`UPDATE policy_overrides SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL`, stationClock.Now().Unix(), id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke override: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil, fmt.Errorf("%w: override %s is not applied, or already revoked", ErrVoucherNotFound, id)
+	}
+	o.auditLog.Record(ctx, AuditEvent{Event: "override_revoked", Detail: fmt.Sprintf("override %s revoked by %s", id, adminIdentity(ctx))})
+	overrides, err := o.query(ctx, ` WHERE id = ?`, id)
+	if err != nil || len(overrides) == 0 {
+		return nil, err
+	}
+	return &overrides[0], nil
+}
+
+// Allow reports whether an applied override waives a failed check for a
+// device, and audits the waiver. cause is the error the check failed with.
+func (o *PolicyOverrides) Allow(ctx context.Context, check, serial, guid string, cause error) bool {
+	if o == nil || serial == "" {
+		return false
+	}
+	serial, _ = serialRules.Normalize(serial, "")
+	now := stationClock.Now()
+	active, err := o.query(ctx, ` WHERE revoked_at IS NULL AND expires_at > ? ORDER BY applied_at`, now.Unix())
+	if err != nil {
+		fmt.Printf("⚠️  Failed to read overrides: %v\n", err)
+		return false
+	}
+	for _, override := range active {
+		if !slices.Contains(override.Checks, check) || !slices.Contains(override.Serials, serial) {
+			continue
+		}
+		if _, err := o.db.db.ExecContext(ctx, `UPDATE policy_overrides SET uses = uses + 1 WHERE id = ?`, override.ID); err != nil {
+			fmt.Printf("⚠️  Failed to count override use: %v\n", err)
+		}
+		o.auditLog.Record(ctx, AuditEvent{
+			Event:  "policy_overridden",
+			Serial: serial,
+			GUID:   guid,
+			Detail: fmt.Sprintf("%s waived by override %s (issued by %s, applied by %s: %s): %v", check, override.ID, override.IssuedBy, override.AppliedBy, override.Reason, cause),
+		})
+		fmt.Printf("🎫 %s waived for %s by override %s: %v\n", check, serialRules.Serial(serial), override.ID, cause)
+		return true
+	}
+	return false
+}
+
+// serials shows the serials of an override as logs do
+func (o *PolicyOverrides) serials(serials []string) string {
+	shown := make([]string, len(serials))
+	for i, serial := range serials {
+		shown[i] = serialRules.Serial(serial)
+	}
+	return strings.Join(shown, ", ")
+}
+
+var policyOverrideListSpec = listSpec{
+	Key:         "id",
+	Sorts:       map[string]string{"applied_at": "applied_at", "expires_at": "expires_at"},
+	DefaultSort: "-applied_at",
+	Filters:     map[string]string{"issued_by": "issued_by", "applied_by": "applied_by"},
+}
+
+// Page returns one page of applied overrides
+func (o *PolicyOverrides) Page(ctx context.Context, q *listQuery) ([]PolicyOverride, string, error) {
+	clause, args := q.sql()
+	overrides, err := o.query(ctx, clause, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	overrides, next := listPage(q, overrides, func(p PolicyOverride, column string) any {
+		switch column {
+		case "applied_at":
+			return p.AppliedAt.Unix()
+		case "expires_at":
+			return p.ExpiresAt.Unix()
+		default:
+			return p.ID
+		}
+	})
+	return overrides, next, nil
+}
+
+// query returns the overrides selected by clause (WHERE, ORDER BY and LIMIT)
+func (o *PolicyOverrides) query(ctx context.Context, clause string, args ...any) ([]PolicyOverride, error) {
+	rows, err := o.db.db.QueryContext(ctx, `
+	SELECT id, serials, checks, reason, issued_by, issued_at, expires_at, applied_by, applied_at, uses, revoked_at
+	FROM policy_overrides`+clause, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query overrides: %w", err)
+	}
+	defer rows.Close()
+
+	overrides := []PolicyOverride{}
+	for rows.Next() {
+		var p PolicyOverride
+		var serials, checks string
+		var issuedAt, expiresAt, appliedAt int64
+		var revokedAt sql.NullInt64
+		if err := rows.Scan(&p.ID, &serials, &checks, &p.Reason, &p.IssuedBy, &issuedAt, &expiresAt, &p.AppliedBy, &appliedAt, &p.Uses, &revokedAt); err != nil {
+			return nil, fmt.Errorf("failed to read override: %w", err)
+		}
+		if err := json.Unmarshal([]byte(serials), &p.Serials); err != nil {
+			return nil, fmt.Errorf("failed to read override %s: %w", p.ID, err)
+		}
+		if err := json.Unmarshal([]byte(checks), &p.Checks); err != nil {
+			return nil, fmt.Errorf("failed to read override %s: %w", p.ID, err)
+		}
+		p.IssuedAt, p.ExpiresAt, p.AppliedAt = time.Unix(issuedAt, 0), time.Unix(expiresAt, 0), time.Unix(appliedAt, 0)
+		if revokedAt.Valid {
+			t := time.Unix(revokedAt.Int64, 0)
+			p.RevokedAt = &t
+		}
+		overrides = append(overrides, p)
+	}
+	return overrides, rows.Err()
+}
+
+// IssueHandler serves POST /api/overrides
+func (o *PolicyOverrides) IssueHandler() http.Handler {
+	return o.handler(func(w http.ResponseWriter, r *http.Request) {
+		var req IssueOverrideRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid request: %v", err))
+			return
+		}
+		issued, err := o.Issue(r.Context(), req)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusCreated, issued)
+	})
+}
+
+// ApplyHandler serves POST /api/overrides/apply
+func (o *PolicyOverrides) ApplyHandler() http.Handler {
+	return o.handler(func(w http.ResponseWriter, r *http.Request) {
+		var req ApplyOverrideRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid request: %v", err))
+			return
+		}
+		override, err := o.Apply(r.Context(), req)
+		switch {
+		case errors.Is(err, ErrOperatorAuth):
+			// Don't tell the client which part of the credentials was wrong
+			writeJSONError(w, http.StatusUnauthorized, ErrOperatorAuth.Error())
+		case errors.Is(err, ErrOverrideToken):
+			writeJSONError(w, http.StatusForbidden, err.Error())
+		case err != nil:
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+		default:
+			writeJSON(w, http.StatusCreated, override)
+		}
+	})
+}
+
+// ListHandler serves GET /api/overrides with the list parameters of policyOverrideListSpec
+func (o *PolicyOverrides) ListHandler() http.Handler {
+	return o.handler(func(w http.ResponseWriter, r *http.Request) {
+		q, err := parseListQuery(r, &policyOverrideListSpec)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		overrides, next, err := o.Page(r.Context(), q)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSONList(w, r, overrides, next)
+	})
+}
+
+// RevokeHandler serves DELETE /api/overrides/{id}
+func (o *PolicyOverrides) RevokeHandler() http.Handler {
+	return o.handler(func(w http.ResponseWriter, r *http.Request) {
+		override, err := o.Revoke(r.Context(), r.PathValue("id"))
+		switch {
+		case errors.Is(err, ErrVoucherNotFound):
+			writeJSONError(w, http.StatusNotFound, err.Error())
+		case err != nil:
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+		default:
+			writeJSON(w, http.StatusOK, override)
+		}
+	})
+}
+
+// handler answers 404 while override tokens are disabled
+func (o *PolicyOverrides) handler(fn http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if o == nil {
+			writeJSONError(w, http.StatusNotFound, "override tokens are disabled")
+			return
+		}
+		fn(w, r)
+	})
+}
@@ -0,0 +1,185 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+)
+
+// Modes of the startup permission audit
+const (
+	PermissionAuditWarn = "warn" // Print what is too permissive and start anyway
+	PermissionAuditFail = "fail" // Refuse to start
+	PermissionAuditOff  = "off"
+)
+
+// permissionCheck is a path the startup audit looks at and the mode bits it
+// must not have
+type permissionCheck struct {
+	name string      // Config key or description shown in findings
+	path string      // File or directory
+	deny fs.FileMode // Permission bits that make it too permissive
+	fix  string      // What to run about it
+}
+
+// permissionChecks lists the key files, databases and voucher directories of
+// a config. Keys and the go-fdo database, which holds the manufacturer keys,
+// must not be accessible to group or others; the station database must not be
+// accessible to others; voucher directories must not be world-writable.
+func permissionChecks(cfg *Config) []permissionCheck {
+	var checks []permissionCheck
+	key := func(name, path string) {
+		if path != "" {
+			checks = append(checks, permissionCheck{name: name, path: path, deny: 0o077, fix: "chmod 600"})
+		}
+	}
+	key("server.key_file", cfg.Server.KeyFile)
+	key("server.admin.key_file", cfg.Server.Admin.KeyFile)
+	key("transfer.signing_key_file", cfg.Transfer.SigningKeyFile)
+	key("override_tokens.key_file", cfg.OverrideTokens.KeyFile)
+	key("serial_rules.pseudonym_key_file", cfg.SerialRules.PseudonymKeyFile)
+	key("audit_anchor.checkpoint_file.signing_key_file", cfg.AuditAnchor.CheckpointFile.SigningKeyFile)
+	vm := &cfg.VoucherManagement
+	key("voucher_management.voucher_signing.fallback.key_file", vm.VoucherSigning.Fallback.KeyFile)
+	key("voucher_management.save_to_disk.manifest.signing_key_file", vm.SaveToDisk.Manifest.SigningKeyFile)
+	profiles := make([]string, 0, len(vm.UploadAuthProfiles))
+	for name := range vm.UploadAuthProfiles {
+		profiles = append(profiles, name)
+	}
+	sort.Strings(profiles)
+	for _, name := range profiles {
+		key("voucher_management.upload_auth_profiles."+name+".client_key", vm.UploadAuthProfiles[name].ClientKey)
+	}
+
+	// SQLite keeps recent writes in -wal and -shm next to the database
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		checks = append(checks,
+			permissionCheck{name: "database.path", path: cfg.Database.Path + suffix, deny: 0o077, fix: "chmod 600"},
+			permissionCheck{name: "station database", path: stationDBPath(cfg) + suffix, deny: 0o007, fix: "chmod o-rwx"})
+	}
+
+	for _, dir := range voucherDirectories(cfg) {
+		checks = append(checks, permissionCheck{name: "voucher directory", path: dir, deny: 0o002, fix: "chmod o-w"})
+	}
+	if vm.TLSTrust.Directory != "" {
+		checks = append(checks, permissionCheck{name: "voucher_management.tls_trust.directory", path: vm.TLSTrust.Directory, deny: 0o022, fix: "chmod go-w"})
+	}
+	return checks
+}
+
+// voucherDirectories lists the directories the station writes vouchers to
+func voucherDirectories(cfg *Config) []string {
+	vm := &cfg.VoucherManagement
+	var dirs []string
+	if vm.SaveToDisk.Directory != "" {
+		dirs = append(dirs, vm.SaveToDisk.Directory)
+	}
+	for _, dest := range vm.SaveToDisk.Destinations {
+		if dest.Directory != "" {
+			dirs = append(dirs, dest.Directory)
+		}
+	}
+	if vm.TempDirectory != "" {
+		dirs = append(dirs, vm.TempDirectory)
+	}
+	return dirs
+}
+
+// auditPermissions reports key files, databases and voucher directories whose
+// modes are too permissive. In "fail" mode any finding stops startup.
+func auditPermissions(cfg *Config) error {
+	mode := cfg.Privileges.PermissionAudit
+	if mode == PermissionAuditOff || runtime.GOOS == "windows" { // Windows has ACLs, not mode bits
+		return nil
+	}
+	findings := 0
+	for _, check := range permissionChecks(cfg) {
+		info, err := os.Stat(check.path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			fmt.Printf("⚠️  Permission audit: cannot check %s %s: %v\n", check.name, check.path, err)
+			continue
+		}
+		if info.Mode().Perm()&check.deny == 0 {
+			continue
+		}
+		findings++
+		fmt.Printf("🔓 Permission audit: %s %s is mode %04o (%s %s)\n",
+			check.name, check.path, info.Mode().Perm(), check.fix, check.path)
+	}
+	if findings > 0 && mode == PermissionAuditFail {
+		return fmt.Errorf("permission audit found %d overly permissive files or directories", findings)
+	}
+	return nil
+}
+
+// checkWritable verifies, after privileges were dropped, that the databases
+// and voucher directories are still writable. SQLite opens new connections
+// and creates journal files as the station goes, so a file left owned by
+// root would fail devices later instead of at startup.
+func checkWritable(cfg *Config) error {
+	if cfg.Privileges.User == "" {
+		return nil
+	}
+	for _, path := range []string{cfg.Database.Path, stationDBPath(cfg)} {
+		f, err := os.OpenFile(path, os.O_RDWR, 0)
+		if err != nil {
+			return fmt.Errorf("cannot write %s as %s: %w", path, cfg.Privileges.User, err)
+		}
+		_ = f.Close()
+	}
+	dirs := append([]string{filepath.Dir(cfg.Database.Path), filepath.Dir(stationDBPath(cfg))}, voucherDirectories(cfg)...)
+	for _, dir := range dirs {
+		if _, err := os.Stat(dir); errors.Is(err, fs.ErrNotExist) {
+			continue // Created on first use
+		}
+		f, err := os.CreateTemp(dir, ".fdo-station-write-check-*")
+		if err != nil {
+			return fmt.Errorf("cannot write to %s as %s: %w", dir, cfg.Privileges.User, err)
+		}
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+	}
+	return nil
+}
+
+// parseUmask parses an octal umask such as "027"
+func parseUmask(umask string) (int, error) {
+	mask, err := strconv.ParseUint(umask, 8, 32)
+	if err != nil || mask > 0o777 {
+		return 0, fmt.Errorf("privileges.umask must be octal between 000 and 777, got %q", umask)
+	}
+	return int(mask), nil
+}
+
+// validatePrivileges checks the privileges settings
+func validatePrivileges(config *PrivilegesConfig) error {
+	switch config.PermissionAudit {
+	case "", PermissionAuditWarn, PermissionAuditFail, PermissionAuditOff:
+	default:
+		return fmt.Errorf("privileges.permission_audit must be %q, %q or %q", PermissionAuditWarn, PermissionAuditFail, PermissionAuditOff)
+	}
+	if config.Group != "" && config.User == "" {
+		return fmt.Errorf("privileges.group requires privileges.user")
+	}
+	if config.Umask != "" {
+		if _, err := parseUmask(config.Umask); err != nil {
+			return err
+		}
+	}
+	if runtime.GOOS == "windows" && (config.User != "" || config.Umask != "") {
+		return fmt.Errorf("privileges.user and privileges.umask are not supported on Windows; run the station as a service account instead")
+	}
+	return nil
+}
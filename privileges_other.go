@@ -0,0 +1,87 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+//go:build !windows
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"syscall"
+)
+
+// applyUmask sets the process umask, so files the station creates get no
+// more than the permissions it leaves
+func applyUmask(config *PrivilegesConfig) error {
+	if config.Umask == "" {
+		return nil
+	}
+	mask, err := parseUmask(config.Umask)
+	if err != nil {
+		return err
+	}
+	syscall.Umask(mask)
+	return nil
+}
+
+// dropPrivileges switches a station started as root to privileges.user and
+// its groups. Call it once every listener is bound.
+func dropPrivileges(cfg *Config) error {
+	config := &cfg.Privileges
+	if config.User == "" {
+		return nil
+	}
+	if os.Geteuid() != 0 {
+		fmt.Printf("⚠️  Not running as root; keeping user %d instead of switching to %s\n", os.Geteuid(), config.User)
+		return nil
+	}
+	u, err := user.Lookup(config.User)
+	if err != nil {
+		return fmt.Errorf("failed to look up privileges.user: %w", err)
+	}
+	uid, err := strconv.Atoi(u.Uid)
+	if err != nil {
+		return fmt.Errorf("user %s has no numeric uid: %s", u.Username, u.Uid)
+	}
+	gid, err := strconv.Atoi(u.Gid)
+	if err != nil {
+		return fmt.Errorf("user %s has no numeric gid: %s", u.Username, u.Gid)
+	}
+	if config.Group != "" {
+		g, err := user.LookupGroup(config.Group)
+		if err != nil {
+			return fmt.Errorf("failed to look up privileges.group: %w", err)
+		}
+		if gid, err = strconv.Atoi(g.Gid); err != nil {
+			return fmt.Errorf("group %s has no numeric gid: %s", g.Name, g.Gid)
+		}
+	}
+	groups := []int{gid}
+	if ids, err := u.GroupIds(); err == nil {
+		for _, id := range ids {
+			if n, err := strconv.Atoi(id); err == nil && n != gid {
+				groups = append(groups, n)
+			}
+		}
+	}
+
+	// Groups first: once the uid is gone they can't be changed
+	if err := syscall.Setgroups(groups); err != nil {
+		return fmt.Errorf("failed to set groups: %w", err)
+	}
+	if err := syscall.Setgid(gid); err != nil {
+		return fmt.Errorf("failed to set gid %d: %w", gid, err)
+	}
+	if err := syscall.Setuid(uid); err != nil {
+		return fmt.Errorf("failed to set uid %d: %w", uid, err)
+	}
+	if syscall.Setuid(0) == nil {
+		return fmt.Errorf("still able to regain root after switching to %s", config.User)
+	}
+	fmt.Printf("🔒 Dropped root; running as %s (uid %d, gid %d)\n", u.Username, uid, gid)
+	return nil
+}
@@ -0,0 +1,25 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+//go:build windows
+
+package main
+
+import "fmt"
+
+// applyUmask is not supported on Windows; validatePrivileges refuses a umask there
+func applyUmask(config *PrivilegesConfig) error {
+	if config.Umask != "" {
+		return fmt.Errorf("privileges.umask is not supported on Windows")
+	}
+	return nil
+}
+
+// dropPrivileges is not supported on Windows, where the station runs as a service account
+func dropPrivileges(cfg *Config) error {
+	if cfg.Privileges.User != "" {
+		return fmt.Errorf("privileges.user is not supported on Windows")
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/fido-device-onboard/go-fdo/cbor"
+)
+
+// supportedProtocolVersions are the FDO protocol versions (the {fdoVer} path
+// segment) that the go-fdo DI responder built into this station implements
+var supportedProtocolVersions = []string{"101", "200"}
+
+// FDO error message fields (FDO spec 3.8, message type 255)
+const (
+	fdoErrorMsgType       = 255
+	fdoInvalidMessageCode = 101 // INVALID_MESSAGE_ERROR
+)
+
+// ProtocolVersionGate rejects DI messages for protocol versions that are not
+// supported or not enabled, and logs the version each DI session starts with
+type ProtocolVersionGate struct {
+	accepted []string
+	auditLog *AuditLog
+}
+
+// NewProtocolVersionGate checks the configured versions against the supported ones
+func NewProtocolVersionGate(config *ProtocolConfig, auditLog *AuditLog) (*ProtocolVersionGate, error) {
+	for _, version := range config.Versions {
+		if !slices.Contains(supportedProtocolVersions, version) {
+			return nil, fmt.Errorf("protocol version %q is not supported (supported: %v)", version, supportedProtocolVersions)
+		}
+	}
+	return &ProtocolVersionGate{accepted: acceptedProtocolVersions(config), auditLog: auditLog}, nil
+}
+
+// acceptedProtocolVersions returns the configured versions, or all supported ones if none are configured
+func acceptedProtocolVersions(config *ProtocolConfig) []string {
+	if len(config.Versions) == 0 {
+		return supportedProtocolVersions
+	}
+	return config.Versions
+}
+
+// Middleware wraps the FDO message handler
+func (g *ProtocolVersionGate) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		version, msg := r.PathValue("fdoVer"), r.PathValue("msg")
+		if !slices.Contains(g.accepted, version) {
+			reason := fmt.Sprintf("FDO protocol version %s is not accepted by this station (accepted: %v)", version, g.accepted)
+			g.auditLog.Record(r.Context(), AuditEvent{
+				Event:  "di_rejected_protocol_version",
+				Detail: fmt.Sprintf("%s; msg %s from %s", reason, msg, r.RemoteAddr),
+			})
+			writeFDOError(w, msg, reason)
+			return
+		}
+
+		// DI.AppStart (10) begins a session
+		if msg == "10" {
+			fmt.Printf("🔢 DI session from %s using FDO protocol version %s\n", r.RemoteAddr, version)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// writeFDOError answers with an FDO error message so the device reports the reason
+func writeFDOError(w http.ResponseWriter, prevMsg, reason string) {
+	writeFDOErrorStatus(w, http.StatusInternalServerError, prevMsg, reason)
+}
+
+// writeFDOErrorStatus answers with an FDO error message and the given HTTP status
+func writeFDOErrorStatus(w http.ResponseWriter, status int, prevMsg, reason string) {
+	var prevMsgType uint
+	_, _ = fmt.Sscan(prevMsg, &prevMsgType)
+
+	// ErrorMessage = [EMErrorCode, EMPrevMsgID, EMErrorStr, EMErrorTs, EMErrorCID]
+	body, err := cbor.Marshal([]any{uint(fdoInvalidMessageCode), prevMsgType, reason, nil, uint(0)})
+	if err != nil {
+		http.Error(w, reason, http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/cbor")
+	w.Header().Set("Message-Type", fmt.Sprint(fdoErrorMsgType))
+	w.WriteHeader(status)
+	_, _ = w.Write(body)
+}
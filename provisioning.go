@@ -0,0 +1,429 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrResourceNotFound is returned for a named config entry that doesn't exist
+var ErrResourceNotFound = errors.New("not found")
+
+// ErrResourceInUse is returned when removing an entry that upload destinations still use
+var ErrResourceInUse = errors.New("in use by upload destinations")
+
+// configResource is a kind of named config entry that infrastructure-as-code
+// tools (Terraform, Pulumi) manage one at a time: PUT creates or replaces an
+// entry under its name, so applying the same spec twice changes nothing.
+// Entries are saved to the config file like any config push.
+type configResource struct {
+	kind   string // Path segment under /api/, e.g. "upload-auth-profiles"
+	noun   string // What an entry is called in errors
+	names  func(cfg *Config) []string
+	get    func(cfg *Config, name string) (any, bool)
+	set    func(cfg *Config, name string, spec []byte) error // Decodes spec into the entry, creating it if needed
+	remove func(cfg *Config, name string) bool
+
+	// customers returns the customers an entry serves, or true if it serves
+	// every customer; using are the upload destinations that use it
+	customers func(cfg *Config, name string, using []*UploadDestination) ([]string, bool)
+	routed    bool // Upload destinations name entries as their auth_profile
+}
+
+// provisionedResources are the config entries the provisioning endpoints manage
+var provisionedResources = []*configResource{
+	{
+		kind: "upload-auth-profiles",
+		noun: "upload auth profile",
+		names: func(cfg *Config) []string {
+			names := make([]string, 0, len(cfg.VoucherManagement.UploadAuthProfiles))
+			for name := range cfg.VoucherManagement.UploadAuthProfiles {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return names
+		},
+		get: func(cfg *Config, name string) (any, bool) {
+			profile, ok := cfg.VoucherManagement.UploadAuthProfiles[name]
+			return profile, ok
+		},
+		set: func(cfg *Config, name string, spec []byte) error {
+			var profile UploadAuthProfile
+			if err := decodeResourceSpec(spec, &profile); err != nil {
+				return err
+			}
+			if cfg.VoucherManagement.UploadAuthProfiles == nil {
+				cfg.VoucherManagement.UploadAuthProfiles = map[string]UploadAuthProfile{}
+			}
+			cfg.VoucherManagement.UploadAuthProfiles[name] = profile
+			return nil
+		},
+		remove: func(cfg *Config, name string) bool {
+			_, ok := cfg.VoucherManagement.UploadAuthProfiles[name]
+			delete(cfg.VoucherManagement.UploadAuthProfiles, name)
+			return ok
+		},
+		customers: func(cfg *Config, name string, using []*UploadDestination) ([]string, bool) {
+			return profileCustomers(&cfg.VoucherManagement, name, using)
+		},
+		routed: true,
+	},
+	{
+		kind: "disk-destinations",
+		noun: "save_to_disk destination",
+		names: func(cfg *Config) []string {
+			var names []string
+			for _, dest := range cfg.VoucherManagement.SaveToDisk.Destinations {
+				names = append(names, dest.Name)
+			}
+			return names
+		},
+		get: func(cfg *Config, name string) (any, bool) {
+			i := diskDestinationIndex(cfg, name)
+			if i < 0 {
+				return nil, false
+			}
+			return cfg.VoucherManagement.SaveToDisk.Destinations[i], true
+		},
+		set: func(cfg *Config, name string, spec []byte) error {
+			var dest DiskDestination
+			if err := decodeResourceSpec(spec, &dest); err != nil {
+				return err
+			}
+			dest.Name = name
+			dests := &cfg.VoucherManagement.SaveToDisk.Destinations
+			if i := diskDestinationIndex(cfg, name); i >= 0 {
+				(*dests)[i] = dest
+			} else {
+				*dests = append(*dests, dest)
+			}
+			return nil
+		},
+		remove: func(cfg *Config, name string) bool {
+			i := diskDestinationIndex(cfg, name)
+			if i < 0 {
+				return false
+			}
+			dests := &cfg.VoucherManagement.SaveToDisk.Destinations
+			*dests = slices.Delete(*dests, i, i+1)
+			return true
+		},
+		customers: func(cfg *Config, name string, _ []*UploadDestination) ([]string, bool) {
+			i := diskDestinationIndex(cfg, name)
+			if i < 0 {
+				return nil, false
+			}
+			customers := cfg.VoucherManagement.SaveToDisk.Destinations[i].Customers
+			return customers, len(customers) == 0
+		},
+	},
+}
+
+// diskDestinationIndex returns the index of a named save_to_disk destination, or -1
+func diskDestinationIndex(cfg *Config, name string) int {
+	return slices.IndexFunc(cfg.VoucherManagement.SaveToDisk.Destinations, func(dest DiskDestination) bool {
+		return dest.Name == name
+	})
+}
+
+// decodeResourceSpec decodes a YAML or JSON entry with its config file keys,
+// refusing unknown ones so a misspelled attribute isn't silently dropped
+func decodeResourceSpec(spec []byte, out any) error {
+	decoder := yaml.NewDecoder(bytes.NewReader(spec))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(out); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("invalid spec: %w", err)
+	}
+	return nil
+}
+
+// resourceSpec returns a config entry with its config file keys, secrets redacted
+func resourceSpec(entry any) (map[string]any, error) {
+	data, err := yaml.Marshal(entry)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling config: %w", err)
+	}
+	spec := map[string]any{}
+	if err := yaml.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("error marshaling config: %w", err)
+	}
+	secrets := secretPathsOf(reflect.TypeOf(entry), "")
+	for key, value := range spec {
+		if slices.Contains(secrets, key) && value != "" {
+			spec[key] = "***"
+		}
+	}
+	return spec, nil
+}
+
+// ProvisionedResource is the response of the provisioning endpoints
+type ProvisionedResource struct {
+	Name            string         `json:"name"`
+	Spec            map[string]any `json:"spec"` // Secrets are "***": they can be set but not read back
+	RestartRequired bool           `json:"restart_required,omitempty"`
+	UsedBy          []string       `json:"used_by,omitempty"` // Upload destinations using the entry by name, so a change applies to them too
+}
+
+// using returns the upload destinations that use an entry
+func (m *ConfigManager) using(ctx context.Context, res *configResource, name string) ([]*UploadDestination, error) {
+	if !res.routed {
+		return nil, nil
+	}
+	return m.destinations.UsingProfile(ctx, name)
+}
+
+// checkScope refuses a change to an entry that serves customers other than
+// those of an admin limited by admin.users customers
+func checkScope(ctx context.Context, res *configResource, cfg *Config, name string, using []*UploadDestination) error {
+	customers, everyCustomer := res.customers(cfg, name, using)
+	return checkAdminCustomers(ctx, fmt.Sprintf("%s %q", res.noun, name), customers, everyCustomer)
+}
+
+// putResource creates or replaces a config entry and reports whether it was
+// new. The entry must serve only the admin's customers before and after.
+func (m *ConfigManager) putResource(ctx context.Context, res *configResource, name string, spec []byte, dryRun bool) (*ConfigDiff, bool, error) {
+	if name == "" {
+		return nil, false, fmt.Errorf("name is required")
+	}
+	using, err := m.using(ctx, res, name)
+	if err != nil {
+		return nil, false, err
+	}
+	created := false
+	diff, err := m.Update(ctx, func(next *Config) error {
+		_, exists := res.get(next, name)
+		created = !exists
+		if err := checkScope(ctx, res, next, name, using); err != nil {
+			return err
+		}
+		if err := res.set(next, name, spec); err != nil {
+			return err
+		}
+		return checkScope(ctx, res, next, name, using)
+	}, dryRun)
+	return diff, created, err
+}
+
+// deleteResource removes a config entry that no upload destination uses
+func (m *ConfigManager) deleteResource(ctx context.Context, res *configResource, name string, dryRun bool) (*ConfigDiff, error) {
+	using, err := m.using(ctx, res, name)
+	if err != nil {
+		return nil, err
+	}
+	return m.Update(ctx, func(next *Config) error {
+		if _, ok := res.get(next, name); !ok {
+			return fmt.Errorf("%s %q: %w", res.noun, name, ErrResourceNotFound)
+		}
+		if err := checkScope(ctx, res, next, name, using); err != nil {
+			return err
+		}
+		if len(using) > 0 {
+			return fmt.Errorf("%s %q: %w %s", res.noun, name, ErrResourceInUse, strings.Join(destinationNames(using), ", "))
+		}
+		res.remove(next, name)
+		return nil
+	}, dryRun)
+}
+
+// destinationNames returns the names of upload destinations
+func destinationNames(destinations []*UploadDestination) []string {
+	names := make([]string, len(destinations))
+	for i, d := range destinations {
+		names[i] = d.Name
+	}
+	return names
+}
+
+// provisionedResource returns the response for a running config entry
+func (m *ConfigManager) provisionedResource(ctx context.Context, res *configResource, name string, restartRequired bool) (*ProvisionedResource, error) {
+	using, err := m.using(ctx, res, name)
+	if err != nil {
+		return nil, err
+	}
+	item := &ProvisionedResource{Name: name, Spec: map[string]any{}, RestartRequired: restartRequired}
+	if len(using) > 0 {
+		item.UsedBy = destinationNames(using)
+	}
+	// The request's config read lock keeps the running config steady
+	entry, ok := res.get(m.running, name)
+	if !ok {
+		// A restart-only entry is saved to the config file but not yet running
+		return item, nil
+	}
+	if item.Spec, err = resourceSpec(entry); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// ResourceListHandler serves GET /api/<kind>
+func (m *ConfigManager) ResourceListHandler(res *configResource) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		names := res.names(m.running)
+		list := []*ProvisionedResource{}
+		for _, name := range names {
+			item, err := m.provisionedResource(r.Context(), res, name, false)
+			if err != nil {
+				writeJSONError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+			list = append(list, item)
+		}
+		writeJSON(w, http.StatusOK, list)
+	})
+}
+
+// ResourceGetHandler serves GET /api/<kind>/{name}
+func (m *ConfigManager) ResourceGetHandler(res *configResource) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("name")
+		_, ok := res.get(m.running, name)
+		if !ok {
+			writeJSONError(w, http.StatusNotFound, fmt.Sprintf("unknown %s %q", res.noun, name))
+			return
+		}
+		item, err := m.provisionedResource(r.Context(), res, name, false)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, item)
+	})
+}
+
+// ResourcePutHandler serves PUT /api/<kind>/{name}: 201 when the entry was
+// created, 200 when it was replaced or already matched the spec
+func (m *ConfigManager) ResourcePutHandler(res *configResource) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		spec, err := io.ReadAll(io.LimitReader(r.Body, 1024*1024))
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("failed to read spec: %v", err))
+			return
+		}
+		name := r.PathValue("name")
+		diff, created, err := m.putResource(r.Context(), res, name, spec, false)
+		if err != nil {
+			writeJSONError(w, scopeStatus(err, http.StatusBadRequest), err.Error())
+			return
+		}
+		if diff.Applied {
+			m.recordApplied(r.Context(), diff, "admin API from "+r.RemoteAddr)
+		}
+		item, err := m.provisionedResource(r.Context(), res, name, diff.RestartRequired)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		status := http.StatusOK
+		if created {
+			status = http.StatusCreated
+		}
+		writeJSON(w, status, item)
+	})
+}
+
+// ResourceDeleteHandler serves DELETE /api/<kind>/{name}
+func (m *ConfigManager) ResourceDeleteHandler(res *configResource) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		diff, err := m.deleteResource(r.Context(), res, r.PathValue("name"), false)
+		if errors.Is(err, ErrResourceNotFound) {
+			writeJSONError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		if errors.Is(err, ErrResourceInUse) {
+			writeJSONError(w, http.StatusConflict, err.Error())
+			return
+		}
+		if err != nil {
+			writeJSONError(w, scopeStatus(err, http.StatusBadRequest), err.Error())
+			return
+		}
+		if diff.Applied {
+			m.recordApplied(r.Context(), diff, "admin API from "+r.RemoteAddr)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// describeResourcePut files an entry change that touches dual-controlled
+// settings (upload auth profiles) for a second admin's approval
+func (m *ConfigManager) describeResourcePut(res *configResource) approvalDescriber {
+	return func(r *http.Request, body []byte) (*ChangeRequest, error) {
+		diff, _, err := m.putResource(r.Context(), res, r.PathValue("name"), body, true)
+		if err != nil || !needsDualControl(diff) {
+			return nil, err
+		}
+		return &ChangeRequest{Target: res.kind + "/" + r.PathValue("name"), Changes: diff.Changes}, nil
+	}
+}
+
+// describeResourceDelete files an entry removal that touches dual-controlled
+// settings for a second admin's approval
+func (m *ConfigManager) describeResourceDelete(res *configResource) approvalDescriber {
+	return func(r *http.Request, _ []byte) (*ChangeRequest, error) {
+		diff, err := m.deleteResource(r.Context(), res, r.PathValue("name"), true)
+		if errors.Is(err, ErrResourceNotFound) {
+			return nil, nil // The handler answers 404
+		}
+		if err != nil || !needsDualControl(diff) {
+			return nil, err
+		}
+		return &ChangeRequest{Target: res.kind + "/" + r.PathValue("name"), Changes: diff.Changes}, nil
+	}
+}
+
+// changedResource returns the resource and entry name of an approved change
+func changedResource(change *ChangeRequest) (*configResource, string, error) {
+	kind, name, _ := strings.Cut(change.Target, "/")
+	for _, res := range provisionedResources {
+		if res.kind == kind {
+			return res, name, nil
+		}
+	}
+	return nil, "", fmt.Errorf("unknown resource %q", change.Target)
+}
+
+// applyResourcePut applies an approved entry change
+func (m *ConfigManager) applyResourcePut(ctx context.Context, change *ChangeRequest) (any, error) {
+	res, name, err := changedResource(change)
+	if err != nil {
+		return nil, err
+	}
+	diff, _, err := m.putResource(ctx, res, name, change.payload, false)
+	if err != nil {
+		return nil, err
+	}
+	if diff.Applied {
+		m.recordApplied(ctx, diff, "approved change "+change.ID)
+	}
+	return m.provisionedResource(ctx, res, name, diff.RestartRequired)
+}
+
+// applyResourceDelete applies an approved entry removal
+func (m *ConfigManager) applyResourceDelete(ctx context.Context, change *ChangeRequest) (any, error) {
+	res, name, err := changedResource(change)
+	if err != nil {
+		return nil, err
+	}
+	diff, err := m.deleteResource(ctx, res, name, false)
+	if err != nil {
+		return nil, err
+	}
+	if diff.Applied {
+		m.recordApplied(ctx, diff, "approved change "+change.ID)
+	}
+	return diff, nil
+}
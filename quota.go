@@ -0,0 +1,365 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"time"
+)
+
+// ErrQuotaExhausted is returned when a hard quota has no units left
+var ErrQuotaExhausted = errors.New("manufacturing quota exhausted")
+
+// QuotaService counts manufactured devices against the configured quotas.
+// Each device is counted when its voucher is built; the count is given back if
+// the voucher pipeline fails afterwards. A nil *QuotaService is valid and
+// enforces nothing.
+type QuotaService struct {
+	rules    []QuotaRule
+	db       *StationDB
+	notifier *Notifier
+}
+
+// QuotaReservation is the set of quota counters one device was counted against
+type QuotaReservation struct {
+	counters []quotaCounter
+}
+
+// quotaCounter identifies a counter row: one rule in one period
+type quotaCounter struct {
+	rule   string
+	period string
+}
+
+// QuotaStatus reports a quota rule's counter for the current period
+type QuotaStatus struct {
+	Name      string `json:"name"`
+	Customer  string `json:"customer,omitempty"`
+	Model     string `json:"model,omitempty"`
+	Period    string `json:"period"`     // "day" | "month" | "year" | "total"
+	PeriodKey string `json:"period_key"` // e.g. "2026-10"
+	Limit     int    `json:"limit"`
+	Extra     int    `json:"extra"` // Additional units granted by an override
+	Used      int    `json:"used"`
+	Remaining int    `json:"remaining"` // Negative when a soft quota is exceeded
+	WarnAt    int    `json:"warn_at"`
+	Soft      bool   `json:"soft"`
+	Reason    string `json:"override_reason,omitempty"`
+}
+
+// QuotaOverrideRequest is the body of POST /api/quotas/{name}/override
+type QuotaOverrideRequest struct {
+	Extra  int    `json:"extra"` // Units added to the limit for the current period; 0 clears the override
+	Reason string `json:"reason"`
+}
+
+// NewQuotaService creates a quota service, or returns nil if no quotas are configured
+func NewQuotaService(config *QuotaConfig, db *StationDB, notifier *Notifier) *QuotaService {
+	if len(config.Rules) == 0 {
+		return nil
+	}
+	rules := make([]QuotaRule, len(config.Rules))
+	for i, rule := range config.Rules {
+		if rule.Period == "" {
+			rule.Period = "month"
+		}
+		if rule.WarnAt == 0 {
+			rule.WarnAt = 90
+		}
+		rules[i] = rule
+	}
+	return &QuotaService{
+		rules:    rules,
+		db:       db,
+		notifier: notifier,
+	}
+}
+
+// Initialize validates the rules and creates the quota_counters table if it doesn't exist
+func (q *QuotaService) Initialize(ctx context.Context) error {
+	if q == nil {
+		return nil
+	}
+	if err := q.validate(); err != nil {
+		return err
+	}
+
+	_, err := q.db.db.ExecContext(ctx, `
+	CREATE TABLE IF NOT EXISTS quota_counters (
+		rule TEXT NOT NULL,
+		period TEXT NOT NULL,
+		used INTEGER NOT NULL DEFAULT 0,
+		extra INTEGER NOT NULL DEFAULT 0,
+		override_reason TEXT,
+		PRIMARY KEY (rule, period)
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create quota_counters table: %w", err)
+	}
+	return nil
+}
+
+// validate checks the quota rules
+func (q *QuotaService) validate() error {
+	if q == nil {
+		return nil
+	}
+	seen := make(map[string]bool)
+	for i, rule := range q.rules {
+		if rule.Name == "" {
+			return fmt.Errorf("quota rule %d: name is required", i+1)
+		}
+		if seen[rule.Name] {
+			return fmt.Errorf("quota rule %q: duplicate name", rule.Name)
+		}
+		seen[rule.Name] = true
+		if rule.Limit <= 0 {
+			return fmt.Errorf("quota rule %q: limit must be positive", rule.Name)
+		}
+		if _, err := quotaPeriodKey(rule.Period, stationClock.Now()); err != nil {
+			return fmt.Errorf("quota rule %q: %w", rule.Name, err)
+		}
+		if _, err := path.Match(rule.Model, ""); err != nil {
+			return fmt.Errorf("quota rule %q: invalid model pattern: %w", rule.Name, err)
+		}
+	}
+	return nil
+}
+
+// Reserve counts one device against every matching quota. If a hard quota is
+// exhausted nothing is counted and an ErrQuotaExhausted error is returned,
+// failing DI.
+func (q *QuotaService) Reserve(ctx context.Context, customer, model string) (*QuotaReservation, error) {
+	return q.reserve(ctx, customer, model, false)
+}
+
+// ReserveOverLimit counts a device whose exhausted quota was waived by an override
+func (q *QuotaService) ReserveOverLimit(ctx context.Context, customer, model string) (*QuotaReservation, error) {
+	return q.reserve(ctx, customer, model, true)
+}
+
+func (q *QuotaService) reserve(ctx context.Context, customer, model string, overLimit bool) (*QuotaReservation, error) {
+	if q == nil {
+		return nil, nil
+	}
+
+	now := stationClock.Now()
+	reservation := &QuotaReservation{}
+	var warnings []string
+
+	tx, err := q.db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin quota transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, rule := range q.rules {
+		if !rule.matches(customer, model) {
+			continue
+		}
+		period, _ := quotaPeriodKey(rule.Period, now)
+
+		if _, err := tx.ExecContext(ctx,
+			`INSERT OR IGNORE INTO quota_counters (rule, period) VALUES (?, ?)`, rule.Name, period); err != nil {
+			return nil, fmt.Errorf("failed to create quota counter %s/%s: %w", rule.Name, period, err)
+		}
+		var used, extra int
+		if err := tx.QueryRowContext(ctx,
+			`SELECT used, extra FROM quota_counters WHERE rule = ? AND period = ?`, rule.Name, period).Scan(&used, &extra); err != nil {
+			return nil, fmt.Errorf("failed to read quota counter %s/%s: %w", rule.Name, period, err)
+		}
+
+		allowed := rule.Limit + extra
+		if used >= allowed && !rule.Soft && !overLimit {
+			q.notifier.Critical("quota:"+rule.Name,
+				fmt.Sprintf("quota %q exhausted for %s (%d/%d); DI is refused until an override is granted", rule.Name, period, used, allowed))
+			return nil, fmt.Errorf("%w: quota %q for %s (%d/%d devices)", ErrQuotaExhausted, rule.Name, period, used, allowed)
+		}
+
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE quota_counters SET used = used + 1 WHERE rule = ? AND period = ?`, rule.Name, period); err != nil {
+			return nil, fmt.Errorf("failed to update quota counter %s/%s: %w", rule.Name, period, err)
+		}
+		reservation.counters = append(reservation.counters, quotaCounter{rule: rule.Name, period: period})
+
+		used++
+		switch {
+		case used > allowed:
+			kind := "soft quota"
+			if !rule.Soft {
+				kind = "quota" // Waived by an override
+			}
+			warnings = append(warnings, fmt.Sprintf("%s %q exceeded for %s (%d/%d devices)", kind, rule.Name, period, used, allowed))
+		case used*100 >= allowed*rule.WarnAt && (used-1)*100 < allowed*rule.WarnAt:
+			warnings = append(warnings, fmt.Sprintf("quota %q reached %d%% for %s (%d/%d devices)", rule.Name, rule.WarnAt, period, used, allowed))
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit quota counters: %w", err)
+	}
+	for _, warning := range warnings {
+		fmt.Printf("⚠️  %s\n", warning)
+	}
+	return reservation, nil
+}
+
+// Release gives back the units counted by Reserve, e.g. when the voucher pipeline failed
+func (q *QuotaService) Release(ctx context.Context, reservation *QuotaReservation) {
+	if q == nil || reservation == nil {
+		return
+	}
+	for _, counter := range reservation.counters {
+		if _, err := q.db.db.ExecContext(ctx,
+			`UPDATE quota_counters SET used = used - 1 WHERE rule = ? AND period = ? AND used > 0`,
+			counter.rule, counter.period); err != nil {
+			fmt.Printf("⚠️  Failed to release quota counter %s/%s: %v\n", counter.rule, counter.period, err)
+		}
+	}
+}
+
+// Status returns the current-period counters of every quota rule
+func (q *QuotaService) Status(ctx context.Context) ([]QuotaStatus, error) {
+	statuses := []QuotaStatus{}
+	if q == nil {
+		return statuses, nil
+	}
+
+	now := stationClock.Now()
+	for _, rule := range q.rules {
+		period, _ := quotaPeriodKey(rule.Period, now)
+		status := QuotaStatus{
+			Name:      rule.Name,
+			Customer:  rule.Customer,
+			Model:     rule.Model,
+			Period:    rule.Period,
+			PeriodKey: period,
+			Limit:     rule.Limit,
+			WarnAt:    rule.WarnAt,
+			Soft:      rule.Soft,
+		}
+		var reason sql.NullString
+		err := q.db.db.QueryRowContext(ctx,
+			`SELECT used, extra, override_reason FROM quota_counters WHERE rule = ? AND period = ?`,
+			rule.Name, period).Scan(&status.Used, &status.Extra, &reason)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("failed to read quota counter %s/%s: %w", rule.Name, period, err)
+		}
+		status.Reason = reason.String
+		status.Remaining = status.Limit + status.Extra - status.Used
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// SetOverride grants extra units to a quota for the current period, replacing any earlier override
+func (q *QuotaService) SetOverride(ctx context.Context, name string, extra int, reason string) (*QuotaStatus, error) {
+	rule, ok := q.rule(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown quota %q", name)
+	}
+	if extra < 0 {
+		return nil, fmt.Errorf("override extra must not be negative")
+	}
+
+	period, _ := quotaPeriodKey(rule.Period, stationClock.Now())
+	if _, err := q.db.db.ExecContext(ctx, `
+	INSERT INTO quota_counters (rule, period, extra, override_reason) VALUES (?, ?, ?, ?)
+	ON CONFLICT (rule, period) DO UPDATE SET extra = excluded.extra, override_reason = excluded.override_reason`,
+		rule.Name, period, extra, reason); err != nil {
+		return nil, fmt.Errorf("failed to store quota override for %s/%s: %w", rule.Name, period, err)
+	}
+	fmt.Printf("🔓 Quota %q override for %s: +%d devices (%s)\n", rule.Name, period, extra, reason)
+
+	statuses, err := q.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, status := range statuses {
+		if status.Name == name {
+			return &status, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown quota %q", name)
+}
+
+// StatusHandler serves GET /api/quotas
+func (q *QuotaService) StatusHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		statuses, err := q.Status(r.Context())
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, statuses)
+	})
+}
+
+// OverrideHandler serves POST /api/quotas/{name}/override
+func (q *QuotaService) OverrideHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("name")
+		if _, ok := q.rule(name); !ok {
+			writeJSONError(w, http.StatusNotFound, fmt.Sprintf("unknown quota %q", name))
+			return
+		}
+		var req QuotaOverrideRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid override request: %v", err))
+			return
+		}
+		if req.Extra < 0 {
+			writeJSONError(w, http.StatusBadRequest, "extra must not be negative")
+			return
+		}
+		status, err := q.SetOverride(r.Context(), name, req.Extra, req.Reason)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, status)
+	})
+}
+
+// rule looks up a quota rule by name
+func (q *QuotaService) rule(name string) (QuotaRule, bool) {
+	if q == nil {
+		return QuotaRule{}, false
+	}
+	for _, rule := range q.rules {
+		if rule.Name == name {
+			return rule, true
+		}
+	}
+	return QuotaRule{}, false
+}
+
+// matches reports whether a device for customer/model counts against the rule
+func (r QuotaRule) matches(customer, model string) bool {
+	return matchesCustomerModel(r.Customer, r.Model, customer, model)
+}
+
+// quotaPeriodKey names the counting period containing t
+func quotaPeriodKey(period string, t time.Time) (string, error) {
+	t = t.UTC()
+	switch period {
+	case "day":
+		return t.Format("2006-01-02"), nil
+	case "month":
+		return t.Format("2006-01"), nil
+	case "year":
+		return t.Format("2006"), nil
+	case "total":
+		return "total", nil
+	default:
+		return "", fmt.Errorf("unsupported period %q (want day, month, year or total)", period)
+	}
+}
@@ -0,0 +1,96 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+)
+
+// runReplica serves the reporting half of the admin API from a read-only view
+// of a production station's database. It opens neither the go-fdo database nor
+// any keys, so analysts get reports without access to a manufacturing station.
+func runReplica(ctx context.Context) error {
+	path := stationDBPath(config)
+	stationDB, err := OpenStationDBReadOnly(path)
+	if err != nil {
+		return err
+	}
+	defer stationDB.Close()
+
+	// The instance ID is the production station's; the replica must not create one
+	var instanceID string
+	_ = stationDB.db.QueryRowContext(ctx, `SELECT value FROM station_meta WHERE key = 'instance_id'`).Scan(&instanceID)
+	buildInfo := currentBuildInfo(config, instanceID)
+
+	auditLog := NewAuditLog(stationDB, &config.Station)
+	batchService := NewBatchService(&config.Batches, stationDB, nil, auditLog)
+	quotaService := NewQuotaService(&config.Quotas, stationDB, nil)
+	uploadDestinations := NewUploadDestinationCatalog(&config.VoucherManagement, stationDB)
+	uploadReceipts := NewUploadReceiptStore(stationDB)
+
+	if config.Admin.Token == "" {
+		fmt.Printf("⚠️  Replica admin API has no token; restrict access to the replica port\n")
+	}
+	mux := http.NewServeMux()
+	mux.Handle("GET /version", versionHandler(buildInfo))
+	mux.Handle("GET /api/openapi.json", openAPIHandler())
+	mux.Handle("GET /api/audit", adminAuth(&config.Admin, auditLog.Handler()))
+	mux.Handle("GET /api/batches", adminAuth(&config.Admin, batchService.ListHandler()))
+	mux.Handle("GET /api/batches/current", adminAuth(&config.Admin, batchService.CurrentHandler()))
+	mux.Handle("GET /api/batches/{id}", adminAuth(&config.Admin, batchService.GetHandler()))
+	mux.Handle("GET /api/batches/{id}/vouchers", adminAuth(&config.Admin, batchService.BatchVouchersHandler()))
+	mux.Handle("GET /api/lots/{lot}", adminAuth(&config.Admin, batchService.LotHandler()))
+	mux.Handle("GET /api/lots/{lot}/vouchers", adminAuth(&config.Admin, batchService.LotVouchersHandler()))
+	mux.Handle("GET /api/vouchers", adminAuth(&config.Admin, batchService.VouchersHandler()))
+	mux.Handle("GET /api/uploads", adminAuth(&config.Admin, uploadReceipts.ListHandler()))
+	mux.Handle("GET /api/quotas", adminAuth(&config.Admin, quotaService.StatusHandler()))
+	mux.Handle("GET /api/destinations", adminAuth(&config.Admin, uploadDestinations.ListHandler()))
+	mux.Handle("GET /api/destinations/{name}", adminAuth(&config.Admin, uploadDestinations.GetHandler()))
+	if config.Admin.GraphQL {
+		// Queries only, so POST is safe on the replica too
+		graphQL := NewGraphQLService(batchService, auditLog, uploadReceipts, uploadDestinations).Handler()
+		mux.Handle("GET /api/graphql", adminAuth(&config.Admin, graphQL))
+		mux.Handle("POST /api/graphql", adminAuth(&config.Admin, graphQL))
+	}
+	// Everything else, including every write, is refused
+	mux.Handle("/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSONError(w, http.StatusForbidden, "read-only replica: only the reporting API is available")
+	}))
+
+	// The replica serves the admin API only, so it takes the admin listener when there is one
+	srv, err := adminServer(&config.Server, adminHTTP(&config.Admin.HTTP, mux))
+	if err != nil {
+		return err
+	}
+	if srv == nil {
+		if srv, err = diServer(&config.Server, adminHTTP(&config.Admin.HTTP, mux)); err != nil {
+			return err
+		}
+	}
+	lis, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		return fmt.Errorf("error listening on %s: %w", srv.Addr, err)
+	}
+	defer func() { _ = lis.Close() }()
+	if err := dropPrivileges(config); err != nil {
+		return fmt.Errorf("failed to drop privileges: %w", err)
+	}
+
+	slog.Info("FDO Manufacturing Station starting",
+		"local", lis.Addr().String(),
+		"station_db", path,
+		"mode", "read-only replica")
+	fmt.Printf("📖 Read-only replica of station instance %s serving reports on %s\n", instanceID, lis.Addr())
+
+	if err := serve(srv, lis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
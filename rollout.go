@@ -0,0 +1,61 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"path"
+)
+
+// Rollout flags, as named in the rollouts config section
+const (
+	RolloutDIDSignover = "did_signover"
+	RolloutHTTPUpload  = "http_upload"
+)
+
+// Includes reports whether a device gets the behavior the rule gates. Devices
+// outside the listed models are picked by a hash of the flag and serial, so a
+// device keeps its decision across retries and a larger percent only adds
+// devices, while each flag picks a different share of the line.
+func (r *RolloutRule) Includes(flag, serial, model string) bool {
+	if r == nil || !r.Enabled {
+		return true
+	}
+	for _, pattern := range r.Models {
+		if ok, _ := path.Match(pattern, model); ok {
+			return true
+		}
+	}
+	if r.Percent <= 0 {
+		return false
+	}
+	sum := sha256.Sum256([]byte(flag + "\x00" + serial))
+	return binary.BigEndian.Uint64(sum[:8])%100 < uint64(r.Percent)
+}
+
+// validateRollouts checks the rollout rules and that the behaviors they gate
+// have a fallback for the devices outside the rollout
+func validateRollouts(cfg *Config) error {
+	for flag, rule := range map[string]*RolloutRule{
+		RolloutDIDSignover: &cfg.Rollouts.DIDSignover,
+		RolloutHTTPUpload:  &cfg.Rollouts.HTTPUpload,
+	} {
+		if rule.Percent < 0 || rule.Percent > 100 {
+			return fmt.Errorf("rollouts.%s.percent must be between 0 and 100", flag)
+		}
+		for _, pattern := range rule.Models {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("rollouts.%s.models: invalid pattern %q: %w", flag, pattern, err)
+			}
+		}
+	}
+	upload := &cfg.VoucherManagement.VoucherUpload
+	if cfg.Rollouts.HTTPUpload.Enabled && (upload.Mode != "http" || upload.ExternalCommand == "") {
+		return fmt.Errorf("rollouts.http_upload needs voucher_upload.mode http and an external_command for devices outside the rollout")
+	}
+	return nil
+}
@@ -0,0 +1,431 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// routingTableVersion is the format version of exported routing tables
+const routingTableVersion = 1
+
+// RoutingTable is the upload routing of a station: every upload destination
+// with the owner (customer) whose vouchers it receives and the auth profile
+// it is uploaded with. It is exported and imported as a whole, so it can be
+// kept in git and reviewed like code. Which owner key or DID a device is
+// signed over to is decided by owner_signover and stays in the config file.
+type RoutingTable struct {
+	Version      int                        `json:"version" yaml:"version"`
+	Destinations []UploadDestinationRequest `json:"destinations" yaml:"destinations"`
+}
+
+// RoutingDiff is the response of the routing diff and import endpoints. Each
+// change has the path "destinations.<name>" and the old and new destination;
+// old is null for an added destination and new is null for a removed one.
+type RoutingDiff struct {
+	Changes []ConfigChange `json:"changes"`
+	Applied bool           `json:"applied"`
+}
+
+// Export returns the routing table, destinations ordered by name
+func (c *UploadDestinationCatalog) Export(ctx context.Context) (*RoutingTable, error) {
+	destinations, err := c.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	table := &RoutingTable{Version: routingTableVersion, Destinations: []UploadDestinationRequest{}}
+	for _, d := range destinations {
+		table.Destinations = append(table.Destinations, routingEntry(d))
+	}
+	return table, nil
+}
+
+// DiffRouting validates a routing table and returns how importing it would
+// change the catalog. Destinations missing from the table are removed.
+func (c *UploadDestinationCatalog) DiffRouting(ctx context.Context, table *RoutingTable) ([]ConfigChange, error) {
+	if table.Version != routingTableVersion {
+		return nil, fmt.Errorf("unsupported routing table version %d (expected %d)", table.Version, routingTableVersion)
+	}
+	names, urls := map[string]bool{}, map[string]string{}
+	for i := range table.Destinations {
+		d := &table.Destinations[i]
+		if err := c.validate(d); err != nil {
+			return nil, fmt.Errorf("destination %d (%q): %w", i+1, d.Name, err)
+		}
+		if names[d.Name] {
+			return nil, fmt.Errorf("destination %q is listed twice", d.Name)
+		}
+		if other, ok := urls[d.URL]; ok {
+			return nil, fmt.Errorf("destinations %q and %q have the same url %s", other, d.Name, d.URL)
+		}
+		names[d.Name], urls[d.URL] = true, d.Name
+		if d.Enabled == nil {
+			enabled := true
+			d.Enabled = &enabled
+		}
+	}
+
+	current, err := c.Export(ctx)
+	if err != nil {
+		return nil, err
+	}
+	old := map[string]UploadDestinationRequest{}
+	for _, d := range current.Destinations {
+		old[d.Name] = d
+	}
+	changes := []ConfigChange{}
+	for _, d := range table.Destinations {
+		previous, ok := old[d.Name]
+		switch {
+		case !ok:
+			changes = append(changes, ConfigChange{Path: "destinations." + d.Name, New: d})
+		case !reflect.DeepEqual(previous, d):
+			changes = append(changes, ConfigChange{Path: "destinations." + d.Name, Old: previous, New: d})
+		}
+	}
+	for _, d := range current.Destinations {
+		if !names[d.Name] {
+			changes = append(changes, ConfigChange{Path: "destinations." + d.Name, Old: d})
+		}
+	}
+	slices.SortFunc(changes, func(a, b ConfigChange) int { return strings.Compare(a.Path, b.Path) })
+	return changes, nil
+}
+
+// ImportRouting replaces the routing with a table in one transaction, keeping
+// the health counters of destinations that stay. The table routes every
+// customer, so admins limited to some customers can't import one.
+func (c *UploadDestinationCatalog) ImportRouting(ctx context.Context, table *RoutingTable) ([]ConfigChange, error) {
+	if err := checkAdminCustomers(ctx, "the routing table", nil, true); err != nil {
+		return nil, err
+	}
+	changes, err := c.DiffRouting(ctx, table)
+	if err != nil || len(changes) == 0 {
+		return changes, err
+	}
+	tx, err := c.db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to import routing table: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Removals first, so a URL can move to another destination name
+	for _, change := range changes {
+		if change.New == nil {
+			name := change.Old.(UploadDestinationRequest).Name
+			if _, err := tx.ExecContext(ctx, `DELETE FROM upload_destinations WHERE name = ?`, name); err != nil {
+				return nil, fmt.Errorf("failed to remove upload destination %s: %w", name, err)
+			}
+		}
+	}
+	for _, change := range changes {
+		if change.New == nil {
+			continue
+		}
+		d := change.New.(UploadDestinationRequest)
+		if _, err := tx.ExecContext(ctx, `
+		INSERT INTO upload_destinations (name, url, auth_profile, owner, enabled) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (name) DO UPDATE SET url = excluded.url, auth_profile = excluded.auth_profile,
+			owner = excluded.owner, enabled = excluded.enabled`,
+			d.Name, d.URL, d.AuthProfile, d.Owner, *d.Enabled); err != nil {
+			return nil, fmt.Errorf("failed to store upload destination %s: %w", d.Name, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to import routing table: %w", err)
+	}
+	return changes, nil
+}
+
+// routingEntry returns the routing of a catalog destination
+func routingEntry(d *UploadDestination) UploadDestinationRequest {
+	enabled := d.Enabled
+	return UploadDestinationRequest{Name: d.Name, URL: d.URL, AuthProfile: d.AuthProfile, Owner: d.Owner, Enabled: &enabled}
+}
+
+// parseRoutingTable reads a routing table in YAML or JSON, refusing unknown fields
+func parseRoutingTable(data []byte) (*RoutingTable, error) {
+	var table RoutingTable
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&table); err != nil {
+		return nil, fmt.Errorf("invalid routing table: %w", err)
+	}
+	return &table, nil
+}
+
+// writeRoutingTable writes a routing table as "yaml" or "json"
+func writeRoutingTable(w io.Writer, table *RoutingTable, format string) error {
+	switch format {
+	case "yaml":
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(table); err != nil {
+			return err
+		}
+		return encoder.Close()
+	case "json", "":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(table)
+	default:
+		return fmt.Errorf("unknown format %q (expected yaml or json)", format)
+	}
+}
+
+// recordRoutingImport logs and audits an imported routing table
+func recordRoutingImport(ctx context.Context, auditLog *AuditLog, changes []ConfigChange, source string) {
+	paths := make([]string, len(changes))
+	for i, change := range changes {
+		paths[i] = change.Path
+	}
+	fmt.Printf("📇 Routing table imported via %s: %s\n", source, strings.Join(paths, ", "))
+	auditLog.Record(ctx, AuditEvent{
+		Event:  "routing_imported",
+		Detail: fmt.Sprintf("via %s: %s", source, strings.Join(paths, ", ")),
+	})
+}
+
+// ExportHandler serves GET /api/routing; format=yaml returns YAML instead of JSON
+func (c *UploadDestinationCatalog) ExportHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		table, err := c.Export(r.Context())
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		format := r.URL.Query().Get("format")
+		var buf bytes.Buffer
+		if err := writeRoutingTable(&buf, table, format); err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if format == "yaml" {
+			w.Header().Set("Content-Type", "application/yaml")
+		} else {
+			w.Header().Set("Content-Type", "application/json")
+		}
+		_, _ = w.Write(buf.Bytes())
+	})
+}
+
+// DiffHandler serves POST /api/routing/diff with a YAML or JSON routing table
+func (c *UploadDestinationCatalog) DiffHandler() http.Handler {
+	return c.routingHandler(nil)
+}
+
+// ImportHandler serves PUT /api/routing with a YAML or JSON routing table
+func (c *UploadDestinationCatalog) ImportHandler(auditLog *AuditLog) http.Handler {
+	return c.routingHandler(auditLog)
+}
+
+// routingHandler diffs, or with an audit log imports, the routing table in the request body
+func (c *UploadDestinationCatalog) routingHandler(auditLog *AuditLog) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if c == nil {
+			writeJSONError(w, http.StatusNotFound, "upload destination catalog is not enabled (voucher_upload.mode must be http)")
+			return
+		}
+		body, err := io.ReadAll(io.LimitReader(r.Body, 1024*1024))
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("failed to read routing table: %v", err))
+			return
+		}
+		table, err := parseRoutingTable(body)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		diff := &RoutingDiff{}
+		if auditLog == nil {
+			diff.Changes, err = c.DiffRouting(r.Context(), table)
+		} else {
+			diff.Changes, err = c.ImportRouting(r.Context(), table)
+			diff.Applied = err == nil && len(diff.Changes) > 0
+		}
+		if err != nil {
+			writeJSONError(w, scopeStatus(err, http.StatusBadRequest), err.Error())
+			return
+		}
+		if diff.Applied {
+			recordRoutingImport(r.Context(), auditLog, diff.Changes, "admin API from "+r.RemoteAddr)
+		}
+		writeJSON(w, http.StatusOK, diff)
+	})
+}
+
+// describeImport files a routing table import that changes anything for a second admin's approval
+func (c *UploadDestinationCatalog) describeImport(r *http.Request, body []byte) (*ChangeRequest, error) {
+	if c == nil {
+		return nil, nil
+	}
+	if err := checkAdminCustomers(r.Context(), "the routing table", nil, true); err != nil {
+		return nil, err
+	}
+	table, err := parseRoutingTable(body)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := c.DiffRouting(r.Context(), table)
+	if err != nil || len(changes) == 0 {
+		return nil, err
+	}
+	paths := make([]string, len(changes))
+	for i, change := range changes {
+		paths[i] = change.Path
+	}
+	return &ChangeRequest{Target: strings.Join(paths, ", "), Changes: changes}, nil
+}
+
+// applyImport returns the applier of approved routing table imports
+func (c *UploadDestinationCatalog) applyImport(auditLog *AuditLog) approvalApplier {
+	return func(ctx context.Context, change *ChangeRequest) (any, error) {
+		if c == nil {
+			return nil, fmt.Errorf("upload destination catalog is not enabled (voucher_upload.mode must be http)")
+		}
+		table, err := parseRoutingTable(change.payload)
+		if err != nil {
+			return nil, err
+		}
+		diff := &RoutingDiff{}
+		if diff.Changes, err = c.ImportRouting(ctx, table); err != nil {
+			return nil, err
+		}
+		if diff.Applied = len(diff.Changes) > 0; diff.Applied {
+			recordRoutingImport(ctx, auditLog, diff.Changes, "approved change "+change.ID)
+		}
+		return diff, nil
+	}
+}
+
+// runRouting implements "routing export [-format yaml|json] [file]" and
+// "routing import [-dry-run] <file>" against the station database. An import
+// prints the changes first; with admin.dual_control on, imports must go
+// through PUT /api/routing so a second admin approves them.
+func runRouting(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: routing export|import ...")
+	}
+	switch args[0] {
+	case "export":
+		fs := flag.NewFlagSet("routing export", flag.ContinueOnError)
+		format := fs.String("format", "yaml", "Output format: yaml or json")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if fs.NArg() > 1 {
+			return fmt.Errorf("usage: routing export [-format yaml|json] [file]")
+		}
+		stationDB, err := OpenStationDBReadOnly(stationDBPath(config))
+		if err != nil {
+			return err
+		}
+		defer stationDB.Close()
+		table, err := NewUploadDestinationCatalog(&config.VoucherManagement, stationDB).Export(context.Background())
+		if err != nil {
+			return err
+		}
+		if fs.NArg() == 0 {
+			return writeRoutingTable(os.Stdout, table, *format)
+		}
+		var buf bytes.Buffer
+		if err := writeRoutingTable(&buf, table, *format); err != nil {
+			return err
+		}
+		if err := os.WriteFile(fs.Arg(0), buf.Bytes(), 0o644); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "📇 %d destinations written to %s\n", len(table.Destinations), fs.Arg(0))
+		return nil
+
+	case "import":
+		fs := flag.NewFlagSet("routing import", flag.ContinueOnError)
+		dryRun := fs.Bool("dry-run", false, "Validate and show the changes without applying them")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if fs.NArg() != 1 {
+			return fmt.Errorf("usage: routing import [-dry-run] <file>")
+		}
+		data, err := os.ReadFile(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		table, err := parseRoutingTable(data)
+		if err != nil {
+			return err
+		}
+		if !*dryRun && config.Admin.DualControl.Enabled {
+			return fmt.Errorf("admin.dual_control is enabled: import with PUT /api/routing so a second admin can approve it")
+		}
+		stationDB, err := OpenStationDB(stationDBPath(config))
+		if err != nil {
+			return err
+		}
+		defer stationDB.Close()
+		ctx := context.Background()
+		catalog := NewUploadDestinationCatalog(&config.VoucherManagement, stationDB)
+		if err := catalog.Initialize(ctx); err != nil {
+			return err
+		}
+
+		changes, err := catalog.DiffRouting(ctx, table)
+		if err != nil {
+			return err
+		}
+		for _, change := range changes {
+			printRoutingChange(change)
+		}
+		if len(changes) == 0 {
+			fmt.Println("✅ Routing table is already up to date")
+			return nil
+		}
+		if *dryRun {
+			fmt.Printf("%d changes (dry run, nothing applied)\n", len(changes))
+			return nil
+		}
+		if changes, err = catalog.ImportRouting(ctx, table); err != nil {
+			return err
+		}
+		auditLog := NewAuditLog(stationDB, &config.Station)
+		if err := auditLog.Initialize(ctx); err != nil {
+			return err
+		}
+		recordRoutingImport(ctx, auditLog, changes, "routing import "+fs.Arg(0))
+		fmt.Printf("✅ %d changes applied\n", len(changes))
+		return nil
+
+	default:
+		return fmt.Errorf("unknown routing command %q (expected export or import)", args[0])
+	}
+}
+
+// printRoutingChange prints one change of a routing import
+func printRoutingChange(change ConfigChange) {
+	describe := func(v any) string {
+		d := v.(UploadDestinationRequest)
+		return fmt.Sprintf("url=%s owner=%q auth_profile=%q enabled=%v", d.URL, d.Owner, d.AuthProfile, *d.Enabled)
+	}
+	switch {
+	case change.Old == nil:
+		fmt.Printf("+ %s: %s\n", change.Path, describe(change.New))
+	case change.New == nil:
+		fmt.Printf("- %s: %s\n", change.Path, describe(change.Old))
+	default:
+		fmt.Printf("~ %s: %s\n  → %s\n", change.Path, describe(change.Old), describe(change.New))
+	}
+}
@@ -0,0 +1,157 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+)
+
+// ErrManufacturingWindowClosed is returned when DI is attempted outside every
+// manufacturing window that applies to the device
+var ErrManufacturingWindowClosed = errors.New("manufacturing window closed")
+
+// ManufacturingSchedule enforces the configured shift windows. A nil
+// *ManufacturingSchedule permits manufacturing at any time.
+type ManufacturingSchedule struct {
+	location *time.Location
+	windows  []scheduleWindow
+}
+
+// scheduleWindow is a ScheduleWindow with its days and times parsed
+type scheduleWindow struct {
+	ScheduleWindow
+	days       map[time.Weekday]bool // nil = every day
+	start, end int                   // minutes since midnight
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// NewManufacturingSchedule parses the schedule config, returning nil if no windows are configured
+func NewManufacturingSchedule(config *ScheduleConfig) (*ManufacturingSchedule, error) {
+	if len(config.Windows) == 0 {
+		return nil, nil
+	}
+
+	location := time.Local
+	if config.Timezone != "" {
+		var err error
+		location, err = time.LoadLocation(config.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("schedule: invalid timezone %q: %w", config.Timezone, err)
+		}
+	}
+
+	schedule := &ManufacturingSchedule{location: location}
+	for i, cfg := range config.Windows {
+		name := cfg.Name
+		if name == "" {
+			name = fmt.Sprintf("%d", i+1)
+		}
+		window := scheduleWindow{ScheduleWindow: cfg}
+		window.Name = name
+
+		var err error
+		if window.start, err = parseClock(cfg.Start); err != nil {
+			return nil, fmt.Errorf("schedule window %s: start: %w", name, err)
+		}
+		if window.end, err = parseClock(cfg.End); err != nil {
+			return nil, fmt.Errorf("schedule window %s: end: %w", name, err)
+		}
+		if window.start == window.end {
+			return nil, fmt.Errorf("schedule window %s: start and end must differ", name)
+		}
+		if _, err := path.Match(cfg.Model, ""); err != nil {
+			return nil, fmt.Errorf("schedule window %s: invalid model pattern: %w", name, err)
+		}
+		if len(cfg.Days) > 0 {
+			window.days = make(map[time.Weekday]bool)
+			for _, day := range cfg.Days {
+				weekday, ok := weekdayNames[strings.ToLower(day)]
+				if !ok {
+					return nil, fmt.Errorf("schedule window %s: unknown day %q", name, day)
+				}
+				window.days[weekday] = true
+			}
+		}
+		schedule.windows = append(schedule.windows, window)
+	}
+	return schedule, nil
+}
+
+// Check returns ErrManufacturingWindowClosed (wrapped) if windows apply to the
+// device but none of them is open at now
+func (s *ManufacturingSchedule) Check(now time.Time, customer, model string) error {
+	if s == nil {
+		return nil
+	}
+
+	now = now.In(s.location)
+	var applicable []string
+	for _, window := range s.windows {
+		if !matchesCustomerModel(window.Customer, window.Model, customer, model) {
+			continue
+		}
+		if window.open(now) {
+			return nil
+		}
+		applicable = append(applicable, window.Name)
+	}
+	if len(applicable) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %s is outside windows %s for customer %q model %q",
+		ErrManufacturingWindowClosed, now.Format("Mon 15:04 MST"), strings.Join(applicable, ", "), customer, model)
+}
+
+// open reports whether the window is open at t. Windows spanning midnight
+// belong to the day they start on.
+func (w scheduleWindow) open(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+	day := t.Weekday()
+	if w.start < w.end {
+		return minute >= w.start && minute < w.end && w.onDay(day)
+	}
+	if minute >= w.start {
+		return w.onDay(day)
+	}
+	if minute < w.end {
+		return w.onDay((day + 6) % 7)
+	}
+	return false
+}
+
+func (w scheduleWindow) onDay(day time.Weekday) bool {
+	return w.days == nil || w.days[day]
+}
+
+// parseClock parses "HH:MM" into minutes since midnight
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("want HH:MM, got %q", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// matchesCustomerModel reports whether a rule scoped to ruleCustomer and the
+// ruleModel glob applies to a device; empty rule fields match anything
+func matchesCustomerModel(ruleCustomer, ruleModel, customer, model string) bool {
+	if ruleCustomer != "" && ruleCustomer != customer {
+		return false
+	}
+	if ruleModel != "" {
+		if ok, _ := path.Match(ruleModel, model); !ok {
+			return false
+		}
+	}
+	return true
+}
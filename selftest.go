@@ -0,0 +1,400 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha512"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fido-device-onboard/go-fdo"
+	"github.com/fido-device-onboard/go-fdo/cbor"
+	"github.com/fido-device-onboard/go-fdo/cose"
+	"github.com/fido-device-onboard/go-fdo/custom"
+	"github.com/fido-device-onboard/go-fdo/protocol"
+	"github.com/fido-device-onboard/go-fdo/sqlite"
+)
+
+// Self-test check outcomes
+const (
+	SelfTestPass = "PASS"
+	SelfTestFail = "FAIL"
+	SelfTestSkip = "SKIP"
+)
+
+// errSelfTestSkipped marks a check that doesn't apply to this configuration
+var errSelfTestSkipped = errors.New("skipped")
+
+// SelfTestResult is one row of the self-test matrix
+type SelfTestResult struct {
+	Check  string
+	Status string
+	Detail string
+	Took   time.Duration
+}
+
+// selfTest runs the checks of "selftest" and collects their results
+type selfTest struct {
+	results []SelfTestResult
+}
+
+// run times one check and records its outcome; detail describes a pass
+func (t *selfTest) run(check string, fn func() (string, error)) {
+	started := time.Now()
+	detail, err := fn()
+	result := SelfTestResult{Check: check, Status: SelfTestPass, Detail: detail, Took: time.Since(started)}
+	switch {
+	case errors.Is(err, errSelfTestSkipped):
+		result.Status = SelfTestSkip
+	case err != nil:
+		result.Status = SelfTestFail
+		result.Detail = err.Error()
+	}
+	t.results = append(t.results, result)
+}
+
+// skip records a check that can't run because an earlier one failed
+func (t *selfTest) skip(check, reason string) {
+	t.results = append(t.results, SelfTestResult{Check: check, Status: SelfTestSkip, Detail: reason})
+}
+
+// failed counts the failed checks
+func (t *selfTest) failed() int {
+	failed := 0
+	for _, r := range t.results {
+		if r.Status == SelfTestFail {
+			failed++
+		}
+	}
+	return failed
+}
+
+// print writes the pass/fail matrix
+func (t *selfTest) print() {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CHECK\tRESULT\tTIME\tDETAIL")
+	for _, r := range t.results {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", r.Check, r.Status, r.Took.Round(time.Millisecond), r.Detail)
+	}
+	_ = w.Flush()
+}
+
+// runSelfTest implements "selftest [-serial S] [-model M] [-lot L]", run at
+// shift start before the line does. It makes up a throwaway device (a fresh
+// GUID and serial), runs it through the full voucher pipeline with the real
+// manufacturer key, owner key command, DID resolution, OVE extra data and
+// signing mode, checks the signed voucher, and checks every save directory
+// and upload endpoint without delivering anything: uploads are only probed
+// with an authenticated HEAD request, the voucher is saved to a temp dir, and
+// no audit, quota, batch or receipt records are written.
+func runSelfTest(args []string) error {
+	fs := flag.NewFlagSet("selftest", flag.ContinueOnError)
+	serial := fs.String("serial", "SELFTEST-"+time.Now().UTC().Format("20060102T150405"), "Serial number of the throwaway device")
+	model := fs.String("model", "selftest", "Model of the throwaway device; use one the line builds so the owner key command knows it")
+	lot := fs.String("lot", "", "Lot (customer order) of the throwaway device")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf("usage: selftest [-serial S] [-model M] [-lot L]")
+	}
+
+	ctx := context.Background()
+	t := &selfTest{}
+	vm := config.VoucherManagement
+
+	t.run("config", func() (string, error) {
+		return *configPath, validateConfig(config)
+	})
+
+	var stationDB *StationDB
+	t.run("station database", func() (string, error) {
+		var err error
+		stationDB, err = OpenStationDBReadOnly(stationDBPath(config))
+		return stationDBPath(config), err
+	})
+	if stationDB != nil {
+		defer stationDB.Close()
+	}
+
+	var state *sqlite.DB
+	t.run("fdo database", func() (string, error) {
+		var err error
+		state, err = sqlite.Open(config.Database.Path, config.Database.Password)
+		return config.Database.Path, err
+	})
+	if state != nil {
+		defer state.Close()
+	}
+
+	// The manufacturer key signs a test digest, as internal signing and the
+	// device CA would; an HSM is exercised by the pipeline below
+	var mfgKey crypto.Signer
+	if state == nil {
+		t.skip("manufacturer key", "fdo database unavailable")
+	} else {
+		t.run("manufacturer key", func() (string, error) {
+			var err error
+			if mfgKey, _, err = state.ManufacturerKey(ctx, protocol.Secp384r1KeyType, 0); err != nil {
+				return "", err
+			}
+			digest := sha512.Sum384([]byte("fdo-selftest"))
+			sig, err := mfgKey.Sign(rand.Reader, digest[:], crypto.SHA384)
+			if err != nil {
+				return "", fmt.Errorf("test signature failed: %w", err)
+			}
+			if pub, ok := mfgKey.Public().(*ecdsa.PublicKey); !ok || !ecdsa.VerifyASN1(pub, digest[:], sig) {
+				return "", fmt.Errorf("test signature does not verify")
+			}
+			return fmt.Sprintf("%s %s", describeKey(mfgKey.Public()), ownerKeySHA256(mfgKey.Public())), nil
+		})
+	}
+
+	t.run("fallback signing key", func() (string, error) {
+		if !vm.VoucherSigning.Fallback.Enabled {
+			return "voucher_signing.fallback disabled", errSelfTestSkipped
+		}
+		signer, err := loadFallbackSigner(&vm.VoucherSigning)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s %s", describeKey(signer.Public()), ownerKeySHA256(signer.Public())), nil
+	})
+
+	// Plugins serve owner key and extra data commands configured as plugins
+	pluginCtx, stopPlugins := context.WithCancel(ctx)
+	defer stopPlugins()
+	commandPlugins = NewCommandPlugins(&config.ExternalCommands)
+	commandPlugins.Start(pluginCtx)
+	if err := configureExtensions(config); err != nil {
+		return err
+	}
+
+	ownerKeys := NewOwnerKeyService(NewExternalCommandExecutor(CommandOwnerSignover, vm.OwnerSignover.ExternalCommand, vm.OwnerSignover.Timeout), &vm.OwnerSignover, &vm.DIDCache, &config.Rollouts, nil)
+	var owner *OwnerKeyResult
+	t.run("owner key", func() (string, error) {
+		var err error
+		if owner, err = ownerKeys.GetOwnerKey(ctx, *serial, *model, *lot); err != nil {
+			return "", err
+		}
+		detail := fmt.Sprintf("%s %s", describeKey(owner.PublicKey), ownerKeySHA256(owner.PublicKey))
+		if owner.DIDURL != "" {
+			detail += ", recipient " + owner.DIDURL
+		}
+		if owner.Customer != "" {
+			detail += ", customer " + owner.Customer
+		}
+		return detail, nil
+	})
+
+	if mfgKey == nil {
+		t.skip("signover pipeline", "manufacturer key unavailable")
+	} else {
+		t.run("signover pipeline", func() (string, error) {
+			return selfTestPipeline(ctx, mfgKey, *serial, *model)
+		})
+	}
+
+	t.run("save directories", func() (string, error) {
+		return selfTestDirectories(&vm)
+	})
+
+	selfTestUploads(ctx, t, stationDB, owner)
+
+	t.print()
+	if failed := t.failed(); failed > 0 {
+		return fmt.Errorf("%d of %d checks failed", failed, len(t.results))
+	}
+	fmt.Printf("✅ All checks passed\n")
+	return nil
+}
+
+// selfTestPipeline signs a throwaway voucher over with the configured
+// signing mode and checks the result; nothing is uploaded or kept
+func selfTestPipeline(ctx context.Context, mfgKey crypto.Signer, serial, model string) (string, error) {
+	tempDir, err := os.MkdirTemp("", "fdo-selftest-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	pipelineConfig := config.VoucherManagement
+	pipelineConfig.VoucherUpload.Enabled = false
+	pipelineConfig.SaveToDisk.Directory = tempDir
+	pipelineConfig.SaveToDisk.Destinations = nil
+	pipelineConfig.SaveToDisk.Manifest.Enabled = false
+	pipelineConfig.VoucherSigning.FailureDirectory = filepath.Join(tempDir, "extend-failures")
+	pipelineConfig.RecordSessions = false
+
+	// The voucher header names the key the configured signing mode signs with
+	header := fdo.VoucherHeader{
+		Version:    101,
+		RvInfo:     [][]protocol.RvInstruction{},
+		DeviceInfo: model,
+	}
+	if _, err := rand.Read(header.GUID[:]); err != nil {
+		return "", fmt.Errorf("failed to generate GUID: %w", err)
+	}
+	if pipelineConfig.VoucherSigning.Mode == "external" && pipelineConfig.VoucherSigning.ManufacturerPublicKeyFile != "" {
+		if header.ManufacturerKey, err = LoadManufacturerPublicKey(pipelineConfig.VoucherSigning.ManufacturerPublicKeyFile); err != nil {
+			return "", err
+		}
+	} else {
+		encoded, err := encodePublicKey(protocol.Secp384r1KeyType, protocol.X509KeyEnc, mfgKey.Public(), nil)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode manufacturer key: %w", err)
+		}
+		header.ManufacturerKey = *encoded
+	}
+	ov := &fdo.Voucher{
+		Version: 101,
+		Header:  *cbor.NewBstr(header),
+		Entries: []cose.Sign1Tag[fdo.VoucherEntryPayload, []byte]{},
+	}
+	guid := fmt.Sprintf("%x", header.GUID[:])
+
+	hashPolicy, err := NewVoucherHashPolicy(&pipelineConfig)
+	if err != nil {
+		return "", err
+	}
+	callbacks := NewVoucherCallbackService(
+		&pipelineConfig,
+		NewOwnerKeyService(NewExternalCommandExecutor(CommandOwnerSignover, pipelineConfig.OwnerSignover.ExternalCommand, pipelineConfig.OwnerSignover.Timeout), &pipelineConfig.OwnerSignover, &pipelineConfig.DIDCache, &config.Rollouts, nil),
+		NewVoucherSigningService(&pipelineConfig.VoucherSigning,
+			NewExternalCommandExecutor(CommandVoucherSign, pipelineConfig.VoucherSigning.ExternalCommand, pipelineConfig.VoucherSigning.ExternalTimeout),
+			config.Station.StationID),
+		nil, // upload probed separately
+		NewVoucherDiskService(&pipelineConfig, nil),
+		NewOVEExtraDataService(&pipelineConfig.OVEExtraData,
+			NewExternalCommandExecutor(CommandOVEExtraData, pipelineConfig.OVEExtraData.ExternalCommand, pipelineConfig.OVEExtraData.Timeout),
+			currentBuildInfo(config, "")),
+		nil, // no quotas
+		nil, // no shift windows
+		nil, // no audit records
+		nil, // no batches
+		hashPolicy,
+		nil, // no revocations
+		nil, // recording disabled
+		nil, // outcomes not counted
+		nil, // vouchers not kept for transfer
+		nil, // signover targets not tracked
+		nil, // no claim URLs
+		nil, // no overrides
+		nil, // owner keys need no proof
+		nil, // no dry runs
+		nil, // no pipeline stages
+		nil,
+	)
+	session := &replaySession{
+		info: custom.DeviceMfgInfo{SerialNumber: serial, DeviceInfo: model},
+		key:  mfgKey,
+	}
+	if _, err := callbacks.BeforeVoucherPersist(ctx, session, ov); err != nil {
+		return "", err
+	}
+
+	data, err := cbor.Marshal(ov)
+	if err != nil {
+		return "", fmt.Errorf("signed voucher does not encode: %w", err)
+	}
+	if err := verifyStoredVoucher(data, guid); err != nil {
+		return "", err
+	}
+	if len(ov.Entries) == 0 {
+		return fmt.Sprintf("GUID %s, not signed over (signing mode %s)", guid, pipelineConfig.VoucherSigning.Mode), nil
+	}
+	return fmt.Sprintf("GUID %s, %d entries, signing mode %s", guid, len(ov.Entries), pipelineConfig.VoucherSigning.Mode), nil
+}
+
+// selfTestDirectories checks that every voucher save directory is writable
+func selfTestDirectories(vm *VoucherConfig) (string, error) {
+	var dirs []string
+	if vm.SaveToDisk.Directory != "" {
+		dirs = append(dirs, vm.SaveToDisk.Directory)
+	}
+	for _, dest := range vm.SaveToDisk.Destinations {
+		if dest.Directory != "" {
+			dirs = append(dirs, dest.Directory)
+		}
+	}
+	if len(dirs) == 0 {
+		return "no save_to_disk directories", errSelfTestSkipped
+	}
+	for _, dir := range dirs {
+		f, err := os.CreateTemp(dir, ".fdo-selftest-*")
+		if err != nil {
+			return "", fmt.Errorf("%s is not writable: %w", dir, err)
+		}
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+	}
+	return fmt.Sprintf("%d writable", len(dirs)), nil
+}
+
+// selfTestUploads probes the owner's voucher recipient and every enabled
+// catalog destination, one row each
+func selfTestUploads(ctx context.Context, t *selfTest, stationDB *StationDB, owner *OwnerKeyResult) {
+	vm := &config.VoucherManagement
+	switch {
+	case !vm.VoucherUpload.Enabled:
+		t.run("upload", func() (string, error) { return "voucher_upload disabled", errSelfTestSkipped })
+		return
+	case vm.VoucherUpload.Mode != "http":
+		t.run("upload", func() (string, error) {
+			return "upload command can't be run without uploading", errSelfTestSkipped
+		})
+		return
+	}
+
+	uploader := NewVoucherHTTPUploader(vm, config.Station.StationID)
+	probed := map[string]bool{}
+	probe := func(check, recipientURL, profile string) {
+		probed[recipientURL] = true
+		t.run(check, func() (string, error) {
+			status, err := uploader.Probe(ctx, recipientURL, profile)
+			return fmt.Sprintf("%s HTTP %d", recipientURL, status), err
+		})
+	}
+
+	recipientURL, profile := vm.VoucherUpload.URL, vm.VoucherUpload.AuthProfile
+	if owner != nil {
+		if owner.DIDURL != "" {
+			recipientURL = owner.DIDURL
+		}
+		if owner.UploadAuthProfile != "" {
+			profile = owner.UploadAuthProfile
+		}
+	}
+	if recipientURL != "" {
+		probe("upload recipient", recipientURL, profile)
+	}
+
+	if stationDB == nil {
+		return
+	}
+	destinations, err := NewUploadDestinationCatalog(vm, stationDB).List(ctx)
+	if err != nil {
+		t.run("upload destinations", func() (string, error) { return "", err })
+		return
+	}
+	for _, d := range destinations {
+		if !d.Enabled || probed[d.URL] {
+			continue
+		}
+		if d.AuthProfile == "" {
+			d.AuthProfile = vm.VoucherUpload.AuthProfile
+		}
+		probe("upload destination "+d.Name, d.URL, d.AuthProfile)
+	}
+}
@@ -0,0 +1,125 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Fields that can be pseudonymized
+const (
+	SerialFieldSerial = "serial"
+	SerialFieldModel  = "model"
+)
+
+// serialRules is set at startup. It is a global so every log line, filename
+// and external command that shows a serial can scrub it without threading
+// the rules through each service. A nil *SerialRules shows values as is.
+var serialRules *SerialRules
+
+// SerialRules normalizes the serial and model a device reports, and replaces
+// the fields some customers consider sensitive with a keyed hash wherever
+// they leave the station's database: logs, filenames and external commands.
+// The hash is stable, so one device's log lines and files still correlate.
+type SerialRules struct {
+	config       *SerialRulesConfig
+	key          []byte
+	pseudoSerial bool
+	pseudoModel  bool
+}
+
+// NewSerialRules creates the rules, or returns nil if none are configured
+func NewSerialRules(config *SerialRulesConfig) (*SerialRules, error) {
+	if config.SerialCase == "" && config.ModelCase == "" && len(config.StripPrefixes) == 0 && len(config.Pseudonymize) == 0 {
+		return nil, nil
+	}
+	for _, c := range []string{config.SerialCase, config.ModelCase} {
+		if c != "" && c != "upper" && c != "lower" {
+			return nil, fmt.Errorf("serial_rules: case must be upper or lower, not %q", c)
+		}
+	}
+
+	r := &SerialRules{config: config}
+	for _, field := range config.Pseudonymize {
+		switch field {
+		case SerialFieldSerial:
+			r.pseudoSerial = true
+		case SerialFieldModel:
+			r.pseudoModel = true
+		default:
+			return nil, fmt.Errorf("serial_rules: cannot pseudonymize %q (want serial or model)", field)
+		}
+	}
+	if len(config.Pseudonymize) > 0 {
+		if config.PseudonymKeyFile == "" {
+			return nil, fmt.Errorf("serial_rules: pseudonymize needs a pseudonym_key_file")
+		}
+		key, err := os.ReadFile(config.PseudonymKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read pseudonym key: %w", err)
+		}
+		r.key = []byte(strings.TrimSpace(string(key)))
+		if len(r.key) < 16 {
+			return nil, fmt.Errorf("serial_rules: pseudonym key must be at least 16 bytes")
+		}
+	}
+	return r, nil
+}
+
+// Normalize applies the case and prefix rules to what a device reports
+func (r *SerialRules) Normalize(serial, model string) (string, string) {
+	if r == nil {
+		return serial, model
+	}
+	for _, prefix := range r.config.StripPrefixes {
+		if rest, ok := strings.CutPrefix(serial, prefix); ok && rest != "" {
+			serial = rest
+			break
+		}
+	}
+	return applyCase(serial, r.config.SerialCase), applyCase(model, r.config.ModelCase)
+}
+
+// Serial returns the serial as logs, filenames and external commands show it
+func (r *SerialRules) Serial(serial string) string {
+	if r == nil || !r.pseudoSerial {
+		return serial
+	}
+	return r.pseudonym(serial)
+}
+
+// Model returns the model as logs, filenames and external commands show it
+func (r *SerialRules) Model(model string) string {
+	if r == nil || !r.pseudoModel {
+		return model
+	}
+	return r.pseudonym(model)
+}
+
+// pseudonym returns a short keyed hash of a value; empty values stay empty
+func (r *SerialRules) pseudonym(value string) string {
+	if value == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, r.key)
+	mac.Write([]byte(value))
+	return "anon-" + hex.EncodeToString(mac.Sum(nil))[:16]
+}
+
+// applyCase converts a value to the configured case
+func applyCase(value, c string) string {
+	switch c {
+	case "upper":
+		return strings.ToUpper(value)
+	case "lower":
+		return strings.ToLower(value)
+	}
+	return value
+}
@@ -0,0 +1,73 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// Pipeline stages that get a share of the DI session time budget
+const (
+	BudgetStageOwnerKey      = "owner_key"      // Owner signover lookup, including DID resolution
+	BudgetStageDIDResolution = "did_resolution" // DIDResolver.ResolveDIDKey
+	BudgetStageSigning       = "signing"        // Voucher signing (external command or HSM)
+	BudgetStageUpload        = "upload"         // Voucher upload
+)
+
+// Share of the total budget a stage gets when its limit isn't configured
+var defaultBudgetShares = map[string]float64{
+	BudgetStageOwnerKey:      0.25,
+	BudgetStageDIDResolution: 0.15,
+	BudgetStageSigning:       0.25,
+	BudgetStageUpload:        0.35,
+}
+
+type sessionBudgetKey struct{}
+
+// sessionBudget is the time budget of one DI session, carried in its context
+type sessionBudget struct {
+	config *TimeBudgetConfig
+}
+
+// startSessionBudget puts the whole voucher pipeline of a DI session under the
+// configured total budget. Without a total the context is returned unchanged.
+func startSessionBudget(ctx context.Context, config *TimeBudgetConfig) (context.Context, context.CancelFunc) {
+	if config.Total <= 0 {
+		return ctx, func() {}
+	}
+	ctx = context.WithValue(ctx, sessionBudgetKey{}, &sessionBudget{config: config})
+	return context.WithTimeout(ctx, config.Total)
+}
+
+// budgetStage limits one pipeline stage to its share of the session budget.
+// The stage never outlives the session: its deadline is also capped by the
+// time left in the budget.
+func budgetStage(ctx context.Context, stage string) (context.Context, context.CancelFunc) {
+	budget, ok := ctx.Value(sessionBudgetKey{}).(*sessionBudget)
+	if !ok {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, budget.limit(stage))
+}
+
+// limit returns the configured limit of a stage, or its default share of the total
+func (b *sessionBudget) limit(stage string) time.Duration {
+	var limit time.Duration
+	switch stage {
+	case BudgetStageOwnerKey:
+		limit = b.config.OwnerKey
+	case BudgetStageDIDResolution:
+		limit = b.config.DIDResolution
+	case BudgetStageSigning:
+		limit = b.config.Signing
+	case BudgetStageUpload:
+		limit = b.config.Upload
+	}
+	if limit <= 0 {
+		limit = time.Duration(float64(b.config.Total) * defaultBudgetShares[stage])
+	}
+	return limit
+}
@@ -0,0 +1,330 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"database/sql"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fido-device-onboard/go-fdo"
+	"github.com/fido-device-onboard/go-fdo/cbor"
+	"github.com/fido-device-onboard/go-fdo/custom"
+	"github.com/fido-device-onboard/go-fdo/protocol"
+)
+
+// SessionRecorder keeps the inputs of the voucher pipeline (device info and the
+// voucher as DI created it) when voucher_management.record_sessions is set, so
+// "voucher replay" can rerun signover logic against real historical sessions
+type SessionRecorder struct {
+	config *VoucherConfig
+	db     *StationDB
+	store  *VoucherStore
+}
+
+// SessionRecord is one recorded pipeline input
+type SessionRecord struct {
+	GUID       string
+	Serial     string
+	Model      string
+	Voucher    []byte // CBOR voucher before signover
+	Hash       string // Content hash of Voucher; empty for records older than the voucher store
+	RecordedAt time.Time
+}
+
+// NewSessionRecorder creates a new session recorder
+func NewSessionRecorder(config *VoucherConfig, db *StationDB) *SessionRecorder {
+	return &SessionRecorder{config: config, db: db, store: NewVoucherStore(db)}
+}
+
+// Initialize creates the session_records table if it doesn't exist
+func (s *SessionRecorder) Initialize(ctx context.Context) error {
+	_, err := s.db.db.ExecContext(ctx, `
+	CREATE TABLE IF NOT EXISTS session_records (
+		guid TEXT PRIMARY KEY,
+		serial TEXT NOT NULL,
+		model TEXT NOT NULL,
+		voucher BLOB NOT NULL,
+		recorded_at INTEGER NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create session_records table: %w", err)
+	}
+	return s.db.addColumnIfMissing(ctx, "session_records", "voucher_hash", "TEXT")
+}
+
+// Record stores the pipeline inputs of a session; failures are logged but don't fail DI
+func (s *SessionRecorder) Record(ctx context.Context, serial, model, guid string, ov *fdo.Voucher) {
+	if s == nil || !s.config.RecordSessions {
+		return
+	}
+	data, err := cbor.Marshal(ov)
+	if err != nil {
+		fmt.Printf("⚠️  Failed to record session %s: %v\n", guid, err)
+		return
+	}
+	if _, err := s.store.write(ctx, "session_records", guid, data, func(tx *sql.Tx, hash string) error {
+		_, err := tx.ExecContext(ctx, `
+		INSERT OR REPLACE INTO session_records (guid, serial, model, voucher, voucher_hash, recorded_at) VALUES (?, ?, ?, x'', ?, ?)`,
+			guid, serial, model, hash, time.Now().Unix())
+		return err
+	}); err != nil {
+		fmt.Printf("⚠️  Failed to record session %s: %v\n", guid, err)
+	}
+}
+
+// Load returns the recorded sessions for the given GUIDs, or the most recent
+// limit sessions if no GUIDs are given, oldest first
+func (s *SessionRecorder) Load(ctx context.Context, guids []string, limit int) ([]SessionRecord, error) {
+	query := `SELECT r.guid, r.serial, r.model, COALESCE(r.voucher_hash, ''), COALESCE(b.voucher, r.voucher), r.recorded_at
+		FROM session_records r LEFT JOIN voucher_blobs b ON b.hash = r.voucher_hash`
+	var args []any
+	if len(guids) > 0 {
+		query += ` WHERE r.guid IN (?` + strings.Repeat(`, ?`, len(guids)-1) + `)`
+		for _, guid := range guids {
+			args = append(args, guid)
+		}
+	} else {
+		query = `SELECT * FROM (` + query + ` ORDER BY recorded_at DESC LIMIT ?)`
+		args = append(args, limit)
+	}
+	rows, err := s.db.db.QueryContext(ctx, query+` ORDER BY recorded_at`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session records: %w", err)
+	}
+	defer rows.Close()
+
+	var records []SessionRecord
+	for rows.Next() {
+		var r SessionRecord
+		var recordedAt int64
+		if err := rows.Scan(&r.GUID, &r.Serial, &r.Model, &r.Hash, &r.Voucher, &recordedAt); err != nil {
+			return nil, fmt.Errorf("failed to read session records: %w", err)
+		}
+		if err := checkVoucherHash(r.Hash, r.Voucher); err != nil {
+			return nil, fmt.Errorf("session record %s: %w", r.GUID, err)
+		}
+		r.RecordedAt = time.Unix(recordedAt, 0)
+		records = append(records, r)
+	}
+	return records, rows.Err()
+}
+
+// replaySession stands in for the go-fdo session state during a replay: it
+// returns the recorded device info and the test signing key
+type replaySession struct {
+	info custom.DeviceMfgInfo
+	key  crypto.Signer
+}
+
+func (s *replaySession) DeviceSelfInfo(context.Context) (*custom.DeviceMfgInfo, error) {
+	return &s.info, nil
+}
+
+func (s *replaySession) ManufacturerKey(context.Context, protocol.KeyType, int) (crypto.Signer, []*x509.Certificate, error) {
+	return s.key, nil, nil
+}
+
+// runVoucherReplay implements "voucher replay": it reruns BeforeVoucherPersist
+// for recorded sessions with the current config and a test signing key. Uploads,
+// disk saves, quotas, batches and audit records are skipped, so it is safe to
+// run against a copy of a production station database.
+func runVoucherReplay(args []string) error {
+	fs := flag.NewFlagSet("voucher replay", flag.ContinueOnError)
+	keyFile := fs.String("key", "", "PEM private key to sign with (default: a fresh key of the voucher's manufacturer key type)")
+	outDir := fs.String("out", "", "Write each replayed voucher to <dir>/<guid>.fdoov")
+	limit := fs.Int("limit", 20, "Number of most recent sessions to replay when no GUIDs are given")
+	at := fs.String("at", "", "Freeze the clock at an RFC 3339 time, or at each session's recording time with \"recorded\"")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	var clock *FrozenClock
+	switch *at {
+	case "":
+	case "recorded":
+		clock = NewFrozenClock(time.Time{})
+	default:
+		t, err := time.Parse(time.RFC3339, *at)
+		if err != nil {
+			return fmt.Errorf("invalid -at time: %w", err)
+		}
+		clock = NewFrozenClock(t)
+	}
+	if clock != nil {
+		stationClock = clock
+		defer func() { stationClock = systemClock{} }()
+	}
+
+	stationDB, err := OpenStationDBReadOnly(stationDBPath(config))
+	if err != nil {
+		return err
+	}
+	defer stationDB.Close()
+
+	ctx := context.Background()
+	records, err := NewSessionRecorder(&config.VoucherManagement, stationDB).Load(ctx, fs.Args(), *limit)
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return fmt.Errorf("no recorded sessions found (enable voucher_management.record_sessions)")
+	}
+	if *outDir != "" {
+		if err := os.MkdirAll(*outDir, 0o755); err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
+	}
+
+	// Replay the signover logic only; nothing leaves the machine
+	replayConfig := config.VoucherManagement
+	replayConfig.VoucherUpload.Enabled = false
+	replayConfig.SaveToDisk.Directory = ""
+	replayConfig.SaveToDisk.Destinations = nil
+	replayConfig.VoucherSigning.Mode = "internal"
+	replayConfig.VoucherSigning.FailureDirectory = filepath.Join(os.TempDir(), "fdo-replay-extend-failures")
+	replayConfig.RecordSessions = false
+
+	// Owner key and extra data commands served by plugins need them running
+	pluginCtx, stopPlugins := context.WithCancel(ctx)
+	defer stopPlugins()
+	commandPlugins = NewCommandPlugins(&config.ExternalCommands)
+	commandPlugins.Start(pluginCtx)
+	if err := configureExtensions(config); err != nil {
+		return err
+	}
+
+	hashPolicy, err := NewVoucherHashPolicy(&replayConfig)
+	if err != nil {
+		return err
+	}
+	callbacks := NewVoucherCallbackService(
+		&replayConfig,
+		NewOwnerKeyService(NewExternalCommandExecutor(CommandOwnerSignover, replayConfig.OwnerSignover.ExternalCommand, replayConfig.OwnerSignover.Timeout), nil, &replayConfig.DIDCache, &config.Rollouts, nil),
+		NewVoucherSigningService(&replayConfig.VoucherSigning, nil, config.Station.StationID),
+		nil, // upload disabled
+		NewVoucherDiskService(&replayConfig, nil),
+		NewOVEExtraDataService(&replayConfig.OVEExtraData,
+			NewExternalCommandExecutor(CommandOVEExtraData, replayConfig.OVEExtraData.ExternalCommand, replayConfig.OVEExtraData.Timeout),
+			currentBuildInfo(config, "")),
+		nil, // no quotas
+		nil, // no shift windows
+		nil, // no audit records
+		nil, // no batches
+		hashPolicy,
+		nil, // no revocations
+		nil, // recording disabled
+		nil, // outcomes not counted
+		nil, // vouchers not kept for transfer
+		nil, // signover targets not tracked
+		nil, // no claim URLs
+		nil, // no overrides
+		nil, // owner keys need no proof
+		nil, // no dry runs
+		nil, // no pipeline stages
+		nil,
+	)
+
+	failed := 0
+	for _, record := range records {
+		fmt.Printf("▶️  %s (GUID %s, model %s, recorded %s)\n", record.Serial, record.GUID, record.Model, record.RecordedAt.Format(time.RFC3339))
+		if *at == "recorded" {
+			clock.Set(record.RecordedAt)
+		}
+		if err := replayRecord(ctx, callbacks, record, *keyFile, *outDir); err != nil {
+			fmt.Printf("   ❌ %v\n", err)
+			failed++
+		}
+	}
+
+	fmt.Printf("Replayed %d sessions: %d ok, %d failed\n", len(records), len(records)-failed, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d sessions failed", failed)
+	}
+	return nil
+}
+
+// replayRecord runs the pipeline for one recorded session
+func replayRecord(ctx context.Context, callbacks *VoucherCallbackService, record SessionRecord, keyFile, outDir string) error {
+	var ov fdo.Voucher
+	if err := cbor.Unmarshal(record.Voucher, &ov); err != nil {
+		return fmt.Errorf("recorded voucher does not decode: %w", err)
+	}
+
+	// The test key replaces the production manufacturer key as the voucher's owner
+	key, err := replaySigner(keyFile, &ov.Header.Val.ManufacturerKey)
+	if err != nil {
+		return err
+	}
+	encoding := ov.Header.Val.ManufacturerKey.Encoding
+	if encoding == protocol.X5ChainKeyEnc {
+		encoding = protocol.X509KeyEnc
+	}
+	mfgKey, err := encodePublicKey(ov.Header.Val.ManufacturerKey.Type, encoding, key.Public(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to encode test key: %w", err)
+	}
+	ov.Header.Val.ManufacturerKey = *mfgKey
+
+	session := &replaySession{
+		info: custom.DeviceMfgInfo{SerialNumber: record.Serial, DeviceInfo: record.Model},
+		key:  key,
+	}
+	persist, err := callbacks.BeforeVoucherPersist(ctx, session, &ov)
+	if err != nil {
+		return err
+	}
+
+	owner := "none"
+	if len(ov.Entries) > 0 {
+		entry := ov.Entries[len(ov.Entries)-1].Payload.Val.PublicKey
+		if pub, err := entry.Public(); err == nil {
+			owner = fmt.Sprintf("%s (%v)", ownerKeySHA256(pub), entry.Encoding)
+		}
+	}
+	fmt.Printf("   ✅ persist=%v, %d entries, next owner %s\n", persist, len(ov.Entries), owner)
+
+	if outDir != "" {
+		text, err := formatVoucherFile(&ov)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(outDir, record.GUID+".fdoov"), []byte(text), 0o644); err != nil {
+			return fmt.Errorf("failed to write replayed voucher: %w", err)
+		}
+	}
+	return nil
+}
+
+// replaySigner loads the test key, or generates one matching the voucher's manufacturer key type
+func replaySigner(keyFile string, mfgKey *protocol.PublicKey) (crypto.Signer, error) {
+	if keyFile != "" {
+		return debugExtendSigner(keyFile)
+	}
+	switch mfgKey.Type {
+	case protocol.Secp256r1KeyType:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case protocol.Secp384r1KeyType:
+		return ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	default:
+		bits := 3072
+		if pub, err := protocolPublicKeyToCrypto(mfgKey); err == nil {
+			if rsaPub, ok := pub.(*rsa.PublicKey); ok {
+				bits = rsaPub.Size() * 8
+			}
+		}
+		return rsa.GenerateKey(rand.Reader, bits)
+	}
+}
@@ -0,0 +1,118 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// sessionTempPrefix starts the name of every session temp dir, so crash
+// recovery only removes what the station created
+const sessionTempPrefix = "session-"
+
+// stationTempRoot holds the session temp dirs (voucher_management.temp_directory)
+var stationTempRoot = filepath.Join(os.TempDir(), "fdo-station")
+
+type sessionTempDirKey struct{}
+
+// initSessionTempDirs sets the temp root and removes the session temp dirs a
+// crashed run left behind. It runs before DI is served, so no session owns them.
+func initSessionTempDirs(root string) error {
+	if root != "" {
+		stationTempRoot = root
+	}
+	if err := os.MkdirAll(stationTempRoot, 0o700); err != nil {
+		return fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	entries, err := os.ReadDir(stationTempRoot)
+	if err != nil {
+		return fmt.Errorf("failed to read temp directory: %w", err)
+	}
+	removed := 0
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), sessionTempPrefix) {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(stationTempRoot, entry.Name())); err != nil {
+			return fmt.Errorf("failed to remove stale session temp dir: %w", err)
+		}
+		removed++
+	}
+	if removed > 0 {
+		fmt.Printf("🧹 Removed %d session temp dirs left by an earlier run from %s\n", removed, stationTempRoot)
+	}
+	return nil
+}
+
+// startSessionTempDir gives a DI session a temp dir of its own, carried in its
+// context. The returned func removes it with everything in it, so call it
+// whether the session succeeds or fails. If the dir can't be created, files
+// go to the temp root and are removed on the next startup.
+func startSessionTempDir(ctx context.Context, guid string) (context.Context, func()) {
+	if err := os.MkdirAll(stationTempRoot, 0o700); err != nil {
+		fmt.Printf("⚠️  Failed to create temp directory: %v\n", err)
+		return ctx, func() {}
+	}
+	dir, err := os.MkdirTemp(stationTempRoot, sessionTempPrefix+guid+"-*")
+	if err != nil {
+		fmt.Printf("⚠️  Failed to create session temp dir for %s: %v\n", guid, err)
+		return ctx, func() {}
+	}
+	return context.WithValue(ctx, sessionTempDirKey{}, dir), func() {
+		if err := os.RemoveAll(dir); err != nil {
+			fmt.Printf("⚠️  Failed to remove session temp dir %s: %v\n", dir, err)
+		}
+	}
+}
+
+// sessionTempDir returns the temp dir of the session in ctx, or the temp root
+// outside a session
+func sessionTempDir(ctx context.Context) (string, error) {
+	if dir, ok := ctx.Value(sessionTempDirKey{}).(string); ok {
+		return dir, nil
+	}
+	if err := os.MkdirAll(stationTempRoot, 0o700); err != nil {
+		return "", fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	return stationTempRoot, nil
+}
+
+// writeViaSessionTemp writes a file in the session temp dir and renames it to
+// path. If the rename fails, e.g. because the temp dir is on another
+// filesystem, path is written directly.
+func writeViaSessionTemp(ctx context.Context, path string, data []byte, perm os.FileMode) error {
+	tmp, err := createSessionTemp(ctx, "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return os.WriteFile(path, data, perm)
+	}
+	return nil
+}
+
+// createSessionTemp creates a temp file in the session's temp dir
+func createSessionTemp(ctx context.Context, pattern string) (*os.File, error) {
+	dir, err := sessionTempDir(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return os.CreateTemp(dir, pattern)
+}
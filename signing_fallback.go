@@ -0,0 +1,255 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/fido-device-onboard/go-fdo"
+	"github.com/fido-device-onboard/go-fdo/protocol"
+)
+
+// When the HSM is unreachable, vouchers of devices whose signover policy sets
+// allow_fallback_signer are signed with a software copy of the manufacturer
+// key instead, so a brief HSM outage doesn't stop the line for low-assurance
+// SKUs. The copy is kept in a passphrase-encrypted file written by
+// "signing encrypt-fallback-key" and is decrypted once at startup. Every
+// fallback signature is audited and raised as a critical notification.
+
+// Fallback key file format
+const (
+	fallbackKeyPEMType           = "FDO ENCRYPTED PRIVATE KEY"
+	fallbackKeyKDF               = "pbkdf2-sha256"
+	fallbackKeyIterations        = 600000
+	defaultFallbackPassphraseEnv = "FDO_SIGNING_FALLBACK_PASSPHRASE"
+)
+
+// ErrFallbackSigner marks a voucher the fallback key could not sign
+var ErrFallbackSigner = errors.New("fallback signer unavailable")
+
+// passphrase reads the key file passphrase from its environment variable
+func (c *FallbackSignerConfig) passphrase() (string, error) {
+	name := c.PassphraseEnv
+	if name == "" {
+		name = defaultFallbackPassphraseEnv
+	}
+	passphrase := os.Getenv(name)
+	if passphrase == "" {
+		return "", fmt.Errorf("fallback signing key passphrase not set (environment variable %s)", name)
+	}
+	return passphrase, nil
+}
+
+// loadFallbackSigner decrypts the fallback key, or returns nil when none is
+// configured. With a manufacturer public key file, the fallback key must be
+// that key, since only the voucher's current owner can sign it over.
+func loadFallbackSigner(config *VoucherSigningConfig) (crypto.Signer, error) {
+	if !config.Fallback.Enabled {
+		return nil, nil
+	}
+	passphrase, err := config.Fallback.passphrase()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(config.Fallback.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fallback signing key: %w", err)
+	}
+	signer, err := decryptFallbackKey(data, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt fallback signing key %s: %w", config.Fallback.KeyFile, err)
+	}
+
+	if config.ManufacturerPublicKeyFile != "" {
+		mfgKey, err := LoadManufacturerPublicKey(config.ManufacturerPublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkFallbackKey(signer, &mfgKey); err != nil {
+			return nil, err
+		}
+	}
+	fmt.Printf("🔑 Fallback signing key loaded (%s %s), used only when the HSM is unreachable\n",
+		describeKey(signer.Public()), ownerKeySHA256(signer.Public()))
+	return signer, nil
+}
+
+// checkFallbackKey verifies that the fallback key is the manufacturer key a voucher names
+func checkFallbackKey(signer crypto.Signer, mfgKey *protocol.PublicKey) error {
+	expected, err := protocolPublicKeyToCrypto(mfgKey)
+	if err != nil {
+		return fmt.Errorf("failed to decode manufacturer key: %w", err)
+	}
+	if chain, ok := expected.([]*x509.Certificate); ok && len(chain) > 0 {
+		expected = chain[0].PublicKey
+	}
+	if k, ok := signer.Public().(interface{ Equal(crypto.PublicKey) bool }); !ok || !k.Equal(expected) {
+		return fmt.Errorf("%w: fallback signing key is not the manufacturer key", ErrFallbackSigner)
+	}
+	return nil
+}
+
+// decryptFallbackKey decrypts a fallback key file: PKCS #8 sealed with
+// AES-256-GCM under a PBKDF2-SHA256 key derived from the passphrase
+func decryptFallbackKey(data []byte, passphrase string) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != fallbackKeyPEMType {
+		return nil, fmt.Errorf("not a %s PEM block", fallbackKeyPEMType)
+	}
+	if kdf := block.Headers["KDF"]; kdf != fallbackKeyKDF {
+		return nil, fmt.Errorf("unsupported KDF %q", kdf)
+	}
+	iterations, err := strconv.Atoi(block.Headers["Iterations"])
+	if err != nil || iterations < 1 {
+		return nil, fmt.Errorf("invalid Iterations header")
+	}
+	salt, err := hex.DecodeString(block.Headers["Salt"])
+	if err != nil || len(salt) == 0 {
+		return nil, fmt.Errorf("invalid Salt header")
+	}
+	nonce, err := hex.DecodeString(block.Headers["Nonce"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid Nonce header")
+	}
+
+	aead, err := fallbackKeyCipher(passphrase, salt, iterations)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("invalid Nonce header")
+	}
+	der, err := aead.Open(nil, nonce, block.Bytes, nil)
+	if err != nil {
+		return nil, fmt.Errorf("wrong passphrase or corrupted key file")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type: %T", key)
+	}
+	return signer, nil
+}
+
+// encryptFallbackKey seals a private key in the fallback key file format
+func encryptFallbackKey(signer crypto.Signer, passphrase string) ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(signer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode private key: %w", err)
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	aead, err := fallbackKeyCipher(passphrase, salt, fallbackKeyIterations)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{
+		Type: fallbackKeyPEMType,
+		Headers: map[string]string{
+			"KDF":        fallbackKeyKDF,
+			"Iterations": strconv.Itoa(fallbackKeyIterations),
+			"Salt":       hex.EncodeToString(salt),
+			"Nonce":      hex.EncodeToString(nonce),
+		},
+		Bytes: aead.Seal(nil, nonce, der, nil),
+	}), nil
+}
+
+// fallbackKeyCipher derives the AES-256-GCM cipher of a fallback key file
+func fallbackKeyCipher(passphrase string, salt []byte, iterations int) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, iterations, 32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+	blockCipher, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(blockCipher)
+}
+
+// SignVoucherFallback signs a voucher over with the fallback key after the
+// HSM failed. The caller decides whether the device may use it.
+func (s *VoucherSigningService) SignVoucherFallback(voucher *fdo.Voucher, nextOwner crypto.PublicKey, serial string, extraData map[int][]byte, hsmErr error) (*fdo.Voucher, error) {
+	if s.fallback == nil {
+		return nil, fmt.Errorf("%w: no fallback signing key configured", ErrFallbackSigner)
+	}
+	if err := checkFallbackKey(s.fallback, &voucher.Header.Val.ManufacturerKey); err != nil {
+		return nil, err
+	}
+	s.notifier.Critical("signing_fallback", fmt.Sprintf("HSM unreachable, signing %s with the fallback software key: %v", serialRules.Serial(serial), hsmErr))
+	extended, err := extendVoucherTo(voucher, s.fallback, nextOwner, extraData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extend voucher with fallback key: %w", err)
+	}
+	return extended, nil
+}
+
+// validateFallbackSigner checks voucher_management.voucher_signing.fallback
+func validateFallbackSigner(config *VoucherSigningConfig) error {
+	if !config.Fallback.Enabled {
+		return nil
+	}
+	if config.Mode != "hsm" && config.Mode != "external" {
+		return fmt.Errorf("voucher_management.voucher_signing.fallback: only for the hsm and external signing modes, not %q", config.Mode)
+	}
+	if config.Fallback.KeyFile == "" {
+		return fmt.Errorf("voucher_management.voucher_signing.fallback.key_file is required")
+	}
+	return nil
+}
+
+// runSigningEncryptFallbackKey implements "signing encrypt-fallback-key
+// -out <file> <key.pem>": it encrypts a PEM private key with the passphrase
+// from the configured environment variable, for voucher_signing.fallback
+func runSigningEncryptFallbackKey(args []string) error {
+	fs := flag.NewFlagSet("signing encrypt-fallback-key", flag.ContinueOnError)
+	outFile := fs.String("out", "", "Write the encrypted key to this file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 || *outFile == "" {
+		return fmt.Errorf("usage: signing encrypt-fallback-key -out <file> <key.pem>")
+	}
+	passphrase, err := config.VoucherManagement.VoucherSigning.Fallback.passphrase()
+	if err != nil {
+		return err
+	}
+	signer, err := loadPrivateKeyFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	data, err := encryptFallbackKey(signer, passphrase)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(*outFile, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write fallback signing key: %w", err)
+	}
+	fmt.Printf("🔑 Wrote encrypted fallback signing key %s (%s %s)\n", *outFile, describeKey(signer.Public()), ownerKeySHA256(signer.Public()))
+	return nil
+}
@@ -0,0 +1,316 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Signover anomaly defaults
+const (
+	defaultAnomalyMinVouchers    = 1
+	defaultAnomalyWebhookTimeout = 10 * time.Second
+)
+
+// SignoverTarget is one owner key (and DID, if it was resolved from one) that
+// a customer/model's vouchers have been extended to
+type SignoverTarget struct {
+	ID        int64     `json:"id"`
+	Customer  string    `json:"customer,omitempty"`
+	Model     string    `json:"model,omitempty"`
+	KeySHA256 string    `json:"key_sha256"`    // Hex SHA-256 of the owner's SubjectPublicKeyInfo
+	DID       string    `json:"did,omitempty"` // DID the key was resolved from
+	Vouchers  int       `json:"vouchers"`      // Vouchers extended to it
+	Anomaly   bool      `json:"anomaly"`       // Its first voucher raised an anomaly
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// SignoverAnomalyEvent is posted to the webhook when an anomaly is raised
+type SignoverAnomalyEvent struct {
+	Station   string    `json:"station"`
+	Customer  string    `json:"customer,omitempty"`
+	Model     string    `json:"model,omitempty"`
+	Serial    string    `json:"serial"`
+	GUID      string    `json:"guid"`
+	KeySHA256 string    `json:"key_sha256"`
+	DID       string    `json:"did,omitempty"`
+	ClaimURL  string    `json:"claim_url,omitempty"`
+	Vouchers  int       `json:"previous_vouchers"` // Vouchers the customer/model was extended to other keys before
+	Time      time.Time `json:"time"`
+}
+
+// SignoverAnomalyDetector keeps the history of owner keys and DIDs each
+// customer/model has been signed over to. Once a customer/model has
+// min_vouchers vouchers, a voucher extended to a key or DID never seen for it
+// before raises an anomaly: a critical notification, an audit event, the
+// station status counter and, if configured, a webhook. Suddenly signing to a
+// new owner is what a routing misconfiguration or a compromised owner key
+// service looks like; the voucher itself is not refused.
+type SignoverAnomalyDetector struct {
+	config    *SignoverAnomalyConfig
+	db        *StationDB
+	auditLog  *AuditLog
+	notifier  *Notifier
+	status    *StationStatus
+	stationID string
+	client    *http.Client
+
+	mu sync.Mutex // Serializes the lookup and insert of a new target
+}
+
+// NewSignoverAnomalyDetector creates the detector, or returns nil if it is disabled
+func NewSignoverAnomalyDetector(config *SignoverAnomalyConfig, db *StationDB, auditLog *AuditLog, notifier *Notifier, status *StationStatus, stationID string) *SignoverAnomalyDetector {
+	if !config.Enabled {
+		return nil
+	}
+	return &SignoverAnomalyDetector{
+		config:    config,
+		db:        db,
+		auditLog:  auditLog,
+		notifier:  notifier,
+		status:    status,
+		stationID: stationID,
+		client:    &http.Client{},
+	}
+}
+
+// Initialize creates the signover_targets table if it doesn't exist
+func (d *SignoverAnomalyDetector) Initialize(ctx context.Context) error {
+	if d == nil {
+		return nil
+	}
+	_, err := d.db.db.ExecContext(ctx, `
+	CREATE TABLE IF NOT EXISTS signover_targets (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		customer TEXT NOT NULL,
+		model TEXT NOT NULL,
+		key_sha256 TEXT NOT NULL,
+		did TEXT NOT NULL,
+		vouchers INTEGER NOT NULL,
+		anomaly INTEGER NOT NULL,
+		first_seen INTEGER NOT NULL,
+		last_seen INTEGER NOT NULL,
+		UNIQUE (customer, model, key_sha256, did)
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create signover_targets table: %w", err)
+	}
+	return nil
+}
+
+// Observe records a voucher extended to ownerKey and raises an anomaly if the
+// key or DID is new for a customer/model that already has a history
+func (d *SignoverAnomalyDetector) Observe(ctx context.Context, serial, guid, customer, model, did, claimURL string, ownerKey crypto.PublicKey) error {
+	if d == nil {
+		return nil
+	}
+	fp := ownerKeySHA256(ownerKey)
+	if fp == "" {
+		return nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := stationClock.Now().Unix()
+	var id int64
+	err := d.db.db.QueryRowContext(ctx, `
+	SELECT id FROM signover_targets WHERE customer = ? AND model = ? AND key_sha256 = ? AND did = ?`,
+		customer, model, fp, did).Scan(&id)
+	if err == nil {
+		if _, err := d.db.db.ExecContext(ctx, `
+		UPDATE signover_targets SET vouchers = vouchers + 1, last_seen = ? WHERE id = ?`, now, id); err != nil {
+			return fmt.Errorf("failed to update signover target: %w", err)
+		}
+		return nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("failed to look up signover target: %w", err)
+	}
+
+	var previous int
+	if err := d.db.db.QueryRowContext(ctx, `
+	SELECT COALESCE(SUM(vouchers), 0) FROM signover_targets WHERE customer = ? AND model = ?`,
+		customer, model).Scan(&previous); err != nil {
+		return fmt.Errorf("failed to read signover history: %w", err)
+	}
+	anomaly := previous >= d.minVouchers()
+	if _, err := d.db.db.ExecContext(ctx, `
+	INSERT INTO signover_targets (customer, model, key_sha256, did, vouchers, anomaly, first_seen, last_seen)
+	VALUES (?, ?, ?, ?, 1, ?, ?, ?)`, customer, model, fp, did, anomaly, now, now); err != nil {
+		return fmt.Errorf("failed to record signover target: %w", err)
+	}
+	if !anomaly {
+		fmt.Printf("📒 Learned signover target %s for customer=%q model=%q\n", fp, customer, model)
+		return nil
+	}
+
+	d.raise(ctx, SignoverAnomalyEvent{
+		Station:   d.stationID,
+		Customer:  customer,
+		Model:     model,
+		Serial:    serial,
+		GUID:      guid,
+		KeySHA256: fp,
+		DID:       did,
+		ClaimURL:  claimURL,
+		Vouchers:  previous,
+		Time:      stationClock.Now().UTC(),
+	})
+	return nil
+}
+
+// raise reports an anomaly through every channel. The webhook is posted in
+// the background so a slow receiver doesn't hold up the device.
+func (d *SignoverAnomalyDetector) raise(ctx context.Context, event SignoverAnomalyEvent) {
+	target := event.KeySHA256
+	if event.DID != "" {
+		target = fmt.Sprintf("%s (%s)", event.DID, event.KeySHA256)
+	}
+	message := fmt.Sprintf("customer=%q model=%q signed over to never-before-seen owner %s after %d vouchers to other owners (serial %s)",
+		event.Customer, event.Model, target, event.Vouchers, serialRules.Serial(event.Serial))
+
+	d.notifier.Critical("signover_anomaly", message)
+	d.status.RecordSignoverAnomaly()
+	d.auditLog.Record(ctx, AuditEvent{
+		Event:    "signover_new_owner_key",
+		Serial:   event.Serial,
+		GUID:     event.GUID,
+		Customer: event.Customer,
+		Model:    event.Model,
+		Detail:   message,
+	})
+
+	if d.config.WebhookURL == "" {
+		return
+	}
+	go withConfigRead(func() {
+		postCtx, cancel := context.WithTimeout(context.Background(), d.webhookTimeout())
+		defer cancel()
+		if err := d.post(postCtx, event); err != nil {
+			fmt.Printf("⚠️  Signover anomaly webhook failed: %v\n", err)
+		}
+	})
+}
+
+// post sends the event as JSON to the webhook
+func (d *SignoverAnomalyDetector) post(ctx context.Context, event SignoverAnomalyEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.config.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range d.config.WebhookHeaders {
+		req.Header.Set(name, value)
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s returned HTTP %d", d.config.WebhookURL, resp.StatusCode)
+	}
+	return nil
+}
+
+var signoverTargetListSpec = listSpec{
+	Key:         "id",
+	Sorts:       map[string]string{"last_seen": "last_seen", "first_seen": "first_seen", "vouchers": "vouchers", "id": "id"},
+	DefaultSort: "-last_seen",
+	Filters:     map[string]string{"customer": "customer", "model": "model", "key_sha256": "key_sha256", "did": "did"},
+}
+
+// Page returns one page of signover targets
+func (d *SignoverAnomalyDetector) Page(ctx context.Context, q *listQuery) ([]SignoverTarget, string, error) {
+	clause, args := q.sql()
+	rows, err := d.db.db.QueryContext(ctx, `
+	SELECT id, customer, model, key_sha256, did, vouchers, anomaly, first_seen, last_seen
+	FROM signover_targets`+clause, args...)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to query signover targets: %w", err)
+	}
+	defer rows.Close()
+
+	targets := []SignoverTarget{}
+	for rows.Next() {
+		var target SignoverTarget
+		var firstSeen, lastSeen int64
+		if err := rows.Scan(&target.ID, &target.Customer, &target.Model, &target.KeySHA256, &target.DID,
+			&target.Vouchers, &target.Anomaly, &firstSeen, &lastSeen); err != nil {
+			return nil, "", fmt.Errorf("failed to read signover target: %w", err)
+		}
+		target.FirstSeen = time.Unix(firstSeen, 0)
+		target.LastSeen = time.Unix(lastSeen, 0)
+		targets = append(targets, target)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+	targets, next := listPage(q, targets, func(target SignoverTarget, column string) any {
+		switch column {
+		case "last_seen":
+			return target.LastSeen.Unix()
+		case "first_seen":
+			return target.FirstSeen.Unix()
+		case "vouchers":
+			return target.Vouchers
+		default:
+			return target.ID
+		}
+	})
+	return targets, next, nil
+}
+
+// ListHandler serves GET /api/signover/targets with the list parameters of signoverTargetListSpec
+func (d *SignoverAnomalyDetector) ListHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if d == nil {
+			writeJSONError(w, http.StatusNotFound, "signover anomaly detection is disabled")
+			return
+		}
+		q, err := parseListQuery(r, &signoverTargetListSpec)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		targets, next, err := d.Page(r.Context(), q)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSONList(w, r, targets, next)
+	})
+}
+
+func (d *SignoverAnomalyDetector) minVouchers() int {
+	if d.config.MinVouchers > 0 {
+		return d.config.MinVouchers
+	}
+	return defaultAnomalyMinVouchers
+}
+
+func (d *SignoverAnomalyDetector) webhookTimeout() time.Duration {
+	if d.config.WebhookTimeout > 0 {
+		return d.config.WebhookTimeout
+	}
+	return defaultAnomalyWebhookTimeout
+}
@@ -0,0 +1,188 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ErrSignoverPolicy marks a device refused by a signover policy rule
+var ErrSignoverPolicy = errors.New("refused by signover policy")
+
+// signoverPolicyVariables are the variables a policy condition can use
+var signoverPolicyVariables = []string{
+	"serial",              // As the device reported it
+	"model",               // As the device reported it
+	"guid",                // Voucher GUID, hex
+	"lot",                 // Lot number of the open batch
+	"batch",               // ID of the open batch
+	"customer",            // Named by owner_signover
+	"upload_auth_profile", // Named by owner_signover
+	"recipient_url",       // voucherRecipientURL of the owner's DID, if it has one
+	"owner_key",           // Type of the owner key: ec256, ec384, rsa2048, rsa3072 (empty = no owner)
+}
+
+// policyKeyTypes are the owner key types require_key can name
+var policyKeyTypes = []string{"ec256", "ec384", "rsa2048", "rsa3072"}
+
+// SignoverPolicyRule decides the signover of the devices its condition matches.
+// Every matching rule applies, in order: a later rule's owner, customer or
+// upload auth profile replaces an earlier one's, and the first matching reject
+// is the reason the device is refused. Conditions see the device and what
+// owner_signover decided, not what earlier rules set.
+type SignoverPolicyRule struct {
+	Name              string   `yaml:"name"`
+	When              string   `yaml:"when"`                // CEL-style condition (empty = every device)
+	Owner             string   `yaml:"owner"`               // DID, owner key URL or PEM public key to sign the device over to
+	KeyEncoding       string   `yaml:"key_encoding"`        // Owner key encoding for that owner: "x509" | "x5chain" | "cosekey"
+	Customer          string   `yaml:"customer"`            // Customer ID, for quotas, schedules and destinations
+	UploadAuthProfile string   `yaml:"upload_auth_profile"` // Upload auth profile for the voucher
+	RequireKey        []string `yaml:"require_key"`         // The owner key must be one of these types
+	Reject            string   `yaml:"reject"`              // Refuse the device, with this reason
+
+	// Sign with voucher_signing.fallback when the HSM is unreachable; for low-assurance SKUs only
+	AllowFallbackSigner bool `yaml:"allow_fallback_signer"`
+}
+
+// SignoverDecision is what the matching policy rules decided for a device
+type SignoverDecision struct {
+	Rules             []string // Names of the matching rules
+	Owner             string
+	KeyEncoding       string
+	Customer          string
+	UploadAuthProfile string
+	RequireKey        []string
+	Reject            string // Reason of the first matching reject, with its rule
+
+	AllowFallbackSigner bool // A matching rule allows the fallback signing key
+}
+
+// label names a rule in errors and logs
+func (r *SignoverPolicyRule) label(i int) string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return fmt.Sprintf("policies[%d]", i)
+}
+
+// evaluateSignoverPolicy applies the policy rules to a device. Whether they
+// refuse it is up to Check, once the owner key is known.
+func evaluateSignoverPolicy(rules []SignoverPolicyRule, vars map[string]string) (*SignoverDecision, error) {
+	decision := &SignoverDecision{}
+	for i := range rules {
+		rule := &rules[i]
+		condition, err := compilePolicyCondition(rule.When, signoverPolicyVariables)
+		if err != nil {
+			return nil, fmt.Errorf("policy %s: %w", rule.label(i), err)
+		}
+		if !condition.eval(vars).(bool) {
+			continue
+		}
+		decision.Rules = append(decision.Rules, rule.label(i))
+		if rule.Reject != "" && decision.Reject == "" {
+			decision.Reject = rule.label(i) + ": " + rule.Reject
+		}
+		if rule.Owner != "" {
+			decision.Owner, decision.KeyEncoding = rule.Owner, rule.KeyEncoding
+		}
+		if rule.Customer != "" {
+			decision.Customer = rule.Customer
+		}
+		if rule.UploadAuthProfile != "" {
+			decision.UploadAuthProfile = rule.UploadAuthProfile
+		}
+		if len(rule.RequireKey) > 0 {
+			decision.RequireKey = rule.RequireKey
+		}
+		if rule.AllowFallbackSigner {
+			decision.AllowFallbackSigner = true
+		}
+	}
+	return decision, nil
+}
+
+// Check returns an ErrSignoverPolicy error if a rule rejects the device or the
+// final owner key is not of a required type
+func (d *SignoverDecision) Check(pub crypto.PublicKey) error {
+	if d.Reject != "" {
+		return fmt.Errorf("%w %s", ErrSignoverPolicy, d.Reject)
+	}
+	if len(d.RequireKey) == 0 {
+		return nil
+	}
+	keyType := ownerKeyType(pub)
+	if keyType == "" {
+		return fmt.Errorf("%w: an owner key of type %s is required, but the device has no owner", ErrSignoverPolicy, strings.Join(d.RequireKey, " or "))
+	}
+	if !slices.Contains(d.RequireKey, keyType) {
+		return fmt.Errorf("%w: owner key is %s, policy requires %s", ErrSignoverPolicy, keyType, strings.Join(d.RequireKey, " or "))
+	}
+	return nil
+}
+
+// ownerKeyType names the type of an owner key as the config does ("ec256",
+// "rsa3072", ...), or returns "" for no key
+func ownerKeyType(pub crypto.PublicKey) string {
+	switch key := pub.(type) {
+	case *ecdsa.PublicKey:
+		if key.Curve != elliptic.P256() && key.Curve != elliptic.P384() {
+			return strings.ToLower(key.Curve.Params().Name) // secp256k1 from did:key
+		}
+		return fmt.Sprintf("ec%d", key.Curve.Params().BitSize)
+	case ed25519.PublicKey:
+		return "ed25519"
+	case *rsa.PublicKey:
+		return fmt.Sprintf("rsa%d", key.N.BitLen())
+	case []*x509.Certificate:
+		if len(key) > 0 {
+			return ownerKeyType(key[0].PublicKey)
+		}
+	}
+	return ""
+}
+
+// validateSignoverPolicies compiles every policy condition and checks the
+// rules' settings, so a typo stops the station rather than a device
+func validateSignoverPolicies(config *VoucherConfig) error {
+	for i := range config.Policies {
+		rule := &config.Policies[i]
+		field := "voucher_management.policies." + rule.label(i)
+		if _, err := compilePolicyCondition(rule.When, signoverPolicyVariables); err != nil {
+			return fmt.Errorf("%s.when: %w", field, err)
+		}
+		if isOwnerKeyURL(rule.Owner) {
+			if _, err := ownerKeyURLDomain(rule.Owner); err != nil {
+				return fmt.Errorf("%s.owner: %w", field, err)
+			}
+		} else if rule.Owner != "" && !strings.HasPrefix(rule.Owner, "did:") {
+			if _, err := parseStaticPublicKey(rule.Owner); err != nil {
+				return fmt.Errorf("%s.owner: not a DID, https:// owner key URL or PEM public key: %w", field, err)
+			}
+		}
+		if err := validateOwnerKeyEncoding(rule.KeyEncoding); err != nil {
+			return fmt.Errorf("%s.key_encoding: %w", field, err)
+		}
+		for _, keyType := range rule.RequireKey {
+			if !slices.Contains(policyKeyTypes, keyType) {
+				return fmt.Errorf("%s.require_key: unsupported key type %q (want %s)", field, keyType, strings.Join(policyKeyTypes, ", "))
+			}
+		}
+		if rule.UploadAuthProfile != "" {
+			if _, ok := config.UploadAuthProfiles[rule.UploadAuthProfile]; !ok {
+				return fmt.Errorf("%s.upload_auth_profile: no upload auth profile %q", field, rule.UploadAuthProfile)
+			}
+		}
+	}
+	return nil
+}
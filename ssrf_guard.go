@@ -0,0 +1,129 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// SSRFGuard blocks outbound resolver connections to loopback, link-local,
+// private and carrier-grade NAT address ranges. DIDs arrive from external callbacks, so without this
+// a crafted did:web could make the station fetch internal URLs.
+type SSRFGuard struct {
+	allowed []netip.Prefix
+}
+
+// NewSSRFGuard creates a guard that permits the given CIDRs even if they fall
+// inside a blocked range. Invalid entries are skipped with a warning, which only
+// makes the guard stricter.
+func NewSSRFGuard(allowCIDRs []string) *SSRFGuard {
+	g := &SSRFGuard{}
+	for _, cidr := range allowCIDRs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			// Accept a bare address as a single-host allowlist entry
+			addr, addrErr := netip.ParseAddr(cidr)
+			if addrErr != nil {
+				fmt.Printf("⚠️  Ignoring invalid SSRF allowlist entry %q: %v\n", cidr, err)
+				continue
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		g.allowed = append(g.allowed, prefix.Masked())
+	}
+	return g
+}
+
+// CheckIP returns an error if the address is in a blocked range and not allowlisted
+func (g *SSRFGuard) CheckIP(ip net.IP) error {
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return fmt.Errorf("invalid IP address %v", ip)
+	}
+	// Treat IPv4-mapped IPv6 (::ffff:10.0.0.1) the same as the IPv4 address
+	addr = addr.Unmap()
+
+	reason := blockedRangeReason(addr)
+	if reason == "" {
+		return nil
+	}
+
+	for _, prefix := range g.allowed {
+		if prefix.Contains(addr) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("outbound request to %s blocked: %s address (add it to did_cache.allow_private_cidrs to permit)", addr, reason)
+}
+
+// CheckHost rejects blocked IP literals up front so we fail before any network activity.
+// Hostnames pass here and are checked against their resolved address at dial time.
+func (g *SSRFGuard) CheckHost(host string) error {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	// Strip brackets from IPv6 literals such as [::1]
+	if len(host) > 1 && host[0] == '[' && host[len(host)-1] == ']' {
+		host = host[1 : len(host)-1]
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return g.CheckIP(ip)
+	}
+	return nil
+}
+
+// Control is a net.Dialer Control hook that checks the address actually being
+// connected to, which also covers DNS rebinding and redirects
+func (g *SSRFGuard) Control(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("invalid dial address %q: %w", address, err)
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return fmt.Errorf("refusing to dial unresolved address %q", address)
+	}
+	return g.CheckIP(ip)
+}
+
+// Dialer returns a net.Dialer that enforces the guard on every connection
+func (g *SSRFGuard) Dialer(timeout time.Duration) *net.Dialer {
+	return &net.Dialer{
+		Timeout: timeout,
+		Control: g.Control,
+	}
+}
+
+// Ranges that netip has no predicate for
+var (
+	thisNetworkRange = netip.MustParsePrefix("0.0.0.0/8")     // RFC 1122; Linux connects 0.x.x.x to the local host
+	sharedRange      = netip.MustParsePrefix("100.64.0.0/10") // RFC 6598 carrier-grade NAT, often internal in cloud networks
+)
+
+// blockedRangeReason returns a description of the blocked range containing addr, or "" if allowed
+func blockedRangeReason(addr netip.Addr) string {
+	switch {
+	case addr.IsLoopback():
+		return "loopback"
+	case addr.IsLinkLocalUnicast(), addr.IsLinkLocalMulticast():
+		return "link-local"
+	case addr.IsPrivate():
+		return "private"
+	case addr.IsUnspecified():
+		return "unspecified"
+	case thisNetworkRange.Contains(addr):
+		return "this-network"
+	case sharedRange.Contains(addr):
+		return "carrier-grade NAT"
+	case addr.IsInterfaceLocalMulticast():
+		return "interface-local"
+	}
+	return ""
+}
@@ -0,0 +1,368 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Databases a standby syncs from the primary: the go-fdo database (manufacturer
+// keys, DI vouchers, DID cache) and the station database (batches, upload
+// destinations and receipts, audit log)
+const (
+	StandbyDBFDO     = "fdo"
+	StandbyDBStation = "station"
+)
+
+// StandbySnapshots serves consistent snapshots of the station's databases to a
+// cold-standby station. Snapshots contain the manufacturer private keys, so
+// they are only served when standby.serve_snapshots is set and behind the
+// admin token.
+type StandbySnapshots struct {
+	paths map[string]string // Standby database name to file path
+}
+
+// NewStandbySnapshots creates the snapshot service, or returns nil if the station doesn't serve a standby
+func NewStandbySnapshots(config *StandbyConfig, cfg *Config) (*StandbySnapshots, error) {
+	if !config.ServeSnapshots {
+		return nil, nil
+	}
+	if cfg.Admin.Token == "" {
+		return nil, fmt.Errorf("standby.serve_snapshots needs admin.token: snapshots contain the manufacturer keys")
+	}
+	if cfg.Database.Password != "" {
+		return nil, fmt.Errorf("standby.serve_snapshots does not support an encrypted database (database.password)")
+	}
+	return &StandbySnapshots{paths: map[string]string{
+		StandbyDBFDO:     cfg.Database.Path,
+		StandbyDBStation: stationDBPath(cfg),
+	}}, nil
+}
+
+// Handler serves GET /api/standby/snapshot/{db}. The ETag changes whenever
+// the database file or its WAL changes, so an idle line costs the standby a 304.
+func (s *StandbySnapshots) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s == nil {
+			writeJSONError(w, http.StatusNotFound, "standby snapshots are disabled")
+			return
+		}
+		path, ok := s.paths[r.PathValue("db")]
+		if !ok {
+			writeJSONError(w, http.StatusNotFound, fmt.Sprintf("unknown database %q", r.PathValue("db")))
+			return
+		}
+		etag, err := standbyETag(path)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		snapshot, err := s.snapshot(r.Context(), path)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		defer func() { _ = os.Remove(snapshot) }()
+		f, err := os.Open(snapshot)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		defer f.Close()
+
+		w.Header().Set("Content-Type", "application/vnd.sqlite3")
+		w.Header().Set("ETag", etag)
+		if info, err := f.Stat(); err == nil {
+			w.Header().Set("Content-Length", fmt.Sprint(info.Size()))
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = io.Copy(w, f)
+	})
+}
+
+// snapshot writes a consistent copy of the database to a temporary file
+// without blocking DI, which keeps writing through its own connection
+func (s *StandbySnapshots) snapshot(ctx context.Context, path string) (string, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".standby-snapshot-*.db")
+	if err != nil {
+		return "", fmt.Errorf("failed to create snapshot file: %w", err)
+	}
+	name := tmp.Name()
+	_ = tmp.Close()
+	_ = os.Remove(name) // VACUUM INTO refuses to overwrite an existing file
+
+	db, err := sql.Open("sqlite3", "file:"+path+"?mode=ro&_pragma=busy_timeout(10000)")
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s for snapshot: %w", path, err)
+	}
+	defer db.Close()
+	if _, err := db.ExecContext(ctx, `VACUUM INTO ?`, name); err != nil {
+		_ = os.Remove(name)
+		return "", fmt.Errorf("failed to snapshot %s: %w", path, err)
+	}
+	return name, nil
+}
+
+// standbyETag identifies the current state of a database from its file and WAL
+func standbyETag(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+	tag := fmt.Sprintf("%x-%x", info.Size(), info.ModTime().UnixNano())
+	if wal, err := os.Stat(path + "-wal"); err == nil {
+		tag += fmt.Sprintf("-%x-%x", wal.Size(), wal.ModTime().UnixNano())
+	}
+	return `"` + tag + `"`, nil
+}
+
+// standbyPromotedPath is the marker that turns a standby into the primary.
+// "standby promote" writes "requested"; once the standby has taken over it
+// records "promoted". The marker survives restarts, so a promoted standby never
+// syncs over its own state again.
+func standbyPromotedPath(cfg *Config) string {
+	return stationDBPath(cfg) + ".promoted"
+}
+
+// runStandby keeps copies of the primary's databases in this station's
+// configured database paths until it is promoted, then runs as the station.
+// It opens neither database while syncing, so the files can be replaced.
+func runStandby(ctx context.Context) error {
+	standby := &config.Standby
+	if _, err := os.Stat(standbyPromotedPath(config)); err != nil && standby.Primary == "" {
+		return fmt.Errorf("standby mode needs standby.primary (the primary's admin API URL)")
+	}
+	syncer := &standbySyncer{
+		config: standby,
+		client: &http.Client{Timeout: 10 * time.Minute},
+		paths: map[string]string{
+			StandbyDBFDO:     config.Database.Path,
+			StandbyDBStation: stationDBPath(config),
+		},
+		etags: map[string]string{},
+	}
+	interval := standby.Interval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+
+	fmt.Printf("🛟 Standby syncing from %s every %s\n", standby.Primary, interval)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	var next time.Time
+	for {
+		if _, err := os.Stat(standbyPromotedPath(config)); err == nil {
+			return promoteStandby(ctx, syncer)
+		}
+		if !time.Now().Before(next) {
+			if err := syncer.sync(ctx); err != nil {
+				fmt.Printf("⚠️  Standby sync failed: %v\n", err)
+			}
+			next = time.Now().Add(interval)
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// promoteStandby makes one last sync attempt, gives the station database a new
+// instance ID and starts the manufacturing station on the synced state. After
+// a restart it starts the station straight away: the primary's state must
+// never replace what the promoted station has built since.
+func promoteStandby(ctx context.Context, syncer *standbySyncer) error {
+	marker := standbyPromotedPath(config)
+	state, err := os.ReadFile(marker)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", marker, err)
+	}
+	if !strings.HasPrefix(string(state), "promoted") {
+		primaryInstance, err := takeOverFromPrimary(ctx, syncer)
+		if err != nil {
+			return err
+		}
+		state := fmt.Sprintf("promoted %s from instance %s\n", time.Now().UTC().Format(time.RFC3339), primaryInstance)
+		if err := os.WriteFile(marker, []byte(state), 0o644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", marker, err)
+		}
+	}
+	config.Manufacturing.FirstTimeInit = false // The keys came from the primary
+	return runManufacturingStation(ctx)
+}
+
+// takeOverFromPrimary makes the last synced state this station's own and
+// returns the instance ID of the primary it took over from
+func takeOverFromPrimary(ctx context.Context, syncer *standbySyncer) (string, error) {
+	fmt.Printf("🛟 Standby promoted; taking over as the station\n")
+	syncCtx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	if err := syncer.sync(syncCtx); err != nil {
+		fmt.Printf("⚠️  Final sync from the primary failed, starting from the last synced state: %v\n", err)
+	}
+	cancel()
+
+	for name, path := range syncer.paths {
+		if _, err := os.Stat(path); err != nil {
+			return "", fmt.Errorf("cannot promote: no %s database was synced from the primary (%s): %w", name, path, err)
+		}
+	}
+
+	stationDB, err := OpenStationDB(stationDBPath(config))
+	if err != nil {
+		return "", err
+	}
+	defer stationDB.Close()
+	primaryInstance, err := stationDB.InstanceID(ctx)
+	if err != nil {
+		return "", err
+	}
+	// The promoted station is a new install that carries on the primary's history
+	if _, err := stationDB.db.ExecContext(ctx, `DELETE FROM station_meta WHERE key = 'instance_id'`); err != nil {
+		return "", fmt.Errorf("failed to reset instance ID: %w", err)
+	}
+	instanceID, err := stationDB.InstanceID(ctx)
+	if err != nil {
+		return "", err
+	}
+	NewAuditLog(stationDB, &config.Station).Record(ctx, AuditEvent{
+		Event:  "standby_promoted",
+		Detail: fmt.Sprintf("instance %s took over from primary instance %s", instanceID, primaryInstance),
+	})
+	return primaryInstance, nil
+}
+
+// runStandbyPromote implements "standby promote": it marks this station
+// promoted, which the running standby picks up within a second
+func runStandbyPromote(args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments %v", args)
+	}
+	marker := standbyPromotedPath(config)
+	if _, err := os.Stat(marker); err == nil {
+		return fmt.Errorf("%s already exists: this station is already promoted or promoting", marker)
+	}
+	if err := os.WriteFile(marker, []byte("requested "+time.Now().UTC().Format(time.RFC3339)+"\n"), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", marker, err)
+	}
+	fmt.Printf("🛟 Promotion requested (%s). The running standby takes over within a second;\n", marker)
+	fmt.Printf("   if it is not running, start it with -standby and it starts as the station.\n")
+	fmt.Printf("   Do not restart the old primary while this station runs.\n")
+	return nil
+}
+
+// standbySyncer downloads database snapshots from the primary
+type standbySyncer struct {
+	config *StandbyConfig
+	client *http.Client
+	paths  map[string]string // Standby database name to local file path
+	etags  map[string]string // ETag of the snapshot each file holds
+}
+
+// sync brings every database up to date with the primary
+func (s *standbySyncer) sync(ctx context.Context) error {
+	var errs []error
+	for _, name := range []string{StandbyDBFDO, StandbyDBStation} {
+		if err := s.fetch(ctx, name); err != nil {
+			errs = append(errs, fmt.Errorf("%s database: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// fetch downloads one snapshot, checks it and moves it into place
+func (s *standbySyncer) fetch(ctx context.Context, name string) error {
+	url := strings.TrimSuffix(s.config.Primary, "/") + "/api/standby/snapshot/" + name
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if s.config.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.config.Token)
+	}
+	path := s.paths[name]
+	if etag := s.etags[name]; etag != "" {
+		if _, err := os.Stat(path); err == nil {
+			req.Header.Set("If-None-Match", etag)
+		}
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified {
+		return nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("primary returned HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".standby-sync-*.db")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := io.Copy(tmp, resp.Body); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to download snapshot: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err := checkStandbySnapshot(ctx, tmp.Name()); err != nil {
+		return err
+	}
+
+	// A WAL left from an earlier copy would be replayed into the new file
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if err := os.Remove(path + suffix); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to remove %s%s: %w", path, suffix, err)
+		}
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+	s.etags[name] = resp.Header.Get("ETag")
+	fmt.Printf("🛟 Synced %s database from the primary\n", name)
+	return nil
+}
+
+// checkStandbySnapshot refuses a snapshot that is not an intact SQLite database
+func checkStandbySnapshot(ctx context.Context, path string) error {
+	db, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return fmt.Errorf("failed to open snapshot: %w", err)
+	}
+	defer db.Close()
+	var result string
+	if err := db.QueryRowContext(ctx, `PRAGMA quick_check`).Scan(&result); err != nil {
+		return fmt.Errorf("snapshot is not a database: %w", err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("snapshot failed integrity check: %s", result)
+	}
+	return nil
+}
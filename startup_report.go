@@ -0,0 +1,256 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// StartupReport is the configuration the station resolved at startup: who
+// vouchers are signed over to, what signs them, where they go, how DIDs are
+// cached, which databases are open and which listeners use TLS. It is logged
+// once and served at GET /api/startup, so a misconfiguration shows before the
+// first device arrives. Config reloads don't change it.
+type StartupReport struct {
+	StartedAt  time.Time         `json:"started_at"`
+	Build      BuildInfo         `json:"build"`
+	Signover   StartupSignover   `json:"signover"`
+	Signing    StartupSigning    `json:"signing"`
+	Upload     StartupUpload     `json:"upload"`
+	SaveToDisk []string          `json:"save_to_disk"` // Directory and named destinations
+	DIDCache   StartupDIDCache   `json:"did_cache"`
+	Database   StartupDatabase   `json:"database"`
+	Listeners  []StartupListener `json:"listeners"`
+	DryRun     bool              `json:"dry_run"`
+	Warnings   []string          `json:"warnings"`
+}
+
+// StartupSignover is the owner vouchers are signed over to
+type StartupSignover struct {
+	Mode        string `json:"mode"`
+	Target      string `json:"target,omitempty"` // Static key type and SHA-256, static DID or owner key command
+	Customer    string `json:"customer,omitempty"`
+	KeyEncoding string `json:"key_encoding,omitempty"`
+	Policies    int    `json:"policies"` // Signover policy rules that can replace the owner
+}
+
+// StartupSigning is the voucher signing backend
+type StartupSigning struct {
+	Mode         string `json:"mode"`
+	OwnerKeyType string `json:"owner_key_type,omitempty"` // internal mode
+	Command      string `json:"command,omitempty"`        // HSM/external mode
+	Fallback     bool   `json:"fallback"`                 // Software key used when the HSM is unreachable
+}
+
+// StartupUpload is where vouchers are delivered
+type StartupUpload struct {
+	Enabled               bool     `json:"enabled"`
+	Mode                  string   `json:"mode,omitempty"`
+	Command               string   `json:"command,omitempty"`
+	URL                   string   `json:"url,omitempty"`
+	AuthProfile           string   `json:"auth_profile,omitempty"`
+	Batch                 bool     `json:"batch"`
+	RequireSignedReceipts bool     `json:"require_signed_receipts"`
+	Destinations          []string `json:"destinations"` // Catalog entries as name=url, disabled ones marked
+}
+
+// StartupDIDCache is how DID documents are resolved and cached
+type StartupDIDCache struct {
+	Enabled           bool     `json:"enabled"`
+	RefreshInterval   string   `json:"refresh_interval"`
+	MaxAge            string   `json:"max_age"`
+	AllowedDomains    []string `json:"allowed_domains"`
+	DeniedDomains     []string `json:"denied_domains"`
+	AllowPrivateCIDRs []string `json:"allow_private_cidrs"`
+}
+
+// StartupDatabase is where station state is kept
+type StartupDatabase struct {
+	Backend     string `json:"backend"`
+	Path        string `json:"path"`         // go-fdo database
+	Encrypted   bool   `json:"encrypted"`    // A database password is set
+	StationPath string `json:"station_path"` // Station bookkeeping database
+}
+
+// StartupListener is one HTTP listener and its TLS state
+type StartupListener struct {
+	Name    string `json:"name"` // "di", "admin" or "di+admin"
+	Addr    string `json:"addr"`
+	TLS     bool   `json:"tls"`
+	MTLS    bool   `json:"mtls"` // Client certificates required
+	Enabled bool   `json:"enabled"`
+}
+
+// NewStartupReport resolves the startup report from the config. Destinations
+// come from the upload destination catalog, nil when uploads aren't over HTTP.
+func NewStartupReport(ctx context.Context, config *Config, build BuildInfo, destinations *UploadDestinationCatalog) *StartupReport {
+	vm := &config.VoucherManagement
+	r := &StartupReport{
+		StartedAt:  stationClock.Now().UTC(),
+		Build:      build,
+		SaveToDisk: []string{},
+		DryRun:     config.DryRun.Enabled,
+		Warnings:   []string{},
+	}
+
+	signover := &vm.OwnerSignover
+	r.Signover = StartupSignover{
+		Mode:        signover.Mode,
+		Customer:    signover.Customer,
+		KeyEncoding: signover.KeyEncoding,
+		Policies:    len(vm.Policies),
+	}
+	switch signover.Mode {
+	case "static":
+		if signover.StaticDID != "" {
+			r.Signover.Target = signover.StaticDID
+		} else if key, err := parseStaticPublicKey(signover.StaticPublicKey); err == nil {
+			r.Signover.Target = ownerKeyType(key) + " sha256:" + ownerKeySHA256(key)
+		}
+	case "dynamic":
+		r.Signover.Target = signover.ExternalCommand
+	case "none":
+		r.warn("owner signover mode is none: vouchers are not extended to an owner")
+	}
+
+	signing := &vm.VoucherSigning
+	r.Signing = StartupSigning{Mode: signing.Mode, Fallback: signing.Fallback.Enabled}
+	if signing.Mode == "internal" {
+		r.Signing.OwnerKeyType = signing.OwnerKeyType
+	} else {
+		r.Signing.Command = signing.ExternalCommand
+	}
+	if signing.Fallback.Enabled {
+		r.warn("fallback signer enabled: policies may allow software signing while the HSM is down")
+	}
+
+	upload := &vm.VoucherUpload
+	r.Upload = StartupUpload{
+		Enabled:               upload.Enabled,
+		Destinations:          []string{},
+		RequireSignedReceipts: upload.RequireSignedReceipts,
+	}
+	if upload.Enabled {
+		r.Upload.Mode = upload.Mode
+		if upload.Mode == "http" {
+			r.Upload.URL, r.Upload.AuthProfile, r.Upload.Batch = upload.URL, upload.AuthProfile, upload.Batch.Enabled
+		} else {
+			r.Upload.Command = upload.ExternalCommand
+		}
+	}
+	list, err := destinations.List(ctx)
+	if err != nil {
+		r.warn(fmt.Sprintf("upload destinations unreadable: %v", err))
+	}
+	for _, d := range list {
+		entry := d.Name + "=" + d.URL
+		if !d.Enabled {
+			entry += " (disabled)"
+		}
+		r.Upload.Destinations = append(r.Upload.Destinations, entry)
+	}
+
+	if vm.SaveToDisk.Directory != "" {
+		r.SaveToDisk = append(r.SaveToDisk, vm.SaveToDisk.Directory)
+	}
+	for _, d := range vm.SaveToDisk.Destinations {
+		target := d.Directory
+		if target == "" {
+			target = d.Command
+		}
+		r.SaveToDisk = append(r.SaveToDisk, d.Name+"="+target)
+	}
+	if !upload.Enabled && len(r.SaveToDisk) == 0 && !vm.PersistToDB {
+		r.warn("vouchers are neither uploaded, saved to disk nor persisted to the database")
+	}
+
+	cache := &vm.DIDCache
+	r.DIDCache = StartupDIDCache{
+		Enabled:           cache.Enabled,
+		RefreshInterval:   cache.RefreshInterval.String(),
+		MaxAge:            cache.MaxAge.String(),
+		AllowedDomains:    append([]string{}, cache.AllowedDomains...),
+		DeniedDomains:     append([]string{}, cache.DeniedDomains...),
+		AllowPrivateCIDRs: append([]string{}, cache.AllowPrivateCIDRs...),
+	}
+
+	r.Database = StartupDatabase{
+		Backend:     "sqlite",
+		Path:        config.Database.Path,
+		Encrypted:   config.Database.Password != "",
+		StationPath: stationDBPath(config),
+	}
+
+	server := &config.Server
+	if server.Admin.Addr == "" {
+		name := "di"
+		if config.Admin.Enabled {
+			name = "di+admin"
+		}
+		r.Listeners = []StartupListener{{Name: name, Addr: server.Addr, TLS: server.UseTLS, Enabled: true}}
+	} else {
+		r.Listeners = []StartupListener{
+			{Name: "di", Addr: server.Addr, TLS: server.UseTLS, Enabled: true},
+			{Name: "admin", Addr: server.Admin.Addr, TLS: server.Admin.UseTLS, MTLS: server.Admin.ClientCAFile != "", Enabled: config.Admin.Enabled},
+		}
+	}
+	if config.Admin.Enabled && config.Admin.Token == "" && len(config.Admin.Users) == 0 {
+		r.warn("admin API enabled without a token")
+	}
+	if r.DryRun {
+		r.warn("dry-run mode: no voucher is kept or delivered")
+	}
+	return r
+}
+
+// warn adds a warning to the report
+func (r *StartupReport) warn(warning string) {
+	r.Warnings = append(r.Warnings, warning)
+}
+
+// Print logs the report: a readable summary and the whole report as one structured log line
+func (r *StartupReport) Print() {
+	fmt.Printf("📋 Startup report\n")
+	target := r.Signover.Target
+	if target == "" {
+		target = "-"
+	}
+	fmt.Printf("   Signover:   %s → %s (%d policies)\n", r.Signover.Mode, target, r.Signover.Policies)
+	signer := r.Signing.OwnerKeyType
+	if signer == "" {
+		signer = r.Signing.Command
+	}
+	fmt.Printf("   Signing:    %s %s (fallback %v)\n", r.Signing.Mode, signer, r.Signing.Fallback)
+	if r.Upload.Enabled {
+		fmt.Printf("   Upload:     %s %s%s, %d destinations\n", r.Upload.Mode, r.Upload.URL, r.Upload.Command, len(r.Upload.Destinations))
+	} else {
+		fmt.Printf("   Upload:     disabled\n")
+	}
+	fmt.Printf("   Disk:       %s\n", strings.Join(r.SaveToDisk, ", "))
+	fmt.Printf("   DID cache:  enabled %v, refresh %s, max age %s\n", r.DIDCache.Enabled, r.DIDCache.RefreshInterval, r.DIDCache.MaxAge)
+	fmt.Printf("   Database:   %s %s (encrypted %v), station %s\n", r.Database.Backend, r.Database.Path, r.Database.Encrypted, r.Database.StationPath)
+	for _, l := range r.Listeners {
+		fmt.Printf("   Listener:   %s on %s (tls %v, mtls %v)\n", l.Name, l.Addr, l.TLS, l.MTLS)
+	}
+	for _, w := range r.Warnings {
+		fmt.Printf("⚠️  %s\n", w)
+	}
+	if data, err := json.Marshal(r); err == nil {
+		slog.Info("Startup report", "report", string(data))
+	}
+}
+
+// Handler serves GET /api/startup
+func (r *StartupReport) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		writeJSON(w, http.StatusOK, r)
+	})
+}
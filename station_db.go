@@ -0,0 +1,134 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	_ "github.com/ncruces/go-sqlite3/driver"
+	_ "github.com/ncruces/go-sqlite3/embed"
+)
+
+// StationDB holds tables owned by the manufacturing station itself (upload
+// receipts and similar bookkeeping). It lives in its own SQLite file next to
+// the go-fdo database so the library schema stays untouched.
+type StationDB struct {
+	db   *sql.DB
+	path string
+}
+
+// OpenStationDB opens (creating if needed) the station database
+func OpenStationDB(path string) (*StationDB, error) {
+	db, err := sql.Open("sqlite3", "file:"+path+"?_pragma=busy_timeout(10000)&_pragma=journal_mode(wal)&_txlock=immediate")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open station database %q: %w", path, err)
+	}
+	if err := db.Ping(); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to open station database %q: %w", path, err)
+	}
+	return &StationDB{db: db, path: path}, nil
+}
+
+// OpenStationDBReadOnly opens an existing station database for reporting. Writes
+// are refused by SQLite, so a replica can never change production bookkeeping.
+func OpenStationDBReadOnly(path string) (*StationDB, error) {
+	db, err := sql.Open("sqlite3", "file:"+path+"?mode=ro&_pragma=busy_timeout(10000)&_pragma=query_only(1)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open station database %q read-only: %w", path, err)
+	}
+	if err := db.Ping(); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to open station database %q read-only: %w", path, err)
+	}
+	return &StationDB{db: db, path: path}, nil
+}
+
+// Close closes the station database
+func (s *StationDB) Close() error {
+	return s.db.Close()
+}
+
+// stationDBPath returns the configured station database path, defaulting to
+// "<database>-station.db" alongside the go-fdo database
+func stationDBPath(cfg *Config) string {
+	if cfg.Database.StationPath != "" {
+		return cfg.Database.StationPath
+	}
+	ext := filepath.Ext(cfg.Database.Path)
+	return strings.TrimSuffix(cfg.Database.Path, ext) + "-station.db"
+}
+
+// InstanceID returns the station's unique instance ID, generating and storing it
+// on first use. Unlike the configured station ID it survives config copies
+// between stations, so audit records and vouchers trace back to one install.
+func (s *StationDB) InstanceID(ctx context.Context) (string, error) {
+	if _, err := s.db.ExecContext(ctx, `
+	CREATE TABLE IF NOT EXISTS station_meta (
+		key TEXT PRIMARY KEY,
+		value TEXT NOT NULL
+	)`); err != nil {
+		return "", fmt.Errorf("failed to create station_meta table: %w", err)
+	}
+
+	candidate, err := newUUID()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate instance ID: %w", err)
+	}
+
+	// Only the first insert wins, so concurrent first starts agree on one ID
+	if _, err := s.db.ExecContext(ctx,
+		`INSERT OR IGNORE INTO station_meta (key, value) VALUES ('instance_id', ?)`, candidate); err != nil {
+		return "", fmt.Errorf("failed to store instance ID: %w", err)
+	}
+
+	var instanceID string
+	if err := s.db.QueryRowContext(ctx,
+		`SELECT value FROM station_meta WHERE key = 'instance_id'`).Scan(&instanceID); err != nil {
+		return "", fmt.Errorf("failed to read instance ID: %w", err)
+	}
+	return instanceID, nil
+}
+
+// newUUID returns a random (version 4) UUID string
+func newUUID() (string, error) {
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return "", err
+	}
+	id[6] = (id[6] & 0x0f) | 0x40
+	id[8] = (id[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", id[0:4], id[4:6], id[6:8], id[8:10], id[10:16]), nil
+}
+
+// addColumnIfMissing adds a column to an existing table (SQLite has no ADD COLUMN IF NOT EXISTS)
+func (s *StationDB) addColumnIfMissing(ctx context.Context, table, column, columnType string) error {
+	rows, err := s.db.QueryContext(ctx, `SELECT name FROM pragma_table_info(?)`, table)
+	if err != nil {
+		return fmt.Errorf("failed to read %s columns: %w", table, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return fmt.Errorf("failed to read %s columns: %w", table, err)
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read %s columns: %w", table, err)
+	}
+	if _, err := s.db.ExecContext(ctx, fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, table, column, columnType)); err != nil {
+		return fmt.Errorf("failed to add %s.%s: %w", table, column, err)
+	}
+	return nil
+}
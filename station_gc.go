@@ -0,0 +1,415 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Kinds of leftovers the station GC finds
+const (
+	GCOrphanBlob       = "orphan_blob"           // Voucher blob no row references
+	GCBlobRefs         = "blob_refs"             // Voucher blob whose reference count is wrong
+	GCQueueMissing     = "queue_missing_voucher" // Batch upload queue row whose voucher blob is gone
+	GCStaleReservation = "stale_reservation"     // Reserved GUID assigned to a DI session that never finished
+	GCStaleSessionDir  = "stale_session_dir"     // Temp dir of a DI session that never finished
+)
+
+// What the GC did about a finding
+const (
+	GCActionReported = "reported" // Found only; gc.repair is off
+	GCActionRepaired = "repaired"
+	GCActionRemoved  = "removed"
+	GCActionFailed   = "failed" // The repair failed; the finding is left as it was
+)
+
+// Defaults when no interval or stale_after is configured
+const (
+	defaultGCInterval   = 24 * time.Hour
+	defaultGCStaleAfter = 24 * time.Hour
+)
+
+// voucherStoreTables are the station database tables that reference voucher_blobs
+var voucherStoreTables = []string{"transfer_vouchers", "session_records", "voucher_batch_queue", "voucher_pipeline"}
+
+// StationGC periodically looks for what interrupted work leaves behind and,
+// with gc.repair, repairs or removes it. Each run is reported, so an operator
+// can see what accumulates before turning repairs on. A nil *StationGC does
+// nothing.
+type StationGC struct {
+	config    *GCConfig
+	cfg       *Config
+	stationDB *StationDB
+	auditLog  *AuditLog
+
+	runMu sync.Mutex // Serializes runs
+	mu    sync.Mutex
+	last  *GCReport
+}
+
+// GCReport is the result of one run, served by GET /api/gc
+type GCReport struct {
+	StartedAt  time.Time      `json:"started_at"`
+	FinishedAt time.Time      `json:"finished_at"`
+	Repair     bool           `json:"repair"` // false = findings were only reported
+	Counts     map[string]int `json:"counts"` // Findings per kind
+	Findings   []GCFinding    `json:"findings"`
+	Errors     []string       `json:"errors,omitempty"` // Checks that could not run
+}
+
+// GCFinding is one leftover and what the run did about it
+type GCFinding struct {
+	Kind   string `json:"kind"`
+	Key    string `json:"key"` // Voucher hash, GUID or directory
+	Detail string `json:"detail"`
+	Action string `json:"action"` // "reported" | "repaired" | "removed" | "failed"
+}
+
+// NewStationGC creates the GC job, or returns nil if it is disabled
+func NewStationGC(config *GCConfig, cfg *Config, stationDB *StationDB, auditLog *AuditLog) *StationGC {
+	if !config.Enabled {
+		return nil
+	}
+	return &StationGC{config: config, cfg: cfg, stationDB: stationDB, auditLog: auditLog}
+}
+
+// Run collects at startup and then every interval until ctx is done
+func (g *StationGC) Run(ctx context.Context) {
+	if g == nil {
+		return
+	}
+	withConfigRead(func() { g.Collect(ctx, g.config.Repair) })
+	interval := g.config.Interval
+	if interval <= 0 {
+		interval = defaultGCInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			withConfigRead(func() { g.Collect(ctx, g.config.Repair) })
+		}
+	}
+}
+
+// Collect finds leftovers, repairs or removes them when repair is set, and
+// audits the run if it found anything
+func (g *StationGC) Collect(ctx context.Context, repair bool) *GCReport {
+	g.runMu.Lock()
+	defer g.runMu.Unlock()
+
+	report := &GCReport{StartedAt: stationClock.Now(), Repair: repair, Counts: map[string]int{}, Findings: []GCFinding{}}
+	staleAfter := g.config.StaleAfter
+	if staleAfter <= 0 {
+		staleAfter = defaultGCStaleAfter
+	}
+	cutoff := report.StartedAt.Add(-staleAfter)
+
+	for _, collect := range []struct {
+		name string
+		fn   func() error
+	}{
+		{"voucher blobs", func() error { return g.collectBlobs(ctx, report) }},
+		{"batch upload queue", func() error { return g.collectQueue(ctx, report) }},
+		{"GUID reservations", func() error { return g.collectReservations(ctx, report, cutoff) }},
+		{"session temp dirs", func() error { return g.collectSessionDirs(report, cutoff) }},
+	} {
+		if err := collect.fn(); err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", collect.name, err))
+		}
+	}
+	report.FinishedAt = stationClock.Now()
+
+	g.mu.Lock()
+	g.last = report
+	g.mu.Unlock()
+
+	for _, err := range report.Errors {
+		fmt.Printf("⚠️  GC: %s\n", err)
+	}
+	fmt.Printf("🧹 GC: %s\n", report.summary())
+	if len(report.Findings) > 0 {
+		g.auditLog.Record(ctx, AuditEvent{Event: "station_gc", Detail: report.summary()})
+	}
+	return report
+}
+
+// found records a finding and, when the run repairs, fixes it with fix, which
+// does action
+func (r *GCReport) found(kind, key, detail, action string, fix func() error) {
+	finding := GCFinding{Kind: kind, Key: key, Detail: detail, Action: GCActionReported}
+	if r.Repair && fix != nil {
+		if err := fix(); err != nil {
+			finding.Action = GCActionFailed
+			finding.Detail += ": " + err.Error()
+		} else {
+			finding.Action = action
+		}
+	}
+	r.Counts[kind]++
+	r.Findings = append(r.Findings, finding)
+}
+
+// summary describes the run in one line
+func (r *GCReport) summary() string {
+	if len(r.Findings) == 0 {
+		return "nothing to collect"
+	}
+	var kinds []string
+	for _, kind := range slices.Sorted(maps.Keys(r.Counts)) {
+		kinds = append(kinds, fmt.Sprintf("%d %s", r.Counts[kind], kind))
+	}
+	actions := map[string]int{}
+	for _, finding := range r.Findings {
+		actions[finding.Action]++
+	}
+	var done []string
+	for _, action := range slices.Sorted(maps.Keys(actions)) {
+		done = append(done, fmt.Sprintf("%d %s", actions[action], action))
+	}
+	return fmt.Sprintf("found %s; %s", strings.Join(kinds, ", "), strings.Join(done, ", "))
+}
+
+// existingTables returns which of tables are in the station database
+func (g *StationGC) existingTables(ctx context.Context, tables ...string) ([]string, error) {
+	rows, err := g.stationDB.db.QueryContext(ctx, `SELECT name FROM sqlite_master WHERE type = 'table'`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+	defer rows.Close()
+	var existing []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to list tables: %w", err)
+		}
+		if slices.Contains(tables, name) {
+			existing = append(existing, name)
+		}
+	}
+	return existing, rows.Err()
+}
+
+// collectBlobs finds voucher blobs no row references and blobs whose reference
+// count has drifted. Each repair re-counts the references in the statement
+// that applies it, so a voucher stored meanwhile is never lost.
+func (g *StationGC) collectBlobs(ctx context.Context, report *GCReport) error {
+	tables, err := g.existingTables(ctx, voucherStoreTables...)
+	if err != nil {
+		return err
+	}
+	refs := "0"
+	for _, table := range tables {
+		refs += ` + (SELECT COUNT(*) FROM ` + table + ` WHERE voucher_hash = voucher_blobs.hash)`
+	}
+
+	rows, err := g.stationDB.db.QueryContext(ctx, `SELECT hash, refs, `+refs+` FROM voucher_blobs`)
+	if err != nil {
+		return fmt.Errorf("failed to read voucher blobs: %w", err)
+	}
+	type blob struct {
+		hash          string
+		stored, found int
+	}
+	var blobs []blob
+	for rows.Next() {
+		var b blob
+		if err := rows.Scan(&b.hash, &b.stored, &b.found); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to read voucher blobs: %w", err)
+		}
+		if b.stored != b.found {
+			blobs = append(blobs, b)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read voucher blobs: %w", err)
+	}
+
+	for _, b := range blobs {
+		if b.found == 0 {
+			report.found(GCOrphanBlob, b.hash, fmt.Sprintf("no row references it (refs %d)", b.stored), GCActionRemoved, func() error {
+				_, err := g.stationDB.db.ExecContext(ctx, `DELETE FROM voucher_blobs WHERE hash = ? AND `+refs+` = 0`, b.hash)
+				return err
+			})
+			continue
+		}
+		report.found(GCBlobRefs, b.hash, fmt.Sprintf("refs %d, referenced by %d rows", b.stored, b.found), GCActionRepaired, func() error {
+			_, err := g.stationDB.db.ExecContext(ctx, `UPDATE voucher_blobs SET refs = `+refs+` WHERE hash = ?`, b.hash)
+			return err
+		})
+	}
+	return nil
+}
+
+// collectQueue finds batch upload queue rows whose voucher blob is gone. They
+// would fail every batch to their destination, so they are removed; the
+// voucher must be resent from the FDO database.
+func (g *StationGC) collectQueue(ctx context.Context, report *GCReport) error {
+	rows, err := g.stationDB.db.QueryContext(ctx, `
+	SELECT q.guid, q.voucher_hash, q.recipient_url FROM voucher_batch_queue q
+	WHERE COALESCE(q.voucher_hash, '') <> '' AND NOT EXISTS (SELECT 1 FROM voucher_blobs WHERE hash = q.voucher_hash)`)
+	if err != nil {
+		if strings.Contains(err.Error(), "no such table") {
+			return nil
+		}
+		return fmt.Errorf("failed to read batch upload queue: %w", err)
+	}
+	type queued struct{ guid, hash, url string }
+	var missing []queued
+	for rows.Next() {
+		var q queued
+		if err := rows.Scan(&q.guid, &q.hash, &q.url); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to read batch upload queue: %w", err)
+		}
+		missing = append(missing, q)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read batch upload queue: %w", err)
+	}
+
+	for _, q := range missing {
+		report.found(GCQueueMissing, q.guid, fmt.Sprintf("queued for %s, voucher %s is gone", q.url, q.hash), GCActionRemoved, func() error {
+			_, err := g.stationDB.db.ExecContext(ctx, `
+			DELETE FROM voucher_batch_queue WHERE guid = ? AND voucher_hash = ?
+				AND NOT EXISTS (SELECT 1 FROM voucher_blobs WHERE hash = ?)`, q.guid, q.hash, q.hash)
+			return err
+		})
+	}
+	return nil
+}
+
+// collectReservations finds reserved GUIDs assigned to a DI session before
+// cutoff that was never marked used. A GUID whose voucher is in the go-fdo
+// database is marked used; any other goes back to reserved for its device.
+func (g *StationGC) collectReservations(ctx context.Context, report *GCReport, cutoff time.Time) error {
+	rows, err := g.stationDB.db.QueryContext(ctx, `
+	SELECT guid, serial, COALESCE(assigned_at, created_at) FROM guid_reservations
+	WHERE status = ? AND COALESCE(assigned_at, created_at) < ?`, GUIDAssigned, cutoff.Unix())
+	if err != nil {
+		if strings.Contains(err.Error(), "no such table") {
+			return nil
+		}
+		return fmt.Errorf("failed to read GUID reservations: %w", err)
+	}
+	type assigned struct {
+		guid, serial string
+		at           int64
+	}
+	var stale []assigned
+	for rows.Next() {
+		var a assigned
+		if err := rows.Scan(&a.guid, &a.serial, &a.at); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to read GUID reservations: %w", err)
+		}
+		stale = append(stale, a)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read GUID reservations: %w", err)
+	}
+
+	for _, a := range stale {
+		detail := fmt.Sprintf("assigned to %s at %s", serialRules.Serial(a.serial), time.Unix(a.at, 0).UTC().Format(time.RFC3339))
+		if g.cfg.Database.Password != "" {
+			report.found(GCStaleReservation, a.guid, detail+"; left assigned, the go-fdo database is encrypted", "", nil)
+			continue
+		}
+		persisted := false
+		if err := checkFDOVouchers(ctx, g.cfg.Database.Path, a.guid, func(string, string, []byte) { persisted = true }); err != nil {
+			report.found(GCStaleReservation, a.guid, detail+"; left assigned: "+err.Error(), "", nil)
+			continue
+		}
+		if persisted {
+			report.found(GCStaleReservation, a.guid, detail+"; its voucher was persisted, marking it used", GCActionRepaired, func() error {
+				_, err := g.stationDB.db.ExecContext(ctx, `UPDATE guid_reservations SET status = ?, used_at = ? WHERE guid = ? AND status = ?`,
+					GUIDUsed, stationClock.Now().Unix(), a.guid, GUIDAssigned)
+				return err
+			})
+			continue
+		}
+		report.found(GCStaleReservation, a.guid, detail+"; no voucher, returning it to reserved", GCActionRepaired, func() error {
+			_, err := g.stationDB.db.ExecContext(ctx, `UPDATE guid_reservations SET status = ?, assigned_at = NULL WHERE guid = ? AND status = ?`,
+				GUIDReserved, a.guid, GUIDAssigned)
+			return err
+		})
+	}
+	return nil
+}
+
+// collectSessionDirs finds session temp dirs last modified before cutoff. DI
+// sessions remove their own; these were left by a session whose cleanup failed.
+func (g *StationGC) collectSessionDirs(report *GCReport, cutoff time.Time) error {
+	entries, err := os.ReadDir(stationTempRoot)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read temp directory: %w", err)
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), sessionTempPrefix) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || !info.ModTime().Before(cutoff) {
+			continue
+		}
+		dir := filepath.Join(stationTempRoot, entry.Name())
+		report.found(GCStaleSessionDir, dir, "last modified "+info.ModTime().UTC().Format(time.RFC3339), GCActionRemoved, func() error {
+			return os.RemoveAll(dir)
+		})
+	}
+	return nil
+}
+
+// Handler serves GET /api/gc, the last run's report, and POST /api/gc, which
+// runs now. POST takes ?repair=true|false (default gc.repair).
+func (g *StationGC) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if g == nil {
+			writeJSONError(w, http.StatusNotFound, "garbage collection is disabled")
+			return
+		}
+		if r.Method == http.MethodPost {
+			repair := g.config.Repair
+			if value := r.URL.Query().Get("repair"); value != "" {
+				parsed, err := strconv.ParseBool(value)
+				if err != nil {
+					writeJSONError(w, http.StatusBadRequest, "repair must be true or false")
+					return
+				}
+				repair = parsed
+			}
+			writeJSON(w, http.StatusOK, g.Collect(r.Context(), repair))
+			return
+		}
+		g.mu.Lock()
+		report := g.last
+		g.mu.Unlock()
+		if report == nil {
+			writeJSONError(w, http.StatusNotFound, "no GC run has finished yet")
+			return
+		}
+		writeJSON(w, http.StatusOK, report)
+	})
+}
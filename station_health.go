@@ -0,0 +1,117 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Health of the station and of each check, worst first
+const (
+	HealthRefusing = "refusing" // New DI sessions are being refused
+	HealthDegraded = "degraded" // Devices are onboarded, but something needs attention
+	HealthOK       = "ok"
+)
+
+// HealthCheck is one part of the station's health
+type HealthCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// HealthReport is the response of GET /api/health: the worst status of its checks
+type HealthReport struct {
+	Status string        `json:"status"` // "ok" | "degraded" | "refusing"
+	Checks []HealthCheck `json:"checks"`
+}
+
+// StationHealth sums up the disk monitor, DI backpressure, voucher integrity
+// checks and station database in one status for line monitoring
+type StationHealth struct {
+	stationDB    *StationDB
+	disk         *DiskMonitor      // nil = disabled
+	backpressure *Backpressure     // nil = disabled
+	integrity    *VoucherIntegrity // nil = disabled
+}
+
+// NewStationHealth creates the health report of a running station
+func NewStationHealth(stationDB *StationDB, disk *DiskMonitor, backpressure *Backpressure, integrity *VoucherIntegrity) *StationHealth {
+	return &StationHealth{stationDB: stationDB, disk: disk, backpressure: backpressure, integrity: integrity}
+}
+
+// Report runs the checks
+func (h *StationHealth) Report(ctx context.Context) HealthReport {
+	report := HealthReport{Status: HealthOK}
+	add := func(name, status, detail string) {
+		report.Checks = append(report.Checks, HealthCheck{Name: name, Status: status, Detail: detail})
+		if healthRank(status) > healthRank(report.Status) {
+			report.Status = status
+		}
+	}
+
+	if err := h.stationDB.db.PingContext(ctx); err != nil {
+		add("database", HealthRefusing, err.Error())
+	} else {
+		add("database", HealthOK, "")
+	}
+
+	if h.disk != nil {
+		disk := h.disk.Report()
+		switch {
+		case disk.Refusing:
+			add("disk", HealthRefusing, "disk space is "+disk.Level)
+		case disk.Level != DiskLevelOK:
+			add("disk", HealthDegraded, "disk space is "+disk.Level)
+		default:
+			add("disk", HealthOK, "")
+		}
+	}
+
+	if h.backpressure != nil {
+		state := h.backpressure.Check(ctx)
+		if state.Engaged {
+			add("backpressure", HealthRefusing, strings.Join(state.Reasons, "; "))
+		} else {
+			add("backpressure", HealthOK, "")
+		}
+	}
+
+	if h.integrity != nil {
+		last := h.integrity.Last()
+		switch {
+		case last == nil:
+			add("integrity", HealthOK, "no integrity check has run yet")
+		case last.Corrupt > 0:
+			add("integrity", HealthDegraded, fmt.Sprintf("%d of %d vouchers failed verification", last.Corrupt, last.total()))
+		case len(last.Errors) > 0:
+			add("integrity", HealthDegraded, strings.Join(last.Errors, "; "))
+		default:
+			add("integrity", HealthOK, "")
+		}
+	}
+	return report
+}
+
+// healthRank orders health statuses from best to worst
+func healthRank(status string) int {
+	switch status {
+	case HealthDegraded:
+		return 1
+	case HealthRefusing:
+		return 2
+	}
+	return 0
+}
+
+// Handler serves GET /api/health
+func (h *StationHealth) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, h.Report(r.Context()))
+	})
+}
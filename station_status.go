@@ -0,0 +1,215 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// StationStatus keeps the few numbers legacy factory monitoring (andon boards,
+// PLC-based dashboards) can show: whether the station is up, how many devices
+// it built in the last hour, how many DI sessions failed, how many vouchers are
+// waiting to be uploaded, and how many signover anomalies were raised. A nil
+// *StationStatus records nothing.
+type StationStatus struct {
+	batcher *VoucherBatchUploader // nil = uploads are not queued
+
+	mu         sync.Mutex
+	draining   bool
+	recent     []diOutcome // DI outcomes in the last hour, oldest first
+	completed  uint32      // DI completions since start
+	failed     uint32      // DI failures since start
+	anomalies  uint32      // Vouchers extended to a never-before-seen owner key since start
+	unextended uint32      // Vouchers delivered without owner signover since start
+
+	products map[ProductLabels]*ProductMetrics // Per-product DI outcomes since start
+}
+
+// ProductLabels identify what a DI session built, so metrics can be broken
+// down per product rather than per station
+type ProductLabels struct {
+	Customer string // Tenant the device is built for
+	Profile  string // Upload auth profile of its owner
+	Model    string
+}
+
+// ProductMetrics are the DI outcomes of one product since start
+type ProductMetrics struct {
+	Completed uint64
+	Failed    uint64
+	Latency   []uint64 // Sessions per latencyBuckets bound, not cumulative; the last counts the rest
+	Seconds   float64  // Total pipeline time
+}
+
+// latencyBuckets are the upper bounds, in seconds, of the DI pipeline latency histogram
+var latencyBuckets = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// diOutcome is the result of one DI session's voucher pipeline
+type diOutcome struct {
+	at     time.Time
+	failed bool
+}
+
+// StatusSnapshot is the station status at one moment
+type StatusSnapshot struct {
+	Up              bool
+	DevicesLastHour int
+	DevicesTotal    uint32
+	Errors          uint32
+	QueueDepth      int
+	Anomalies       uint32
+	Unextended      uint32
+}
+
+// NewStationStatus creates the status tracker
+func NewStationStatus(batcher *VoucherBatchUploader) *StationStatus {
+	return &StationStatus{batcher: batcher, products: make(map[ProductLabels]*ProductMetrics)}
+}
+
+// RecordDI counts the outcome and pipeline time of one DI session's voucher pipeline
+func (s *StationStatus) RecordDI(labels ProductLabels, elapsed time.Duration, err error) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	product, ok := s.products[labels]
+	if !ok {
+		product = &ProductMetrics{Latency: make([]uint64, len(latencyBuckets)+1)}
+		s.products[labels] = product
+	}
+	if err != nil {
+		s.failed++
+		product.Failed++
+	} else {
+		s.completed++
+		product.Completed++
+	}
+	seconds := elapsed.Seconds()
+	bucket := len(latencyBuckets)
+	for i, bound := range latencyBuckets {
+		if seconds <= bound {
+			bucket = i
+			break
+		}
+	}
+	product.Latency[bucket]++
+	product.Seconds += seconds
+	now := time.Now()
+	s.recent = append(s.prune(now), diOutcome{at: now, failed: err != nil})
+}
+
+// RecordSignoverAnomaly counts a voucher extended to a never-before-seen owner key
+func (s *StationStatus) RecordSignoverAnomaly() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.anomalies++
+	s.mu.Unlock()
+}
+
+// RecordUnextendedVoucher counts a voucher delivered without owner signover
+func (s *StationStatus) RecordUnextendedVoucher() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.unextended++
+	s.mu.Unlock()
+}
+
+// SetDraining marks the station down while it shuts down
+func (s *StationStatus) SetDraining() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.draining = true
+	s.mu.Unlock()
+}
+
+// Snapshot returns the current status. A queue depth that can't be read is reported as 0.
+func (s *StationStatus) Snapshot(ctx context.Context) StatusSnapshot {
+	if s == nil {
+		return StatusSnapshot{Up: true}
+	}
+	depth, err := s.batcher.QueueDepth(ctx)
+	if err != nil {
+		depth = 0
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.recent = s.prune(time.Now())
+	devices := 0
+	for _, outcome := range s.recent {
+		if !outcome.failed {
+			devices++
+		}
+	}
+	return StatusSnapshot{
+		Up:              !s.draining,
+		DevicesLastHour: devices,
+		DevicesTotal:    s.completed,
+		Errors:          s.failed,
+		QueueDepth:      depth,
+		Anomalies:       s.anomalies,
+		Unextended:      s.unextended,
+	}
+}
+
+// Products returns a copy of the per-product DI outcomes
+func (s *StationStatus) Products() map[ProductLabels]ProductMetrics {
+	products := make(map[ProductLabels]ProductMetrics)
+	if s == nil {
+		return products
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for labels, product := range s.products {
+		copied := *product
+		copied.Latency = slices.Clone(product.Latency)
+		products[labels] = copied
+	}
+	return products
+}
+
+// FailureRate returns the percentage of DI sessions that failed within window
+// (at most an hour), and how many sessions that is out of
+func (s *StationStatus) FailureRate(window time.Duration) (float64, int) {
+	if s == nil {
+		return 0, 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cutoff := time.Now().Add(-window)
+	sessions, failed := 0, 0
+	for _, outcome := range s.recent {
+		if outcome.at.After(cutoff) {
+			sessions++
+			if outcome.failed {
+				failed++
+			}
+		}
+	}
+	if sessions == 0 {
+		return 0, 0
+	}
+	return 100 * float64(failed) / float64(sessions), sessions
+}
+
+// prune drops outcomes older than an hour; the caller holds s.mu
+func (s *StationStatus) prune(now time.Time) []diOutcome {
+	cutoff := now.Add(-time.Hour)
+	i := 0
+	for i < len(s.recent) && !s.recent[i].at.After(cutoff) {
+		i++
+	}
+	return s.recent[i:]
+}
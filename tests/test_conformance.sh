@@ -0,0 +1,158 @@
+#!/bin/bash
+# SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+# SPDX-License-Identifier: Apache 2.0
+# Author: Brad Goodman
+#
+# DI protocol conformance suite
+#
+# Runs the go-fdo reference client against the station in parallel for every
+# device key type / key encoding combination, sends malformed DI messages that
+# must be answered with an FDO error message, and optionally runs an external
+# interop suite. Results are written to a report so protocol regressions show up
+# when go-fdo is upgraded.
+#
+# Usage: tests/test_conformance.sh
+#   FDO_INTEROP_SUITE  Optional command run with the station URL as its argument
+#                      (e.g. the published FDO DI test vectors runner); its exit
+#                      status is reported as one more case
+#   REPORT             Report file (default /tmp/fdo_conformance_report.tsv)
+
+set -eu
+
+cd "$(dirname "$0")/.."
+
+PORT=9998
+URL="http://localhost:$PORT"
+WORK_DIR="/tmp/fdo_conformance"
+SERVER_LOG="$WORK_DIR/server.log"
+REPORT="${REPORT:-/tmp/fdo_conformance_report.tsv}"
+SERVER_PID=""
+
+cleanup() {
+    if [ -n "$SERVER_PID" ] && kill -0 "$SERVER_PID" 2>/dev/null; then
+        kill -9 "$SERVER_PID" 2>/dev/null || true
+        wait "$SERVER_PID" 2>/dev/null || true
+    fi
+}
+
+trap cleanup EXIT
+
+echo "=== DI Conformance Suite ==="
+
+rm -rf "$WORK_DIR" /tmp/fdo_conformance_test.db*
+mkdir -p "$WORK_DIR"
+printf "case\tresult\tdetail\n" > "$REPORT"
+
+echo "Building station and reference client..."
+go build -o fdo-manufacturing-station .
+(cd go-fdo/examples && go build -o "$WORK_DIR/fdo-client" ./cmd)
+
+echo "Starting server..."
+./fdo-manufacturing-station -config tests/config_conformance_test.cfg > "$SERVER_LOG" 2>&1 &
+SERVER_PID=$!
+
+for _ in $(seq 1 20); do
+    if curl -s -o /dev/null "$URL/version" 2>/dev/null; then
+        break
+    fi
+    if ! kill -0 "$SERVER_PID" 2>/dev/null; then
+        echo "❌ Server failed to start!"
+        cat "$SERVER_LOG"
+        exit 1
+    fi
+    sleep 1
+done
+echo "✅ Server started (PID: $SERVER_PID)"
+
+# DI with the reference client, one case per device key type and encoding
+CASES=(
+    "ec256 x509" "ec256 x5chain" "ec256 cose"
+    "ec384 x509" "ec384 x5chain" "ec384 cose"
+    "rsa2048 x509" "rsa2048 x5chain"
+    "rsa3072 x509" "rsa3072 x5chain"
+)
+
+run_di_case() {
+    local key="$1" enc="$2"
+    local name="di-$key-$enc"
+    if timeout 60s "$WORK_DIR/fdo-client" client -di "$URL" -di-key "$key" -di-key-enc "$enc" \
+        -blob "$WORK_DIR/$name.bin" > "$WORK_DIR/$name.log" 2>&1 && [ -s "$WORK_DIR/$name.bin" ]; then
+        echo "PASS" > "$WORK_DIR/$name.result"
+    else
+        echo "FAIL" > "$WORK_DIR/$name.result"
+    fi
+}
+
+echo "Running ${#CASES[@]} DI cases in parallel..."
+PIDS=()
+for c in "${CASES[@]}"; do
+    # shellcheck disable=SC2086 # split "key enc"
+    run_di_case $c &
+    PIDS+=($!)
+done
+for pid in "${PIDS[@]}"; do
+    wait "$pid" || true
+done
+
+for c in "${CASES[@]}"; do
+    read -r key enc <<< "$c"
+    name="di-$key-$enc"
+    result=$(cat "$WORK_DIR/$name.result")
+    detail=""
+    if [ "$result" = "FAIL" ]; then
+        detail=$(tail -1 "$WORK_DIR/$name.log" | tr '\t' ' ')
+    fi
+    printf "%s\t%s\t%s\n" "$name" "$result" "$detail" >> "$REPORT"
+done
+
+# Every successful DI must be reported complete by the station
+DI_PASSED=$(grep -c "^di-.*"$'\t'"PASS" "$REPORT" || true)
+COMPLETED=$(grep -c "DI Completed" "$SERVER_LOG" || true)
+if [ "$COMPLETED" -eq "$DI_PASSED" ]; then
+    printf "di-completed-events\tPASS\t%d devices\n" "$COMPLETED" >> "$REPORT"
+else
+    printf "di-completed-events\tFAIL\tstation completed %d of %d devices\n" "$COMPLETED" "$DI_PASSED" >> "$REPORT"
+fi
+
+# Malformed messages must be answered with an FDO error message (type 255)
+negative_case() {
+    local name="$1" msg="$2" body="$3"
+    local headers="$WORK_DIR/$name.headers"
+    printf '%b' "$body" | curl -s -o /dev/null -D "$headers" -X POST \
+        -H "Content-Type: application/cbor" --data-binary @- "$URL/fdo/101/msg/$msg" || true
+    if grep -qi "^Message-Type: *255" "$headers"; then
+        printf "%s\tPASS\t\n" "$name" >> "$REPORT"
+    else
+        printf "%s\tFAIL\t%s\n" "$name" "$(head -1 "$headers" | tr -d '\r')" >> "$REPORT"
+    fi
+}
+
+echo "Running negative cases..."
+negative_case "appstart-invalid-cbor" 10 '\xff\xff\xff'
+negative_case "appstart-wrong-shape" 10 '\x82\x01\x02'
+negative_case "sethmac-without-session" 12 '\x81\x82\x05\x40'
+negative_case "unexpected-message-type" 60 '\x80'
+
+# Optional external interop suite / published test vectors
+if [ -n "${FDO_INTEROP_SUITE:-}" ]; then
+    echo "Running interop suite: $FDO_INTEROP_SUITE"
+    if $FDO_INTEROP_SUITE "$URL" > "$WORK_DIR/interop.log" 2>&1; then
+        printf "interop-suite\tPASS\t\n" >> "$REPORT"
+    else
+        printf "interop-suite\tFAIL\tsee %s\n" "$WORK_DIR/interop.log" >> "$REPORT"
+    fi
+fi
+
+PASSED=$(tail -n +2 "$REPORT" | grep -c $'\tPASS\t' || true)
+TOTAL=$(tail -n +2 "$REPORT" | wc -l)
+
+echo ""
+column -t -s "$(printf '\t')" "$REPORT" 2>/dev/null || cat "$REPORT"
+echo ""
+echo "Compliance: $PASSED/$TOTAL cases passed (report: $REPORT)"
+
+if [ "$PASSED" -ne "$TOTAL" ]; then
+    echo "❌ Conformance failures; client logs are in $WORK_DIR"
+    exit 1
+fi
+echo "✅ DI conformance suite passed!"
@@ -0,0 +1,614 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tlsTrust is set at startup from voucher_management.tls_trust. It is nil in
+// offline tools, which use the system pool only.
+var tlsTrust *TLSTrust
+
+// TLSTrust adds trust anchors for factory-internal owner endpoints, scoped to
+// the hosts they are configured for: a CA file per host pattern, and
+// certificates pinned with "trust pin" in the trust directory. Hosts without
+// extra anchors are verified against the system pool as usual. Pinned files
+// are read when they change, so a pin takes effect without a restart. TLS
+// policies narrow what a host may negotiate: versions, TLS 1.2 cipher suites
+// and the certificates its verified chain must contain.
+type TLSTrust struct {
+	config *TLSTrustConfig
+
+	mu    sync.Mutex
+	files map[string]trustFile // Parsed anchor files, by path
+}
+
+// defaultAnchorRefresh is how often an anchor URL is fetched when no refresh_interval is configured
+const defaultAnchorRefresh = time.Hour
+
+// trustFile is an anchor file as of its modification time
+type trustFile struct {
+	modTime time.Time
+	certs   []*x509.Certificate
+}
+
+// NewTLSTrust creates the trust store
+func NewTLSTrust(config *TLSTrustConfig) *TLSTrust {
+	return &TLSTrust{config: config, files: map[string]trustFile{}}
+}
+
+// wrapTransport makes transport verify TLS servers against the system pool
+// plus the anchors of their host. Without any anchors configured the
+// transport is left as it is.
+func (t *TLSTrust) wrapTransport(transport *http.Transport) {
+	if t == nil || (t.config.Directory == "" && len(t.config.Anchors) == 0 && t.config.MinVersion == "" && len(t.config.Policies) == 0) {
+		return
+	}
+	dial := transport.DialContext
+	if dial == nil {
+		dial = (&net.Dialer{Timeout: 30 * time.Second}).DialContext
+	}
+	base := transport.TLSClientConfig
+	transport.DialTLSContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		roots, err := t.roots(host)
+		if err != nil {
+			return nil, err
+		}
+		cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+		if base != nil {
+			cfg = base.Clone()
+		}
+		cfg.ServerName = host
+		cfg.RootCAs = roots
+		if err := t.applyPolicy(cfg, host); err != nil {
+			return nil, err
+		}
+		raw, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		conn := tls.Client(raw, cfg)
+		if err := conn.HandshakeContext(ctx); err != nil {
+			raw.Close()
+			return nil, err
+		}
+		return conn, nil
+	}
+}
+
+// policy returns the first TLS policy whose hosts match host, or nil
+func (t *TLSTrust) policy(host string) *TLSPolicy {
+	host = strings.ToLower(host)
+	for i := range t.config.Policies {
+		for _, pattern := range t.config.Policies[i].Hosts {
+			if matchDomainPattern(pattern, host) {
+				return &t.config.Policies[i]
+			}
+		}
+	}
+	return nil
+}
+
+// applyPolicy sets the versions, cipher suites and pins of host's policy on
+// cfg, or the default minimum version for a host without one
+func (t *TLSTrust) applyPolicy(cfg *tls.Config, host string) error {
+	minVersion := t.config.MinVersion
+	policy := t.policy(host)
+	if policy != nil && policy.MinVersion != "" {
+		minVersion = policy.MinVersion
+	}
+	if minVersion != "" {
+		version, err := parseTLSVersion(minVersion)
+		if err != nil {
+			return err
+		}
+		cfg.MinVersion = version
+	}
+	if policy == nil {
+		return nil
+	}
+	if policy.MaxVersion != "" {
+		version, err := parseTLSVersion(policy.MaxVersion)
+		if err != nil {
+			return err
+		}
+		cfg.MaxVersion = version
+	}
+	if len(policy.CipherSuites) > 0 {
+		suites, err := parseCipherSuites(policy.CipherSuites)
+		if err != nil {
+			return err
+		}
+		cfg.CipherSuites = suites
+	}
+	if len(policy.PinSHA256) > 0 {
+		pins := policy.PinSHA256
+		// Runs after the chain is verified, so a pin narrows trust and never replaces it
+		cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+			for _, chain := range cs.VerifiedChains {
+				for _, cert := range chain {
+					fingerprint := certSHA256(cert)
+					for _, pin := range pins {
+						if strings.EqualFold(normalizeFingerprint(pin), fingerprint) {
+							return nil
+						}
+					}
+				}
+			}
+			return fmt.Errorf("no certificate of %s matches its pin_sha256", host)
+		}
+	}
+	return nil
+}
+
+// parseTLSVersion parses a TLS version of a policy; versions before 1.2 are refused
+func parseTLSVersion(version string) (uint16, error) {
+	switch version {
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	}
+	return 0, fmt.Errorf("unsupported TLS version %q (expected 1.2 or 1.3)", version)
+}
+
+// parseCipherSuites resolves TLS 1.2 cipher suites by their Go names,
+// including the ones Go lists as insecure that legacy owner services need
+func parseCipherSuites(names []string) ([]uint16, error) {
+	known := map[string]uint16{}
+	for _, suite := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+		for _, version := range suite.SupportedVersions {
+			if version == tls.VersionTLS12 {
+				known[suite.Name] = suite.ID
+			}
+		}
+	}
+	suites := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := known[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown TLS 1.2 cipher suite %q", name)
+		}
+		suites = append(suites, id)
+	}
+	return suites, nil
+}
+
+// normalizeFingerprint accepts a hex fingerprint with or without colons and a sha256: prefix
+func normalizeFingerprint(fingerprint string) string {
+	fingerprint = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(fingerprint)), "sha256:")
+	return strings.ReplaceAll(fingerprint, ":", "")
+}
+
+// roots returns the system pool plus the anchors of host, or nil (the system
+// pool) if the host has none
+func (t *TLSTrust) roots(host string) (*x509.CertPool, error) {
+	host = strings.ToLower(host)
+	var paths []string
+	for _, anchor := range t.config.Anchors {
+		for _, pattern := range anchor.Hosts {
+			if matchDomainPattern(pattern, host) {
+				paths = append(paths, anchor.CAFile)
+				break
+			}
+		}
+	}
+	if t.config.Directory != "" {
+		pinned := pinnedCertPath(t.config.Directory, host)
+		if _, err := os.Stat(pinned); err == nil {
+			paths = append(paths, pinned)
+		}
+	}
+	if len(paths) == 0 {
+		return nil, nil
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	for _, path := range paths {
+		certs, err := t.load(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, cert := range certs {
+			pool.AddCert(cert)
+		}
+	}
+	return pool, nil
+}
+
+// load returns the certificates of an anchor file, parsing it again only when
+// it changes. A changed file that can't be parsed, e.g. one an owner's tooling
+// is still writing, leaves the certificates read before it in place.
+func (t *TLSTrust) load(path string) ([]*x509.Certificate, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read trust anchor: %w", err)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	last, loaded := t.files[path]
+	if loaded && last.modTime.Equal(info.ModTime()) {
+		return last.certs, nil
+	}
+	certs, err := readPEMCertificates(path)
+	if err != nil && loaded {
+		fmt.Printf("⚠️  Trust anchor %s changed but is unusable, keeping the previous certificates: %v\n", path, err)
+		t.files[path] = trustFile{modTime: info.ModTime(), certs: last.certs}
+		return last.certs, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("trust anchor %s: %w", path, err)
+	}
+	if loaded {
+		fmt.Printf("🔄 Trust anchor %s reloaded (%d certificates)\n", path, len(certs))
+	}
+	t.files[path] = trustFile{modTime: info.ModTime(), certs: certs}
+	return certs, nil
+}
+
+// readPEMCertificates reads and parses a PEM certificate file
+func readPEMCertificates(path string) ([]*x509.Certificate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read trust anchor: %w", err)
+	}
+	return parsePEMCertificates(data)
+}
+
+// Run keeps the anchors that have a URL up to date: each is fetched at
+// startup and then every refresh_interval, and written to its ca_file when it
+// changed. A failed fetch keeps the current file, so an unreachable owner
+// never removes trust that was working.
+func (t *TLSTrust) Run(ctx context.Context) {
+	if t == nil {
+		return
+	}
+	for i := range t.config.Anchors {
+		anchor := &t.config.Anchors[i]
+		if anchor.URL == "" {
+			continue
+		}
+		go func() {
+			interval := anchor.RefreshInterval
+			if interval <= 0 {
+				interval = defaultAnchorRefresh
+			}
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				if err := t.refresh(ctx, anchor); err != nil {
+					fmt.Printf("⚠️  Failed to refresh trust anchor %s: %v\n", anchor.URL, err)
+				}
+				select {
+				case <-ticker.C:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+}
+
+// refresh fetches an anchor's bundle and replaces its ca_file in one rename
+// if the bundle is valid and differs from the file
+func (t *TLSTrust) refresh(ctx context.Context, anchor *TrustAnchor) error {
+	// The bundle host is verified like any other: the system pool plus its own anchors
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	t.wrapTransport(transport)
+	client := &http.Client{Timeout: 30 * time.Second, Transport: transport}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, anchor.URL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("failed to read bundle: %w", err)
+	}
+	certs, err := parsePEMCertificates(data)
+	if err != nil {
+		return fmt.Errorf("bundle rejected: %w", err)
+	}
+	if current, err := os.ReadFile(anchor.CAFile); err == nil && bytes.Equal(current, data) {
+		return nil
+	}
+	if err := writeViaSessionTemp(ctx, anchor.CAFile, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", anchor.CAFile, err)
+	}
+	fmt.Printf("🔄 Trust anchor %s updated from %s (%d certificates)\n", anchor.CAFile, anchor.URL, len(certs))
+	return nil
+}
+
+// parsePEMCertificates parses every CERTIFICATE block of a PEM file
+func parsePEMCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid certificate: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no certificates found")
+	}
+	return certs, nil
+}
+
+// pinnedCertPath is the file a host's pinned certificate is kept in
+func pinnedCertPath(dir, host string) string {
+	return filepath.Join(dir, strings.ToLower(host)+".pem")
+}
+
+// validateTLSTrust checks that the configured anchor files can be used and
+// that every TLS policy can be negotiated
+func validateTLSTrust(config *TLSTrustConfig) error {
+	defaultMin := uint16(tls.VersionTLS12)
+	if config.MinVersion != "" {
+		version, err := parseTLSVersion(config.MinVersion)
+		if err != nil {
+			return fmt.Errorf("voucher_management.tls_trust.min_version: %w", err)
+		}
+		defaultMin = version
+	}
+	for i, policy := range config.Policies {
+		if len(policy.Hosts) == 0 {
+			return fmt.Errorf("voucher_management.tls_trust.policies[%d] needs hosts", i)
+		}
+		minVersion, maxVersion := defaultMin, uint16(tls.VersionTLS13)
+		if policy.MinVersion != "" {
+			version, err := parseTLSVersion(policy.MinVersion)
+			if err != nil {
+				return fmt.Errorf("voucher_management.tls_trust.policies[%d].min_version: %w", i, err)
+			}
+			minVersion = version
+		}
+		if policy.MaxVersion != "" {
+			version, err := parseTLSVersion(policy.MaxVersion)
+			if err != nil {
+				return fmt.Errorf("voucher_management.tls_trust.policies[%d].max_version: %w", i, err)
+			}
+			maxVersion = version
+		}
+		if minVersion > maxVersion {
+			return fmt.Errorf("voucher_management.tls_trust.policies[%d]: the minimum TLS version is above max_version", i)
+		}
+		if len(policy.CipherSuites) > 0 {
+			if minVersion == tls.VersionTLS13 {
+				return fmt.Errorf("voucher_management.tls_trust.policies[%d]: cipher_suites only apply to TLS 1.2, but min_version is 1.3", i)
+			}
+			if _, err := parseCipherSuites(policy.CipherSuites); err != nil {
+				return fmt.Errorf("voucher_management.tls_trust.policies[%d].cipher_suites: %w", i, err)
+			}
+		}
+		for _, pin := range policy.PinSHA256 {
+			if b, err := hex.DecodeString(normalizeFingerprint(pin)); err != nil || len(b) != sha256.Size {
+				return fmt.Errorf("voucher_management.tls_trust.policies[%d].pin_sha256: %q is not a SHA-256 fingerprint", i, pin)
+			}
+		}
+	}
+	for i, anchor := range config.Anchors {
+		if len(anchor.Hosts) == 0 || anchor.CAFile == "" {
+			return fmt.Errorf("voucher_management.tls_trust.anchors[%d] needs hosts and a ca_file", i)
+		}
+		if anchor.URL != "" {
+			if u, err := url.Parse(anchor.URL); err != nil || u.Scheme != "https" || u.Host == "" {
+				return fmt.Errorf("voucher_management.tls_trust.anchors[%d].url must be an https URL", i)
+			}
+			if anchor.RefreshInterval < 0 || (anchor.RefreshInterval > 0 && anchor.RefreshInterval < time.Minute) {
+				return fmt.Errorf("voucher_management.tls_trust.anchors[%d].refresh_interval must be at least 1m", i)
+			}
+			if _, err := os.Stat(anchor.CAFile); errors.Is(err, os.ErrNotExist) {
+				continue // Written by the first fetch
+			}
+		}
+		if _, err := readPEMCertificates(anchor.CAFile); err != nil {
+			return fmt.Errorf("voucher_management.tls_trust.anchors[%d] %s: %w", i, anchor.CAFile, err)
+		}
+	}
+	return nil
+}
+
+// certSHA256 is the hex SHA-256 fingerprint of a certificate
+func certSHA256(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// runTrust implements "trust pin [-yes] <url|host[:port]>", which fetches a
+// server's certificate, shows it for the operator to check against the
+// fingerprint from the endpoint's owner, and pins it for that host, and
+// "trust list"
+func runTrust(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: trust pin|list ...")
+	}
+	dir := config.VoucherManagement.TLSTrust.Directory
+	if dir == "" {
+		return fmt.Errorf("voucher_management.tls_trust.directory is not configured")
+	}
+	switch args[0] {
+	case "pin":
+		fs := flag.NewFlagSet("trust pin", flag.ContinueOnError)
+		yes := fs.Bool("yes", false, "Pin without asking for confirmation")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if fs.NArg() != 1 {
+			return fmt.Errorf("usage: trust pin [-yes] <url|host[:port]>")
+		}
+		host, addr, err := trustTarget(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+
+		// The certificate is fetched unverified: trusting it is what the operator decides here
+		dialer := &tls.Dialer{
+			NetDialer: &net.Dialer{Timeout: 15 * time.Second},
+			Config:    &tls.Config{ServerName: host, InsecureSkipVerify: true, MinVersion: tls.VersionTLS12},
+		}
+		conn, err := dialer.DialContext(context.Background(), "tcp", addr)
+		if err != nil {
+			return fmt.Errorf("failed to connect to %s: %w", addr, err)
+		}
+		chain := conn.(*tls.Conn).ConnectionState().PeerCertificates
+		conn.Close()
+		if len(chain) == 0 {
+			return fmt.Errorf("%s sent no certificate", addr)
+		}
+
+		// Pin the top of the chain the server sent: its root, else the highest
+		// intermediate or the leaf itself
+		pinned := chain[len(chain)-1]
+		for i, cert := range chain {
+			fmt.Printf("[%d] subject:  %s\n    issuer:   %s\n    valid:    %s to %s\n    sha256:   %s\n",
+				i, cert.Subject, cert.Issuer, cert.NotBefore.UTC().Format(time.RFC3339), cert.NotAfter.UTC().Format(time.RFC3339), certSHA256(cert))
+		}
+		if err := chain[0].VerifyHostname(host); err != nil {
+			return fmt.Errorf("certificate does not match %s: %w", host, err)
+		}
+		fmt.Printf("\nPin certificate [%d] (sha256 %s) as a trust anchor for %s?\n", len(chain)-1, certSHA256(pinned), host)
+		if !*yes {
+			fmt.Print("Compare the fingerprint with the endpoint's owner, then type \"yes\" to pin: ")
+			answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+			if strings.TrimSpace(answer) != "yes" {
+				return fmt.Errorf("not pinned")
+			}
+		}
+
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("failed to create trust directory: %w", err)
+		}
+		data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: pinned.Raw})
+		path := pinnedCertPath(dir, host)
+		if err := writeViaSessionTemp(context.Background(), path, data, 0o644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		recordTrustPin(host, certSHA256(pinned))
+		fmt.Printf("📌 Pinned %s for %s in %s\n", certSHA256(pinned), host, path)
+		return nil
+
+	case "list":
+		entries, err := os.ReadDir(dir)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		for _, entry := range entries {
+			host, ok := strings.CutSuffix(entry.Name(), ".pem")
+			if !ok {
+				continue
+			}
+			data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+			if err != nil {
+				return err
+			}
+			certs, err := parsePEMCertificates(data)
+			if err != nil {
+				fmt.Printf("%s: %v\n", host, err)
+				continue
+			}
+			for _, cert := range certs {
+				fmt.Printf("%s  %s  %s  expires %s\n", host, certSHA256(cert), cert.Subject, cert.NotAfter.UTC().Format(time.RFC3339))
+			}
+		}
+		for _, anchor := range config.VoucherManagement.TLSTrust.Anchors {
+			fmt.Printf("%s  %s (ca_file)\n", strings.Join(anchor.Hosts, ","), anchor.CAFile)
+		}
+		for _, policy := range config.VoucherManagement.TLSTrust.Policies {
+			for _, pin := range policy.PinSHA256 {
+				fmt.Printf("%s  %s (pin_sha256)\n", strings.Join(policy.Hosts, ","), normalizeFingerprint(pin))
+			}
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unknown trust command %q (expected pin or list)", args[0])
+	}
+}
+
+// trustTarget returns the host and dial address of a URL or host[:port]
+func trustTarget(target string) (string, string, error) {
+	if strings.Contains(target, "://") {
+		u, err := url.Parse(target)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid URL: %w", err)
+		}
+		if u.Scheme != "https" {
+			return "", "", fmt.Errorf("only https endpoints can be pinned")
+		}
+		target = u.Host
+	}
+	host, port, err := net.SplitHostPort(target)
+	if err != nil {
+		host, port = target, "443"
+	}
+	if host == "" {
+		return "", "", fmt.Errorf("no host in %q", target)
+	}
+	return host, net.JoinHostPort(host, port), nil
+}
+
+// recordTrustPin audits a pinned certificate in the station database, if there is one
+func recordTrustPin(host, fingerprint string) {
+	stationDB, err := OpenStationDB(stationDBPath(config))
+	if err != nil {
+		fmt.Printf("⚠️  Pin not audited: %v\n", err)
+		return
+	}
+	defer stationDB.Close()
+	ctx := context.Background()
+	auditLog := NewAuditLog(stationDB, &config.Station)
+	if err := auditLog.Initialize(ctx); err != nil {
+		fmt.Printf("⚠️  Pin not audited: %v\n", err)
+		return
+	}
+	auditLog.Record(ctx, AuditEvent{
+		Event:  "tls_cert_pinned",
+		Detail: fmt.Sprintf("certificate %s pinned as trust anchor for %s", fingerprint, host),
+	})
+}
@@ -0,0 +1,690 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+)
+
+// Circuit breaker states of an upload destination
+const (
+	BreakerClosed   = "closed"    // Uploads flow normally
+	BreakerOpen     = "open"      // Uploads fail fast until the cooldown ends
+	BreakerHalfOpen = "half_open" // One trial upload decides whether to close or reopen
+)
+
+// ErrDestinationUnavailable is returned when a destination is disabled or its breaker is open
+var ErrDestinationUnavailable = errors.New("upload destination unavailable")
+
+// UploadDestination is a known voucher recipient and its health
+type UploadDestination struct {
+	Name                string     `json:"name"`
+	URL                 string     `json:"url"`
+	AuthProfile         string     `json:"auth_profile"`
+	Owner               string     `json:"owner"` // Customer ID whose vouchers go here; overrides the DID/config URL
+	Enabled             bool       `json:"enabled"`
+	LastSuccess         *time.Time `json:"last_success,omitempty"`
+	LastFailure         *time.Time `json:"last_failure,omitempty"`
+	LastError           string     `json:"last_error,omitempty"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	TotalFailures       int        `json:"total_failures"`
+	TotalSuccesses      int        `json:"total_successes"`
+	BreakerState        string     `json:"breaker_state"`
+	BreakerOpenedAt     *time.Time `json:"breaker_opened_at,omitempty"`
+}
+
+// UploadDestinationRequest creates or updates a destination via the admin API
+type UploadDestinationRequest struct {
+	Name        string `json:"name" yaml:"name"`
+	URL         string `json:"url" yaml:"url"`
+	AuthProfile string `json:"auth_profile" yaml:"auth_profile"`
+	Owner       string `json:"owner" yaml:"owner"`
+	Enabled     *bool  `json:"enabled" yaml:"enabled"` // Default true
+}
+
+// UploadDestinationCatalog keeps the voucher recipients the station uploads to,
+// so an endpoint can be moved, disabled or re-authenticated through the admin
+// API without editing owner DIDs or config files. Destinations seen for the
+// first time are added automatically, and every upload updates the health
+// counters and circuit breaker of its destination.
+type UploadDestinationCatalog struct {
+	config   *VoucherConfig
+	db       *StationDB
+	throttle *UploadThrottle
+}
+
+// NewUploadDestinationCatalog creates a new upload destination catalog
+func NewUploadDestinationCatalog(config *VoucherConfig, db *StationDB) *UploadDestinationCatalog {
+	return &UploadDestinationCatalog{config: config, db: db, throttle: NewUploadThrottle(&config.VoucherUpload.RateLimit)}
+}
+
+// Throttle returns the rate limiter of the destinations
+func (c *UploadDestinationCatalog) Throttle() *UploadThrottle {
+	if c == nil {
+		return nil
+	}
+	return c.throttle
+}
+
+// Initialize creates the upload_destinations table if it doesn't exist
+func (c *UploadDestinationCatalog) Initialize(ctx context.Context) error {
+	_, err := c.db.db.ExecContext(ctx, `
+	CREATE TABLE IF NOT EXISTS upload_destinations (
+		name TEXT PRIMARY KEY,
+		url TEXT NOT NULL UNIQUE,
+		auth_profile TEXT NOT NULL DEFAULT '',
+		owner TEXT NOT NULL DEFAULT '',
+		enabled INTEGER NOT NULL DEFAULT 1,
+		last_success INTEGER,
+		last_failure INTEGER,
+		last_error TEXT,
+		consecutive_failures INTEGER NOT NULL DEFAULT 0,
+		total_failures INTEGER NOT NULL DEFAULT 0,
+		total_successes INTEGER NOT NULL DEFAULT 0,
+		breaker_state TEXT NOT NULL DEFAULT 'closed',
+		breaker_opened_at INTEGER
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create upload_destinations table: %w", err)
+	}
+	return nil
+}
+
+const uploadDestinationColumns = `name, url, auth_profile, owner, enabled, last_success, last_failure, last_error,
+	consecutive_failures, total_failures, total_successes, breaker_state, breaker_opened_at`
+
+// scanUploadDestination reads one upload_destinations row
+func scanUploadDestination(row interface{ Scan(...any) error }) (*UploadDestination, error) {
+	var d UploadDestination
+	var lastSuccess, lastFailure, openedAt sql.NullInt64
+	var lastError sql.NullString
+	if err := row.Scan(&d.Name, &d.URL, &d.AuthProfile, &d.Owner, &d.Enabled, &lastSuccess, &lastFailure, &lastError,
+		&d.ConsecutiveFailures, &d.TotalFailures, &d.TotalSuccesses, &d.BreakerState, &openedAt); err != nil {
+		return nil, err
+	}
+	d.LastSuccess = nullUnixTime(lastSuccess)
+	d.LastFailure = nullUnixTime(lastFailure)
+	d.BreakerOpenedAt = nullUnixTime(openedAt)
+	d.LastError = lastError.String
+	return &d, nil
+}
+
+func nullUnixTime(v sql.NullInt64) *time.Time {
+	if !v.Valid {
+		return nil
+	}
+	t := time.Unix(v.Int64, 0)
+	return &t
+}
+
+// List returns all destinations ordered by name
+func (c *UploadDestinationCatalog) List(ctx context.Context) ([]*UploadDestination, error) {
+	destinations := []*UploadDestination{}
+	if c == nil {
+		return destinations, nil
+	}
+	rows, err := c.db.db.QueryContext(ctx, `SELECT `+uploadDestinationColumns+` FROM upload_destinations ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list upload destinations: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		d, err := scanUploadDestination(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list upload destinations: %w", err)
+		}
+		destinations = append(destinations, d)
+	}
+	return destinations, rows.Err()
+}
+
+// UsingProfile returns the destinations that authenticate with an upload auth profile
+func (c *UploadDestinationCatalog) UsingProfile(ctx context.Context, profile string) ([]*UploadDestination, error) {
+	destinations := []*UploadDestination{}
+	if c == nil {
+		return destinations, nil
+	}
+	rows, err := c.db.db.QueryContext(ctx, `SELECT `+uploadDestinationColumns+` FROM upload_destinations WHERE auth_profile = ? ORDER BY name`, profile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list upload destinations of profile %s: %w", profile, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		d, err := scanUploadDestination(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list upload destinations of profile %s: %w", profile, err)
+		}
+		destinations = append(destinations, d)
+	}
+	return destinations, rows.Err()
+}
+
+// profileCustomers returns the owners whose vouchers are uploaded with an
+// auth profile, given the destinations using it. The profile serves every
+// customer when it is voucher_upload.auth_profile or a destination using it
+// has no owner.
+func profileCustomers(config *VoucherConfig, profile string, using []*UploadDestination) ([]string, bool) {
+	if config.VoucherUpload.AuthProfile == profile {
+		return nil, true
+	}
+	var customers []string
+	for _, d := range using {
+		if d.Owner == "" {
+			return nil, true
+		}
+		customers = append(customers, d.Owner)
+	}
+	return customers, false
+}
+
+// checkScope refuses a change to a destination by an admin limited to some
+// customers, unless the destination, before and after the change, and the
+// auth profile it names serve only those customers. req is nil for a removal.
+func (c *UploadDestinationCatalog) checkScope(ctx context.Context, name string, req *UploadDestinationRequest) error {
+	what := "upload destination " + name
+	existing, err := c.Get(ctx, name)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		if err := checkAdminCustomers(ctx, what, []string{existing.Owner}, existing.Owner == ""); err != nil {
+			return err
+		}
+	}
+	if req == nil {
+		return nil
+	}
+	if err := checkAdminCustomers(ctx, what, []string{req.Owner}, req.Owner == ""); err != nil {
+		return err
+	}
+
+	// Uploading with another customer's credentials is out of scope too
+	profile := req.AuthProfile
+	if profile == "" {
+		profile = c.config.VoucherUpload.AuthProfile
+	}
+	if profile == "" {
+		return nil
+	}
+	using, err := c.UsingProfile(ctx, profile)
+	if err != nil {
+		return err
+	}
+	using = slices.DeleteFunc(using, func(d *UploadDestination) bool { return d.Name == name })
+	customers, everyCustomer := profileCustomers(c.config, profile, using)
+	return checkAdminCustomers(ctx, "upload auth profile "+profile, customers, everyCustomer)
+}
+
+// destinationListSpec is the sort and filter spec of GET /api/destinations
+var destinationListSpec = listSpec{
+	Key:         "name",
+	Sorts:       map[string]string{"name": "name", "url": "url", "consecutive_failures": "consecutive_failures"},
+	DefaultSort: "name",
+	Filters:     map[string]string{"owner": "owner", "auth_profile": "auth_profile", "breaker_state": "breaker_state"},
+}
+
+// Page returns one page of destinations
+func (c *UploadDestinationCatalog) Page(ctx context.Context, q *listQuery) ([]*UploadDestination, string, error) {
+	destinations := []*UploadDestination{}
+	if c == nil {
+		return destinations, "", nil
+	}
+	clause, args := q.sql()
+	rows, err := c.db.db.QueryContext(ctx, `SELECT `+uploadDestinationColumns+` FROM upload_destinations`+clause, args...)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list upload destinations: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		d, err := scanUploadDestination(rows)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to list upload destinations: %w", err)
+		}
+		destinations = append(destinations, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+	destinations, next := listPage(q, destinations, func(d *UploadDestination, column string) any {
+		switch column {
+		case "url":
+			return d.URL
+		case "consecutive_failures":
+			return d.ConsecutiveFailures
+		default:
+			return d.Name
+		}
+	})
+	return destinations, next, nil
+}
+
+// Get returns a destination by name, or nil if there is none
+func (c *UploadDestinationCatalog) Get(ctx context.Context, name string) (*UploadDestination, error) {
+	return c.getWhere(ctx, "name", name)
+}
+
+// getWhere returns the destination whose column equals value, or nil if there is none
+func (c *UploadDestinationCatalog) getWhere(ctx context.Context, column, value string) (*UploadDestination, error) {
+	d, err := scanUploadDestination(c.db.db.QueryRowContext(ctx,
+		`SELECT `+uploadDestinationColumns+` FROM upload_destinations WHERE `+column+` = ? ORDER BY name LIMIT 1`, value))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read upload destination %s: %w", value, err)
+	}
+	return d, nil
+}
+
+// Resolve picks the destination for a voucher. A destination assigned to the
+// owner wins over the URL from the owner DID or config; otherwise the URL's
+// catalog entry is used, and added if this is the first upload to it.
+func (c *UploadDestinationCatalog) Resolve(ctx context.Context, owner, recipientURL, authProfile string) (*UploadDestination, error) {
+	if c == nil {
+		return &UploadDestination{URL: recipientURL, AuthProfile: authProfile, Enabled: true, BreakerState: BreakerClosed}, nil
+	}
+
+	if owner != "" {
+		d, err := c.getWhere(ctx, "owner", owner)
+		if err != nil {
+			return nil, err
+		}
+		if d != nil {
+			// Routing an owner's vouchers to its own destination is multi-tenancy
+			if err := stationEntitlements.Require(EntitlementMultiTenant, "upload destination "+d.Name+" for owner "+owner); err != nil {
+				return nil, err
+			}
+			if d.URL != recipientURL && recipientURL != "" {
+				fmt.Printf("🔀 Catalog destination %q overrides %s for owner %s\n", d.Name, recipientURL, owner)
+			}
+			return c.withDefaultProfile(d, authProfile), nil
+		}
+	}
+	if recipientURL == "" {
+		return nil, fmt.Errorf("http upload mode requires a voucherRecipientURL from the owner DID, voucher_upload.url or an upload destination for owner %q", owner)
+	}
+
+	d, err := c.getWhere(ctx, "url", recipientURL)
+	if err != nil || d != nil {
+		return c.withDefaultProfile(d, authProfile), err
+	}
+
+	d = &UploadDestination{
+		Name:         c.autoName(ctx, recipientURL),
+		URL:          recipientURL,
+		AuthProfile:  authProfile,
+		Enabled:      true,
+		BreakerState: BreakerClosed,
+	}
+	if _, err := c.db.db.ExecContext(ctx,
+		`INSERT OR IGNORE INTO upload_destinations (name, url, auth_profile) VALUES (?, ?, ?)`,
+		d.Name, d.URL, d.AuthProfile); err != nil {
+		return nil, fmt.Errorf("failed to add upload destination %s: %w", recipientURL, err)
+	}
+	fmt.Printf("📇 Added upload destination %q (%s) to the catalog\n", d.Name, recipientURL)
+	return d, nil
+}
+
+// withDefaultProfile fills in the caller's auth profile when the catalog entry names none
+func (c *UploadDestinationCatalog) withDefaultProfile(d *UploadDestination, authProfile string) *UploadDestination {
+	if d != nil && d.AuthProfile == "" {
+		d.AuthProfile = authProfile
+	}
+	return d
+}
+
+// autoName names a newly seen destination after the URL's host, adding a
+// short URL hash if another destination already uses that name
+func (c *UploadDestinationCatalog) autoName(ctx context.Context, recipientURL string) string {
+	name := recipientURL
+	if u, err := url.Parse(recipientURL); err == nil && u.Host != "" {
+		name = u.Host
+	}
+	if existing, err := c.Get(ctx, name); err == nil && existing == nil {
+		return name
+	}
+	sum := sha256.Sum256([]byte(recipientURL))
+	return name + "-" + hex.EncodeToString(sum[:4])
+}
+
+// Allow reports whether an upload may be attempted. An open breaker fails fast
+// until the cooldown has passed, then lets one trial upload through. Uploads
+// over the destination's rate limit wait here for their turn.
+func (c *UploadDestinationCatalog) Allow(ctx context.Context, d *UploadDestination) error {
+	if c == nil || d.Name == "" {
+		return nil
+	}
+	if !d.Enabled {
+		return fmt.Errorf("%w: %q is disabled", ErrDestinationUnavailable, d.Name)
+	}
+	if d.BreakerState != BreakerOpen {
+		return c.throttle.Wait(ctx, d.Name)
+	}
+	if d.BreakerOpenedAt != nil && time.Since(*d.BreakerOpenedAt) < c.cooldown() {
+		return fmt.Errorf("%w: circuit breaker for %q is open after %d consecutive failures (last: %s)",
+			ErrDestinationUnavailable, d.Name, d.ConsecutiveFailures, d.LastError)
+	}
+
+	// Only the request that moves the breaker to half-open gets the trial
+	res, err := c.db.db.ExecContext(ctx,
+		`UPDATE upload_destinations SET breaker_state = ? WHERE name = ? AND breaker_state = ?`,
+		BreakerHalfOpen, d.Name, BreakerOpen)
+	if err != nil {
+		return fmt.Errorf("failed to update upload destination %s: %w", d.Name, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("%w: trial upload to %q already in progress", ErrDestinationUnavailable, d.Name)
+	}
+	fmt.Printf("🔌 Circuit breaker for %q half-open, sending a trial upload\n", d.Name)
+	return c.throttle.Wait(ctx, d.Name)
+}
+
+// AllowURL is Allow for the catalog entry of a recipient URL
+func (c *UploadDestinationCatalog) AllowURL(ctx context.Context, recipientURL string) error {
+	if c == nil {
+		return nil
+	}
+	d, err := c.getWhere(ctx, "url", recipientURL)
+	if err != nil || d == nil {
+		return err
+	}
+	return c.Allow(ctx, d)
+}
+
+// RecordResult updates a destination's health after an upload attempt and
+// opens its breaker once failure_threshold consecutive uploads have failed
+// (or the half-open trial failed)
+func (c *UploadDestinationCatalog) RecordResult(ctx context.Context, recipientURL string, uploadErr error) {
+	if c == nil {
+		return
+	}
+	now := time.Now().Unix()
+	var err error
+	if uploadErr == nil {
+		_, err = c.db.db.ExecContext(ctx, `
+		UPDATE upload_destinations SET last_success = ?, consecutive_failures = 0,
+			total_successes = total_successes + 1, breaker_state = ?, breaker_opened_at = NULL
+		WHERE url = ?`, now, BreakerClosed, recipientURL)
+	} else {
+		_, err = c.db.db.ExecContext(ctx, `
+		UPDATE upload_destinations SET last_failure = ?, last_error = ?,
+			consecutive_failures = consecutive_failures + 1, total_failures = total_failures + 1,
+			breaker_state = CASE WHEN breaker_state = ? OR consecutive_failures + 1 >= ? THEN ? ELSE breaker_state END,
+			breaker_opened_at = CASE WHEN breaker_state = ? OR consecutive_failures + 1 >= ? THEN ? ELSE breaker_opened_at END
+		WHERE url = ?`,
+			now, uploadErr.Error(),
+			BreakerHalfOpen, c.failureThreshold(), BreakerOpen,
+			BreakerHalfOpen, c.failureThreshold(), now,
+			recipientURL)
+	}
+	if err != nil {
+		fmt.Printf("⚠️  Failed to update health of upload destination %s: %v\n", recipientURL, err)
+	}
+}
+
+// Put creates or replaces the configuration of a destination, keeping its health counters
+func (c *UploadDestinationCatalog) Put(ctx context.Context, req *UploadDestinationRequest) (*UploadDestination, error) {
+	if err := c.validate(req); err != nil {
+		return nil, err
+	}
+	enabled := req.Enabled == nil || *req.Enabled
+	_, err := c.db.db.ExecContext(ctx, `
+	INSERT INTO upload_destinations (name, url, auth_profile, owner, enabled) VALUES (?, ?, ?, ?, ?)
+	ON CONFLICT (name) DO UPDATE SET url = excluded.url, auth_profile = excluded.auth_profile,
+		owner = excluded.owner, enabled = excluded.enabled`,
+		req.Name, req.URL, req.AuthProfile, req.Owner, enabled)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store upload destination %s: %w", req.Name, err)
+	}
+	fmt.Printf("📇 Upload destination %q set to %s (owner %q, enabled %v)\n", req.Name, req.URL, req.Owner, enabled)
+	return c.Get(ctx, req.Name)
+}
+
+// validate checks a destination request before it is stored
+func (c *UploadDestinationCatalog) validate(req *UploadDestinationRequest) error {
+	if req.Name == "" {
+		return fmt.Errorf("destination name is required")
+	}
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("destination url must be an absolute http(s) URL")
+	}
+	if req.AuthProfile != "" {
+		if _, ok := c.config.UploadAuthProfiles[req.AuthProfile]; !ok {
+			return fmt.Errorf("unknown upload auth profile %q", req.AuthProfile)
+		}
+	}
+	if req.Owner != "" {
+		if err := stationEntitlements.Require(EntitlementMultiTenant, "upload destination "+req.Name+" for owner "+req.Owner); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Delete removes a destination. It is re-added on the next upload to its URL.
+func (c *UploadDestinationCatalog) Delete(ctx context.Context, name string) (bool, error) {
+	res, err := c.db.db.ExecContext(ctx, `DELETE FROM upload_destinations WHERE name = ?`, name)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete upload destination %s: %w", name, err)
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// Reset closes a destination's breaker and clears its consecutive failure count
+func (c *UploadDestinationCatalog) Reset(ctx context.Context, name string) (*UploadDestination, error) {
+	if _, err := c.db.db.ExecContext(ctx, `
+	UPDATE upload_destinations SET breaker_state = ?, breaker_opened_at = NULL, consecutive_failures = 0
+	WHERE name = ?`, BreakerClosed, name); err != nil {
+		return nil, fmt.Errorf("failed to reset upload destination %s: %w", name, err)
+	}
+	fmt.Printf("🔌 Circuit breaker for %q reset\n", name)
+	return c.Get(ctx, name)
+}
+
+// failureThreshold returns the consecutive failures that open a breaker
+func (c *UploadDestinationCatalog) failureThreshold() int {
+	if c.config.VoucherUpload.Breaker.FailureThreshold > 0 {
+		return c.config.VoucherUpload.Breaker.FailureThreshold
+	}
+	return 5
+}
+
+// cooldown returns how long an open breaker fails fast before a trial upload
+func (c *UploadDestinationCatalog) cooldown() time.Duration {
+	if c.config.VoucherUpload.Breaker.Cooldown > 0 {
+		return c.config.VoucherUpload.Breaker.Cooldown
+	}
+	return 5 * time.Minute
+}
+
+// ListHandler serves GET /api/destinations
+func (c *UploadDestinationCatalog) ListHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q, err := parseListQuery(r, &destinationListSpec)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		destinations, next, err := c.Page(r.Context(), q)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSONList(w, r, destinations, next)
+	})
+}
+
+// GetHandler serves GET /api/destinations/{name}
+func (c *UploadDestinationCatalog) GetHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.writeDestination(w, r.PathValue("name"), func(name string) (*UploadDestination, error) {
+			return c.Get(r.Context(), name)
+		})
+	})
+}
+
+// PutHandler serves POST /api/destinations and PUT /api/destinations/{name}:
+// 201 when the destination was created, 200 when it was replaced
+func (c *UploadDestinationCatalog) PutHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if c == nil {
+			writeJSONError(w, http.StatusNotFound, "upload destination catalog is not enabled (voucher_upload.mode must be http)")
+			return
+		}
+		var req UploadDestinationRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid destination request: %v", err))
+			return
+		}
+		if name := r.PathValue("name"); name != "" {
+			req.Name = name
+		}
+		if err := c.validate(&req); err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err := c.checkScope(r.Context(), req.Name, &req); err != nil {
+			writeJSONError(w, scopeStatus(err, http.StatusInternalServerError), err.Error())
+			return
+		}
+		existing, err := c.Get(r.Context(), req.Name)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		d, err := c.Put(r.Context(), &req)
+		if err != nil {
+			writeJSONError(w, http.StatusConflict, err.Error())
+			return
+		}
+		status := http.StatusOK
+		if existing == nil {
+			status = http.StatusCreated
+		}
+		writeJSON(w, status, d)
+	})
+}
+
+// describePut files a destination change for a second admin's approval
+func (c *UploadDestinationCatalog) describePut(r *http.Request, body []byte) (*ChangeRequest, error) {
+	if c == nil {
+		return nil, nil
+	}
+	var req UploadDestinationRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, fmt.Errorf("invalid destination request: %v", err)
+	}
+	if name := r.PathValue("name"); name != "" {
+		req.Name = name
+	}
+	if err := c.validate(&req); err != nil {
+		return nil, err
+	}
+	if err := c.checkScope(r.Context(), req.Name, &req); err != nil {
+		return nil, err
+	}
+	return &ChangeRequest{Target: req.Name, Destination: &req}, nil
+}
+
+// applyPut applies an approved destination change
+func (c *UploadDestinationCatalog) applyPut(ctx context.Context, change *ChangeRequest) (any, error) {
+	if c == nil || change.Destination == nil {
+		return nil, fmt.Errorf("upload destination catalog is not enabled (voucher_upload.mode must be http)")
+	}
+	if err := c.checkScope(ctx, change.Destination.Name, change.Destination); err != nil {
+		return nil, err
+	}
+	return c.Put(ctx, change.Destination)
+}
+
+// describeDelete files a destination removal for a second admin's approval
+func (c *UploadDestinationCatalog) describeDelete(r *http.Request, _ []byte) (*ChangeRequest, error) {
+	if c == nil {
+		return nil, nil
+	}
+	if err := c.checkScope(r.Context(), r.PathValue("name"), nil); err != nil {
+		return nil, err
+	}
+	return &ChangeRequest{Target: r.PathValue("name")}, nil
+}
+
+// applyDelete applies an approved destination removal
+func (c *UploadDestinationCatalog) applyDelete(ctx context.Context, change *ChangeRequest) (any, error) {
+	if c == nil {
+		return nil, fmt.Errorf("unknown destination %q", change.Target)
+	}
+	if err := c.checkScope(ctx, change.Target, nil); err != nil {
+		return nil, err
+	}
+	deleted, err := c.Delete(ctx, change.Target)
+	if err == nil && !deleted {
+		err = fmt.Errorf("unknown destination %q", change.Target)
+	}
+	return nil, err
+}
+
+// DeleteHandler serves DELETE /api/destinations/{name}
+func (c *UploadDestinationCatalog) DeleteHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("name")
+		if c == nil {
+			writeJSONError(w, http.StatusNotFound, fmt.Sprintf("unknown destination %q", name))
+			return
+		}
+		if err := c.checkScope(r.Context(), name, nil); err != nil {
+			writeJSONError(w, scopeStatus(err, http.StatusInternalServerError), err.Error())
+			return
+		}
+		deleted, err := c.Delete(r.Context(), name)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if !deleted {
+			writeJSONError(w, http.StatusNotFound, fmt.Sprintf("unknown destination %q", name))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// ResetHandler serves POST /api/destinations/{name}/reset
+func (c *UploadDestinationCatalog) ResetHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.writeDestination(w, r.PathValue("name"), func(name string) (*UploadDestination, error) {
+			return c.Reset(r.Context(), name)
+		})
+	})
+}
+
+// writeDestination runs a per-destination operation and writes its result
+func (c *UploadDestinationCatalog) writeDestination(w http.ResponseWriter, name string, op func(string) (*UploadDestination, error)) {
+	if c == nil {
+		writeJSONError(w, http.StatusNotFound, fmt.Sprintf("unknown destination %q", name))
+		return
+	}
+	d, err := op(name)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if d == nil {
+		writeJSONError(w, http.StatusNotFound, fmt.Sprintf("unknown destination %q", name))
+		return
+	}
+	writeJSON(w, http.StatusOK, d)
+}
@@ -0,0 +1,224 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/fido-device-onboard/go-fdo"
+	"github.com/fido-device-onboard/go-fdo/cbor"
+)
+
+// uploadReceiptPrefix starts the statement an owner signs to acknowledge a
+// voucher and versions its format
+const uploadReceiptPrefix = "fdo-upload-receipt.v1:"
+
+// Verification outcomes of an upload receipt signature
+const (
+	ReceiptVerified = "verified" // Signed by the owner key the voucher was signed over to
+	ReceiptInvalid  = "invalid"  // Signed, but the signature doesn't verify with that key
+	ReceiptUnsigned = "unsigned" // The recipient returned no signature
+)
+
+// UploadReceipt records a recipient's acknowledgment of an uploaded voucher
+type UploadReceipt struct {
+	GUID           string    `json:"guid"`
+	Serial         string    `json:"serial"`
+	RecipientURL   string    `json:"recipient_url"`
+	ReceiptID      string    `json:"receipt_id,omitempty"`     // Receipt/confirmation ID returned by the recipient (may be empty)
+	Status         string    `json:"status"`                   // "accepted" | "duplicate"
+	VoucherHash    string    `json:"voucher_sha256,omitempty"` // SHA-256 of the uploaded CBOR voucher
+	UploadedAt     time.Time `json:"uploaded_at"`
+	Signature      string    `json:"receipt_signature,omitempty"` // Owner's base64 signature of the receipt statement
+	Verification   string    `json:"verification,omitempty"`      // "verified" | "invalid" | "unsigned"; empty for receipts older than verification
+	OwnerKeySHA256 string    `json:"owner_key_sha256,omitempty"`  // Owner key the signature was checked with
+}
+
+// statement returns the string the owner signs to acknowledge the voucher:
+// fdo-upload-receipt.v1:<guid>:<voucher_sha256>:<receipt_id>
+func (r *UploadReceipt) statement() string {
+	return uploadReceiptPrefix + r.GUID + ":" + r.VoucherHash + ":" + r.ReceiptID
+}
+
+// verify checks the receipt signature against the owner key the voucher was
+// signed over to, which is the key its owner DID resolved to, and records the
+// outcome in Verification. It returns nil only for a verified receipt.
+func (r *UploadReceipt) verify(ov *fdo.Voucher) error {
+	if r.Signature == "" {
+		r.Verification = ReceiptUnsigned
+		return fmt.Errorf("recipient returned no receipt signature")
+	}
+	r.Verification = ReceiptInvalid
+	if ov == nil || len(ov.Entries) == 0 {
+		return fmt.Errorf("voucher has no owner key to check the receipt signature with")
+	}
+	key, err := ov.Entries[len(ov.Entries)-1].Payload.Val.PublicKey.Public()
+	if err != nil {
+		return fmt.Errorf("failed to decode owner key: %w", err)
+	}
+	r.OwnerKeySHA256 = ownerKeySHA256(key)
+	if chain, ok := key.([]*x509.Certificate); ok && len(chain) > 0 {
+		key = chain[0].PublicKey
+	}
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return fmt.Errorf("failed to encode owner key: %w", err)
+	}
+	if err := verifyOwnerKeyProof(der, r.statement(), r.Signature); err != nil {
+		return fmt.Errorf("receipt signature: %w", err)
+	}
+	r.Verification = ReceiptVerified
+	return nil
+}
+
+// verifyFile is verify for a voucher file as it was uploaded
+func (r *UploadReceipt) verifyFile(voucherFile []byte) error {
+	var ov *fdo.Voucher
+	if data, err := decodeVoucherFile(voucherFile); err == nil {
+		var decoded fdo.Voucher
+		if cbor.Unmarshal(data, &decoded) == nil {
+			ov = &decoded
+		}
+	}
+	return r.verify(ov)
+}
+
+// UploadReceiptStore persists upload receipts keyed by voucher GUID
+type UploadReceiptStore struct {
+	db *StationDB
+}
+
+// NewUploadReceiptStore creates a new upload receipt store
+func NewUploadReceiptStore(db *StationDB) *UploadReceiptStore {
+	return &UploadReceiptStore{db: db}
+}
+
+// Initialize creates the voucher_upload_receipts table if it doesn't exist
+func (s *UploadReceiptStore) Initialize(ctx context.Context) error {
+	_, err := s.db.db.ExecContext(ctx, `
+	CREATE TABLE IF NOT EXISTS voucher_upload_receipts (
+		guid TEXT PRIMARY KEY,
+		serial TEXT NOT NULL,
+		recipient_url TEXT NOT NULL,
+		receipt_id TEXT,
+		status TEXT NOT NULL,
+		uploaded_at INTEGER NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create voucher_upload_receipts table: %w", err)
+	}
+	for _, column := range []string{"voucher_hash", "receipt_signature", "verification", "owner_key_sha256"} {
+		if err := s.db.addColumnIfMissing(ctx, "voucher_upload_receipts", column, "TEXT"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Save stores (or replaces) the receipt for a voucher
+func (s *UploadReceiptStore) Save(ctx context.Context, receipt *UploadReceipt) error {
+	_, err := s.db.db.ExecContext(ctx, `
+	INSERT OR REPLACE INTO voucher_upload_receipts
+		(guid, serial, recipient_url, receipt_id, status, voucher_hash, uploaded_at, receipt_signature, verification, owner_key_sha256)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		receipt.GUID, receipt.Serial, receipt.RecipientURL, receipt.ReceiptID, receipt.Status, receipt.VoucherHash, receipt.UploadedAt.Unix(),
+		receipt.Signature, receipt.Verification, receipt.OwnerKeySHA256)
+	if err != nil {
+		return fmt.Errorf("failed to save upload receipt for %s: %w", receipt.GUID, err)
+	}
+	return nil
+}
+
+// Get returns the receipt for a voucher GUID, or nil if it has not been uploaded
+func (s *UploadReceiptStore) Get(ctx context.Context, guid string) (*UploadReceipt, error) {
+	var receipt UploadReceipt
+	var receiptID sql.NullString
+	var uploadedAt int64
+	err := s.db.db.QueryRowContext(ctx, `
+	SELECT guid, serial, recipient_url, receipt_id, status, COALESCE(voucher_hash, ''), uploaded_at,
+		COALESCE(receipt_signature, ''), COALESCE(verification, ''), COALESCE(owner_key_sha256, '')
+	FROM voucher_upload_receipts WHERE guid = ?`, guid).Scan(
+		&receipt.GUID, &receipt.Serial, &receipt.RecipientURL, &receiptID, &receipt.Status, &receipt.VoucherHash, &uploadedAt,
+		&receipt.Signature, &receipt.Verification, &receipt.OwnerKeySHA256)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read upload receipt for %s: %w", guid, err)
+	}
+	receipt.ReceiptID = receiptID.String
+	receipt.UploadedAt = time.Unix(uploadedAt, 0)
+	return &receipt, nil
+}
+
+// uploadListSpec is the sort and filter spec of GET /api/uploads
+var uploadListSpec = listSpec{
+	Key:         "guid",
+	Sorts:       map[string]string{"uploaded_at": "uploaded_at", "guid": "guid", "serial": "serial"},
+	DefaultSort: "-uploaded_at",
+	Filters:     map[string]string{"serial": "serial", "recipient_url": "recipient_url", "status": "status", "receipt_id": "receipt_id", "voucher_sha256": "voucher_hash", "verification": "verification"},
+}
+
+// Page returns one page of upload receipts
+func (s *UploadReceiptStore) Page(ctx context.Context, q *listQuery) ([]UploadReceipt, string, error) {
+	clause, args := q.sql()
+	rows, err := s.db.db.QueryContext(ctx, `
+	SELECT guid, serial, recipient_url, COALESCE(receipt_id, ''), status, COALESCE(voucher_hash, ''), uploaded_at,
+		COALESCE(receipt_signature, ''), COALESCE(verification, ''), COALESCE(owner_key_sha256, '')
+	FROM voucher_upload_receipts`+clause, args...)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to query upload receipts: %w", err)
+	}
+	defer rows.Close()
+
+	receipts := []UploadReceipt{}
+	for rows.Next() {
+		var receipt UploadReceipt
+		var uploadedAt int64
+		if err := rows.Scan(&receipt.GUID, &receipt.Serial, &receipt.RecipientURL, &receipt.ReceiptID, &receipt.Status, &receipt.VoucherHash, &uploadedAt,
+			&receipt.Signature, &receipt.Verification, &receipt.OwnerKeySHA256); err != nil {
+			return nil, "", fmt.Errorf("failed to read upload receipt: %w", err)
+		}
+		receipt.UploadedAt = time.Unix(uploadedAt, 0)
+		receipts = append(receipts, receipt)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+	receipts, next := listPage(q, receipts, func(receipt UploadReceipt, column string) any {
+		switch column {
+		case "uploaded_at":
+			return receipt.UploadedAt.Unix()
+		case "serial":
+			return receipt.Serial
+		default:
+			return receipt.GUID
+		}
+	})
+	return receipts, next, nil
+}
+
+// ListHandler serves GET /api/uploads with the list parameters of uploadListSpec
+func (s *UploadReceiptStore) ListHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q, err := parseListQuery(r, &uploadListSpec)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		receipts, next, err := s.Page(r.Context(), q)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSONList(w, r, receipts, next)
+	})
+}
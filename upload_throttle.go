@@ -0,0 +1,148 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultThrottleMaxWait = 30 * time.Second
+
+// ErrUploadThrottled is returned when an upload would wait longer than
+// rate_limit.max_wait for its turn at a destination
+var ErrUploadThrottled = errors.New("upload throttled")
+
+// ThrottleStats counts the throttling applied to one destination
+type ThrottleStats struct {
+	Destination string  // Catalog destination name
+	Delayed     uint64  // Uploads that waited for their turn
+	Rejected    uint64  // Uploads that would have waited longer than max_wait
+	WaitSeconds float64 // Total time uploads waited
+	Waiting     int     // Uploads waiting now
+}
+
+// UploadThrottle limits the request rate to each upload destination, so a
+// burst on the line doesn't flood a customer's voucher recipient. Requests
+// over the rate queue in arrival order and are let through one interval
+// apart, after the destination's burst is used up.
+type UploadThrottle struct {
+	config *RateLimitConfig
+
+	mu      sync.Mutex
+	buckets map[string]*throttleBucket // Keyed by destination name
+}
+
+// throttleBucket schedules the requests to one destination. next is the time
+// the request after the last admitted one would be due without a burst.
+type throttleBucket struct {
+	next  time.Time
+	stats ThrottleStats
+}
+
+// NewUploadThrottle creates the throttle
+func NewUploadThrottle(config *RateLimitConfig) *UploadThrottle {
+	return &UploadThrottle{config: config, buckets: map[string]*throttleBucket{}}
+}
+
+// limit returns the rate and burst of a destination; a rate of 0 is unlimited
+func (t *UploadThrottle) limit(destination string) (float64, int) {
+	rate, burst := t.config.RequestsPerSecond, t.config.Burst
+	if override, ok := t.config.Destinations[destination]; ok {
+		rate, burst = override.RequestsPerSecond, override.Burst
+	}
+	return rate, max(burst, 1)
+}
+
+// Wait blocks until an upload to destination may be sent. It fails without
+// waiting if the upload's turn is more than max_wait away.
+func (t *UploadThrottle) Wait(ctx context.Context, destination string) error {
+	if t == nil {
+		return nil
+	}
+	rate, burst := t.limit(destination)
+	if rate <= 0 {
+		return nil
+	}
+	interval := time.Duration(float64(time.Second) / rate)
+	maxWait := t.config.MaxWait
+	if maxWait <= 0 {
+		maxWait = defaultThrottleMaxWait
+	}
+
+	t.mu.Lock()
+	b, ok := t.buckets[destination]
+	if !ok {
+		b = &throttleBucket{stats: ThrottleStats{Destination: destination}}
+		t.buckets[destination] = b
+	}
+	now := time.Now()
+	if b.next.Before(now) {
+		b.next = now
+	}
+	wait := b.next.Add(-time.Duration(burst-1) * interval).Sub(now)
+	if wait > maxWait {
+		b.stats.Rejected++
+		t.mu.Unlock()
+		return fmt.Errorf("%w: %q is at its limit of %g uploads/s; next turn in %s", ErrUploadThrottled, destination, rate, wait.Round(time.Millisecond))
+	}
+	b.next = b.next.Add(interval)
+	if wait <= 0 {
+		t.mu.Unlock()
+		return nil
+	}
+	b.stats.Delayed++
+	b.stats.Waiting++
+	t.mu.Unlock()
+
+	fmt.Printf("🚥 Upload to %q throttled, waiting %s\n", destination, wait.Round(time.Millisecond))
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	var err error
+	select {
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-timer.C:
+	}
+
+	t.mu.Lock()
+	b.stats.Waiting--
+	b.stats.WaitSeconds += time.Since(now).Seconds()
+	t.mu.Unlock()
+	return err
+}
+
+// Stats returns the throttling counters of every destination that has been rate limited, by name
+func (t *UploadThrottle) Stats() []ThrottleStats {
+	stats := []ThrottleStats{}
+	if t == nil {
+		return stats
+	}
+	t.mu.Lock()
+	for _, b := range t.buckets {
+		stats = append(stats, b.stats)
+	}
+	t.mu.Unlock()
+	slices.SortFunc(stats, func(a, b ThrottleStats) int { return strings.Compare(a.Destination, b.Destination) })
+	return stats
+}
+
+// validateRateLimit checks the upload rate limits
+func validateRateLimit(config *RateLimitConfig) error {
+	if config.RequestsPerSecond < 0 || config.Burst < 0 {
+		return fmt.Errorf("voucher_upload.rate_limit: requests_per_second and burst must not be negative")
+	}
+	for name, limit := range config.Destinations {
+		if limit.RequestsPerSecond < 0 || limit.Burst < 0 {
+			return fmt.Errorf("voucher_upload.rate_limit.destinations.%s: requests_per_second and burst must not be negative", name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime"
+	runtimedebug "runtime/debug"
+	"time"
+
+	"fdo-manufacturing-station/extension"
+)
+
+// Build information, set at link time:
+//
+//	go build -ldflags "-X main.Version=1.2.0 -X main.GitCommit=$(git rev-parse HEAD) -X main.BuildDate=$(date -u +%FT%TZ)"
+//
+// GitCommit and BuildDate fall back to the VCS info Go embeds in module builds.
+var (
+	Version   = "0.1.0-dev"
+	GitCommit = ""
+	BuildDate = ""
+)
+
+// BuildInfo describes this station binary and install
+type BuildInfo struct {
+	Version    string        `json:"version"`
+	GitCommit  string        `json:"git_commit"`
+	BuildDate  string        `json:"build_date"`
+	GoVersion  string        `json:"go_version"`
+	InstanceID string        `json:"instance_id,omitempty"`
+	SiteCode   string        `json:"site_code,omitempty"`
+	LineID     string        `json:"line_id,omitempty"`
+	StationID  string        `json:"station_id,omitempty"`
+	Features   BuildFeatures `json:"features"`
+
+	// Premium features this station is entitled to
+	Entitlements EntitlementInfo `json:"entitlements"`
+}
+
+// BuildFeatures lists compiled-in and enabled capabilities
+type BuildFeatures struct {
+	HSM              bool     `json:"hsm"`               // Voucher signing via external HSM command
+	KMS              bool     `json:"kms"`               // Cloud KMS signing (not compiled in)
+	DIDMethods       []string `json:"did_methods"`       // DID methods the resolver supports
+	UploadModes      []string `json:"upload_modes"`      // Voucher upload transports compiled in
+	ProtocolVersions []string `json:"protocol_versions"` // FDO protocol versions accepted for DI
+	ExtensionAPI     int      `json:"extension_api"`     // Extension API version the station implements
+	Extensions       []string `json:"extensions"`        // Compiled-in extensions, as name@v<api version>
+}
+
+// currentBuildInfo returns the build information for this binary and config
+func currentBuildInfo(cfg *Config, instanceID string) BuildInfo {
+	info := BuildInfo{
+		Version:    Version,
+		GitCommit:  GitCommit,
+		BuildDate:  BuildDate,
+		GoVersion:  runtime.Version(),
+		InstanceID: instanceID,
+		Features: BuildFeatures{
+			KMS:              false,
+			DIDMethods:       supportedDIDMethods,
+			UploadModes:      []string{"command", "http", "batch"},
+			ProtocolVersions: supportedProtocolVersions,
+			ExtensionAPI:     extension.APIVersion,
+			Extensions:       compiledExtensions(),
+		},
+		Entitlements: stationEntitlements.Info(),
+	}
+	if cfg != nil {
+		info.SiteCode = cfg.Station.SiteCode
+		info.LineID = cfg.Station.LineID
+		info.StationID = cfg.Station.StationID
+		info.Features.HSM = cfg.VoucherManagement.VoucherSigning.Mode == "external"
+		info.Features.ProtocolVersions = acceptedProtocolVersions(&cfg.Protocol)
+	}
+
+	if bi, ok := runtimedebug.ReadBuildInfo(); ok {
+		for _, setting := range bi.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if info.GitCommit == "" {
+					info.GitCommit = setting.Value
+				}
+			case "vcs.time":
+				if info.BuildDate == "" {
+					info.BuildDate = setting.Value
+				}
+			}
+		}
+	}
+	return info
+}
+
+// String formats build info for --version
+func (b BuildInfo) String() string {
+	s := fmt.Sprintf("fdo-manufacturing-station %s\n  commit:      %s\n  built:       %s\n  go:          %s\n",
+		b.Version, valueOrUnknown(b.GitCommit), valueOrUnknown(b.BuildDate), b.GoVersion)
+	if b.InstanceID != "" {
+		s += fmt.Sprintf("  instance id: %s\n", b.InstanceID)
+	}
+	s += fmt.Sprintf("  station:     site=%s line=%s station=%s\n",
+		valueOrUnknown(b.SiteCode), valueOrUnknown(b.LineID), valueOrUnknown(b.StationID))
+	s += fmt.Sprintf("  features:    hsm=%t kms=%t did=%v upload=%v fdo=%v\n",
+		b.Features.HSM, b.Features.KMS, b.Features.DIDMethods, b.Features.UploadModes, b.Features.ProtocolVersions)
+	s += fmt.Sprintf("  extensions:  api=v%d %v\n", b.Features.ExtensionAPI, b.Features.Extensions)
+	s += fmt.Sprintf("  entitled:    %s %v", b.Entitlements.Status, b.Entitlements.Features)
+	if b.Entitlements.Licensee != "" {
+		s += fmt.Sprintf(" licensee=%s", b.Entitlements.Licensee)
+	}
+	if b.Entitlements.NotAfter != nil {
+		s += fmt.Sprintf(" until=%s", b.Entitlements.NotAfter.Format(time.RFC3339))
+	}
+	if b.Entitlements.Problem != "" {
+		s += fmt.Sprintf(" (%s)", b.Entitlements.Problem)
+	}
+	s += "\n"
+	return s
+}
+
+// versionHandler serves GET /version
+func versionHandler(info BuildInfo) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(info); err != nil {
+			fmt.Printf("⚠️  Failed to write version response: %v\n", err)
+		}
+	})
+}
+
+func valueOrUnknown(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+	return s
+}
@@ -0,0 +1,481 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// VoucherBatchUploader queues vouchers per destination and ships them as a single
+// zip/tar archive with a manifest, either on a timer or once a destination's queue
+// reaches the configured size. The queue lives in the station database so pending
+// vouchers survive a restart.
+type VoucherBatchUploader struct {
+	config   *VoucherConfig
+	http     *VoucherHTTPUploader
+	db       *StationDB
+	store    *VoucherStore
+	receipts *UploadReceiptStore
+	catalog  *UploadDestinationCatalog
+	notifier *Notifier
+
+	flushMu sync.Mutex // serializes flushes so a voucher is never in two batches
+}
+
+// BatchManifest is written as manifest.json at the root of every batch archive
+type BatchManifest struct {
+	BatchID      string               `json:"batch_id"`
+	Manufacturer string               `json:"manufacturer"`
+	CreatedAt    string               `json:"created_at"`
+	Vouchers     []BatchManifestEntry `json:"vouchers"`
+}
+
+// BatchManifestEntry describes one voucher file in a batch archive
+type BatchManifestEntry struct {
+	GUID   string `json:"guid"`
+	Serial string `json:"serial"`
+	Model  string `json:"model"`
+	File   string `json:"file"`
+	SHA256 string `json:"voucher_sha256"` // SHA-256 of the file's CBOR voucher
+}
+
+// BatchResponse is the recipient's response manifest with a per-voucher acknowledgment
+type BatchResponse struct {
+	BatchID  string               `json:"batch_id"`
+	Vouchers []BatchResponseEntry `json:"vouchers"`
+}
+
+// BatchResponseEntry acknowledges (or rejects) one voucher from a batch
+type BatchResponseEntry struct {
+	GUID             string `json:"guid"`
+	Status           string `json:"status"` // "accepted" | "duplicate" | "rejected"
+	ReceiptID        string `json:"receipt_id"`
+	Message          string `json:"message"`
+	ReceiptSignature string `json:"receipt_signature"` // Owner's signature of the receipt statement, if the recipient signs receipts
+}
+
+// queuedVoucher is a row of the batch queue
+type queuedVoucher struct {
+	guid, serial, model string
+	hash                string // Content hash of the CBOR voucher
+	voucherFile         []byte
+}
+
+// NewVoucherBatchUploader creates a new batch uploader
+func NewVoucherBatchUploader(config *VoucherConfig, httpUploader *VoucherHTTPUploader, db *StationDB, receipts *UploadReceiptStore, catalog *UploadDestinationCatalog, notifier *Notifier) *VoucherBatchUploader {
+	return &VoucherBatchUploader{
+		config:   config,
+		http:     httpUploader,
+		db:       db,
+		store:    NewVoucherStore(db),
+		receipts: receipts,
+		catalog:  catalog,
+		notifier: notifier,
+	}
+}
+
+// Initialize creates the voucher_batch_queue table if it doesn't exist
+func (b *VoucherBatchUploader) Initialize(ctx context.Context) error {
+	_, err := b.db.db.ExecContext(ctx, `
+	CREATE TABLE IF NOT EXISTS voucher_batch_queue (
+		guid TEXT PRIMARY KEY,
+		recipient_url TEXT NOT NULL,
+		auth_profile TEXT NOT NULL,
+		serial TEXT NOT NULL,
+		model TEXT NOT NULL,
+		voucher_file BLOB NOT NULL,
+		queued_at INTEGER NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create voucher_batch_queue table: %w", err)
+	}
+	return b.db.addColumnIfMissing(ctx, "voucher_batch_queue", "voucher_hash", "TEXT")
+}
+
+// QueueDepth returns how many vouchers are waiting to be shipped, across every destination
+func (b *VoucherBatchUploader) QueueDepth(ctx context.Context) (int, error) {
+	if b == nil {
+		return 0, nil
+	}
+	var depth int
+	if err := b.db.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM voucher_batch_queue`).Scan(&depth); err != nil {
+		return 0, fmt.Errorf("failed to count queued vouchers: %w", err)
+	}
+	return depth, nil
+}
+
+// Enqueue adds a voucher to its destination's batch. A full batch is shipped in the background.
+func (b *VoucherBatchUploader) Enqueue(ctx context.Context, recipientURL, authProfile, serial, model, guid string, voucherFile []byte) error {
+	// The queue keeps the CBOR voucher in the voucher store and armors it again when shipping
+	data, err := decodeVoucherFile(voucherFile)
+	if err != nil {
+		return fmt.Errorf("failed to queue voucher %s for batch upload: %w", guid, err)
+	}
+	if _, err := b.store.write(ctx, "voucher_batch_queue", guid, data, func(tx *sql.Tx, hash string) error {
+		_, err := tx.ExecContext(ctx, `
+		INSERT OR REPLACE INTO voucher_batch_queue (guid, recipient_url, auth_profile, serial, model, voucher_file, voucher_hash, queued_at)
+		VALUES (?, ?, ?, ?, ?, x'', ?, ?)`,
+			guid, recipientURL, authProfile, serial, model, hash, time.Now().Unix())
+		return err
+	}); err != nil {
+		return fmt.Errorf("failed to queue voucher %s for batch upload: %w", guid, err)
+	}
+
+	var queued int
+	if err := b.db.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM voucher_batch_queue WHERE recipient_url = ? AND auth_profile = ?`,
+		recipientURL, authProfile).Scan(&queued); err != nil {
+		return fmt.Errorf("failed to count batch queue: %w", err)
+	}
+	fmt.Printf("📦 Queued voucher for %s for batch upload to %s (%d pending)\n", serialRules.Serial(serial), recipientURL, queued)
+
+	if queued >= b.maxVouchers() {
+		go withConfigRead(func() {
+			if err := b.flushDestination(context.Background(), recipientURL, authProfile); err != nil {
+				fmt.Printf("⚠️  Batch upload to %s failed: %v\n", recipientURL, err)
+			}
+		})
+	}
+	return nil
+}
+
+// Run ships pending batches every interval until ctx is cancelled, then makes a final flush
+func (b *VoucherBatchUploader) Run(ctx context.Context) {
+	ticker := time.NewTicker(b.interval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			withConfigRead(func() { b.FlushAll(ctx) })
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.Background(), b.config.VoucherUpload.Timeout)
+			withConfigRead(func() { b.FlushAll(flushCtx) })
+			cancel()
+			return
+		}
+	}
+}
+
+// FlushAll ships the pending batch for every destination
+func (b *VoucherBatchUploader) FlushAll(ctx context.Context) {
+	rows, err := b.db.db.QueryContext(ctx, `SELECT DISTINCT recipient_url, auth_profile FROM voucher_batch_queue`)
+	if err != nil {
+		fmt.Printf("⚠️  Failed to read batch queue: %v\n", err)
+		return
+	}
+	var destinations [][2]string
+	for rows.Next() {
+		var dest [2]string
+		if err := rows.Scan(&dest[0], &dest[1]); err != nil {
+			fmt.Printf("⚠️  Failed to read batch queue: %v\n", err)
+			break
+		}
+		destinations = append(destinations, dest)
+	}
+	_ = rows.Close()
+
+	for _, dest := range destinations {
+		if err := b.flushDestination(ctx, dest[0], dest[1]); err != nil {
+			fmt.Printf("⚠️  Batch upload to %s failed: %v\n", dest[0], err)
+		}
+	}
+}
+
+// flushDestination sends up to max_vouchers queued vouchers to one destination and
+// removes the ones the recipient acknowledged. Unacknowledged vouchers stay queued.
+func (b *VoucherBatchUploader) flushDestination(ctx context.Context, recipientURL, authProfile string) error {
+	b.flushMu.Lock()
+	defer b.flushMu.Unlock()
+
+	vouchers, err := b.loadQueued(ctx, recipientURL, authProfile)
+	if err != nil {
+		return err
+	}
+	if len(vouchers) == 0 {
+		return nil
+	}
+	// An open breaker leaves the batch queued until the destination recovers
+	if err := b.catalog.AllowURL(ctx, recipientURL); err != nil {
+		return err
+	}
+
+	batchID, err := newBatchID()
+	if err != nil {
+		return err
+	}
+
+	archive, contentType, err := b.buildArchive(batchID, vouchers)
+	if err != nil {
+		return err
+	}
+
+	resp, err := b.post(ctx, recipientURL, authProfile, batchID, contentType, archive, len(vouchers))
+	b.catalog.RecordResult(ctx, recipientURL, err)
+	if err != nil {
+		// Failed batches stay queued; a growing backlog is worth paging about
+		b.notifier.RecordFailure("batch_upload:"+recipientURL,
+			fmt.Sprintf("%d+ vouchers waiting: %v", len(vouchers), err))
+		return err
+	}
+	b.notifier.RecordSuccess("batch_upload:" + recipientURL)
+
+	byGUID := make(map[string]queuedVoucher, len(vouchers))
+	for _, qv := range vouchers {
+		byGUID[qv.guid] = qv
+	}
+
+	acked, rejected := 0, 0
+	for _, entry := range resp.Vouchers {
+		qv, ok := byGUID[entry.GUID]
+		if !ok {
+			continue
+		}
+		switch entry.Status {
+		case "accepted", "duplicate":
+			receipt := &UploadReceipt{
+				GUID:         qv.guid,
+				Serial:       qv.serial,
+				RecipientURL: recipientURL,
+				ReceiptID:    entry.ReceiptID,
+				Status:       entry.Status,
+				VoucherHash:  qv.hash,
+				UploadedAt:   time.Now(),
+				Signature:    entry.ReceiptSignature,
+			}
+			if err := receipt.verifyFile(qv.voucherFile); err != nil {
+				if b.config.VoucherUpload.RequireSignedReceipts {
+					// Resending won't make the owner sign; drop it like a rejection
+					fmt.Printf("❌ Batch %s: receipt for %s not signed by the owner: %v\n", batchID, serialRules.Serial(qv.serial), err)
+					rejected++
+					break
+				}
+				if receipt.Verification == ReceiptInvalid {
+					fmt.Printf("⚠️  Batch %s: receipt for %s not verified: %v\n", batchID, serialRules.Serial(qv.serial), err)
+				}
+			}
+			if b.receipts != nil {
+				if err := b.receipts.Save(ctx, receipt); err != nil {
+					fmt.Printf("⚠️  %v\n", err)
+				}
+			}
+			acked++
+		case "rejected", "error":
+			// Resending a rejected voucher won't help; drop it so it doesn't block the queue
+			fmt.Printf("❌ Batch %s: recipient rejected voucher for %s: %s\n", batchID, serialRules.Serial(qv.serial), entry.Message)
+			rejected++
+		default:
+			continue
+		}
+		if err := b.dequeue(ctx, qv.guid); err != nil {
+			fmt.Printf("⚠️  %v\n", err)
+		}
+	}
+
+	fmt.Printf("✅ Batch %s: %d of %d vouchers acknowledged by %s\n", batchID, acked, len(vouchers), recipientURL)
+	if pending := len(vouchers) - acked - rejected; pending > 0 {
+		fmt.Printf("⚠️  Batch %s: %d unacknowledged vouchers left queued for the next batch\n", batchID, pending)
+	}
+	return nil
+}
+
+// loadQueued returns the oldest queued vouchers for a destination, up to the batch size
+func (b *VoucherBatchUploader) loadQueued(ctx context.Context, recipientURL, authProfile string) ([]queuedVoucher, error) {
+	rows, err := b.db.db.QueryContext(ctx, `
+	SELECT q.guid, q.serial, q.model, COALESCE(q.voucher_hash, ''), COALESCE(v.voucher, q.voucher_file)
+	FROM voucher_batch_queue q LEFT JOIN voucher_blobs v ON v.hash = q.voucher_hash
+	WHERE q.recipient_url = ? AND q.auth_profile = ?
+	ORDER BY q.queued_at LIMIT ?`,
+		recipientURL, authProfile, b.maxVouchers())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read batch queue: %w", err)
+	}
+	defer rows.Close()
+
+	var vouchers []queuedVoucher
+	for rows.Next() {
+		var qv queuedVoucher
+		var data []byte
+		if err := rows.Scan(&qv.guid, &qv.serial, &qv.model, &qv.hash, &data); err != nil {
+			return nil, fmt.Errorf("failed to read batch queue: %w", err)
+		}
+		if qv.hash == "" {
+			// Queued before the voucher store: the row holds the armored file
+			qv.voucherFile = data
+			if cborData, err := decodeVoucherFile(data); err == nil {
+				qv.hash = voucherHash(cborData)
+			}
+		} else {
+			if err := checkVoucherHash(qv.hash, data); err != nil {
+				return nil, fmt.Errorf("batch queue voucher %s: %w", qv.guid, err)
+			}
+			qv.voucherFile = []byte(armorVoucher(data))
+		}
+		vouchers = append(vouchers, qv)
+	}
+	return vouchers, rows.Err()
+}
+
+// dequeue removes an acknowledged voucher from the queue
+func (b *VoucherBatchUploader) dequeue(ctx context.Context, guid string) error {
+	return b.store.remove(ctx, "voucher_batch_queue", guid)
+}
+
+// buildArchive packs the vouchers and manifest.json into a zip or tar archive
+func (b *VoucherBatchUploader) buildArchive(batchID string, vouchers []queuedVoucher) ([]byte, string, error) {
+	manifest := BatchManifest{
+		BatchID:      batchID,
+		Manufacturer: b.http.stationID,
+		CreatedAt:    time.Now().UTC().Format(time.RFC3339),
+	}
+	files := make(map[string][]byte, len(vouchers)+1)
+	names := make([]string, 0, len(vouchers)+1)
+	for _, qv := range vouchers {
+		name := qv.guid + ".fdoov"
+		manifest.Vouchers = append(manifest.Vouchers, BatchManifestEntry{
+			GUID:   qv.guid,
+			Serial: qv.serial,
+			Model:  qv.model,
+			File:   name,
+			SHA256: qv.hash,
+		})
+		files[name] = qv.voucherFile
+		names = append(names, name)
+	}
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to encode batch manifest: %w", err)
+	}
+	files["manifest.json"] = manifestJSON
+	names = append([]string{"manifest.json"}, names...)
+
+	var buf bytes.Buffer
+	switch b.config.VoucherUpload.Batch.Format {
+	case "tar":
+		tw := tar.NewWriter(&buf)
+		for _, name := range names {
+			hdr := &tar.Header{Name: name, Mode: 0o644, Size: int64(len(files[name])), ModTime: time.Now()}
+			if err := tw.WriteHeader(hdr); err != nil {
+				return nil, "", fmt.Errorf("failed to write batch archive: %w", err)
+			}
+			if _, err := tw.Write(files[name]); err != nil {
+				return nil, "", fmt.Errorf("failed to write batch archive: %w", err)
+			}
+		}
+		if err := tw.Close(); err != nil {
+			return nil, "", fmt.Errorf("failed to write batch archive: %w", err)
+		}
+		return buf.Bytes(), "application/x-tar", nil
+	case "", "zip":
+		zw := zip.NewWriter(&buf)
+		for _, name := range names {
+			w, err := zw.Create(name)
+			if err != nil {
+				return nil, "", fmt.Errorf("failed to write batch archive: %w", err)
+			}
+			if _, err := w.Write(files[name]); err != nil {
+				return nil, "", fmt.Errorf("failed to write batch archive: %w", err)
+			}
+		}
+		if err := zw.Close(); err != nil {
+			return nil, "", fmt.Errorf("failed to write batch archive: %w", err)
+		}
+		return buf.Bytes(), "application/zip", nil
+	default:
+		return nil, "", fmt.Errorf("unsupported batch format: %s", b.config.VoucherUpload.Batch.Format)
+	}
+}
+
+// post sends the archive to the recipient and parses its response manifest
+func (b *VoucherBatchUploader) post(ctx context.Context, recipientURL, authProfile, batchID, contentType string, archive []byte, count int) (*BatchResponse, error) {
+	profile, err := b.http.lookupProfile(authProfile)
+	if err != nil {
+		return nil, err
+	}
+	client, err := b.http.clientFor(authProfile, profile, recipientURL)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := b.http.encodeBody(profile, archive)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, recipientURL, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create batch upload request: %w", err)
+	}
+	timestamp := time.Now().UTC().Format(time.RFC3339)
+	req.Header.Set("Content-Type", contentType)
+	if encoding := b.http.contentEncoding(profile); encoding != "" {
+		req.Header.Set("Content-Encoding", encoding)
+	}
+	req.Header.Set("X-FDO-Version", "1.0")
+	req.Header.Set("X-FDO-Client-ID", b.http.stationID)
+	req.Header.Set("X-FDO-Batch-Count", strconv.Itoa(count))
+	req.Header.Set("Idempotency-Key", batchID)
+	if err := applyUploadAuth(req, profile, timestamp, payload); err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("📤 Uploading batch %s (%d vouchers) to %s\n", batchID, count, recipientURL)
+	if err := faultInjector.Inject(ctx, FaultStageUpload); err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("batch upload request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024*1024))
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
+		return nil, fmt.Errorf("%w: voucher recipient returned HTTP %d: %s", ErrUploadRejected, resp.StatusCode, string(respBody))
+	}
+
+	var parsed BatchResponse
+	if err := json.Unmarshal(respBody, &parsed); err != nil {
+		return nil, fmt.Errorf("invalid batch response manifest: %w", err)
+	}
+	return &parsed, nil
+}
+
+// maxVouchers returns the batch size threshold
+func (b *VoucherBatchUploader) maxVouchers() int {
+	if b.config.VoucherUpload.Batch.MaxVouchers > 0 {
+		return b.config.VoucherUpload.Batch.MaxVouchers
+	}
+	return 100
+}
+
+// interval returns the batch shipping interval
+func (b *VoucherBatchUploader) interval() time.Duration {
+	if b.config.VoucherUpload.Batch.Interval > 0 {
+		return b.config.VoucherUpload.Batch.Interval
+	}
+	return 5 * time.Minute
+}
+
+// newBatchID returns a random batch identifier
+func newBatchID() (string, error) {
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return "", fmt.Errorf("failed to generate batch ID: %w", err)
+	}
+	return hex.EncodeToString(id[:]), nil
+}
@@ -7,13 +7,15 @@ package main
 import (
 	"context"
 	"crypto"
-	"crypto/ecdsa"
-	"crypto/rsa"
 	"crypto/x509"
 	"encoding/pem"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/fido-device-onboard/go-fdo"
+	"github.com/fido-device-onboard/go-fdo/cbor"
 	"github.com/fido-device-onboard/go-fdo/custom"
 )
 
@@ -25,6 +27,21 @@ type VoucherCallbackService struct {
 	voucherUploadService  *VoucherUploadService
 	voucherDiskService    *VoucherDiskService
 	oveExtraDataService   *OVEExtraDataService
+	quotaService          *QuotaService
+	schedule              *ManufacturingSchedule
+	auditLog              *AuditLog
+	batchService          *BatchService
+	hashPolicy            *VoucherHashPolicy
+	revocations           *OwnerRevocations        // nil = no owners revoked
+	recorder              *SessionRecorder         // nil = sessions not recorded
+	status                *StationStatus           // nil = outcomes not counted
+	transfers             *VoucherTransferService  // nil = vouchers not kept for transfer
+	anomalies             *SignoverAnomalyDetector // nil = signover targets not tracked
+	claimURLs             *ClaimURLs               // nil = no claim URLs
+	overrides             *PolicyOverrides         // nil = no checks waived
+	proofs                *OwnerKeyProofs          // nil = owner keys need no proof of possession
+	dryRun                *DryRun                  // nil = no dry runs
+	pipeline              *VoucherPipeline         // nil = retried sessions start over
 	signingKey            crypto.Signer
 }
 
@@ -36,6 +53,21 @@ func NewVoucherCallbackService(
 	voucherUploadService *VoucherUploadService,
 	voucherDiskService *VoucherDiskService,
 	oveExtraDataService *OVEExtraDataService,
+	quotaService *QuotaService,
+	schedule *ManufacturingSchedule,
+	auditLog *AuditLog,
+	batchService *BatchService,
+	hashPolicy *VoucherHashPolicy,
+	revocations *OwnerRevocations,
+	recorder *SessionRecorder,
+	status *StationStatus,
+	transfers *VoucherTransferService,
+	anomalies *SignoverAnomalyDetector,
+	claimURLs *ClaimURLs,
+	overrides *PolicyOverrides,
+	proofs *OwnerKeyProofs,
+	dryRun *DryRun,
+	pipeline *VoucherPipeline,
 	signingKey crypto.Signer,
 ) *VoucherCallbackService {
 	return &VoucherCallbackService{
@@ -45,12 +77,27 @@ func NewVoucherCallbackService(
 		voucherUploadService:  voucherUploadService,
 		voucherDiskService:    voucherDiskService,
 		oveExtraDataService:   oveExtraDataService,
+		quotaService:          quotaService,
+		schedule:              schedule,
+		auditLog:              auditLog,
+		batchService:          batchService,
+		hashPolicy:            hashPolicy,
+		revocations:           revocations,
+		recorder:              recorder,
+		status:                status,
+		transfers:             transfers,
+		anomalies:             anomalies,
+		claimURLs:             claimURLs,
+		overrides:             overrides,
+		proofs:                proofs,
+		dryRun:                dryRun,
+		pipeline:              pipeline,
 		signingKey:            signingKey,
 	}
 }
 
 // BeforeVoucherPersist is called before a voucher is persisted to storage
-func (v *VoucherCallbackService) BeforeVoucherPersist(ctx context.Context, sessionState interface{}, ov *fdo.Voucher) (bool, error) {
+func (v *VoucherCallbackService) BeforeVoucherPersist(ctx context.Context, sessionState interface{}, ov *fdo.Voucher) (persist bool, err error) {
 	// Get device info from session state
 	serial, model, _ := v.getDeviceInfo(ctx, sessionState, ov)
 
@@ -67,7 +114,7 @@ func (v *VoucherCallbackService) BeforeVoucherPersist(ctx context.Context, sessi
 		fmt.Printf("🔍 DEBUG: Session state supports DeviceSelfInfo interface\n")
 		devInfo, err := deviceSelfInfoStore.DeviceSelfInfo(ctx)
 		if err == nil {
-			fmt.Printf("🔍 DEBUG: Got device info from session: serial=%s, deviceInfo=%s\n", devInfo.SerialNumber, devInfo.DeviceInfo)
+			fmt.Printf("🔍 DEBUG: Got device info from session: serial=%s, deviceInfo=%s\n", serialRules.Serial(devInfo.SerialNumber), serialRules.Model(devInfo.DeviceInfo))
 			serial = devInfo.SerialNumber
 			model = devInfo.DeviceInfo
 		} else {
@@ -87,45 +134,124 @@ func (v *VoucherCallbackService) BeforeVoucherPersist(ctx context.Context, sessi
 
 	guidStr := fmt.Sprintf("%x", ov.Header.Val.GUID[:])
 
-	fmt.Printf("🔍 DEBUG: Final values - serial=%s, model=%s, guid=%s\n", serial, model, guidStr)
+	// Filled in once the owner is known, for per-product metrics
+	var uploadProfile string // Upload auth profile named by the owner entry
+	var customer string      // Customer/licensee the device is built for
+
+	// Hold the pipeline to the session time budget so a slow dependency can't pin the DI handler
+	started := time.Now()
+	ctx, cancel := startSessionBudget(ctx, &v.config.TimeBudget)
+	defer cancel()
+	defer func() {
+		v.status.RecordDI(ProductLabels{Customer: customer, Profile: uploadProfile, Model: model}, time.Since(started), err)
+		if errors.Is(err, context.DeadlineExceeded) {
+			v.auditLog.Record(context.Background(), AuditEvent{
+				Event:  "di_time_budget_exceeded",
+				Serial: serial,
+				GUID:   guidStr,
+				Model:  model,
+				Detail: err.Error(),
+			})
+		}
+	}()
+
+	// Anything the pipeline writes goes to a temp dir of this session, removed when it ends
+	ctx, removeTempDir := startSessionTempDir(ctx, guidStr)
+	defer removeTempDir()
+
+	fmt.Printf("🔍 DEBUG: Final values - serial=%s, model=%s, guid=%s\n", serialRules.Serial(serial), serialRules.Model(model), guidStr)
 	fmt.Printf("🔍 DEBUG: VoucherSigning.Mode=%v, VoucherUpload.Enabled=%v, PersistToDB=%v\n",
 		v.config.VoucherSigning.Mode, v.config.VoucherUpload.Enabled, v.config.PersistToDB)
 
+	// Link the voucher to the open production batch; DI is refused without one when batches are required
+	batch, err := v.batchService.Current(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	// A session retried after a crash resumes after the last stage it completed
+	run, err := v.pipeline.Resumable(ctx, guidStr, ov)
+	if err != nil {
+		return false, err
+	}
+	if run != nil {
+		customer, uploadProfile = run.Customer, run.UploadProfile
+		resumed, nextOwner, err := run.Resume()
+		if err != nil {
+			return false, err
+		}
+		*ov = *resumed
+		v.auditLog.Record(ctx, AuditEvent{
+			Event:    "di_pipeline_resumed",
+			Serial:   run.Serial,
+			GUID:     guidStr,
+			Customer: customer,
+			Model:    run.Model,
+			Detail:   fmt.Sprintf("retried session resumed after stage %s", run.Stage),
+		})
+		return v.deliver(ctx, run, ov, nextOwner, batch)
+	}
+
 	// 1. Get owner signover key first (who we're signing TO)
 	var nextOwner crypto.PublicKey
-	var err error
 	var didURL string // Store DID URL for upload
+	var ownerChain []*x509.Certificate
+	var keyEncoding string // Owner key encoding in the new voucher entry; empty = go-fdo default
 
 	// Owner signover logic - get the public key of the recipient we're signing over TO
 	switch v.config.OwnerSignover.Mode {
 	case "static":
 		// Static mode: use configured public key or DID for all devices
+		customer = v.config.OwnerSignover.Customer
 		if v.config.OwnerSignover.StaticDID != "" {
-			// Handle static DID
+			// Resolve the static DID like a DID returned by the owner key command
 			fmt.Printf("🔧 DEBUG: Using static DID for signover: %s\n", v.config.OwnerSignover.StaticDID)
-			// TODO: Implement DID resolution for static case
-			fmt.Printf("⚠️  Static DID resolution not yet implemented\n")
+			ownerCtx, cancel := budgetStage(ctx, BudgetStageOwnerKey)
+			result, err := v.ownerKeyService.ResolveOwner(ownerCtx, v.config.OwnerSignover.StaticDID, v.config.OwnerSignover.KeyEncoding)
+			cancel()
+			if err != nil {
+				return false, fmt.Errorf("failed to resolve static owner DID: %w", err)
+			}
+			nextOwner, ownerChain, didURL, keyEncoding = result.PublicKey, result.CertChain, result.DIDURL, result.KeyEncoding
+			uploadProfile = v.config.OwnerSignover.UploadAuthProfile
 		} else if v.config.OwnerSignover.StaticPublicKey != "" {
 			// Handle static PEM key (existing logic)
 			nextOwner, err = parseStaticPublicKey(v.config.OwnerSignover.StaticPublicKey)
 			if err != nil {
 				return false, fmt.Errorf("failed to parse static public key: %w", err)
 			}
+			ownerChain, err = parseCertificateChainPEM([]byte(v.config.OwnerSignover.StaticPublicKey))
+			if err != nil {
+				return false, fmt.Errorf("failed to parse static certificate chain: %w", err)
+			}
+			keyEncoding = v.config.OwnerSignover.KeyEncoding
 			fmt.Printf("🔧 DEBUG: Using static owner key for signover\n")
+			uploadProfile = v.config.OwnerSignover.UploadAuthProfile
 		} else {
-			fmt.Printf("🔧 DEBUG: No static public key or DID configured - no owner signover\n")
+			// Not signing over is mode "none", never a side effect of a missing key
+			return false, fmt.Errorf("owner signover mode static has no static_public_key or static_did")
 		}
 
 	case "dynamic":
 		// Dynamic mode: per-device/customer public keys via callback
 		if v.config.OwnerSignover.ExternalCommand != "" {
-			ownerKeyResult, err := v.ownerKeyService.GetOwnerKey(ctx, serial, model)
+			ownerCtx, cancel := budgetStage(ctx, BudgetStageOwnerKey)
+			var lot string
+			if batch != nil {
+				lot = batch.LotNumber
+			}
+			ownerKeyResult, err := v.ownerKeyService.GetOwnerKey(ownerCtx, serial, model, lot)
+			cancel()
 			if err != nil {
 				return false, fmt.Errorf("failed to get dynamic owner key: %w", err)
 			}
 			// Convert to crypto.PublicKey
 			nextOwner = ownerKeyResult.PublicKey.(crypto.PublicKey)
 			didURL = ownerKeyResult.DIDURL // Store DID URL for upload
+			uploadProfile = ownerKeyResult.UploadAuthProfile
+			customer = ownerKeyResult.Customer
+			ownerChain = ownerKeyResult.CertChain
+			keyEncoding = ownerKeyResult.KeyEncoding
 			fmt.Printf("🔧 DEBUG: Using dynamic owner key for signover\n")
 			// Store DID URL for upload if available
 			if ownerKeyResult.DIDURL != "" {
@@ -135,10 +261,163 @@ func (v *VoucherCallbackService) BeforeVoucherPersist(ctx context.Context, sessi
 			return false, fmt.Errorf("dynamic mode enabled but no external command configured")
 		}
 
+	case "none":
+		// Explicitly configured: the voucher stays with the manufacturer and is flagged below
+		customer = v.config.OwnerSignover.Customer
+
 	default:
-		fmt.Printf("🔧 DEBUG: Unsupported owner signover mode: %s - no owner signover\n", v.config.OwnerSignover.Mode)
+		return false, fmt.Errorf("unsupported owner signover mode %q", v.config.OwnerSignover.Mode)
+	}
+
+	// Apply the signover policy rules, which can refuse the device or replace
+	// what owner signover decided
+	policyVars := map[string]string{
+		"serial":              serial,
+		"model":               model,
+		"guid":                guidStr,
+		"customer":            customer,
+		"upload_auth_profile": uploadProfile,
+		"recipient_url":       didURL,
+		"owner_key":           ownerKeyType(nextOwner),
+	}
+	if batch != nil {
+		policyVars["lot"], policyVars["batch"] = batch.LotNumber, batch.ID
+	}
+	policy, err := evaluateSignoverPolicy(v.config.Policies, policyVars)
+	if err == nil && policy.Owner != "" {
+		var result *OwnerKeyResult
+		if result, err = v.ownerKeyService.ResolveOwner(ctx, policy.Owner, policy.KeyEncoding); err == nil {
+			nextOwner, ownerChain, didURL, keyEncoding = result.PublicKey, result.CertChain, result.DIDURL, result.KeyEncoding
+		}
+	}
+	if err == nil {
+		if policy.Customer != "" {
+			customer = policy.Customer
+		}
+		if policy.UploadAuthProfile != "" {
+			uploadProfile = policy.UploadAuthProfile
+		}
+		err = policy.Check(nextOwner)
+		if errors.Is(err, ErrSignoverPolicy) && v.overrides.Allow(ctx, OverrideSignoverPolicy, serial, guidStr, err) {
+			err = nil
+		}
+	}
+	if err != nil {
+		v.auditLog.Record(ctx, AuditEvent{
+			Event:    "di_rejected_policy",
+			Serial:   serial,
+			GUID:     guidStr,
+			Customer: customer,
+			Model:    model,
+			Detail:   err.Error(),
+		})
+		return false, err
+	}
+	if len(policy.Rules) > 0 {
+		fmt.Printf("📏 Signover policies for %s: %s\n", serialRules.Serial(serial), strings.Join(policy.Rules, ", "))
 	}
 
+	// A dry run goes through every stage but keeps and delivers nothing
+	var report *DryRunReport
+	if v.dryRun.Applies(uploadProfile) {
+		report = v.dryRun.Start(serial, model, guidStr, customer, uploadProfile)
+		defer func() { v.dryRun.Finish(ctx, report, ov, err) }()
+	} else {
+		// Keep the voucher as DI created it, before signover, for "voucher replay"
+		v.recorder.Record(ctx, serial, model, guidStr, ov)
+	}
+
+	// Encode the owner key the way the recipient parses it: the owner entry's or
+	// DID's choice, else the one configured on its upload auth profile
+	if keyEncoding == "" && uploadProfile != "" {
+		keyEncoding = v.config.UploadAuthProfiles[uploadProfile].OwnerKeyEncoding
+	}
+	if nextOwner != nil {
+		nextOwner, err = selectNextOwner(nextOwner, ownerChain, keyEncoding)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	// Refuse owners revoked by the central management plane
+	if err := v.revocations.Check(customer, didURL, nextOwner); err != nil {
+		v.auditLog.Record(ctx, AuditEvent{
+			Event:    "di_rejected_revoked_owner",
+			Serial:   serial,
+			GUID:     guidStr,
+			Customer: customer,
+			Model:    model,
+			Detail:   err.Error(),
+		})
+		return false, err
+	}
+
+	// Refuse owner keys whose owner hasn't proven it holds the private key
+	if err := v.proofs.Check(customer, nextOwner); err != nil {
+		v.auditLog.Record(ctx, AuditEvent{
+			Event:    "di_rejected_unproven_owner",
+			Serial:   serial,
+			GUID:     guidStr,
+			Customer: customer,
+			Model:    model,
+			Detail:   err.Error(),
+		})
+		return false, err
+	}
+
+	// Refuse devices and owner keys that don't use the configured voucher hash
+	if err := v.checkHashPolicy(ov, nextOwner); err != nil {
+		v.auditLog.Record(ctx, AuditEvent{
+			Event:    "di_rejected_hash",
+			Serial:   serial,
+			GUID:     guidStr,
+			Customer: customer,
+			Model:    model,
+			Detail:   err.Error(),
+		})
+		return false, err
+	}
+
+	// Refuse after-hours builds outside the configured shift windows
+	if err := v.schedule.Check(stationClock.Now(), customer, model); err != nil && !v.overrides.Allow(ctx, OverrideSchedule, serial, guidStr, err) {
+		v.auditLog.Record(ctx, AuditEvent{
+			Event:    "di_rejected_schedule",
+			Serial:   serial,
+			GUID:     guidStr,
+			Customer: customer,
+			Model:    model,
+			Detail:   err.Error(),
+		})
+		return false, err
+	}
+
+	if report != nil {
+		report.OwnerKey = "none"
+		if nextOwner != nil {
+			report.OwnerKey = ownerKeyType(nextOwner) + " " + ownerKeySHA256(nextOwner)
+		}
+		report.stage("owner", "%s %s, policies %v", v.config.OwnerSignover.Mode, report.OwnerKey, policy.Rules)
+	}
+
+	// Count the device against manufacturing quotas, giving the unit back if the pipeline fails
+	var reservation *QuotaReservation
+	if report != nil {
+		report.stage("quota", "not counted")
+	} else {
+		reservation, err = v.quotaService.Reserve(ctx, customer, model)
+		if errors.Is(err, ErrQuotaExhausted) && v.overrides.Allow(ctx, OverrideQuota, serial, guidStr, err) {
+			reservation, err = v.quotaService.ReserveOverLimit(ctx, customer, model)
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+	defer func() {
+		if err != nil {
+			v.quotaService.Release(context.Background(), reservation)
+		}
+	}()
+
 	// 2. Voucher signing if configured
 	if v.config.VoucherSigning.Mode != "" {
 
@@ -146,6 +425,18 @@ func (v *VoucherCallbackService) BeforeVoucherPersist(ctx context.Context, sessi
 		var extraData map[int][]byte
 		if v.oveExtraDataService != nil {
 			extraData, err = v.oveExtraDataService.GetOVEExtraData(ctx, serial, model)
+			if errors.Is(err, ErrOVEExtraInvalid) {
+				// The "reject" policy: no voucher rather than one owners can't parse
+				v.auditLog.Record(ctx, AuditEvent{
+					Event:    "di_rejected_extra_data",
+					Serial:   serial,
+					GUID:     guidStr,
+					Customer: customer,
+					Model:    model,
+					Detail:   err.Error(),
+				})
+				return false, err
+			}
 			if err != nil {
 				fmt.Printf("⚠️  Failed to get OVEExtra data: %v\n", err)
 				// Continue without extra data
@@ -153,41 +444,62 @@ func (v *VoucherCallbackService) BeforeVoucherPersist(ctx context.Context, sessi
 			}
 		}
 
+		// Attach the operator who opened the batch
+		if batch != nil && batch.OperatorID != "" {
+			extraData, err = v.oveExtraDataService.addOperator(extraData, batch.OperatorID)
+			if err != nil {
+				return false, err
+			}
+		}
+
+		// Carry the head of the station's audit chain out with the device
+		extraData, err = v.oveExtraDataService.addAuditAnchor(extraData)
+		if err != nil {
+			return false, err
+		}
+
 		// Set session state for voucher signing service to access manufacturer keys
 		v.voucherSigningService.SetSessionState(sessionState)
 
 		// Always call voucher signing - default mode is "internal" which lets go-fdo handle it
 		fmt.Printf("🔐 DEBUG: About to call SignVoucher with mode=%s, nextOwner=%v\n", v.config.VoucherSigning.Mode, nextOwner != nil)
-		signedVoucher, err := v.voucherSigningService.SignVoucher(ctx, ov, nextOwner, serial, model, extraData)
+		signCtx, cancel := budgetStage(ctx, BudgetStageSigning)
+		signedVoucher, err := v.voucherSigningService.SignVoucher(signCtx, ov, nextOwner, serial, model, extraData)
+		cancel()
+		if errors.Is(err, ErrSignerUnavailable) && policy.AllowFallbackSigner && v.voucherSigningService.HasFallback() {
+			// The HSM is down and this SKU's policy accepts the software key
+			hsmErr := err
+			signedVoucher, err = v.voucherSigningService.SignVoucherFallback(ov, nextOwner, serial, extraData, hsmErr)
+			event := "di_signed_fallback"
+			detail := fmt.Sprintf("HSM unreachable (%v); signed with the fallback key (policy %s)", hsmErr, strings.Join(policy.Rules, ", "))
+			if err != nil {
+				event, detail = "di_fallback_signing_failed", fmt.Sprintf("HSM unreachable (%v); fallback key failed: %v", hsmErr, err)
+			}
+			v.auditLog.Record(ctx, AuditEvent{
+				Event:    event,
+				Serial:   serial,
+				GUID:     guidStr,
+				Customer: customer,
+				Model:    model,
+				Detail:   detail,
+			})
+		}
 		if err != nil {
+			recordExtendFailure(v.config.VoucherSigning.FailureDirectory, v.config.VoucherSigning.Mode, serial, model, guidStr, ov, nextOwner, extraData, err)
 			return false, fmt.Errorf("voucher signing failed: %w", err)
 		}
 		*ov = *signedVoucher // Replace with signed version
+		if report != nil {
+			report.stage("signing", "signed in %s mode, %d extra data entries", v.config.VoucherSigning.Mode, len(extraData))
+		}
 	} else {
 		// No voucher signing configured, but we still might have owner signover
 		if nextOwner != nil {
 			// We have an owner key but no voucher signing - extend voucher directly
-			var extended *fdo.Voucher
-
-			// Use type assertion with the specific types that satisfy the constraint
-			switch key := nextOwner.(type) {
-			case *rsa.PublicKey:
-				extended, err = fdo.ExtendVoucher(ov, nil, key, nil)
-				if err != nil {
-					return false, fmt.Errorf("failed to extend voucher to owner: %w", err)
-				}
-			case *ecdsa.PublicKey:
-				extended, err = fdo.ExtendVoucher(ov, nil, key, nil)
-				if err != nil {
-					return false, fmt.Errorf("failed to extend voucher to owner: %w", err)
-				}
-			case []*x509.Certificate:
-				extended, err = fdo.ExtendVoucher(ov, nil, key, nil)
-				if err != nil {
-					return false, fmt.Errorf("failed to extend voucher to owner: %w", err)
-				}
-			default:
-				return false, fmt.Errorf("unsupported owner key type: %T", nextOwner)
+			extended, err := extendVoucherTo(ov, nil, nextOwner, nil)
+			if err != nil {
+				recordExtendFailure(v.config.VoucherSigning.FailureDirectory, "none", serial, model, guidStr, ov, nextOwner, nil, err)
+				return false, fmt.Errorf("failed to extend voucher to owner: %w", err)
 			}
 
 			*ov = *extended // Replace with signed version
@@ -195,18 +507,126 @@ func (v *VoucherCallbackService) BeforeVoucherPersist(ctx context.Context, sessi
 		}
 	}
 
-	// 2. Voucher upload if configured
-	if v.config.VoucherUpload.Enabled {
-		if err := v.voucherUploadService.UploadVoucher(ctx, serial, model, guidStr, ov, didURL); err != nil {
-			return false, fmt.Errorf("voucher upload failed: %w", err)
+	// Everything from here on keeps or delivers the voucher; a dry run only reports it
+	if report != nil {
+		if nextOwner != nil {
+			if err := checkOwnerKeyEncoding(ov, keyEncoding); err != nil {
+				return false, err
+			}
 		}
+		report.stage("claim_url", "not generated")
+		v.dryRun.StubUpload(report, v.config, didURL, uploadProfile)
+		report.stage("batch", "not recorded")
+		if err := v.dryRun.SaveToTemp(report, ov); err != nil {
+			return false, err
+		}
+		return false, nil
 	}
 
-	// 3. Save to disk if configured
-	if v.config.SaveToDisk.Directory != "" {
-		if err := v.voucherDiskService.SaveVoucherToDisk(ov, serial); err != nil {
-			fmt.Printf("⚠️  Failed to save voucher to disk: %v\n", err)
-			// Don't fail the entire operation for disk save errors
+	if nextOwner != nil {
+		if err := checkOwnerKeyEncoding(ov, keyEncoding); err != nil {
+			return false, err
+		}
+	}
+
+	// From here on a retried session resumes instead of signing again. The
+	// device has a voucher now, so its quota unit stays counted if a later
+	// stage fails.
+	run = &PipelineRun{
+		GUID:          guidStr,
+		Serial:        serial,
+		Model:         model,
+		Customer:      customer,
+		UploadProfile: uploadProfile,
+		DIDURL:        didURL,
+		Extended:      nextOwner != nil,
+	}
+	if err := v.pipeline.Start(ctx, run, ov); err != nil {
+		return false, err
+	}
+	reservation = nil
+	return v.deliver(ctx, run, ov, nextOwner, batch)
+}
+
+// deliver runs the stages of the voucher pipeline after signing that run
+// hasn't completed yet, recording each one as it completes: upload, then the
+// batch record, transfer copy and disk saves
+func (v *VoucherCallbackService) deliver(ctx context.Context, run *PipelineRun, ov *fdo.Voucher, nextOwner crypto.PublicKey, batch *Batch) (bool, error) {
+	serial, model, guidStr, customer := run.Serial, run.Model, run.GUID, run.Customer
+
+	if run.Stage == PipelineSigned {
+		// The URL the customer scans to claim the device, for its label
+		claimURL, err := v.claimURLs.Generate(ctx, guidStr, serial, model, customer)
+		if err != nil {
+			return false, err
+		}
+
+		// Alert on a never-before-seen owner for this customer/model
+		if nextOwner != nil {
+			if err := v.anomalies.Observe(ctx, serial, guidStr, customer, model, run.DIDURL, claimURL, nextOwner); err != nil {
+				fmt.Printf("⚠️  Failed to track signover target: %v\n", err)
+			}
+		}
+
+		// 2. Voucher upload if configured; receipts keep a repeated upload from duplicating
+		if v.config.VoucherUpload.Enabled {
+			uploadCtx, cancel := budgetStage(ctx, BudgetStageUpload)
+			err := v.voucherUploadService.UploadVoucher(uploadCtx, serial, model, guidStr, ov, run.DIDURL, run.UploadProfile, customer)
+			cancel()
+			if err != nil {
+				return false, fmt.Errorf("voucher upload failed: %w", err)
+			}
+		}
+		if err := v.pipeline.Advance(ctx, run, PipelineUploaded); err != nil {
+			return false, err
+		}
+	}
+
+	if run.Stage == PipelineUploaded {
+		// Record the voucher in its batch so a lot can be traced for recalls; its
+		// content hash lets owner services check they received these exact bytes
+		data, err := cbor.Marshal(ov)
+		if err != nil {
+			return false, fmt.Errorf("failed to encode voucher %s: %w", guidStr, err)
+		}
+		if err := v.batchService.RecordVoucher(ctx, batch, guidStr, serial, model, customer, voucherHash(data)); err != nil {
+			return false, err
+		}
+
+		// Keep the final voucher so it can be exported to another station
+		if err := v.transfers.Keep(ctx, batch, serial, model, customer, guidStr, ov); err != nil {
+			return false, err
+		}
+
+		// 3. Save to disk if configured; only destinations with on_error "fail" fail the device
+		diskTarget := DiskTarget{
+			Serial:   serial,
+			Model:    model,
+			GUID:     guidStr,
+			Customer: customer,
+			Profile:  run.UploadProfile,
+		}
+		if batch != nil {
+			diskTarget.BatchID, diskTarget.LotNumber = batch.ID, batch.LotNumber
+		}
+		if err := v.voucherDiskService.SaveVoucherToDisk(ctx, ov, diskTarget); err != nil {
+			return false, err
+		}
+
+		// Flag every voucher that leaves the station without an owner
+		if !run.Extended {
+			v.status.RecordUnextendedVoucher()
+			v.auditLog.Record(ctx, AuditEvent{
+				Event:    "di_voucher_not_extended",
+				Serial:   serial,
+				GUID:     guidStr,
+				Customer: customer,
+				Model:    model,
+				Detail:   fmt.Sprintf("owner signover mode %s: voucher not extended to an owner", v.config.OwnerSignover.Mode),
+			})
+		}
+		if err := v.pipeline.Advance(ctx, run, PipelineDone); err != nil {
+			return false, err
 		}
 	}
 
@@ -216,6 +636,17 @@ func (v *VoucherCallbackService) BeforeVoucherPersist(ctx context.Context, sessi
 	return result, nil
 }
 
+// checkHashPolicy checks the new voucher and, when there is one, the next owner key against the hash policy
+func (v *VoucherCallbackService) checkHashPolicy(ov *fdo.Voucher, nextOwner crypto.PublicKey) error {
+	if err := v.hashPolicy.CheckVoucher(ov); err != nil {
+		return err
+	}
+	if nextOwner == nil {
+		return nil
+	}
+	return v.hashPolicy.CheckOwnerKey(nextOwner)
+}
+
 // getDeviceInfo extracts serial, model, and guid information from the session state or voucher
 func (v *VoucherCallbackService) getDeviceInfo(ctx context.Context, sessionState interface{}, ov *fdo.Voucher) (string, string, string) {
 	var serial, model string
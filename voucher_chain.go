@@ -0,0 +1,368 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fido-device-onboard/go-fdo"
+	"github.com/fido-device-onboard/go-fdo/cbor"
+	"github.com/fido-device-onboard/go-fdo/protocol"
+)
+
+// maxChainEvents bounds the audit events returned with a voucher chain
+const maxChainEvents = 200
+
+// errVoucherUndecodable marks a stored voucher that is not a valid voucher
+var errVoucherUndecodable = errors.New("stored voucher does not decode")
+
+// VoucherChain is the ownership chain of a stored voucher, for support staff
+// to confirm who a voucher is signed over to
+type VoucherChain struct {
+	GUID       string       `json:"guid"`
+	Source     string       `json:"source"` // Where the voucher was found, as in X-Voucher-Source
+	DeviceInfo string       `json:"device_info"`
+	Serial     string       `json:"serial,omitempty"`
+	Model      string       `json:"model,omitempty"`
+	Customer   string       `json:"customer,omitempty"`
+	CreatedAt  *time.Time   `json:"created_at,omitempty"` // When the station issued it, from its batch
+	Links      []ChainLink  `json:"links"`                // Manufacturer key first, then one per voucher entry
+	Upload     *ChainUpload `json:"upload,omitempty"`
+	Events     []ChainEvent `json:"events"` // Audit events of the GUID, oldest first
+}
+
+// ChainLink is one key of the ownership chain
+type ChainLink struct {
+	Index       int        `json:"index"` // 0 = manufacturer key, n = voucher entry n
+	Role        string     `json:"role"`  // "manufacturer" | "owner"
+	Current     bool       `json:"current"`
+	KeyType     string     `json:"key_type"`
+	KeyEncoding string     `json:"key_encoding"`
+	Key         string     `json:"key"` // Curve or RSA size
+	KeySHA256   string     `json:"key_sha256,omitempty"`
+	DIDs        []string   `json:"dids,omitempty"`       // DIDs the signover history (GET /api/signover/targets) records for the key
+	Customers   []string   `json:"customers,omitempty"`  // Customers signed over to the key, from signover history
+	FirstSeen   *time.Time `json:"first_seen,omitempty"` // First signover to the key, from signover history
+	Error       string     `json:"error,omitempty"`      // Why the key could not be decoded
+}
+
+// ChainUpload is the upload receipt of the voucher
+type ChainUpload struct {
+	RecipientURL string    `json:"recipient_url"`
+	Status       string    `json:"status"`
+	ReceiptID    string    `json:"receipt_id,omitempty"`
+	UploadedAt   time.Time `json:"uploaded_at"`
+}
+
+// ChainEvent is an audit event of the voucher's GUID
+type ChainEvent struct {
+	Time   time.Time `json:"time"`
+	Event  string    `json:"event"`
+	Detail string    `json:"detail,omitempty"`
+}
+
+// buildVoucherChain decodes a stored voucher and annotates its keys with what
+// the station database knows about them
+func buildVoucherChain(ctx context.Context, db *sql.DB, data []byte, source string) (*VoucherChain, error) {
+	var ov fdo.Voucher
+	if err := cbor.Unmarshal(data, &ov); err != nil {
+		return nil, fmt.Errorf("%w (%s): %v", errVoucherUndecodable, source, err)
+	}
+	chain := &VoucherChain{
+		GUID:       fmt.Sprintf("%x", ov.Header.Val.GUID[:]),
+		Source:     source,
+		DeviceInfo: ov.Header.Val.DeviceInfo,
+		Links:      []ChainLink{chainLink(0, "manufacturer", ov.Header.Val.ManufacturerKey)},
+		Events:     []ChainEvent{},
+	}
+	for i, entry := range ov.Entries {
+		chain.Links = append(chain.Links, chainLink(i+1, "owner", entry.Payload.Val.PublicKey))
+	}
+	chain.Links[len(chain.Links)-1].Current = true
+
+	// The rest is best effort: tables of disabled features don't exist
+	var createdAt int64
+	var customer sql.NullString
+	err := db.QueryRowContext(ctx, `SELECT serial, model, customer, created_at FROM batch_vouchers WHERE guid = ?`, chain.GUID).
+		Scan(&chain.Serial, &chain.Model, &customer, &createdAt)
+	if err == nil {
+		t := time.Unix(createdAt, 0)
+		chain.Customer, chain.CreatedAt = customer.String, &t
+	} else if !chainOptional(err) {
+		return nil, fmt.Errorf("failed to read batch voucher: %w", err)
+	}
+
+	var upload ChainUpload
+	var receiptID sql.NullString
+	var uploadedAt int64
+	err = db.QueryRowContext(ctx, `SELECT recipient_url, status, receipt_id, uploaded_at FROM voucher_upload_receipts WHERE guid = ?`, chain.GUID).
+		Scan(&upload.RecipientURL, &upload.Status, &receiptID, &uploadedAt)
+	if err == nil {
+		upload.ReceiptID, upload.UploadedAt = receiptID.String, time.Unix(uploadedAt, 0)
+		chain.Upload = &upload
+	} else if !chainOptional(err) {
+		return nil, fmt.Errorf("failed to read upload receipt: %w", err)
+	}
+
+	for i := range chain.Links {
+		if err := annotateChainLink(ctx, db, &chain.Links[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	rows, err := db.QueryContext(ctx, `
+	SELECT time, event, COALESCE(detail, '') FROM audit_events WHERE guid = ? ORDER BY time, id LIMIT ?`, chain.GUID, maxChainEvents)
+	if err != nil && !chainOptional(err) {
+		return nil, fmt.Errorf("failed to read audit events: %w", err)
+	}
+	if err == nil {
+		defer rows.Close()
+		for rows.Next() {
+			var event ChainEvent
+			var t int64
+			if err := rows.Scan(&t, &event.Event, &event.Detail); err != nil {
+				return nil, fmt.Errorf("failed to read audit event: %w", err)
+			}
+			event.Time = time.Unix(t, 0)
+			chain.Events = append(chain.Events, event)
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return chain, nil
+}
+
+// chainLink describes one key of a voucher
+func chainLink(index int, role string, key protocol.PublicKey) ChainLink {
+	link := ChainLink{
+		Index:       index,
+		Role:        role,
+		KeyType:     fmt.Sprint(key.Type),
+		KeyEncoding: fmt.Sprint(key.Encoding),
+	}
+	pub, err := key.Public()
+	if err != nil {
+		link.Error = err.Error()
+		return link
+	}
+	link.Key = describeKey(pub)
+	link.KeySHA256 = ownerKeySHA256(pub)
+	return link
+}
+
+// annotateChainLink adds the DIDs, customers and first signover time the
+// signover history holds for a key
+func annotateChainLink(ctx context.Context, db *sql.DB, link *ChainLink) error {
+	if link.KeySHA256 == "" {
+		return nil
+	}
+	rows, err := db.QueryContext(ctx, `SELECT customer, did, first_seen FROM signover_targets WHERE key_sha256 = ? ORDER BY first_seen`, link.KeySHA256)
+	if chainOptional(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read signover targets: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var customer, did string
+		var firstSeen int64
+		if err := rows.Scan(&customer, &did, &firstSeen); err != nil {
+			return fmt.Errorf("failed to read signover target: %w", err)
+		}
+		if did != "" && !slices.Contains(link.DIDs, did) {
+			link.DIDs = append(link.DIDs, did)
+		}
+		if customer != "" && !slices.Contains(link.Customers, customer) {
+			link.Customers = append(link.Customers, customer)
+		}
+		if link.FirstSeen == nil {
+			t := time.Unix(firstSeen, 0)
+			link.FirstSeen = &t
+		}
+	}
+	return rows.Err()
+}
+
+// chainOptional reports whether a query failed only because the row or the
+// table of a disabled feature isn't there
+func chainOptional(err error) bool {
+	return errors.Is(err, sql.ErrNoRows) || (err != nil && strings.Contains(err.Error(), "no such table"))
+}
+
+// label is the text of a link's node, one line per item
+func (l *ChainLink) label() []string {
+	title := "Manufacturer"
+	if l.Role == "owner" {
+		title = fmt.Sprintf("Owner %d", l.Index)
+	}
+	if l.Current {
+		title += " (current)"
+	}
+	lines := []string{title, fmt.Sprintf("%s, %s", l.KeyType, l.KeyEncoding)}
+	switch {
+	case l.Error != "":
+		lines = append(lines, "undecodable key")
+	case len(l.KeySHA256) >= 16:
+		lines = append(lines, l.Key+" sha256:"+l.KeySHA256[:16]+"…")
+	}
+	lines = append(lines, l.DIDs...)
+	if len(l.Customers) > 0 {
+		lines = append(lines, "customer "+strings.Join(l.Customers, ", "))
+	}
+	if l.FirstSeen != nil {
+		lines = append(lines, "since "+l.FirstSeen.UTC().Format(time.RFC3339))
+	}
+	return lines
+}
+
+// deviceLabel is the text of the device node
+func (c *VoucherChain) deviceLabel() []string {
+	lines := []string{"Device " + c.GUID}
+	if c.Serial != "" {
+		lines = append(lines, "serial "+c.Serial)
+	}
+	if c.DeviceInfo != "" {
+		lines = append(lines, c.DeviceInfo)
+	}
+	if c.CreatedAt != nil {
+		lines = append(lines, "issued "+c.CreatedAt.UTC().Format(time.RFC3339))
+	}
+	return lines
+}
+
+// DOT renders the chain as a Graphviz digraph, device to current owner
+func (c *VoucherChain) DOT() string {
+	quote := func(lines []string) string {
+		escaped := make([]string, len(lines))
+		for i, line := range lines {
+			escaped[i] = strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(line)
+		}
+		return `"` + strings.Join(escaped, `\n`) + `"`
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph voucher {\n  rankdir=LR;\n  node [shape=box, fontname=\"Helvetica\"];\n")
+	fmt.Fprintf(&b, "  device [shape=ellipse, label=%s];\n", quote(c.deviceLabel()))
+	prev := "device"
+	for _, link := range c.Links {
+		node := fmt.Sprintf("key%d", link.Index)
+		style := ""
+		if link.Current {
+			style = ", style=bold"
+		}
+		fmt.Fprintf(&b, "  %s [label=%s%s];\n", node, quote(link.label()), style)
+		edge := "header"
+		if link.Index > 0 {
+			edge = fmt.Sprintf("entry %d", link.Index)
+		}
+		fmt.Fprintf(&b, "  %s -> %s [label=%q];\n", prev, node, edge)
+		prev = node
+	}
+	if c.Upload != nil {
+		fmt.Fprintf(&b, "  upload [shape=note, label=%s];\n  %s -> upload [style=dashed];\n",
+			quote([]string{"Uploaded " + c.Upload.UploadedAt.UTC().Format(time.RFC3339), c.Upload.RecipientURL, c.Upload.Status}), prev)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// SVG renders the chain as a left-to-right row of boxes, so support staff can
+// view it in a browser without Graphviz
+func (c *VoucherChain) SVG() string {
+	const width, gap, lineHeight, pad = 300, 60, 16, 10
+	nodes := [][]string{c.deviceLabel()}
+	for _, link := range c.Links {
+		nodes = append(nodes, link.label())
+	}
+	if c.Upload != nil {
+		nodes = append(nodes, []string{"Uploaded " + c.Upload.UploadedAt.UTC().Format(time.RFC3339), c.Upload.RecipientURL, c.Upload.Status})
+	}
+	height := 0
+	for _, lines := range nodes {
+		height = max(height, len(lines)*lineHeight+2*pad)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" font-family="Helvetica, sans-serif" font-size="12">`+"\n",
+		len(nodes)*(width+gap)-gap+20, height+20)
+	b.WriteString(`<defs><marker id="arrow" markerWidth="10" markerHeight="10" refX="9" refY="5" orient="auto"><path d="M0,0 L10,5 L0,10 z"/></marker></defs>` + "\n")
+	for i, lines := range nodes {
+		x := 10 + i*(width+gap)
+		stroke := "1"
+		if i > 0 && i <= len(c.Links) && c.Links[i-1].Current {
+			stroke = "3"
+		}
+		fmt.Fprintf(&b, `<rect x="%d" y="10" width="%d" height="%d" rx="6" fill="#f6f8fa" stroke="#333" stroke-width="%s"/>`+"\n", x, width, height, stroke)
+		for j, line := range lines {
+			weight := ""
+			if j == 0 {
+				weight = ` font-weight="bold"`
+			}
+			fmt.Fprintf(&b, `<text x="%d" y="%d"%s>%s</text>`+"\n", x+pad, 10+pad+(j+1)*lineHeight-4, weight, html.EscapeString(line))
+		}
+		if i > 0 {
+			edge := "header"
+			if i > 1 && i <= len(c.Links) {
+				edge = fmt.Sprintf("entry %d", c.Links[i-1].Index)
+			} else if i > len(c.Links) {
+				edge = "upload"
+			}
+			fmt.Fprintf(&b, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="#333" marker-end="url(#arrow)"/>`+"\n", x-gap, 10+height/2, x-2, 10+height/2)
+			fmt.Fprintf(&b, `<text x="%d" y="%d" font-size="10" text-anchor="middle">%s</text>`+"\n", x-gap/2, 4+height/2, edge)
+		}
+	}
+	b.WriteString("</svg>\n")
+	return b.String()
+}
+
+// ChainHandler serves GET /api/vouchers/{guid}/chain; format=dot or format=svg
+// render the chain as a graph instead of JSON
+func (c *CBORDiag) ChainHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		format := r.URL.Query().Get("format")
+		if format != "" && format != "json" && format != "dot" && format != "svg" {
+			writeJSONError(w, http.StatusBadRequest, "format must be json, dot or svg")
+			return
+		}
+		data, source, err := findStoredVoucher(r.Context(), c.cfg, c.stationDB, r.PathValue("guid"))
+		if errors.Is(err, ErrVoucherNotFound) {
+			writeJSONError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		chain, err := buildVoucherChain(r.Context(), c.stationDB.db, data, source)
+		if errors.Is(err, errVoucherUndecodable) {
+			writeJSONError(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		switch format {
+		case "dot":
+			w.Header().Set("Content-Type", "text/vnd.graphviz; charset=utf-8")
+			_, _ = io.WriteString(w, chain.DOT())
+		case "svg":
+			w.Header().Set("Content-Type", "image/svg+xml")
+			_, _ = io.WriteString(w, chain.SVG())
+		default:
+			writeJSON(w, http.StatusOK, chain)
+		}
+	})
+}
@@ -10,19 +10,63 @@ import (
 
 // VoucherSigningConfig contains configuration for voucher signing
 type VoucherSigningConfig struct {
-	Mode                      string        `yaml:"mode"`                         // "internal" | "external"
-	OwnerKeyType              string        `yaml:"owner_key_type"`               // for internal mode
-	FirstTimeInit             bool          `yaml:"first_time_init"`              // for internal mode
-	ExternalCommand           string        `yaml:"external_command"`             // for external mode
-	ExternalTimeout           time.Duration `yaml:"external_timeout"`             // for external mode
-	ManufacturerPublicKeyFile string        `yaml:"manufacturer_public_key_file"` // PEM file with manufacturer public key
+	Mode                      string               `yaml:"mode"`                         // "internal" | "external"
+	OwnerKeyType              string               `yaml:"owner_key_type"`               // for internal mode
+	FirstTimeInit             bool                 `yaml:"first_time_init"`              // for internal mode
+	ExternalCommand           string               `yaml:"external_command"`             // for external mode
+	ExternalTimeout           time.Duration        `yaml:"external_timeout"`             // for external mode
+	ReconnectAttempts         int                  `yaml:"reconnect_attempts"`           // external mode: retries after the HSM session is lost (default 3)
+	ReconnectBackoff          time.Duration        `yaml:"reconnect_backoff"`            // external mode: wait before the first retry, doubling (default 1s)
+	ManufacturerPublicKeyFile string               `yaml:"manufacturer_public_key_file"` // PEM file with manufacturer public key
+	FailureDirectory          string               `yaml:"failure_directory"`            // Extension failure dumps for "voucher debug-extend" (default "extend-failures")
+	Fallback                  FallbackSignerConfig `yaml:"fallback"`                     // hsm/external mode: software key used when the HSM is unreachable
+}
+
+// FallbackSignerConfig is the software key used when the HSM is unreachable
+type FallbackSignerConfig struct {
+	Enabled       bool   `yaml:"enabled"`
+	KeyFile       string `yaml:"key_file"`       // Encrypted PEM written by "signing encrypt-fallback-key"
+	PassphraseEnv string `yaml:"passphrase_env"` // Environment variable holding the passphrase (default FDO_SIGNING_FALLBACK_PASSPHRASE)
+}
+
+// DiskDestination is one named place vouchers are saved to, such as a
+// customer's SMB or NFS share mounted on the station, or an object store
+// reached through a command
+type DiskDestination struct {
+	Name        string        `yaml:"name"`
+	Directory   string        `yaml:"directory"`   // Local directory or mounted share
+	Command     string        `yaml:"command"`     // Instead of directory: stores {voucherfile} as {filename}, e.g. in an object store
+	Timeout     time.Duration `yaml:"timeout"`     // Command timeout (default 30s)
+	Filename    string        `yaml:"filename"`    // Naming template (default "{serialno}.fdoov"); may contain subdirectories
+	Compression string        `yaml:"compression"` // "gzip" adds .gz to the name (empty = none)
+	OnError     string        `yaml:"on_error"`    // "warn" (default) logs and continues | "fail" fails the device
+	Customers   []string      `yaml:"customers"`   // Devices built for these customers
+	Profiles    []string      `yaml:"profiles"`    // Devices whose owner names these upload auth profiles
+}
+
+// DiskManifestConfig writes a signed manifest of the vouchers saved to each
+// directory, per batch or per UTC day for vouchers built outside a batch
+type DiskManifestConfig struct {
+	Enabled        bool   `yaml:"enabled"`
+	SigningKeyFile string `yaml:"signing_key_file"` // PEM private key (default transfer.signing_key_file)
 }
 
 // OVEExtraDataConfig contains configuration for OVEExtra data
 type OVEExtraDataConfig struct {
-	Enabled         bool          `yaml:"enabled"`
-	ExternalCommand string        `yaml:"external_command"` // script to call for extra data
-	Timeout         time.Duration `yaml:"timeout"`
+	Enabled            bool                     `yaml:"enabled"`
+	ExternalCommand    string                   `yaml:"external_command"` // script to call for extra data
+	Timeout            time.Duration            `yaml:"timeout"`
+	IncludeStationInfo bool                     `yaml:"include_station_info"` // Add station instance ID, version and site/line/station under "fdo_station"
+	Validation         OVEExtraValidationConfig `yaml:"validation"`
+}
+
+// OVEExtraValidationConfig limits the extra data a provider may return
+type OVEExtraValidationConfig struct {
+	Policy       string `yaml:"policy"`         // "drop" (default) | "truncate" | "reject"
+	MinKey       int    `yaml:"min_key"`        // Lowest key a provider may use (default 1)
+	MaxKey       int    `yaml:"max_key"`        // Highest key a provider may use (default 65535)
+	MaxEntrySize int    `yaml:"max_entry_size"` // CBOR bytes per entry (default 1024)
+	MaxTotalSize int    `yaml:"max_total_size"` // CBOR bytes of all provider entries (default 8192)
 }
 
 // DIDCache configuration for DID resolution caching
@@ -33,12 +77,41 @@ type DIDCache struct {
 	FailureBackoff  time.Duration `yaml:"failure_backoff"`  // Backoff after failed refresh
 	PurgeUnused     time.Duration `yaml:"purge_unused"`     // Delete if unused for this duration
 	PurgeOnStartup  bool          `yaml:"purge_on_startup"` // Run purge cleanup on server start
+
+	// SSRF guard: loopback, link-local, private and CGNAT ranges are blocked unless listed here
+	AllowPrivateCIDRs []string `yaml:"allow_private_cidrs"` // e.g. "10.20.0.0/16" or a single address
+
+	// Acceptable did:web signover domains; exact names or "*.example.com" patterns
+	AllowedDomains []string `yaml:"allowed_domains"` // Empty = any domain not denied
+	DeniedDomains  []string `yaml:"denied_domains"`  // Always rejected, checked first
+
+	// Planned key rotations announced with nextRotation in the DID's FDO extension
+	RotationDelay time.Duration `yaml:"rotation_delay"` // Re-resolve this long after the announced time (default 1m)
+	RotationRetry time.Duration `yaml:"rotation_retry"` // Between attempts until the new key is accepted (default 1m)
+	RotationGrace time.Duration `yaml:"rotation_grace"` // How long the previous key stays in use meanwhile (default 1h)
+
+	// Admin pins of a DID to its last resolved key (PUT /api/did/pins/{did})
+	MaxPinDuration time.Duration `yaml:"max_pin_duration"` // Longest pin an admin may set (default 72h)
+
+	// Key of a JWKS owner key URL that names no kid: "first" (default) | "single"
+	JWKSKeySelection string `yaml:"jwks_key_selection"`
+
+	// Universal Resolver for DID methods the station doesn't resolve itself,
+	// e.g. "http://localhost:8080" (empty = those methods are unsupported)
+	UniversalResolverURL string `yaml:"universal_resolver_url"`
 }
 
 // VoucherConfig contains configuration for voucher management
 type VoucherConfig struct {
 	PersistToDB bool `yaml:"persist_to_db"`
 
+	// Record each DI session's device info and pre-signover voucher for "voucher replay"
+	RecordSessions bool `yaml:"record_sessions"`
+
+	// Hash for voucher header hashes and the device HMAC: "sha256" | "sha384" (empty = as negotiated).
+	// Must match the strength of the owner and manufacturer keys (P-256/RSA-2048 vs P-384/RSA-3072).
+	HashAlgorithm string `yaml:"hash_algorithm"`
+
 	// New voucher signing configuration
 	VoucherSigning VoucherSigningConfig `yaml:"voucher_signing"`
 
@@ -47,24 +120,161 @@ type VoucherConfig struct {
 
 	// Save vouchers to disk configuration
 	SaveToDisk struct {
-		Directory string `yaml:"directory"` // Directory to save vouchers (empty = disabled)
+		Directory    string             `yaml:"directory"`    // Directory to save vouchers (empty = disabled)
+		Compression  string             `yaml:"compression"`  // "gzip" writes <serial>.fdoov.gz (empty = none)
+		Destinations []DiskDestination  `yaml:"destinations"` // Named destinations by customer or profile; devices matching none go to directory
+		Checksums    bool               `yaml:"checksums"`    // Write a <file>.sha256 sidecar next to each saved voucher
+		Manifest     DiskManifestConfig `yaml:"manifest"`     // Signed per-batch (or per-day) manifest in each directory
 	} `yaml:"save_to_disk"`
 
 	// Owner signover configuration
-	OwnerSignover struct {
-		Mode            string        `yaml:"mode"`              // "static" or "dynamic"
-		StaticPublicKey string        `yaml:"static_public_key"` // PEM-encoded public key for static mode
-		StaticDID       string        `yaml:"static_did"`        // DID URI for static mode
-		ExternalCommand string        `yaml:"external_command"`  // Command for dynamic mode
-		Timeout         time.Duration `yaml:"timeout"`
-	} `yaml:"owner_signover"`
+	OwnerSignover OwnerSignoverConfig `yaml:"owner_signover"`
+
+	// Policy rules applied after owner signover: they can refuse a device, or
+	// replace its owner, customer and upload auth profile
+	Policies []SignoverPolicyRule `yaml:"policies"`
 
 	// DID cache configuration
 	DIDCache DIDCache `yaml:"did_cache"`
 
-	VoucherUpload struct {
-		Enabled         bool          `yaml:"enabled"`
-		ExternalCommand string        `yaml:"external_command"`
-		Timeout         time.Duration `yaml:"timeout"`
-	} `yaml:"voucher_upload"`
+	VoucherUpload VoucherUploadConfig `yaml:"voucher_upload"`
+
+	// Named authentication profiles for the HTTP uploader, referenced by owner entries
+	UploadAuthProfiles map[string]UploadAuthProfile `yaml:"upload_auth_profiles"`
+
+	// Time budget for the voucher pipeline of each DI session
+	TimeBudget TimeBudgetConfig `yaml:"time_budget"`
+
+	// Directory for the per-session temp dirs (empty = fdo-station in the OS temp dir).
+	// Put it on the save_to_disk filesystem so saved vouchers appear atomically.
+	TempDirectory string `yaml:"temp_directory"`
+
+	// Extra TLS trust anchors and TLS policies for upload destinations and did:web hosts
+	TLSTrust TLSTrustConfig `yaml:"tls_trust"`
+}
+
+// TLSTrustConfig trusts internal PKI for the hosts it is configured for, in
+// addition to the system pool, and sets the TLS versions, cipher suites and
+// certificate pins outbound connections accept
+type TLSTrustConfig struct {
+	Directory  string        `yaml:"directory"` // Pinned certificates, <host>.pem, written by "trust pin"
+	Anchors    []TrustAnchor `yaml:"anchors"`
+	MinVersion string        `yaml:"min_version"` // Hosts without a policy: "1.2" | "1.3" (default "1.2")
+	Policies   []TLSPolicy   `yaml:"policies"`    // The first policy whose hosts match applies
+}
+
+// TrustAnchor is a CA file trusted for a set of hosts. The file is read again
+// whenever it changes; with a URL the station also keeps it up to date itself.
+type TrustAnchor struct {
+	Hosts           []string      `yaml:"hosts"`            // Exact names or "*.example.com" patterns
+	CAFile          string        `yaml:"ca_file"`          // PEM CA certificates
+	URL             string        `yaml:"url"`              // https URL the owner publishes the bundle at; fetched into ca_file
+	RefreshInterval time.Duration `yaml:"refresh_interval"` // How often url is fetched (default 1h)
+}
+
+// TLSPolicy is the TLS versions, cipher suites and certificate pins accepted from a set of hosts
+type TLSPolicy struct {
+	Hosts        []string `yaml:"hosts"`         // Exact names or "*.example.com" patterns
+	MinVersion   string   `yaml:"min_version"`   // "1.2" | "1.3" (default tls_trust.min_version)
+	MaxVersion   string   `yaml:"max_version"`   // "1.2" | "1.3" (default 1.3)
+	CipherSuites []string `yaml:"cipher_suites"` // TLS 1.2 suites by Go name; TLS 1.3 suites are not configurable
+	PinSHA256    []string `yaml:"pin_sha256"`    // Certificate SHA-256 fingerprints as "trust list" prints them; one must be in the chain
+}
+
+// TimeBudgetConfig bounds the voucher pipeline of a DI session, so one slow
+// dependency can't hold the DI handler open. Stage limits default to a share
+// of the total: owner key 25%, DID resolution 15%, signing 25%, upload 35%.
+type TimeBudgetConfig struct {
+	Total         time.Duration `yaml:"total"`          // Whole pipeline (0 = no budget)
+	OwnerKey      time.Duration `yaml:"owner_key"`      // Owner key lookup, including DID resolution
+	DIDResolution time.Duration `yaml:"did_resolution"` // Each DID resolution
+	Signing       time.Duration `yaml:"signing"`        // Voucher signing
+	Upload        time.Duration `yaml:"upload"`         // Voucher upload
+}
+
+// OwnerSignoverConfig contains configuration for owner signover
+type OwnerSignoverConfig struct {
+	Mode              string        `yaml:"mode"`                // "static" | "dynamic" | "none" (vouchers not extended to an owner)
+	StaticPublicKey   string        `yaml:"static_public_key"`   // PEM-encoded public key for static mode
+	StaticDID         string        `yaml:"static_did"`          // DID URI for static mode
+	ExternalCommand   string        `yaml:"external_command"`    // Command for dynamic mode
+	Timeout           time.Duration `yaml:"timeout"`             // Timeout for the dynamic mode command
+	UploadAuthProfile string        `yaml:"upload_auth_profile"` // Upload auth profile for the static owner
+	Customer          string        `yaml:"customer"`            // Customer ID of the static owner, for quotas
+	KeyEncoding       string        `yaml:"key_encoding"`        // Owner key encoding in the voucher entry: "x509" | "x5chain" | "cosekey"
+	CacheTTL          time.Duration `yaml:"cache_ttl"`           // Dynamic mode: reuse a key for the same model and lot this long (0 = run the command per device)
+}
+
+// VoucherUploadConfig contains configuration for voucher upload
+type VoucherUploadConfig struct {
+	Enabled         bool              `yaml:"enabled"`
+	Mode            string            `yaml:"mode"` // "command" (default) | "http"
+	ExternalCommand string            `yaml:"external_command"`
+	Timeout         time.Duration     `yaml:"timeout"`
+	URL             string            `yaml:"url"`              // http mode: recipient URL when the owner has no voucherRecipientURL
+	AuthProfile     string            `yaml:"auth_profile"`     // http mode: profile used when the owner entry names none
+	ContentEncoding string            `yaml:"content_encoding"` // http mode: "gzip" compresses request bodies (empty = none)
+	Batch           BatchUploadConfig `yaml:"batch"`
+	Breaker         BreakerConfig     `yaml:"breaker"`    // http mode: per-destination circuit breaker
+	RateLimit       RateLimitConfig   `yaml:"rate_limit"` // http mode: per-destination request rate
+
+	// Fail uploads whose recipient doesn't return a receipt signed by the
+	// owner key the voucher was signed over to
+	RequireSignedReceipts bool `yaml:"require_signed_receipts"`
+}
+
+// RateLimitConfig smooths uploads to each destination so a burst of
+// manufacturing doesn't flood a customer's voucher recipient
+type RateLimitConfig struct {
+	RequestsPerSecond float64                         `yaml:"requests_per_second"` // Per destination; 0 = unlimited
+	Burst             int                             `yaml:"burst"`               // Requests sent back to back before the rate applies (default 1)
+	MaxWait           time.Duration                   `yaml:"max_wait"`            // Longest an upload queues for its turn (default 30s)
+	Destinations      map[string]DestinationRateLimit `yaml:"destinations"`        // Overrides by catalog destination name
+}
+
+// DestinationRateLimit is the rate limit of one destination
+type DestinationRateLimit struct {
+	RequestsPerSecond float64 `yaml:"requests_per_second"` // 0 = unlimited
+	Burst             int     `yaml:"burst"`
+}
+
+// BreakerConfig stops uploads to a failing destination for a while instead of
+// failing every DI on a slow timeout
+type BreakerConfig struct {
+	FailureThreshold int           `yaml:"failure_threshold"` // Consecutive failures that open the breaker (default 5)
+	Cooldown         time.Duration `yaml:"cooldown"`          // Time before a trial upload is let through (default 5m)
+}
+
+// BatchUploadConfig ships vouchers in periodic zip/tar batches instead of one request each (http mode)
+type BatchUploadConfig struct {
+	Enabled     bool          `yaml:"enabled"`
+	Format      string        `yaml:"format"`       // "zip" (default) | "tar"
+	MaxVouchers int           `yaml:"max_vouchers"` // Ship a destination's batch once this many are queued (default 100)
+	Interval    time.Duration `yaml:"interval"`     // Ship all pending batches this often (default 5m)
+}
+
+// UploadAuthProfile describes how the HTTP uploader authenticates to a voucher recipient
+type UploadAuthProfile struct {
+	Type string `yaml:"type"` // "none" | "bearer" | "basic" | "hmac" | "mtls"
+
+	// Owner key encoding the recipient parses, used when the owner entry names none
+	OwnerKeyEncoding string `yaml:"owner_key_encoding"` // "x509" | "x5chain" | "cosekey"
+
+	// Content-Encoding the recipient accepts, overriding voucher_upload.content_encoding
+	ContentEncoding string `yaml:"content_encoding"` // "gzip" | "none"
+
+	// bearer
+	Token string `yaml:"token" secret:"true"`
+
+	// basic
+	Username string `yaml:"username"`
+	Password string `yaml:"password" secret:"true"`
+
+	// hmac: HMAC-SHA256 over "<timestamp>\n<body>" sent in HMACHeader
+	HMACKey    string `yaml:"hmac_key" secret:"true"`
+	HMACHeader string `yaml:"hmac_header"` // default "X-FDO-Signature"
+
+	// mtls: PEM files for the client certificate and key
+	ClientCert string `yaml:"client_cert"`
+	ClientKey  string `yaml:"client_key"`
 }
@@ -0,0 +1,388 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/fido-device-onboard/go-fdo"
+	"github.com/fido-device-onboard/go-fdo/cbor"
+	"github.com/fido-device-onboard/go-fdo/protocol"
+)
+
+// VoucherDifference is one field in which a voucher differs from another
+// voucher or from the profile that should have produced it
+type VoucherDifference struct {
+	Field    string `json:"field"`    // e.g. "header.rv_info" or "entries[1].public_key"
+	Got      string `json:"got"`      // The voucher under examination
+	Expected string `json:"expected"` // The other voucher, or the expected profile
+}
+
+// VoucherComparison is the result of a voucher diff or profile check
+type VoucherComparison struct {
+	GUID        string              `json:"guid"`
+	Source      string              `json:"source,omitempty"` // Where the stored voucher was found, as in X-Voucher-Source
+	Match       bool                `json:"match"`
+	Differences []VoucherDifference `json:"differences"`
+}
+
+// VoucherProfile is what a voucher should have been built with. Empty
+// members are not checked.
+type VoucherProfile struct {
+	RvInfo      *[][]protocol.RvInstruction // Rendezvous directives of the header
+	OwnerSHA256 string                      // SHA-256 fingerprint of the current owner key
+	OVEKeys     []int                       // OVEExtra keys of the last entry, exactly
+}
+
+// newVoucherComparison collects differences into a comparison
+func newVoucherComparison(ov *fdo.Voucher, source string, differences []VoucherDifference) *VoucherComparison {
+	if differences == nil {
+		differences = []VoucherDifference{}
+	}
+	return &VoucherComparison{
+		GUID:        fmt.Sprintf("%x", ov.Header.Val.GUID[:]),
+		Source:      source,
+		Match:       len(differences) == 0,
+		Differences: differences,
+	}
+}
+
+// diffVouchers compares the header, device certificate chain and entries of
+// two vouchers. Entry signatures are not compared: signing the same entry
+// twice gives different signatures.
+func diffVouchers(got, expected *fdo.Voucher) []VoucherDifference {
+	var differences []VoucherDifference
+	compare := func(field, got, expected string) {
+		if got != expected {
+			differences = append(differences, VoucherDifference{Field: field, Got: got, Expected: expected})
+		}
+	}
+	gh, eh := &got.Header.Val, &expected.Header.Val
+	compare("version", fmt.Sprint(got.Version), fmt.Sprint(expected.Version))
+	compare("header.guid", fmt.Sprintf("%x", gh.GUID[:]), fmt.Sprintf("%x", eh.GUID[:]))
+	compare("header.device_info", gh.DeviceInfo, eh.DeviceInfo)
+	compare("header.rv_info", describeRvInfo(gh.RvInfo), describeRvInfo(eh.RvInfo))
+	compare("header.manufacturer_key", describeVoucherKey(gh.ManufacturerKey), describeVoucherKey(eh.ManufacturerKey))
+	compare("header.cert_chain_hash", cborDigest(gh.CertChainHash), cborDigest(eh.CertChainHash))
+	compare("hmac", cborDigest(got.Hmac), cborDigest(expected.Hmac))
+	compare("cert_chain", cborDigest(got.CertChain), cborDigest(expected.CertChain))
+	compare("entries", strconv.Itoa(len(got.Entries)), strconv.Itoa(len(expected.Entries)))
+	for i := 0; i < min(len(got.Entries), len(expected.Entries)); i++ {
+		gp, ep := &got.Entries[i].Payload.Val, &expected.Entries[i].Payload.Val
+		field := fmt.Sprintf("entries[%d]", i+1)
+		compare(field+".public_key", describeVoucherKey(gp.PublicKey), describeVoucherKey(ep.PublicKey))
+		gExtra, eExtra := entryExtra(gp), entryExtra(ep)
+		compare(field+".ove_keys", describeOVEKeys(gExtra), describeOVEKeys(eExtra))
+		for _, key := range sortedOVEKeys(gExtra) {
+			if value, ok := eExtra[key]; ok {
+				compare(fmt.Sprintf("%s.ove[%d]", field, key), shortDigest(gExtra[key]), shortDigest(value))
+			}
+		}
+	}
+	return differences
+}
+
+// checkVoucherProfile compares a voucher with the profile that should have produced it
+func checkVoucherProfile(ov *fdo.Voucher, profile *VoucherProfile) []VoucherDifference {
+	var differences []VoucherDifference
+	compare := func(field, got, expected string) {
+		if got != expected {
+			differences = append(differences, VoucherDifference{Field: field, Got: got, Expected: expected})
+		}
+	}
+	if profile.RvInfo != nil {
+		compare("header.rv_info", describeRvInfo(ov.Header.Val.RvInfo), describeRvInfo(*profile.RvInfo))
+	}
+	if profile.OwnerSHA256 != "" {
+		owner := ov.Header.Val.ManufacturerKey
+		if len(ov.Entries) > 0 {
+			owner = ov.Entries[len(ov.Entries)-1].Payload.Val.PublicKey
+		}
+		compare("owner_sha256", chainLink(0, "", owner).KeySHA256, normalizeFingerprint(profile.OwnerSHA256))
+	}
+	if profile.OVEKeys != nil {
+		var extra map[int][]byte
+		if len(ov.Entries) > 0 {
+			extra = entryExtra(&ov.Entries[len(ov.Entries)-1].Payload.Val)
+		}
+		expected := make(map[int][]byte, len(profile.OVEKeys))
+		for _, key := range profile.OVEKeys {
+			expected[key] = nil
+		}
+		compare("ove_keys", describeOVEKeys(extra), describeOVEKeys(expected))
+	}
+	return differences
+}
+
+// entryExtra returns the OVEExtra data of a voucher entry
+func entryExtra(payload *fdo.VoucherEntryPayload) map[int][]byte {
+	if payload.Extra == nil {
+		return nil
+	}
+	return payload.Extra.Val
+}
+
+// sortedOVEKeys returns the keys of OVEExtra data in order
+func sortedOVEKeys(extra map[int][]byte) []int {
+	keys := make([]int, 0, len(extra))
+	for key := range extra {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// describeOVEKeys renders OVEExtra keys as "[1 2 3]"
+func describeOVEKeys(extra map[int][]byte) string {
+	return fmt.Sprint(sortedOVEKeys(extra))
+}
+
+// describeRvInfo renders rendezvous directives as "var=hex var=hex | ..."
+func describeRvInfo(rvInfo [][]protocol.RvInstruction) string {
+	if len(rvInfo) == 0 {
+		return "none"
+	}
+	directives := make([]string, 0, len(rvInfo))
+	for _, directive := range rvInfo {
+		instructions := make([]string, 0, len(directive))
+		for _, instruction := range directive {
+			instructions = append(instructions, fmt.Sprintf("%v=%x", instruction.Variable, instruction.Value))
+		}
+		directives = append(directives, strings.Join(instructions, " "))
+	}
+	return strings.Join(directives, " | ")
+}
+
+// describeVoucherKey renders a voucher key as its type, encoding and fingerprint
+func describeVoucherKey(key protocol.PublicKey) string {
+	link := chainLink(0, "", key)
+	if link.Error != "" {
+		return fmt.Sprintf("%s %s (undecodable: %s)", link.KeyType, link.KeyEncoding, link.Error)
+	}
+	return fmt.Sprintf("%s %s sha256:%s", link.Key, link.KeyEncoding, link.KeySHA256)
+}
+
+// cborDigest renders a CBOR-encoded value as a short SHA-256, for fields
+// that only need to be told apart
+func cborDigest(v any) string {
+	data, err := cbor.Marshal(v)
+	if err != nil {
+		return "unencodable: " + err.Error()
+	}
+	return shortDigest(data)
+}
+
+// shortDigest is the first 16 hex digits of the SHA-256 of data
+func shortDigest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:8])
+}
+
+// decodeVoucherArtifact decodes a voucher given as a .fdoov PEM file or raw CBOR
+func decodeVoucherArtifact(data []byte) (*fdo.Voucher, error) {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("-----BEGIN")) {
+		var err error
+		if data, err = decodeVoucherFile(data); err != nil {
+			return nil, err
+		}
+	}
+	var ov fdo.Voucher
+	if err := cbor.Unmarshal(data, &ov); err != nil {
+		return nil, fmt.Errorf("voucher does not decode: %w", err)
+	}
+	return &ov, nil
+}
+
+// parseOVEKeys parses a comma-separated list of OVEExtra keys. Names are
+// mapped to keys the way ove_extra_data maps its non-numeric keys.
+func parseOVEKeys(list string) []int {
+	keys := []int{}
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		key, err := strconv.Atoi(name)
+		if err != nil {
+			key = hashString(name)
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// loadVoucherArg loads the voucher of a CLI argument: a voucher file, or the stored voucher of a GUID
+func loadVoucherArg(ctx context.Context, arg string) (*fdo.Voucher, string, error) {
+	if _, err := os.Stat(arg); err == nil {
+		data, err := readVoucherArtifact(arg)
+		if err != nil {
+			return nil, "", err
+		}
+		ov, err := decodeVoucherArtifact(data)
+		if err != nil {
+			return nil, "", fmt.Errorf("%s: %w", arg, err)
+		}
+		return ov, arg, nil
+	}
+	stationDB, err := OpenStationDBReadOnly(stationDBPath(config))
+	if err != nil {
+		return nil, "", err
+	}
+	defer stationDB.Close()
+	data, source, err := findStoredVoucher(ctx, config, stationDB, arg)
+	if err != nil {
+		return nil, "", err
+	}
+	ov, err := decodeVoucherArtifact(data)
+	if err != nil {
+		return nil, "", fmt.Errorf("stored voucher (%s): %w", source, err)
+	}
+	return ov, source, nil
+}
+
+// printVoucherComparison prints a comparison and returns an error when it found differences
+func printVoucherComparison(comparison *VoucherComparison, gotName, expectedName string) error {
+	if comparison.Match {
+		fmt.Printf("✅ %s matches %s\n", gotName, expectedName)
+		return nil
+	}
+	for _, d := range comparison.Differences {
+		fmt.Printf("❌ %s\n     %s: %s\n     %s: %s\n", d.Field, gotName, d.Got, expectedName, d.Expected)
+	}
+	return fmt.Errorf("%d differences", len(comparison.Differences))
+}
+
+// runVoucherDiff implements "voucher diff <file|guid> <file|guid>"
+func runVoucherDiff(args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("usage: voucher diff <file|guid> <file|guid>")
+	}
+	ctx := context.Background()
+	got, gotSource, err := loadVoucherArg(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	expected, expectedSource, err := loadVoucherArg(ctx, args[1])
+	if err != nil {
+		return err
+	}
+	return printVoucherComparison(newVoucherComparison(got, "", diffVouchers(got, expected)), gotSource, expectedSource)
+}
+
+// runVoucherCheck implements "voucher check [-rv] [-owner <sha256>]
+// [-ove-keys <keys>] <file|guid>": it checks a voucher against the profile
+// that should have produced it
+func runVoucherCheck(args []string) error {
+	fs := flag.NewFlagSet("voucher check", flag.ContinueOnError)
+	rv := fs.Bool("rv", false, "Expect the rendezvous info of this station's config")
+	owner := fs.String("owner", "", "Expected SHA-256 fingerprint of the current owner key")
+	oveKeys := fs.String("ove-keys", "", "Expected OVEExtra keys of the last entry, comma-separated numbers or names; \"none\" for no keys")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 || (!*rv && *owner == "" && *oveKeys == "") {
+		return fmt.Errorf("usage: voucher check [-rv] [-owner <sha256>] [-ove-keys <keys>] <file|guid>")
+	}
+	profile := &VoucherProfile{OwnerSHA256: *owner}
+	if *rv {
+		rvInfo, err := rendezvousInfo(config.Rendezvous.Entries)
+		if err != nil {
+			return err
+		}
+		profile.RvInfo = &rvInfo
+	}
+	if *oveKeys == "none" {
+		profile.OVEKeys = []int{}
+	} else if *oveKeys != "" {
+		profile.OVEKeys = parseOVEKeys(*oveKeys)
+	}
+	ov, source, err := loadVoucherArg(context.Background(), fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	return printVoucherComparison(newVoucherComparison(ov, "", checkVoucherProfile(ov, profile)), source, "expected")
+}
+
+// DiffHandler serves POST /api/vouchers/{guid}/diff: the voucher in the
+// body (PEM or CBOR), e.g. the one a customer received, against the stored voucher
+func (c *CBORDiag) DiffHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(io.LimitReader(r.Body, 1024*1024))
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("failed to read voucher: %v", err))
+			return
+		}
+		got, err := decodeVoucherArtifact(body)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		expected, source, ok := c.storedVoucher(w, r)
+		if !ok {
+			return
+		}
+		writeJSON(w, http.StatusOK, newVoucherComparison(expected, source, diffVouchers(got, expected)))
+	})
+}
+
+// CheckHandler serves GET /api/vouchers/{guid}/check: the stored voucher
+// against the profile named by the query (rv, owner_sha256, ove_keys)
+func (c *CBORDiag) CheckHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		profile := &VoucherProfile{OwnerSHA256: query.Get("owner_sha256")}
+		if query.Get("rv") == "true" {
+			rvInfo, err := rendezvousInfo(c.cfg.Rendezvous.Entries)
+			if err != nil {
+				writeJSONError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+			profile.RvInfo = &rvInfo
+		}
+		if query.Has("ove_keys") {
+			profile.OVEKeys = parseOVEKeys(query.Get("ove_keys"))
+		}
+		if profile.RvInfo == nil && profile.OwnerSHA256 == "" && profile.OVEKeys == nil {
+			writeJSONError(w, http.StatusBadRequest, "name what to check: rv=true, owner_sha256 or ove_keys")
+			return
+		}
+		ov, source, ok := c.storedVoucher(w, r)
+		if !ok {
+			return
+		}
+		writeJSON(w, http.StatusOK, newVoucherComparison(ov, source, checkVoucherProfile(ov, profile)))
+	})
+}
+
+// storedVoucher decodes the stored voucher of the request's GUID, writing the
+// error response when there is none
+func (c *CBORDiag) storedVoucher(w http.ResponseWriter, r *http.Request) (*fdo.Voucher, string, bool) {
+	data, source, err := findStoredVoucher(r.Context(), c.cfg, c.stationDB, r.PathValue("guid"))
+	if errors.Is(err, ErrVoucherNotFound) {
+		writeJSONError(w, http.StatusNotFound, err.Error())
+		return nil, "", false
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return nil, "", false
+	}
+	ov, err := decodeVoucherArtifact(data)
+	if err != nil {
+		writeJSONError(w, http.StatusUnprocessableEntity, fmt.Sprintf("stored voucher (%s): %v", source, err))
+		return nil, "", false
+	}
+	return ov, source, true
+}
@@ -5,12 +5,15 @@
 package main
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/base64"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/fido-device-onboard/go-fdo"
 	"github.com/fido-device-onboard/go-fdo/cbor"
@@ -20,55 +23,229 @@ import (
 
 // VoucherDiskService handles saving vouchers to disk
 type VoucherDiskService struct {
-	config *VoucherConfig
+	config    *VoucherConfig
+	manifests *DiskManifests // nil = no manifests
 }
 
 // NewVoucherDiskService creates a new voucher disk service
-func NewVoucherDiskService(config *VoucherConfig) *VoucherDiskService {
+func NewVoucherDiskService(config *VoucherConfig, manifests *DiskManifests) *VoucherDiskService {
 	return &VoucherDiskService{
-		config: config,
+		config:    config,
+		manifests: manifests,
 	}
 }
 
-// SaveVoucherToDisk saves an ownership voucher to disk in the format used by go-fdo command-line tools
-func (v *VoucherDiskService) SaveVoucherToDisk(ov *fdo.Voucher, serialNumber string) error {
-	if v.config.SaveToDisk.Directory == "" {
-		// Directory not specified, disk saving disabled
+// Disk destination error policies
+const (
+	DiskOnErrorWarn = "warn" // Log the failure and carry on
+	DiskOnErrorFail = "fail" // Fail the device
+)
+
+const (
+	defaultDiskFilename       = "{serialno}.fdoov"
+	defaultDiskCommandTimeout = 30 * time.Second
+)
+
+// DiskTarget identifies the device a voucher is saved for
+type DiskTarget struct {
+	Serial    string
+	Model     string
+	GUID      string
+	Customer  string
+	Profile   string // Upload auth profile named by the owner entry
+	BatchID   string // Open batch, if any
+	LotNumber string
+}
+
+// SaveVoucherToDisk saves an ownership voucher in the format used by go-fdo
+// command-line tools to every destination the device matches, or to the
+// save_to_disk directory if it matches none. It returns an error only when a
+// destination with on_error "fail" can't be written.
+func (v *VoucherDiskService) SaveVoucherToDisk(ctx context.Context, ov *fdo.Voucher, target DiskTarget) error {
+	destinations := v.destinations(target)
+	if len(destinations) == 0 {
+		// No directory and no matching destination: disk saving disabled
 		return nil
 	}
 
-	// Create directory if it doesn't exist
-	if err := os.MkdirAll(v.config.SaveToDisk.Directory, 0755); err != nil {
-		return fmt.Errorf("failed to create voucher directory: %w", err)
+	// Convert voucher to the same format as go-fdo command-line tools
+	voucherText, err := v.formatVoucherForDisk(ov, target.Serial)
+	if err != nil {
+		return fmt.Errorf("failed to format voucher for disk: %w", err)
 	}
 
-	// Generate filename using serial number
-	filename := fmt.Sprintf("%s.fdoov", serialNumber)
-	filepath := filepath.Join(v.config.SaveToDisk.Directory, filename)
+	for _, dest := range destinations {
+		err := v.save(ctx, dest, target, []byte(voucherText))
+		if err == nil {
+			continue
+		}
+		if dest.OnError == DiskOnErrorFail {
+			return fmt.Errorf("failed to save voucher to %s: %w", dest.Name, err)
+		}
+		fmt.Printf("⚠️  Failed to save voucher to %s: %v\n", dest.Name, err)
+	}
+	return nil
+}
 
-	// Convert voucher to the same format as go-fdo command-line tools
-	voucherText, err := v.formatVoucherForDisk(ov, serialNumber)
+// destinations returns the destinations a device's voucher is saved to
+func (v *VoucherDiskService) destinations(target DiskTarget) []DiskDestination {
+	var matched []DiskDestination
+	for _, dest := range v.config.SaveToDisk.Destinations {
+		if (target.Customer != "" && slices.Contains(dest.Customers, target.Customer)) ||
+			(target.Profile != "" && slices.Contains(dest.Profiles, target.Profile)) {
+			matched = append(matched, dest)
+		}
+	}
+	if len(matched) == 0 && v.config.SaveToDisk.Directory != "" {
+		matched = append(matched, DiskDestination{
+			Name:        "save_to_disk",
+			Directory:   v.config.SaveToDisk.Directory,
+			Compression: v.config.SaveToDisk.Compression,
+		})
+	}
+	return matched
+}
+
+// save writes a voucher to one destination. The file is written in the session
+// temp dir and renamed into place, so a directory never holds a partly written
+// voucher; for a command destination the temp file is what it is handed.
+func (v *VoucherDiskService) save(ctx context.Context, dest DiskDestination, target DiskTarget, voucher []byte) error {
+	data, err := compress(dest.Compression, voucher)
 	if err != nil {
 		return fmt.Errorf("failed to format voucher for disk: %w", err)
 	}
+	filename, err := diskFilename(dest, target)
+	if err != nil {
+		return err
+	}
 
-	// Write voucher to file
-	if err := os.WriteFile(filepath, []byte(voucherText), 0644); err != nil {
+	if dest.Command != "" {
+		f, err := createSessionTemp(ctx, "voucher-*"+filepath.Ext(filename))
+		if err != nil {
+			return fmt.Errorf("failed to create temp file: %w", err)
+		}
+		defer os.Remove(f.Name())
+		_, err = f.Write(data)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write temp file: %w", err)
+		}
+		timeout := dest.Timeout
+		if timeout <= 0 {
+			timeout = defaultDiskCommandTimeout
+		}
+		_, err = NewExternalCommandExecutor(CommandSaveToDisk, dest.Command, timeout).Execute(ctx, map[string]string{
+			"voucherfile": f.Name(),
+			"filename":    filename,
+			"destination": dest.Name,
+			"serialno":    target.Serial,
+			"model":       target.Model,
+			"guid":        target.GUID,
+			"customer":    target.Customer,
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("💾 Saved ownership voucher to %s as %s\n", dest.Name, filename)
+		return nil
+	}
+
+	path := filepath.Join(dest.Directory, filename)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create voucher directory: %w", err)
+	}
+	if err := writeViaSessionTemp(ctx, path, data, 0644); err != nil {
 		return fmt.Errorf("failed to write voucher to disk: %w", err)
 	}
+	sum, sidecar := sha256Sidecar(data, path)
+	if v.config.SaveToDisk.Checksums {
+		if err := writeViaSessionTemp(ctx, path+".sha256", sidecar, 0644); err != nil {
+			return fmt.Errorf("failed to write checksum: %w", err)
+		}
+	}
+	if err := v.manifests.Record(ctx, target, dest.Directory, filepath.ToSlash(filename), sum); err != nil {
+		return err
+	}
+	fmt.Printf("💾 Saved ownership voucher to disk: %s\n", path)
+	return nil
+}
 
-	fmt.Printf("💾 Saved ownership voucher to disk: %s\n", filepath)
+// diskFilename expands a destination's naming template. Each value has its
+// path separators replaced, so only the template itself can name
+// subdirectories, and the result must stay inside the destination.
+func diskFilename(dest DiskDestination, target DiskTarget) (string, error) {
+	template := dest.Filename
+	if template == "" {
+		template = defaultDiskFilename
+	}
+	sanitize := strings.NewReplacer("/", "_", "\\", "_", "..", "_")
+	variables := map[string]string{
+		"serialno": serialRules.Serial(target.Serial),
+		"model":    serialRules.Model(target.Model),
+		"guid":     target.GUID,
+		"customer": target.Customer,
+		"profile":  target.Profile,
+		"date":     time.Now().UTC().Format("2006-01-02"),
+	}
+	name := template
+	for key, value := range variables {
+		name = strings.ReplaceAll(name, "{"+key+"}", sanitize.Replace(value))
+	}
+	name = filepath.Clean(name + compressedSuffix(dest.Compression))
+	if !filepath.IsLocal(name) {
+		return "", fmt.Errorf("filename %q leaves the destination", name)
+	}
+	return name, nil
+}
+
+// validateDiskDestinations checks the save_to_disk destinations
+func validateDiskDestinations(config *VoucherConfig) error {
+	names := map[string]bool{}
+	for i, dest := range config.SaveToDisk.Destinations {
+		if dest.Name == "" {
+			return fmt.Errorf("save_to_disk.destinations[%d] needs a name", i)
+		}
+		if names[dest.Name] {
+			return fmt.Errorf("save_to_disk.destinations: %q is defined twice", dest.Name)
+		}
+		names[dest.Name] = true
+		if (dest.Directory == "") == (dest.Command == "") {
+			return fmt.Errorf("save_to_disk.destinations.%s needs either a directory or a command", dest.Name)
+		}
+		if len(dest.Customers) == 0 && len(dest.Profiles) == 0 {
+			return fmt.Errorf("save_to_disk.destinations.%s needs customers or profiles", dest.Name)
+		}
+		switch dest.OnError {
+		case "", DiskOnErrorWarn, DiskOnErrorFail:
+		default:
+			return fmt.Errorf("save_to_disk.destinations.%s.on_error: unknown policy %q (expected warn or fail)", dest.Name, dest.OnError)
+		}
+		if err := validateCompression(dest.Compression); err != nil {
+			return fmt.Errorf("save_to_disk.destinations.%s.compression: %w", dest.Name, err)
+		}
+	}
 	return nil
 }
 
 // formatVoucherForDisk formats the voucher in the same style as go-fdo command-line tools
 func (v *VoucherDiskService) formatVoucherForDisk(ov *fdo.Voucher, serialNumber string) (string, error) {
+	return formatVoucherFile(ov)
+}
+
+// formatVoucherFile encodes a voucher as a .fdoov file (PEM-style armored base64 CBOR)
+func formatVoucherFile(ov *fdo.Voucher) (string, error) {
 	// Serialize voucher to CBOR
 	voucherBytes, err := cbor.Marshal(ov)
 	if err != nil {
 		return "", fmt.Errorf("failed to marshal voucher: %w", err)
 	}
+	return armorVoucher(voucherBytes), nil
+}
 
+// armorVoucher wraps a CBOR voucher in the .fdoov armor
+func armorVoucher(voucherBytes []byte) string {
 	// Base64 encode the CBOR (without line breaks)
 	voucherBase64 := base64.StdEncoding.EncodeToString(voucherBytes)
 
@@ -85,7 +262,7 @@ func (v *VoucherDiskService) formatVoucherForDisk(ov *fdo.Voucher, serialNumber
 	// Footer
 	builder.WriteString("-----END OWNERSHIP VOUCHER-----\n")
 
-	return builder.String(), nil
+	return builder.String()
 }
 
 // GenerateTestVoucher creates a test voucher for testing purposes
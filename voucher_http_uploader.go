@@ -0,0 +1,338 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrUploadRejected marks a voucher the recipient refused or failed to accept
+var ErrUploadRejected = errors.New("voucher upload rejected")
+
+// VoucherHTTPUploader pushes vouchers to an owner's voucher recipient endpoint
+// (POST multipart/form-data, see voucher_transfer_spec.md) using a named auth profile
+type VoucherHTTPUploader struct {
+	config    *VoucherConfig
+	stationID string
+
+	mu      sync.Mutex
+	clients map[uploadClientKey]*http.Client
+}
+
+// uploadClientKey names a cached client: its auth profile, and whether its
+// connections go through the SSRF guard
+type uploadClientKey struct {
+	profile string
+	guarded bool
+}
+
+// NewVoucherHTTPUploader creates a new HTTP voucher uploader
+func NewVoucherHTTPUploader(config *VoucherConfig, stationID string) *VoucherHTTPUploader {
+	return &VoucherHTTPUploader{
+		config:    config,
+		stationID: stationID,
+		clients:   make(map[uploadClientKey]*http.Client),
+	}
+}
+
+// UploadResponse is the structured response body defined by the voucher transfer spec
+type UploadResponse struct {
+	Status           string `json:"status"`
+	VoucherID        string `json:"voucher_id"`
+	ReceiptID        string `json:"receipt_id"`
+	ConfirmationID   string `json:"confirmation_id"`
+	Message          string `json:"message"`
+	ReceiptSignature string `json:"receipt_signature"` // Owner's signature of the receipt statement, if the recipient signs receipts
+}
+
+// Upload posts a voucher file to the recipient URL, authenticating with the named profile.
+// The voucher GUID is sent as the Idempotency-Key so a recipient can collapse racing
+// retries; "already uploaded" responses are reported as a successful duplicate receipt.
+func (u *VoucherHTTPUploader) Upload(ctx context.Context, recipientURL, profileName, serial, model, guid string, voucherFile []byte) (*UploadReceipt, error) {
+	profile, err := u.lookupProfile(profileName)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := u.clientFor(profileName, profile, recipientURL)
+	if err != nil {
+		return nil, err
+	}
+
+	timestamp := time.Now().UTC().Format(time.RFC3339)
+	var hash string
+	if data, err := decodeVoucherFile(voucherFile); err == nil {
+		hash = voucherHash(data)
+	}
+
+	// Build multipart body
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("voucher", serial+".fdoov")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create voucher form file: %w", err)
+	}
+	if _, err := part.Write(voucherFile); err != nil {
+		return nil, fmt.Errorf("failed to write voucher form file: %w", err)
+	}
+	for field, value := range map[string]string{
+		"serial":         serial,
+		"model":          model,
+		"manufacturer":   u.stationID,
+		"timestamp":      timestamp,
+		"voucher_sha256": hash,
+	} {
+		if err := writer.WriteField(field, value); err != nil {
+			return nil, fmt.Errorf("failed to write form field %s: %w", field, err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close multipart body: %w", err)
+	}
+
+	payload, err := u.encodeBody(profile, body.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, recipientURL, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create upload request: %w", err)
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	if encoding := u.contentEncoding(profile); encoding != "" {
+		req.Header.Set("Content-Encoding", encoding)
+	}
+	req.Header.Set("X-FDO-Version", "1.0")
+	req.Header.Set("X-FDO-Client-ID", u.stationID)
+
+	req.Header.Set("Idempotency-Key", guid)
+	req.Header.Set("X-Voucher-SHA256", hash)
+
+	if err := applyUploadAuth(req, profile, timestamp, payload); err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("📤 Uploading voucher for %s to %s (auth profile %q)\n", serialRules.Serial(serial), recipientURL, profileName)
+	if err := faultInjector.Inject(ctx, FaultStageUpload); err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("voucher upload request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+
+	// Structured responses are optional; a non-JSON body just yields no receipt ID
+	var parsed UploadResponse
+	_ = json.Unmarshal(respBody, &parsed)
+
+	receipt := &UploadReceipt{
+		GUID:         guid,
+		Serial:       serial,
+		RecipientURL: recipientURL,
+		ReceiptID:    parsed.receiptID(),
+		Status:       "accepted",
+		VoucherHash:  hash,
+		UploadedAt:   time.Now(),
+		Signature:    parsed.ReceiptSignature,
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusConflict || parsed.isDuplicate():
+		// Another attempt (or an earlier run) already delivered this voucher
+		receipt.Status = "duplicate"
+		fmt.Printf("✅ Voucher for %s already uploaded to recipient (HTTP %d), treating as success\n", serialRules.Serial(serial), resp.StatusCode)
+		return receipt, nil
+	case resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted:
+		return nil, fmt.Errorf("%w: voucher recipient returned HTTP %d: %s", ErrUploadRejected, resp.StatusCode, string(respBody))
+	case parsed.Status == "error":
+		return nil, fmt.Errorf("%w: voucher recipient reported error: %s", ErrUploadRejected, parsed.Message)
+	}
+
+	fmt.Printf("✅ Voucher for %s accepted by recipient (HTTP %d, receipt %q)\n", serialRules.Serial(serial), resp.StatusCode, receipt.ReceiptID)
+	return receipt, nil
+}
+
+// Probe checks that a recipient URL answers an authenticated HEAD request,
+// without sending a voucher. Any response but an auth refusal or a server
+// error counts as reachable; a POST-only endpoint answers 405, which is fine.
+func (u *VoucherHTTPUploader) Probe(ctx context.Context, recipientURL, profileName string) (int, error) {
+	profile, err := u.lookupProfile(profileName)
+	if err != nil {
+		return 0, err
+	}
+	client, err := u.clientFor(profileName, profile, recipientURL)
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, recipientURL, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create probe request: %w", err)
+	}
+	req.Header.Set("X-FDO-Version", "1.0")
+	req.Header.Set("X-FDO-Client-ID", u.stationID)
+	if err := applyUploadAuth(req, profile, time.Now().UTC().Format(time.RFC3339), nil); err != nil {
+		return 0, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("voucher recipient unreachable: %w", err)
+	}
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return resp.StatusCode, fmt.Errorf("%w: voucher recipient refused auth profile %q (HTTP %d)", ErrUploadRejected, profileName, resp.StatusCode)
+	case resp.StatusCode >= 500:
+		return resp.StatusCode, fmt.Errorf("voucher recipient returned HTTP %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// receiptID returns the recipient's receipt identifier, accepting the common field names
+func (r *UploadResponse) receiptID() string {
+	switch {
+	case r.ReceiptID != "":
+		return r.ReceiptID
+	case r.ConfirmationID != "":
+		return r.ConfirmationID
+	default:
+		return r.VoucherID
+	}
+}
+
+// isDuplicate reports whether the response says the voucher was already uploaded
+func (r *UploadResponse) isDuplicate() bool {
+	switch strings.ToLower(r.Status) {
+	case "duplicate", "already_uploaded", "already_exists", "exists":
+		return true
+	}
+	return false
+}
+
+// contentEncoding returns the Content-Encoding for uploads with the profile, or "" for none
+func (u *VoucherHTTPUploader) contentEncoding(profile UploadAuthProfile) string {
+	encoding := profile.ContentEncoding
+	if encoding == "" {
+		encoding = u.config.VoucherUpload.ContentEncoding
+	}
+	if encoding == "none" {
+		return ""
+	}
+	return encoding
+}
+
+// encodeBody compresses a request body with the profile's Content-Encoding.
+// HMAC signatures cover the body as sent.
+func (u *VoucherHTTPUploader) encodeBody(profile UploadAuthProfile, body []byte) ([]byte, error) {
+	payload, err := compress(u.contentEncoding(profile), body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode upload body: %w", err)
+	}
+	return payload, nil
+}
+
+// lookupProfile returns the named auth profile; an empty name means no authentication
+func (u *VoucherHTTPUploader) lookupProfile(name string) (UploadAuthProfile, error) {
+	if name == "" {
+		return UploadAuthProfile{Type: "none"}, nil
+	}
+	profile, ok := u.config.UploadAuthProfiles[name]
+	if !ok {
+		return UploadAuthProfile{}, fmt.Errorf("unknown upload auth profile: %s", name)
+	}
+	return profile, nil
+}
+
+// clientFor returns an HTTP client for the profile and recipient, building
+// clients once and reusing them. Only voucher_upload.url is trusted config;
+// any other recipient, such as an owner DID's voucherRecipientURL, is dialed
+// through the SSRF guard with the DID resolver's did_cache.allow_private_cidrs.
+func (u *VoucherHTTPUploader) clientFor(name string, profile UploadAuthProfile, recipientURL string) (*http.Client, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	key := uploadClientKey{profile: name, guarded: recipientURL != u.config.VoucherUpload.URL}
+	if client, ok := u.clients[key]; ok {
+		return client, nil
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if key.guarded {
+		// No proxy: the guard would only see the proxy's address, not the recipient's
+		transport.Proxy = nil
+		transport.DialContext = NewSSRFGuard(u.config.DIDCache.AllowPrivateCIDRs).Dialer(30 * time.Second).DialContext
+	}
+	if profile.Type == "mtls" {
+		if profile.ClientCert == "" || profile.ClientKey == "" {
+			return nil, fmt.Errorf("upload auth profile %s: mtls requires client_cert and client_key", name)
+		}
+		cert, err := tls.LoadX509KeyPair(profile.ClientCert, profile.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("upload auth profile %s: failed to load client certificate: %w", name, err)
+		}
+		transport.TLSClientConfig = &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			MinVersion:   tls.VersionTLS12,
+		}
+	}
+
+	tlsTrust.wrapTransport(transport)
+
+	client := &http.Client{
+		Timeout:   u.config.VoucherUpload.Timeout,
+		Transport: transport,
+	}
+	u.clients[key] = client
+	return client, nil
+}
+
+// applyUploadAuth adds the request headers required by the auth profile
+func applyUploadAuth(req *http.Request, profile UploadAuthProfile, timestamp string, body []byte) error {
+	switch profile.Type {
+	case "", "none", "mtls":
+		// mTLS authenticates at the transport layer
+		return nil
+	case "bearer":
+		if profile.Token == "" {
+			return fmt.Errorf("bearer auth profile has no token")
+		}
+		req.Header.Set("Authorization", "Bearer "+profile.Token)
+	case "basic":
+		req.SetBasicAuth(profile.Username, profile.Password)
+	case "hmac":
+		if profile.HMACKey == "" {
+			return fmt.Errorf("hmac auth profile has no hmac_key")
+		}
+		header := profile.HMACHeader
+		if header == "" {
+			header = "X-FDO-Signature"
+		}
+		mac := hmac.New(sha256.New, []byte(profile.HMACKey))
+		mac.Write([]byte(timestamp + "\n"))
+		mac.Write(body)
+		req.Header.Set("X-FDO-Timestamp", timestamp)
+		req.Header.Set(header, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	default:
+		return fmt.Errorf("unsupported upload auth type: %s", profile.Type)
+	}
+	return nil
+}
@@ -0,0 +1,346 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/pem"
+	"fmt"
+	"maps"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fido-device-onboard/go-fdo"
+	"github.com/fido-device-onboard/go-fdo/cbor"
+)
+
+// Voucher stores checked for integrity
+const (
+	IntegritySourceFDO      = "fdo_db"          // mfg_vouchers of the go-fdo database
+	IntegritySourceTransfer = "transfer"        // transfer_vouchers
+	IntegritySourceSessions = "session_records" // Recorded DI sessions
+	IntegritySourceQueue    = "batch_queue"     // Vouchers waiting for a batch upload
+	IntegritySourceDisk     = "save_to_disk"    // .fdoov files
+)
+
+// defaultIntegrityInterval is how often the job runs when no interval is configured
+const defaultIntegrityInterval = 24 * time.Hour
+
+// VoucherIntegrity periodically re-verifies every voucher the station keeps,
+// so bit rot or a partial write is found when it happens, not when the
+// voucher is needed for an RMA. A nil *VoucherIntegrity checks nothing.
+type VoucherIntegrity struct {
+	config    *VoucherIntegrityConfig
+	cfg       *Config
+	stationDB *StationDB
+	auditLog  *AuditLog
+	notifier  *Notifier
+
+	mu      sync.Mutex
+	last    *IntegrityReport
+	flagged map[string]bool // Corrupt vouchers already audited, by source and key
+}
+
+// IntegrityReport is the result of one check, served by GET /api/integrity
+type IntegrityReport struct {
+	StartedAt  time.Time          `json:"started_at"`
+	FinishedAt time.Time          `json:"finished_at"`
+	Checked    map[string]int     `json:"checked"` // Vouchers checked per source
+	Corrupt    int                `json:"corrupt"`
+	Failures   []IntegrityFailure `json:"failures"`
+	Errors     []string           `json:"errors,omitempty"` // Sources that could not be read
+}
+
+// IntegrityFailure is one voucher that failed verification
+type IntegrityFailure struct {
+	Source string `json:"source"`
+	Key    string `json:"key"` // GUID, or file name for save_to_disk
+	Error  string `json:"error"`
+}
+
+// NewVoucherIntegrity creates the integrity job, or returns nil if it is disabled
+func NewVoucherIntegrity(config *VoucherIntegrityConfig, cfg *Config, stationDB *StationDB, auditLog *AuditLog, notifier *Notifier) *VoucherIntegrity {
+	if !config.Enabled {
+		return nil
+	}
+	return &VoucherIntegrity{
+		config:    config,
+		cfg:       cfg,
+		stationDB: stationDB,
+		auditLog:  auditLog,
+		notifier:  notifier,
+		flagged:   make(map[string]bool),
+	}
+}
+
+// Run checks the vouchers at startup and then every interval until ctx is done
+func (v *VoucherIntegrity) Run(ctx context.Context) {
+	if v == nil {
+		return
+	}
+	v.Check(ctx)
+	interval := v.config.Interval
+	if interval <= 0 {
+		interval = defaultIntegrityInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			v.Check(ctx)
+		}
+	}
+}
+
+// Check verifies every stored voucher and audits corruption not reported before
+func (v *VoucherIntegrity) Check(ctx context.Context) *IntegrityReport {
+	report := checkVoucherIntegrity(ctx, v.cfg, v.stationDB)
+
+	v.mu.Lock()
+	v.last = report
+	var fresh []IntegrityFailure
+	for _, failure := range report.Failures {
+		key := failure.Source + "/" + failure.Key
+		if !v.flagged[key] {
+			v.flagged[key] = true
+			fresh = append(fresh, failure)
+		}
+	}
+	v.mu.Unlock()
+
+	for _, failure := range fresh {
+		event := AuditEvent{Event: "voucher_integrity_failed", Detail: fmt.Sprintf("%s %s: %s", failure.Source, failure.Key, failure.Error)}
+		if failure.Source != IntegritySourceDisk {
+			event.GUID = failure.Key
+		}
+		v.auditLog.Record(ctx, event)
+	}
+	if len(fresh) > 0 {
+		v.notifier.Critical("voucher_integrity", fmt.Sprintf("%d stored vouchers failed verification (%d newly found)", report.Corrupt, len(fresh)))
+	}
+	for _, err := range report.Errors {
+		fmt.Printf("⚠️  Voucher integrity: %s\n", err)
+	}
+	fmt.Printf("🔏 Voucher integrity check: %d vouchers checked, %d corrupt\n", report.total(), report.Corrupt)
+	return report
+}
+
+// Handler serves GET /api/integrity, the last check's report
+func (v *VoucherIntegrity) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if v == nil {
+			writeJSONError(w, http.StatusNotFound, "voucher integrity checks are disabled")
+			return
+		}
+		report := v.Last()
+		if report == nil {
+			writeJSONError(w, http.StatusNotFound, "no integrity check has run yet")
+			return
+		}
+		writeJSON(w, http.StatusOK, report)
+	})
+}
+
+// Last returns the report of the latest check, or nil before the first
+func (v *VoucherIntegrity) Last() *IntegrityReport {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.last
+}
+
+// total returns the number of vouchers checked
+func (r *IntegrityReport) total() int {
+	n := 0
+	for _, count := range r.Checked {
+		n += count
+	}
+	return n
+}
+
+// stationVoucherSources are the station database tables holding vouchers, each
+// keyed by GUID (t.guid); vouchers are in the voucher store, or inline for rows
+// written before it existed
+var stationVoucherSources = []struct{ source, query string }{
+	{IntegritySourceTransfer, `SELECT t.guid, COALESCE(t.voucher_hash, ''), COALESCE(b.voucher, t.voucher)
+		FROM transfer_vouchers t LEFT JOIN voucher_blobs b ON b.hash = t.voucher_hash`},
+	{IntegritySourceSessions, `SELECT t.guid, COALESCE(t.voucher_hash, ''), COALESCE(b.voucher, t.voucher)
+		FROM session_records t LEFT JOIN voucher_blobs b ON b.hash = t.voucher_hash`},
+	{IntegritySourceQueue, `SELECT t.guid, COALESCE(t.voucher_hash, ''), COALESCE(b.voucher, t.voucher_file)
+		FROM voucher_batch_queue t LEFT JOIN voucher_blobs b ON b.hash = t.voucher_hash`},
+}
+
+// checkVoucherIntegrity verifies the vouchers of every store
+func checkVoucherIntegrity(ctx context.Context, cfg *Config, stationDB *StationDB) *IntegrityReport {
+	report := &IntegrityReport{StartedAt: time.Now(), Checked: map[string]int{}, Failures: []IntegrityFailure{}}
+	fail := func(source, key string, err error) {
+		report.Corrupt++
+		report.Failures = append(report.Failures, IntegrityFailure{Source: source, Key: key, Error: err.Error()})
+	}
+
+	for _, table := range stationVoucherSources {
+		err := scanVouchers(ctx, stationDB.db, table.query, func(guid, hash string, data []byte) {
+			report.Checked[table.source]++
+			if table.source == IntegritySourceQueue && hash == "" {
+				// Queued before the voucher store: the row holds the armored file
+				var err error
+				if data, err = decodeVoucherFile(data); err != nil {
+					fail(table.source, guid, err)
+					return
+				}
+			}
+			if err := checkVoucherHash(hash, data); err != nil {
+				fail(table.source, guid, err)
+				return
+			}
+			if err := verifyStoredVoucher(data, guid); err != nil {
+				fail(table.source, guid, err)
+			}
+		})
+		if err != nil && !strings.Contains(err.Error(), "no such table") {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", table.source, err))
+		}
+	}
+
+	// go-fdo database, through its own read-only connection
+	if cfg.Database.Password != "" {
+		report.Errors = append(report.Errors, fmt.Sprintf("%s: skipped, the database is encrypted", IntegritySourceFDO))
+	} else if err := checkFDOVouchers(ctx, cfg.Database.Path, "", func(guid, _ string, data []byte) {
+		report.Checked[IntegritySourceFDO]++
+		if err := verifyStoredVoucher(data, guid); err != nil {
+			fail(IntegritySourceFDO, guid, err)
+		}
+	}); err != nil {
+		report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", IntegritySourceFDO, err))
+	}
+
+	// .fdoov files written by save_to_disk, compressed or not
+	if dir := cfg.VoucherManagement.SaveToDisk.Directory; dir != "" {
+		var files []string
+		for _, pattern := range []string{"*.fdoov", "*.fdoov.gz"} {
+			matches, err := filepath.Glob(filepath.Join(dir, pattern))
+			if err != nil {
+				report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", IntegritySourceDisk, err))
+			}
+			files = append(files, matches...)
+		}
+		for _, file := range files {
+			report.Checked[IntegritySourceDisk]++
+			name := filepath.Base(file)
+			text, err := readVoucherArtifact(file)
+			if err != nil {
+				fail(IntegritySourceDisk, name, err)
+				continue
+			}
+			data, err := decodeVoucherFile(text)
+			if err != nil {
+				fail(IntegritySourceDisk, name, err)
+				continue
+			}
+			if err := verifyStoredVoucher(data, ""); err != nil {
+				fail(IntegritySourceDisk, name, err)
+			}
+		}
+	}
+
+	report.FinishedAt = time.Now()
+	return report
+}
+
+// checkFDOVouchers reads the vouchers go-fdo persisted at DI, or only the one
+// of guid if it is set
+func checkFDOVouchers(ctx context.Context, path, guid string, check func(guid, hash string, data []byte)) error {
+	db, err := sql.Open("sqlite3", "file:"+path+"?mode=ro&_pragma=busy_timeout(10000)&_pragma=query_only(1)")
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer db.Close()
+	query := `SELECT lower(hex(guid)), '', cbor FROM mfg_vouchers`
+	if guid != "" {
+		return scanVouchers(ctx, db, query+` WHERE lower(hex(guid)) = ?`, check, strings.ToLower(guid))
+	}
+	return scanVouchers(ctx, db, query, check)
+}
+
+// scanVouchers calls check with the GUID, content hash (if any) and voucher
+// bytes of every row of query, run with args
+func scanVouchers(ctx context.Context, db *sql.DB, query string, check func(guid, hash string, data []byte), args ...any) error {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var guid, hash string
+		var data []byte
+		if err := rows.Scan(&guid, &hash, &data); err != nil {
+			return err
+		}
+		check(guid, hash, data)
+	}
+	return rows.Err()
+}
+
+// decodeVoucherFile returns the CBOR voucher of a .fdoov file
+func decodeVoucherFile(text []byte) ([]byte, error) {
+	block, _ := pem.Decode(text)
+	if block == nil || block.Type != "OWNERSHIP VOUCHER" {
+		return nil, fmt.Errorf("not an OWNERSHIP VOUCHER PEM block")
+	}
+	return block.Bytes, nil
+}
+
+// verifyStoredVoucher checks that a voucher decodes, belongs to the GUID it is
+// stored under (when known), and that its entry chain, which hashes the
+// header, is correctly signed
+func verifyStoredVoucher(data []byte, guid string) error {
+	var ov fdo.Voucher
+	if err := cbor.Unmarshal(data, &ov); err != nil {
+		return fmt.Errorf("voucher does not decode: %w", err)
+	}
+	if actual := fmt.Sprintf("%x", ov.Header.Val.GUID[:]); guid != "" && actual != guid {
+		return fmt.Errorf("voucher GUID %s does not match %s", actual, guid)
+	}
+	if err := ov.VerifyEntries(); err != nil {
+		return fmt.Errorf("voucher entry chain verification failed: %w", err)
+	}
+	return nil
+}
+
+// runVoucherVerify implements "voucher verify": one integrity check, printed.
+// It reads the databases only; corruption is not audited.
+func runVoucherVerify(args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments %v", args)
+	}
+	stationDB, err := OpenStationDBReadOnly(stationDBPath(config))
+	if err != nil {
+		return err
+	}
+	defer stationDB.Close()
+
+	report := checkVoucherIntegrity(context.Background(), config, stationDB)
+	for _, source := range slices.Sorted(maps.Keys(report.Checked)) {
+		fmt.Printf("%s: %d vouchers checked\n", source, report.Checked[source])
+	}
+	for _, err := range report.Errors {
+		fmt.Printf("⚠️  %s\n", err)
+	}
+	for _, failure := range report.Failures {
+		fmt.Printf("❌ %s %s: %s\n", failure.Source, failure.Key, failure.Error)
+	}
+	if report.Corrupt > 0 {
+		return fmt.Errorf("%d of %d vouchers failed verification", report.Corrupt, report.total())
+	}
+	fmt.Printf("✅ All %d vouchers verified\n", report.total())
+	return nil
+}
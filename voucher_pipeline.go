@@ -0,0 +1,231 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/fido-device-onboard/go-fdo"
+	"github.com/fido-device-onboard/go-fdo/cbor"
+)
+
+// Stages of the voucher pipeline a DI session can resume after
+const (
+	PipelineSigned   = "signed"   // Owner decided and voucher signed; nothing delivered yet
+	PipelineUploaded = "uploaded" // Uploaded, or upload not configured
+	PipelineDone     = "done"     // Recorded in its batch, kept for transfer and saved to disk
+)
+
+// pipelineRetention is how long a GUID's pipeline state is kept. go-fdo
+// sessions expire long before, so no retry can come after it.
+const pipelineRetention = 24 * time.Hour
+
+// VoucherPipeline records, per GUID, the last stage of BeforeVoucherPersist
+// that completed, together with the signed voucher and the owner decisions.
+// When the station crashes after signing and the device retries its DI
+// session, the callback resumes after that stage: the voucher is not signed
+// over a second time and a finished upload is not repeated. Signed vouchers
+// are kept in the voucher store. A nil *VoucherPipeline records nothing.
+type VoucherPipeline struct {
+	db    *StationDB
+	store *VoucherStore
+}
+
+// PipelineRun is the pipeline state of one GUID
+type PipelineRun struct {
+	GUID          string
+	Serial        string
+	Model         string
+	Customer      string
+	UploadProfile string
+	DIDURL        string
+	Extended      bool // Signed over to an owner; false for signover mode none
+	Stage         string
+	Voucher       []byte // Signed voucher, CBOR
+	UpdatedAt     time.Time
+}
+
+// NewVoucherPipeline creates the pipeline state store
+func NewVoucherPipeline(db *StationDB) *VoucherPipeline {
+	return &VoucherPipeline{db: db, store: NewVoucherStore(db)}
+}
+
+// Initialize creates the voucher_pipeline table if it doesn't exist
+func (p *VoucherPipeline) Initialize(ctx context.Context) error {
+	_, err := p.db.db.ExecContext(ctx, `
+	CREATE TABLE IF NOT EXISTS voucher_pipeline (
+		guid TEXT PRIMARY KEY,
+		serial TEXT NOT NULL,
+		model TEXT NOT NULL,
+		customer TEXT NOT NULL,
+		upload_profile TEXT NOT NULL,
+		did_url TEXT NOT NULL,
+		extended INTEGER NOT NULL,
+		stage TEXT NOT NULL,
+		voucher_hash TEXT NOT NULL,
+		updated_at INTEGER NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create voucher_pipeline table: %w", err)
+	}
+	return nil
+}
+
+// Load returns the pipeline state of a GUID, or nil if its pipeline never got as far as signing
+func (p *VoucherPipeline) Load(ctx context.Context, guid string) (*PipelineRun, error) {
+	if p == nil {
+		return nil, nil
+	}
+	run := PipelineRun{GUID: guid}
+	var extended int
+	var updatedAt int64
+	err := p.db.db.QueryRowContext(ctx, `
+	SELECT p.serial, p.model, p.customer, p.upload_profile, p.did_url, p.extended, p.stage, b.voucher, p.updated_at
+	FROM voucher_pipeline p JOIN voucher_blobs b ON b.hash = p.voucher_hash WHERE p.guid = ?`, guid).Scan(
+		&run.Serial, &run.Model, &run.Customer, &run.UploadProfile, &run.DIDURL, &extended, &run.Stage, &run.Voucher, &updatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pipeline state of %s: %w", guid, err)
+	}
+	run.Extended = extended != 0
+	run.UpdatedAt = time.Unix(updatedAt, 0).UTC()
+	return &run, nil
+}
+
+// Resumable returns the pipeline state a DI session can resume from, or nil
+// to sign from scratch. A DI retried for the same serial gets its reserved
+// GUID back, but with a new HMAC secret and device certificate, so a stored
+// voucher is only resumed when its header and HMAC are those of the session's
+// voucher. The state of an earlier session is discarded.
+func (p *VoucherPipeline) Resumable(ctx context.Context, guid string, ov *fdo.Voucher) (*PipelineRun, error) {
+	run, err := p.Load(ctx, guid)
+	if err != nil || run == nil {
+		return nil, err
+	}
+	stored, _, err := run.Resume()
+	if err != nil {
+		return nil, err
+	}
+	same, err := sameVoucherSession(stored, ov)
+	if err != nil {
+		return nil, err
+	}
+	if same {
+		return run, nil
+	}
+	if err := p.store.remove(ctx, "voucher_pipeline", guid); err != nil {
+		return nil, fmt.Errorf("failed to discard stale pipeline state of %s: %w", guid, err)
+	}
+	fmt.Printf("⚠️  Discarded pipeline state of %s at stage %s: it belongs to an earlier DI session with another HMAC\n", guid, run.Stage)
+	return nil, nil
+}
+
+// sameVoucherSession reports whether two vouchers were made in the same DI
+// session: their header bytes and HMACs are equal
+func sameVoucherSession(a, b *fdo.Voucher) (bool, error) {
+	for _, pair := range [][2]any{{&a.Header, &b.Header}, {a.Hmac, b.Hmac}} {
+		x, err := cbor.Marshal(pair[0])
+		if err != nil {
+			return false, fmt.Errorf("failed to encode voucher: %w", err)
+		}
+		y, err := cbor.Marshal(pair[1])
+		if err != nil {
+			return false, fmt.Errorf("failed to encode voucher: %w", err)
+		}
+		if !bytes.Equal(x, y) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// Start records a signed voucher as stage "signed", and drops the state of
+// GUIDs past pipelineRetention
+func (p *VoucherPipeline) Start(ctx context.Context, run *PipelineRun, ov *fdo.Voucher) error {
+	run.Stage = PipelineSigned
+	if p == nil {
+		return nil
+	}
+	data, err := cbor.Marshal(ov)
+	if err != nil {
+		return fmt.Errorf("failed to encode voucher %s: %w", run.GUID, err)
+	}
+	run.Voucher, run.UpdatedAt = data, stationClock.Now().UTC()
+	if _, err := p.store.write(ctx, "voucher_pipeline", run.GUID, data, func(tx *sql.Tx, hash string) error {
+		_, err := tx.ExecContext(ctx, `
+		INSERT OR REPLACE INTO voucher_pipeline (guid, serial, model, customer, upload_profile, did_url, extended, stage, voucher_hash, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			run.GUID, run.Serial, run.Model, run.Customer, run.UploadProfile, run.DIDURL, run.Extended, run.Stage, hash, run.UpdatedAt.Unix())
+		return err
+	}); err != nil {
+		return fmt.Errorf("failed to record pipeline state of %s: %w", run.GUID, err)
+	}
+	p.prune(ctx)
+	return nil
+}
+
+// Advance records that a run completed a stage
+func (p *VoucherPipeline) Advance(ctx context.Context, run *PipelineRun, stage string) error {
+	now := stationClock.Now().UTC()
+	if p == nil {
+		run.Stage, run.UpdatedAt = stage, now
+		return nil
+	}
+	if _, err := p.db.db.ExecContext(ctx, `UPDATE voucher_pipeline SET stage = ?, updated_at = ? WHERE guid = ?`,
+		stage, now.Unix(), run.GUID); err != nil {
+		return fmt.Errorf("failed to record pipeline stage %s of %s: %w", stage, run.GUID, err)
+	}
+	run.Stage, run.UpdatedAt = stage, now
+	return nil
+}
+
+// prune removes the state of GUIDs not touched within pipelineRetention
+func (p *VoucherPipeline) prune(ctx context.Context) {
+	rows, err := p.db.db.QueryContext(ctx, `SELECT guid FROM voucher_pipeline WHERE updated_at < ? LIMIT 100`,
+		stationClock.Now().Add(-pipelineRetention).Unix())
+	if err != nil {
+		fmt.Printf("⚠️  Failed to prune pipeline state: %v\n", err)
+		return
+	}
+	var guids []string
+	for rows.Next() {
+		var guid string
+		if err := rows.Scan(&guid); err == nil {
+			guids = append(guids, guid)
+		}
+	}
+	rows.Close()
+	for _, guid := range guids {
+		if err := p.store.remove(ctx, "voucher_pipeline", guid); err != nil {
+			fmt.Printf("⚠️  Failed to prune pipeline state: %v\n", err)
+			return
+		}
+	}
+}
+
+// Resume returns the signed voucher of a run and the owner it was signed
+// over to, nil for an unextended voucher
+func (run *PipelineRun) Resume() (*fdo.Voucher, crypto.PublicKey, error) {
+	var ov fdo.Voucher
+	if err := cbor.Unmarshal(run.Voucher, &ov); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode stored voucher %s: %w", run.GUID, err)
+	}
+	if !run.Extended || len(ov.Entries) == 0 {
+		return &ov, nil, nil
+	}
+	owner, err := ov.Entries[len(ov.Entries)-1].Payload.Val.PublicKey.Public()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read owner key of stored voucher %s: %w", run.GUID, err)
+	}
+	return &ov, owner, nil
+}
@@ -0,0 +1,96 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/fido-device-onboard/go-fdo"
+	"github.com/fido-device-onboard/go-fdo/cbor"
+	"github.com/fido-device-onboard/go-fdo/cose"
+	"github.com/fido-device-onboard/go-fdo/protocol"
+)
+
+// TestPipelineRetryWithNewHMAC checks that a DI retried for the same serial,
+// which gets the reserved GUID back with a new HMAC, doesn't resume the
+// voucher signed for the earlier session
+func TestPipelineRetryWithNewHMAC(t *testing.T) {
+	ctx := context.Background()
+	db, err := OpenStationDB(filepath.Join(t.TempDir(), "station.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	pipeline := NewVoucherPipeline(db)
+	if err := pipeline.store.Initialize(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := pipeline.Initialize(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mfgKey, err := encodePublicKey(protocol.Secp384r1KeyType, protocol.X509KeyEnc, key.Public(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := fdo.VoucherHeader{
+		Version:         101,
+		RvInfo:          [][]protocol.RvInstruction{},
+		DeviceInfo:      "model-a",
+		ManufacturerKey: *mfgKey,
+	}
+	if _, err := rand.Read(header.GUID[:]); err != nil {
+		t.Fatal(err)
+	}
+	guid := fmt.Sprintf("%x", header.GUID[:])
+
+	// Each DI session of the device has its own HMAC secret
+	session := func() *fdo.Voucher {
+		hmac := make([]byte, 32)
+		_, _ = rand.Read(hmac)
+		return &fdo.Voucher{
+			Version: 101,
+			Header:  *cbor.NewBstr(header),
+			Hmac:    protocol.Hmac{Algorithm: protocol.HmacSha256Hash, Value: hmac},
+			Entries: []cose.Sign1Tag[fdo.VoucherEntryPayload, []byte]{},
+		}
+	}
+
+	first := session()
+	if err := pipeline.Start(ctx, &PipelineRun{GUID: guid, Serial: "SN-1", Model: "model-a"}, first); err != nil {
+		t.Fatal(err)
+	}
+
+	// The same session retried after a crash resumes
+	run, err := pipeline.Resumable(ctx, guid, first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run == nil || run.Stage != PipelineSigned {
+		t.Fatalf("retried session did not resume: %+v", run)
+	}
+
+	// A new DI for the same serial is signed from scratch, and the stale run is dropped
+	run, err = pipeline.Resumable(ctx, guid, session())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run != nil {
+		t.Fatalf("session with a new HMAC resumed the voucher of an earlier session")
+	}
+	if run, err := pipeline.Load(ctx, guid); err != nil || run != nil {
+		t.Fatalf("stale pipeline state kept: %+v, %v", run, err)
+	}
+}
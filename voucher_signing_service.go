@@ -14,6 +14,7 @@ import (
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/pem"
+	"errors"
 	"fmt"
 	"time"
 
@@ -21,6 +22,10 @@ import (
 	"github.com/fido-device-onboard/go-fdo/protocol"
 )
 
+// ErrSignerUnavailable marks a voucher that could not be signed because the
+// manufacturer key or external HSM could not be reached
+var ErrSignerUnavailable = errors.New("voucher signer unavailable")
+
 // VoucherSigningRequest represents a voucher signing request to external HSM
 type VoucherSigningRequest struct {
 	Voucher              string         `json:"voucher"`               // base64-encoded CBOR voucher
@@ -58,18 +63,33 @@ type VoucherSigningService struct {
 	config       *VoucherSigningConfig
 	executor     *ExternalCommandExecutor
 	stationID    string
-	sessionState interface{} // For accessing manufacturer keys
+	sessionState interface{}   // For accessing manufacturer keys
+	hsmSession   *HSMSession   // Key handle and reconnect state of the external HSM
+	fallback     crypto.Signer // Software copy of the manufacturer key for HSM outages; nil = none
+	notifier     *Notifier
 }
 
 // NewVoucherSigningService creates a new voucher signing service
 func NewVoucherSigningService(config *VoucherSigningConfig, executor *ExternalCommandExecutor, stationID string) *VoucherSigningService {
 	return &VoucherSigningService{
-		config:    config,
-		executor:  executor,
-		stationID: stationID,
+		config:     config,
+		executor:   executor,
+		stationID:  stationID,
+		hsmSession: NewHSMSession(config),
 	}
 }
 
+// SetFallbackSigner sets the software key used when the HSM is unreachable
+func (s *VoucherSigningService) SetFallbackSigner(fallback crypto.Signer, notifier *Notifier) {
+	s.fallback = fallback
+	s.notifier = notifier
+}
+
+// HasFallback reports whether a fallback signing key is configured
+func (s *VoucherSigningService) HasFallback() bool {
+	return s.fallback != nil
+}
+
 // SetSessionState sets the session state for accessing manufacturer keys
 func (s *VoucherSigningService) SetSessionState(sessionState interface{}) {
 	s.sessionState = sessionState
@@ -106,26 +126,13 @@ func (s *VoucherSigningService) signVoucherInternal(ctx context.Context, voucher
 	}
 
 	if manufacturerKey == nil {
-		return nil, fmt.Errorf("no manufacturer key available for internal signing")
+		return nil, fmt.Errorf("%w: no manufacturer key available for internal signing", ErrSignerUnavailable)
 	}
 
 	fmt.Printf("🔐 Using manufacturer key to extend voucher to next owner\n")
 
 	// Use fdo.ExtendVoucher with the manufacturer key and next owner
-	var extendedVoucher *fdo.Voucher
-
-	// Type assert nextOwner to satisfy protocol.PublicKeyOrChain constraint
-	switch key := nextOwner.(type) {
-	case *ecdsa.PublicKey:
-		extendedVoucher, err = fdo.ExtendVoucher(voucher, manufacturerKey, key, extraData)
-	case *rsa.PublicKey:
-		extendedVoucher, err = fdo.ExtendVoucher(voucher, manufacturerKey, key, extraData)
-	case []*x509.Certificate:
-		extendedVoucher, err = fdo.ExtendVoucher(voucher, manufacturerKey, key, extraData)
-	default:
-		return nil, fmt.Errorf("unsupported nextOwner key type: %T", nextOwner)
-	}
-
+	extendedVoucher, err := extendVoucherTo(voucher, manufacturerKey, nextOwner, extraData)
 	if err != nil {
 		return nil, fmt.Errorf("failed to extend voucher with internal signing: %w", err)
 	}
@@ -137,7 +144,7 @@ func (s *VoucherSigningService) signVoucherInternal(ctx context.Context, voucher
 // getManufacturerKey retrieves the manufacturer private key from the session state
 func (s *VoucherSigningService) getManufacturerKey(ctx context.Context) (crypto.Signer, error) {
 	if s.sessionState == nil {
-		return nil, fmt.Errorf("no session state available")
+		return nil, fmt.Errorf("%w: no session state available", ErrSignerUnavailable)
 	}
 
 	// Type assert to get the ManufacturerKey method
@@ -146,13 +153,13 @@ func (s *VoucherSigningService) getManufacturerKey(ctx context.Context) (crypto.
 		ManufacturerKey(ctx context.Context, keyType protocol.KeyType, rsaBits int) (crypto.Signer, []*x509.Certificate, error)
 	})
 	if !ok {
-		return nil, fmt.Errorf("session state does not support ManufacturerKey method")
+		return nil, fmt.Errorf("%w: session state does not support ManufacturerKey method", ErrSignerUnavailable)
 	}
 
 	// Get ECDSA P-384 manufacturer key (same as used in main.go)
 	manufacturerKey, _, err := state.ManufacturerKey(ctx, protocol.Secp384r1KeyType, 0)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get manufacturer key: %w", err)
+		return nil, fmt.Errorf("%w: failed to get manufacturer key: %w", ErrSignerUnavailable, err)
 	}
 
 	return manufacturerKey, nil
@@ -190,24 +197,11 @@ func (s *VoucherSigningService) signVoucherHSM(ctx context.Context, voucher *fdo
 		return nil, fmt.Errorf("failed to convert manufacturer public key: %w", convertErr)
 	}
 
-	externalSigner := NewExternalHSMSigner(cryptoPubKey, s.executor, s.config, s.stationID)
+	externalSigner := NewExternalHSMSigner(ctx, cryptoPubKey, s.executor, s.config, s.hsmSession, s.stationID)
 
 	// Use fdo.ExtendVoucher with the external signer
 	// The external signer will intercept crypto.Sign calls and delegate to HSM
-	var extendedVoucher *fdo.Voucher
-	var err error
-
-	// Type assert nextOwner to satisfy protocol.PublicKeyOrChain constraint
-	switch key := nextOwner.(type) {
-	case *ecdsa.PublicKey:
-		extendedVoucher, err = fdo.ExtendVoucher(voucher, externalSigner, key, extraData)
-	case *rsa.PublicKey:
-		extendedVoucher, err = fdo.ExtendVoucher(voucher, externalSigner, key, extraData)
-	case []*x509.Certificate:
-		extendedVoucher, err = fdo.ExtendVoucher(voucher, externalSigner, key, extraData)
-	default:
-		return nil, fmt.Errorf("unsupported nextOwner key type: %T", nextOwner)
-	}
+	extendedVoucher, err := extendVoucherTo(voucher, externalSigner, nextOwner, extraData)
 	if err != nil {
 		return nil, fmt.Errorf("failed to extend voucher with external HSM: %w", err)
 	}
@@ -0,0 +1,142 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// ErrVoucherHashMismatch marks a stored voucher whose bytes no longer match its hash
+var ErrVoucherHashMismatch = errors.New("voucher does not match its content hash")
+
+// VoucherStore keeps each distinct voucher once in the station database,
+// keyed by the SHA-256 of its CBOR encoding and counted by the rows that
+// reference it. Tables that hold vouchers (transfer_vouchers, session_records,
+// voucher_batch_queue, voucher_pipeline) store the hash in voucher_hash; rows
+// written before the store existed keep their voucher inline.
+type VoucherStore struct {
+	db *StationDB
+}
+
+// NewVoucherStore creates the voucher store
+func NewVoucherStore(db *StationDB) *VoucherStore {
+	return &VoucherStore{db: db}
+}
+
+// Initialize creates the voucher_blobs table if it doesn't exist
+func (s *VoucherStore) Initialize(ctx context.Context) error {
+	if _, err := s.db.db.ExecContext(ctx, `
+	CREATE TABLE IF NOT EXISTS voucher_blobs (
+		hash TEXT PRIMARY KEY,
+		voucher BLOB NOT NULL,
+		refs INTEGER NOT NULL,
+		created_at INTEGER NOT NULL
+	)`); err != nil {
+		return fmt.Errorf("failed to create voucher_blobs table: %w", err)
+	}
+	return nil
+}
+
+// voucherHash returns the content hash of a CBOR voucher, as lowercase hex
+func voucherHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// checkVoucherHash verifies a voucher read from the store against its hash.
+// Inline vouchers (no hash) are returned as they are.
+func checkVoucherHash(hash string, data []byte) error {
+	if hash != "" && voucherHash(data) != hash {
+		return fmt.Errorf("%w: %s", ErrVoucherHashMismatch, hash)
+	}
+	return nil
+}
+
+// write points the row of table for guid at a voucher. It stores the voucher
+// unless an identical one is already stored, runs insert (which writes the
+// row, with the hash in voucher_hash), and releases the voucher the row
+// referenced before, all in one transaction.
+func (s *VoucherStore) write(ctx context.Context, table, guid string, data []byte, insert func(tx *sql.Tx, hash string) error) (string, error) {
+	tx, err := s.db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to begin voucher store transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	previous, err := referencedHash(ctx, tx, table, guid)
+	if err != nil {
+		return "", err
+	}
+	hash := voucherHash(data)
+	if _, err := tx.ExecContext(ctx, `
+	INSERT INTO voucher_blobs (hash, voucher, refs, created_at) VALUES (?, ?, 1, ?)
+	ON CONFLICT (hash) DO UPDATE SET refs = refs + 1`,
+		hash, data, stationClock.Now().Unix()); err != nil {
+		return "", fmt.Errorf("failed to store voucher %s: %w", guid, err)
+	}
+	if err := insert(tx, hash); err != nil {
+		return "", err
+	}
+	if err := releaseVoucher(ctx, tx, previous); err != nil {
+		return "", err
+	}
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("failed to commit voucher %s: %w", guid, err)
+	}
+	return hash, nil
+}
+
+// remove deletes the row of table for guid and releases its voucher
+func (s *VoucherStore) remove(ctx context.Context, table, guid string) error {
+	tx, err := s.db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin voucher store transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	previous, err := referencedHash(ctx, tx, table, guid)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM `+table+` WHERE guid = ?`, guid); err != nil {
+		return fmt.Errorf("failed to remove voucher %s from %s: %w", guid, table, err)
+	}
+	if err := releaseVoucher(ctx, tx, previous); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit removal of voucher %s: %w", guid, err)
+	}
+	return nil
+}
+
+// referencedHash returns the voucher hash the row of table for guid holds, or "" if none
+func referencedHash(ctx context.Context, tx *sql.Tx, table, guid string) (string, error) {
+	var hash string
+	err := tx.QueryRowContext(ctx, `SELECT COALESCE(voucher_hash, '') FROM `+table+` WHERE guid = ?`, guid).Scan(&hash)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("failed to read voucher reference of %s: %w", guid, err)
+	}
+	return hash, nil
+}
+
+// releaseVoucher drops one reference to a voucher, deleting it with the last
+func releaseVoucher(ctx context.Context, tx *sql.Tx, hash string) error {
+	if hash == "" {
+		return nil
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE voucher_blobs SET refs = refs - 1 WHERE hash = ?`, hash); err != nil {
+		return fmt.Errorf("failed to release voucher %s: %w", hash, err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM voucher_blobs WHERE hash = ? AND refs <= 0`, hash); err != nil {
+		return fmt.Errorf("failed to release voucher %s: %w", hash, err)
+	}
+	return nil
+}
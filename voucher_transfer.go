@@ -0,0 +1,414 @@
+// SPDX-FileCopyrightText: (C) 2026 Dell Technologies
+// SPDX-License-Identifier: Apache 2.0
+// Author: Brad Goodman
+
+package main
+
+import (
+	"context"
+	"crypto"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fido-device-onboard/go-fdo"
+	"github.com/fido-device-onboard/go-fdo/cbor"
+)
+
+// TransferFormat identifies the transfer bundle format
+const TransferFormat = "fdo-station-transfer/1"
+
+// Largest transfer envelope accepted on import
+const maxTransferSize = 256 << 20
+
+// ErrTransferRejected marks an import that is not from a trusted station, is
+// not correctly signed, or is not a transfer bundle
+var ErrTransferRejected = errors.New("voucher transfer rejected")
+
+// TransferEnvelope is what export returns and import accepts. The bundle is
+// carried base64-encoded so the signed bytes survive being stored or re-indented.
+type TransferEnvelope struct {
+	Station   string `json:"station"`   // Exporting station, selects the key that verifies the signature
+	Bundle    string `json:"bundle"`    // Base64 JSON TransferBundle
+	Signature string `json:"signature"` // Base64 signature over the decoded bundle
+}
+
+// TransferBundle is the signed content of a transfer
+type TransferBundle struct {
+	Format     string            `json:"format"`
+	Station    string            `json:"station"`
+	InstanceID string            `json:"instance_id"`
+	CreatedAt  time.Time         `json:"created_at"`
+	Vouchers   []TransferVoucher `json:"vouchers"`
+}
+
+// TransferVoucher is one voucher with its metadata and audit trail
+type TransferVoucher struct {
+	GUID      string       `json:"guid"`
+	Serial    string       `json:"serial"`
+	Model     string       `json:"model"`
+	Customer  string       `json:"customer,omitempty"`
+	BatchID   string       `json:"batch_id,omitempty"`
+	LotNumber string       `json:"lot_number,omitempty"`
+	Origin    string       `json:"origin"` // Station that built the voucher
+	CreatedAt time.Time    `json:"created_at"`
+	Voucher   []byte       `json:"voucher"`                  // CBOR voucher as extended to its owner
+	Hash      string       `json:"voucher_sha256,omitempty"` // SHA-256 of Voucher
+	Audit     []AuditEvent `json:"audit,omitempty"`
+}
+
+// TransferImportResult reports what an import did with each voucher
+type TransferImportResult struct {
+	Station    string                `json:"station"`
+	Imported   []string              `json:"imported"`
+	Duplicates []string              `json:"duplicates"` // Already held, identical
+	Conflicts  []string              `json:"conflicts"`  // Already held with different voucher bytes; not replaced
+	Invalid    []TransferImportError `json:"invalid"`
+}
+
+// TransferImportError is a voucher that failed verification
+type TransferImportError struct {
+	GUID  string `json:"guid"`
+	Error string `json:"error"`
+}
+
+// VoucherTransferService keeps every voucher the station builds so it can be
+// exported to another station, e.g. when responsibility for a product moves
+// from a contract manufacturer to an OEM hub, and imports vouchers exported by
+// trusted stations. Exports are signed with the station's transfer key;
+// imports are verified against the exporting station's public key, every
+// voucher's entry chain is checked, and vouchers already held are detected by
+// GUID. A nil *VoucherTransferService keeps nothing.
+type VoucherTransferService struct {
+	config    *TransferConfig
+	db        *StationDB
+	store     *VoucherStore
+	auditLog  *AuditLog
+	buildInfo BuildInfo
+	signer    crypto.Signer               // nil = this station can't export
+	trusted   map[string]crypto.PublicKey // Station ID to transfer public key
+}
+
+// NewVoucherTransferService creates the transfer service, or returns nil if it is disabled
+func NewVoucherTransferService(config *TransferConfig, db *StationDB, auditLog *AuditLog, buildInfo BuildInfo) (*VoucherTransferService, error) {
+	if !config.Enabled {
+		return nil, nil
+	}
+	t := &VoucherTransferService{config: config, db: db, store: NewVoucherStore(db), auditLog: auditLog, buildInfo: buildInfo, trusted: map[string]crypto.PublicKey{}}
+	if config.SigningKeyFile != "" {
+		signer, err := loadPrivateKeyFile(config.SigningKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("transfer signing key: %w", err)
+		}
+		t.signer = signer
+	}
+	for _, station := range config.TrustedStations {
+		if station.StationID == "" || station.PublicKeyFile == "" {
+			return nil, fmt.Errorf("transfer: trusted stations need a station_id and public_key_file")
+		}
+		pemData, err := os.ReadFile(station.PublicKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read transfer key of %s: %w", station.StationID, err)
+		}
+		key, err := parseStaticPublicKey(string(pemData))
+		if err != nil {
+			return nil, fmt.Errorf("invalid transfer key of %s: %w", station.StationID, err)
+		}
+		t.trusted[station.StationID] = key
+	}
+	return t, nil
+}
+
+// Initialize creates the transfer_vouchers table if it doesn't exist
+func (t *VoucherTransferService) Initialize(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+	_, err := t.db.db.ExecContext(ctx, `
+	CREATE TABLE IF NOT EXISTS transfer_vouchers (
+		guid TEXT PRIMARY KEY,
+		serial TEXT NOT NULL,
+		model TEXT NOT NULL,
+		customer TEXT,
+		batch_id TEXT,
+		lot_number TEXT,
+		origin TEXT NOT NULL,
+		imported_from TEXT,
+		voucher BLOB NOT NULL,
+		created_at INTEGER NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create transfer_vouchers table: %w", err)
+	}
+	return t.db.addColumnIfMissing(ctx, "transfer_vouchers", "voucher_hash", "TEXT")
+}
+
+// Keep stores a voucher built by this station so it can be exported later
+func (t *VoucherTransferService) Keep(ctx context.Context, batch *Batch, serial, model, customer, guid string, ov *fdo.Voucher) error {
+	if t == nil {
+		return nil
+	}
+	data, err := cbor.Marshal(ov)
+	if err != nil {
+		return fmt.Errorf("failed to encode voucher %s for transfer: %w", guid, err)
+	}
+	var batchID, lot string
+	if batch != nil {
+		batchID, lot = batch.ID, batch.LotNumber
+	}
+	if _, err := t.store.write(ctx, "transfer_vouchers", guid, data, func(tx *sql.Tx, hash string) error {
+		_, err := tx.ExecContext(ctx, `
+		INSERT OR REPLACE INTO transfer_vouchers (guid, serial, model, customer, batch_id, lot_number, origin, voucher, voucher_hash, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, x'', ?, ?)`,
+			guid, serial, model, customer, batchID, lot, t.buildInfo.StationID, hash, time.Now().Unix())
+		return err
+	}); err != nil {
+		return fmt.Errorf("failed to keep voucher %s for transfer: %w", guid, err)
+	}
+	return nil
+}
+
+// transferFilters are the export filters and their columns
+var transferFilters = map[string]string{
+	"guid": "t.guid", "serial": "t.serial", "model": "t.model", "customer": "t.customer",
+	"batch_id": "t.batch_id", "lot": "t.lot_number", "origin": "t.origin", "voucher_sha256": "t.voucher_hash",
+}
+
+// Export builds and signs a transfer of the vouchers matching the filters
+func (t *VoucherTransferService) Export(ctx context.Context, filters map[string]string) (*TransferEnvelope, int, error) {
+	if t.signer == nil {
+		return nil, 0, fmt.Errorf("transfer: no signing_key_file configured; this station can't export")
+	}
+	var conds []string
+	var args []any
+	for name, value := range filters {
+		conds = append(conds, transferFilters[name]+" = ?")
+		args = append(args, value)
+	}
+	query := `SELECT t.guid, t.serial, t.model, COALESCE(t.customer, ''), COALESCE(t.batch_id, ''), COALESCE(t.lot_number, ''),
+		t.origin, COALESCE(t.voucher_hash, ''), COALESCE(b.voucher, t.voucher), t.created_at
+		FROM transfer_vouchers t LEFT JOIN voucher_blobs b ON b.hash = t.voucher_hash`
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	rows, err := t.db.db.QueryContext(ctx, query+" ORDER BY t.created_at, t.guid", args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query transfer vouchers: %w", err)
+	}
+	defer rows.Close()
+
+	bundle := TransferBundle{
+		Format:     TransferFormat,
+		Station:    t.buildInfo.StationID,
+		InstanceID: t.buildInfo.InstanceID,
+		CreatedAt:  time.Now().UTC(),
+		Vouchers:   []TransferVoucher{},
+	}
+	for rows.Next() {
+		var v TransferVoucher
+		var createdAt int64
+		if err := rows.Scan(&v.GUID, &v.Serial, &v.Model, &v.Customer, &v.BatchID, &v.LotNumber,
+			&v.Origin, &v.Hash, &v.Voucher, &createdAt); err != nil {
+			return nil, 0, fmt.Errorf("failed to read transfer voucher: %w", err)
+		}
+		if err := checkVoucherHash(v.Hash, v.Voucher); err != nil {
+			return nil, 0, fmt.Errorf("transfer voucher %s: %w", v.GUID, err)
+		}
+		v.Hash = voucherHash(v.Voucher)
+		v.CreatedAt = time.Unix(createdAt, 0).UTC()
+		bundle.Vouchers = append(bundle.Vouchers, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	rows.Close()
+
+	for i := range bundle.Vouchers {
+		q, err := parseListParams(nil, &auditListSpec)
+		if err != nil {
+			return nil, 0, err
+		}
+		q.limit = maxListLimit
+		q.filter("guid", bundle.Vouchers[i].GUID)
+		events, _, err := t.auditLog.List(ctx, q)
+		if err != nil {
+			return nil, 0, err
+		}
+		bundle.Vouchers[i].Audit = events
+	}
+
+	body, err := json.Marshal(bundle)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to encode transfer bundle: %w", err)
+	}
+	signature, err := signBundle(t.signer, body)
+	if err != nil {
+		return nil, 0, err
+	}
+	return &TransferEnvelope{
+		Station:   bundle.Station,
+		Bundle:    base64.StdEncoding.EncodeToString(body),
+		Signature: signature,
+	}, len(bundle.Vouchers), nil
+}
+
+// Import verifies a transfer from a trusted station and stores its new vouchers
+// and their audit trails. Vouchers already held are reported, never replaced.
+func (t *VoucherTransferService) Import(ctx context.Context, envelope *TransferEnvelope) (*TransferImportResult, error) {
+	key, ok := t.trusted[envelope.Station]
+	if !ok {
+		return nil, fmt.Errorf("%w: station %q is not a trusted transfer source", ErrTransferRejected, envelope.Station)
+	}
+	body, err := base64.StdEncoding.DecodeString(envelope.Bundle)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid bundle encoding: %w", ErrTransferRejected, err)
+	}
+	if err := verifyBundleSignature(key, body, envelope.Signature); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrTransferRejected, err)
+	}
+	var bundle TransferBundle
+	if err := json.Unmarshal(body, &bundle); err != nil {
+		return nil, fmt.Errorf("%w: invalid bundle: %w", ErrTransferRejected, err)
+	}
+	if bundle.Format != TransferFormat {
+		return nil, fmt.Errorf("%w: unsupported bundle format %q", ErrTransferRejected, bundle.Format)
+	}
+	if bundle.Station != envelope.Station {
+		return nil, fmt.Errorf("%w: bundle was signed for station %q, not %q", ErrTransferRejected, bundle.Station, envelope.Station)
+	}
+
+	result := &TransferImportResult{Station: bundle.Station, Imported: []string{}, Duplicates: []string{},
+		Conflicts: []string{}, Invalid: []TransferImportError{}}
+	for _, v := range bundle.Vouchers {
+		if err := verifyTransferVoucher(&v); err != nil {
+			result.Invalid = append(result.Invalid, TransferImportError{GUID: v.GUID, Error: err.Error()})
+			continue
+		}
+
+		var heldHash string
+		var held []byte
+		err := t.db.db.QueryRowContext(ctx, `SELECT COALESCE(voucher_hash, ''), voucher FROM transfer_vouchers WHERE guid = ?`, v.GUID).Scan(&heldHash, &held)
+		if err == nil && heldHash == "" {
+			heldHash = voucherHash(held)
+		}
+		switch {
+		case err == nil && heldHash == voucherHash(v.Voucher):
+			result.Duplicates = append(result.Duplicates, v.GUID)
+			continue
+		case err == nil:
+			result.Conflicts = append(result.Conflicts, v.GUID)
+			continue
+		case !errors.Is(err, sql.ErrNoRows):
+			return nil, fmt.Errorf("failed to look up voucher %s: %w", v.GUID, err)
+		}
+
+		origin := v.Origin
+		if origin == "" {
+			origin = bundle.Station
+		}
+		if _, err := t.store.write(ctx, "transfer_vouchers", v.GUID, v.Voucher, func(tx *sql.Tx, hash string) error {
+			_, err := tx.ExecContext(ctx, `
+			INSERT INTO transfer_vouchers (guid, serial, model, customer, batch_id, lot_number, origin, imported_from, voucher, voucher_hash, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, x'', ?, ?)`,
+				v.GUID, v.Serial, v.Model, v.Customer, v.BatchID, v.LotNumber, origin, bundle.Station, hash, v.CreatedAt.Unix())
+			return err
+		}); err != nil {
+			return nil, fmt.Errorf("failed to import voucher %s: %w", v.GUID, err)
+		}
+		for _, event := range v.Audit {
+			if err := t.auditLog.Import(ctx, event); err != nil {
+				return nil, fmt.Errorf("failed to import audit trail of %s: %w", v.GUID, err)
+			}
+		}
+		result.Imported = append(result.Imported, v.GUID)
+	}
+
+	t.auditLog.Record(ctx, AuditEvent{
+		Event: "voucher_transfer_imported",
+		Detail: fmt.Sprintf("from %s: %d imported, %d duplicates, %d conflicts, %d invalid", bundle.Station,
+			len(result.Imported), len(result.Duplicates), len(result.Conflicts), len(result.Invalid)),
+	})
+	return result, nil
+}
+
+// verifyTransferVoucher checks that a voucher decodes, belongs to the GUID it
+// is filed under, and that its entry chain is correctly signed
+func verifyTransferVoucher(v *TransferVoucher) error {
+	var ov fdo.Voucher
+	if err := cbor.Unmarshal(v.Voucher, &ov); err != nil {
+		return fmt.Errorf("invalid voucher: %w", err)
+	}
+	if v.Hash != "" && voucherHash(v.Voucher) != v.Hash {
+		return fmt.Errorf("%w: %s", ErrVoucherHashMismatch, v.Hash)
+	}
+	if guid := fmt.Sprintf("%x", ov.Header.Val.GUID[:]); guid != v.GUID {
+		return fmt.Errorf("voucher GUID %s does not match %s", guid, v.GUID)
+	}
+	if err := ov.VerifyEntries(); err != nil {
+		return fmt.Errorf("voucher entry chain verification failed: %w", err)
+	}
+	return nil
+}
+
+// ExportHandler serves GET /api/transfer/export. The filters guid, serial,
+// model, customer, batch_id, lot and origin select the vouchers; with none,
+// every voucher is exported.
+func (t *VoucherTransferService) ExportHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if t == nil {
+			writeJSONError(w, http.StatusNotFound, "voucher transfer is disabled")
+			return
+		}
+		filters := map[string]string{}
+		for name, values := range r.URL.Query() {
+			if _, ok := transferFilters[name]; !ok {
+				writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("unsupported filter %q", name))
+				return
+			}
+			filters[name] = values[0]
+		}
+		envelope, count, err := t.Export(r.Context(), filters)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		t.auditLog.Record(r.Context(), AuditEvent{
+			Event:  "voucher_transfer_exported",
+			Detail: fmt.Sprintf("%d vouchers, filters %v", count, filters),
+		})
+		writeJSON(w, http.StatusOK, envelope)
+	})
+}
+
+// ImportHandler serves POST /api/transfer/import
+func (t *VoucherTransferService) ImportHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if t == nil {
+			writeJSONError(w, http.StatusNotFound, "voucher transfer is disabled")
+			return
+		}
+		var envelope TransferEnvelope
+		if err := json.NewDecoder(io.LimitReader(r.Body, maxTransferSize)).Decode(&envelope); err != nil {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid transfer envelope: %v", err))
+			return
+		}
+		result, err := t.Import(r.Context(), &envelope)
+		if errors.Is(err, ErrTransferRejected) {
+			t.auditLog.Record(r.Context(), AuditEvent{Event: "voucher_transfer_rejected", Detail: err.Error()})
+			writeJSONError(w, http.StatusForbidden, err.Error())
+			return
+		}
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, result)
+	})
+}
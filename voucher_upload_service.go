@@ -7,8 +7,10 @@ package main
 import (
 	"context"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/fido-device-onboard/go-fdo"
 	"github.com/fido-device-onboard/go-fdo/cbor"
@@ -16,62 +18,196 @@ import (
 
 // VoucherUploadService handles uploading vouchers to external systems
 type VoucherUploadService struct {
-	executor *ExternalCommandExecutor
+	config       *VoucherConfig
+	executor     *ExternalCommandExecutor
+	httpUploader *VoucherHTTPUploader
+	batcher      *VoucherBatchUploader // nil = batch upload disabled
+	receipts     *UploadReceiptStore   // nil = receipts are not recorded
+	destinations *UploadDestinationCatalog
+	rollouts     *RolloutsConfig
+	notifier     *Notifier
 }
 
 // NewVoucherUploadService creates a new voucher upload service
-func NewVoucherUploadService(executor *ExternalCommandExecutor) *VoucherUploadService {
+func NewVoucherUploadService(config *VoucherConfig, executor *ExternalCommandExecutor, httpUploader *VoucherHTTPUploader, batcher *VoucherBatchUploader, receipts *UploadReceiptStore, destinations *UploadDestinationCatalog, rollouts *RolloutsConfig, notifier *Notifier) *VoucherUploadService {
 	return &VoucherUploadService{
-		executor: executor,
+		config:       config,
+		executor:     executor,
+		httpUploader: httpUploader,
+		batcher:      batcher,
+		receipts:     receipts,
+		destinations: destinations,
+		rollouts:     rollouts,
+		notifier:     notifier,
 	}
 }
 
-// UploadVoucher uploads a voucher to an external system
-func (v *VoucherUploadService) UploadVoucher(ctx context.Context, serial, model, guid string, voucher *fdo.Voucher, didURL string) error {
+// UploadVoucher uploads a voucher to an external system. authProfile names the
+// upload auth profile from the owner entry (empty = voucher_upload.auth_profile);
+// owner is the customer ID used to look up a catalog destination.
+func (v *VoucherUploadService) UploadVoucher(ctx context.Context, serial, model, guid string, voucher *fdo.Voucher, didURL, authProfile, owner string) error {
 	fmt.Printf("🔍 DEBUG: VoucherUploadService.UploadVoucher called!\n")
-	fmt.Printf("🔍 DEBUG: serial=%s, model=%s, guid=%s\n", serial, model, guid)
+	fmt.Printf("🔍 DEBUG: serial=%s, model=%s, guid=%s\n", serialRules.Serial(serial), serialRules.Model(model), guid)
 	if didURL != "" {
 		fmt.Printf("🔍 DEBUG: DID URL available: %s\n", didURL)
 	}
 
-	// Write voucher to temporary file
-	voucherFile, err := os.CreateTemp("", "voucher-*.cbor")
+	// Ensure we have a GUID if not provided
+	if guid == "" {
+		guid = hex.EncodeToString(voucher.Header.Val.GUID[:])
+	}
+
+	// A stored receipt means an earlier attempt already delivered this voucher
+	if v.receipts != nil {
+		existing, err := v.receipts.Get(ctx, guid)
+		if err != nil {
+			fmt.Printf("⚠️  Failed to check upload receipt for %s: %v\n", guid, err)
+		} else if existing != nil {
+			fmt.Printf("✅ Voucher %s already uploaded (receipt %q), skipping upload\n", guid, existing.ReceiptID)
+			return nil
+		}
+	}
+
+	var receipt *UploadReceipt
+	var err error
+	if v.config.VoucherUpload.Mode == "http" && v.rollouts.HTTPUpload.Includes(RolloutHTTPUpload, serial, model) {
+		receipt, err = v.uploadHTTP(ctx, serial, model, guid, voucher, didURL, authProfile, owner)
+	} else {
+		receipt, err = v.uploadCommand(ctx, serial, model, guid, voucher, didURL)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to create temp voucher file: %w", err)
+		v.notifier.RecordFailure("voucher_upload", fmt.Sprintf("upload of %s failed: %v", serialRules.Serial(serial), err))
+		return err
+	}
+	if err := v.verifyReceipt(receipt, voucher); err != nil {
+		v.notifier.RecordFailure("voucher_upload", fmt.Sprintf("upload of %s not acknowledged by the owner: %v", serialRules.Serial(serial), err))
+		return err
+	}
+	v.notifier.RecordSuccess("voucher_upload")
+
+	v.saveReceipt(ctx, receipt)
+	return nil
+}
+
+// verifyReceipt checks the owner's signature of an upload receipt. Unsigned
+// and invalid receipts are still stored, with their verification status,
+// unless voucher_upload.require_signed_receipts makes them an upload failure.
+func (v *VoucherUploadService) verifyReceipt(receipt *UploadReceipt, voucher *fdo.Voucher) error {
+	if receipt == nil {
+		return nil // Batched; verified when the batch is acknowledged
 	}
-	if err := os.Remove(voucherFile.Name()); err != nil {
-		fmt.Printf("Warning: failed to remove voucher file: %v\n", err)
+	err := receipt.verify(voucher)
+	switch {
+	case err == nil:
+		fmt.Printf("🔏 Receipt for %s signed by owner key %s\n", serialRules.Serial(receipt.Serial), receipt.OwnerKeySHA256)
+	case v.config.VoucherUpload.RequireSignedReceipts:
+		return fmt.Errorf("%w: %v", ErrUploadRejected, err)
+	case receipt.Verification == ReceiptInvalid:
+		fmt.Printf("⚠️  Receipt for %s not verified: %v\n", serialRules.Serial(receipt.Serial), err)
 	}
+	return nil
+}
+
+// uploadCommand hands the voucher to the configured external upload command
+func (v *VoucherUploadService) uploadCommand(ctx context.Context, serial, model, guid string, voucher *fdo.Voucher, didURL string) (*UploadReceipt, error) {
+	// Write voucher to a file in the session temp dir
+	voucherFile, err := createSessionTemp(ctx, "voucher-*.cbor")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp voucher file: %w", err)
+	}
+	defer func() {
+		_ = os.Remove(voucherFile.Name())
+	}()
 
 	// Serialize voucher to file
 	voucherData, err := cbor.Marshal(voucher)
 	if err != nil {
-		return fmt.Errorf("failed to marshal voucher: %w", err)
+		return nil, fmt.Errorf("failed to marshal voucher: %w", err)
 	}
 	if _, err := voucherFile.Write(voucherData); err != nil {
-		return fmt.Errorf("failed to write voucher file: %w", err)
+		return nil, fmt.Errorf("failed to write voucher file: %w", err)
 	}
 	if err := voucherFile.Close(); err != nil {
-		return fmt.Errorf("failed to close voucher file: %w", err)
+		return nil, fmt.Errorf("failed to close voucher file: %w", err)
 	}
 
-	// Ensure we have a GUID if not provided
-	if guid == "" {
-		guid = hex.EncodeToString(voucher.Header.Val.GUID[:])
+	variables := map[string]string{
+		"serialno":       serial,
+		"model":          model,
+		"voucherfile":    voucherFile.Name(),
+		"guid":           guid,
+		"did_url":        didURL, // DID URL for voucher upload (empty if not available)
+		"voucher_sha256": voucherHash(voucherData),
 	}
 
-	variables := map[string]string{
-		"serialno":    serial,
-		"model":       model,
-		"voucherfile": voucherFile.Name(),
-		"guid":        guid,
-		"did_url":     didURL, // DID URL for voucher upload (empty if not available)
+	output, err := v.executor.Execute(ctx, variables)
+	if err != nil {
+		return nil, fmt.Errorf("voucher upload failed: %w", err)
+	}
+
+	// Commands may print the recipient's structured response to pass its receipt on
+	var parsed UploadResponse
+	_ = json.Unmarshal([]byte(output), &parsed)
+	status := "accepted"
+	if parsed.isDuplicate() {
+		status = "duplicate"
+	}
+
+	return &UploadReceipt{
+		GUID:         guid,
+		Serial:       serial,
+		RecipientURL: didURL,
+		ReceiptID:    parsed.receiptID(),
+		Status:       status,
+		VoucherHash:  voucherHash(voucherData),
+		UploadedAt:   time.Now(),
+		Signature:    parsed.ReceiptSignature,
+	}, nil
+}
+
+// saveReceipt records the upload receipt; failures are logged but don't fail the upload
+func (v *VoucherUploadService) saveReceipt(ctx context.Context, receipt *UploadReceipt) {
+	if v.receipts == nil || receipt == nil {
+		return
+	}
+	if err := v.receipts.Save(ctx, receipt); err != nil {
+		fmt.Printf("⚠️  %v\n", err)
+	}
+}
+
+// uploadHTTP pushes the voucher to the owner's catalog destination, or else the
+// owner's voucherRecipientURL (or the configured URL)
+func (v *VoucherUploadService) uploadHTTP(ctx context.Context, serial, model, guid string, voucher *fdo.Voucher, didURL, authProfile, owner string) (*UploadReceipt, error) {
+	recipientURL := didURL
+	if recipientURL == "" {
+		recipientURL = v.config.VoucherUpload.URL
+	}
+	if authProfile == "" {
+		authProfile = v.config.VoucherUpload.AuthProfile
 	}
 
-	_, err = v.executor.Execute(ctx, variables)
+	dest, err := v.destinations.Resolve(ctx, owner, recipientURL, authProfile)
 	if err != nil {
-		return fmt.Errorf("voucher upload failed: %w", err)
+		return nil, err
+	}
+	if dest.URL == "" {
+		return nil, fmt.Errorf("http upload mode requires a voucherRecipientURL from the owner DID or voucher_upload.url")
 	}
 
-	return nil
+	voucherText, err := formatVoucherFile(voucher)
+	if err != nil {
+		return nil, fmt.Errorf("failed to format voucher for upload: %w", err)
+	}
+
+	// Batched vouchers get their receipt when the batch is acknowledged
+	if v.batcher != nil {
+		return nil, v.batcher.Enqueue(ctx, dest.URL, dest.AuthProfile, serial, model, guid, []byte(voucherText))
+	}
+
+	if err := v.destinations.Allow(ctx, dest); err != nil {
+		return nil, err
+	}
+	receipt, err := v.httpUploader.Upload(ctx, dest.URL, dest.AuthProfile, serial, model, guid, []byte(voucherText))
+	v.destinations.RecordResult(ctx, dest.URL, err)
+	return receipt, err
 }